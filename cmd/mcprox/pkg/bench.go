@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/bench"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	benchURLs        []string
+	benchTimeout     int
+	benchCalls       int
+	benchConcurrency int
+	benchTool        string
+	benchArgs        string
+)
+
+func init() {
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure the overhead of serve mode's tool calls",
+		Long: `Builds the serve mode server for OpenAPI documentation, points every API at a
+local stub upstream and replays synthetic tool calls through the server's
+JSON-RPC handling. Reports the latency percentiles and heap allocations per
+call, which makes regressions in the handler path measurable.
+
+Tools are called in turn with arguments synthesized from their input schemas,
+unless --tool selects one, optionally with --args as a JSON object. No request
+reaches the configured services.
+
+Example:
+  mcprox bench --url https://api.example.com/openapi.json
+  mcprox bench --url https://api.example.com/openapi.json --calls 10000 --concurrency 8 --tool get_pets`,
+		RunE: benchServer,
+	}
+
+	benchCmd.Flags().StringArrayVarP(&benchURLs, "url", "u", nil, "URL to fetch OpenAPI documentation, as url or name=url (repeatable; default: the configured sources)")
+	benchCmd.Flags().IntVarP(&benchTimeout, "timeout", "t", 30, "Timeout in seconds for fetching the OpenAPI documentation")
+	benchCmd.Flags().IntVarP(&benchCalls, "calls", "n", 1000, "Number of measured tool calls")
+	benchCmd.Flags().IntVarP(&benchConcurrency, "concurrency", "c", 1, "Number of tool calls in flight at once")
+	benchCmd.Flags().StringVar(&benchTool, "tool", "", "Only call this tool")
+	benchCmd.Flags().StringVar(&benchArgs, "args", "", "Arguments of --tool as a JSON object (default: synthesized from its schema)")
+
+	benchCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+
+	rootCmd.AddCommand(benchCmd)
+}
+
+func benchServer(cmd *cobra.Command, args []string) error {
+	opts := bench.Options{
+		Calls:       benchCalls,
+		Concurrency: benchConcurrency,
+		Warmup:      min(benchCalls, 100),
+		Tool:        benchTool,
+	}
+	if benchArgs != "" {
+		if benchTool == "" {
+			return fmt.Errorf("--args requires --tool")
+		}
+		if err := json.Unmarshal([]byte(benchArgs), &opts.Arguments); err != nil {
+			return fmt.Errorf("invalid --args, expected a JSON object: %w", err)
+		}
+	}
+
+	upstream := bench.NewStubUpstream()
+	defer upstream.Close()
+	if err := useUpstream(upstream.URL); err != nil {
+		return err
+	}
+
+	api, err := buildServer(benchURLs, benchTimeout, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("Benchmarking tool calls", zap.Int("calls", opts.Calls), zap.Int("concurrency", opts.Concurrency))
+	result, err := bench.Run(ctx, api.server, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Tools:        %d\n", result.Tools)
+	fmt.Printf("Calls:        %d (%d errors)\n", result.Calls, result.Errors)
+	fmt.Printf("Duration:     %s (%.0f calls/s)\n", result.Duration.Round(time.Microsecond), result.CallsPerSecond())
+	fmt.Printf("Latency p50:  %s\n", result.P50)
+	fmt.Printf("Latency p95:  %s\n", result.P95)
+	fmt.Printf("Latency p99:  %s\n", result.P99)
+	fmt.Printf("Latency max:  %s\n", result.Max)
+	fmt.Printf("Allocations:  %d allocs/call, %d B/call (stub upstream included)\n", result.AllocsPerCall, result.BytesPerCall)
+	if result.Errors > 0 {
+		return fmt.Errorf("%d of %d tool calls failed", result.Errors, result.Calls)
+	}
+	return nil
+}
+
+// useUpstream points the service URL of every API at url
+func useUpstream(url string) error {
+	viper.Set("service.url", url)
+
+	sources, err := config.GetSources()
+	if err != nil {
+		return err
+	}
+	overridden := make([]map[string]interface{}, 0, len(sources))
+	for _, source := range sources {
+		overridden = append(overridden, map[string]interface{}{
+			"name":          source.Name,
+			"url":           source.URL,
+			"service_url":   url,
+			"authorization": source.Authorization,
+		})
+	}
+	viper.Set("sources", overridden)
+	return nil
+}
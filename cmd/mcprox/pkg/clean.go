@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"fmt"
+
+	"github.com/berkantay/mcprox/internal/mcp/generator"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	cleanDryRun bool
+	cleanForce  bool
+)
+
+func init() {
+	cleanCmd := &cobra.Command{
+		Use:   "clean <project-dir>...",
+		Short: "Remove previously generated MCP server files",
+		Long: `Removes the files mcprox generated into a project, as recorded in its
+` + generator.ManifestFileName + ` manifest. Files added by hand are kept, as are the
+directories containing them. Generated files edited since are kept too unless
+--force is given.
+
+Example:
+  mcprox clean ./generated/pet_store_mcp_server`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeProjectDir(0),
+		RunE:              cleanProjects,
+	}
+
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "List the files that would be removed without removing them")
+	cleanCmd.Flags().BoolVar(&cleanForce, "force", false, "Also remove generated files that were edited by hand")
+
+	rootCmd.AddCommand(cleanCmd)
+}
+
+func cleanProjects(cmd *cobra.Command, args []string) error {
+	for _, projectDir := range args {
+		removed, kept, err := generator.Clean(projectDir, cleanDryRun, cleanForce)
+		if err != nil {
+			return fmt.Errorf("failed to clean %s: %w", projectDir, err)
+		}
+
+		for _, path := range removed {
+			if cleanDryRun {
+				fmt.Println("would remove", path)
+			} else {
+				logger.Debug("Removed", zap.String("path", path))
+			}
+		}
+		for _, path := range kept {
+			logger.Warn("Kept generated file edited by hand; use --force to remove it", zap.String("path", path))
+		}
+		logger.Info("Cleaned generated project",
+			zap.String("project_dir", projectDir),
+			zap.Int("removed", len(removed)),
+			zap.Bool("dry_run", cleanDryRun))
+	}
+	return nil
+}
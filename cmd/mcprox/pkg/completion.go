@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/generator"
+	"github.com/spf13/cobra"
+)
+
+// loadCompletionConfig reloads the configuration when --config is given on
+// the command line being completed: the initializers loading it run before
+// the flags of the completed command are parsed
+func loadCompletionConfig() {
+	if cfgFile != "" {
+		initConfig()
+	}
+}
+
+// completeValues completes a flag taking one of a fixed set of values
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeFileExt completes a flag taking a file with one of the given extensions
+func completeFileExt(extensions ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return extensions, cobra.ShellCompDirectiveFilterFileExt
+	}
+}
+
+// completeDirs completes a flag taking a directory
+func completeDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return nil, cobra.ShellCompDirectiveFilterDirs
+}
+
+// completeSourceURLs completes --url with the APIs configured as sources and
+// the services of the project file, as name=url, falling back to no
+// completion for URLs typed by hand
+func completeSourceURLs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	loadCompletionConfig()
+	sources, err := config.GetSources()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var completions []string
+	add := func(name, url, description string) {
+		if name == "" || url == "" {
+			return
+		}
+		value := name + "=" + url
+		if strings.HasPrefix(value, toComplete) {
+			completions = append(completions, value+"\t"+description)
+		}
+	}
+	for _, source := range sources {
+		add(source.Name, source.URL, source.Name+" API")
+	}
+	for _, service := range projectServices(cmd) {
+		add(service.Name, service.URL, service.Name+" service")
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// projectServices returns the services of the project file given by
+// --project, or of the default one for commands without the flag
+func projectServices(cmd *cobra.Command) []config.ProjectService {
+	path := config.ProjectFileName
+	if flag := cmd.Flags().Lookup("project"); flag != nil {
+		path = flag.Value.String()
+	}
+	project, err := config.LoadProject(path)
+	if err != nil {
+		return nil
+	}
+	return project.Services
+}
+
+// completeProjectDir completes the project directory arguments of a command
+// taking at most max of them (0 for any number) with the generated projects in
+// the configured output directory, or any directory if there are none
+func completeProjectDir(max int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if max > 0 && len(args) >= max {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		loadCompletionConfig()
+		outputDir := config.GetString("output.dir")
+		entries, err := os.ReadDir(outputDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		var projects []string
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			dir := filepath.Join(outputDir, entry.Name())
+			if strings.HasPrefix(toComplete, "."+string(filepath.Separator)) {
+				dir = "." + string(filepath.Separator) + dir
+			}
+			if !strings.HasPrefix(dir, toComplete) || slices.Contains(args, dir) {
+				continue
+			}
+			if _, err := generator.ReadManifest(dir); err == nil {
+				projects = append(projects, dir)
+			}
+		}
+		if len(projects) == 0 {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		return projects, cobra.ShellCompDirectiveNoFileComp
+	}
+}
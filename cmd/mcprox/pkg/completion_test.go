@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func writeProject(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mcprox.yaml")
+	project := `services:
+  - name: billing
+    url: https://billing.internal/openapi.json
+  - name: users
+    url: https://users.internal/openapi.json
+`
+	if err := os.WriteFile(path, []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompleteSourceURLs(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("sources", []map[string]interface{}{{"name": "pets", "url": "https://pets.internal/openapi.json"}})
+
+	project := writeProject(t)
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+	tests := []struct {
+		name       string
+		project    *string
+		toComplete string
+		want       []string
+	}{
+		{"sources and services", &project, "", []string{
+			"pets=https://pets.internal/openapi.json\tpets API",
+			"billing=https://billing.internal/openapi.json\tbilling service",
+			"users=https://users.internal/openapi.json\tusers service",
+		}},
+		{"prefix", &project, "u", []string{"users=https://users.internal/openapi.json\tusers service"}},
+		{"missing project", &missing, "", []string{"pets=https://pets.internal/openapi.json\tpets API"}},
+		{"no project flag", nil, "b", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "generate"}
+			if tt.project != nil {
+				cmd.Flags().String("project", *tt.project, "")
+			}
+			got, directive := completeSourceURLs(cmd, nil, tt.toComplete)
+			if !slices.Equal(got, tt.want) || directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("completeSourceURLs() = %q, %v, want %q", got, directive, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/version"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"go.uber.org/zap"
+)
+
+// Formats of the CLI reference generated by mcprox docs
+const (
+	docsFormatMarkdown = "markdown"
+	docsFormatMan      = "man"
+)
+
+var (
+	docsFormat string
+	docsDir    string
+)
+
+func init() {
+	docsCmd := &cobra.Command{
+		Use:   "docs",
+		Short: "Generate the CLI reference as markdown or man pages",
+		Long: `Generates reference documentation for every mcprox command and flag, plus a
+page listing every configuration key with its environment variable and default.
+
+Markdown writes one mcprox_<command>.md file per command and
+mcprox_configuration.md. Man writes mcprox-<command>.1 pages and the
+mcprox-config.5 page. Set SOURCE_DATE_EPOCH for reproducible man pages.
+
+Example:
+  mcprox docs --dir ./docs
+  mcprox docs --format man --dir ./man`,
+		Args: cobra.NoArgs,
+		RunE: generateDocs,
+	}
+
+	docsCmd.Flags().StringVar(&docsFormat, "format", docsFormatMarkdown, "Documentation format: markdown or man")
+	docsCmd.Flags().StringVarP(&docsDir, "dir", "d", "docs", "Directory to write the documentation to")
+
+	docsCmd.RegisterFlagCompletionFunc("format", completeValues(docsFormatMarkdown, docsFormatMan))
+	docsCmd.RegisterFlagCompletionFunc("dir", completeDirs)
+
+	rootCmd.AddCommand(docsCmd)
+}
+
+func generateDocs(cmd *cobra.Command, args []string) error {
+	if err := os.MkdirAll(docsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", docsDir, err)
+	}
+
+	// Keep the output free of generation dates so it can be committed
+	rootCmd.DisableAutoGenTag = true
+
+	switch docsFormat {
+	case docsFormatMarkdown:
+		if err := doc.GenMarkdownTree(rootCmd, docsDir); err != nil {
+			return fmt.Errorf("failed to generate markdown docs: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(docsDir, "mcprox_configuration.md"), []byte(configMarkdown()), 0644); err != nil {
+			return err
+		}
+	case docsFormatMan:
+		date, err := manDate()
+		if err != nil {
+			return err
+		}
+		header := &doc.GenManHeader{
+			Title:   "MCPROX",
+			Section: "1",
+			Date:    &date,
+			Source:  "mcprox " + version.Get(),
+			Manual:  "mcprox Manual",
+		}
+		if err := doc.GenManTree(rootCmd, header, docsDir); err != nil {
+			return fmt.Errorf("failed to generate man pages: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(docsDir, "mcprox-config.5"), []byte(configManPage(header)), 0644); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid --format %q, expected %s or %s", docsFormat, docsFormatMarkdown, docsFormatMan)
+	}
+
+	logger.Info("Generated CLI reference", zap.String("dir", docsDir), zap.String("format", docsFormat))
+	return nil
+}
+
+// manDate returns the date of the man pages, taken from SOURCE_DATE_EPOCH if
+// set, as cobra does
+func manDate() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %w", err)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// configMarkdown renders the configuration keys as a markdown page
+func configMarkdown() string {
+	var sb strings.Builder
+	sb.WriteString("## mcprox configuration\n\n")
+	sb.WriteString("Configuration keys of mcprox\n\n")
+	sb.WriteString("### Synopsis\n\n")
+	sb.WriteString("mcprox reads its configuration from a YAML file ($HOME/.mcprox.yaml by default, or --config),\n")
+	sb.WriteString("from environment variables and from command line flags, which take precedence in reverse order.\n")
+	sb.WriteString("Nested keys are written as YAML mappings, e.g. `service.url` as `url` under `service`.\n\n")
+	sb.WriteString("### Keys\n\n")
+	sb.WriteString("| Key | Environment variable | Default | Description |\n")
+	sb.WriteString("| --- | --- | --- | --- |\n")
+	for _, key := range config.Keys {
+		sb.WriteString(fmt.Sprintf("| `%s` | `%s` | %s | %s |\n", key.Name, key.EnvVar(), markdownDefault(key.Default), key.Description))
+	}
+	sb.WriteString("\n### SEE ALSO\n\n")
+	sb.WriteString("* [mcprox](mcprox.md)\t - " + rootCmd.Short + "\n")
+	return sb.String()
+}
+
+// markdownDefault formats the default value of a key for a markdown table
+func markdownDefault(value interface{}) string {
+	text := defaultText(value)
+	if text == "" {
+		return ""
+	}
+	return "`" + text + "`"
+}
+
+// defaultText formats the default value of a key, empty if it has none
+func defaultText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// configManPage renders the configuration keys as a section 5 man page
+func configManPage(header *doc.GenManHeader) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(".TH \"MCPROX-CONFIG\" \"5\" \"%s\" \"%s\" \"%s\"\n", header.Date.Format("Jan 2006"), header.Source, header.Manual))
+	sb.WriteString(".SH NAME\n")
+	sb.WriteString("mcprox-config \\- configuration keys of mcprox\n")
+	sb.WriteString(".SH DESCRIPTION\n")
+	sb.WriteString("mcprox reads its configuration from a YAML file ($HOME/.mcprox.yaml by default, or \\fB\\-\\-config\\fP),\n")
+	sb.WriteString("from environment variables and from command line flags, which take precedence in reverse order.\n")
+	sb.WriteString(".SH KEYS\n")
+	for _, key := range config.Keys {
+		sb.WriteString(fmt.Sprintf(".TP\n\\fB%s\\fP (\\fB%s\\fP)\n", manEscape(key.Name), manEscape(key.EnvVar())))
+		sb.WriteString(manEscape(key.Description))
+		if text := defaultText(key.Default); text != "" {
+			sb.WriteString(fmt.Sprintf(" (default: %s)", manEscape(text)))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(".SH SEE ALSO\n")
+	sb.WriteString("\\fBmcprox(1)\\fP\n")
+	return sb.String()
+}
+
+// manEscape escapes the characters troff interprets in running text
+func manEscape(text string) string {
+	return strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/export"
+	"github.com/berkantay/mcprox/internal/mcp"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	exportURLs    []string
+	exportTimeout int
+	exportFormat  string
+	exportOutput  string
+)
+
+func init() {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Describe the tools mcprox serves in another format",
+		Long: `Builds the tools serve mode would serve for the OpenAPI documentation given by
+--url, or the configured sources, with every filter, rename, override and
+script applied, and describes them in another format.
+
+The openapi format is an OpenAPI 3 document with a POST /tools/{name}
+operation per tool, its request body the tool's arguments, for reviewing what
+models can actually call. The upstream operation a generated tool calls is
+given by the x-mcprox-upstream extension.
+
+The mcp-manifest format is a JSON list of the tools with their input and
+output schemas and annotations, for cataloging servers in a registry.
+
+The openai-functions format is the tools parameter of the OpenAI API, for
+calling the same operations from an OpenAI model without MCP, and the
+anthropic-tools format is the tools parameter of the Anthropic Messages API.
+
+Example:
+  mcprox export --format openapi --url https://api.example.com/openapi.json
+  mcprox export --format openapi --output tools.json
+  mcprox export --format mcp-manifest --output manifest.json
+  mcprox export --format openai-functions --output functions.json
+  mcprox export --format anthropic-tools --output tools.json`,
+		Args: cobra.NoArgs,
+		RunE: exportTools,
+	}
+
+	exportCmd.Flags().StringArrayVarP(&exportURLs, "url", "u", nil, "URL to fetch OpenAPI documentation, as url or name=url (repeatable)")
+	exportCmd.Flags().IntVarP(&exportTimeout, "timeout", "t", 30, "Timeout in seconds for fetching the OpenAPI documentation")
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", export.FormatOpenAPI, "Format of the export: "+strings.Join(export.Formats, ", "))
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write the export to (default: standard output)")
+
+	exportCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+	exportCmd.RegisterFlagCompletionFunc("format", completeValues(export.Formats...))
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+func exportTools(cmd *cobra.Command, args []string) error {
+	specs, err := resolveSpecs(exportURLs)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), time.Duration(exportTimeout)*time.Second)
+	defer cancel()
+	doc, _, err := loadDocument(ctx, specs, config.Filters{})
+	if err != nil {
+		return err
+	}
+	generator := mcp.NewGenerator(logger)
+	if _, err := generator.BuildServer(doc); err != nil {
+		return fmt.Errorf("failed to build MCP server: %w", err)
+	}
+
+	tools := generator.ExportedTools()
+	exported, err := export.Export(exportFormat, doc.Info.Title, doc.Info.Version, tools)
+	if err != nil {
+		return err
+	}
+
+	if exportOutput == "" {
+		return printJSON(exported)
+	}
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(exportOutput, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", exportOutput, err)
+	}
+	logger.Info("Exported tools", zap.String("format", exportFormat), zap.String("output", exportOutput), zap.Int("tools", len(tools)))
+	return nil
+}
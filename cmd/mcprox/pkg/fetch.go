@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	fetchURL             string
+	fetchTimeout         int
+	fetchOutput          string
+	fetchTransformations string
+)
+
+func init() {
+	fetchCmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Download an OpenAPI document and write it normalized to a file",
+		Long: `Fetches the OpenAPI documentation at --url with the configured spec headers,
+applies the same preprocessing as generate and serve, validates the result and
+writes it to --output. Generating from the written file then needs no network
+access, so fetching can run on a CI machine and generating in a locked-down
+build step.
+
+Example:
+  mcprox fetch --url https://api.example.com/openapi.json --output openapi.json
+  mcprox fetch --url https://internal.example.com/openapi.json --header "Authorization: Bearer $TOKEN" --output openapi.json
+  mcprox fetch --url https://sso.example.com/openapi.json --cookie-file cookies.txt --output openapi.json
+  mcprox generate --url openapi.json`,
+		Args: cobra.NoArgs,
+		RunE: fetchSpec,
+	}
+
+	fetchCmd.Flags().StringVarP(&fetchURL, "url", "u", "", "URL to fetch OpenAPI documentation from (required)")
+	fetchCmd.Flags().IntVarP(&fetchTimeout, "timeout", "t", 30, "Timeout in seconds for fetching the OpenAPI documentation")
+	fetchCmd.Flags().StringVarP(&fetchOutput, "output", "o", "", "File to write the normalized document to (default: standard output)")
+	fetchCmd.Flags().StringVar(&fetchTransformations, "transformations", "", "File to write the log of the changes preprocessing made to")
+	fetchCmd.Flags().StringArrayP("header", "H", nil, "Header sent with the request, as Name: value (repeatable)")
+	fetchCmd.Flags().StringArrayP("cookie", "b", nil, "Cookie sent with the request, as name=value (repeatable)")
+	fetchCmd.Flags().String("cookie-file", "", "Cookie file in the Netscape cookies.txt format to send the cookies of")
+	fetchCmd.MarkFlagRequired("url")
+
+	fetchCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+	fetchCmd.RegisterFlagCompletionFunc("output", completeFileExt("json"))
+	fetchCmd.RegisterFlagCompletionFunc("transformations", completeFileExt("log"))
+	fetchCmd.RegisterFlagCompletionFunc("cookie-file", completeFileExt("txt"))
+
+	viper.BindPFlag("spec.headers", fetchCmd.Flags().Lookup("header"))
+	viper.BindPFlag("spec.cookies", fetchCmd.Flags().Lookup("cookie"))
+	viper.BindPFlag("spec.cookie_file", fetchCmd.Flags().Lookup("cookie-file"))
+
+	rootCmd.AddCommand(fetchCmd)
+}
+
+func fetchSpec(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(cmd.Context(), time.Duration(fetchTimeout)*time.Second)
+	defer cancel()
+
+	parser := openapi.NewParser(logger)
+	doc, err := parser.FetchAndParse(ctx, fetchURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch and parse OpenAPI documentation: %w", err)
+	}
+	fetched := parser.Fetched()[0]
+
+	var normalized bytes.Buffer
+	if err := json.Indent(&normalized, fetched.Preprocessed, "", "  "); err != nil {
+		return err
+	}
+	normalized.WriteByte('\n')
+	if fetchOutput == "" {
+		if _, err := os.Stdout.Write(normalized.Bytes()); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(fetchOutput, normalized.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", fetchOutput, err)
+	}
+
+	if fetchTransformations != "" {
+		var log strings.Builder
+		for _, transformation := range fetched.Transformations {
+			log.WriteString(transformation.String() + "\n")
+		}
+		if err := os.WriteFile(fetchTransformations, []byte(log.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", fetchTransformations, err)
+		}
+	}
+
+	logger.Info("Fetched OpenAPI documentation",
+		zap.String("title", doc.Info.Title),
+		zap.String("output", fetchOutput),
+		zap.String("sha256", fetched.SHA256),
+		zap.Int("transformations", len(fetched.Transformations)))
+	return nil
+}
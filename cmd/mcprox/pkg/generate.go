@@ -3,17 +3,26 @@ package pkg
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/berkantay/mcprox/internal/config"
 	"github.com/berkantay/mcprox/internal/mcp"
+	"github.com/berkantay/mcprox/internal/mcp/utils"
 	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/berkantay/mcprox/internal/telemetry"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
 )
 
 var (
-	swaggerURL string
-	timeout    int
-	outputDir  string
+	swaggerURLs []string
+	timeout     int
+	outputDir   string
+	generateAll bool
+	projectFile string
 )
 
 func init() {
@@ -23,41 +32,216 @@ func init() {
 		Long: `Fetches OpenAPI/Swagger documentation from a URL and generates a fully functional 
 Model Context Protocol (MCP) server.
 
+Pass --url several times, optionally as name=url, to aggregate multiple APIs
+into one server. Their tools are namespaced by name, which defaults to the
+API's title; per-API service URLs and credentials can be set under the sources
+key of the config file.
+
 Example:
-  godoc-mcp generate --url http://localhost:8080/swagger/doc.json`,
+  godoc-mcp generate --url http://localhost:8080/swagger/doc.json
+  godoc-mcp generate --url billing=http://billing/openapi.json --url users=http://users/openapi.json
+
+With --all, every service listed in the project file (mcprox.yaml by default)
+is regenerated instead:
+  godoc-mcp generate --all --project ./mcprox.yaml`,
 		RunE: generateMCP,
 	}
 
-	generateCmd.Flags().StringVarP(&swaggerURL, "url", "u", "", "URL to fetch OpenAPI documentation (required)")
-	generateCmd.MarkFlagRequired("url")
+	generateCmd.Flags().StringArrayVarP(&swaggerURLs, "url", "u", nil, "URL or local file to fetch OpenAPI documentation from, as url or name=url; repeat to aggregate several APIs (required unless sources are configured)")
 	generateCmd.Flags().IntVarP(&timeout, "timeout", "t", 30, "Timeout in seconds for HTTP requests")
 	generateCmd.Flags().StringVarP(&outputDir, "output", "o", "", "Output directory for generated server (default is ./generated)")
+	generateCmd.Flags().BoolVar(&generateAll, "all", false, "Generate every service listed in the project file")
+	generateCmd.Flags().StringVar(&projectFile, "project", config.ProjectFileName, "Project file listing the services generated by --all")
+	generateCmd.Flags().Bool("syntax-check", true, "Compile the generated Python code to catch syntax errors (requires a local Python interpreter)")
+	generateCmd.Flags().Bool("meta-tools", false, "Generate search_endpoints, call_endpoint and describe_operation meta tools for exploring large APIs")
+	generateCmd.Flags().Bool("transforms", false, "Translate the CEL response transforms configured under transform into the generated Python")
+	generateCmd.Flags().String("target", config.DefaultTarget, "Kind of server to generate: "+strings.Join(config.Targets, ", "))
+	generateCmd.Flags().Bool("langchain", false, "Also generate src/langchain_tools.py exposing the operation tools as LangChain StructuredTools")
+
+	generateCmd.Flags().Bool("force", false, "Overwrite an existing, non-empty project directory")
+	generateCmd.Flags().Bool("backup", false, "Move an existing, non-empty project directory aside before generating")
+	generateCmd.Flags().String("python-deps", "pyproject", "Where the generated project declares its dependencies: pyproject, requirements or both")
+	generateCmd.Flags().String("python-packaging", "setuptools", "Build system of the generated project: setuptools (installed with uv) or poetry")
+	generateCmd.Flags().String("formatter", utils.FormatterNone, "Formatter run on the generated Python code if installed: "+strings.Join(utils.Formatters, ", "))
+	generateCmd.Flags().String("python-sdk-version", "", "MCP Python SDK release the generated server targets and pins, e.g. 1.9.4 (default: the latest)")
+	generateCmd.Flags().Bool("conda", false, "Also generate an environment.yml for conda environments")
+	generateCmd.Flags().Bool("lock", false, "Resolve the generated project's dependencies to exact versions in uv.lock, poetry.lock or requirements.lock (requires uv or Poetry)")
+
+	generateCmd.Flags().String("author", "", "Author of the generated project (default: the spec's contact name)")
+	generateCmd.Flags().String("author-email", "", "Author email of the generated project (default: the spec's contact email)")
+	generateCmd.Flags().String("homepage", "", "Homepage URL of the generated project (default: the spec's contact URL)")
+	generateCmd.Flags().String("bug-tracker", "", "Bug tracker URL of the generated project")
+	generateCmd.Flags().String("license", "", "License of the generated project (default: the spec's license, or MIT)")
+
+	generateCmd.Flags().String("requires-python", "", "Python versions supported by the generated project (default: >=3.11)")
+	generateCmd.Flags().StringArray("pin", nil, "Pinned requirement replacing a default dependency, e.g. mcp==1.6.0 (repeatable)")
+	generateCmd.Flags().StringArray("extra-dependency", nil, "Additional runtime requirement of the generated project (repeatable)")
+
+	generateCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+	generateCmd.RegisterFlagCompletionFunc("output", completeDirs)
+	generateCmd.RegisterFlagCompletionFunc("project", completeFileExt("yaml", "yml"))
+	generateCmd.RegisterFlagCompletionFunc("target", completeValues(config.Targets...))
+	generateCmd.RegisterFlagCompletionFunc("python-deps", completeValues(utils.DepsPyproject, utils.DepsRequirements, utils.DepsBoth))
+	generateCmd.RegisterFlagCompletionFunc("python-packaging", completeValues(utils.PackagingSetuptools, utils.PackagingPoetry))
+	generateCmd.RegisterFlagCompletionFunc("formatter", completeValues(utils.Formatters...))
+
+	viper.BindPFlag("pyproject.requires_python", generateCmd.Flags().Lookup("requires-python"))
+	viper.BindPFlag("pyproject.pins", generateCmd.Flags().Lookup("pin"))
+	viper.BindPFlag("pyproject.extra_dependencies", generateCmd.Flags().Lookup("extra-dependency"))
+	viper.BindPFlag("pyproject.author", generateCmd.Flags().Lookup("author"))
+	viper.BindPFlag("pyproject.email", generateCmd.Flags().Lookup("author-email"))
+	viper.BindPFlag("pyproject.homepage", generateCmd.Flags().Lookup("homepage"))
+	viper.BindPFlag("pyproject.bug_tracker", generateCmd.Flags().Lookup("bug-tracker"))
+	viper.BindPFlag("pyproject.license", generateCmd.Flags().Lookup("license"))
+	viper.BindPFlag("generate.force", generateCmd.Flags().Lookup("force"))
+	viper.BindPFlag("generate.backup", generateCmd.Flags().Lookup("backup"))
+	viper.BindPFlag("generate.python_deps", generateCmd.Flags().Lookup("python-deps"))
+	viper.BindPFlag("generate.python_packaging", generateCmd.Flags().Lookup("python-packaging"))
+	viper.BindPFlag("generate.formatter", generateCmd.Flags().Lookup("formatter"))
+	viper.BindPFlag("generate.python_sdk_version", generateCmd.Flags().Lookup("python-sdk-version"))
+	viper.BindPFlag("generate.conda", generateCmd.Flags().Lookup("conda"))
+	viper.BindPFlag("generate.lock", generateCmd.Flags().Lookup("lock"))
+	viper.BindPFlag("generate.syntax_check", generateCmd.Flags().Lookup("syntax-check"))
+	viper.BindPFlag("generate.meta_tools", generateCmd.Flags().Lookup("meta-tools"))
+	viper.BindPFlag("generate.transforms", generateCmd.Flags().Lookup("transforms"))
+	viper.BindPFlag("generate.target", generateCmd.Flags().Lookup("target"))
+	viper.BindPFlag("generate.langchain", generateCmd.Flags().Lookup("langchain"))
 
 	rootCmd.AddCommand(generateCmd)
 }
 
 func generateMCP(cmd *cobra.Command, args []string) error {
+	if generateAll {
+		return generateProject(projectFile)
+	}
+
+	specs, err := resolveSpecs(swaggerURLs)
+	if err != nil {
+		return err
+	}
+
+	if err := generateServer(specs, outputDir, config.Filters{}, config.GetString("generate.target")); err != nil {
+		return err
+	}
+
+	logger.Info("MCP server generation completed successfully")
+	return nil
+}
+
+// generateProject generates every service of a project file. A failing
+// service doesn't stop the others; all failures are reported at the end.
+func generateProject(path string) error {
+	project, err := config.LoadProject(path)
+	if err != nil {
+		return err
+	}
+	if len(project.Services) == 0 {
+		return fmt.Errorf("no services listed in %s", path)
+	}
+
+	var failed []string
+	for _, service := range project.Services {
+		logger.Info("Generating service", zap.String("service", service.Name))
+
+		specs, err := resolveSpecs([]string{service.URL})
+		if err == nil {
+			err = generateServer(specs, service.Output, service.Filters, service.Target)
+		}
+		if err != nil {
+			logger.Error("Failed to generate service", zap.String("service", service.Name), zap.Error(err))
+			failed = append(failed, service.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to generate %d of %d services: %s", len(failed), len(project.Services), strings.Join(failed, ", "))
+	}
+
+	logger.Info("Generated all services", zap.Int("services", len(project.Services)))
+	return nil
+}
+
+// generateServer fetches the OpenAPI documents and generates one MCP server
+// of a target from the operations selected by filters
+func generateServer(specs []openapi.Spec, output string, filters config.Filters, target string) error {
+	if !config.ValidTarget(target) {
+		return fmt.Errorf("invalid target %q: must be one of %s", target, strings.Join(config.Targets, ", "))
+	}
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
 
-	// Create OpenAPI parser
-	parser := openapi.NewParser(logger)
-
-	// Fetch and parse OpenAPI documentation
-	doc, err := parser.FetchAndParse(ctx, swaggerURL)
+	doc, fetched, err := loadDocument(ctx, specs, filters)
 	if err != nil {
-		return fmt.Errorf("failed to fetch and parse OpenAPI documentation: %w", err)
+		return err
 	}
+	telemetry.SetTarget(target)
 
 	// Create MCP generator
-	generator := mcp.NewGenerator(logger, outputDir)
+	generator := mcp.NewGenerator(logger, output)
+	generator.SetSpecSources(fetched)
+	generator.SetTarget(target)
 
 	// Generate MCP server
 	if err := generator.Generate(ctx, doc); err != nil {
 		return fmt.Errorf("failed to generate MCP server: %w", err)
 	}
-
-	logger.Info("MCP server generation completed successfully")
 	return nil
 }
+
+// loadDocument fetches and parses the OpenAPI documents, merging them if there
+// are several APIs, and keeps the operations selected by filters. It also
+// returns the URLs and digests of the fetched documents.
+func loadDocument(ctx context.Context, specs []openapi.Spec, filters config.Filters) (*openapi3.T, []openapi.SpecSource, error) {
+	parser := openapi.NewParser(logger)
+
+	var doc *openapi3.T
+	var err error
+	if len(specs) == 1 && specs[0].Name == "" {
+		doc, err = parser.FetchAndParse(ctx, specs[0].URL)
+	} else {
+		doc, err = parser.FetchAndMerge(ctx, specs)
+	}
+	if err != nil {
+		return nil, nil, telemetry.WithClass(fmt.Errorf("failed to fetch and parse OpenAPI documentation: %w", err), telemetry.ClassSpec)
+	}
+
+	if removed := openapi.FilterOperations(doc, filters); removed > 0 {
+		logger.Info("Filtered out operations", zap.Int("removed", removed))
+	}
+	telemetry.SetSpecSize(len(openapi.Operations(doc)))
+	return doc, parser.Fetched(), nil
+}
+
+// resolveSpecs returns the OpenAPI documents to generate from: the --url flags
+// if given, the configured sources otherwise
+func resolveSpecs(urls []string) ([]openapi.Spec, error) {
+	var specs []openapi.Spec
+	for _, u := range urls {
+		// A name= prefix can't contain the characters of a URL scheme or path
+		name, specURL, found := strings.Cut(u, "=")
+		if !found || strings.ContainsAny(name, ":/?") {
+			name, specURL = "", u
+		}
+		specs = append(specs, openapi.Spec{Name: name, URL: specURL})
+	}
+	if len(specs) > 0 {
+		return specs, nil
+	}
+
+	sources, err := config.GetSources()
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if source.URL == "" {
+			return nil, fmt.Errorf("source %q has no url", source.Name)
+		}
+		specs = append(specs, openapi.Spec{Name: source.Name, URL: source.URL})
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("required flag \"url\" not set and no sources configured")
+	}
+	return specs, nil
+}
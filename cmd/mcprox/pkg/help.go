@@ -45,6 +45,30 @@ func showHelp() {
 	fmt.Println("    # Generate with custom output directory")
 	fmt.Println("    mcprox generate --url https://api.example.com/swagger --output /path/to/output")
 
+	fmt.Println("    # Regenerate every service listed in mcprox.yaml")
+	fmt.Println("    mcprox generate --all")
+
+	fmt.Println("    # Serve the API's operations as MCP tools without generating code")
+	fmt.Println("    mcprox serve --url https://api.example.com/swagger --service-url https://api.example.com")
+
+	fmt.Println("    # Try out a generated server in the MCP Inspector")
+	fmt.Println("    mcprox inspect ./generated/example_api_mcp_server")
+
+	fmt.Println("    # Set up and run a generated server in one step")
+	fmt.Println("    mcprox run ./generated/example_api_mcp_server")
+
+	fmt.Println("    # Smoke test a generated server, failing if any step fails")
+	fmt.Println("    mcprox test ./generated/example_api_mcp_server")
+
+	fmt.Println("    # Measure the overhead of serve mode's tool calls")
+	fmt.Println("    mcprox bench --url https://api.example.com/swagger --calls 10000")
+
+	fmt.Println("    # Generate man pages for every command and configuration key")
+	fmt.Println("    mcprox docs --format man --dir ./man")
+
+	fmt.Println("    # Update mcprox to the latest signed release")
+	fmt.Println("    mcprox self-update")
+
 	fmt.Println("    # Use a custom configuration file")
 	fmt.Println("    mcprox --config /path/to/config.yaml generate --url http://localhost:8080/swagger/doc.json")
 
@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/history"
+	"github.com/spf13/cobra"
+)
+
+// historyArgumentsWidth bounds the arguments shown per call in the listing
+const historyArgumentsWidth = 60
+
+var (
+	historyTool    string
+	historySession string
+	historyStatus  string
+	historySince   time.Duration
+	historyLimit   int
+	historyJSON    bool
+)
+
+func init() {
+	historyCmd := &cobra.Command{
+		Use:   "history [call-id]",
+		Short: "Show the tool calls serve mode recorded",
+		Long: `Lists the tool calls serve mode recorded in the history configured by
+history.path, the most recent first, or shows a single call with its full
+arguments and result.
+
+Example:
+  mcprox history --since 24h
+  mcprox history --tool post_orders --status error
+  mcprox history 42`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: showHistory,
+	}
+
+	historyCmd.Flags().String("db", "", "History database (default: history.path)")
+	historyCmd.Flags().StringVar(&historyTool, "tool", "", "Only show calls of this tool")
+	historyCmd.Flags().StringVar(&historySession, "session", "", "Only show calls of this MCP session")
+	historyCmd.Flags().StringVar(&historyStatus, "status", "", "Only show calls with this status: ok, tool_error or error")
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only show calls of this last period, e.g. 24h")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 50, "Maximum number of calls shown; 0 shows all")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the calls as JSON, with their results")
+
+	historyCmd.RegisterFlagCompletionFunc("status", completeValues(history.StatusOK, history.StatusToolError, history.StatusError))
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+func showHistory(cmd *cobra.Command, args []string) error {
+	store, err := openHistory(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if len(args) == 1 {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid call ID %q", args[0])
+		}
+		call, err := store.Call(cmd.Context(), id)
+		if err != nil {
+			return err
+		}
+		if historyJSON {
+			return printJSON(call)
+		}
+		printCall(call)
+		return nil
+	}
+
+	query := history.Query{Tool: historyTool, Session: historySession, Status: historyStatus, Limit: historyLimit}
+	if historySince > 0 {
+		query.Since = time.Now().Add(-historySince)
+	}
+	calls, err := store.Calls(cmd.Context(), query)
+	if err != nil {
+		return err
+	}
+	if historyJSON {
+		if calls == nil {
+			calls = []history.Call{}
+		}
+		return printJSON(calls)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tTOOL\tSTATUS\tLATENCY\tARGUMENTS")
+	for _, call := range calls {
+		arguments := call.Arguments
+		if len(arguments) > historyArgumentsWidth {
+			arguments = arguments[:historyArgumentsWidth-3] + "..."
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%dms\t%s\n", call.ID, call.Time.Local().Format(time.DateTime), call.Tool, call.Status, call.LatencyMS, arguments)
+	}
+	return w.Flush()
+}
+
+// openHistory opens the existing history given by the command's --db flag or
+// history.path
+func openHistory(cmd *cobra.Command) (*history.Store, error) {
+	path, _ := cmd.Flags().GetString("db")
+	if path == "" {
+		path = config.GetString("history.path")
+	}
+	if path == "" {
+		return nil, fmt.Errorf("no history configured: set history.path or pass --db")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	return history.Open(path)
+}
+
+// printCall prints a recorded call with its arguments and result
+func printCall(call history.Call) {
+	fmt.Printf("ID:         %d\n", call.ID)
+	fmt.Printf("Time:       %s\n", call.Time.Local().Format(time.RFC3339))
+	fmt.Printf("Tool:       %s\n", call.Tool)
+	if call.SessionID != "" {
+		fmt.Printf("Session:    %s\n", call.SessionID)
+	}
+	fmt.Printf("Status:     %s\n", call.Status)
+	fmt.Printf("Latency:    %dms\n", call.LatencyMS)
+	fmt.Printf("Arguments:  %s\n", call.Arguments)
+	if call.Error != "" {
+		fmt.Printf("Error:      %s\n", call.Error)
+	}
+	if call.Result != "" {
+		fmt.Printf("Result:\n%s\n", call.Result)
+	}
+}
+
+// printJSON prints a value as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
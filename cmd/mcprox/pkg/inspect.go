@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// inspectorPackage is the npm package of the MCP Inspector
+const inspectorPackage = "@modelcontextprotocol/inspector"
+
+var (
+	inspectURLs     []string
+	inspectTimeout  int
+	inspectPort     int
+	inspectNoLaunch bool
+)
+
+func init() {
+	inspectCmd := &cobra.Command{
+		Use:   "inspect [project-dir]",
+		Short: "Serve an MCP server over SSE and open it in the MCP Inspector",
+		Long: `Starts an MCP server over SSE, prints how to connect to it and launches the
+MCP Inspector (` + inspectorPackage + `, run with npx) to try out its tools.
+
+Given a generated project directory, the project's server is started with the
+Python interpreter of its .venv, or the one on the PATH. Otherwise the
+OpenAPI documentation given by --url, or the configured sources, is served
+directly as with mcprox serve.
+
+The server and the Inspector run until interrupted.
+
+Example:
+  mcprox inspect --url https://api.example.com/openapi.json --service-url https://api.example.com
+  mcprox inspect ./generated/pet_store_mcp_server`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeProjectDir(1),
+		RunE:              inspectMCP,
+	}
+
+	inspectCmd.Flags().StringArrayVarP(&inspectURLs, "url", "u", nil, "URL to fetch OpenAPI documentation when no project is given, as url or name=url (repeatable)")
+	inspectCmd.Flags().IntVarP(&inspectTimeout, "timeout", "t", 30, "Timeout in seconds for fetching the OpenAPI documentation")
+	inspectCmd.Flags().IntVar(&inspectPort, "port", 0, "Port the server listens on (default: a free port)")
+	inspectCmd.Flags().BoolVar(&inspectNoLaunch, "no-launch", false, "Only start the server and print the connection details")
+
+	inspectCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+
+	rootCmd.AddCommand(inspectCmd)
+}
+
+func inspectMCP(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Whichever of the server and the Inspector stops first stops the other
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var sseURL string
+	serverDone := make(chan error, 1)
+	if len(args) == 1 {
+		url, err := startProjectServer(ctx, args[0], inspectPort, serverDone)
+		if err != nil {
+			return err
+		}
+		sseURL = url
+	} else {
+		url, err := startServeMode(ctx, inspectURLs, inspectTimeout, inspectPort, serverDone)
+		if err != nil {
+			return err
+		}
+		sseURL = url
+	}
+
+	fmt.Println("MCP server ready for the MCP Inspector")
+	fmt.Println("  Transport: SSE")
+	fmt.Println("  URL:       " + sseURL)
+	fmt.Println("In the Inspector, select the SSE transport, enter the URL above and connect.")
+
+	inspectorDone := make(chan error, 1)
+	if inspectNoLaunch {
+		fmt.Printf("Start the Inspector with: npx %s\n", inspectorPackage)
+	} else if err := launchInspector(ctx, inspectorDone); err != nil {
+		logger.Warn("Failed to launch the MCP Inspector; start it yourself with npx "+inspectorPackage, zap.Error(err))
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-inspectorDone:
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("MCP Inspector exited", zap.Error(err))
+		}
+		return nil
+	case err := <-serverDone:
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			err = errors.New("server stopped")
+		}
+		return fmt.Errorf("MCP server exited: %w", err)
+	}
+}
+
+// launchInspector runs the MCP Inspector with npx, reporting its exit on done
+func launchInspector(ctx context.Context, done chan<- error) error {
+	npx, err := exec.LookPath("npx")
+	if err != nil {
+		return fmt.Errorf("npx not found; install Node.js: %w", err)
+	}
+
+	inspector := exec.CommandContext(ctx, npx, "-y", inspectorPackage)
+	inspector.Stdout = os.Stdout
+	inspector.Stderr = os.Stderr
+	if err := inspector.Start(); err != nil {
+		return err
+	}
+	go func() {
+		done <- inspector.Wait()
+	}()
+	return nil
+}
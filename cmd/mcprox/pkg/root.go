@@ -1,52 +1,88 @@
 package pkg
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/telemetry"
+	"github.com/berkantay/mcprox/internal/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 var (
-	cfgFile string
-	debug   bool
-	logger  *zap.Logger
-	rootCmd = &cobra.Command{
+	cfgFile        string
+	configChecksum string
+	debug          bool
+	logger         *zap.Logger
+	rootCmd        = &cobra.Command{
 		Use:   "mcprox",
 		Short: "Generate MCP proxy from OpenAPI documentation",
 		Long: `A robust tool that retrieves and parses OpenAPI/Swagger documentation from a URL and
 generates a fully functional Model Context Protocol (MCP) proxy using the mark3labs/mcp-go library.`,
+		Version: version.Get(),
 	}
 )
 
 // Execute executes the root command.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	reportUsage(cmd, time.Since(start), err)
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
+// reportUsage reports the invocation of a command if telemetry is enabled
+func reportUsage(cmd *cobra.Command, duration time.Duration, err error) {
+	// The logger and configuration aren't initialized for --help or --version
+	if logger == nil || cmd == nil {
+		return
+	}
+	switch cmd.Name() {
+	case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return
+	}
+	telemetry.Report(context.Background(), telemetry.NewEvent(cmd.CommandPath(), duration, err), logger)
+}
+
 func init() {
 	cobra.OnInitialize(initConfig, initLogger)
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.mcprox.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file or http(s) URL (default is $HOME/.mcprox.yaml)")
+	rootCmd.PersistentFlags().StringVar(&configChecksum, "config-checksum", "", "expected SHA-256 of a remote config file, as sha256:<hex>")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging")
 
 	// Add service configuration flags
 	rootCmd.PersistentFlags().String("service-url", "", "base URL of the target API service")
 	rootCmd.PersistentFlags().String("service-auth", "", "authorization header value for the target API")
 
+	// Add spec parsing flags
+	rootCmd.PersistentFlags().Bool("lenient", false, "skip the operations of OpenAPI documents that fail validation instead of failing")
+
+	rootCmd.RegisterFlagCompletionFunc("config", completeFileExt("yaml", "yml", "json", "toml"))
+
 	// Bind flags to viper
 	viper.BindPFlag("service.url", rootCmd.PersistentFlags().Lookup("service-url"))
 	viper.BindPFlag("service.authorization", rootCmd.PersistentFlags().Lookup("service-auth"))
+	viper.BindPFlag("spec.lenient", rootCmd.PersistentFlags().Lookup("lenient"))
 }
 
 func initConfig() {
-	config.Init(cfgFile)
+	// The config file can't come from viper itself, so its variables are read directly
+	if cfgFile == "" {
+		cfgFile = os.Getenv(config.EnvPrefix + "_CONFIG")
+	}
+	if configChecksum == "" {
+		configChecksum = os.Getenv(config.EnvPrefix + "_CONFIG_CHECKSUM")
+	}
+	config.Init(cfgFile, configChecksum)
 
 	// Override config with command line flags
 	if debug {
@@ -66,4 +102,6 @@ func initLogger() {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
+	// Code without a logger at hand, e.g. in a context, logs through this one
+	zap.ReplaceGlobals(logger)
 }
@@ -0,0 +1,156 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/generator"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	runTransport string
+	runPort      int
+	runEnv       []string
+	runNoInstall bool
+)
+
+func init() {
+	runCmd := &cobra.Command{
+		Use:   "run <project-dir> [-- server-args...]",
+		Short: "Set up and run a generated MCP server",
+		Long: `Creates the virtual environment of a generated project with uv, installs its
+dependencies as its setup script would, and runs its server. Poetry projects
+are installed with Poetry instead.
+
+The configured service URL and authorization are passed to the server as
+SERVICE_URL and SERVICE_AUTH_HEADER, overriding its .env file, along with any
+--env variables. Setup output goes to standard error, so a project run over
+stdio can be launched by MCP clients directly.
+
+Example:
+  mcprox run ./generated/pet_store_mcp_server
+  mcprox run ./generated/pet_store_mcp_server --transport sse --port 8000 \
+    --service-url https://api.example.com --env LOG_LEVEL=DEBUG`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completeProjectDir(1),
+		RunE:              runProject,
+	}
+
+	runCmd.Flags().StringVar(&runTransport, "transport", "", "MCP transport of the server: stdio, sse or streamable-http (default: the server's MCP_TRANSPORT)")
+	runCmd.Flags().IntVar(&runPort, "port", 0, "Port of the server for network transports (default: the server's PORT)")
+	runCmd.Flags().StringArrayVarP(&runEnv, "env", "e", nil, "Environment variable of the server as KEY=VALUE (repeatable)")
+	runCmd.Flags().BoolVar(&runNoInstall, "no-install", false, "Run the server without setting up its environment")
+
+	runCmd.RegisterFlagCompletionFunc("transport", completeValues("stdio", "sse", "streamable-http"))
+
+	rootCmd.AddCommand(runCmd)
+}
+
+func runProject(cmd *cobra.Command, args []string) error {
+	projectDir := args[0]
+	manifest, err := generator.ReadManifest(projectDir)
+	if err != nil {
+		return err
+	}
+	env, err := runEnvironment()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !runNoInstall {
+		if err := setupProject(ctx, projectDir, manifest); err != nil {
+			return err
+		}
+	}
+
+	python, err := projectPython(projectDir)
+	if err != nil {
+		return err
+	}
+	serverArgs := []string{filepath.Join("src", "mcp_server.py")}
+	if runTransport != "" {
+		serverArgs = append(serverArgs, "--transport", runTransport)
+	}
+	if runPort != 0 {
+		serverArgs = append(serverArgs, "--port", strconv.Itoa(runPort))
+	}
+	serverArgs = append(serverArgs, args[1:]...)
+
+	server := exec.CommandContext(ctx, python, serverArgs...)
+	server.Dir = projectDir
+	server.Env = env
+	server.Stdin = os.Stdin
+	server.Stdout = os.Stdout
+	server.Stderr = os.Stderr
+
+	logger.Info("Running generated server", zap.String("project_dir", projectDir), zap.String("python", python))
+	if err := server.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("generated server failed: %w", err)
+	}
+	return nil
+}
+
+// runEnvironment returns the environment of the generated server: mcprox's
+// own, the configured service settings and the --env variables
+func runEnvironment() ([]string, error) {
+	env := os.Environ()
+	if serviceURL := config.GetString("service.url"); serviceURL != "" {
+		env = append(env, "SERVICE_URL="+serviceURL)
+	}
+	if authorization := config.GetString("service.authorization"); authorization != "" {
+		env = append(env, "SERVICE_AUTH_HEADER="+authorization)
+	}
+	for _, variable := range runEnv {
+		if name, _, ok := strings.Cut(variable, "="); !ok || name == "" {
+			return nil, fmt.Errorf("invalid --env %q, expected KEY=VALUE", variable)
+		}
+		env = append(env, variable)
+	}
+	return env, nil
+}
+
+// setupProject creates the virtual environment of a generated project if it
+// doesn't exist yet and installs the project's dependencies into it
+func setupProject(ctx context.Context, projectDir string, manifest *generator.Manifest) error {
+	install := manifest.InstallCommand()
+	if _, err := os.Stat(filepath.Join(projectDir, ".venv")); errors.Is(err, os.ErrNotExist) && install[0] == "uv" {
+		if err := runSetupCommand(ctx, projectDir, "uv", "venv"); err != nil {
+			return err
+		}
+	}
+	return runSetupCommand(ctx, projectDir, install[0], install[1:]...)
+}
+
+// runSetupCommand runs a setup command in the project directory, sending its
+// output to standard error
+func runSetupCommand(ctx context.Context, projectDir, name string, args ...string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("setting up the project requires %s (or run it with --no-install): %w", name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = projectDir
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	logger.Info("Setting up project", zap.String("command", strings.Join(append([]string{name}, args...), " ")))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/update"
+	"github.com/berkantay/mcprox/internal/version"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	updateCheck         bool
+	updateVersion       string
+	updateForce         bool
+	updateSkipSignature bool
+)
+
+func init() {
+	selfUpdateCmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update mcprox to the latest release",
+		Long: `Looks up the latest mcprox release on GitHub, downloads the archive for this
+platform and replaces the running binary with the one it contains.
+
+The release's ` + update.ChecksumsFile + ` must carry a valid ed25519 signature by the key built
+into mcprox, and the archive must match its checksum. Builds without a key,
+e.g. from go install, refuse to update unless --insecure-skip-signature is
+given, in which case only the checksum is verified.
+
+Example:
+  mcprox self-update --check
+  mcprox self-update
+  mcprox self-update --version v1.4.2`,
+		Args: cobra.NoArgs,
+		RunE: selfUpdate,
+	}
+
+	selfUpdateCmd.Flags().BoolVar(&updateCheck, "check", false, "Only report whether a newer release is available")
+	selfUpdateCmd.Flags().StringVar(&updateVersion, "version", "", "Install this release tag instead of the latest, e.g. v1.4.2")
+	selfUpdateCmd.Flags().BoolVar(&updateForce, "force", false, "Reinstall even if the release isn't newer than this binary")
+	selfUpdateCmd.Flags().BoolVar(&updateSkipSignature, "insecure-skip-signature", false, "Don't require a signed release, relying on its checksum only")
+
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+func selfUpdate(cmd *cobra.Command, args []string) error {
+	updater, err := update.New(updateSkipSignature)
+	if errors.Is(err, update.ErrNoPublicKey) {
+		return fmt.Errorf("%w; install a release build or pass --insecure-skip-signature", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	release, err := updater.Release(ctx, updateVersion)
+	if err != nil {
+		return err
+	}
+	current := version.Get()
+	newer := update.Newer(release.TagName, current)
+	if updateCheck {
+		if newer {
+			fmt.Printf("mcprox %s is available (current: %s)\n", release.TagName, current)
+		} else {
+			fmt.Printf("mcprox %s is up to date\n", current)
+		}
+		return nil
+	}
+	if !newer && !updateForce && updateVersion == "" {
+		fmt.Printf("mcprox %s is up to date\n", current)
+		return nil
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the mcprox binary: %w", err)
+	}
+	if exePath, err = filepath.EvalSymlinks(exePath); err != nil {
+		return fmt.Errorf("failed to locate the mcprox binary: %w", err)
+	}
+
+	logger.Info("Downloading release", zap.String("version", release.TagName), zap.Bool("signature_verified", updater.PublicKey != nil))
+	binary, err := updater.Download(ctx, release)
+	if err != nil {
+		return err
+	}
+	if err := update.Replace(exePath, binary); err != nil {
+		return err
+	}
+	fmt.Printf("Updated mcprox from %s to %s\n", current, release.TagName)
+	return nil
+}
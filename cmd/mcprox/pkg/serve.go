@@ -0,0 +1,313 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/admin"
+	"github.com/berkantay/mcprox/internal/clientauth"
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/downstream"
+	"github.com/berkantay/mcprox/internal/health"
+	"github.com/berkantay/mcprox/internal/history"
+	"github.com/berkantay/mcprox/internal/mcp"
+	"github.com/berkantay/mcprox/internal/mcp/generator"
+	"github.com/berkantay/mcprox/internal/mcp/serve"
+	"github.com/berkantay/mcprox/internal/metrics"
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/berkantay/mcprox/internal/ratelimit"
+	"github.com/berkantay/mcprox/internal/telemetry"
+	"github.com/berkantay/mcprox/internal/tenant"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	serveURLs    []string
+	serveTimeout int
+)
+
+func init() {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an MCP server for OpenAPI documentation without generating code",
+		Long: `Fetches OpenAPI/Swagger documentation and serves its operations as MCP tools
+directly, calling the configured service. No Python project is generated.
+
+The stdio transport is meant to be launched by MCP clients, the sse transport
+serves any number of clients over HTTP, and the websocket transport serves
+them over WebSocket connections at /ws, with the same authentication, TLS and
+tenants.
+
+Example:
+  mcprox serve --url https://api.example.com/openapi.json --service-url https://api.example.com
+  mcprox serve --url https://api.example.com/openapi.json --transport sse --port 8080
+  mcprox serve --url https://api.example.com/openapi.json --transport websocket --port 8080
+  mcprox serve --url https://api.example.com/openapi.json --transport sse --passthrough-header X-Api-Token
+  mcprox serve --url https://api.example.com/openapi.json --transport sse --tls-cert cert.pem --tls-key key.pem
+  mcprox serve --url https://api.example.com/openapi.json --transport sse --port 443 --acme-domain mcp.example.com`,
+		RunE: serveMCP,
+	}
+
+	serveCmd.Flags().StringArrayVarP(&serveURLs, "url", "u", nil, "URL to fetch OpenAPI documentation, as url or name=url; repeat to aggregate several APIs (required unless sources are configured)")
+	serveCmd.Flags().IntVarP(&serveTimeout, "timeout", "t", 30, "Timeout in seconds for fetching the OpenAPI documentation")
+	serveCmd.Flags().String("transport", serve.TransportStdio, "MCP transport: stdio, sse or websocket")
+	serveCmd.Flags().String("host", "", "Interface the sse transport listens on (default: localhost)")
+	serveCmd.Flags().Int("port", config.DefaultPort, "Port the sse transport listens on")
+	serveCmd.Flags().String("passthrough-header", "", "Header of the MCP client forwarded as the upstream Authorization header (sse only)")
+	serveCmd.Flags().Int("drain-timeout", int(serve.DefaultDrainTimeout.Seconds()), "Seconds tool calls in flight may finish when shutting down before they're canceled")
+	serveCmd.Flags().String("tls-cert", "", "PEM certificate chain to serve the sse transport over HTTPS with")
+	serveCmd.Flags().String("tls-key", "", "PEM private key of the TLS certificate")
+	serveCmd.Flags().Bool("tls-self-signed", false, "Serve the sse transport over HTTPS with a self-signed certificate, for development")
+	serveCmd.Flags().StringArray("acme-domain", nil, "Domain to obtain a certificate for from Let's Encrypt; repeat for several domains")
+
+	serveCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+	serveCmd.RegisterFlagCompletionFunc("transport", completeValues(serve.TransportStdio, serve.TransportSSE, serve.TransportWebSocket))
+
+	viper.BindPFlag("serve.transport", serveCmd.Flags().Lookup("transport"))
+	viper.BindPFlag("server.host", serveCmd.Flags().Lookup("host"))
+	viper.BindPFlag("server.port", serveCmd.Flags().Lookup("port"))
+	viper.BindPFlag("auth.passthrough.header", serveCmd.Flags().Lookup("passthrough-header"))
+	viper.BindPFlag("serve.drain_timeout", serveCmd.Flags().Lookup("drain-timeout"))
+	viper.BindPFlag("server.tls.cert_file", serveCmd.Flags().Lookup("tls-cert"))
+	viper.BindPFlag("server.tls.key_file", serveCmd.Flags().Lookup("tls-key"))
+	viper.BindPFlag("server.tls.self_signed", serveCmd.Flags().Lookup("tls-self-signed"))
+	viper.BindPFlag("server.tls.acme.domains", serveCmd.Flags().Lookup("acme-domain"))
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func serveMCP(cmd *cobra.Command, args []string) error {
+	opts := serve.Options{
+		Transport: config.GetString("serve.transport"),
+		Host:      config.GetString("server.host"),
+		Port:      config.GetInt("server.port"),
+		// Forwarded by the auth provider built with the server
+		CredentialHeader: config.GetString("auth.passthrough.header"),
+		CORS: serve.CORS{
+			AllowedOrigins: config.GetStringSlice("server.cors.allowed_origins"),
+			AllowedHeaders: config.GetStringSlice("server.cors.allowed_headers"),
+		},
+		TLS: serve.TLS{
+			CertFile:     config.GetString("server.tls.cert_file"),
+			KeyFile:      config.GetString("server.tls.key_file"),
+			SelfSigned:   config.GetBool("server.tls.self_signed"),
+			ACMEDomains:  config.GetStringSlice("server.tls.acme.domains"),
+			ACMEEmail:    config.GetString("server.tls.acme.email"),
+			ACMECacheDir: config.GetString("server.tls.acme.cache_dir"),
+		},
+	}
+	tenants, err := tenant.FromConfig()
+	if err != nil {
+		return err
+	}
+	opts.Tenants = tenants
+	opts.Auth = clientauth.FromConfig()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	// Let the calls in flight finish when shutting down, refusing new ones
+	// first so that they aren't recorded
+	opts.Drain = &serve.Drainer{Timeout: time.Duration(config.GetInt("serve.drain_timeout")) * time.Second}
+	middlewares := []generator.ToolMiddleware{opts.Drain}
+
+	// Refuse the calls of tools disabled through the admin API, then those
+	// beyond their rate limit
+	limiter, err := ratelimit.FromConfig()
+	if err != nil {
+		return err
+	}
+	adminAPI, err := admin.FromConfig(limiter, logger)
+	if err != nil {
+		return err
+	}
+	if adminAPI != nil {
+		middlewares = append(middlewares, adminAPI)
+	}
+	middlewares = append(middlewares, limiter)
+
+	// Count the tool calls if their metrics are served
+	if config.GetBool("server.metrics.enabled") || adminAPI != nil {
+		toolMetrics := metrics.New()
+		middlewares = append(middlewares, toolMetrics)
+		if config.GetBool("server.metrics.enabled") {
+			opts.Metrics = toolMetrics
+		}
+		if adminAPI != nil {
+			adminAPI.Metrics = toolMetrics
+		}
+	}
+
+	// Record the tool calls if a history is configured
+	store, err := history.FromConfig(context.Background())
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		defer store.Close()
+		middlewares = append(middlewares, store)
+		logger.Info("Recording tool calls", zap.String("history", config.GetString("history.path")))
+	}
+
+	// Serve the tools of the downstream MCP servers alongside the API's
+	definitions, err := downstream.FromConfig()
+	if err != nil {
+		return err
+	}
+	downstreams, err := downstream.ConnectAll(context.Background(), definitions, logger)
+	if err != nil {
+		return err
+	}
+	defer downstream.CloseAll(downstreams)
+
+	api, err := buildServer(serveURLs, serveTimeout, downstreams, middlewares...)
+	if err != nil {
+		return err
+	}
+	opts.Health = health.FromConfig(api.specs, serviceURLs())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// A second signal exits right away instead of waiting for the drain
+		<-ctx.Done()
+		stop()
+	}()
+
+	if adminAPI != nil {
+		adminAPI.History = store
+		adminAPI.SetTools(api.server, api.generator.ServedTools())
+		listener, err := adminAPI.Listener()
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := adminAPI.Serve(ctx, listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("Admin API failed", zap.Error(err))
+			}
+		}()
+		logger.Info("Serving the admin API", zap.String("listen", adminAPI.Listen), zap.Bool("dashboard", adminAPI.Dashboard))
+	}
+
+	go reloadOnHangup(ctx, api, opts.Health, adminAPI)
+	return serve.Run(ctx, api.server, opts, logger)
+}
+
+// servedAPI is the MCP server serve mode runs and what it was built from
+type servedAPI struct {
+	server *server.MCPServer
+	// specs are the fetched OpenAPI documents
+	specs     []openapi.SpecSource
+	generator *mcp.Generator
+	urls      []string
+	timeout   time.Duration
+}
+
+// buildServer fetches the OpenAPI documents given by urls, or the configured
+// sources, and builds the MCP server serve mode runs, with the tools of the
+// downstream servers too, its tool calls wrapped in middlewares
+func buildServer(urls []string, timeoutSeconds int, downstreams []*downstream.Server, middlewares ...generator.ToolMiddleware) (*servedAPI, error) {
+	api := &servedAPI{generator: mcp.NewGenerator(logger), urls: urls, timeout: time.Duration(timeoutSeconds) * time.Second}
+	doc, err := api.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(serviceURLs()) == 0 {
+		logger.Warn("No service URL configured; tools return mock responses instead of calling the API")
+	}
+
+	api.generator.UseDownstream(downstreams...)
+	api.generator.UseToolMiddleware(middlewares...)
+	if api.server, err = api.generator.BuildServer(doc); err != nil {
+		return nil, fmt.Errorf("failed to build MCP server: %w", err)
+	}
+	telemetry.SetTarget("serve")
+	logger.Info("Built MCP server", zap.String("title", doc.Info.Title))
+	return api, nil
+}
+
+// load fetches the OpenAPI documents of the API
+func (a *servedAPI) load() (*openapi3.T, error) {
+	specs, err := resolveSpecs(a.urls)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	doc, fetched, err := loadDocument(ctx, specs, config.Filters{})
+	if err != nil {
+		return nil, err
+	}
+	a.specs = fetched
+	return doc, nil
+}
+
+// reload fetches the OpenAPI documents again and replaces the tools of the
+// server with theirs, notifying connected clients
+func (a *servedAPI) reload() error {
+	doc, err := a.load()
+	if err != nil {
+		return err
+	}
+	return a.generator.ReloadServer(a.server, doc)
+}
+
+// serviceURLs returns the configured service URLs tool calls are sent to, of
+// the top level, the sources and the tenants
+func serviceURLs() []string {
+	var urls []string
+	if serviceURL := config.GetString("service.url"); serviceURL != "" {
+		urls = append(urls, serviceURL)
+	}
+	sources, _ := config.GetSources()
+	for _, source := range sources {
+		if source.ServiceURL != "" {
+			urls = append(urls, source.ServiceURL)
+		}
+	}
+	tenants, _ := tenant.FromConfig()
+	for _, t := range tenants {
+		if t.ServiceURL != "" {
+			urls = append(urls, t.ServiceURL)
+		}
+	}
+	return urls
+}
+
+// reloadOnHangup reloads the served API on SIGHUP until the context is
+// canceled. A failed reload keeps the current tools.
+func reloadOnHangup(ctx context.Context, api *servedAPI, checker *health.Checker, adminAPI *admin.API) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			logger.Info("Reloading the OpenAPI documentation")
+			if err := api.reload(); err != nil {
+				logger.Error("Failed to reload; keeping the current tools", zap.Error(err))
+				continue
+			}
+			if checker != nil {
+				checker.Reloaded(api.specs)
+			}
+			// Tools disabled before stay disabled
+			if adminAPI != nil {
+				adminAPI.SetTools(api.server, api.generator.ServedTools())
+			}
+		}
+	}
+}
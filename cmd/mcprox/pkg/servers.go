@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/mcp/generator"
+	"github.com/berkantay/mcprox/internal/mcp/serve"
+	"go.uber.org/zap"
+)
+
+// serverStartTimeout bounds how long a generated server may take to start
+const serverStartTimeout = 30 * time.Second
+
+// startServeMode serves the OpenAPI documentation given by urls over SSE in
+// process, on a free port if port is 0, and returns the URL of its SSE
+// endpoint. The server's exit is reported on done.
+func startServeMode(ctx context.Context, urls []string, timeoutSeconds, port int, done chan<- error) (string, error) {
+	api, err := buildServer(urls, timeoutSeconds, nil)
+	if err != nil {
+		return "", err
+	}
+
+	ready := make(chan string, 1)
+	opts := serve.Options{
+		Transport: serve.TransportSSE,
+		Host:      "localhost",
+		Port:      port,
+		Ready:     func(sseURL string) { ready <- sseURL },
+	}
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- serve.Run(ctx, api.server, opts, logger)
+	}()
+
+	select {
+	case sseURL := <-ready:
+		go func() {
+			done <- <-stopped
+		}()
+		return sseURL, nil
+	case err := <-stopped:
+		return "", err
+	}
+}
+
+// startProjectServer starts the server of a generated project over SSE, on a
+// free port if port is 0, and returns the URL of its SSE endpoint once it
+// accepts connections. The server's exit is reported on done.
+func startProjectServer(ctx context.Context, projectDir string, port int, done chan<- error) (string, error) {
+	if _, err := generator.ReadManifest(projectDir); err != nil {
+		return "", err
+	}
+	python, err := projectPython(projectDir)
+	if err != nil {
+		return "", err
+	}
+
+	if port == 0 {
+		if port, err = freePort(); err != nil {
+			return "", err
+		}
+	}
+	opts := serve.Options{Transport: serve.TransportSSE, Host: "localhost", Port: port}
+
+	server := exec.CommandContext(ctx, python, filepath.Join("src", "mcp_server.py"),
+		"--transport", serve.TransportSSE, "--port", strconv.Itoa(port))
+	server.Dir = projectDir
+	// Keep standard output for the connection details
+	server.Stdout = os.Stderr
+	server.Stderr = os.Stderr
+	if err := server.Start(); err != nil {
+		return "", fmt.Errorf("failed to start %s: %w", projectDir, err)
+	}
+	logger.Info("Started generated server", zap.String("project_dir", projectDir), zap.String("python", python))
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- server.Wait()
+	}()
+
+	deadline := time.Now().Add(serverStartTimeout)
+	for {
+		conn, err := net.DialTimeout("tcp", opts.Addr(), time.Second)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		select {
+		case err := <-exited:
+			return "", fmt.Errorf("generated server exited before accepting connections (are its dependencies installed?): %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("generated server didn't accept connections on port %d within %s", port, serverStartTimeout)
+		}
+	}
+
+	go func() {
+		done <- <-exited
+	}()
+	return opts.SSEURL(), nil
+}
+
+// projectPython returns the Python interpreter of a generated project's
+// virtual environment, or the one on the PATH
+func projectPython(projectDir string) (string, error) {
+	venvPython := filepath.Join(projectDir, ".venv", "bin", "python")
+	if runtime.GOOS == "windows" {
+		venvPython = filepath.Join(projectDir, ".venv", "Scripts", "python.exe")
+	}
+	if _, err := os.Stat(venvPython); err == nil {
+		return filepath.Abs(venvPython)
+	}
+
+	for _, name := range []string{"python3", "python"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("no Python interpreter found; run the project's setup script first")
+}
+
+// freePort returns a TCP port that is currently free on localhost
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
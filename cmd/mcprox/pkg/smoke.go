@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/smoketest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	testURLs    []string
+	testTimeout int
+)
+
+func init() {
+	testCmd := &cobra.Command{
+		Use:   "test [project-dir]",
+		Short: "Smoke test an MCP server end to end",
+		Long: `Starts an MCP server over SSE, performs the MCP handshake, lists its tools and
+calls one of them, reporting each step as PASS or FAIL. The command fails if
+any step does, which makes it suitable as a CI gate after generation.
+
+Given a generated project directory, the project's server is tested, started
+as by mcprox inspect. Otherwise the OpenAPI documentation given by --url, or
+the configured sources, is served and tested as with mcprox serve.
+
+The tool called is given by --call, with --args as a JSON object. By default
+` + smoketest.HealthTool + ` is called if the server has it, which generated servers do.
+
+Example:
+  mcprox test ./generated/pet_store_mcp_server
+  mcprox test --url https://api.example.com/openapi.json --service-url https://api.example.com \
+    --call get_pets --args '{"limit": 1}'`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeProjectDir(1),
+		RunE:              smokeTest,
+	}
+
+	testCmd.Flags().StringArrayVarP(&testURLs, "url", "u", nil, "URL to fetch OpenAPI documentation when no project is given, as url or name=url (repeatable)")
+	testCmd.Flags().IntVarP(&testTimeout, "timeout", "t", 60, "Timeout in seconds for the whole test")
+	testCmd.Flags().String("call", "", "Tool to call, which should be free of side effects (default: "+smoketest.HealthTool+" if available)")
+	testCmd.Flags().String("args", "", "Arguments of the called tool as a JSON object")
+
+	testCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+
+	viper.BindPFlag("test.call", testCmd.Flags().Lookup("call"))
+	viper.BindPFlag("test.args", testCmd.Flags().Lookup("args"))
+
+	rootCmd.AddCommand(testCmd)
+}
+
+func smokeTest(cmd *cobra.Command, args []string) error {
+	opts := smoketest.Options{Tool: config.GetString("test.call")}
+	if raw := config.GetString("test.args"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &opts.Arguments); err != nil {
+			return fmt.Errorf("invalid --args, expected a JSON object: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(testTimeout)*time.Second)
+	defer cancel()
+
+	var sseURL string
+	var err error
+	serverDone := make(chan error, 1)
+	if len(args) == 1 {
+		sseURL, err = startProjectServer(ctx, args[0], 0, serverDone)
+	} else {
+		sseURL, err = startServeMode(ctx, testURLs, testTimeout, 0, serverDone)
+	}
+	if err != nil {
+		fmt.Printf("FAIL  start server: %v\n", err)
+		return fmt.Errorf("smoke test failed: %w", err)
+	}
+	fmt.Println("PASS  start server")
+
+	steps, err := smoketest.Run(ctx, sseURL, opts)
+	for _, step := range steps {
+		if step.Passed() {
+			fmt.Printf("PASS  %s: %s\n", step.Name, step.Detail)
+		} else {
+			fmt.Printf("FAIL  %s: %v\n", step.Name, step.Err)
+		}
+	}
+
+	// Stop the server before reporting
+	cancel()
+	<-serverDone
+
+	if err != nil {
+		return fmt.Errorf("smoke test failed: %w", err)
+	}
+	fmt.Println("Smoke test passed")
+	return nil
+}
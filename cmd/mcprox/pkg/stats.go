@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/history"
+	"github.com/berkantay/mcprox/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+// Orders of mcprox stats
+const (
+	statsSortCalls     = "calls"
+	statsSortErrors    = "errors"
+	statsSortErrorRate = "error_rate"
+	statsSortLatency   = "latency"
+)
+
+var (
+	statsURLs    []string
+	statsTimeout int
+	statsSince   time.Duration
+	statsTop     int
+	statsSort    string
+	statsJSON    bool
+)
+
+func init() {
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show which tools serve mode's clients call",
+		Long: `Summarizes the tool calls serve mode recorded in the history configured by
+history.path: calls, errors, error rate and latency of each tool, the most
+called first.
+
+Given the OpenAPI documentation served, the tools never called are listed too,
+so the operations models don't use can be pruned.
+
+Example:
+  mcprox stats --since 168h
+  mcprox stats --top 10 --sort error_rate
+  mcprox stats --url https://api.example.com/openapi.json`,
+		Args: cobra.NoArgs,
+		RunE: showStats,
+	}
+
+	statsCmd.Flags().String("db", "", "History database (default: history.path)")
+	statsCmd.Flags().StringArrayVarP(&statsURLs, "url", "u", nil, "URL of the OpenAPI documentation served, as url or name=url, to also list the tools never called; repeat for several APIs")
+	statsCmd.Flags().IntVarP(&statsTimeout, "timeout", "t", 30, "Timeout in seconds for fetching the OpenAPI documentation")
+	statsCmd.Flags().DurationVar(&statsSince, "since", 0, "Only count calls of this last period, e.g. 168h")
+	statsCmd.Flags().IntVarP(&statsTop, "top", "n", 0, "Only show this many tools; 0 shows all")
+	statsCmd.Flags().StringVar(&statsSort, "sort", statsSortCalls, "Order of the tools: calls, errors, error_rate or latency")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the statistics as JSON")
+
+	statsCmd.RegisterFlagCompletionFunc("url", completeSourceURLs)
+	statsCmd.RegisterFlagCompletionFunc("sort", completeValues(statsSortCalls, statsSortErrors, statsSortErrorRate, statsSortLatency))
+
+	rootCmd.AddCommand(statsCmd)
+}
+
+func showStats(cmd *cobra.Command, args []string) error {
+	less, ok := statsOrders[statsSort]
+	if !ok {
+		return fmt.Errorf("invalid --sort %q: must be calls, errors, error_rate or latency", statsSort)
+	}
+	store, err := openHistory(cmd)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var since time.Time
+	if statsSince > 0 {
+		since = time.Now().Add(-statsSince)
+	}
+	stats, err := store.Stats(cmd.Context(), since)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return less(stats[i], stats[j]) })
+
+	var unused []string
+	served := 0
+	if len(statsURLs) > 0 {
+		if unused, served, err = uncalledTools(cmd.Context(), stats); err != nil {
+			return err
+		}
+	}
+	if statsTop > 0 && len(stats) > statsTop {
+		stats = stats[:statsTop]
+	}
+
+	if statsJSON {
+		if stats == nil {
+			stats = []history.ToolStats{}
+		}
+		report := map[string]interface{}{"tools": stats}
+		if unused != nil {
+			report["uncalled"] = unused
+		}
+		return printJSON(report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOOL\tCALLS\tERRORS\tERROR RATE\tAVG LATENCY\tLAST CALL")
+	for _, tool := range stats {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\t%dms\t%s\n", tool.Tool, tool.Calls, tool.Errors, tool.ErrorRate*100, tool.AvgLatencyMS, tool.LastCall.Local().Format(time.DateTime))
+	}
+	for _, tool := range unused {
+		fmt.Fprintf(w, "%s\t0\t0\t-\t-\tnever\n", tool)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(statsURLs) > 0 {
+		fmt.Printf("\n%d of %d tools never called\n", len(unused), served)
+	}
+	return nil
+}
+
+// statsOrders sort the statistics of mcprox stats, the most significant first
+var statsOrders = map[string]func(a, b history.ToolStats) bool{
+	statsSortCalls:     func(a, b history.ToolStats) bool { return a.Calls > b.Calls },
+	statsSortErrors:    func(a, b history.ToolStats) bool { return a.Errors > b.Errors },
+	statsSortErrorRate: func(a, b history.ToolStats) bool { return a.ErrorRate > b.ErrorRate },
+	statsSortLatency:   func(a, b history.ToolStats) bool { return a.AvgLatencyMS > b.AvgLatencyMS },
+}
+
+// uncalledTools returns the tools served for the documentation of --url that
+// have no calls in stats, sorted by name, and how many tools are served
+func uncalledTools(ctx context.Context, stats []history.ToolStats) ([]string, int, error) {
+	specs, err := resolveSpecs(statsURLs)
+	if err != nil {
+		return nil, 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(statsTimeout)*time.Second)
+	defer cancel()
+	doc, _, err := loadDocument(ctx, specs, config.Filters{})
+	if err != nil {
+		return nil, 0, err
+	}
+	generator := mcp.NewGenerator(logger)
+	if _, err := generator.BuildServer(doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to build MCP server: %w", err)
+	}
+
+	called := make(map[string]bool, len(stats))
+	for _, tool := range stats {
+		called[tool.Tool] = true
+	}
+	tools := generator.ServedTools()
+	unused := []string{}
+	for _, tool := range tools {
+		if !called[tool.Tool.Name] {
+			unused = append(unused, tool.Tool.Name)
+		}
+	}
+	return unused, len(tools), nil
+}
@@ -0,0 +1,291 @@
+// Package admin serves the runtime administration API of serve mode, which
+// lists the served tools, disables and enables them and adjusts their rate
+// limits without a restart, e.g. to stop a model misbehaving against one
+// endpoint during an incident, and the web dashboard built on it
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/history"
+	"github.com/berkantay/mcprox/internal/ratelimit"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// UnixPrefix marks a listen address as the path of a Unix socket
+const UnixPrefix = "unix:"
+
+// shutdownTimeout bounds how long the API waits for open requests when
+// shutting down
+const shutdownTimeout = 5 * time.Second
+
+// Tool is the state of a served tool
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	// RateLimit is the calls per minute the tool is allowed, 0 if unlimited
+	RateLimit int `json:"rate_limit"`
+}
+
+// API administers the tools of an MCP server
+type API struct {
+	// Listen is a host:port, or UnixPrefix and the path of a socket
+	Listen string
+	// Token must be presented as a bearer token. It is required on TCP
+	// addresses; the permissions of a socket protect it otherwise.
+	Token string
+	// Limiter enforces the rate limits the API adjusts
+	Limiter *ratelimit.Limiter
+	// History, if set, holds the recorded calls the API reports on
+	History *history.Store
+	// Metrics, if set, serves the tool call metrics at metrics.Path
+	Metrics http.Handler
+	// Dashboard serves the web dashboard at DashboardPath
+	Dashboard bool
+	Logger    *zap.Logger
+
+	mu       sync.Mutex
+	server   *server.MCPServer
+	tools    map[string]server.ServerTool
+	disabled map[string]bool
+}
+
+// FromConfig returns the API configured under admin, nil if admin.listen
+// isn't set
+func FromConfig(limiter *ratelimit.Limiter, logger *zap.Logger) (*API, error) {
+	listen := config.GetString("admin.listen")
+	if listen == "" {
+		return nil, nil
+	}
+	a := &API{Listen: listen, Token: config.GetString("admin.token"), Limiter: limiter, Dashboard: config.GetBool("admin.dashboard"), Logger: logger}
+	if a.Token == "" && !strings.HasPrefix(listen, UnixPrefix) {
+		return nil, errors.New("admin.token must be set to serve the admin API on a TCP address")
+	}
+	return a, nil
+}
+
+// SetTools records the tools an MCP server serves, e.g. after it was built or
+// reloaded, and removes those disabled from it
+func (a *API) SetTools(s *server.MCPServer, tools []server.ServerTool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.server = s
+	a.tools = make(map[string]server.ServerTool, len(tools))
+	for _, tool := range tools {
+		a.tools[tool.Tool.Name] = tool
+	}
+	var disabled []string
+	for name := range a.disabled {
+		if _, ok := a.tools[name]; ok {
+			disabled = append(disabled, name)
+		}
+	}
+	if len(disabled) > 0 {
+		s.DeleteTools(disabled...)
+	}
+}
+
+// Tools returns the state of the served tools, sorted by name
+func (a *API) Tools() []Tool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tools := make([]Tool, 0, len(a.tools))
+	for name, tool := range a.tools {
+		tools = append(tools, a.state(name, tool))
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+func (a *API) state(name string, tool server.ServerTool) Tool {
+	return Tool{Name: name, Description: tool.Tool.Description, Enabled: !a.disabled[name], RateLimit: a.Limiter.Limit(name)}
+}
+
+// SetEnabled enables or disables a tool. Disabled tools are removed from the
+// server, which notifies connected clients, and their calls refused.
+func (a *API) SetEnabled(name string, enabled bool) (Tool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tool, ok := a.tools[name]
+	if !ok {
+		return Tool{}, fmt.Errorf("no tool %q", name)
+	}
+	if enabled == !a.disabled[name] {
+		return a.state(name, tool), nil
+	}
+
+	if a.disabled == nil {
+		a.disabled = make(map[string]bool)
+	}
+	if enabled {
+		delete(a.disabled, name)
+		a.server.AddTools(tool)
+	} else {
+		a.disabled[name] = true
+		a.server.DeleteTools(name)
+	}
+	return a.state(name, tool), nil
+}
+
+// Wrap returns a handler refusing the calls of disabled tools, which clients
+// that haven't listed the tools again may still make
+func (a *API) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		a.mu.Lock()
+		disabled := a.disabled[request.Params.Name]
+		a.mu.Unlock()
+		if disabled {
+			result := mcp.NewToolResultText(fmt.Sprintf("The tool %s has been disabled by an operator", request.Params.Name))
+			result.IsError = true
+			return result, nil
+		}
+		return next(ctx, request)
+	}
+}
+
+// Handler returns the HTTP handler of the API:
+//
+//	GET  /tools                 lists the tools
+//	POST /tools/{name}/enable   enables a tool
+//	POST /tools/{name}/disable  disables a tool
+//	GET  /rate_limits           lists the rate limits, keyed by tool or *
+//	PUT  /rate_limits/{name}    sets the calls per minute of a tool, or of
+//	                            every tool for *, as {"rate_limit": 30}
+//
+// and those of handleMonitoring
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tools", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.Tools())
+	})
+	toggle := func(enabled bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tool, err := a.SetEnabled(r.PathValue("name"), enabled)
+			if err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			a.Logger.Warn("Tool toggled through the admin API", zap.String("tool", tool.Name), zap.Bool("enabled", enabled), zap.String("remote", r.RemoteAddr))
+			writeJSON(w, http.StatusOK, tool)
+		}
+	}
+	mux.HandleFunc("POST /tools/{name}/enable", toggle(true))
+	mux.HandleFunc("POST /tools/{name}/disable", toggle(false))
+	mux.HandleFunc("GET /rate_limits", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.Limiter.Limits())
+	})
+	mux.HandleFunc("PUT /rate_limits/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		a.mu.Lock()
+		_, known := a.tools[name]
+		a.mu.Unlock()
+		if !known && name != ratelimit.AllTools {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no tool %q", name))
+			return
+		}
+
+		var body struct {
+			RateLimit *int `json:"rate_limit"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RateLimit == nil {
+			writeError(w, http.StatusBadRequest, errors.New(`expected a body like {"rate_limit": 30}`))
+			return
+		}
+		if err := a.Limiter.Set(name, *body.RateLimit); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		a.Logger.Warn("Rate limit changed through the admin API", zap.String("tool", name), zap.Int("rate_limit", *body.RateLimit), zap.String("remote", r.RemoteAddr))
+		writeJSON(w, http.StatusOK, a.Limiter.Limits())
+	})
+	a.handleMonitoring(mux)
+	return a.authenticate(mux)
+}
+
+// authenticate returns a handler refusing requests without the token. The
+// dashboard's files are public: its page asks for the token to call the API.
+func (a *API) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		public := a.Dashboard && (r.URL.Path == "/" || strings.HasPrefix(r.URL.Path, DashboardPath))
+		if a.Token != "" && !public {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+				a.Logger.Warn("Refused admin request without a valid token", zap.String("path", r.URL.Path), zap.String("remote", r.RemoteAddr))
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, errors.New("a valid admin token is required"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Serve serves the API on a listener, see Listener, until the context is
+// canceled
+func (a *API) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{Handler: a.Handler()}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return httpServer.Shutdown(shutdownCtx)
+	}
+}
+
+// Listener listens on the API's address. A socket is only accessible to the
+// user running mcprox.
+func (a *API) Listener() (net.Listener, error) {
+	path, unix := strings.CutPrefix(a.Listen, UnixPrefix)
+	if !unix {
+		listener, err := net.Listen("tcp", a.Listen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", a.Listen, err)
+		}
+		return listener, nil
+	}
+
+	// A socket left behind by a previous run would fail the listen
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/history"
+	"github.com/berkantay/mcprox/internal/metrics"
+)
+
+// DashboardPath is where the dashboard is served when enabled
+const DashboardPath = "/dashboard/"
+
+// Bounds of the calls GET /calls returns
+const (
+	defaultCalls = 50
+	maxCalls     = 500
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// errNoHistory answers the endpoints needing the recorded calls
+var errNoHistory = errors.New("no tool calls are recorded: set history.path")
+
+// handleMonitoring adds the read-only endpoints the dashboard shows:
+//
+//	GET /calls   lists the recent calls, filtered by ?tool=, ?status= and
+//	             bounded by ?limit=
+//	GET /stats   summarizes the calls of each tool since ?since=, a
+//	             duration such as 1h, 24h by default
+//	GET /config  shows the configuration, with credentials masked
+//	GET /metrics serves the tool call metrics, if counted
+func (a *API) handleMonitoring(mux *http.ServeMux) {
+	mux.HandleFunc("GET /calls", func(w http.ResponseWriter, r *http.Request) {
+		if a.History == nil {
+			writeError(w, http.StatusNotFound, errNoHistory)
+			return
+		}
+		query := history.Query{Tool: r.URL.Query().Get("tool"), Status: r.URL.Query().Get("status"), Limit: defaultCalls}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n <= 0 {
+				writeError(w, http.StatusBadRequest, errors.New("limit must be a positive number"))
+				return
+			}
+			query.Limit = min(n, maxCalls)
+		}
+		calls, err := a.History.Calls(r.Context(), query)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if calls == nil {
+			calls = []history.Call{}
+		}
+		writeJSON(w, http.StatusOK, calls)
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		if a.History == nil {
+			writeError(w, http.StatusNotFound, errNoHistory)
+			return
+		}
+		window := 24 * time.Hour
+		if since := r.URL.Query().Get("since"); since != "" {
+			d, err := time.ParseDuration(since)
+			if err != nil || d <= 0 {
+				writeError(w, http.StatusBadRequest, errors.New("since must be a duration such as 1h"))
+				return
+			}
+			window = d
+		}
+		stats, err := a.History.Stats(r.Context(), time.Now().Add(-window))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if stats == nil {
+			stats = []history.ToolStats{}
+		}
+		writeJSON(w, http.StatusOK, stats)
+	})
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, config.Settings())
+	})
+
+	if a.Metrics != nil {
+		mux.Handle("GET "+metrics.Path, a.Metrics)
+	}
+
+	if a.Dashboard {
+		files, _ := fs.Sub(dashboardFiles, "dashboard")
+		fileServer := http.StripPrefix(DashboardPath, http.FileServerFS(files))
+		mux.HandleFunc("GET "+DashboardPath, func(w http.ResponseWriter, r *http.Request) {
+			// The page only loads its own files, and can't be framed
+			w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			fileServer.ServeHTTP(w, r)
+		})
+		mux.Handle("GET /{$}", http.RedirectHandler(DashboardPath, http.StatusFound))
+	}
+}
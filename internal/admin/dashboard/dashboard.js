@@ -0,0 +1,132 @@
+// Dashboard of mcprox's admin API, refreshed every few seconds. The admin
+// token, if the API requires one, is kept for the browser session.
+"use strict";
+
+const refreshInterval = 10000;
+
+function token() {
+  return sessionStorage.getItem("mcprox-admin-token") || "";
+}
+
+async function api(method, path) {
+  const headers = {};
+  if (token()) {
+    headers.Authorization = "Bearer " + token();
+  }
+  const response = await fetch(path, { method, headers });
+  if (response.status === 401) {
+    document.getElementById("login").hidden = false;
+    throw new Error("Sign in with the admin token");
+  }
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function percent(rate) {
+  return (rate * 100).toFixed(1) + "%";
+}
+
+function renderTools(tools, stats) {
+  const byTool = new Map((stats || []).map((s) => [s.tool, s]));
+  const body = document.getElementById("tools");
+  body.replaceChildren();
+  for (const tool of tools) {
+    const row = body.insertRow();
+    if (!tool.enabled) {
+      row.className = "disabled";
+    }
+    const s = byTool.get(tool.name);
+    cell(row, tool.name, "code");
+    cell(row, tool.description);
+    cell(row, tool.rate_limit ? tool.rate_limit + "/min" : "none");
+    cell(row, s ? s.calls : 0);
+    cell(row, s ? percent(s.error_rate) : "", s && s.errors ? "error" : "");
+    cell(row, s ? s.avg_latency_ms + " ms" : "");
+    const button = document.createElement("button");
+    button.textContent = tool.enabled ? "Disable" : "Enable";
+    button.addEventListener("click", async () => {
+      const action = tool.enabled ? "disable" : "enable";
+      if (action === "disable" && !confirm("Disable " + tool.name + " for every client?")) {
+        return;
+      }
+      await run(() => api("POST", "/tools/" + encodeURIComponent(tool.name) + "/" + action));
+    });
+    row.insertCell().append(button);
+  }
+}
+
+function renderCalls(calls) {
+  const body = document.getElementById("calls");
+  body.replaceChildren();
+  for (const call of calls) {
+    const row = body.insertRow();
+    cell(row, new Date(call.time).toLocaleString());
+    cell(row, call.tool, "code");
+    cell(row, call.status, call.status);
+    cell(row, call.latency_ms + " ms");
+    cell(row, call.arguments, "code");
+    cell(row, call.error || "");
+  }
+}
+
+function renderConfig(settings) {
+  const body = document.getElementById("config");
+  body.replaceChildren();
+  for (const key of Object.keys(settings).sort()) {
+    const row = body.insertRow();
+    cell(row, key, "code");
+    cell(row, JSON.stringify(settings[key]), "code");
+  }
+}
+
+async function refresh() {
+  const [tools, config] = await Promise.all([api("GET", "/tools"), api("GET", "/config")]);
+  let stats = [];
+  let calls = [];
+  const note = document.getElementById("history-note");
+  try {
+    [stats, calls] = await Promise.all([api("GET", "/stats"), api("GET", "/calls")]);
+    note.hidden = true;
+  } catch (err) {
+    note.textContent = err.message;
+    note.hidden = false;
+  }
+  renderTools(tools, stats);
+  renderCalls(calls);
+  renderConfig(config);
+  document.getElementById("updated").textContent = "Updated " + new Date().toLocaleTimeString();
+}
+
+async function run(action) {
+  const error = document.getElementById("error");
+  try {
+    await action();
+    await refresh();
+    error.hidden = true;
+  } catch (err) {
+    error.textContent = err.message;
+    error.hidden = false;
+  }
+}
+
+document.getElementById("login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  sessionStorage.setItem("mcprox-admin-token", document.getElementById("token").value);
+  document.getElementById("login").hidden = true;
+  run(() => Promise.resolve());
+});
+
+run(() => Promise.resolve());
+setInterval(() => run(() => Promise.resolve()), refreshInterval);
@@ -0,0 +1,83 @@
+// Package auth provides the credentials serve mode sends to upstream APIs
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+)
+
+// Request describes the upstream request credentials are needed for
+type Request struct {
+	// Tool is the MCP tool making the request
+	Tool string
+	// Method and Path are the HTTP method and path of the upstream request
+	Method string
+	Path   string
+	// API is the name of the operation's API when several are aggregated
+	API string
+}
+
+// Provider supplies the Authorization header of upstream requests
+type Provider interface {
+	// Authorization returns the Authorization header value for a request,
+	// empty for none
+	Authorization(ctx context.Context, req Request) (string, error)
+}
+
+// Static is a provider sending the same Authorization header with every request
+type Static string
+
+// Authorization returns the static header
+func (s Static) Authorization(ctx context.Context, req Request) (string, error) {
+	return string(s), nil
+}
+
+// FromConfig returns the provider configured for upstream requests: a JWT
+// provider if auth.jwt.private_key_file is set, the static
+// service.authorization header otherwise, or nil if neither is configured.
+// With auth.passthrough.header set, the MCP client's credentials take
+// precedence and the configured provider is the fallback.
+func FromConfig() (Provider, error) {
+	provider, err := configuredProvider()
+	if err != nil || config.GetString("auth.passthrough.header") == "" {
+		return provider, err
+	}
+	return Passthrough{
+		Scheme:   config.GetString("auth.passthrough.scheme"),
+		Required: config.GetBool("auth.passthrough.required"),
+		Fallback: provider,
+	}, nil
+}
+
+// configuredProvider returns the provider of the configured shared credentials
+func configuredProvider() (Provider, error) {
+	keyFile := config.GetString("auth.jwt.private_key_file")
+	if keyFile == "" {
+		if authorization := config.GetString("service.authorization"); authorization != "" {
+			return Static(authorization), nil
+		}
+		return nil, nil
+	}
+
+	pemData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT signing key: %w", err)
+	}
+	key, err := ParsePrivateKey(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT signing key %s: %w", keyFile, err)
+	}
+
+	return NewJWT(key, JWTOptions{
+		Issuer:   config.GetString("auth.jwt.issuer"),
+		Audience: config.GetString("auth.jwt.audience"),
+		Subject:  config.GetString("auth.jwt.subject"),
+		KeyID:    config.GetString("auth.jwt.key_id"),
+		TTL:      time.Duration(config.GetInt("auth.jwt.ttl")) * time.Second,
+		Claims:   config.GetStringMap("auth.jwt.claims"),
+	})
+}
@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// decodeToken verifies a token's signature with pub and returns its header and claims
+func decodeToken(t *testing.T, token string, pub crypto.PublicKey) (map[string]interface{}, map[string]interface{}) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed token %q", token)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("invalid signature encoding: %v", err)
+	}
+	input := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(input)
+
+	var valid bool
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, input, signature)
+	case *ecdsa.PublicKey:
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		valid = len(signature) == 64 && ecdsa.Verify(key, digest[:], r, s)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		t.Fatalf("signature of %q does not verify", token)
+	}
+
+	decode := func(part string) map[string]interface{} {
+		data, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			t.Fatalf("invalid token segment: %v", err)
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			t.Fatalf("invalid token segment %s: %v", data, err)
+		}
+		return values
+	}
+	return decode(parts[0]), decode(parts[1])
+}
+
+func TestJWTAlgorithms(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		key       crypto.Signer
+		algorithm string
+	}{
+		{edKey, "EdDSA"},
+		{ecKey, "ES256"},
+		{rsaKey, "RS256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			provider, err := NewJWT(tt.key, JWTOptions{Issuer: "mcprox"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := provider.Mint(Request{Tool: "getPet"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, _ := decodeToken(t, token, tt.key.Public())
+			if header["alg"] != tt.algorithm {
+				t.Errorf("expected alg %s, got %v", tt.algorithm, header["alg"])
+			}
+		})
+	}
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if _, err := NewJWT(p384, JWTOptions{}); err == nil {
+		t.Error("expected P-384 keys to be rejected")
+	}
+}
+
+func TestJWTClaims(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	provider, err := NewJWT(key, JWTOptions{
+		Issuer:   "mcprox",
+		Audience: "https://api.example.com",
+		Subject:  "svc-mcp",
+		KeyID:    "key-1",
+		TTL:      time.Minute,
+		Claims: map[string]interface{}{
+			"scope":  "{{.Method}} {{.Path}}",
+			"tool":   "{{.Tool}}",
+			"tenant": 7,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	provider.now = func() time.Time { return now }
+
+	authorization, err := provider.Authorization(context.Background(), Request{Tool: "getPet", Method: "GET", Path: "/pets/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		t.Fatalf("expected a bearer token, got %q", authorization)
+	}
+	header, claims := decodeToken(t, token, pub)
+
+	if header["kid"] != "key-1" || header["typ"] != "JWT" {
+		t.Errorf("unexpected header %v", header)
+	}
+	expected := map[string]interface{}{
+		"iss":    "mcprox",
+		"aud":    "https://api.example.com",
+		"sub":    "svc-mcp",
+		"iat":    float64(now.Unix()),
+		"exp":    float64(now.Add(time.Minute).Unix()),
+		"scope":  "GET /pets/1",
+		"tool":   "getPet",
+		"tenant": float64(7),
+	}
+	for name, value := range expected {
+		if claims[name] != value {
+			t.Errorf("expected claim %s=%v, got %v", name, value, claims[name])
+		}
+	}
+	if claims["jti"] == "" {
+		t.Error("expected a jti claim")
+	}
+
+	// Every request gets a fresh token
+	other, _ := provider.Mint(Request{Tool: "getPet"})
+	if _, otherClaims := decodeToken(t, other, pub); otherClaims["jti"] == claims["jti"] {
+		t.Error("expected unique jti claims")
+	}
+}
+
+func TestJWTInvalidTemplate(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := NewJWT(key, JWTOptions{Claims: map[string]interface{}{"scope": "{{.Tool"}}); err == nil {
+		t.Error("expected an invalid template to be rejected")
+	}
+
+	provider, err := NewJWT(key, JWTOptions{Claims: map[string]interface{}{"scope": "{{.Unknown}}"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Mint(Request{}); err == nil {
+		t.Error("expected an unknown template field to fail")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	blocks := map[string]*pem.Block{
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+		"sec1":  {Type: "EC PRIVATE KEY", Bytes: sec1},
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+	}
+	for name, block := range blocks {
+		if _, err := ParsePrivateKey(pem.EncodeToMemory(block)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	if _, err := ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Error("expected non-PEM data to be rejected")
+	}
+	if _, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{1}})); err == nil {
+		t.Error("expected a public key to be rejected")
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	provider, err := FromConfig()
+	if err != nil || provider != nil {
+		t.Fatalf("expected no provider without credentials, got %v, %v", provider, err)
+	}
+
+	viper.Set("service.authorization", "Bearer abc")
+	if provider, _ := FromConfig(); provider != Static("Bearer abc") {
+		t.Errorf("expected the static service.authorization, got %v", provider)
+	}
+
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("auth.jwt.private_key_file", keyFile)
+	viper.Set("auth.jwt.audience", "billing")
+	viper.Set("auth.jwt.claims", map[string]interface{}{"api": "{{.API}}"})
+
+	provider, err = FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorization, err := provider.Authorization(context.Background(), Request{API: "billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, claims := decodeToken(t, strings.TrimPrefix(authorization, "Bearer "), pub)
+	if claims["aud"] != "billing" || claims["api"] != "billing" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	if exp, iat := claims["exp"].(float64), claims["iat"].(float64); exp-iat != DefaultJWTTTL.Seconds() {
+		t.Errorf("expected the default lifetime, got %vs", exp-iat)
+	}
+
+	viper.Set("auth.jwt.private_key_file", filepath.Join(t.TempDir(), "missing.pem"))
+	if _, err := FromConfig(); err == nil {
+		t.Error("expected a missing key file to fail")
+	}
+}
+
+func TestStatic(t *testing.T) {
+	authorization, err := Static("Bearer abc").Authorization(context.Background(), Request{})
+	if err != nil || authorization != "Bearer abc" {
+		t.Errorf("unexpected authorization %q, %v", authorization, err)
+	}
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// DefaultJWTTTL is the lifetime of minted tokens when none is configured
+const DefaultJWTTTL = 5 * time.Minute
+
+// JWTOptions configures the tokens a JWT provider mints
+type JWTOptions struct {
+	// Issuer, Audience and Subject fill the iss, aud and sub claims if set
+	Issuer   string
+	Audience string
+	Subject  string
+	// KeyID fills the kid header if set
+	KeyID string
+	// TTL is the lifetime of a token, DefaultJWTTTL if zero
+	TTL time.Duration
+	// Claims are additional claims. String values are templates executed
+	// with the Request, e.g. "{{.Tool}}" or "{{.Method}} {{.Path}}".
+	Claims map[string]interface{}
+}
+
+// JWT is a provider minting a short-lived, self-signed JWT for every
+// upstream request, sent as a bearer token
+type JWT struct {
+	key       crypto.Signer
+	algorithm string
+	opts      JWTOptions
+	templates map[string]*template.Template
+	now       func() time.Time
+}
+
+// NewJWT creates a JWT provider signing with key, which must be an RSA
+// (RS256), P-256 ECDSA (ES256) or Ed25519 (EdDSA) private key
+func NewJWT(key crypto.Signer, opts JWTOptions) (*JWT, error) {
+	algorithm, err := signingAlgorithm(key)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultJWTTTL
+	}
+
+	templates := make(map[string]*template.Template)
+	for name, value := range opts.Claims {
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of claim %s: %w", name, err)
+		}
+		templates[name] = tmpl
+	}
+
+	return &JWT{key: key, algorithm: algorithm, opts: opts, templates: templates, now: time.Now}, nil
+}
+
+// Algorithm returns the JWS algorithm tokens are signed with
+func (j *JWT) Algorithm() string {
+	return j.algorithm
+}
+
+// Authorization mints a token for the request
+func (j *JWT) Authorization(ctx context.Context, req Request) (string, error) {
+	token, err := j.Mint(req)
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
+
+// Mint returns a signed token for the request
+func (j *JWT) Mint(req Request) (string, error) {
+	header := map[string]string{"alg": j.algorithm, "typ": "JWT"}
+	if j.opts.KeyID != "" {
+		header["kid"] = j.opts.KeyID
+	}
+
+	claims := make(map[string]interface{}, len(j.opts.Claims)+6)
+	for name, value := range j.opts.Claims {
+		if tmpl, ok := j.templates[name]; ok {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, req); err != nil {
+				return "", fmt.Errorf("failed to render claim %s: %w", name, err)
+			}
+			value = buf.String()
+		}
+		claims[name] = value
+	}
+	now := j.now()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(j.opts.TTL).Unix()
+	claims["jti"] = randomID()
+	if j.opts.Issuer != "" {
+		claims["iss"] = j.opts.Issuer
+	}
+	if j.opts.Audience != "" {
+		claims["aud"] = j.opts.Audience
+	}
+	if j.opts.Subject != "" {
+		claims["sub"] = j.opts.Subject
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("invalid claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	signature, err := j.sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// sign signs the JWS signing input with the provider's key
+func (j *JWT) sign(input []byte) ([]byte, error) {
+	switch key := j.key.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, input), nil
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// JWS wants the fixed-size concatenation of r and s, not ASN.1
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	default:
+		digest := sha256.Sum256(input)
+		return j.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+}
+
+// signingAlgorithm returns the JWS algorithm of a private key
+func signingAlgorithm(key crypto.Signer) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return "", errors.New("RSA signing keys must have at least 2048 bits")
+		}
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", errors.New("ECDSA signing keys must use the P-256 curve")
+		}
+		return "ES256", nil
+	case ed25519.PrivateKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("unsupported signing key type %T", key)
+	}
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS#8, PKCS#1 RSA or SEC 1 EC private key
+func ParsePrivateKey(pemData []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// randomID returns a random token identifier for the jti claim
+func randomID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/berkantay/mcprox/internal/upstream"
+)
+
+// Refresher is implemented by providers caching credentials, which must be
+// renewed rather than reused once the upstream API rejects them
+type Refresher interface {
+	// Refresh discards the credentials of a request and returns new ones
+	Refresh(ctx context.Context, req Request) (string, error)
+}
+
+type requestKey struct{}
+
+// WithRequest returns a copy of ctx describing the upstream request it is used for
+func WithRequest(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+// RequestFromContext returns the request description stored by WithRequest
+func RequestFromContext(ctx context.Context) (Request, bool) {
+	req, ok := ctx.Value(requestKey{}).(Request)
+	return req, ok
+}
+
+// TokenManager authorizes upstream requests through a provider. When the API
+// answers 401 Unauthorized it refreshes the credentials and retries the
+// request once, provided the credentials changed and its body can be replayed.
+type TokenManager struct {
+	provider Provider
+}
+
+// NewTokenManager creates a token manager authorizing requests through provider
+func NewTokenManager(provider Provider) *TokenManager {
+	return &TokenManager{provider: provider}
+}
+
+// Wrap returns a round tripper authorizing requests sent through next. It is
+// an upstream.Middleware. Requests that already carry an Authorization
+// header, e.g. a source's own credentials, and redirects to other hosts are
+// sent unchanged.
+func (m *TokenManager) Wrap(next http.RoundTripper) http.RoundTripper {
+	return upstream.RoundTripperFunc(func(httpReq *http.Request) (*http.Response, error) {
+		if httpReq.Header.Get("Authorization") != "" || crossHostRedirect(httpReq) {
+			return next.RoundTrip(httpReq)
+		}
+
+		ctx := httpReq.Context()
+		req, _ := RequestFromContext(ctx)
+		authorization, err := m.provider.Authorization(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authorize request: %w", err)
+		}
+
+		resp, err := next.RoundTrip(authorize(httpReq, authorization))
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		if httpReq.Body != nil && httpReq.GetBody == nil {
+			return resp, nil
+		}
+
+		refreshed, err := m.refresh(ctx, req)
+		if err != nil || refreshed == authorization {
+			// Retrying with the same credentials would fail again
+			return resp, nil
+		}
+		retry := authorize(httpReq, refreshed)
+		if httpReq.GetBody != nil {
+			if retry.Body, err = httpReq.GetBody(); err != nil {
+				return resp, nil
+			}
+		}
+
+		// Drain the rejected response so its connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return next.RoundTrip(retry)
+	})
+}
+
+// refresh returns renewed credentials for a request
+func (m *TokenManager) refresh(ctx context.Context, req Request) (string, error) {
+	if refresher, ok := m.provider.(Refresher); ok {
+		return refresher.Refresh(ctx, req)
+	}
+	return m.provider.Authorization(ctx, req)
+}
+
+// crossHostRedirect reports whether a request follows a redirect to another
+// host, which must not receive the credentials
+func crossHostRedirect(req *http.Request) bool {
+	return req.Response != nil && req.Response.Request != nil && req.Response.Request.URL.Host != req.URL.Host
+}
+
+// authorize returns a copy of req carrying the Authorization header
+func authorize(req *http.Request, authorization string) *http.Request {
+	req = req.Clone(req.Context())
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return req
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// rotatingProvider hands out numbered tokens, a new one on every refresh
+type rotatingProvider struct {
+	mu        sync.Mutex
+	token     int
+	refreshes int
+	requests  []Request
+}
+
+func (p *rotatingProvider) Authorization(ctx context.Context, req Request) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+	return fmt.Sprintf("Bearer token-%d", p.token), nil
+}
+
+func (p *rotatingProvider) Refresh(ctx context.Context, req Request) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.token++
+	p.refreshes++
+	return fmt.Sprintf("Bearer token-%d", p.token), nil
+}
+
+// upstreamAccepting returns a server accepting only the given Authorization
+// header and echoing the request body, along with the headers it received
+func upstreamAccepting(t *testing.T, authorization string) (*httptest.Server, *[]string) {
+	var received []string
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("Authorization"))
+		mu.Unlock()
+		if r.Header.Get("Authorization") != authorization {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &received
+}
+
+func TestTokenManagerRefreshesOnUnauthorized(t *testing.T) {
+	srv, received := upstreamAccepting(t, "Bearer token-1")
+	provider := &rotatingProvider{}
+	client := &http.Client{Transport: NewTokenManager(provider).Wrap(http.DefaultTransport)}
+
+	ctx := WithRequest(context.Background(), Request{Tool: "createPet", Method: "POST", Path: "/pets"})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/pets", strings.NewReader(`{"name":"Rex"}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || string(body) != `{"name":"Rex"}` {
+		t.Errorf("expected the retry to succeed with the original body, got %d %q", resp.StatusCode, body)
+	}
+	if strings.Join(*received, ", ") != "Bearer token-0, Bearer token-1" {
+		t.Errorf("expected one retry with the refreshed token, got %v", *received)
+	}
+	if provider.requests[0].Tool != "createPet" {
+		t.Errorf("expected the provider to see the request, got %+v", provider.requests[0])
+	}
+}
+
+func TestTokenManagerRetriesOnce(t *testing.T) {
+	srv, received := upstreamAccepting(t, "Bearer never")
+	provider := &rotatingProvider{}
+	client := &http.Client{Transport: NewTokenManager(provider).Wrap(http.DefaultTransport)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the 401 to be returned, got %d", resp.StatusCode)
+	}
+	if len(*received) != 2 || provider.refreshes != 1 {
+		t.Errorf("expected a single retry, got %d requests and %d refreshes", len(*received), provider.refreshes)
+	}
+}
+
+func TestTokenManagerUnchangedCredentials(t *testing.T) {
+	srv, received := upstreamAccepting(t, "Bearer valid")
+	client := &http.Client{Transport: NewTokenManager(Static("Bearer stale")).Wrap(http.DefaultTransport)}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized || len(*received) != 1 {
+		t.Errorf("expected no retry with unchanged credentials, got %d after %d requests", resp.StatusCode, len(*received))
+	}
+}
+
+func TestTokenManagerKeepsExplicitAuthorization(t *testing.T) {
+	srv, received := upstreamAccepting(t, "Bearer source")
+	client := &http.Client{Transport: NewTokenManager(Static("Bearer provider")).Wrap(http.DefaultTransport)}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Authorization", "Bearer source")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || (*received)[0] != "Bearer source" {
+		t.Errorf("expected the explicit header to be kept, got %v", *received)
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoClientCredentials is returned by a passthrough provider requiring
+// client credentials for a tool call made without them
+var ErrNoClientCredentials = errors.New("the MCP client presented no credentials")
+
+type clientCredentialsKey struct{}
+
+// WithClientCredentials returns a copy of ctx carrying the credentials the
+// connecting MCP client presented, e.g. the value of one of its headers
+func WithClientCredentials(ctx context.Context, credentials string) context.Context {
+	return context.WithValue(ctx, clientCredentialsKey{}, credentials)
+}
+
+// ClientCredentials returns the credentials stored by WithClientCredentials
+func ClientCredentials(ctx context.Context) string {
+	credentials, _ := ctx.Value(clientCredentialsKey{}).(string)
+	return credentials
+}
+
+// Passthrough is a provider forwarding the MCP client's own credentials, so
+// each end user calls the API with their own permissions
+type Passthrough struct {
+	// Scheme, if set, prefixes the client's credentials, e.g. "Bearer"
+	Scheme string
+	// Required fails tool calls without client credentials instead of
+	// falling back
+	Required bool
+	// Fallback authorizes calls without client credentials, none if nil
+	Fallback Provider
+}
+
+// Authorization returns the client's credentials, or the fallback's
+func (p Passthrough) Authorization(ctx context.Context, req Request) (string, error) {
+	if credentials := ClientCredentials(ctx); credentials != "" {
+		if p.Scheme != "" {
+			return p.Scheme + " " + credentials, nil
+		}
+		return credentials, nil
+	}
+	if p.Required {
+		return "", ErrNoClientCredentials
+	}
+	if p.Fallback == nil {
+		return "", nil
+	}
+	return p.Fallback.Authorization(ctx, req)
+}
+
+// Refresh renews the fallback's credentials. Those of the client are
+// returned unchanged, as only the client can renew them.
+func (p Passthrough) Refresh(ctx context.Context, req Request) (string, error) {
+	if ClientCredentials(ctx) == "" {
+		if refresher, ok := p.Fallback.(Refresher); ok {
+			return refresher.Refresh(ctx, req)
+		}
+	}
+	return p.Authorization(ctx, req)
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPassthrough(t *testing.T) {
+	client := WithClientCredentials(context.Background(), "user-token")
+	anonymous := context.Background()
+
+	tests := []struct {
+		name     string
+		provider Passthrough
+		ctx      context.Context
+		want     string
+		wantErr  error
+	}{
+		{"client credentials", Passthrough{Fallback: Static("Bearer shared")}, client, "user-token", nil},
+		{"scheme", Passthrough{Scheme: "Bearer"}, client, "Bearer user-token", nil},
+		{"fallback", Passthrough{Scheme: "Bearer", Fallback: Static("Bearer shared")}, anonymous, "Bearer shared", nil},
+		{"no fallback", Passthrough{}, anonymous, "", nil},
+		{"required", Passthrough{Required: true, Fallback: Static("Bearer shared")}, anonymous, "", ErrNoClientCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.provider.Authorization(tt.ctx, Request{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPassthroughRefresh(t *testing.T) {
+	provider := Passthrough{Fallback: &rotatingProvider{}}
+
+	// Only the fallback's credentials can be renewed
+	if got, _ := provider.Refresh(context.Background(), Request{}); got != "Bearer token-1" {
+		t.Errorf("expected the fallback to be refreshed, got %q", got)
+	}
+	client := WithClientCredentials(context.Background(), "user-token")
+	if got, _ := provider.Refresh(client, Request{}); got != "user-token" {
+		t.Errorf("expected the client's credentials unchanged, got %q", got)
+	}
+}
+
+func TestFromConfigPassthrough(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("service.authorization", "Bearer shared")
+	viper.Set("auth.passthrough.header", "X-Api-Token")
+	viper.Set("auth.passthrough.scheme", "Token")
+
+	provider, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Passthrough{Scheme: "Token", Fallback: Static("Bearer shared")}
+	if provider != expected {
+		t.Errorf("expected %+v, got %+v", expected, provider)
+	}
+}
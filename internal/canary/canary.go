@@ -0,0 +1,69 @@
+// Package canary routes a share of tool calls to another version of the API,
+// so that API teams can validate a new backend under real agent traffic
+package canary
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+)
+
+// Router picks the tool calls sent to the canary base URL
+type Router struct {
+	// URL is the base URL of the canary
+	URL string
+	// Weight is the percentage of tool calls sent to the canary
+	Weight float64
+	// Tools are the weights of tools overriding Weight, by lower-cased name
+	Tools map[string]float64
+
+	random func() float64
+}
+
+// New returns a router sending weight percent of tool calls to url, and the
+// percentages of tools to it for the tools listed
+func New(url string, weight float64, tools map[string]float64) *Router {
+	lowered := make(map[string]float64, len(tools))
+	for tool, toolWeight := range tools {
+		lowered[strings.ToLower(tool)] = toolWeight
+	}
+	return &Router{URL: url, Weight: weight, Tools: lowered, random: rand.Float64}
+}
+
+// FromConfig returns the router configured under canary, nil if canary.url
+// is empty
+func FromConfig() (*Router, error) {
+	url := config.GetString("canary.url")
+	if url == "" {
+		return nil, nil
+	}
+	weight := config.GetFloat64("canary.weight")
+	if weight < 0 || weight > 100 {
+		return nil, fmt.Errorf("invalid canary weight %v: must be a percentage", weight)
+	}
+	tools := make(map[string]float64)
+	for tool, value := range config.GetStringMapString("canary.tools") {
+		toolWeight, err := strconv.ParseFloat(value, 64)
+		if err != nil || toolWeight < 0 || toolWeight > 100 {
+			return nil, fmt.Errorf("invalid canary weight %v of %s: must be a percentage", value, tool)
+		}
+		tools[tool] = toolWeight
+	}
+	return New(url, weight, tools), nil
+}
+
+// WeightOf returns the percentage of the calls of a tool sent to the canary
+func (r *Router) WeightOf(tool string) float64 {
+	if weight, ok := r.Tools[strings.ToLower(tool)]; ok {
+		return weight
+	}
+	return r.Weight
+}
+
+// Route reports whether a call of a tool goes to the canary
+func (r *Router) Route(tool string) bool {
+	return r.random()*100 < r.WeightOf(tool)
+}
@@ -0,0 +1,52 @@
+package canary
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestRoute(t *testing.T) {
+	r := New("https://v2.example.com", 5, map[string]float64{"Get_Pets": 50, "delete_pet": 0})
+	for _, test := range []struct {
+		tool   string
+		random float64
+		want   bool
+	}{
+		{"post_pets", 0.04, true},
+		{"post_pets", 0.05, false},
+		{"get_pets", 0.49, true},
+		{"get_pets", 0.5, false},
+		{"delete_pet", 0, false},
+	} {
+		r.random = func() float64 { return test.random }
+		if got := r.Route(test.tool); got != test.want {
+			t.Errorf("Route(%s) with %v = %v, want %v", test.tool, test.random, got, test.want)
+		}
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if r, err := FromConfig(); r != nil || err != nil {
+		t.Errorf("FromConfig() without a URL = %v, %v, want nil", r, err)
+	}
+
+	viper.Set("canary.url", "https://v2.example.com")
+	viper.Set("canary.weight", 2.5)
+	viper.Set("canary.tools", map[string]interface{}{"get_pets": 20})
+	r, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.WeightOf("get_pets") != 20 || r.WeightOf("post_pets") != 2.5 {
+		t.Errorf("weights = %v and %v, want 20 and 2.5", r.WeightOf("get_pets"), r.WeightOf("post_pets"))
+	}
+
+	viper.Set("canary.tools", map[string]interface{}{"get_pets": 120})
+	if _, err := FromConfig(); err == nil {
+		t.Error("expected a weight over 100 to be refused")
+	}
+}
@@ -0,0 +1,181 @@
+// Package clientauth authenticates the MCP clients connecting to serve mode's
+// network transports, with static API keys or bearer tokens, or with OAuth
+// access tokens validated as a resource server
+package clientauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader carries an API key, as an alternative to an Authorization:
+// Bearer header
+const APIKeyHeader = "X-API-Key"
+
+// MetadataPath is where the OAuth protected resource metadata (RFC 9728) of
+// the endpoint is served when access tokens are validated
+const MetadataPath = "/.well-known/oauth-protected-resource"
+
+// ErrNoCredentials is returned for requests presenting no credentials
+var ErrNoCredentials = errors.New("no credentials presented")
+
+// ErrInvalidCredentials is returned for requests presenting unknown credentials
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Identity is the authenticated client of a request
+type Identity struct {
+	// Subject is the sub claim of an access token, empty for API keys
+	Subject string
+	// Method is "api_key" or "oauth"
+	Method string
+}
+
+// Authenticator checks the credentials of the requests to the MCP endpoint
+type Authenticator struct {
+	// Keys are the accepted API keys and static bearer tokens
+	Keys []string
+	// Tokens validates OAuth access tokens, none if nil
+	Tokens *TokenValidator
+	// Resource is the URL of the endpoint, advertised in its metadata
+	Resource string
+	Logger   *zap.Logger
+}
+
+// FromConfig returns the authenticator configured under server.auth, nil if
+// neither server.auth.keys nor server.auth.jwks_url is set
+func FromConfig() *Authenticator {
+	keys := config.GetStringSlice("server.auth.keys")
+	jwksURL := config.GetString("server.auth.jwks_url")
+	if len(keys) == 0 && jwksURL == "" {
+		return nil
+	}
+
+	a := &Authenticator{Keys: keys}
+	if jwksURL != "" {
+		a.Tokens = &TokenValidator{
+			JWKSURL:  jwksURL,
+			Issuer:   config.GetString("server.auth.issuer"),
+			Audience: config.GetString("server.auth.audience"),
+		}
+	}
+	return a
+}
+
+// Authenticate returns the identity of the client of a request. API keys
+// are accepted in an X-API-Key header or as bearer tokens, access tokens as
+// bearer tokens. An X-API-Key that isn't an accepted key, e.g. a tenant's,
+// leaves the bearer token to authenticate the request.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	apiKey := r.Header.Get(APIKeyHeader)
+	if apiKey != "" && a.validKey(apiKey) {
+		return Identity{Method: "api_key"}, nil
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		if apiKey != "" {
+			return Identity{}, ErrInvalidCredentials
+		}
+		return Identity{}, ErrNoCredentials
+	}
+	if a.validKey(token) {
+		return Identity{Method: "api_key"}, nil
+	}
+	if a.Tokens == nil {
+		return Identity{}, ErrInvalidCredentials
+	}
+	claims, err := a.Tokens.Validate(r.Context(), token)
+	if err != nil {
+		return Identity{}, err
+	}
+	subject, _ := claims["sub"].(string)
+	return Identity{Subject: subject, Method: "oauth"}, nil
+}
+
+// validKey reports whether key is one of the accepted keys
+func (a *Authenticator) validKey(key string) bool {
+	valid := false
+	for _, accepted := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(accepted)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// Wrap returns a handler serving only authenticated requests with next,
+// whose contexts carry the client's identity. Unauthenticated requests are
+// refused with 401 and a WWW-Authenticate header pointing OAuth clients at
+// the endpoint's metadata, which is served without authentication. Access
+// tokens must be issued for Resource unless another audience is configured.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	if a.Tokens != nil && a.Tokens.Audience == "" {
+		a.Tokens.Audience = a.Resource
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Tokens != nil && r.URL.Path == MetadataPath {
+			a.serveMetadata(w)
+			return
+		}
+
+		identity, err := a.Authenticate(r)
+		if err != nil {
+			a.logger().Warn("Refused unauthenticated request",
+				zap.String("path", r.URL.Path),
+				zap.String("remote", r.RemoteAddr),
+				zap.Error(err))
+			challenge := "Bearer"
+			if a.Tokens != nil {
+				challenge += ` resource_metadata="` + a.Resource + MetadataPath + `"`
+				if !errors.Is(err, ErrNoCredentials) {
+					challenge += `, error="invalid_token"`
+				}
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// serveMetadata writes the OAuth protected resource metadata of the endpoint
+func (a *Authenticator) serveMetadata(w http.ResponseWriter) {
+	metadata := map[string]interface{}{
+		"resource":                 a.Resource,
+		"bearer_methods_supported": []string{"header"},
+	}
+	if a.Tokens.Issuer != "" {
+		metadata["authorization_servers"] = []string{a.Tokens.Issuer}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metadata)
+}
+
+func (a *Authenticator) logger() *zap.Logger {
+	if a.Logger == nil {
+		return zap.NewNop()
+	}
+	return a.Logger
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity of a client
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity stored by WithIdentity
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,214 @@
+package clientauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/auth"
+)
+
+// jwksServer serves the public keys of signers as a JWKS, by key ID
+func jwksServer(t *testing.T, signers map[string]crypto.Signer) *httptest.Server {
+	t.Helper()
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	var keys []map[string]string
+	for kid, signer := range signers {
+		switch pub := signer.Public().(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{"kty": "RSA", "kid": kid, "n": encode(pub.N.Bytes()), "e": encode(big.NewInt(int64(pub.E)).Bytes())})
+		case *ecdsa.PublicKey:
+			keys = append(keys, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": encode(pub.X.FillBytes(make([]byte, 32))), "y": encode(pub.Y.FillBytes(make([]byte, 32)))})
+		case ed25519.PublicKey:
+			keys = append(keys, map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": encode(pub)})
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func mint(t *testing.T, signer crypto.Signer, opts auth.JWTOptions) string {
+	t.Helper()
+	provider, err := auth.NewJWT(signer, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := provider.Mint(auth.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestTokenValidator(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ts := jwksServer(t, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey, "ed": edKey})
+
+	v := &TokenValidator{JWKSURL: ts.URL, Issuer: "https://issuer.example.com", Audience: "https://mcp.example.com"}
+	valid := auth.JWTOptions{Issuer: v.Issuer, Audience: v.Audience, Subject: "alice"}
+
+	for kid, signer := range map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey, "ed": edKey} {
+		opts := valid
+		opts.KeyID = kid
+		claims, err := v.Validate(context.Background(), mint(t, signer, opts))
+		if err != nil {
+			t.Errorf("%s: Validate() error = %v", kid, err)
+		} else if claims["sub"] != "alice" {
+			t.Errorf("%s: sub = %v", kid, claims["sub"])
+		}
+	}
+
+	invalid := map[string]string{
+		"unknown key":    mint(t, otherKey, auth.JWTOptions{Issuer: v.Issuer, Audience: v.Audience, KeyID: "other"}),
+		"wrong signer":   mint(t, otherKey, auth.JWTOptions{Issuer: v.Issuer, Audience: v.Audience, KeyID: "ec"}),
+		"wrong issuer":   mint(t, ecKey, auth.JWTOptions{Issuer: "https://evil.example.com", Audience: v.Audience, KeyID: "ec"}),
+		"wrong audience": mint(t, ecKey, auth.JWTOptions{Issuer: v.Issuer, Audience: "https://other.example.com", KeyID: "ec"}),
+		"malformed":      "not-a-token",
+	}
+	for name, token := range invalid {
+		if _, err := v.Validate(context.Background(), token); err == nil {
+			t.Errorf("%s: Validate() succeeded, want error", name)
+		}
+	}
+
+	// Without an audience to check, any token is refused
+	opts := valid
+	opts.KeyID = "ec"
+	token := mint(t, ecKey, opts)
+	unchecked := &TokenValidator{JWKSURL: ts.URL}
+	if _, err := unchecked.Validate(context.Background(), token); err == nil {
+		t.Error("Validate() without an audience succeeded, want error")
+	}
+
+	// Tokens are checked against the clock, with some leeway
+	v.now = func() time.Time { return time.Now().Add(auth.DefaultJWTTTL + 2*Leeway) }
+	if _, err := v.Validate(context.Background(), token); err == nil {
+		t.Error("Validate() of an expired token succeeded, want error")
+	}
+}
+
+func TestTokenValidatorSlowJWKS(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys := jwksServer(t, map[string]crypto.Signer{"ec": ecKey})
+
+	// The key set is served once, then the endpoint hangs
+	var requests atomic.Int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			<-release
+		}
+		http.Redirect(w, r, keys.URL, http.StatusFound)
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	v := &TokenValidator{JWKSURL: ts.URL, Audience: "https://mcp.example.com"}
+	token := mint(t, ecKey, auth.JWTOptions{Audience: v.Audience, KeyID: "ec"})
+	if _, err := v.Validate(context.Background(), token); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	// A token signed with an unknown key refetches the key set...
+	unknown := mint(t, otherKey, auth.JWTOptions{Audience: v.Audience, KeyID: "other"})
+	v.fetched = time.Time{}
+	go v.Validate(context.Background(), unknown)
+	for requests.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// ...which doesn't hold up the tokens signed with known keys
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Validate(context.Background(), token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Validate() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Validate() waited for the JWKS endpoint")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ts := jwksServer(t, map[string]crypto.Signer{"ec": ecKey})
+	a := &Authenticator{
+		Keys:     []string{"static-key"},
+		Tokens:   &TokenValidator{JWKSURL: ts.URL, Issuer: "https://issuer.example.com"},
+		Resource: "https://mcp.example.com",
+	}
+
+	var identity Identity
+	handler := a.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ = IdentityFromContext(r.Context())
+	}))
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/sse", nil)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, headers := range []map[string]string{
+		{APIKeyHeader: "static-key"},
+		{"Authorization": "Bearer static-key"},
+		{"Authorization": "Bearer " + mint(t, ecKey, auth.JWTOptions{Issuer: "https://issuer.example.com", Audience: "https://mcp.example.com", Subject: "alice", KeyID: "ec"})},
+		{APIKeyHeader: "tenant-key", "Authorization": "Bearer static-key"},
+	} {
+		if w := serve(headers); w.Code != http.StatusOK {
+			t.Errorf("%v: status %d, want 200", headers, w.Code)
+		}
+	}
+	if identity.Method != "api_key" {
+		t.Errorf("identity = %+v, want an API key", identity)
+	}
+
+	w := serve(nil)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), `resource_metadata="https://mcp.example.com`+MetadataPath+`"`) {
+		t.Errorf("without credentials: status %d, WWW-Authenticate %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if w := serve(map[string]string{"Authorization": "Bearer wrong"}); w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), "invalid_token") {
+		t.Errorf("with invalid credentials: status %d, WWW-Authenticate %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+
+	// Without a configured audience, tokens must be issued for the endpoint
+	other := mint(t, ecKey, auth.JWTOptions{Issuer: "https://issuer.example.com", Audience: "https://other.example.com", Subject: "alice", KeyID: "ec"})
+	if w := serve(map[string]string{"Authorization": "Bearer " + other}); w.Code != http.StatusUnauthorized {
+		t.Errorf("with a token for another audience: status %d, want 401", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, MetadataPath, nil)
+	metadata := httptest.NewRecorder()
+	handler.ServeHTTP(metadata, r)
+	var body map[string]interface{}
+	if err := json.Unmarshal(metadata.Body.Bytes(), &body); err != nil || body["resource"] != "https://mcp.example.com" {
+		t.Errorf("metadata = %s", metadata.Body.String())
+	}
+}
@@ -0,0 +1,315 @@
+package clientauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Leeway tolerates clock skew between mcprox and the authorization server
+// when checking the exp and nbf claims
+const Leeway = time.Minute
+
+// jwksRefreshInterval bounds how often the key set is fetched again for a
+// token signed with an unknown key, so that forged kids can't flood the
+// authorization server
+const jwksRefreshInterval = time.Minute
+
+// TokenValidator validates OAuth access tokens issued as JWTs, signed with
+// RS256, ES256 or EdDSA by a key of the authorization server's JWKS
+type TokenValidator struct {
+	// JWKSURL is where the authorization server publishes its keys
+	JWKSURL string
+	// Issuer, if set, must match the iss claim
+	Issuer string
+	// Audience must be in the aud claim, so that tokens issued for other
+	// services are refused. Tokens are refused if it isn't set.
+	Audience string
+	// Client fetches the key set, http.DefaultClient if nil
+	Client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	// fetching is closed once the key set being fetched, if any, is in
+	fetching chan struct{}
+	now      func() time.Time
+}
+
+// Validate checks the signature and claims of a token and returns its claims
+func (v *TokenValidator) Validate(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed access token")
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed access token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed access token signature")
+	}
+
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed access token claims: %w", err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkClaims checks the expiry, issuer and audience of a token
+func (v *TokenValidator) checkClaims(claims map[string]interface{}) error {
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("access token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(Leeway)) {
+		return errors.New("access token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("access token not valid yet")
+	}
+	if v.Issuer != "" && claims["iss"] != v.Issuer {
+		return fmt.Errorf("access token issued by %v, want %s", claims["iss"], v.Issuer)
+	}
+	if v.Audience == "" {
+		return errors.New("no audience configured to check access tokens against")
+	}
+	if !hasAudience(claims["aud"], v.Audience) {
+		return fmt.Errorf("access token not issued for %s", v.Audience)
+	}
+	return nil
+}
+
+// hasAudience reports whether an aud claim, a string or an array of
+// strings, contains audience
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the key with an ID, or the only key of the set for tokens
+// without one, fetching the key set if it doesn't have it yet. The set is
+// fetched without holding the lock, so that a slow JWKS endpoint doesn't hold
+// up the tokens signed with known keys; concurrent lookups of unknown keys
+// wait for the same fetch.
+func (v *TokenValidator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	if key, ok := v.lookup(kid); ok {
+		v.mu.Unlock()
+		return key, nil
+	}
+	done := v.fetching
+	if done == nil {
+		if !v.fetched.IsZero() && time.Since(v.fetched) < jwksRefreshInterval {
+			v.mu.Unlock()
+			return nil, fmt.Errorf("access token signed with unknown key %q", kid)
+		}
+		done = make(chan struct{})
+		v.fetching = done
+		v.mu.Unlock()
+
+		keys, err := fetchKeys(ctx, v.client(), v.JWKSURL)
+		v.mu.Lock()
+		v.fetched = time.Now()
+		v.fetching = nil
+		if err == nil {
+			v.keys = keys
+		}
+		close(done)
+		v.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		v.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("access token signed with unknown key %q", kid)
+}
+
+// lookup returns a key of the current set
+func (v *TokenValidator) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *TokenValidator) client() *http.Client {
+	if v.Client == nil {
+		return http.DefaultClient
+	}
+	return v.Client
+}
+
+// jwk is a JSON Web Key of the RSA, EC P-256 or OKP Ed25519 type
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// fetchKeys fetches a JWKS and returns its signing keys by ID. Keys of
+// unsupported types are skipped.
+func fetchKeys(ctx context.Context, client *http.Client, url string) (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey returns the public key a JWK describes
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.KeyType)
+	}
+}
+
+// verify checks a JWS signature made with algorithm, which must match the
+// type of key so that a token can't pick a weaker verification
+func verify(algorithm string, key crypto.PublicKey, input, signature []byte) error {
+	digest := sha256.Sum256(input)
+	valid := false
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		valid = algorithm == "RS256" && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		valid = algorithm == "ES256" && len(signature) == 64 &&
+			ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:]))
+	case ed25519.PublicKey:
+		valid = algorithm == "EdDSA" && ed25519.Verify(key, input, signature)
+	}
+	if !valid {
+		return errors.New("invalid access token signature")
+	}
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -3,7 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -15,8 +15,30 @@ const (
 	DefaultTimeout = 30
 )
 
-// Init initializes the configuration
-func Init(cfgFile string) {
+// EnvPrefix prefixes the environment variables overriding configuration keys
+const EnvPrefix = "MCPROX"
+
+// Init initializes the configuration. cfgFile may be an http(s) URL, in which
+// case the file is downloaded and, if checksum is set, verified against it.
+func Init(cfgFile, checksum string) {
+	if isRemote(cfgFile) {
+		if checksum == "" && strings.HasPrefix(cfgFile, "http://") {
+			fmt.Fprintln(os.Stderr, "Warning: loading config over plain HTTP without --config-checksum")
+		}
+		SetDefaults()
+		BindEnv()
+		if err := readRemoteConfig(cfgFile, checksum); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "Using config file:", cfgFile)
+		return
+	}
+	if checksum != "" {
+		fmt.Fprintln(os.Stderr, "--config-checksum requires a remote --config URL")
+		os.Exit(1)
+	}
+
 	// Use config file from the flag if provided
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
@@ -38,7 +60,7 @@ func Init(cfgFile string) {
 	SetDefaults()
 
 	// Environment variables override config file
-	viper.AutomaticEnv()
+	BindEnv()
 
 	// Read in config file
 	if err := viper.ReadInConfig(); err == nil {
@@ -46,14 +68,21 @@ func Init(cfgFile string) {
 	}
 }
 
+// BindEnv makes every configuration key settable from an environment variable
+// named after it, e.g. MCPROX_SERVICE_URL for service.url
+func BindEnv() {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	viper.AutomaticEnv()
+}
+
 // SetDefaults sets the default configuration values
 func SetDefaults() {
-	viper.SetDefault("server.port", DefaultPort)
-	viper.SetDefault("client.timeout", DefaultTimeout)
-	viper.SetDefault("debug", false)
-	viper.SetDefault("output.dir", filepath.Join(".", "generated"))
-	viper.SetDefault("service.url", "")
-	viper.SetDefault("service.authorization", "")
+	for _, key := range Keys {
+		if key.Default != nil {
+			viper.SetDefault(key.Name, key.Default)
+		}
+	}
 }
 
 // GetString retrieves a string configuration value
@@ -66,21 +95,93 @@ func GetInt(key string) int {
 	return viper.GetInt(key)
 }
 
+// GetFloat64 retrieves a floating-point configuration value
+func GetFloat64(key string) float64 {
+	return viper.GetFloat64(key)
+}
+
 // GetBool retrieves a boolean configuration value
 func GetBool(key string) bool {
 	return viper.GetBool(key)
 }
 
+// GetStringSlice retrieves a list configuration value
+func GetStringSlice(key string) []string {
+	return viper.GetStringSlice(key)
+}
+
 // GetStringMap retrieves a map configuration value
 func GetStringMap(key string) map[string]interface{} {
 	return viper.GetStringMap(key)
 }
 
+// GetStringMapString retrieves a map of strings configuration value
+func GetStringMapString(key string) map[string]string {
+	return viper.GetStringMapString(key)
+}
+
 // SetBool sets a boolean configuration value
 func SetBool(key string, value bool) {
 	viper.Set(key, value)
 }
 
+// Source describes one API aggregated into a single generated server
+type Source struct {
+	// Name namespaces the API's tools; derived from its title if empty
+	Name string `mapstructure:"name"`
+	// URL is where the API's OpenAPI document is fetched from
+	URL string `mapstructure:"url"`
+	// ServiceURL is the API's base URL, overriding service.url
+	ServiceURL string `mapstructure:"service_url"`
+	// Authorization is the API's Authorization header, overriding service.authorization
+	Authorization string `mapstructure:"authorization"`
+}
+
+// GetSources retrieves the APIs listed under the sources key
+func GetSources() ([]Source, error) {
+	var sources []Source
+	if err := viper.UnmarshalKey("sources", &sources); err != nil {
+		return nil, fmt.Errorf("invalid sources configuration: %w", err)
+	}
+	return sources, nil
+}
+
+// GetSource retrieves the configured source with the given name, or whose
+// name has the given namespace
+func GetSource(name string) (Source, bool) {
+	if name == "" {
+		return Source{}, false
+	}
+	sources, err := GetSources()
+	if err != nil {
+		return Source{}, false
+	}
+	for _, source := range sources {
+		if Namespace(source.Name) == Namespace(name) {
+			return source, true
+		}
+	}
+	return Source{}, false
+}
+
+// Namespace turns an API name or title into the lower-case identifier its
+// operations are namespaced under when several APIs are aggregated, e.g.
+// billing_api for "Billing API"
+func Namespace(name string) string {
+	var sb strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			underscore = false
+		} else if !underscore && sb.Len() > 0 {
+			sb.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "_")
+}
+
 // GetDuration gets a duration value from the configuration
 func GetDuration(key string) time.Duration {
 	return viper.GetDuration(key)
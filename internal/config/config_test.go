@@ -0,0 +1,98 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestBindEnv(t *testing.T) {
+	viper.Reset()
+	SetDefaults()
+	BindEnv()
+
+	t.Setenv("MCPROX_SERVICE_URL", "https://api.internal")
+	t.Setenv("MCPROX_GENERATE_META_TOOLS", "true")
+	t.Setenv("SERVICE_URL", "https://unprefixed.internal")
+
+	if got := GetString("service.url"); got != "https://api.internal" {
+		t.Errorf("Expected service.url from MCPROX_SERVICE_URL, got '%s'", got)
+	}
+	if !GetBool("generate.meta_tools") {
+		t.Error("Expected generate.meta_tools from MCPROX_GENERATE_META_TOOLS")
+	}
+	if got := GetInt("client.timeout"); got != DefaultTimeout {
+		t.Errorf("Expected default client.timeout %d, got %d", DefaultTimeout, got)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, key := range Keys {
+		if seen[key.Name] {
+			t.Errorf("Key %s is listed twice", key.Name)
+		}
+		seen[key.Name] = true
+		if key.Description == "" {
+			t.Errorf("Key %s has no description", key.Name)
+		}
+	}
+
+	key := Key{Name: "generate.meta_tools"}
+	if got := key.EnvVar(); got != "MCPROX_GENERATE_META_TOOLS" {
+		t.Errorf("Expected MCPROX_GENERATE_META_TOOLS, got %s", got)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	SetDefaults()
+	viper.Set("service.url", "https://api.internal")
+	viper.Set("service.authorization", "Bearer secret")
+	viper.Set("tenants", []map[string]interface{}{{"name": "acme", "api_key": "acme-key"}})
+	viper.Set("downstream", []map[string]interface{}{{"name": "github", "command": "github-mcp", "env": map[string]string{"GITHUB_TOKEN": "ghp-secret"}}})
+	viper.Set("request", map[string]interface{}{"get_pets": map[string]interface{}{"inject": map[string]string{"header.X-Api-Key": "api-secret"}}})
+	viper.Set("webhook.url", "https://hooks.example.com/T000/secret")
+
+	settings := Settings()
+	if got := settings["service.url"]; got != "https://api.internal" {
+		t.Errorf("service.url = %v, want https://api.internal", got)
+	}
+	for _, key := range []string{"service.authorization", "tenants", "downstream", "request", "webhook.url"} {
+		if got := settings[key]; got != Masked {
+			t.Errorf("%s = %v, want it masked", key, got)
+		}
+	}
+	if got := settings["admin.token"]; got != "" {
+		t.Errorf("admin.token = %v, want an unset secret left empty", got)
+	}
+}
+
+func TestGetSource(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("sources", []map[string]interface{}{{
+		"name":          "Billing API",
+		"url":           "https://billing.internal/openapi.json",
+		"service_url":   "https://billing.internal",
+		"authorization": "Bearer billing",
+	}})
+
+	if got := Namespace("Billing API"); got != "billing_api" {
+		t.Errorf("Namespace(Billing API) = %q, want billing_api", got)
+	}
+	for _, name := range []string{"Billing API", "billing_api", "BILLING-API"} {
+		source, ok := GetSource(name)
+		if !ok {
+			t.Errorf("GetSource(%q) found no source", name)
+			continue
+		}
+		if source.ServiceURL != "https://billing.internal" || source.Authorization != "Bearer billing" {
+			t.Errorf("GetSource(%q) = %+v, want the Billing API source", name, source)
+		}
+	}
+	if _, ok := GetSource("payments"); ok {
+		t.Error("GetSource(payments) found a source")
+	}
+}
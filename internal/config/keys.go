@@ -0,0 +1,173 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Key describes a configuration key
+type Key struct {
+	Name string
+	// Default is the key's default value; nil if it has none
+	Default     interface{}
+	Description string
+	// Secret keys hold credentials, which Settings masks
+	Secret bool
+}
+
+// Masked replaces the values of secret keys in Settings
+const Masked = "********"
+
+// EnvVar returns the environment variable overriding the key
+func (k Key) EnvVar() string {
+	return EnvPrefix + "_" + strings.NewReplacer(".", "_", "-", "_").Replace(strings.ToUpper(k.Name))
+}
+
+// Keys lists every configuration key mcprox reads. The defaults are set from
+// this list and the CLI reference generated by mcprox docs is rendered from
+// it, so any new key must be registered here.
+var Keys = []Key{
+	{Name: "debug", Default: false, Description: "Enable debug logging"},
+	{Name: "telemetry", Default: false, Description: "Report anonymous usage events (command, spec size bucket, target, error class) to telemetry_endpoint"},
+	{Name: "telemetry_endpoint", Default: "", Description: "URL telemetry events are posted to as JSON"},
+	{Name: "client.timeout", Default: DefaultTimeout, Description: "Timeout in seconds for HTTP requests"},
+	{Name: "client.compression", Default: true, Description: "Ask upstream APIs and spec servers for gzip or deflate responses and decompress them"},
+	{Name: "client.compress_requests", Default: 0, Description: "Size in bytes above which serve mode gzips upstream request bodies, none if 0"},
+	{Name: "client.http2", Default: "auto", Description: "How serve mode speaks HTTP/2 to upstream APIs: auto negotiates it over TLS, prior_knowledge always speaks it, cleartext (h2c) to http URLs, and off never does"},
+	{Name: "spec.lenient", Default: false, Description: "Skip the operations of OpenAPI documents that fail validation, with a warning each, instead of refusing the whole document"},
+	{Name: "spec.headers", Default: []string{}, Description: "Headers sent when fetching OpenAPI documents, as Name: value, e.g. the Authorization of a protected spec endpoint", Secret: true},
+	{Name: "spec.cookies", Default: []string{}, Description: "Cookies sent when fetching OpenAPI documents, as name=value, e.g. the session cookie of a spec endpoint behind SSO", Secret: true},
+	{Name: "spec.cookie_file", Default: "", Description: "Cookie file in the Netscape cookies.txt format whose cookies are sent when fetching OpenAPI documents"},
+	{Name: "spec.lazy_threshold", Default: 0, Description: "Size in bytes above which OpenAPI documents are loaded and validated one operation at a time, each with only the components it references, to bound the memory huge documents take; 0 loads them as a whole"},
+	{Name: "spec.max_redirects", Default: 10, Description: "Redirects followed when fetching OpenAPI documents; 0 refuses them"},
+	{Name: "output.dir", Default: filepath.Join(".", "generated"), Description: "Output directory for generated servers"},
+	{Name: "service.url", Default: "", Description: "Base URL of the API service"},
+	{Name: "service.authorization", Default: "", Description: "Authorization header sent to the API service", Secret: true},
+	{Name: "service.resolve", Default: []string{}, Description: "Addresses to connect to instead of those of API hosts, as host:port=address:port, e.g. for pre-production clusters not in DNS yet"},
+	{Name: "service.fallback_url", Default: "", Description: "Base URL serve mode sends the requests to service.url to once it fails service.failover.threshold times in a row"},
+	{Name: "service.failover.threshold", Default: 3, Description: "How many unreachable or 5xx responses of service.url in a row fail over to service.fallback_url"},
+	{Name: "service.failover.cooldown", Default: 30, Description: "Time in seconds requests go to service.fallback_url before service.url is tried again"},
+	{Name: "canary.url", Default: "", Description: "Base URL of a new version of the API serve mode sends a share of the tool calls to service.url to"},
+	{Name: "canary.weight", Default: 0, Description: "Percentage of tool calls sent to canary.url"},
+	{Name: "canary.tools", Description: "Percentages of the calls of tools sent to canary.url, keyed by tool name, overriding canary.weight"},
+	{Name: "auth.jwt.private_key_file", Default: "", Description: "PEM private key (RSA, P-256 ECDSA or Ed25519) serve mode signs a short-lived JWT for every upstream request with, overriding service.authorization"},
+	{Name: "auth.jwt.issuer", Default: "", Description: "iss claim of minted JWTs"},
+	{Name: "auth.jwt.audience", Default: "", Description: "aud claim of minted JWTs"},
+	{Name: "auth.jwt.subject", Default: "", Description: "sub claim of minted JWTs"},
+	{Name: "auth.jwt.key_id", Default: "", Description: "kid header of minted JWTs"},
+	{Name: "auth.jwt.ttl", Default: 300, Description: "Lifetime in seconds of minted JWTs"},
+	{Name: "auth.jwt.claims", Description: "Additional claims of minted JWTs; string values are templates over .Tool, .Method, .Path and .API"},
+	{Name: "auth.passthrough.header", Default: "", Description: "Header of the MCP client's requests over sse forwarded as the upstream Authorization header, so each user acts with their own permissions"},
+	{Name: "auth.passthrough.scheme", Default: "", Description: "Scheme prefixed to forwarded client credentials, e.g. Bearer"},
+	{Name: "auth.passthrough.required", Default: false, Description: "Fail tool calls whose client presented no credentials instead of using the shared ones"},
+	{Name: "proxy.allowed_hosts", Default: []string{}, Description: "Hosts serve mode may call besides those of the service URLs, as host or host:port; *.example.com matches subdomains and * any host"},
+	{Name: "proxy.max_redirects", Default: 5, Description: "Redirects serve mode's upstream requests follow; 0 returns redirect responses as they are"},
+	{Name: "proxy.allow_private_redirects", Default: false, Description: "Let upstream redirects reach private, loopback and link-local addresses"},
+	{Name: "response.redact", Default: []string{}, Description: "JSON fields masked in API responses before they reach the model, by name at any depth or by dotted path such as customer.email"},
+	{Name: "response.fields", Default: false, Description: "Add a fields argument to serve mode's GET tools naming the JSON fields of the response to return"},
+	{Name: "offload.threshold", Default: 0, Description: "Size in bytes above which serve mode stores a response as an MCP resource and returns its URI with a summary instead (default: never)"},
+	{Name: "offload.dir", Default: "", Description: "Directory the offloaded responses are written to (default: kept in memory)"},
+	{Name: "offload.ttl", Default: 3600, Description: "Time in seconds an offloaded response can be read"},
+	{Name: "download.dir", Default: "", Description: "Directory serve mode saves the files tools download to, e.g. images or PDFs, returning their path and metadata instead of the content"},
+	{Name: "download.resources", Default: false, Description: "Store the files tools download as MCP resources for offload.ttl, returning their URI and metadata, unless download.dir is set"},
+	{Name: "upload.roots", Default: []string{}, Description: "Directories serve mode's upload tools can send local files from, given in a file_path argument (default: no file uploads)"},
+	{Name: "upload.max_size", Default: 104857600, Description: "Size in bytes of the largest file upload tools send, any if 0"},
+	{Name: "pii.action", Default: "", Description: "What serve mode does with tool results containing personal data: scrub it or block the result (default: no scanning)"},
+	{Name: "pii.entities", Default: []string{}, Description: "Built-in entity types scanned for: EMAIL, PHONE, SSN, CREDIT_CARD, IP_ADDRESS and IBAN (default: all)"},
+	{Name: "pii.patterns", Description: "Additional entity types scanned for, as name: regular expression"},
+	{Name: "webhook.url", Default: "", Description: "URL serve mode posts an event to after each tool call: tool, SHA-256 of the arguments, status and latency", Secret: true},
+	{Name: "webhook.secret", Default: "", Description: "Key of the HMAC-SHA256 signature of webhook events, sent in the X-Mcprox-Signature header", Secret: true},
+	{Name: "webhook.timeout", Default: 5, Description: "Timeout in seconds for delivering a webhook event"},
+	{Name: "history.path", Default: "", Description: "SQLite database serve mode records every tool call and its result in, queried with mcprox history (default: no history)"},
+	{Name: "history.retention", Default: 30, Description: "Days recorded tool calls are kept; 0 keeps them forever"},
+	{Name: "idempotency.enabled", Default: false, Description: "Send an idempotency key with the upstream requests of POST tools and answer retried calls with the result of the first"},
+	{Name: "idempotency.header", Default: "Idempotency-Key", Description: "Header carrying the idempotency key"},
+	{Name: "idempotency.window", Default: 600, Description: "Seconds the result of a POST tool call answers its retries"},
+	{Name: "batch.enabled", Default: false, Description: "Add a batch_execute tool to serve mode making several calls of the other tools at once"},
+	{Name: "batch.max_calls", Default: 20, Description: "Calls a batch may make"},
+	{Name: "batch.concurrency", Default: 4, Description: "Calls of a parallel batch made at a time"},
+	{Name: "rate_limits", Description: "Calls per minute serve mode allows each tool across clients, keyed by tool name; * sets the limit of the others"},
+	{Name: "admin.listen", Default: "", Description: "Address serve mode's admin API listens on, as host:port or unix:<socket path>; empty disables it"},
+	{Name: "admin.token", Default: "", Description: "Bearer token the admin API requires, mandatory on TCP addresses", Secret: true},
+	{Name: "admin.dashboard", Default: false, Description: "Serve a web dashboard of the tools, recent calls, error rates and configuration at /dashboard of the admin API"},
+	{Name: "transform", Description: "CEL expressions reshaping the JSON responses of tools, keyed by tool name, with the response bound to response"},
+	{Name: "request", Description: "Request rules of tools, keyed by tool name: parameters and body fields renamed, injected with constants or dropped", Secret: true},
+	{Name: "tools.max_name_length", Default: 64, Description: "Length above which tool names derived from operations are shortened, ending in a hash of the method and path; 0 for no limit"},
+	{Name: "pagination.normalize", Default: false, Description: "Present the page, limit, offset and cursor query parameters of every tool under the same argument names and descriptions, whatever the API calls them"},
+	{Name: "downstream", Description: "MCP servers whose tools serve mode also serves as <name>_<tool>, each with a name and either a command, with args and env, or the url of its SSE endpoint", Secret: true},
+	{Name: "scripts", Description: "Starlark scripts defining or overriding tools of serve mode, each with a tool, file, description and arguments"},
+	{Name: "sources", Description: "APIs aggregated into a single server, each with a name, url, service_url and authorization", Secret: true},
+	{Name: "tenants", Description: "Tenants of serve mode's sse transport, each with a name (its path prefix), an api_key its clients present, and its own service_url and authorization", Secret: true},
+	{Name: "server.host", Default: "localhost", Description: "Interface serve mode's sse transport listens on"},
+	{Name: "server.port", Default: DefaultPort, Description: "Port serve mode's sse transport listens on"},
+	{Name: "server.auth.keys", Default: []string{}, Description: "API keys or static bearer tokens MCP clients must present to serve mode's sse transport, in an X-API-Key or Authorization: Bearer header", Secret: true},
+	{Name: "server.auth.jwks_url", Default: "", Description: "JWKS of the authorization server whose OAuth access tokens MCP clients may present instead"},
+	{Name: "server.auth.issuer", Default: "", Description: "Required iss claim of access tokens, advertised as the endpoint's authorization server"},
+	{Name: "server.auth.audience", Default: "", Description: "Required aud claim of access tokens (default: the server's URL)"},
+	{Name: "server.cors.allowed_origins", Default: []string{}, Description: "Web origins besides the server's own that browser MCP clients may connect from, e.g. https://app.example.com, or * for any"},
+	{Name: "server.cors.allowed_headers", Default: []string{}, Description: "Request headers allowed origins may send (default: Authorization, Content-Type, X-API-Key, Mcp-Session-Id and Last-Event-ID)"},
+	{Name: "server.tls.cert_file", Default: "", Description: "PEM certificate chain the sse transport serves HTTPS with"},
+	{Name: "server.tls.key_file", Default: "", Description: "PEM private key of the TLS certificate"},
+	{Name: "server.tls.self_signed", Default: false, Description: "Serve HTTPS with a certificate generated at startup, for development"},
+	{Name: "server.tls.acme.domains", Default: []string{}, Description: "Domains to obtain certificates for from Let's Encrypt with the TLS-ALPN-01 challenge"},
+	{Name: "server.tls.acme.email", Default: "", Description: "Contact email of the ACME account"},
+	{Name: "server.tls.acme.cache_dir", Default: "", Description: "Directory keeping the ACME account and certificates (default: mcprox/acme in the user cache directory)"},
+	{Name: "server.health.enabled", Default: true, Description: "Serve /healthz, /readyz and /version alongside the sse transport"},
+	{Name: "server.health.timeout", Default: 5, Description: "Timeout in seconds for the readiness checks"},
+	{Name: "server.health.max_spec_age", Default: 0, Description: "Seconds after which /readyz fails if the served OpenAPI documents couldn't be confirmed to match their sources; 0 doesn't check"},
+	{Name: "server.metrics.enabled", Default: false, Description: "Serve the tool call metrics in the Prometheus text format at /metrics of the sse transport, to authenticated clients"},
+	{Name: "serve.transport", Default: "stdio", Description: "MCP transport of serve mode: stdio, sse or websocket"},
+	{Name: "serve.drain_timeout", Default: 20, Description: "Seconds tool calls in flight may finish when serve mode shuts down before they're canceled"},
+	{Name: "test.call", Default: "", Description: "Tool called by mcprox test (default: check_api_health if available)"},
+	{Name: "test.args", Default: "", Description: "Arguments of the tool called by mcprox test, as a JSON object"},
+	{Name: "generate.syntax_check", Default: true, Description: "Compile the generated Python code and fail on syntax errors"},
+	{Name: "generate.meta_tools", Default: false, Description: "Generate meta tools for discovering, invoking and inspecting operations"},
+	{Name: "generate.transforms", Default: false, Description: "Translate the CEL expressions under transform into Python and apply them in the generated server"},
+	{Name: "generate.target", Default: DefaultTarget, Description: "Kind of server generate writes: python, a project, node-single, one executable .mjs file for Node.js or Bun, cloudflare-workers, a Workers project, or aws-lambda, a Lambda function behind API Gateway"},
+	{Name: "generate.langchain", Default: false, Description: "Also generate src/langchain_tools.py exposing the operation tools as LangChain StructuredTools"},
+	{Name: "generate.force", Default: false, Description: "Overwrite an existing, non-empty project directory"},
+	{Name: "generate.backup", Default: false, Description: "Move an existing, non-empty project directory aside before generating"},
+	{Name: "generate.python_deps", Default: "pyproject", Description: "Where the generated project declares its dependencies: pyproject, requirements or both"},
+	{Name: "generate.python_packaging", Default: "setuptools", Description: "Build system of the generated project: setuptools or poetry"},
+	{Name: "generate.formatter", Default: "none", Description: "Formatter run on the generated Python code if installed: none, auto (ruff, else black), black or ruff"},
+	{Name: "generate.python_sdk_version", Default: "", Description: "MCP Python SDK release the generated server targets and pins, e.g. 1.9.4; empty for the latest"},
+	{Name: "generate.conda", Default: false, Description: "Also write an environment.yml for conda"},
+	{Name: "generate.lock", Default: false, Description: "Resolve the generated project's dependencies into lock files"},
+	{Name: "pyproject.author", Default: "", Description: "Author of the generated project"},
+	{Name: "pyproject.email", Default: "", Description: "Email of the generated project's author"},
+	{Name: "pyproject.homepage", Default: "", Description: "Homepage of the generated project"},
+	{Name: "pyproject.bug_tracker", Default: "", Description: "Bug tracker of the generated project"},
+	{Name: "pyproject.license", Default: "", Description: "License of the generated project"},
+	{Name: "pyproject.requires_python", Default: "", Description: "Python versions the generated project supports (default: >=3.11)"},
+	{Name: "pyproject.pins", Default: []string{}, Description: "Pinned requirements replacing dependencies of the generated project"},
+	{Name: "pyproject.extra_dependencies", Default: []string{}, Description: "Additional requirements of the generated project"},
+}
+
+// Settings returns the effective value of every key, with those of secret
+// keys masked if set
+func Settings() map[string]interface{} {
+	settings := make(map[string]interface{}, len(Keys))
+	for _, key := range Keys {
+		value := viper.Get(key.Name)
+		if key.Secret && !isEmpty(value) {
+			value = Masked
+		}
+		settings[key.Name] = value
+	}
+	return settings
+}
+
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
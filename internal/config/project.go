@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ProjectFileName is the default project file read by generate --all
+const ProjectFileName = "mcprox.yaml"
+
+// Targets, the kinds of servers mcprox generates
+const (
+	// TargetPython is a Python project built on the MCP Python SDK
+	TargetPython = "python"
+	// TargetNodeSingle is a single executable ES module for Node.js or Bun
+	TargetNodeSingle = "node-single"
+	// TargetCloudflareWorkers is a Cloudflare Workers project serving MCP
+	// over streamable HTTP
+	TargetCloudflareWorkers = "cloudflare-workers"
+	// TargetAWSLambda is a Node.js function serving MCP over streamable HTTP
+	// behind API Gateway
+	TargetAWSLambda = "aws-lambda"
+)
+
+// DefaultTarget is the kind of server mcprox generates unless told otherwise
+const DefaultTarget = TargetPython
+
+// Targets lists the kinds of servers mcprox generates
+var Targets = []string{TargetPython, TargetNodeSingle, TargetCloudflareWorkers, TargetAWSLambda}
+
+// ValidTarget reports whether mcprox generates a kind of server
+func ValidTarget(target string) bool {
+	for _, t := range Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Project lists the servers generated together by generate --all
+type Project struct {
+	Services []ProjectService `mapstructure:"services"`
+}
+
+// ProjectService describes one server of a project
+type ProjectService struct {
+	// Name identifies the service in logs and errors
+	Name string `mapstructure:"name"`
+	// URL is where the service's OpenAPI document is fetched from
+	URL string `mapstructure:"url"`
+	// Target is the kind of server to generate
+	Target string `mapstructure:"target"`
+	// Output is the directory the server is generated into
+	Output string `mapstructure:"output"`
+	// Filters select the operations that become tools
+	Filters Filters `mapstructure:"filters"`
+}
+
+// Filters select operations by tag and by path glob. An operation is kept if it
+// matches any include pattern (or there are none) and no exclude pattern.
+type Filters struct {
+	IncludeTags  []string `mapstructure:"include_tags"`
+	ExcludeTags  []string `mapstructure:"exclude_tags"`
+	IncludePaths []string `mapstructure:"include_paths"`
+	ExcludePaths []string `mapstructure:"exclude_paths"`
+}
+
+// LoadProject reads a project file
+func LoadProject(path string) (*Project, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+
+	var project Project
+	if err := v.Unmarshal(&project); err != nil {
+		return nil, fmt.Errorf("invalid project file %s: %w", path, err)
+	}
+
+	for i, service := range project.Services {
+		if service.Name == "" {
+			return nil, fmt.Errorf("service %d in %s has no name", i+1, path)
+		}
+		if service.URL == "" {
+			return nil, fmt.Errorf("service %q in %s has no url", service.Name, path)
+		}
+		if service.Target == "" {
+			project.Services[i].Target = DefaultTarget
+		} else if !ValidTarget(service.Target) {
+			return nil, fmt.Errorf("service %q in %s has unsupported target %q (supported: %s)", service.Name, path, service.Target, strings.Join(Targets, ", "))
+		}
+	}
+
+	return &project, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// remoteConfigTimeout bounds the download of a remote config file
+const remoteConfigTimeout = 30 * time.Second
+
+// maxRemoteConfigSize bounds the size of a remote config file
+const maxRemoteConfigSize = 1 << 20
+
+// isRemote reports whether a config file is given as an http(s) URL
+func isRemote(cfgFile string) bool {
+	return strings.HasPrefix(cfgFile, "https://") || strings.HasPrefix(cfgFile, "http://")
+}
+
+// readRemoteConfig downloads a config file and loads it into viper. The format
+// is taken from the URL's extension and defaults to YAML. A non-empty checksum
+// must be the file's SHA-256 digest in hex, optionally prefixed with "sha256:".
+func readRemoteConfig(cfgURL, checksum string) error {
+	u, err := url.Parse(cfgURL)
+	if err != nil {
+		return fmt.Errorf("invalid config URL: %w", err)
+	}
+
+	client := &http.Client{Timeout: remoteConfigTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to fetch config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch config: received non-OK response: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(data) > maxRemoteConfigSize {
+		return fmt.Errorf("config at %s exceeds %d bytes", cfgURL, maxRemoteConfigSize)
+	}
+
+	if err := verifyChecksum(data, checksum); err != nil {
+		return fmt.Errorf("config at %s: %w", cfgURL, err)
+	}
+
+	configType := strings.TrimPrefix(path.Ext(u.Path), ".")
+	if configType == "" || configType == "yml" {
+		configType = "yaml"
+	}
+	viper.SetConfigType(configType)
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to parse config at %s: %w", cfgURL, err)
+	}
+	return nil
+}
+
+// verifyChecksum compares the SHA-256 digest of data with the expected one
+func verifyChecksum(data []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+
+	expected := strings.ToLower(strings.TrimPrefix(checksum, "sha256:"))
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch: expected sha256:%s, got sha256:%s", expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestReadRemoteConfig(t *testing.T) {
+	const body = "service:\n  url: https://api.internal\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		checksum string
+		wantErr  bool
+	}{
+		{"no checksum", "", false},
+		{"matching checksum", "sha256:e9841096ee2a83a7a25a8258a1b0686b6bcc8fcbaac89e4b54ede94878f2644e", false},
+		{"mismatching checksum", "sha256:0000000000000000000000000000000000000000000000000000000000000000", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			err := readRemoteConfig(server.URL+"/prod.yaml", tt.checksum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && viper.GetString("service.url") != "https://api.internal" {
+				t.Errorf("Expected service.url from remote config, got '%s'", viper.GetString("service.url"))
+			}
+		})
+	}
+}
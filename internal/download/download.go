@@ -0,0 +1,164 @@
+// Package download keeps the files tools download, e.g. images or PDFs, out of
+// their results: a file is saved to a directory or stored as an MCP resource,
+// and the tool returns where it is with its metadata instead of binary content
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/offload"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Downloader saves the files of tool responses
+type Downloader struct {
+	// Dir is the directory files are saved to; if empty, they are stored in
+	// Store as resources
+	Dir string
+	// Store keeps the files stored as resources
+	Store *offload.Store
+}
+
+// File describes a downloaded file, at either Path or URI
+type File struct {
+	Path        string `json:"path,omitempty"`
+	URI         string `json:"uri,omitempty"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// FromConfig returns the downloader configured under download, nil if files
+// are returned inline. Files are stored as resources in store unless
+// download.dir is set.
+func FromConfig(store *offload.Store) (*Downloader, error) {
+	dir := config.GetString("download.dir")
+	if dir == "" && !config.GetBool("download.resources") {
+		return nil, nil
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("invalid download directory: %w", err)
+		}
+	}
+	return &Downloader{Dir: dir, Store: store}, nil
+}
+
+// Resources reports whether files are stored as resources
+func (d *Downloader) Resources() bool {
+	return d.Dir == ""
+}
+
+// IsFile reports whether a response is a file rather than data for the
+// model: an attachment, or content that isn't text, JSON or XML
+func IsFile(header http.Header) bool {
+	if disposition, _, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && disposition == "attachment" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return false
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-ndjson", "application/yaml",
+		"application/javascript", "application/x-www-form-urlencoded":
+		return false
+	}
+	return true
+}
+
+// Save saves the file a tool downloaded, named after the response's
+// Content-Disposition or else the tool
+func (d *Downloader) Save(tool string, header http.Header, body []byte) (File, error) {
+	sum := sha256.Sum256(body)
+	file := File{
+		Filename:    Filename(tool, header),
+		ContentType: header.Get("Content-Type"),
+		Size:        len(body),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+
+	var err error
+	if d.Resources() {
+		file.URI, err = d.Store.Put(file.ContentType, body)
+	} else {
+		file.Path, err = d.write(file.Filename, body)
+	}
+	return file, err
+}
+
+// Result saves the file a tool downloaded and returns the tool result
+// describing it
+func (d *Downloader) Result(tool string, header http.Header, body []byte) (*mcp.CallToolResult, error) {
+	file, err := d.Save(tool, header, body)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// write writes a file to the directory under its name, numbered rather than
+// overwriting another file, and returns its absolute path
+func (d *Downloader) write(filename string, body []byte) (string, error) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for i := 0; ; i++ {
+		name := filename
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		path, err := filepath.Abs(filepath.Join(d.Dir, name))
+		if err != nil {
+			return "", err
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to save file: %w", err)
+		}
+		if _, err := f.Write(body); err != nil {
+			f.Close()
+			return "", fmt.Errorf("failed to save file: %w", err)
+		}
+		return path, f.Close()
+	}
+}
+
+// Filename returns the name of a downloaded file: the base name of the
+// response's Content-Disposition filename, or else the tool's name with the
+// extension of the content type
+func Filename(tool string, header http.Header) string {
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		if name := filepath.Base(filepath.Clean("/" + params["filename"])); name != "/" && name != "." {
+			return name
+		}
+	}
+	ext := ".bin"
+	if mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return tool + ext
+}
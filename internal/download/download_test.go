@@ -0,0 +1,86 @@
+package download
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/offload"
+)
+
+func TestIsFile(t *testing.T) {
+	for _, test := range []struct {
+		contentType, disposition string
+		want                     bool
+	}{
+		{"application/pdf", "", true},
+		{"image/png", "", true},
+		{"application/octet-stream", "", true},
+		{"text/csv", `attachment; filename="pets.csv"`, true},
+		{"application/json; charset=utf-8", "", false},
+		{"application/problem+json", "", false},
+		{"text/plain", "inline", false},
+		{"", "", false},
+	} {
+		header := http.Header{"Content-Type": {test.contentType}, "Content-Disposition": {test.disposition}}
+		if got := IsFile(header); got != test.want {
+			t.Errorf("IsFile(%q, %q) = %v, want %v", test.contentType, test.disposition, got, test.want)
+		}
+	}
+}
+
+func TestFilename(t *testing.T) {
+	for _, test := range []struct {
+		header http.Header
+		want   string
+	}{
+		{http.Header{"Content-Disposition": {`attachment; filename="invoice.pdf"`}}, "invoice.pdf"},
+		{http.Header{"Content-Disposition": {`attachment; filename="../../etc/passwd"`}}, "passwd"},
+		{http.Header{"Content-Type": {"application/octet-stream"}}, "get_report.bin"},
+	} {
+		if got := Filename("get_report", test.header); got != test.want {
+			t.Errorf("Filename(%v) = %s, want %s", test.header, got, test.want)
+		}
+	}
+}
+
+func TestSaveToDirectory(t *testing.T) {
+	d := &Downloader{Dir: t.TempDir()}
+	header := http.Header{"Content-Type": {"application/pdf"}, "Content-Disposition": {`attachment; filename="invoice.pdf"`}}
+
+	first, err := d.Save("get_invoice", header, []byte("%PDF-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := d.Save("get_invoice", header, []byte("%PDF-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(first.Path) != "invoice.pdf" || filepath.Base(second.Path) != "invoice-1.pdf" {
+		t.Errorf("paths = %s and %s, want the second numbered", first.Path, second.Path)
+	}
+	if data, _ := os.ReadFile(first.Path); string(data) != "%PDF-1" {
+		t.Errorf("first file = %q, want it kept", data)
+	}
+	if first.Size != 6 || len(first.SHA256) != 64 || first.ContentType != "application/pdf" {
+		t.Errorf("metadata = %+v", first)
+	}
+}
+
+func TestSaveAsResource(t *testing.T) {
+	store := offload.New(0, "", time.Minute)
+	d := &Downloader{Store: store}
+	file, err := d.Save("get_photo", http.Header{"Content-Type": {"image/png"}}, []byte{0x89, 'P', 'N', 'G'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(file.URI, offload.URIPrefix) || file.Path != "" {
+		t.Errorf("file = %+v, want a resource URI", file)
+	}
+	if data, mimeType, err := store.Get(file.URI); err != nil || len(data) != 4 || mimeType != "image/png" {
+		t.Errorf("resource = %v, %s, %v", data, mimeType, err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package downstream attaches existing MCP servers to serve mode, launched by
+// a command over stdio or reached at an SSE URL, so that their tools are
+// served alongside the generated ones, each under the namespace of its server
+package downstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/berkantay/mcprox/internal/version"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// handshakeTimeout bounds the MCP handshake with a downstream server
+const handshakeTimeout = 30 * time.Second
+
+// namespace matches the names of downstream servers, which prefix their tools
+var namespace = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Definition configures a downstream MCP server, launched by Command with
+// Args and Env, or reached at the SSE endpoint URL
+type Definition struct {
+	// Name is the namespace of the server's tools, served as <name>_<tool>
+	Name    string   `mapstructure:"name"`
+	Command string   `mapstructure:"command"`
+	Args    []string `mapstructure:"args"`
+	// Env are KEY=VALUE variables added to the environment of Command
+	Env []string `mapstructure:"env"`
+	URL string   `mapstructure:"url"`
+}
+
+// Server is a connected downstream MCP server
+type Server struct {
+	Name   string
+	client client.MCPClient
+	// tools are the server's tools, under their own names
+	tools  []mcp.Tool
+	logger *zap.Logger
+}
+
+// FromConfig returns the downstream servers configured under downstream
+func FromConfig() ([]Definition, error) {
+	var definitions []Definition
+	if err := viper.UnmarshalKey("downstream", &definitions); err != nil {
+		return nil, fmt.Errorf("invalid downstream configuration: %w", err)
+	}
+	names := make(map[string]bool, len(definitions))
+	for i, definition := range definitions {
+		if !namespace.MatchString(definition.Name) {
+			return nil, fmt.Errorf("invalid downstream server %d: a name of letters, digits, underscores and dashes is required", i+1)
+		}
+		if (definition.Command == "") == (definition.URL == "") {
+			return nil, fmt.Errorf("invalid downstream server %s: either a command or a url is required", definition.Name)
+		}
+		if names[definition.Name] {
+			return nil, fmt.Errorf("invalid downstream server %s: the name is already taken", definition.Name)
+		}
+		names[definition.Name] = true
+	}
+	return definitions, nil
+}
+
+// Connect starts or connects to a downstream server, performs the MCP
+// handshake and lists its tools. The connection to an SSE server lasts as
+// long as ctx.
+func Connect(ctx context.Context, definition Definition, logger *zap.Logger) (*Server, error) {
+	var c client.MCPClient
+	if definition.Command != "" {
+		stdio, err := client.NewStdioMCPClient(definition.Command, definition.Env, definition.Args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to launch downstream server %s: %w", definition.Name, err)
+		}
+		c = stdio
+	} else {
+		sse, err := client.NewSSEMCPClient(definition.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL of downstream server %s: %w", definition.Name, err)
+		}
+		if err := sse.Start(ctx); err != nil {
+			sse.Close()
+			return nil, fmt.Errorf("failed to connect to downstream server %s: %w", definition.Name, err)
+		}
+		c = sse
+	}
+
+	s := &Server{Name: definition.Name, client: c, logger: logging.Component(logger, "downstream")}
+	handshakeCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	if err := s.initialize(handshakeCtx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("downstream server %s: %w", definition.Name, err)
+	}
+	s.logger.Info("Connected to downstream MCP server", zap.String("name", s.Name), zap.Int("tools", len(s.tools)))
+	return s, nil
+}
+
+// ConnectAll connects to every downstream server, closing those connected
+// if one fails
+func ConnectAll(ctx context.Context, definitions []Definition, logger *zap.Logger) ([]*Server, error) {
+	servers := make([]*Server, 0, len(definitions))
+	for _, definition := range definitions {
+		s, err := Connect(ctx, definition, logger)
+		if err != nil {
+			CloseAll(servers)
+			return nil, err
+		}
+		servers = append(servers, s)
+	}
+	return servers, nil
+}
+
+// CloseAll closes the connections to downstream servers
+func CloseAll(servers []*Server) error {
+	var errs []error
+	for _, s := range servers {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// initialize performs the MCP handshake and lists the server's tools
+func (s *Server) initialize(ctx context.Context) error {
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = mcp.Implementation{Name: "mcprox", Version: version.Get()}
+	if _, err := s.client.Initialize(ctx, request); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	tools, err := s.client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return fmt.Errorf("listing tools failed: %w", err)
+	}
+	s.tools = tools.Tools
+	return nil
+}
+
+// ToolName returns the name a tool of the server is served under
+func (s *Server) ToolName(tool string) string {
+	return s.Name + "_" + tool
+}
+
+// Tools returns the tools of the server under its namespace, their calls
+// forwarded to it
+func (s *Server) Tools() []server.ServerTool {
+	tools := make([]server.ServerTool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		namespaced := tool
+		namespaced.Name = s.ToolName(tool.Name)
+		tools = append(tools, server.ServerTool{Tool: namespaced, Handler: s.forward(tool.Name)})
+	}
+	return tools
+}
+
+// forward returns the handler calling a tool of the server
+func (s *Server) forward(tool string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		forwarded := request
+		forwarded.Params.Name = tool
+		result, err := s.client.CallTool(ctx, forwarded)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Downstream tool call failed", zap.String("downstream", s.Name), zap.Error(err))
+			return nil, fmt.Errorf("downstream server %s failed: %w", s.Name, err)
+		}
+		return result, nil
+	}
+}
+
+// Close closes the connection to the server, stopping its command
+func (s *Server) Close() error {
+	if err := s.client.Close(); err != nil {
+		return fmt.Errorf("failed to close downstream server %s: %w", s.Name, err)
+	}
+	return nil
+}
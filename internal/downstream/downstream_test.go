@@ -0,0 +1,82 @@
+package downstream
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if definitions, err := FromConfig(); err != nil || len(definitions) != 0 {
+		t.Errorf("FromConfig() without downstream servers = %v, %v", definitions, err)
+	}
+
+	viper.Set("downstream", []map[string]interface{}{
+		{"name": "files", "command": "mcp-server-filesystem", "args": []string{"/tmp"}},
+		{"name": "search", "url": "http://localhost:9000/sse"},
+	})
+	definitions, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) != 2 || definitions[0].Args[0] != "/tmp" || definitions[1].URL != "http://localhost:9000/sse" {
+		t.Errorf("definitions = %+v", definitions)
+	}
+
+	for _, invalid := range [][]map[string]interface{}{
+		{{"command": "mcp-server-filesystem"}},
+		{{"name": "files.v2", "command": "mcp-server-filesystem"}},
+		{{"name": "files"}},
+		{{"name": "files", "command": "mcp-server-filesystem", "url": "http://localhost:9000/sse"}},
+		{{"name": "files", "command": "a"}, {"name": "files", "command": "b"}},
+	} {
+		viper.Set("downstream", invalid)
+		if _, err := FromConfig(); err == nil {
+			t.Errorf("expected %v to be refused", invalid)
+		}
+	}
+}
+
+func TestConnect(t *testing.T) {
+	s := server.NewMCPServer("Search", "1.0.0")
+	s.AddTool(mcp.NewTool("query", mcp.WithString("q", mcp.Required())), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("results for " + request.Params.Arguments["q"].(string)), nil
+	})
+	ts := server.NewTestServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	servers, err := ConnectAll(ctx, []Definition{{Name: "search", URL: ts.URL + "/sse"}}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer CloseAll(servers)
+
+	tools := servers[0].Tools()
+	if len(tools) != 1 || tools[0].Tool.Name != "search_query" || tools[0].Tool.InputSchema.Required[0] != "q" {
+		t.Fatalf("tools = %+v, want search_query", tools)
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "search_query"
+	request.Params.Arguments = map[string]interface{}{"q": "mcp"}
+	result, err := tools[0].Handler(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; text != "results for mcp" {
+		t.Errorf("result = %q", text)
+	}
+
+	if _, err := Connect(ctx, Definition{Name: "missing", Command: "/nonexistent/mcp-server"}, zap.NewNop()); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Connect() of a missing command = %v, want an error naming the server", err)
+	}
+}
@@ -0,0 +1,29 @@
+package export
+
+// AnthropicTool is a client tool of the Anthropic Messages API
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// AnthropicTools returns the tools as the tools parameter of the Anthropic
+// Messages API, failing on a name the API refuses
+func AnthropicTools(tools []Tool) ([]AnthropicTool, error) {
+	described := make([]AnthropicTool, 0, len(tools))
+	for _, tool := range tools {
+		if err := checkName(tool.Name, "an Anthropic tool"); err != nil {
+			return nil, err
+		}
+		input, err := InputSchema(tool.Tool)
+		if err != nil {
+			return nil, err
+		}
+		described = append(described, AnthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: input,
+		})
+	}
+	return described, nil
+}
@@ -0,0 +1,90 @@
+// Package export describes the tools mcprox serves, after filters, renames,
+// scripts and other overrides, in formats other tools read, e.g. an OpenAPI
+// document for reviewing what models can call
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Formats tools are exported in
+const (
+	// FormatOpenAPI is an OpenAPI 3 document with an operation per tool
+	FormatOpenAPI = "openapi"
+	// FormatMCPManifest is a JSON manifest of the tools for a registry
+	FormatMCPManifest = "mcp-manifest"
+	// FormatOpenAIFunctions is the tools parameter of the OpenAI API
+	FormatOpenAIFunctions = "openai-functions"
+	// FormatAnthropicTools is the tools parameter of the Anthropic Messages API
+	FormatAnthropicTools = "anthropic-tools"
+)
+
+// Formats lists the formats tools are exported in
+var Formats = []string{FormatOpenAPI, FormatMCPManifest, FormatOpenAIFunctions, FormatAnthropicTools}
+
+// toolName matches the tool names model APIs accept
+var toolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Tool is a served tool with the upstream operation it calls
+type Tool struct {
+	mcp.Tool
+	// Method and Path are the upstream operation the tool calls, empty for
+	// the tools of scripts and the batch tool
+	Method string
+	Path   string
+	// Output is the schema of the tool's result when it is the JSON response
+	// of the upstream operation as is, nil if unknown
+	Output *openapi3.Schema
+}
+
+// Export returns the document describing tools in a format, of the API of
+// title and version
+func Export(format, title, version string, tools []Tool) (interface{}, error) {
+	switch format {
+	case FormatOpenAPI:
+		return OpenAPI(title, version, tools)
+	case FormatMCPManifest:
+		return MCPManifest(title, version, tools)
+	case FormatOpenAIFunctions:
+		return OpenAIFunctions(tools)
+	case FormatAnthropicTools:
+		return AnthropicTools(tools)
+	}
+	return nil, fmt.Errorf("invalid format %q, expected one of %v", format, Formats)
+}
+
+// InputSchema returns the JSON schema of a tool's arguments, which is either
+// built with options or given raw
+func InputSchema(tool mcp.Tool) (map[string]interface{}, error) {
+	data, err := json.Marshal(tool)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input schema of tool %s: %w", tool.Name, err)
+	}
+	var described struct {
+		InputSchema map[string]interface{} `json:"inputSchema"`
+	}
+	if err := json.Unmarshal(data, &described); err != nil {
+		return nil, fmt.Errorf("invalid input schema of tool %s: %w", tool.Name, err)
+	}
+	schema := described.InputSchema
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object"}
+	}
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]interface{}{}
+	}
+	return schema, nil
+}
+
+// checkName fails on a tool name model APIs refuse, as the tool of a kind
+func checkName(name, kind string) error {
+	if !toolName.MatchString(name) {
+		return fmt.Errorf("tool %s can't be %s: names are up to 64 letters, digits, underscores and dashes", name, kind)
+	}
+	return nil
+}
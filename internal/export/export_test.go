@@ -0,0 +1,186 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// testTools returns a generated tool and a script tool without arguments
+func testTools() []Tool {
+	return []Tool{
+		{
+			Tool: mcp.NewTool("get_pets_petId",
+				mcp.WithDescription("Get a pet"),
+				mcp.WithString("petId", mcp.Required(), mcp.Description("ID of the pet")),
+				mcp.WithNumber("limit")),
+			Method: "GET",
+			Path:   "/pets/{petId}",
+		},
+		{Tool: mcp.NewTool("summarize", mcp.WithDescription("Summarize the pets"))},
+	}
+}
+
+func TestInputSchema(t *testing.T) {
+	schema, err := InputSchema(testTools()[0].Tool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties := schema["properties"].(map[string]interface{})
+	if schema["type"] != "object" || len(properties) != 2 {
+		t.Errorf("schema = %v, want an object of 2 properties", schema)
+	}
+	if required := schema["required"].([]interface{}); len(required) != 1 || required[0] != "petId" {
+		t.Errorf("required = %v, want [petId]", required)
+	}
+
+	raw := mcp.NewToolWithRawSchema("raw", "", json.RawMessage(`{"type":"object"}`))
+	if schema, err := InputSchema(raw); err != nil || schema["properties"] == nil {
+		t.Errorf("InputSchema(raw) = %v, %v, want an object with properties", schema, err)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc, err := OpenAPI("Pet Store", "1.0.0", testTools())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	if doc.Info.Title != "Pet Store tools" || doc.Paths.Len() != 2 {
+		t.Errorf("title %q with %d paths, want Pet Store tools with 2", doc.Info.Title, doc.Paths.Len())
+	}
+
+	op := doc.Paths.Value("/tools/get_pets_petId").Post
+	if op.OperationID != "get_pets_petId" || op.Description != "Get a pet" {
+		t.Errorf("operation = %s %q", op.OperationID, op.Description)
+	}
+	schema := op.RequestBody.Value.Content.Get("application/json").Schema.Value
+	if schema.Properties["petId"].Value.Type != "string" || schema.Properties["limit"].Value.Type != "number" || len(schema.Required) != 1 {
+		t.Errorf("request body = %+v", schema)
+	}
+	upstream, _ := op.Extensions[UpstreamExtension].(map[string]string)
+	if upstream["method"] != "GET" || upstream["path"] != "/pets/{petId}" {
+		t.Errorf("upstream = %v, want GET /pets/{petId}", op.Extensions[UpstreamExtension])
+	}
+
+	if op := doc.Paths.Value("/tools/summarize").Post; op.Extensions[UpstreamExtension] != nil {
+		t.Errorf("script tool has upstream %v", op.Extensions[UpstreamExtension])
+	}
+}
+
+func TestExport(t *testing.T) {
+	if _, err := Export(FormatOpenAPI, "Pet Store", "1.0.0", testTools()); err != nil {
+		t.Error(err)
+	}
+	if _, err := Export("wsdl", "Pet Store", "1.0.0", testTools()); err == nil {
+		t.Error("expected an unknown format to be refused")
+	}
+}
+
+func TestMCPManifest(t *testing.T) {
+	tools := testTools()
+	tools[0].Output = openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema())
+	manifest, err := MCPManifest("Pet Store", "1.0.0", tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Name != "Pet Store" || manifest.Version != "1.0.0" || len(manifest.Tools) != 2 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	pet := manifest.Tools[0]
+	if pet.InputSchema["type"] != "object" || pet.OutputSchema == nil || pet.Upstream == nil || pet.Upstream.Path != "/pets/{petId}" {
+		t.Errorf("tool = %+v", pet)
+	}
+	if want := (Annotations{ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: true}); pet.Annotations == nil || *pet.Annotations != want {
+		t.Errorf("annotations = %+v, want %+v", pet.Annotations, want)
+	}
+	if script := manifest.Tools[1]; script.Annotations != nil || script.Upstream != nil || script.OutputSchema != nil {
+		t.Errorf("script tool = %+v, want no annotations, upstream or output", script)
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"outputSchema":{"properties":{"name":{"type":"string"}},"type":"object"}`) {
+		t.Errorf("manifest JSON = %s", data)
+	}
+}
+
+func TestAnnotationsOf(t *testing.T) {
+	for method, want := range map[string]Annotations{
+		"GET":    {ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: true},
+		"post":   {OpenWorldHint: true},
+		"PUT":    {IdempotentHint: true, OpenWorldHint: true},
+		"PATCH":  {OpenWorldHint: true},
+		"DELETE": {DestructiveHint: true, IdempotentHint: true, OpenWorldHint: true},
+	} {
+		if got := annotationsOf(method); *got != want {
+			t.Errorf("annotationsOf(%s) = %+v, want %+v", method, *got, want)
+		}
+	}
+}
+
+func TestResponseSchema(t *testing.T) {
+	pet := openapi3.NewObjectSchema()
+	op := &openapi3.Operation{Responses: openapi3.NewResponses(
+		openapi3.WithStatus(404, &openapi3.ResponseRef{Value: openapi3.NewResponse().WithJSONSchema(openapi3.NewStringSchema())}),
+		openapi3.WithStatus(204, &openapi3.ResponseRef{Value: openapi3.NewResponse().WithDescription("No content")}),
+		openapi3.WithStatus(200, &openapi3.ResponseRef{Value: openapi3.NewResponse().WithContent(openapi3.NewContentWithSchema(pet, []string{"application/hal+json", "text/plain"}))}),
+	)}
+	if got := ResponseSchema(op); got != pet {
+		t.Errorf("ResponseSchema() = %+v, want the schema of the 200 response", got)
+	}
+	if got := ResponseSchema(&openapi3.Operation{Responses: openapi3.NewResponses()}); got != nil {
+		t.Errorf("ResponseSchema() of no JSON response = %+v, want nil", got)
+	}
+}
+
+func TestOpenAIFunctions(t *testing.T) {
+	functions, err := OpenAIFunctions(testTools())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(functions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"function","function":{"name":"get_pets_petId","description":"Get a pet","parameters":{"properties":{"limit":{"type":"number"},"petId":{"description":"ID of the pet","type":"string"}},"required":["petId"],"type":"object"}}}`
+	if string(data) != want {
+		t.Errorf("function = %s, want %s", data, want)
+	}
+	if len(functions) != 2 || functions[1].Function.Parameters["type"] != "object" {
+		t.Errorf("functions = %+v", functions)
+	}
+
+	if _, err := OpenAIFunctions([]Tool{{Tool: mcp.NewTool("list pets")}}); err == nil {
+		t.Error("expected a name with a space to be refused")
+	}
+}
+
+func TestAnthropicTools(t *testing.T) {
+	tools, err := AnthropicTools(testTools())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"name":"get_pets_petId","description":"Get a pet","input_schema":{"properties":{"limit":{"type":"number"},"petId":{"description":"ID of the pet","type":"string"}},"required":["petId"],"type":"object"}},` +
+		`{"name":"summarize","description":"Summarize the pets","input_schema":{"properties":{},"type":"object"}}]`
+	if string(data) != want {
+		t.Errorf("tools = %s, want %s", data, want)
+	}
+
+	if _, err := AnthropicTools([]Tool{{Tool: mcp.NewTool("pets.list")}}); err == nil {
+		t.Error("expected a name with a dot to be refused")
+	}
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/version"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Manifest lists the tools of a server for cataloging it in a registry
+type Manifest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Generator is the mcprox version the tools were derived with
+	Generator string         `json:"generator"`
+	Tools     []ManifestTool `json:"tools"`
+}
+
+// ManifestTool describes a tool of a manifest
+type ManifestTool struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema *openapi3.Schema       `json:"outputSchema,omitempty"`
+	Annotations  *Annotations           `json:"annotations,omitempty"`
+	Upstream     *Upstream              `json:"upstream,omitempty"`
+}
+
+// Annotations are the MCP hints about how a tool behaves, derived from the
+// method of the upstream operation it calls
+type Annotations struct {
+	ReadOnlyHint    bool `json:"readOnlyHint"`
+	DestructiveHint bool `json:"destructiveHint"`
+	IdempotentHint  bool `json:"idempotentHint"`
+	OpenWorldHint   bool `json:"openWorldHint"`
+}
+
+// Upstream is the upstream operation a tool calls
+type Upstream struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// MCPManifest returns the manifest of the tools of the API of title and
+// version
+func MCPManifest(title, apiVersion string, tools []Tool) (*Manifest, error) {
+	manifest := &Manifest{
+		Name:      title,
+		Version:   apiVersion,
+		Generator: "mcprox " + version.Get(),
+		Tools:     make([]ManifestTool, 0, len(tools)),
+	}
+	for _, tool := range tools {
+		input, err := InputSchema(tool.Tool)
+		if err != nil {
+			return nil, err
+		}
+		described := ManifestTool{
+			Name:         tool.Name,
+			Description:  tool.Description,
+			InputSchema:  input,
+			OutputSchema: tool.Output,
+		}
+		if tool.Method != "" {
+			described.Annotations = annotationsOf(tool.Method)
+			described.Upstream = &Upstream{Method: tool.Method, Path: tool.Path}
+		}
+		manifest.Tools = append(manifest.Tools, described)
+	}
+	return manifest, nil
+}
+
+// annotationsOf returns the annotations of a tool calling an operation of a
+// method: safe methods only read, DELETE destroys, and repeating a safe
+// method, PUT or DELETE has no further effect
+func annotationsOf(method string) *Annotations {
+	method = strings.ToUpper(method)
+	readOnly := method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	return &Annotations{
+		ReadOnlyHint:    readOnly,
+		DestructiveHint: method == http.MethodDelete,
+		IdempotentHint:  readOnly || method == http.MethodPut || method == http.MethodDelete,
+		OpenWorldHint:   true,
+	}
+}
+
+// ResponseSchema returns the schema of the JSON body of the first successful
+// response of an operation, nil if it has none
+func ResponseSchema(op *openapi3.Operation) *openapi3.Schema {
+	if op == nil || op.Responses == nil {
+		return nil
+	}
+	responses := op.Responses.Map()
+	statuses := make([]string, 0, len(responses))
+	for status := range responses {
+		if strings.HasPrefix(status, "2") {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		response := responses[status]
+		if response == nil || response.Value == nil {
+			continue
+		}
+		mediaTypes := make([]string, 0, len(response.Value.Content))
+		for mediaType := range response.Value.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes)
+		for _, mediaType := range mediaTypes {
+			media := response.Value.Content[mediaType]
+			if strings.HasSuffix(mediaType, "json") && media.Schema != nil && media.Schema.Value != nil {
+				return media.Schema.Value
+			}
+		}
+	}
+	return nil
+}
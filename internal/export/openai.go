@@ -0,0 +1,38 @@
+package export
+
+// OpenAITool is a function tool of the OpenAI Chat Completions API
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes a function a model can call
+type OpenAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// OpenAIFunctions returns the tools as the tools parameter of the OpenAI
+// Chat Completions API, failing on a name OpenAI refuses
+func OpenAIFunctions(tools []Tool) ([]OpenAITool, error) {
+	functions := make([]OpenAITool, 0, len(tools))
+	for _, tool := range tools {
+		if err := checkName(tool.Name, "an OpenAI function"); err != nil {
+			return nil, err
+		}
+		parameters, err := InputSchema(tool.Tool)
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, OpenAITool{
+			Type: "function",
+			Function: OpenAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  parameters,
+			},
+		})
+	}
+	return functions, nil
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// UpstreamExtension is the extension of the operations of the OpenAPI
+// document naming the upstream operation a tool calls
+const UpstreamExtension = "x-mcprox-upstream"
+
+// OpenAPI returns an OpenAPI document describing tools as operations: a tool
+// is a POST to /tools/{name} whose JSON body is its arguments and whose
+// response is its text result
+func OpenAPI(title, version string, tools []Tool) (*openapi3.T, error) {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       title + " tools",
+			Version:     version,
+			Description: "The tools mcprox serves for " + title + ", as models see them",
+		},
+		Paths: openapi3.NewPaths(),
+	}
+
+	for _, tool := range tools {
+		input, err := InputSchema(tool.Tool)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewObjectSchema()
+		if err := json.Unmarshal(data, schema); err != nil {
+			return nil, fmt.Errorf("invalid input schema of tool %s: %w", tool.Name, err)
+		}
+
+		op := &openapi3.Operation{
+			OperationID: tool.Name,
+			Description: tool.Description,
+			RequestBody: &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().
+				WithRequired(true).
+				WithJSONSchema(schema)},
+			Responses: openapi3.NewResponses(openapi3.WithStatus(http.StatusOK, &openapi3.ResponseRef{Value: openapi3.NewResponse().
+				WithDescription("Result of the tool").
+				WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{"text/plain"}))})),
+		}
+		if tool.Method != "" {
+			op.Extensions = map[string]interface{}{
+				UpstreamExtension: map[string]string{"method": tool.Method, "path": tool.Path},
+			}
+		}
+		doc.Paths.Set("/tools/"+url.PathEscape(tool.Name), &openapi3.PathItem{Post: op})
+	}
+	return doc, nil
+}
@@ -0,0 +1,263 @@
+// Package health serves the liveness, readiness and build information
+// endpoints of serve mode, which orchestrators such as Kubernetes probe
+package health
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/berkantay/mcprox/internal/version"
+)
+
+// Paths of the endpoints
+const (
+	// LivenessPath answers as long as the process serves requests
+	LivenessPath = "/healthz"
+	// ReadinessPath answers whether the server can serve tool calls
+	ReadinessPath = "/readyz"
+	// VersionPath describes the running binary
+	VersionPath = "/version"
+)
+
+// checkTTL is how long the results of the readiness checks are reused, so
+// that frequent probes don't flood the upstream API
+const checkTTL = 5 * time.Second
+
+// specCheckInterval bounds how often the OpenAPI documents are fetched again
+// to check that the served one is still current
+const specCheckInterval = time.Minute
+
+// Check is the result of one readiness check
+type Check struct {
+	Name      string `json:"name"`
+	Target    string `json:"target,omitempty"`
+	OK        bool   `json:"ok"`
+	LatencyMS int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Readiness is the body of the readiness endpoint
+type Readiness struct {
+	Ready  bool    `json:"ready"`
+	Checks []Check `json:"checks"`
+	// SpecLoadedAt is when the served OpenAPI documents were fetched, and
+	// SpecVerifiedAt when they were last confirmed to be current
+	SpecLoadedAt   time.Time `json:"spec_loaded_at"`
+	SpecVerifiedAt time.Time `json:"spec_verified_at"`
+}
+
+// Checker serves the health endpoints of a server
+type Checker struct {
+	// Upstreams are the service URLs tool calls reach, which must respond
+	// for the server to be ready. Any HTTP response will do.
+	Upstreams []string
+	// Specs are the OpenAPI documents served, with their digests when loaded
+	Specs []openapi.SpecSource
+	// LoadedAt is when Specs were fetched
+	LoadedAt time.Time
+	// MaxSpecAge, if set, fails readiness once the served documents haven't
+	// been confirmed to match their sources for that long, e.g. because the
+	// API changed and the server must be reloaded to serve the new tools
+	MaxSpecAge time.Duration
+	// Timeout bounds each check, 5 seconds if 0
+	Timeout time.Duration
+	// Client sends the checks' requests, http.DefaultClient if nil
+	Client *http.Client
+
+	mu        sync.Mutex
+	checked   time.Time
+	result    Readiness
+	verified  time.Time
+	specCheck time.Time
+	specError string
+}
+
+// FromConfig returns a checker of a server serving specs, loaded now, and
+// calling upstreams, or nil if the health endpoints are disabled
+func FromConfig(specs []openapi.SpecSource, upstreams []string) *Checker {
+	if !config.GetBool("server.health.enabled") {
+		return nil
+	}
+	return &Checker{
+		Upstreams:  upstreams,
+		Specs:      specs,
+		LoadedAt:   time.Now(),
+		MaxSpecAge: time.Duration(config.GetInt("server.health.max_spec_age")) * time.Second,
+		Timeout:    time.Duration(config.GetInt("server.health.timeout")) * time.Second,
+	}
+}
+
+// Reloaded records that the server now serves specs, fetched now
+func (c *Checker) Reloaded(specs []openapi.SpecSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Specs, c.LoadedAt = specs, time.Now()
+	c.verified, c.specCheck, c.checked = time.Time{}, time.Time{}, time.Time{}
+}
+
+// Wrap returns a handler serving the health endpoints and passing other
+// requests to next. It goes outside client authentication: probes carry no
+// credentials.
+func (c *Checker) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case LivenessPath:
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		case ReadinessPath:
+			readiness := c.Ready(r.Context())
+			status := http.StatusOK
+			if !readiness.Ready {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, readiness)
+		case VersionPath:
+			writeJSON(w, http.StatusOK, version.Info())
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Ready runs the readiness checks, or returns their last results if they ran
+// recently
+func (c *Checker) Ready(ctx context.Context) Readiness {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checked.IsZero() && time.Since(c.checked) < checkTTL {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	checks := make([]Check, len(c.Upstreams))
+	for i, upstream := range c.Upstreams {
+		wg.Add(1)
+		go func(i int, upstream string) {
+			defer wg.Done()
+			checks[i] = c.checkUpstream(ctx, upstream)
+		}(i, upstream)
+	}
+	wg.Wait()
+	checks = append(checks, c.checkSpec(ctx))
+
+	result := Readiness{Ready: true, Checks: checks, SpecLoadedAt: c.LoadedAt, SpecVerifiedAt: c.verifiedAt()}
+	for _, check := range checks {
+		result.Ready = result.Ready && check.OK
+	}
+	c.checked, c.result = time.Now(), result
+	return result
+}
+
+// checkUpstream checks that an upstream API responds
+func (c *Checker) checkUpstream(ctx context.Context, upstream string) Check {
+	check := Check{Name: "upstream", Target: upstream}
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, upstream, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	resp, err := c.client().Do(req)
+	check.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	resp.Body.Close()
+	check.OK = true
+	return check
+}
+
+// checkSpec checks that the served OpenAPI documents were confirmed to match
+// their sources within MaxSpecAge, fetching them again when due
+func (c *Checker) checkSpec(ctx context.Context) Check {
+	check := Check{Name: "spec", OK: true}
+	if c.MaxSpecAge <= 0 {
+		return check
+	}
+	if c.specCheck.IsZero() || time.Since(c.specCheck) >= specCheckInterval {
+		c.specCheck = time.Now()
+		c.specError = ""
+		if err := c.verifySpecs(ctx); err != nil {
+			c.specError = err.Error()
+		} else {
+			c.verified = time.Now()
+		}
+	}
+	if age := time.Since(c.verifiedAt()); age > c.MaxSpecAge {
+		check.OK = false
+		check.Error = fmt.Sprintf("spec not confirmed current for %s", age.Round(time.Second))
+		if c.specError != "" {
+			check.Error += ": " + c.specError
+		}
+	}
+	return check
+}
+
+// verifySpecs fetches the sources of the served documents and compares them
+// with the loaded ones
+func (c *Checker) verifySpecs(ctx context.Context) error {
+	for _, spec := range c.Specs {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, spec.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.client().Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", spec.URL, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", spec.URL, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to fetch %s: %s", spec.URL, resp.Status)
+		}
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != spec.SHA256 {
+			return fmt.Errorf("%s changed since it was loaded", spec.URL)
+		}
+	}
+	return nil
+}
+
+// verifiedAt returns when the served documents were last confirmed current
+func (c *Checker) verifiedAt() time.Time {
+	if c.verified.IsZero() {
+		return c.LoadedAt
+	}
+	return c.verified
+}
+
+func (c *Checker) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return 5 * time.Second
+	}
+	return c.Timeout
+}
+
+func (c *Checker) client() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+	return c.Client
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
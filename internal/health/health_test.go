@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/openapi"
+)
+
+func TestWrap(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	defer upstream.Close()
+	c := &Checker{Upstreams: []string{upstream.URL}, LoadedAt: time.Now()}
+	handler := c.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := serve(LivenessPath); w.Code != http.StatusOK {
+		t.Errorf("%s: status %d, want 200", LivenessPath, w.Code)
+	}
+	w := serve(ReadinessPath)
+	var readiness Readiness
+	if err := json.Unmarshal(w.Body.Bytes(), &readiness); err != nil || w.Code != http.StatusOK || !readiness.Ready {
+		t.Errorf("%s: status %d, body %s", ReadinessPath, w.Code, w.Body.String())
+	}
+	var info map[string]interface{}
+	if w := serve(VersionPath); json.Unmarshal(w.Body.Bytes(), &info) != nil || info["version"] == "" || info["go_version"] == "" {
+		t.Errorf("%s: body %s", VersionPath, w.Body.String())
+	}
+	if w := serve("/sse"); w.Code != http.StatusTeapot {
+		t.Errorf("/sse: status %d, want it passed on", w.Code)
+	}
+}
+
+func TestReadyUnreachableUpstream(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+	c := &Checker{Upstreams: []string{upstream.URL}, LoadedAt: time.Now()}
+
+	readiness := c.Ready(context.Background())
+	if readiness.Ready || len(readiness.Checks) != 2 || readiness.Checks[0].OK || readiness.Checks[0].Error == "" {
+		t.Errorf("Ready() = %+v, want the upstream check to fail", readiness)
+	}
+}
+
+func TestReadySpecFreshness(t *testing.T) {
+	served := []byte(`{"openapi":"3.0.0"}`)
+	current := served
+	spec := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(current)
+	}))
+	defer spec.Close()
+	sum := sha256.Sum256(served)
+	specs := []openapi.SpecSource{{URL: spec.URL, SHA256: hex.EncodeToString(sum[:])}}
+	loadedAt := time.Now().Add(-2 * time.Hour)
+
+	// An unchanged document is confirmed current again
+	c := &Checker{Specs: specs, LoadedAt: loadedAt, MaxSpecAge: time.Hour}
+	if readiness := c.Ready(context.Background()); !readiness.Ready || !readiness.SpecVerifiedAt.After(loadedAt) {
+		t.Errorf("Ready() with an unchanged spec = %+v, want ready", readiness)
+	}
+
+	current = []byte(`{"openapi":"3.0.0","paths":{}}`)
+	c = &Checker{Specs: specs, LoadedAt: loadedAt, MaxSpecAge: time.Hour}
+	if readiness := c.Ready(context.Background()); readiness.Ready {
+		t.Errorf("Ready() with a changed spec = %+v, want not ready", readiness)
+	}
+
+	// Within MaxSpecAge a changed document doesn't fail readiness yet
+	c = &Checker{Specs: specs, LoadedAt: time.Now(), MaxSpecAge: time.Hour}
+	if readiness := c.Ready(context.Background()); !readiness.Ready {
+		t.Errorf("Ready() with a recently loaded spec = %+v, want ready", readiness)
+	}
+}
@@ -0,0 +1,288 @@
+// Package history records the tool calls of serve mode and their results in a
+// SQLite database, so what a model did can be looked up afterwards with
+// mcprox history
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// MaxResultBytes bounds the recorded text of a result; longer results are
+// truncated so that a few large responses can't bloat the database
+const MaxResultBytes = 64 << 10
+
+// Statuses of tool calls
+const (
+	// StatusOK is a call that returned a result
+	StatusOK = "ok"
+	// StatusToolError is a call whose result reports an error
+	StatusToolError = "tool_error"
+	// StatusError is a call that failed without a result
+	StatusError = "error"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS calls (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	time INTEGER NOT NULL,
+	session_id TEXT NOT NULL,
+	tool TEXT NOT NULL,
+	arguments TEXT NOT NULL,
+	status TEXT NOT NULL,
+	result TEXT NOT NULL,
+	error TEXT NOT NULL,
+	latency_ms INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS calls_time ON calls (time);
+CREATE INDEX IF NOT EXISTS calls_tool ON calls (tool, time);
+`
+
+// Call is a recorded tool call
+type Call struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id,omitempty"`
+	Tool      string    `json:"tool"`
+	// Arguments are the call's arguments as a JSON object
+	Arguments string `json:"arguments"`
+	Status    string `json:"status"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// Query selects recorded calls, the most recent first
+type Query struct {
+	// Tool, Session and Status, if set, must match exactly
+	Tool    string
+	Session string
+	Status  string
+	// Since, if not zero, excludes older calls
+	Since time.Time
+	// Limit bounds the number of calls returned, all if 0
+	Limit int
+}
+
+// Store is a history database, safe for concurrent use
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the history database at path, creating it if needed
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// FromConfig opens the history configured by history.path, nil if unset, and
+// deletes the calls older than history.retention days, if positive
+func FromConfig(ctx context.Context) (*Store, error) {
+	path := config.GetString("history.path")
+	if path == "" {
+		return nil, nil
+	}
+	store, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if days := config.GetInt("history.retention"); days > 0 {
+		if _, err := store.Prune(ctx, time.Now().AddDate(0, 0, -days)); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to prune history %s: %w", path, err)
+		}
+	}
+	return store, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record adds a call to the history, truncating its result
+func (s *Store) Record(ctx context.Context, call Call) (int64, error) {
+	if len(call.Result) > MaxResultBytes {
+		call.Result = strings.ToValidUTF8(call.Result[:MaxResultBytes], "") + "…"
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO calls (time, session_id, tool, arguments, status, result, error, latency_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		call.Time.UnixMilli(), call.SessionID, call.Tool, call.Arguments, call.Status, call.Result, call.Error, call.LatencyMS)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Calls returns the recorded calls matching a query, the most recent first
+func (s *Store) Calls(ctx context.Context, q Query) ([]Call, error) {
+	var conditions []string
+	var args []interface{}
+	for _, filter := range [][2]string{{"tool", q.Tool}, {"session_id", q.Session}, {"status", q.Status}} {
+		if filter[1] != "" {
+			conditions = append(conditions, filter[0]+" = ?")
+			args = append(args, filter[1])
+		}
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, q.Since.UnixMilli())
+	}
+
+	query := `SELECT id, time, session_id, tool, arguments, status, result, error, latency_ms FROM calls`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY time DESC, id DESC"
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var calls []Call
+	for rows.Next() {
+		var call Call
+		var millis int64
+		if err := rows.Scan(&call.ID, &millis, &call.SessionID, &call.Tool, &call.Arguments, &call.Status, &call.Result, &call.Error, &call.LatencyMS); err != nil {
+			return nil, err
+		}
+		call.Time = time.UnixMilli(millis).UTC()
+		calls = append(calls, call)
+	}
+	return calls, rows.Err()
+}
+
+// Call returns the recorded call with an ID
+func (s *Store) Call(ctx context.Context, id int64) (Call, error) {
+	call := Call{ID: id}
+	var millis int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT time, session_id, tool, arguments, status, result, error, latency_ms FROM calls WHERE id = ?`, id).
+		Scan(&millis, &call.SessionID, &call.Tool, &call.Arguments, &call.Status, &call.Result, &call.Error, &call.LatencyMS)
+	if err == sql.ErrNoRows {
+		return Call{}, fmt.Errorf("no call %d in the history", id)
+	}
+	if err != nil {
+		return Call{}, err
+	}
+	call.Time = time.UnixMilli(millis).UTC()
+	return call, nil
+}
+
+// ToolStats summarizes the recorded calls of a tool
+type ToolStats struct {
+	Tool   string `json:"tool"`
+	Calls  int64  `json:"calls"`
+	Errors int64  `json:"errors"`
+	// ErrorRate is the share of calls that failed or returned an error result
+	ErrorRate    float64   `json:"error_rate"`
+	AvgLatencyMS int64     `json:"avg_latency_ms"`
+	LastCall     time.Time `json:"last_call"`
+}
+
+// Stats summarizes the calls recorded since a time, all if zero, by tool
+func (s *Store) Stats(ctx context.Context, since time.Time) ([]ToolStats, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT tool, COUNT(*), SUM(CASE WHEN status = ? THEN 0 ELSE 1 END), CAST(AVG(latency_ms) AS INTEGER), MAX(time)
+		FROM calls WHERE time >= ? GROUP BY tool ORDER BY tool`, StatusOK, since.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var stats []ToolStats
+	for rows.Next() {
+		var tool ToolStats
+		var millis int64
+		if err := rows.Scan(&tool.Tool, &tool.Calls, &tool.Errors, &tool.AvgLatencyMS, &millis); err != nil {
+			return nil, err
+		}
+		tool.ErrorRate = float64(tool.Errors) / float64(tool.Calls)
+		tool.LastCall = time.UnixMilli(millis).UTC()
+		stats = append(stats, tool)
+	}
+	return stats, rows.Err()
+}
+
+// Prune deletes the calls recorded before a time and returns how many
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM calls WHERE time < ?`, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Wrap returns a handler recording each call of next. Failing to record a
+// call is logged and never fails the call.
+func (s *Store) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		call := Call{
+			Time:      start,
+			Tool:      request.Params.Name,
+			Status:    StatusOK,
+			LatencyMS: time.Since(start).Milliseconds(),
+		}
+		arguments := request.Params.Arguments
+		if arguments == nil {
+			arguments = map[string]interface{}{}
+		}
+		if data, marshalErr := json.Marshal(arguments); marshalErr == nil {
+			call.Arguments = string(data)
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			call.SessionID = session.SessionID()
+		}
+		switch {
+		case err != nil:
+			call.Status = StatusError
+			call.Error = err.Error()
+		case result != nil:
+			if result.IsError {
+				call.Status = StatusToolError
+			}
+			call.Result = resultText(result)
+		}
+
+		// Record even if the client went away, which is worth knowing about
+		if _, recordErr := s.Record(context.WithoutCancel(ctx), call); recordErr != nil {
+			logging.FromContext(ctx).Warn("Failed to record tool call in the history", zap.Error(recordErr))
+		}
+		return result, err
+	}
+}
+
+// resultText returns the text of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var text strings.Builder
+	for _, content := range result.Content {
+		if textContent, ok := content.(mcp.TextContent); ok {
+			text.WriteString(textContent.Text)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,146 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestWrap(t *testing.T) {
+	store := openStore(t)
+	handler := store.Wrap(func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if request.Params.Name == "delete_pet" {
+			return nil, errors.New("upstream unavailable")
+		}
+		return mcp.NewToolResultText(`[{"name":"Rex"}]`), nil
+	})
+
+	for _, tool := range []string{"get_pets", "delete_pet"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = tool
+		request.Params.Arguments = map[string]interface{}{"id": 1}
+		handler(context.Background(), request)
+	}
+
+	calls, err := store.Calls(context.Background(), Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("recorded %d calls, want 2", len(calls))
+	}
+	if calls[0].Tool != "delete_pet" || calls[0].Status != StatusError || calls[0].Error != "upstream unavailable" {
+		t.Errorf("unexpected call %+v", calls[0])
+	}
+	if calls[1].Tool != "get_pets" || calls[1].Status != StatusOK || calls[1].Result != `[{"name":"Rex"}]` || calls[1].Arguments != `{"id":1}` {
+		t.Errorf("unexpected call %+v", calls[1])
+	}
+
+	call, err := store.Call(context.Background(), calls[1].ID)
+	if err != nil || call != calls[1] {
+		t.Errorf("Call(%d) = %+v, %v", calls[1].ID, call, err)
+	}
+	if _, err := store.Call(context.Background(), 999); err == nil {
+		t.Error("expected an error for an unknown call")
+	}
+}
+
+func TestCallsQuery(t *testing.T) {
+	store := openStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	for _, call := range []Call{
+		{Time: now.Add(-48 * time.Hour), Tool: "get_pets", Status: StatusOK, SessionID: "a"},
+		{Time: now.Add(-time.Hour), Tool: "get_pets", Status: StatusToolError, SessionID: "b"},
+		{Time: now, Tool: "post_pets", Status: StatusOK, SessionID: "b"},
+	} {
+		if _, err := store.Record(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, test := range map[string]struct {
+		query Query
+		want  int
+	}{
+		"all":     {Query{}, 3},
+		"tool":    {Query{Tool: "get_pets"}, 2},
+		"session": {Query{Session: "b"}, 2},
+		"status":  {Query{Tool: "get_pets", Status: StatusOK}, 1},
+		"since":   {Query{Since: now.Add(-24 * time.Hour)}, 2},
+		"limit":   {Query{Limit: 1}, 1},
+	} {
+		calls, err := store.Calls(ctx, test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) != test.want {
+			t.Errorf("%s: got %d calls, want %d", name, len(calls), test.want)
+		}
+	}
+
+	pruned, err := store.Prune(ctx, now.Add(-24*time.Hour))
+	if err != nil || pruned != 1 {
+		t.Errorf("Prune = %d, %v, want 1", pruned, err)
+	}
+}
+
+func TestRecordTruncatesResult(t *testing.T) {
+	store := openStore(t)
+	id, err := store.Record(context.Background(), Call{Time: time.Now(), Tool: "get_pets", Result: strings.Repeat("x", MaxResultBytes+10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := store.Call(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(call.Result, "…") || len(call.Result) > MaxResultBytes+len("…") {
+		t.Errorf("result of %d bytes not truncated", len(call.Result))
+	}
+}
+
+func TestStats(t *testing.T) {
+	store := openStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	for _, call := range []Call{
+		{Time: now.Add(-48 * time.Hour), Tool: "get_pets", Status: StatusError, LatencyMS: 1000},
+		{Time: now.Add(-time.Hour), Tool: "get_pets", Status: StatusOK, LatencyMS: 10},
+		{Time: now, Tool: "get_pets", Status: StatusToolError, LatencyMS: 30},
+		{Time: now, Tool: "post_pets", Status: StatusOK, LatencyMS: 5},
+	} {
+		if _, err := store.Record(ctx, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := store.Stats(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("stats of %d tools, want 2", len(stats))
+	}
+	if got := stats[0]; got.Tool != "get_pets" || got.Calls != 2 || got.Errors != 1 || got.ErrorRate != 0.5 || got.AvgLatencyMS != 20 {
+		t.Errorf("unexpected stats %+v", got)
+	}
+	if got := stats[1]; got.Tool != "post_pets" || got.Calls != 1 || got.Errors != 0 || !got.LastCall.Equal(now.Truncate(time.Millisecond)) {
+		t.Errorf("unexpected stats %+v", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Package idempotency attaches idempotency keys to the upstream requests of
+// unsafe tool calls and deduplicates calls agents retry, so that a retried
+// call can't place an order or a payment twice
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultHeader is the header the key is sent in unless configured otherwise
+const DefaultHeader = "Idempotency-Key"
+
+// Keyer derives the idempotency keys of tool calls and remembers the results
+// of the calls made with them. A call is identified by its session, tool and
+// arguments: a retry gets the same key, and within Window the result of the
+// first call instead of calling the API again.
+type Keyer struct {
+	// Header is the request header carrying the key
+	Header string
+	// Window is how long the result of a call answers its retries
+	Window time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*call
+	now   func() time.Time
+}
+
+// call is a call made with a key, in flight until done is closed
+type call struct {
+	done    chan struct{}
+	result  *mcp.CallToolResult
+	expires time.Time
+}
+
+type keyContextKey struct{}
+
+// New returns a keyer sending keys in header, remembering results for window
+func New(header string, window time.Duration) *Keyer {
+	if header == "" {
+		header = DefaultHeader
+	}
+	return &Keyer{Header: header, Window: window, calls: make(map[string]*call), now: time.Now}
+}
+
+// FromConfig returns the keyer configured under idempotency, nil if disabled
+func FromConfig() *Keyer {
+	if !config.GetBool("idempotency.enabled") {
+		return nil
+	}
+	return New(config.GetString("idempotency.header"), time.Duration(config.GetInt("idempotency.window"))*time.Second)
+}
+
+// Key returns the idempotency key of a call of a tool with arguments
+func (k *Keyer) Key(ctx context.Context, tool string, arguments map[string]interface{}) string {
+	// Map keys are marshaled sorted, which makes the arguments canonical
+	data, _ := json.Marshal(arguments)
+	h := sha256.New()
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		h.Write([]byte(session.SessionID()))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(tool))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// WithKey returns a context carrying the idempotency key of a call
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the idempotency key of the call of a context
+func KeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(string)
+	return key, ok
+}
+
+// Do makes a call with a key unless a call with the same key succeeded within
+// the window, whose result it returns, or is in flight, which it waits for.
+// It reports whether the result is that of an earlier call. Failed calls
+// aren't remembered, so that their retries reach the API.
+func (k *Keyer) Do(ctx context.Context, key string, fn func(ctx context.Context) (*mcp.CallToolResult, error)) (*mcp.CallToolResult, bool, error) {
+	for {
+		k.mu.Lock()
+		k.expire()
+		c, ok := k.calls[key]
+		if !ok {
+			c = &call{done: make(chan struct{})}
+			k.calls[key] = c
+			k.mu.Unlock()
+			return k.run(ctx, key, c, fn)
+		}
+		k.mu.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		k.mu.Lock()
+		result := c.result
+		k.mu.Unlock()
+		if result != nil {
+			return result, true, nil
+		}
+		// The call failed and was forgotten; make it again
+	}
+}
+
+// run makes the call of a key and records its result
+func (k *Keyer) run(ctx context.Context, key string, c *call, fn func(ctx context.Context) (*mcp.CallToolResult, error)) (*mcp.CallToolResult, bool, error) {
+	result, err := fn(WithKey(ctx, key))
+	k.mu.Lock()
+	if err != nil || result == nil || result.IsError {
+		delete(k.calls, key)
+	} else {
+		c.result = result
+		c.expires = k.now().Add(k.Window)
+	}
+	k.mu.Unlock()
+	close(c.done)
+	return result, false, err
+}
+
+// expire forgets the results older than the window
+func (k *Keyer) expire() {
+	now := k.now()
+	for key, c := range k.calls {
+		if c.result != nil && now.After(c.expires) {
+			delete(k.calls, key)
+		}
+	}
+}
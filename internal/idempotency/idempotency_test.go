@@ -0,0 +1,116 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestKey(t *testing.T) {
+	k := New("", time.Minute)
+	a := k.Key(context.Background(), "post_orders", map[string]interface{}{"item": "book", "quantity": 1})
+	b := k.Key(context.Background(), "post_orders", map[string]interface{}{"quantity": 1, "item": "book"})
+	if a != b {
+		t.Errorf("keys of the same call differ: %s and %s", a, b)
+	}
+	if c := k.Key(context.Background(), "post_orders", map[string]interface{}{"item": "pen", "quantity": 1}); c == a {
+		t.Error("calls with other arguments got the same key")
+	}
+	if d := k.Key(context.Background(), "post_payments", map[string]interface{}{"item": "book", "quantity": 1}); d == a {
+		t.Error("calls of another tool got the same key")
+	}
+	if k.Header != DefaultHeader {
+		t.Errorf("header = %s, want %s", k.Header, DefaultHeader)
+	}
+}
+
+func TestDo(t *testing.T) {
+	now := time.Unix(0, 0)
+	k := New("", time.Minute)
+	k.now = func() time.Time { return now }
+
+	calls := 0
+	var keys []string
+	fn := func(ctx context.Context) (*mcp.CallToolResult, error) {
+		calls++
+		key, _ := KeyFromContext(ctx)
+		keys = append(keys, key)
+		return mcp.NewToolResultText("order 1"), nil
+	}
+
+	if _, retried, err := k.Do(context.Background(), "key", fn); err != nil || retried {
+		t.Fatalf("first call: retried %v, error %v", retried, err)
+	}
+	result, retried, err := k.Do(context.Background(), "key", fn)
+	if err != nil || !retried || result.Content[0].(mcp.TextContent).Text != "order 1" {
+		t.Errorf("retry = %+v, %v, %v, want the first result", result, retried, err)
+	}
+	if calls != 1 || keys[0] != "key" {
+		t.Errorf("calls = %d with keys %v, want 1 with the key", calls, keys)
+	}
+
+	// Once the window passed, the call is made again
+	now = now.Add(2 * time.Minute)
+	if _, retried, _ := k.Do(context.Background(), "key", fn); retried || calls != 2 {
+		t.Errorf("call after the window: retried %v, calls %d", retried, calls)
+	}
+}
+
+func TestDoForgetsFailures(t *testing.T) {
+	k := New("", time.Minute)
+	calls := 0
+	fn := func(ctx context.Context) (*mcp.CallToolResult, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("upstream unavailable")
+		}
+		return mcp.NewToolResultText("ok"), nil
+	}
+	if _, _, err := k.Do(context.Background(), "key", fn); err == nil {
+		t.Fatal("expected the first call to fail")
+	}
+	if _, retried, err := k.Do(context.Background(), "key", fn); err != nil || retried || calls != 2 {
+		t.Errorf("retry of a failed call: retried %v, error %v, calls %d", retried, err, calls)
+	}
+}
+
+func TestDoWaitsForCallInFlight(t *testing.T) {
+	k := New("", time.Minute)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	fn := func(ctx context.Context) (*mcp.CallToolResult, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		close(started)
+		<-release
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		k.Do(context.Background(), "key", fn)
+	}()
+	<-started
+
+	retried := make(chan bool, 1)
+	go func() {
+		_, r, _ := k.Do(context.Background(), "key", fn)
+		retried <- r
+	}()
+	close(release)
+	<-done
+	if !<-retried {
+		t.Error("concurrent retry made the call again, want it to wait for the first")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,49 @@
+// Package logging carries zap loggers through contexts, so that everything
+// logged while handling an MCP session or tool call shares its fields
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+// contextKey is the context key of the logger
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or zap's global logger if
+// there is none
+func FromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.L()
+}
+
+// With returns a copy of ctx whose logger has the given fields added, and that
+// logger
+func With(ctx context.Context, fields ...zap.Field) (context.Context, *zap.Logger) {
+	logger := FromContext(ctx).With(fields...)
+	return WithLogger(ctx, logger), logger
+}
+
+// Component returns a logger for a component of mcprox, e.g. "serve"
+func Component(logger *zap.Logger, name string) *zap.Logger {
+	return logger.Named(name)
+}
+
+// NewRequestID returns a random identifier for correlating the logs of a request
+func NewRequestID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWith(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := WithLogger(context.Background(), Component(zap.New(core), "serve"))
+
+	ctx, _ = With(ctx, zap.String("session_id", "abc"))
+	_, logger := With(ctx, zap.String("tool", "get_pets"))
+	logger.Info("Calling tool")
+	FromContext(ctx).Info("Session message")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 log entries, got %d", len(entries))
+	}
+	if entries[0].LoggerName != "serve" {
+		t.Errorf("Expected logger name serve, got %q", entries[0].LoggerName)
+	}
+	fields := entries[0].ContextMap()
+	if fields["session_id"] != "abc" || fields["tool"] != "get_pets" {
+		t.Errorf("Expected session and tool fields, got %v", fields)
+	}
+	if _, ok := entries[1].ContextMap()["tool"]; ok {
+		t.Error("Expected the tool field to stay on the tool call's logger")
+	}
+}
+
+func TestFromContextFallback(t *testing.T) {
+	if FromContext(context.Background()) != zap.L() {
+		t.Error("Expected the global logger without a logger in the context")
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	if len(a) != 16 || a == b {
+		t.Errorf("Expected distinct 16 character IDs, got %q and %q", a, b)
+	}
+}
@@ -5,8 +5,12 @@ package mcp
 import (
 	"context"
 
+	"github.com/berkantay/mcprox/internal/downstream"
+	"github.com/berkantay/mcprox/internal/export"
 	"github.com/berkantay/mcprox/internal/mcp/generator"
+	"github.com/berkantay/mcprox/internal/openapi"
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/server"
 	"go.uber.org/zap"
 )
 
@@ -24,7 +28,57 @@ type Generator struct {
 	gen *generator.Generator
 }
 
+// SetSpecSources records the OpenAPI documents the server is generated from
+func (g *Generator) SetSpecSources(specs []openapi.SpecSource) {
+	g.gen.SetSpecSources(specs)
+}
+
+// SetTarget sets the kind of server Generate writes
+func (g *Generator) SetTarget(target string) {
+	g.gen.SetTarget(target)
+}
+
 // Generate generates an MCP server from an OpenAPI spec
 func (g *Generator) Generate(ctx context.Context, doc *openapi3.T) error {
 	return g.gen.Generate(ctx, doc)
 }
+
+// BuildServer creates an MCP server calling the configured service directly
+func (g *Generator) BuildServer(doc *openapi3.T) (*server.MCPServer, error) {
+	return g.gen.BuildServer(doc)
+}
+
+// ReloadServer replaces the tools of a server BuildServer created with those
+// of doc, notifying connected clients
+func (g *Generator) ReloadServer(s *server.MCPServer, doc *openapi3.T) error {
+	return g.gen.ReloadServer(s, doc)
+}
+
+// ServedTools returns the tools of the last server built, sorted by name
+func (g *Generator) ServedTools() []server.ServerTool {
+	return g.gen.ServedTools()
+}
+
+// ToolOperation returns the upstream operation a served tool calls, if it is
+// a generated tool
+func (g *Generator) ToolOperation(name string) (openapi.Operation, bool) {
+	return g.gen.ToolOperation(name)
+}
+
+// ExportedTools returns the tools of the last server built, sorted by name,
+// with the upstream operations they call
+func (g *Generator) ExportedTools() []export.Tool {
+	return g.gen.ExportedTools()
+}
+
+// UseDownstream adds the tools of MCP servers to the servers BuildServer
+// creates
+func (g *Generator) UseDownstream(servers ...*downstream.Server) {
+	g.gen.UseDownstream(servers...)
+}
+
+// UseToolMiddleware adds middlewares around the tool calls of the servers
+// BuildServer creates, the first one outermost
+func (g *Generator) UseToolMiddleware(middlewares ...generator.ToolMiddleware) {
+	g.gen.UseToolMiddleware(middlewares...)
+}
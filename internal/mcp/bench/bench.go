@@ -0,0 +1,247 @@
+// Package bench measures the overhead of serving OpenAPI operations as MCP
+// tools by replaying synthetic tool calls against a stub upstream
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// stubResponse is the body the stub upstream answers every request with
+var stubResponse = []byte(`{"id":1,"name":"stub","status":"ok","tags":["bench"]}`)
+
+// NewStubUpstream starts an HTTP server answering every request with the same
+// small JSON document, standing in for the proxied service
+func NewStubUpstream() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(stubResponse)
+	}))
+}
+
+// Options configures a benchmark
+type Options struct {
+	// Calls is the number of measured tool calls
+	Calls int
+	// Concurrency is the number of calls in flight at once
+	Concurrency int
+	// Warmup is the number of unmeasured calls made first
+	Warmup int
+	// Tool restricts the calls to one tool; all tools are called in turn if empty
+	Tool string
+	// Arguments are the arguments of Tool; synthesized from its schema if nil
+	Arguments map[string]interface{}
+}
+
+// Result summarizes a benchmark
+type Result struct {
+	Calls    int
+	Errors   int
+	Tools    int
+	Duration time.Duration
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+	// AllocsPerCall and BytesPerCall are the heap allocations per call, the
+	// stub upstream's included
+	AllocsPerCall uint64
+	BytesPerCall  uint64
+}
+
+// CallsPerSecond is the throughput of the benchmark
+func (r *Result) CallsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Calls) / r.Duration.Seconds()
+}
+
+// Run replays synthetic tools/call requests through the JSON-RPC handling of
+// an MCP server, as its transports do, and measures their latency and
+// allocations. The server's tools are expected to call a stub upstream.
+func Run(ctx context.Context, s *server.MCPServer, opts Options) (*Result, error) {
+	if opts.Calls <= 0 {
+		return nil, errors.New("the number of calls must be positive")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	requests, err := callRequests(ctx, s, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < opts.Warmup; i++ {
+		if err := call(ctx, s, requests[i%len(requests)]); err != nil {
+			return nil, fmt.Errorf("warmup call failed: %w", err)
+		}
+	}
+
+	latencies := make([]time.Duration, opts.Calls)
+	failed := make([]bool, opts.Calls)
+	next := make(chan int)
+	var wg sync.WaitGroup
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				callStart := time.Now()
+				failed[i] = call(ctx, s, requests[i%len(requests)]) != nil
+				latencies[i] = time.Since(callStart)
+			}
+		}()
+	}
+	for i := 0; i < opts.Calls && ctx.Err() == nil; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	duration := time.Since(start)
+	runtime.ReadMemStats(&after)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Calls:         opts.Calls,
+		Tools:         len(requests),
+		Duration:      duration,
+		AllocsPerCall: (after.Mallocs - before.Mallocs) / uint64(opts.Calls),
+		BytesPerCall:  (after.TotalAlloc - before.TotalAlloc) / uint64(opts.Calls),
+	}
+	for _, f := range failed {
+		if f {
+			result.Errors++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = percentile(latencies, 50)
+	result.P95 = percentile(latencies, 95)
+	result.P99 = percentile(latencies, 99)
+	result.Max = latencies[len(latencies)-1]
+	return result, nil
+}
+
+// percentile returns the p-th percentile of sorted latencies, by the nearest
+// rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// callRequests returns the encoded tools/call requests to replay
+func callRequests(ctx context.Context, s *server.MCPServer, opts Options) ([]json.RawMessage, error) {
+	response := s.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":0,"method":"tools/list"}`))
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Result mcp.ListToolsResult `json:"result"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	var requests []json.RawMessage
+	for _, tool := range list.Result.Tools {
+		if opts.Tool != "" && tool.Name != opts.Tool {
+			continue
+		}
+		args := opts.Arguments
+		if args == nil {
+			args = SyntheticArguments(tool.InputSchema)
+		}
+		request, err := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      len(requests) + 1,
+			"method":  "tools/call",
+			"params":  map[string]interface{}{"name": tool.Name, "arguments": args},
+		})
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	if len(requests) == 0 {
+		if opts.Tool != "" {
+			return nil, fmt.Errorf("the server has no tool %q", opts.Tool)
+		}
+		return nil, errors.New("the server has no tools")
+	}
+	return requests, nil
+}
+
+// call sends one request to the server, encodes the response as transports
+// do and reports a JSON-RPC or tool error
+func call(ctx context.Context, s *server.MCPServer, request json.RawMessage) error {
+	message := s.HandleMessage(ctx, request)
+	if _, err := json.Marshal(message); err != nil {
+		return err
+	}
+	switch response := message.(type) {
+	case mcp.JSONRPCError:
+		return errors.New(response.Error.Message)
+	case mcp.JSONRPCResponse:
+		if result, ok := response.Result.(*mcp.CallToolResult); ok && result.IsError {
+			return errors.New("tool returned an error")
+		}
+	}
+	return nil
+}
+
+// SyntheticArguments returns arguments satisfying the required properties of
+// a tool's input schema, using enum values and defaults where present
+func SyntheticArguments(schema mcp.ToolInputSchema) map[string]interface{} {
+	args := make(map[string]interface{}, len(schema.Required))
+	for _, name := range schema.Required {
+		property, _ := schema.Properties[name].(map[string]interface{})
+		args[name] = syntheticValue(property)
+	}
+	return args
+}
+
+// syntheticValue returns a value valid for a JSON schema
+func syntheticValue(schema map[string]interface{}) interface{} {
+	if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+		return values[0]
+	}
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+	switch schema["type"] {
+	case "integer", "number":
+		return 1
+	case "boolean":
+		return true
+	case "array":
+		return []interface{}{}
+	case "object":
+		return map[string]interface{}{}
+	default:
+		return "1"
+	}
+}
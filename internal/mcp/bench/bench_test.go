@@ -0,0 +1,100 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestRun(t *testing.T) {
+	upstream := NewStubUpstream()
+	defer upstream.Close()
+
+	s := server.NewMCPServer("Test", "1.0.0")
+	s.AddTool(mcp.NewTool("get_item", mcp.WithNumber("id", mcp.Required())), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, ok := request.Params.Arguments["id"]; !ok {
+			return nil, io.ErrUnexpectedEOF
+		}
+		resp, err := http.Get(upstream.URL)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(string(body)), nil
+	})
+	s.AddTool(mcp.NewTool("broken"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, io.EOF
+	})
+
+	result, err := Run(context.Background(), s, Options{Calls: 50, Concurrency: 4, Warmup: 5, Tool: "get_item"})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Calls != 50 || result.Tools != 1 || result.Errors != 0 {
+		t.Errorf("Run() = %+v, want 50 calls of 1 tool without errors", result)
+	}
+	if result.P50 <= 0 || result.P50 > result.P95 || result.P95 > result.P99 || result.P99 > result.Max {
+		t.Errorf("Run() latencies p50 %s, p95 %s, p99 %s, max %s aren't ordered", result.P50, result.P95, result.P99, result.Max)
+	}
+	if result.AllocsPerCall == 0 || result.CallsPerSecond() <= 0 {
+		t.Errorf("Run() = %+v, want allocations and throughput measured", result)
+	}
+
+	if _, err := Run(context.Background(), s, Options{Calls: 10, Warmup: 1, Tool: "broken"}); err == nil {
+		t.Error("Run() error = nil, want the failed warmup call reported")
+	}
+	result, err = Run(context.Background(), s, Options{Calls: 10, Tool: "broken"})
+	if err != nil || result.Errors != 10 {
+		t.Errorf("Run() = %+v, %v, want 10 errors counted", result, err)
+	}
+	if _, err := Run(context.Background(), s, Options{Calls: 10, Tool: "missing"}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Run() error = %v, want the missing tool reported", err)
+	}
+	if _, err := Run(context.Background(), s, Options{}); err == nil {
+		t.Error("Run() error = nil, want an error for zero calls")
+	}
+}
+
+func TestSyntheticArguments(t *testing.T) {
+	schema := mcp.ToolInputSchema{
+		Type: "object",
+		Properties: map[string]interface{}{
+			"id":     map[string]interface{}{"type": "integer"},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"sold", "available"}},
+			"limit":  map[string]interface{}{"type": "integer", "default": 20},
+			"tags":   map[string]interface{}{"type": "array"},
+			"name":   map[string]interface{}{"type": "string"},
+		},
+		Required: []string{"id", "status", "limit", "tags"},
+	}
+	want := map[string]interface{}{"id": 1, "status": "sold", "limit": 20, "tags": []interface{}{}}
+	if got := SyntheticArguments(schema); !reflect.DeepEqual(got, want) {
+		t.Errorf("SyntheticArguments() = %v, want %v", got, want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i))
+	}
+	for p, want := range map[int]time.Duration{50: 50, 95: 95, 99: 99, 100: 100} {
+		if got := percentile(latencies, p); got != want {
+			t.Errorf("percentile(%d) = %d, want %d", p, got, want)
+		}
+	}
+	if got := percentile(latencies[:1], 50); got != 1 {
+		t.Errorf("percentile() of one latency = %d, want 1", got)
+	}
+}
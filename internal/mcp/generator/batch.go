@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// BatchTool is the name of the tool making several tool calls at once
+const BatchTool = "batch_execute"
+
+// batchCall is a call of a batch
+type batchCall struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// batchResult is the outcome of a call of a batch
+type batchResult struct {
+	Tool   string      `json:"tool"`
+	OK     bool        `json:"ok"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// addBatch adds the batch tool to the server if batch.enabled is set. It
+// calls the other tools through their middlewares, so each call of a batch is
+// authorized, limited and recorded like a call of its own.
+func (g *Generator) addBatch(s *server.MCPServer) {
+	if !config.GetBool("batch.enabled") {
+		return
+	}
+	if _, ok := g.served[BatchTool]; ok {
+		g.logger.Warn("A tool is already named "+BatchTool+"; not adding the batch tool", zap.String("tool", BatchTool))
+		return
+	}
+	maxCalls := config.GetInt("batch.max_calls")
+	concurrency := max(config.GetInt("batch.concurrency"), 1)
+
+	tool := mcp.NewTool(BatchTool,
+		mcp.WithDescription(fmt.Sprintf("Make several calls of the other tools at once, e.g. for bulk lookups, and return their results in order. At most %d calls per batch.", maxCalls)),
+		mcp.WithArray("calls",
+			mcp.Required(),
+			mcp.Description("Calls to make, each with the name of a tool and its arguments"),
+			mcp.MinItems(1),
+			mcp.MaxItems(maxCalls),
+			mcp.Items(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"tool":      map[string]interface{}{"type": "string", "description": "Name of the tool"},
+					"arguments": map[string]interface{}{"type": "object", "description": "Arguments of the tool"},
+				},
+				"required": []string{"tool"},
+			}),
+		),
+		mcp.WithBoolean("parallel", mcp.Description(fmt.Sprintf("Make up to %d calls at a time instead of one after the other; only for calls that don't depend on each other", concurrency))),
+	)
+	g.addTool(s, tool, g.batchHandler(g.served, maxCalls, concurrency))
+}
+
+// batchHandler returns the handler of the batch tool, calling the tools
+// served, which it keeps, with their middlewares
+func (g *Generator) batchHandler(served map[string]server.ServerTool, maxCalls, concurrency int) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls, err := batchCalls(request.Params.Arguments["calls"])
+		if err != nil {
+			return nil, err
+		}
+		if len(calls) > maxCalls {
+			return nil, fmt.Errorf("a batch has at most %d calls, got %d", maxCalls, len(calls))
+		}
+		parallel, _ := request.Params.Arguments["parallel"].(bool)
+		slots := make(chan struct{}, 1)
+		if parallel {
+			slots = make(chan struct{}, concurrency)
+		}
+		logging.FromContext(ctx).Debug("Running batch", zap.Int("calls", len(calls)), zap.Bool("parallel", parallel))
+
+		results := make([]batchResult, len(calls))
+		var wg sync.WaitGroup
+		for i, call := range calls {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(i int, call batchCall) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				results[i] = runBatchCall(ctx, served, call)
+			}(i, call)
+		}
+		wg.Wait()
+
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+// batchCalls decodes the calls argument of the batch tool
+func batchCalls(value interface{}) ([]batchCall, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var calls []batchCall
+	if err := json.Unmarshal(data, &calls); err != nil || len(calls) == 0 {
+		return nil, fmt.Errorf("calls must be a list of objects with a tool and its arguments")
+	}
+	return calls, nil
+}
+
+// runBatchCall makes a call of a batch
+func runBatchCall(ctx context.Context, served map[string]server.ServerTool, call batchCall) batchResult {
+	outcome := batchResult{Tool: call.Tool}
+	tool, ok := served[call.Tool]
+	if !ok || call.Tool == BatchTool {
+		outcome.Error = fmt.Sprintf("no tool %q", call.Tool)
+		return outcome
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = call.Tool
+	request.Params.Arguments = call.Arguments
+	if request.Params.Arguments == nil {
+		request.Params.Arguments = map[string]interface{}{}
+	}
+	result, err := tool.Handler(ctx, request)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	text := resultText(result)
+	if result.IsError {
+		outcome.Error = text
+		return outcome
+	}
+
+	// JSON responses are nested as they are rather than as strings
+	outcome.OK = true
+	outcome.Result = text
+	if json.Valid([]byte(text)) {
+		outcome.Result = json.RawMessage(text)
+	}
+	return outcome
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestBatchTool(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/owners" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"name":"Rex"}]`))
+	}))
+	defer upstream.Close()
+	viper.Set("service.url", upstream.URL)
+	viper.Set("batch.enabled", true)
+	viper.Set("batch.max_calls", 3)
+	viper.Set("batch.concurrency", 2)
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List pets", Responses: openapi3.NewResponses()}})
+	doc.Paths.Set("/owners", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List owners", Responses: openapi3.NewResponses()}})
+
+	// Middlewares see each call of a batch
+	var mu sync.Mutex
+	var called []string
+	g := New(zap.NewNop())
+	g.UseToolMiddleware(ToolMiddlewareFunc(func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if request.Params.Name != BatchTool {
+				mu.Lock()
+				called = append(called, request.Params.Name)
+				mu.Unlock()
+			}
+			return next(ctx, request)
+		}
+	}))
+	s, err := g.BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(arguments string) mcp.JSONRPCMessage {
+		return s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"batch_execute","arguments":`+arguments+`}}`))
+	}
+	response, ok := call(`{"calls":[{"tool":"get_pets"},{"tool":"get_owners"},{"tool":"delete_pets","arguments":{}}]}`).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("batch failed")
+	}
+	var results []batchResult
+	if err := json.Unmarshal([]byte(resultText(response.Result.(*mcp.CallToolResult))), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %+v, want 3", results)
+	}
+	if !results[0].OK || results[0].Tool != "get_pets" {
+		t.Errorf("first result = %+v, want get_pets succeeding", results[0])
+	}
+	if pets, ok := results[0].Result.([]interface{}); !ok || len(pets) != 1 {
+		t.Errorf("first result = %#v, want the JSON response nested", results[0].Result)
+	}
+	if results[1].OK || results[1].Error == "" {
+		t.Errorf("second result = %+v, want the upstream error", results[1])
+	}
+	if results[2].OK || results[2].Error != `no tool "delete_pets"` {
+		t.Errorf("third result = %+v, want an unknown tool", results[2])
+	}
+	if len(called) != 2 {
+		t.Errorf("middleware saw %v, want the calls of both tools", called)
+	}
+
+	// Parallel batches return their results in order too
+	response, ok = call(`{"calls":[{"tool":"get_pets"},{"tool":"get_owners"}],"parallel":true}`).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("parallel batch failed")
+	}
+	results = nil
+	json.Unmarshal([]byte(resultText(response.Result.(*mcp.CallToolResult))), &results)
+	if len(results) != 2 || results[0].Tool != "get_pets" || results[1].Tool != "get_owners" {
+		t.Errorf("parallel results = %+v, want them in order", results)
+	}
+
+	if _, ok := call(`{"calls":[{"tool":"get_pets"},{"tool":"get_pets"},{"tool":"get_pets"},{"tool":"get_pets"}]}`).(mcp.JSONRPCError); !ok {
+		t.Error("batch beyond batch.max_calls succeeded, want an error")
+	}
+	if _, ok := call(`{"calls":[{"tool":"batch_execute","arguments":{}}]}`).(mcp.JSONRPCResponse); !ok {
+		t.Error("nested batch failed the batch, want an error result for its call")
+	}
+}
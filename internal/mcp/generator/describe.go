@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/transform"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// schemaRefPattern matches references to component schemas in marshaled JSON
+var schemaRefPattern = regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`)
+
+// operationDetails is the full description of an operation returned by the
+// describe_operation meta tool
+type operationDetails struct {
+	Name        string                         `json:"name"`
+	Method      string                         `json:"method"`
+	Path        string                         `json:"path"`
+	Summary     string                         `json:"summary,omitempty"`
+	Description string                         `json:"description,omitempty"`
+	Parameters  []parameterDetails             `json:"parameters"`
+	RequestBody *openapi3.RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*openapi3.Response  `json:"responses,omitempty"`
+	Schemas     map[string]*openapi3.SchemaRef `json:"schemas,omitempty"`
+}
+
+// parameterDetails describes a single operation parameter and the tool argument it maps to
+type parameterDetails struct {
+	Name        string              `json:"name"`
+	Argument    string              `json:"argument"`
+	In          string              `json:"in"`
+	Required    bool                `json:"required"`
+	Description string              `json:"description,omitempty"`
+	Schema      *openapi3.SchemaRef `json:"schema,omitempty"`
+	Example     interface{}         `json:"example,omitempty"`
+	Examples    openapi3.Examples   `json:"examples,omitempty"`
+}
+
+// describeOperation renders the parameter, request body and response schemas of
+// an operation as JSON, including every component schema they reference.
+// Parameters hidden by the tool's request rule are left out.
+func describeOperation(doc *openapi3.T, span operationSpan, rule transform.RequestRule) (string, error) {
+	op := span.op
+	details := operationDetails{
+		Name:        span.toolID,
+		Method:      span.method,
+		Path:        span.path,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Parameters:  []parameterDetails{},
+	}
+
+	argNames := resolveParamNames(op, rule)
+	for _, paramRef := range op.Parameters {
+		if paramRef == nil || paramRef.Value == nil || rule.Hidden(paramRef.Value.Name) {
+			continue
+		}
+		param := paramRef.Value
+		details.Parameters = append(details.Parameters, parameterDetails{
+			Name:        param.Name,
+			Argument:    argNames[param],
+			In:          param.In,
+			Required:    param.Required,
+			Description: param.Description,
+			Schema:      param.Schema,
+			Example:     param.Example,
+			Examples:    param.Examples,
+		})
+	}
+
+	if op.RequestBody != nil {
+		details.RequestBody = op.RequestBody.Value
+	}
+
+	if op.Responses != nil {
+		details.Responses = make(map[string]*openapi3.Response)
+		for status, respRef := range op.Responses.Map() {
+			if respRef != nil && respRef.Value != nil {
+				details.Responses[status] = respRef.Value
+			}
+		}
+	}
+
+	// Pull in referenced component schemas until no new references show up
+	for {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal details of %s: %w", span.toolID, err)
+		}
+
+		var missing []string
+		for _, match := range schemaRefPattern.FindAllStringSubmatch(string(data), -1) {
+			name := strings.NewReplacer("~1", "/", "~0", "~").Replace(match[1])
+			if _, seen := details.Schemas[name]; !seen && doc.Components != nil && doc.Components.Schemas[name] != nil {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 {
+			return string(data), nil
+		}
+
+		sort.Strings(missing)
+		if details.Schemas == nil {
+			details.Schemas = make(map[string]*openapi3.SchemaRef)
+		}
+		for _, name := range missing {
+			details.Schemas[name] = doc.Components.Schemas[name]
+		}
+	}
+}
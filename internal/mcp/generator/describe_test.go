@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/transform"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestDescribeOperationSchemaClosure(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "Shop", "version": "1.0.0"},
+		"paths": {"/orders/{id}": {"get": {
+			"parameters": [
+				{"name": "id", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/OrderID"}},
+				{"name": "debug", "in": "query", "schema": {"$ref": "#/components/schemas/Debug"}}
+			],
+			"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Order"}}}}}
+		}}},
+		"components": {"schemas": {
+			"OrderID": {"type": "string"},
+			"Debug": {"type": "boolean"},
+			"Order": {"type": "object", "properties": {"customer": {"$ref": "#/components/schemas/Customer"}}},
+			"Customer": {"type": "object", "properties": {
+				"address": {"$ref": "#/components/schemas/Address"},
+				"orders": {"type": "array", "items": {"$ref": "#/components/schemas/Order"}}
+			}},
+			"Address": {"type": "object", "properties": {"country": {"$ref": "#/components/schemas/Country"}}},
+			"Country": {"type": "string", "enum": ["NL", "TR"]},
+			"Unused": {"type": "string"}
+		}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Paths.Value("/orders/{id}").Get
+	span := operationSpan{toolID: "get_orders_id", method: "GET", path: "/orders/{id}", op: op}
+
+	// The debug parameter is hidden, so its schema isn't pulled in
+	rule := transform.RequestRule{Drop: []string{"debug"}}
+	data, err := describeOperation(doc, span, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var details struct {
+		Name       string                     `json:"name"`
+		Parameters []parameterDetails         `json:"parameters"`
+		Schemas    map[string]json.RawMessage `json:"schemas"`
+	}
+	if err := json.Unmarshal([]byte(data), &details); err != nil {
+		t.Fatalf("invalid details %s: %v", data, err)
+	}
+	if details.Name != "get_orders_id" || len(details.Parameters) != 1 || details.Parameters[0].Name != "id" {
+		t.Errorf("details = %s, want get_orders_id with the id parameter", data)
+	}
+
+	// The chain Order, Customer, Address, Country is followed to its end, and
+	// the cycle back from Customer to Order ends it
+	var names []string
+	for name := range details.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "Address,Country,Customer,Order,OrderID" {
+		t.Errorf("schemas = %s, want Address,Country,Customer,Order,OrderID", got)
+	}
+	if country := string(details.Schemas["Country"]); !strings.Contains(country, `"enum":["NL","TR"]`) {
+		t.Errorf("Country = %s, want its enum", country)
+	}
+	if customer := string(details.Schemas["Customer"]); !strings.Contains(customer, `"$ref":"#/components/schemas/Order"`) {
+		t.Errorf("Customer = %s, want the reference back to Order kept", customer)
+	}
+}
@@ -0,0 +1,28 @@
+package generator
+
+import (
+	"github.com/berkantay/mcprox/internal/downstream"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// UseDownstream adds the tools of MCP servers to the servers BuildServer
+// creates, under the namespaces of the servers
+func (g *Generator) UseDownstream(servers ...*downstream.Server) {
+	g.downstreams = append(g.downstreams, servers...)
+}
+
+// addDownstreamTools adds the tools of the downstream servers to a server,
+// except those named like a generated tool
+func (g *Generator) addDownstreamTools(s *server.MCPServer) {
+	for _, downstreamServer := range g.downstreams {
+		for _, tool := range downstreamServer.Tools() {
+			if _, ok := g.served[tool.Tool.Name]; ok {
+				g.logger.Warn("A tool is already named like a downstream tool; not adding it",
+					zap.String("tool", tool.Tool.Name), zap.String("downstream", downstreamServer.Name))
+				continue
+			}
+			g.addTool(s, tool.Tool, tool.Handler)
+		}
+	}
+}
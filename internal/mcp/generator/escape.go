@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pyString renders s as a double-quoted Python string literal. Every string that
+// comes from the OpenAPI document must go through this (or pyDocstring) before
+// being embedded in generated code.
+func pyString(s string) string {
+	return `"` + escapePython(s, true) + `"`
+}
+
+// pyDocstring escapes s for use between triple double quotes. Newlines and tabs
+// are kept so multi-line descriptions stay readable in the generated source.
+func pyDocstring(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return escapePython(s, false)
+}
+
+// pyValue renders a configured value as the equivalent Python literal.
+// Strings, booleans and null are written as such, anything else is decoded
+// from JSON.
+func pyValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "None", nil
+	case bool:
+		if v {
+			return "True", nil
+		}
+		return "False", nil
+	case string:
+		return pyString(v), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("json.loads(%s)", pyString(string(data))), nil
+}
+
+// escapePython escapes backslashes, double quotes and control characters so the
+// result can't terminate the surrounding literal or change its meaning
+func escapePython(s string, escapeNewlines bool) string {
+	var sb strings.Builder
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\n' && escapeNewlines:
+			sb.WriteString(`\n`)
+		case r == '\t' && escapeNewlines:
+			sb.WriteString(`\t`)
+		case r == '\n' || r == '\t':
+			sb.WriteRune(r)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\x%02x`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// hostileStrings are spec values that used to break the generated Python
+var hostileStrings = []string{
+	`plain description`,
+	`triple """ quotes`,
+	`ends with a quote"`,
+	`trailing backslash \`,
+	`{braces} and f"{strings}" and %s %d`,
+	"multi\nline\r\ndescription\twith tabs",
+	"control \x00 \x07 \x1b characters",
+	`''' single triple quotes '''`,
+	"invalid utf-8 \xff\xfe",
+	`unicode ✓ 日本語`,
+}
+
+func FuzzPyString(f *testing.F) {
+	for _, s := range hostileStrings {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		literal := pyString(s)
+		if strings.ContainsAny(literal, "\n\r") {
+			t.Fatalf("Expected single-line literal, got %q", literal)
+		}
+
+		// The escapes produced are a subset of Go's, so the literal must round-trip
+		got, err := strconv.Unquote(literal)
+		if err != nil {
+			t.Fatalf("Expected valid literal for %q, got error: %v", s, err)
+		}
+		if expected := strings.ToValidUTF8(s, "\uFFFD"); got != expected {
+			t.Errorf("Expected %q after round trip, got %q", expected, got)
+		}
+	})
+}
+
+func FuzzPyDocstring(f *testing.F) {
+	for _, s := range hostileStrings {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		escaped := pyDocstring(s)
+		if strings.Contains(escaped, `"""`) {
+			t.Fatalf("Expected no unescaped triple quotes, got %q", escaped)
+		}
+
+		quoted := `"` + strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(escaped) + `"`
+		got, err := strconv.Unquote(quoted)
+		if err != nil {
+			t.Fatalf("Expected valid docstring for %q, got error: %v", s, err)
+		}
+		expected := strings.ReplaceAll(strings.ToValidUTF8(s, "\uFFFD"), "\r\n", "\n")
+		if got != expected {
+			t.Errorf("Expected %q after round trip, got %q", expected, got)
+		}
+	})
+}
+
+func FuzzToolDefinition(f *testing.F) {
+	python := findPython()
+	for _, s := range hostileStrings {
+		f.Add(s, s, s)
+	}
+
+	f.Fuzz(func(t *testing.T, summary, paramName, path string) {
+		if python == "" {
+			t.Skip("Python interpreter not available")
+		}
+
+		op := &openapi3.Operation{
+			Summary: summary,
+			Parameters: openapi3.Parameters{
+				{Value: &openapi3.Parameter{Name: paramName, In: "query", Schema: openapi3.NewStringSchema().NewRef()}},
+				{Value: &openapi3.Parameter{Name: paramName, In: "header", Schema: openapi3.NewStringSchema().NewRef()}},
+			},
+			RequestBody: &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody()},
+		}
+
+		tb := NewToolBuilder()
+		tb.WriteCreateMCPServer(summary)
+		tb.WriteToolDefinition("/"+path, "POST", op)
+
+		file := filepath.Join(t.TempDir(), "server.py")
+		if err := os.WriteFile(file, []byte(tb.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := exec.Command(python, "-c", syntaxCheckScript, file).CombinedOutput()
+		if err != nil {
+			t.Errorf("Expected generated code to compile, got %s\n%s", out, tb.String())
+		}
+	})
+}
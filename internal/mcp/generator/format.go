@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"go.uber.org/zap"
+)
+
+// pythonLine is a line of Python code with the lexical state it starts in
+type pythonLine struct {
+	text string
+	// inString reports whether the line starts inside a triple-quoted string
+	inString bool
+	// logical reports whether the line starts a logical line, outside of
+	// strings, brackets and backslash continuations
+	logical bool
+	// endsInString reports whether the line ends inside a triple-quoted string
+	endsInString bool
+}
+
+// scanPython splits code into lines, tracking the strings and brackets that
+// span several of them
+func scanPython(code string) []pythonLine {
+	var lines []pythonLine
+	triple := ""
+	depth := 0
+	continued := false
+	for _, text := range strings.Split(code, "\n") {
+		line := pythonLine{text: text, inString: triple != "", logical: triple == "" && depth == 0 && !continued}
+		continued = false
+		for i := 0; i < len(text); i++ {
+			if triple != "" {
+				if text[i] == '\\' {
+					i++
+				} else if strings.HasPrefix(text[i:], triple) {
+					i += len(triple) - 1
+					triple = ""
+				}
+				continue
+			}
+			switch c := text[i]; c {
+			case '#':
+				i = len(text)
+			case '"', '\'':
+				if quotes := strings.Repeat(string(c), 3); strings.HasPrefix(text[i:], quotes) {
+					triple = quotes
+					i += 2
+					continue
+				}
+				for i++; i < len(text) && text[i] != c; i++ {
+					if text[i] == '\\' {
+						i++
+					}
+				}
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			case '\\':
+				continued = i == len(text)-1
+			}
+		}
+		line.endsInString = triple != ""
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// indentation returns the width of a line's leading whitespace
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// isDefinition reports whether a line starts a function or class definition
+// or decorates one
+func isDefinition(line string) bool {
+	code := strings.TrimSpace(line)
+	for _, prefix := range []string{"def ", "async def ", "class ", "@"} {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPython lays generated Python code out the way black does: without
+// trailing whitespace, with two blank lines around module-level definitions,
+// one before nested ones, and at most two consecutive blank lines at module
+// level and one in blocks. Strings are left untouched. It returns the code and
+// the line number of every input line in it, or of the next line kept for the
+// blank lines removed.
+func formatPython(code string) (string, []int) {
+	lines := scanPython(code)
+	isBlank := func(i int) bool {
+		return !lines[i].inString && strings.TrimSpace(lines[i].text) == ""
+	}
+	isComment := func(i int) bool {
+		return lines[i].logical && strings.HasPrefix(strings.TrimSpace(lines[i].text), "#")
+	}
+
+	// The blank lines required before a line, -1 where any are kept. A
+	// definition takes its comments along.
+	required := make([]int, len(lines))
+	for i := range required {
+		required[i] = -1
+	}
+	attach := func(i, blanks int) {
+		for i > 0 && isComment(i-1) && indentation(lines[i-1].text) == indentation(lines[i].text) {
+			i--
+		}
+		if blanks > required[i] {
+			required[i] = blanks
+		}
+	}
+	previous := ""    // the last logical line of code
+	lastCode := ""    // the last physical line of code, ending a block opener
+	inTopDef := false // within the body of a module-level definition
+	for i, line := range lines {
+		if !line.logical || isBlank(i) || isComment(i) {
+			if !isBlank(i) && !isComment(i) {
+				lastCode = strings.TrimSpace(line.text)
+			}
+			continue
+		}
+		code := strings.TrimSpace(line.text)
+		topLevel := indentation(line.text) == 0
+		switch {
+		case strings.HasPrefix(previous, "@"):
+			required[i] = 0
+		case isDefinition(line.text) && !strings.HasSuffix(lastCode, ":"):
+			if topLevel {
+				attach(i, 2)
+			} else {
+				attach(i, 1)
+			}
+		case topLevel && inTopDef:
+			attach(i, 2)
+		}
+		if topLevel {
+			inTopDef = isDefinition(line.text) && !strings.HasPrefix(code, "@")
+		}
+		previous, lastCode = code, code
+	}
+
+	var out []string
+	lineMap := make([]int, len(lines))
+	blanks := 0
+	var skipped []int
+	for i, line := range lines {
+		if isBlank(i) && line.logical {
+			blanks++
+			skipped = append(skipped, i)
+			continue
+		}
+		keep := blanks
+		if line.logical {
+			switch {
+			case len(out) == 0:
+				keep = 0
+			case required[i] >= 0:
+				keep = required[i]
+			case indentation(line.text) == 0:
+				keep = min(blanks, 2)
+			default:
+				keep = min(blanks, 1)
+			}
+		}
+		for j := 0; j < keep; j++ {
+			out = append(out, "")
+		}
+		for _, s := range skipped {
+			lineMap[s] = len(out) + 1
+		}
+		blanks, skipped = 0, skipped[:0]
+
+		text := line.text
+		if !line.endsInString {
+			text = strings.TrimRight(text, " \t")
+		}
+		out = append(out, text)
+		lineMap[i] = len(out)
+	}
+	for _, s := range skipped {
+		lineMap[s] = len(out)
+	}
+	return strings.Join(out, "\n") + "\n", lineMap
+}
+
+// Format lays the code built so far out with formatPython, keeping track of
+// the lines of the operations
+func (tb *ToolBuilder) Format() {
+	code, lineMap := formatPython(tb.builder.String())
+	tb.builder.Reset()
+	tb.builder.WriteString(code)
+	remap := func(line int) int {
+		if line < 1 || line > len(lineMap) {
+			return line
+		}
+		return lineMap[line-1]
+	}
+	for i := range tb.operations {
+		tb.operations[i].startLine = remap(tb.operations[i].startLine)
+		tb.operations[i].endLine = remap(tb.operations[i].endLine)
+	}
+}
+
+// runFormatter formats the Python files of the generated project with the
+// configured formatter. A formatter that isn't installed is skipped with a
+// warning, as generating doesn't depend on it.
+func (g *Generator) runFormatter(formatter string, deps utils.PythonDependencies) error {
+	var name string
+	var args []string
+	switch formatter {
+	case "", utils.FormatterNone:
+		return nil
+	case utils.FormatterAuto:
+		if _, err := exec.LookPath("ruff"); err == nil {
+			return g.runFormatter(utils.FormatterRuff, deps)
+		}
+		if _, err := exec.LookPath("black"); err == nil {
+			return g.runFormatter(utils.FormatterBlack, deps)
+		}
+		g.logger.Warn("Neither ruff nor black is installed, leaving the generated code unformatted")
+		return nil
+	case utils.FormatterRuff:
+		name = "ruff"
+		args = []string{"format", "--no-cache", "--line-length", "100", "--target-version", deps.TargetVersion(), "."}
+	case utils.FormatterBlack:
+		name = "black"
+		args = []string{"--quiet", "--line-length", "100", "--target-version", deps.TargetVersion(), "."}
+	default:
+		return fmt.Errorf("invalid formatter %q: must be one of %s", formatter, strings.Join(utils.Formatters, ", "))
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		g.logger.Warn("Formatter not installed, leaving the generated code unformatted", zap.String("formatter", name))
+		return nil
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Dir = g.outputDir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	g.logger.Debug("Formatted generated code", zap.String("formatter", name))
+	return nil
+}
+
+// formatPythonCode lays Python code out with formatPython
+func formatPythonCode(code string) string {
+	formatted, _ := formatPython(code)
+	return formatted
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"go.uber.org/zap"
+)
+
+func TestFormatPython(t *testing.T) {
+	tests := []struct {
+		name, code, want string
+	}{
+		{
+			name: "definitions",
+			code: "\n\nimport os   \nx = 1\ndef f():\n    return 1\ny = 2\n\n\n\n\nclass C:\n    a = 1\n    def m(self):\n\n\n        return 2\n",
+			want: "import os\nx = 1\n\n\ndef f():\n    return 1\n\n\ny = 2\n\n\nclass C:\n    a = 1\n\n    def m(self):\n\n        return 2\n",
+		},
+		{
+			name: "comments and decorators",
+			code: "x = 1\n# About f\n@mcp.tool()\n\ndef f():\n    pass\n",
+			want: "x = 1\n\n\n# About f\n@mcp.tool()\ndef f():\n    pass\n",
+		},
+		{
+			name: "strings and brackets",
+			code: "def f(\n    a,\n) -> str:\n    \"\"\"Doc   \n\n\n\n    def g(): \"\"\"\n    return a\nX = {\n\n    \"a\": 1,\n}\n",
+			want: "def f(\n    a,\n) -> str:\n    \"\"\"Doc   \n\n\n\n    def g(): \"\"\"\n    return a\n\n\nX = {\n\n    \"a\": 1,\n}\n",
+		},
+		{
+			name: "nested definitions",
+			code: "try:\n    class A:\n        pass\n    x = A()\n    def f():\n        pass\nexcept ImportError:\n    pass\n",
+			want: "try:\n    class A:\n        pass\n    x = A()\n\n    def f():\n        pass\nexcept ImportError:\n    pass\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := formatPython(tt.code)
+			if got != tt.want {
+				t.Errorf("formatPython() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if again, _ := formatPython(got); again != got {
+				t.Errorf("formatPython() isn't idempotent:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormatKeepsOperationLines(t *testing.T) {
+	tb := NewToolBuilder()
+	tb.builder.WriteString("x = 1\n\n\n\n\ndef f():\n    pass\n")
+	tb.operations = []operationSpan{{toolID: "f", startLine: 6, endLine: 7}}
+	tb.Format()
+	if op, ok := tb.operationAt(4); !ok || op.toolID != "f" {
+		t.Errorf("operationAt(4) = %v, %v, want f", op, ok)
+	}
+	if _, ok := tb.operationAt(2); ok {
+		t.Error("operationAt(2) found an operation in the blank lines")
+	}
+}
+
+func TestRunFormatter(t *testing.T) {
+	g := New(zap.NewNop(), t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	for _, formatter := range []string{"", utils.FormatterNone, utils.FormatterAuto, utils.FormatterBlack, utils.FormatterRuff} {
+		if err := g.runFormatter(formatter, utils.PythonDependencies{}); err != nil {
+			t.Errorf("runFormatter(%q) without formatters installed error = %v", formatter, err)
+		}
+	}
+	if err := g.runFormatter("yapf", utils.PythonDependencies{}); err == nil {
+		t.Error("expected an unknown formatter to be rejected")
+	}
+}
@@ -3,13 +3,34 @@ package generator
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/berkantay/mcprox/internal/auth"
+	"github.com/berkantay/mcprox/internal/canary"
 	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/download"
+	"github.com/berkantay/mcprox/internal/downstream"
+	"github.com/berkantay/mcprox/internal/export"
+	"github.com/berkantay/mcprox/internal/idempotency"
+	"github.com/berkantay/mcprox/internal/logging"
 	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/berkantay/mcprox/internal/offload"
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/berkantay/mcprox/internal/pii"
+	"github.com/berkantay/mcprox/internal/redact"
+	"github.com/berkantay/mcprox/internal/script"
+	"github.com/berkantay/mcprox/internal/tenant"
+	"github.com/berkantay/mcprox/internal/transform"
+	"github.com/berkantay/mcprox/internal/upload"
+	"github.com/berkantay/mcprox/internal/upstream"
+	"github.com/berkantay/mcprox/internal/webhook"
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"go.uber.org/zap"
 )
@@ -18,7 +39,71 @@ import (
 type Generator struct {
 	logger    *zap.Logger
 	outputDir string
-	document  *openapi3.T
+	// target is the kind of server Generate writes, see config.Targets
+	target   string
+	document *openapi3.T
+	// files lists the generated files relative to the project directory
+	files []string
+	// specs are the OpenAPI documents the server is generated from
+	specs    []openapi.SpecSource
+	metadata Metadata
+	// middlewares wrap the upstream requests of serve mode's tools
+	middlewares []upstream.Middleware
+	// toolMiddlewares wrap the calls of serve mode's tools
+	toolMiddlewares []ToolMiddleware
+	// transport sends the upstream requests of serve mode's tools
+	transport http.RoundTripper
+	// maxRedirects is how many redirects upstream requests follow
+	maxRedirects int
+	// redactor masks sensitive fields of upstream responses
+	redactor *redact.Redactor
+	// scrubber scans tool results for personal data, none if nil
+	scrubber pii.Scrubber
+	// transformer reshapes upstream responses with the configured expressions
+	transformer *transform.Transformer
+	// requestRules mutate the upstream requests of tools
+	requestRules transform.RequestRules
+	// selectFields adds the fields argument to GET tools
+	selectFields bool
+	// pagination presents the pagination parameters of every tool under the
+	// same arguments
+	pagination bool
+	// maxNameLength is the length tool IDs are shortened to, 0 for no limit
+	maxNameLength int
+	// tools and handlers are the generated tools of serve mode, by name,
+	// with their handlers before any middleware
+	tools    map[string]mcp.Tool
+	handlers map[string]server.ToolHandlerFunc
+	// operations are the upstream operations the served generated tools
+	// call, by name
+	operations map[string]openapi.Operation
+	// scripts define or override tools of serve mode
+	scripts []*script.Script
+	// downstreams are the MCP servers whose tools serve mode also serves
+	downstreams []*downstream.Server
+	// served are the tools of the last server built, by name, with their
+	// wrapped handlers
+	served map[string]server.ServerTool
+	// notifier posts an event after each tool call, none if nil
+	notifier *webhook.Notifier
+	// offload stores the responses too large to return in tool results as
+	// resources, and the files downloaded as resources
+	offload *offload.Store
+	// downloads saves the files tools download, none if nil
+	downloads *download.Downloader
+	// uploads reads the local files upload tools send, none if nil
+	uploads *upload.Uploader
+	// canary routes a share of the tool calls to another version of the API,
+	// none if nil
+	canary *canary.Router
+	// idempotency keys the upstream requests of POST tools and deduplicates
+	// their retried calls, none if nil
+	idempotency *idempotency.Keyer
+	// previous is the project regenerated in place, nil for a new one
+	previous *snapshot
+	// modules are the manifest entries of the modules regenerated
+	// incrementally
+	modules []ManifestFile
 }
 
 // New creates a new MCP generator
@@ -30,57 +115,432 @@ func New(logger *zap.Logger, outputDir ...string) *Generator {
 	}
 
 	return &Generator{
-		logger:    logger,
-		outputDir: dir,
+		logger:        logging.Component(logger, "generator"),
+		outputDir:     dir,
+		target:        config.GetString("generate.target"),
+		maxNameLength: config.GetInt("tools.max_name_length"),
 	}
 }
 
+// SetTarget sets the kind of server Generate writes, replacing the one
+// configured by generate.target
+func (g *Generator) SetTarget(target string) {
+	g.target = target
+}
+
+// SetSpecSources records the OpenAPI documents the server is generated from,
+// which are stamped into the generated project
+func (g *Generator) SetSpecSources(specs []openapi.SpecSource) {
+	g.specs = specs
+}
+
 // Generate generates an MCP server from an OpenAPI spec
 func (g *Generator) Generate(ctx context.Context, doc *openapi3.T) error {
 	g.logger.Info("Generating MCP server from OpenAPI documentation")
 
 	// Store the document in the generator
 	g.document = doc
+	g.metadata = newMetadata(g.specs)
 
-	folderName := strings.ToLower(strings.ReplaceAll(doc.Info.Title, " ", "_")) + "_mcp_server"
+	switch g.target {
+	case "", config.TargetPython:
+	case config.TargetNodeSingle, config.TargetCloudflareWorkers, config.TargetAWSLambda:
+		if config.GetBool("generate.langchain") {
+			g.logger.Warn("LangChain tools are only generated for the python target")
+		}
+		if _, err := g.BuildServer(doc); err != nil {
+			return err
+		}
+		switch g.target {
+		case config.TargetCloudflareWorkers:
+			return g.generateWorkers()
+		case config.TargetAWSLambda:
+			return g.generateLambda()
+		}
+		return g.generateNodeSingle()
+	default:
+		return fmt.Errorf("invalid target %q, expected one of %v", g.target, config.Targets)
+	}
+
+	// Fail on invalid dependency settings before touching the output directory
+	deps := pythonDependencies()
+	if err := deps.Validate(); err != nil {
+		return err
+	}
+	formatter := config.GetString("generate.formatter")
+	if !utils.ValidFormatter(formatter) {
+		return fmt.Errorf("invalid formatter %q: must be one of %s", formatter, strings.Join(utils.Formatters, ", "))
+	}
+	if deps.Locked {
+		for _, tool := range lockTools(deps) {
+			if _, err := findLockTool(tool); err != nil {
+				return err
+			}
+		}
+	}
+
+	folderName := utils.ProjectFolderName(doc.Info.Title)
 
 	// Set up project directory
 	projectDir := filepath.Join(g.outputDir, folderName)
 	g.outputDir = projectDir
 
+	// Don't clobber an existing project unless asked to
+	if err := g.prepareOutputDir(); err != nil {
+		return err
+	}
+
 	// Create project directory structure
 	if err := g.createProjectStructure(); err != nil {
 		return fmt.Errorf("failed to create project structure: %w", err)
 	}
 
-	// Create MCP server
-	mcpServer := server.NewMCPServer(
-		doc.Info.Title,
-		doc.Info.Version,
-	)
-
-	// Process paths into tools
-	if err := g.processPathsIntoTools(doc, mcpServer); err != nil {
+	// Build the MCP server the generated code mirrors
+	if _, err := g.BuildServer(doc); err != nil {
 		return err
 	}
+	for _, script := range g.scripts {
+		g.logger.Warn("Scripts only run in serve mode; the generated server keeps its own tool", zap.String("tool", script.Tool))
+	}
 
 	// Generate server code
 	serverPath := filepath.Join(g.outputDir, "src", "mcp_server.py")
 	if err := g.generateServerCode(serverPath); err != nil {
 		return fmt.Errorf("failed to generate server code: %w", err)
 	}
+	g.addFiles("src/mcp_server.py")
+
+	// Generate the LangChain tools wrapping the same upstream calls
+	if config.GetBool("generate.langchain") {
+		if err := g.generateLangChainTools(filepath.Join(g.outputDir, "src", "langchain_tools.py")); err != nil {
+			return err
+		}
+		g.addFiles("src/langchain_tools.py")
+	}
+
+	// Embed the OpenAPI documents the server is generated from
+	specFiles, specs, err := g.specFiles()
+	if err != nil {
+		return err
+	}
+	if err := g.writeFiles(specFiles); err != nil {
+		return err
+	}
 
 	// Generate project files
-	if err := g.generateProjectFiles(doc); err != nil {
+	if err := g.generateProjectFiles(doc, specs); err != nil {
 		return fmt.Errorf("failed to generate project files: %w", err)
 	}
 
+	// Hand the Python code to the configured formatter, if installed
+	if err := g.runFormatter(formatter, deps); err != nil {
+		return err
+	}
+
+	// Keep the time of the previous generation if the code didn't change
+	if err := g.keepGeneratedAt(); err != nil {
+		return fmt.Errorf("failed to keep the generation time: %w", err)
+	}
+
+	// Keep the hand edits of the functions whose generated code didn't change
+	if err := g.regenerateModule(serverModule); err != nil {
+		return fmt.Errorf("failed to regenerate %s: %w", serverModule, err)
+	}
+
+	// Record what the project was generated from
+	if err := writeMetadata(g.outputDir, g.metadata); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	g.addFiles(MetadataFileName)
+
+	// Record the generated files so that they can be cleaned up later
+	if err := writeManifest(g.outputDir, g.files, g.modules...); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	g.keepTimestamps()
+
 	g.logger.Info("Successfully generated MCP server project",
 		zap.String("project_dir", projectDir))
 
 	return nil
 }
 
+// UseUpstream adds middlewares around the upstream requests of the servers
+// BuildServer creates, between the built-in host guard and token manager
+func (g *Generator) UseUpstream(middlewares ...upstream.Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// SetScrubber sets the scrubber the tools of the servers BuildServer creates
+// scan their results with, replacing the one configured by pii.action
+func (g *Generator) SetScrubber(scrubber pii.Scrubber) {
+	g.scrubber = scrubber
+}
+
+// BuildServer creates an MCP server exposing one tool per operation of the
+// spec, which calls the configured service directly. It is what serve mode runs.
+func (g *Generator) BuildServer(doc *openapi3.T) (*server.MCPServer, error) {
+	g.document = doc
+
+	provider, err := auth.FromConfig()
+	if err != nil {
+		return nil, err
+	}
+	guard, err := guardFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	g.maxRedirects = config.GetInt("proxy.max_redirects")
+	g.redactor = redact.New(config.GetStringSlice("response.redact"))
+	if g.scrubber == nil {
+		if g.scrubber, err = pii.FromConfig(); err != nil {
+			return nil, err
+		}
+	}
+	if g.transformer, err = transform.FromConfig(); err != nil {
+		return nil, err
+	}
+	g.warnUnknownTools(doc, "Transform", g.transformer.Tools())
+	if g.requestRules, err = transform.RequestRulesFromConfig(); err != nil {
+		return nil, err
+	}
+	g.warnUnknownTools(doc, "Request rule", g.requestRules.Tools())
+	g.selectFields = config.GetBool("response.fields")
+	g.pagination = config.GetBool("pagination.normalize")
+	g.notifier = webhook.FromConfig(doc.Info.Title, g.logger)
+	g.idempotency = idempotency.FromConfig()
+	if g.offload, err = offload.FromConfig(); err != nil {
+		return nil, err
+	}
+	if g.downloads, err = download.FromConfig(g.offload); err != nil {
+		return nil, err
+	}
+	if g.uploads, err = upload.FromConfig(); err != nil {
+		return nil, err
+	}
+	if g.canary, err = canary.FromConfig(); err != nil {
+		return nil, err
+	}
+
+	// Upstream requests go through the host guard, the failover to the
+	// fallback URL if any, the registered middlewares, then the token manager
+	// authorizing them, so a retry after a refresh is not seen twice and
+	// credentials never reach a refused host. Compression comes last, so that
+	// the others see plain bodies.
+	middlewares := []upstream.Middleware{guard.Wrap}
+	if fallbackURL := config.GetString("service.fallback_url"); fallbackURL != "" {
+		failover, err := upstream.NewFailover(config.GetString("service.url"), fallbackURL,
+			config.GetInt("service.failover.threshold"), time.Duration(config.GetInt("service.failover.cooldown"))*time.Second, g.logger)
+		if err != nil {
+			return nil, err
+		}
+		middlewares = append(middlewares, failover.Wrap)
+	}
+	middlewares = append(middlewares, g.middlewares...)
+	if provider != nil {
+		middlewares = append(middlewares, auth.NewTokenManager(provider).Wrap)
+	}
+	if config.GetBool("client.compression") {
+		compression := &upstream.Compression{MinRequestSize: config.GetInt("client.compress_requests")}
+		middlewares = append(middlewares, compression.Wrap)
+	}
+	overrides, err := upstream.ParseOverrides(config.GetStringSlice("service.resolve"))
+	if err != nil {
+		return nil, err
+	}
+	base, err := upstream.NewTransport(config.GetString("client.http2"), overrides, guard.Control)
+	if err != nil {
+		return nil, err
+	}
+	g.transport = upstream.Chain(base, middlewares...)
+
+	// Clients are notified when the tools change, see ReloadServer
+	mcpServer := server.NewMCPServer(
+		doc.Info.Title,
+		doc.Info.Version,
+		server.WithToolCapabilities(true),
+	)
+	if g.offload.Threshold > 0 || g.downloads != nil && g.downloads.Resources() {
+		mcpServer.AddResourceTemplate(g.offload.ResourceTemplate(), g.offload.ReadResource)
+	}
+	if err := g.addTools(doc, mcpServer); err != nil {
+		return nil, err
+	}
+	return mcpServer, nil
+}
+
+// ReloadServer replaces the tools of a server BuildServer created with those
+// of doc, e.g. a new version of the API's spec, at once, so that connected
+// clients get a single notifications/tools/list_changed. The configuration
+// read by BuildServer is kept.
+func (g *Generator) ReloadServer(s *server.MCPServer, doc *openapi3.T) error {
+	// A server no client initialized collects the new tools without notifying
+	scratch := server.NewMCPServer(doc.Info.Title, doc.Info.Version)
+	if err := g.addTools(doc, scratch); err != nil {
+		return err
+	}
+	s.SetTools(g.ServedTools()...)
+	g.logger.Info("Reloaded tools", zap.Int("tools", len(g.served)))
+	return nil
+}
+
+// ServedTools returns the tools of the last server built, sorted by name
+func (g *Generator) ServedTools() []server.ServerTool {
+	tools := make([]server.ServerTool, 0, len(g.served))
+	for _, tool := range g.served {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Tool.Name < tools[j].Tool.Name })
+	return tools
+}
+
+// ToolOperation returns the upstream operation a tool of the last server
+// built calls, if it is a generated tool no script overrides
+func (g *Generator) ToolOperation(name string) (openapi.Operation, bool) {
+	operation, ok := g.operations[name]
+	return operation, ok
+}
+
+// ExportedTools returns the tools of the last server built, sorted by name,
+// with the upstream operations they call and, unless transformed, the
+// schemas of their results
+func (g *Generator) ExportedTools() []export.Tool {
+	served := g.ServedTools()
+	tools := make([]export.Tool, 0, len(served))
+	for _, tool := range served {
+		exported := export.Tool{Tool: tool.Tool}
+		if operation, ok := g.operations[tool.Tool.Name]; ok {
+			exported.Method, exported.Path = operation.Method, operation.Path
+			if !g.transformer.Has(tool.Tool.Name) {
+				exported.Output = export.ResponseSchema(operation.Operation)
+			}
+		}
+		tools = append(tools, exported)
+	}
+	return tools
+}
+
+// addTools adds a tool per operation of doc to a server, then the tools of
+// the downstream servers, then those of scripts, replacing generated ones of
+// the same name
+func (g *Generator) addTools(doc *openapi3.T, s *server.MCPServer) error {
+	g.served = make(map[string]server.ServerTool)
+	if err := g.processPathsIntoTools(doc, s); err != nil {
+		return err
+	}
+	g.addDownstreamTools(s)
+	if err := g.addScripts(s); err != nil {
+		return err
+	}
+	g.addBatch(s)
+	return nil
+}
+
+// addTool adds a tool to a server, wrapping its handler, and records it
+func (g *Generator) addTool(s *server.MCPServer, tool mcp.Tool, handler server.ToolHandlerFunc) {
+	wrapped := server.ServerTool{Tool: tool, Handler: g.wrapTool(tool.Name, handler)}
+	g.served[tool.Name] = wrapped
+	s.AddTools(wrapped)
+}
+
+// warnUnknownTools warns about settings of a kind, e.g. transforms,
+// configured for tools the document has no operation for, e.g. after a typo
+func (g *Generator) warnUnknownTools(doc *openapi3.T, kind string, configured []string) {
+	tools := make(map[string]bool)
+	for _, op := range openapi.Operations(doc) {
+		tools[utils.SanitizePathForToolID(op.Path, op.Method, g.maxNameLength)] = true
+	}
+	for _, tool := range configured {
+		if !tools[tool] {
+			g.logger.Warn(kind+" configured for an unknown tool", zap.String("tool", tool))
+		}
+	}
+}
+
+// guardFromConfig returns the guard restricting upstream requests to the
+// hosts of the configured service URLs, the fallback, canary and tenants'
+// included, and proxy.allowed_hosts
+func guardFromConfig() (*upstream.Guard, error) {
+	serviceURLs := []string{config.GetString("service.url"), config.GetString("service.fallback_url"), config.GetString("canary.url")}
+	sources, err := config.GetSources()
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		serviceURLs = append(serviceURLs, source.ServiceURL)
+	}
+	tenants, err := tenant.FromConfig()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tenants {
+		serviceURLs = append(serviceURLs, t.ServiceURL)
+	}
+
+	return &upstream.Guard{
+		AllowedHosts:          upstream.AllowedHosts(serviceURLs, config.GetStringSlice("proxy.allowed_hosts")...),
+		AllowPrivateRedirects: config.GetBool("proxy.allow_private_redirects"),
+	}, nil
+}
+
+// prepareOutputDir checks that generating into the project directory won't
+// overwrite an existing project by accident. A previously generated project
+// without manual edits, other than to the functions and protected regions of
+// its server module, is regenerated in place. Any other non-empty directory
+// is moved aside with generate.backup, overwritten with generate.force and
+// refused otherwise.
+func (g *Generator) prepareOutputDir() error {
+	entries, err := os.ReadDir(g.outputDir)
+	if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read output directory: %w", err)
+	}
+
+	if config.GetBool("generate.backup") {
+		backupDir := fmt.Sprintf("%s.bak-%s", g.outputDir, time.Now().Format("20060102-150405"))
+		if err := os.Rename(g.outputDir, backupDir); err != nil {
+			return fmt.Errorf("failed to back up existing project: %w", err)
+		}
+		g.logger.Info("Backed up existing project", zap.String("backup_dir", backupDir))
+		return nil
+	}
+
+	if config.GetBool("generate.force") {
+		g.logger.Warn("Overwriting existing project", zap.String("project_dir", g.outputDir))
+		return nil
+	}
+
+	// Generated files that still match the manifest can safely be rewritten
+	manifest, err := ReadManifest(g.outputDir)
+	if err != nil {
+		return fmt.Errorf("output directory %s already exists and is not empty; use --force to overwrite it or --backup to move it aside first", g.outputDir)
+	}
+	modified, err := manifest.ModifiedFiles(g.outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to check for manual edits: %w", err)
+	}
+	previous, err := takeSnapshot(g.outputDir, manifest)
+	if err != nil {
+		return fmt.Errorf("failed to read existing project: %w", err)
+	}
+	// Edits of functions and protected regions are carried over
+	edited := modified[:0]
+	for _, file := range modified {
+		if !previous.mergeable(file) {
+			edited = append(edited, file)
+		}
+	}
+	if len(edited) > 0 {
+		return fmt.Errorf("generated files in %s were edited by hand: %s; use --force to overwrite them or --backup to move the project aside first", g.outputDir, strings.Join(edited, ", "))
+	}
+
+	g.previous = previous
+	g.logger.Info("Regenerating existing project", zap.String("project_dir", g.outputDir))
+	return nil
+}
+
 // createProjectStructure creates the directory structure for the Python project
 func (g *Generator) createProjectStructure() error {
 	dirs := []string{
@@ -99,18 +559,58 @@ func (g *Generator) createProjectStructure() error {
 	return nil
 }
 
-// generateProjectFiles generates all required project files
-func (g *Generator) generateProjectFiles(doc *openapi3.T) error {
-	// Generate requirements.txt
-	// requirementsPath := filepath.Join(g.outputDir, "requirements.txt")
-	// if err := utils.GenerateRequirements(requirementsPath); err != nil {
-	// 	return fmt.Errorf("failed to generate requirements.txt: %w", err)
-	// }
+// generateProjectFiles generates all required project files, the README
+// referencing the embedded OpenAPI documents
+func (g *Generator) generateProjectFiles(doc *openapi3.T, specs utils.EmbeddedSpecs) error {
+	deps := pythonDependencies()
+
+	// Generate requirements.txt and requirements-dev.txt
+	if deps.UsesRequirements() {
+		if err := utils.GenerateRequirements(g.outputDir, deps); err != nil {
+			return err
+		}
+		g.addFiles("requirements.txt", "requirements-dev.txt")
+	}
+
+	// Generate pyproject.toml, or the pytest configuration it would hold
+	if deps.UsesPyproject() {
+		pyprojectPath := filepath.Join(g.outputDir, "pyproject.toml")
+		if err := utils.GeneratePyprojectToml(pyprojectPath, doc, projectMetadata(doc), deps); err != nil {
+			return fmt.Errorf("failed to generate pyproject.toml: %w", err)
+		}
+		g.addFiles("pyproject.toml")
+
+		if deps.UsesPoetry() {
+			poetryConfigPath := filepath.Join(g.outputDir, "poetry.toml")
+			if err := utils.GeneratePoetryConfig(poetryConfigPath); err != nil {
+				return fmt.Errorf("failed to generate poetry.toml: %w", err)
+			}
+			g.addFiles("poetry.toml")
+		}
+	} else {
+		pytestPath := filepath.Join(g.outputDir, "pytest.ini")
+		if err := utils.GeneratePytestConfig(pytestPath); err != nil {
+			return fmt.Errorf("failed to generate pytest.ini: %w", err)
+		}
+		g.addFiles("pytest.ini")
+	}
+
+	// Generate environment.yml for conda users
+	if deps.Conda {
+		environmentPath := filepath.Join(g.outputDir, "environment.yml")
+		if err := utils.GenerateCondaEnvironment(environmentPath, doc, deps); err != nil {
+			return fmt.Errorf("failed to generate environment.yml: %w", err)
+		}
+		g.addFiles("environment.yml")
+	}
 
-	// Generate pyproject.toml
-	pyprojectPath := filepath.Join(g.outputDir, "pyproject.toml")
-	if err := utils.GeneratePyprojectToml(pyprojectPath, doc); err != nil {
-		return fmt.Errorf("failed to generate pyproject.toml: %w", err)
+	// Pin every dependency to an exact version for reproducible installs
+	if deps.Locked {
+		lockFiles, err := g.lockDependencies(deps)
+		if err != nil {
+			return err
+		}
+		g.addFiles(lockFiles...)
 	}
 
 	// Generate .gitignore
@@ -118,22 +618,116 @@ func (g *Generator) generateProjectFiles(doc *openapi3.T) error {
 	if err := utils.GenerateGitignore(gitignorePath); err != nil {
 		return fmt.Errorf("failed to generate .gitignore: %w", err)
 	}
+	g.addFiles(".gitignore")
+
+	// Document the per-API variables of servers aggregating several APIs
+	var extraEnv []utils.EnvGroup
+	if sources := apiSources(doc); len(sources) > 0 {
+		names := make([]string, 0, len(sources))
+		serviceURLs := make(map[string]string, len(sources))
+		for _, source := range sources {
+			names = append(names, source.Name)
+			serviceURLs[source.Name] = source.ServiceURL
+		}
+		extraEnv = append(extraEnv, utils.AggregatedAPIEnvGroup(names, serviceURLs))
+	}
+
+	// Generate .env.example
+	envExamplePath := filepath.Join(g.outputDir, ".env.example")
+	if err := utils.GenerateEnvExample(envExamplePath, extraEnv...); err != nil {
+		return fmt.Errorf("failed to generate .env.example: %w", err)
+	}
+	g.addFiles(".env.example")
 
 	// Generate README.md
 	readmePath := filepath.Join(g.outputDir, "README.md")
-	if err := utils.GenerateReadme(readmePath, doc); err != nil {
+	if err := utils.GenerateReadme(readmePath, doc, deps, specs, extraEnv...); err != nil {
 		return fmt.Errorf("failed to generate README.md: %w", err)
 	}
+	g.addFiles("README.md")
 
 	// Generate setup scripts
-	if err := utils.GenerateSetupScripts(g.outputDir); err != nil {
+	if err := utils.GenerateSetupScripts(g.outputDir, deps); err != nil {
 		return fmt.Errorf("failed to generate setup scripts: %w", err)
 	}
+	g.addFiles("scripts/setup.sh", "scripts/setup.bat", "scripts/run.py")
 
 	// Generate __init__.py files for package structure
 	if err := utils.GenerateInitFiles(g.outputDir); err != nil {
 		return fmt.Errorf("failed to generate __init__.py files: %w", err)
 	}
+	g.addFiles("src/__init__.py", "tests/__init__.py")
+
+	// Generate smoke tests
+	if err := utils.GenerateTests(g.outputDir, doc, g.maxNameLength, g.builtinTools()...); err != nil {
+		return fmt.Errorf("failed to generate tests: %w", err)
+	}
+	g.addFiles("tests/test_server.py")
 
 	return nil
 }
+
+// projectMetadata returns the configured pyproject metadata, completed from the spec
+func projectMetadata(doc *openapi3.T) utils.ProjectMetadata {
+	return utils.ProjectMetadata{
+		Author:     config.GetString("pyproject.author"),
+		Email:      config.GetString("pyproject.email"),
+		Homepage:   config.GetString("pyproject.homepage"),
+		BugTracker: config.GetString("pyproject.bug_tracker"),
+		License:    config.GetString("pyproject.license"),
+	}.WithSpecDefaults(doc)
+}
+
+// pythonDependencies returns the configured Python version and requirements
+func pythonDependencies() utils.PythonDependencies {
+	return withLangChain(utils.PythonDependencies{
+		Format:         config.GetString("generate.python_deps"),
+		Packaging:      config.GetString("generate.python_packaging"),
+		RequiresPython: config.GetString("pyproject.requires_python"),
+		Pins:           config.GetStringSlice("pyproject.pins"),
+		Extra:          config.GetStringSlice("pyproject.extra_dependencies"),
+		Conda:          config.GetBool("generate.conda"),
+		Locked:         config.GetBool("generate.lock"),
+		SDKVersion:     config.GetString("generate.python_sdk_version"),
+	})
+}
+
+// addFiles records generated files, given relative to the project directory
+// with forward slashes
+func (g *Generator) addFiles(files ...string) {
+	g.files = append(g.files, files...)
+}
+
+// builtinTools returns the names of the tools generated in addition to the
+// operation tools
+func (g *Generator) builtinTools() []string {
+	tools := []string{"check_api_health"}
+	if config.GetBool("generate.meta_tools") {
+		tools = append(tools, "search_endpoints", "call_endpoint", "describe_operation")
+	}
+	return tools
+}
+
+// apiSources returns the APIs aggregated into a merged document, sorted by
+// namespace and completed with their configured settings. It returns nil for
+// documents describing a single API.
+func apiSources(doc *openapi3.T) []config.Source {
+	seen := make(map[string]bool)
+	var sources []config.Source
+	for _, op := range openapi.Operations(doc) {
+		name := openapi.SourceName(op.Operation)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		source, _ := config.GetSource(name)
+		source.Name = name
+		sources = append(sources, source)
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name < sources[j].Name
+	})
+	return sources
+}
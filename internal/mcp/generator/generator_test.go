@@ -0,0 +1,523 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/downstream"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// notifiedSession records the notifications sent to a client
+type notifiedSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *notifiedSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func (s *notifiedSession) SessionID() string { return "test-session" }
+
+func TestReloadServer(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	newDoc := func(paths ...string) *openapi3.T {
+		doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+		for _, path := range paths {
+			doc.Paths.Set(path, &openapi3.PathItem{Get: &openapi3.Operation{Summary: "Get " + path, Responses: openapi3.NewResponses()}})
+		}
+		return doc
+	}
+	listTools := func(s *server.MCPServer) []string {
+		response, ok := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatal("tools/list failed")
+		}
+		var names []string
+		for _, tool := range response.Result.(mcp.ListToolsResult).Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	g := New(zap.NewNop())
+	s, err := g.BuildServer(newDoc("/pets"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A connected client that advertised the tools capability
+	session := &notifiedSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	if err := s.RegisterSession(session); err != nil {
+		t.Fatal(err)
+	}
+	initialize := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`))
+	result := initialize.(mcp.JSONRPCResponse).Result.(mcp.InitializeResult)
+	if result.Capabilities.Tools == nil || !result.Capabilities.Tools.ListChanged {
+		t.Errorf("capabilities = %+v, want tools.listChanged", result.Capabilities)
+	}
+
+	if err := g.ReloadServer(s, newDoc("/pets", "/owners")); err != nil {
+		t.Fatal(err)
+	}
+	if got := listTools(s); len(got) != 2 {
+		t.Errorf("tools after reload = %v, want get_pets and get_owners", got)
+	}
+	if len(session.notifications) != 1 {
+		t.Fatalf("client got %d notifications, want 1", len(session.notifications))
+	}
+	if notification := <-session.notifications; notification.Method != "notifications/tools/list_changed" {
+		t.Errorf("notification = %s, want notifications/tools/list_changed", notification.Method)
+	}
+}
+
+func TestIdempotentPostTool(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	var mu sync.Mutex
+	var keys []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Method+" "+r.Header.Get("X-Idempotency-Key"))
+		mu.Unlock()
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer upstream.Close()
+	viper.Set("service.url", upstream.URL)
+	viper.Set("idempotency.enabled", true)
+	viper.Set("idempotency.header", "X-Idempotency-Key")
+	viper.Set("idempotency.window", 60)
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Orders", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/orders", &openapi3.PathItem{
+		Get:  &openapi3.Operation{Summary: "List orders", Responses: openapi3.NewResponses()},
+		Post: &openapi3.Operation{Summary: "Place an order", Responses: openapi3.NewResponses()},
+	})
+	s, err := New(zap.NewNop()).BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(tool string) {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `","arguments":{"body":"{\"item\":\"book\"}"}}}`
+		if response, ok := s.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse); !ok || response.Result.(*mcp.CallToolResult).IsError {
+			t.Fatalf("call of %s failed: %+v", tool, response)
+		}
+	}
+	call("post_orders")
+	call("post_orders")
+	call("get_orders")
+	call("get_orders")
+
+	if len(keys) != 3 {
+		t.Fatalf("upstream requests = %v, want the retried POST deduplicated", keys)
+	}
+	if !strings.HasPrefix(keys[0], "POST ") || len(keys[0]) == len("POST ") {
+		t.Errorf("POST request = %q, want an idempotency key", keys[0])
+	}
+	if keys[1] != "GET " || keys[2] != "GET " {
+		t.Errorf("GET requests = %v, want them without a key and not deduplicated", keys[1:])
+	}
+}
+
+func TestFieldsArgument(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	var query string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`[{"id":1,"name":"Rex","tag":"dog"},{"id":2,"name":"Tom","tag":"cat"}]`))
+	}))
+	defer upstream.Close()
+	viper.Set("service.url", upstream.URL)
+	viper.Set("response.fields", true)
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{
+		Get:  &openapi3.Operation{Summary: "List pets", Responses: openapi3.NewResponses()},
+		Post: &openapi3.Operation{Summary: "Add a pet", Responses: openapi3.NewResponses()},
+	})
+	s, err := New(zap.NewNop()).BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tools := make(map[string]mcp.Tool)
+	response := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)).(mcp.JSONRPCResponse)
+	for _, tool := range response.Result.(mcp.ListToolsResult).Tools {
+		tools[tool.Name] = tool
+	}
+	if _, ok := tools["get_pets"].InputSchema.Properties["fields"]; !ok {
+		t.Error("get_pets has no fields argument")
+	}
+	if _, ok := tools["post_pets"].InputSchema.Properties["fields"]; ok {
+		t.Error("post_pets has a fields argument, want it on GET tools only")
+	}
+
+	response, ok := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_pets","arguments":{"fields":"id, name"}}}`)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("call failed")
+	}
+	if got := resultText(response.Result.(*mcp.CallToolResult)); got != `[{"id":1,"name":"Rex"},{"id":2,"name":"Tom"}]` {
+		t.Errorf("result = %s, want the ids and names", got)
+	}
+	if query != "" {
+		t.Errorf("upstream query = %q, want the fields argument kept from the API", query)
+	}
+}
+
+func TestPaginationArguments(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	var query string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`[]`))
+	}))
+	defer upstream.Close()
+	viper.Set("service.url", upstream.URL)
+	viper.Set("pagination.normalize", true)
+
+	integer := &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "integer"}}
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{Get: &openapi3.Operation{
+		Summary: "List pets",
+		Parameters: openapi3.Parameters{
+			{Value: &openapi3.Parameter{Name: "per_page", In: openapi3.ParameterInQuery, Description: "At most 100", Schema: integer}},
+			{Value: &openapi3.Parameter{Name: "page_no", In: openapi3.ParameterInQuery, Schema: integer}},
+		},
+		Responses: openapi3.NewResponses(),
+	}})
+	s, err := New(zap.NewNop()).BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)).(mcp.JSONRPCResponse)
+	properties := response.Result.(mcp.ListToolsResult).Tools[0].InputSchema.Properties
+	limit, ok := properties["limit"].(map[string]interface{})
+	if !ok || limit["description"] != "Maximum number of results to return per page. At most 100" {
+		t.Errorf("limit argument = %v, want per_page with the common description", properties["limit"])
+	}
+	if _, ok := properties["page"]; !ok {
+		t.Errorf("arguments = %v, want page_no presented as page", properties)
+	}
+
+	if _, ok := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_pets","arguments":{"limit":10,"page":2}}}`)).(mcp.JSONRPCResponse); !ok {
+		t.Fatal("call failed")
+	}
+	if query != "page_no=2&per_page=10" {
+		t.Errorf("upstream query = %q, want the API's parameter names", query)
+	}
+}
+
+func TestOffloadedResponse(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	body := `[{"id":1,"name":"Rex"},{"id":2,"name":"Tom"}]`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer upstream.Close()
+	viper.Set("service.url", upstream.URL)
+	viper.Set("offload.threshold", 20)
+	viper.Set("offload.ttl", 60)
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List pets", Responses: openapi3.NewResponses()}})
+	s, err := New(zap.NewNop()).BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_pets","arguments":{}}}`)).(mcp.JSONRPCResponse)
+	text := resultText(response.Result.(*mcp.CallToolResult))
+	uri := regexp.MustCompile(`mcprox://responses/[0-9a-f]+`).FindString(text)
+	if uri == "" || !strings.Contains(text, "JSON array of 2 items") {
+		t.Fatalf("result = %q, want the URI and a summary of the response", text)
+	}
+
+	response, ok := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"`+uri+`"}}`)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("reading the resource failed")
+	}
+	contents := response.Result.(mcp.ReadResourceResult).Contents
+	if got := contents[0].(mcp.TextResourceContents); got.Text != body || got.MIMEType != "application/json" {
+		t.Errorf("resource = %+v, want the response", got)
+	}
+}
+
+func TestDownloadedFile(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="invoice.pdf"`)
+		w.Write([]byte("%PDF-1.7"))
+	}))
+	defer upstream.Close()
+	dir := t.TempDir()
+	viper.Set("service.url", upstream.URL)
+	viper.Set("download.dir", dir)
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Billing", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/invoice", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "Download the invoice", Responses: openapi3.NewResponses()}})
+	s, err := New(zap.NewNop()).BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_invoice","arguments":{}}}`)).(mcp.JSONRPCResponse)
+	var file struct {
+		Path string `json:"path"`
+		Size int    `json:"size"`
+	}
+	if err := json.Unmarshal([]byte(resultText(response.Result.(*mcp.CallToolResult))), &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Path != filepath.Join(dir, "invoice.pdf") || file.Size != 8 {
+		t.Errorf("file = %+v, want invoice.pdf in the download directory", file)
+	}
+	if data, _ := os.ReadFile(file.Path); string(data) != "%PDF-1.7" {
+		t.Errorf("saved file = %q", data)
+	}
+}
+
+func TestFileUpload(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	var contentType, upload string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		data, _ := io.ReadAll(r.Body)
+		upload = string(data)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer upstream.Close()
+	root := t.TempDir()
+	photo := filepath.Join(root, "rex.png")
+	os.WriteFile(photo, []byte("PNG"), 0o600)
+	viper.Set("service.url", upstream.URL)
+	viper.Set("upload.roots", []string{root})
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/photos", &openapi3.PathItem{Post: &openapi3.Operation{
+		Summary:     "Upload a photo",
+		RequestBody: &openapi3.RequestBodyRef{Value: &openapi3.RequestBody{Required: true, Content: openapi3.Content{"image/png": openapi3.NewMediaType()}}},
+		Responses:   openapi3.NewResponses(),
+	}})
+	s, err := New(zap.NewNop()).BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)).(mcp.JSONRPCResponse)
+	tool := response.Result.(mcp.ListToolsResult).Tools[0]
+	if _, ok := tool.InputSchema.Properties["file_path"]; !ok || len(tool.InputSchema.Required) != 0 {
+		t.Errorf("schema = %+v, want an optional body and a file_path argument", tool.InputSchema)
+	}
+
+	call := func(path string) mcp.JSONRPCMessage {
+		arguments, _ := json.Marshal(map[string]interface{}{"file_path": path})
+		return s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"post_photos","arguments":`+string(arguments)+`}}`))
+	}
+	if _, ok := call(photo).(mcp.JSONRPCResponse); !ok {
+		t.Fatal("upload failed")
+	}
+	if upload != "PNG" || contentType != "image/png" {
+		t.Errorf("upstream got %q of type %s, want the file", upload, contentType)
+	}
+	if _, ok := call(filepath.Join(t.TempDir(), "rex.png")).(mcp.JSONRPCError); !ok {
+		t.Error("upload from outside the roots succeeded")
+	}
+}
+
+func TestCanaryRouting(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	serve := func(version string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(version))
+		}))
+	}
+	stable, next := serve("v1"), serve("v2")
+	defer stable.Close()
+	defer next.Close()
+	viper.Set("service.url", stable.URL)
+	viper.Set("canary.url", next.URL)
+	viper.Set("canary.weight", 100)
+	viper.Set("canary.tools", map[string]interface{}{"post_pets": 0})
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{
+		Get:  &openapi3.Operation{Summary: "List pets", Responses: openapi3.NewResponses()},
+		Post: &openapi3.Operation{Summary: "Add a pet", Responses: openapi3.NewResponses()},
+	})
+	s, err := New(zap.NewNop()).BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for tool, want := range map[string]string{"get_pets": "v2", "post_pets": "v1"} {
+		response, ok := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+tool+`","arguments":{}}}`)).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("call of %s failed", tool)
+		}
+		if got := resultText(response.Result.(*mcp.CallToolResult)); got != want {
+			t.Errorf("%s was served by %s, want %s", tool, got, want)
+		}
+	}
+}
+
+func TestDownstreamTools(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	search := server.NewMCPServer("Search", "1.0.0")
+	search.AddTool(mcp.NewTool("query"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("found"), nil
+	})
+	search.AddTool(mcp.NewTool("pets"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("downstream pets"), nil
+	})
+	ts := server.NewTestServer(search)
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	downstreams, err := downstream.ConnectAll(ctx, []downstream.Definition{{Name: "get", URL: ts.URL + "/sse"}}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer downstream.CloseAll(downstreams)
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List pets", Responses: openapi3.NewResponses()}})
+	g := New(zap.NewNop())
+	g.UseDownstream(downstreams...)
+	s, err := g.BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// get_pets is generated, so the downstream pets tool isn't served
+	tools := g.ServedTools()
+	if len(tools) != 2 || tools[0].Tool.Name != "get_pets" || tools[1].Tool.Name != "get_query" {
+		t.Fatalf("tools = %+v, want get_pets and get_query", tools)
+	}
+	response, ok := s.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_query","arguments":{}}}`)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("call of get_query failed")
+	}
+	if got := resultText(response.Result.(*mcp.CallToolResult)); got != "found" {
+		t.Errorf("get_query = %q, want found", got)
+	}
+}
+
+func TestPrepareOutputDir(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup fills the project directory; without it there is none
+		setup   func(t *testing.T, dir string)
+		config  map[string]interface{}
+		wantErr string
+		// kept, backedUp and regenerated tell whether the existing file stays
+		// in place, is moved aside, and is regenerated in place
+		kept, backedUp, regenerated bool
+	}{
+		{name: "missing directory"},
+		{name: "empty directory", setup: func(t *testing.T, dir string) {}},
+		{name: "non-empty directory", setup: writeExisting, wantErr: "already exists and is not empty", kept: true},
+		{name: "force", setup: writeExisting, config: map[string]interface{}{"generate.force": true}, kept: true},
+		{name: "backup", setup: writeExisting, config: map[string]interface{}{"generate.backup": true}, backedUp: true},
+		{name: "backup over force", setup: writeExisting, config: map[string]interface{}{"generate.backup": true, "generate.force": true}, backedUp: true},
+		{name: "generated project", setup: writeGenerated, kept: true, regenerated: true},
+		{name: "edited project", setup: func(t *testing.T, dir string) {
+			writeGenerated(t, dir)
+			if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("edited"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, wantErr: "edited by hand: existing.txt", kept: true},
+		{name: "edited project with force", setup: func(t *testing.T, dir string) {
+			writeGenerated(t, dir)
+			if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("edited"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, config: map[string]interface{}{"generate.force": true}, kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for key, value := range tt.config {
+				viper.Set(key, value)
+			}
+
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "project")
+			if tt.setup != nil {
+				if err := os.Mkdir(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				tt.setup(t, dir)
+			}
+
+			g := New(zap.NewNop(), dir)
+			err := g.prepareOutputDir()
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("prepareOutputDir() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "existing.txt")); (err == nil) != tt.kept {
+				t.Errorf("existing file kept = %v, want %v", err == nil, tt.kept)
+			}
+			backups, _ := filepath.Glob(filepath.Join(parent, "project.bak-*", "existing.txt"))
+			if (len(backups) == 1) != tt.backedUp {
+				t.Errorf("backups = %v, want backed up %v", backups, tt.backedUp)
+			}
+			if (g.previous != nil) != tt.regenerated {
+				t.Errorf("regenerated in place = %v, want %v", g.previous != nil, tt.regenerated)
+			}
+		})
+	}
+}
+
+// writeExisting writes a file of the user's own into a project directory
+func writeExisting(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeGenerated writes a file into a project directory along with the
+// manifest recording it as generated
+func writeGenerated(t *testing.T, dir string) {
+	t.Helper()
+	writeExisting(t, dir)
+	if err := writeManifest(dir, []string{"existing.txt"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the generated code")
+
+// TestGoldenPythonServer compares the generated server and project file of
+// petsDoc, for the latest MCP Python SDK and pinned older releases, with
+// testdata/golden. Run with -update after intended changes.
+func TestGoldenPythonServer(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	for name, sdkVersion := range map[string]string{"sdk-latest": "", "sdk-1.9.4": "1.9.4", "sdk-1.6.0": "1.6.0"} {
+		t.Run(name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			viper.Set("generate.python_sdk_version", sdkVersion)
+
+			dir := t.TempDir()
+			if err := New(zap.NewNop(), dir).Generate(context.Background(), petsDoc()); err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range []string{"src/mcp_server.py", "pyproject.toml"} {
+				got, err := os.ReadFile(filepath.Join(dir, utils.ProjectFolderName("Pet Store"), filepath.FromSlash(file)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				golden := filepath.Join("testdata", "golden", name, filepath.Base(file))
+				if *update {
+					os.MkdirAll(filepath.Dir(golden), 0755)
+					if err := os.WriteFile(golden, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("%v; run go test -run TestGoldenPythonServer -update", err)
+				}
+				if string(got) != string(want) {
+					t.Errorf("%s differs from %s; run go test -run TestGoldenPythonServer -update if intended", file, golden)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Markers of a protected region of a generated Python module, followed by the
+// region's name. Regeneration keeps the code between them.
+const (
+	protectedBegin = "# mcprox:begin protected "
+	protectedEnd   = "# mcprox:end protected "
+)
+
+// serverModule is the generated module whose functions are regenerated
+// incrementally
+const serverModule = "src/mcp_server.py"
+
+// pythonSection is a module-level function or protected region of Python code
+type pythonSection struct {
+	// name is "def <function>" or "protected <region>"
+	name string
+	// start and end are the indexes of its first line and the line after it
+	start, end int
+}
+
+// pythonSections returns the module-level functions, with their decorators,
+// and the protected regions of Python code, in order
+func pythonSections(lines []pythonLine) []pythonSection {
+	topLevel := func(i int) bool {
+		text := lines[i].text
+		return lines[i].logical && strings.TrimSpace(text) != "" && indentation(text) == 0
+	}
+
+	var sections []pythonSection
+	for i := 0; i < len(lines); i++ {
+		if !topLevel(i) {
+			continue
+		}
+		text := lines[i].text
+		if name, ok := strings.CutPrefix(text, protectedBegin); ok {
+			for j := i + 1; j < len(lines); j++ {
+				if topLevel(j) && lines[j].text == protectedEnd+name {
+					sections = append(sections, pythonSection{name: "protected " + name, start: i, end: j + 1})
+					i = j
+					break
+				}
+			}
+			continue
+		}
+
+		start := i
+		for i < len(lines) && topLevel(i) && strings.HasPrefix(lines[i].text, "@") {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+		signature := strings.TrimPrefix(lines[i].text, "async ")
+		name, found := strings.CutPrefix(signature, "def ")
+		if !found {
+			continue
+		}
+		name, _, _ = strings.Cut(name, "(")
+
+		end := i + 1
+		for end < len(lines) && !topLevel(end) {
+			end++
+		}
+		// Trailing blank lines belong to the module
+		for end > i+1 && strings.TrimSpace(lines[end-1].text) == "" && !lines[end-1].inString {
+			end--
+		}
+		sections = append(sections, pythonSection{name: "def " + strings.TrimSpace(name), start: start, end: end})
+		i = end - 1
+	}
+	return sections
+}
+
+// sectionText returns the code of a section
+func sectionText(lines []pythonLine, section pythonSection) string {
+	texts := make([]string, 0, section.end-section.start)
+	for _, line := range lines[section.start:section.end] {
+		texts = append(texts, line.text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// digest returns the hex SHA-256 digest of a string
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// moduleEntry returns the manifest entry of a generated Python module: its
+// digest, the digests of its sections and of the code around them
+func moduleEntry(path, code string) ManifestFile {
+	lines := scanPython(code)
+	entry := ManifestFile{Path: path, SHA256: digest(code), Sections: map[string]string{}}
+	var skeleton strings.Builder
+	next := 0
+	for _, section := range pythonSections(lines) {
+		for _, line := range lines[next:section.start] {
+			skeleton.WriteString(line.text + "\n")
+		}
+		skeleton.WriteString("<" + section.name + ">\n")
+		entry.Sections[section.name] = digest(sectionText(lines, section))
+		next = section.end
+	}
+	for _, line := range lines[next:] {
+		skeleton.WriteString(line.text + "\n")
+	}
+	entry.Skeleton = digest(skeleton.String())
+	return entry
+}
+
+// mergeModule carries the hand edits of a previously generated module over
+// into its regenerated code: protected regions are kept, and so are the
+// functions whose generated code didn't change. It also returns the functions
+// edited by hand that were regenerated, losing the edits.
+func mergeModule(previous, generated string, entry ManifestFile) (string, []string) {
+	oldLines := scanPython(previous)
+	oldSections := make(map[string]string)
+	for _, section := range pythonSections(oldLines) {
+		oldSections[section.name] = sectionText(oldLines, section)
+	}
+
+	lines := scanPython(generated)
+	var merged []string
+	var replaced []string
+	next := 0
+	for _, section := range pythonSections(lines) {
+		for _, line := range lines[next:section.start] {
+			merged = append(merged, line.text)
+		}
+		next = section.end
+
+		text := sectionText(lines, section)
+		old, ok := oldSections[section.name]
+		switch {
+		case !ok:
+		case strings.HasPrefix(section.name, "protected "), entry.Sections[section.name] == digest(text):
+			text = old
+		case entry.Sections[section.name] != digest(old):
+			replaced = append(replaced, strings.TrimPrefix(section.name, "def "))
+		}
+		merged = append(merged, text)
+	}
+	for _, line := range lines[next:] {
+		merged = append(merged, line.text)
+	}
+	return strings.Join(merged, "\n"), replaced
+}
+
+// snapshot is the content and modification time of the files of a project
+// about to be regenerated in place
+type snapshot struct {
+	manifest *Manifest
+	contents map[string][]byte
+	modTimes map[string]time.Time
+}
+
+// takeSnapshot records the files of a project's manifest, and the manifest
+func takeSnapshot(projectDir string, manifest *Manifest) (*snapshot, error) {
+	s := &snapshot{manifest: manifest, contents: map[string][]byte{}, modTimes: map[string]time.Time{}}
+	paths := []string{ManifestFileName}
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	for _, path := range paths {
+		full := filepath.Join(projectDir, filepath.FromSlash(path))
+		info, err := os.Stat(full)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return nil, err
+		}
+		s.contents[path] = data
+		s.modTimes[path] = info.ModTime()
+	}
+	return s, nil
+}
+
+// entry returns the manifest entry of a file, if it has one
+func (s *snapshot) entry(path string) (ManifestFile, bool) {
+	for _, file := range s.manifest.Files {
+		if file.Path == path {
+			return file, true
+		}
+	}
+	return ManifestFile{}, false
+}
+
+// mergeable reports whether a file was only edited by hand in the sections
+// regeneration carries over
+func (s *snapshot) mergeable(path string) bool {
+	entry, ok := s.entry(path)
+	content, exists := s.contents[path]
+	return ok && exists && entry.Skeleton != "" && moduleEntry(path, string(content)).Skeleton == entry.Skeleton
+}
+
+// regenerateModule records the manifest entry of a freshly generated Python
+// module and, when regenerating a project in place, merges the hand edits of
+// the previous version into it
+func (g *Generator) regenerateModule(path string) error {
+	full := filepath.Join(g.outputDir, filepath.FromSlash(path))
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return err
+	}
+	entry := moduleEntry(path, string(data))
+	g.modules = append(g.modules, entry)
+
+	if g.previous == nil {
+		return nil
+	}
+	previous, ok := g.previous.entry(path)
+	if !ok || previous.Sections == nil {
+		return nil
+	}
+	merged, replaced := mergeModule(string(g.previous.contents[path]), string(data), previous)
+	if len(replaced) > 0 {
+		g.logger.Warn("Regenerated functions edited by hand whose generated code changed; recover the edits from version control",
+			zap.String("file", path), zap.Strings("functions", replaced))
+	}
+	if merged == string(data) {
+		return nil
+	}
+	if err := os.WriteFile(full, []byte(merged), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	g.logger.Debug("Kept hand edits of unchanged functions and protected regions", zap.String("file", path))
+	return nil
+}
+
+// keepGeneratedAt stamps a project regenerated in place with the time of the
+// previous generation when the generated code of its server module didn't
+// change, so that a regeneration changing nothing rewrites no file
+func (g *Generator) keepGeneratedAt() error {
+	if g.previous == nil {
+		return nil
+	}
+	var previous Metadata
+	if err := json.Unmarshal(g.previous.contents[MetadataFileName], &previous); err != nil || previous.GeneratedAt == "" || previous.GeneratedAt == g.metadata.GeneratedAt {
+		return nil
+	}
+	entry, ok := g.previous.entry(serverModule)
+	if !ok || entry.Sections == nil {
+		return nil
+	}
+
+	// The Python modules are stamped in their first line
+	stamp := func(generatedAt string) string { return " at " + generatedAt + ". Do not edit by hand" }
+	restamped := map[string][]byte{}
+	for _, path := range g.files {
+		if !strings.HasSuffix(path, ".py") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(g.outputDir, filepath.FromSlash(path)))
+		if err != nil {
+			return err
+		}
+		if code := strings.Replace(string(data), stamp(g.metadata.GeneratedAt), stamp(previous.GeneratedAt), 1); code != string(data) {
+			restamped[path] = []byte(code)
+		}
+	}
+	code, ok := restamped[serverModule]
+	if !ok {
+		return nil
+	}
+	regenerated := moduleEntry(serverModule, string(code))
+	if regenerated.Skeleton != entry.Skeleton || !maps.Equal(regenerated.Sections, entry.Sections) {
+		return nil
+	}
+
+	for path, data := range restamped {
+		if err := os.WriteFile(filepath.Join(g.outputDir, filepath.FromSlash(path)), data, 0755); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	g.metadata.GeneratedAt = previous.GeneratedAt
+	return nil
+}
+
+// keepTimestamps restores the modification time of the files a regeneration
+// in place rewrote with the same content, so that they look untouched
+func (g *Generator) keepTimestamps() {
+	if g.previous == nil {
+		return
+	}
+	for path, content := range g.previous.contents {
+		full := filepath.Join(g.outputDir, filepath.FromSlash(path))
+		data, err := os.ReadFile(full)
+		if err != nil || string(data) != string(content) {
+			continue
+		}
+		modTime := g.previous.modTimes[path]
+		if err := os.Chtimes(full, modTime, modTime); err != nil {
+			g.logger.Debug("Failed to keep the timestamp of an unchanged file", zap.String("file", path), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestPythonSections(t *testing.T) {
+	code := "import os\n\n\n@decorator\nasync def f(a):\n    \"\"\"Doc\n\ndef g(): \"\"\"\n    return a\n\n\n# About g\ndef g():\n    pass\n\n\n" +
+		protectedBegin + "custom\nx = 1\n" + protectedEnd + "custom\n"
+	lines := scanPython(code)
+	var got []string
+	for _, section := range pythonSections(lines) {
+		got = append(got, section.name+"="+sectionText(lines, section))
+	}
+	want := []string{
+		"def f=@decorator\nasync def f(a):\n    \"\"\"Doc\n\ndef g(): \"\"\"\n    return a",
+		"def g=def g():\n    pass",
+		"protected custom=" + protectedBegin + "custom\nx = 1\n" + protectedEnd + "custom",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("pythonSections() = %q, want %q", got, want)
+	}
+}
+
+func TestIncrementalRegeneration(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	generate := func(doc *openapi3.T) error {
+		return New(zap.NewNop(), dir).Generate(context.Background(), doc)
+	}
+	if err := generate(petsDoc()); err != nil {
+		t.Fatal(err)
+	}
+	projectDir := filepath.Join(dir, utils.ProjectFolderName("Pet Store"))
+	serverPath := filepath.Join(projectDir, "src", "mcp_server.py")
+	readmePath := filepath.Join(projectDir, "README.md")
+
+	// Edit a tool and the protected region by hand, and date the README back
+	data, err := os.ReadFile(serverPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := "@mcp.tool()\nasync def my_tool() -> str:\n    return \"mine\"\n"
+	edited := strings.Replace(string(data), "    \"\"\"Get a pet\"\"\"\n", "    \"\"\"Get a pet\"\"\"\n    # tuned by hand\n", 1)
+	edited = strings.Replace(edited, protectedEnd+"custom", custom+protectedEnd+"custom", 1)
+	if err := os.WriteFile(serverPath, []byte(edited), 0755); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(readmePath, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	// Regenerating from the same spec keeps both edits and the README untouched
+	if err := generate(petsDoc()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(serverPath)
+	if !strings.Contains(string(data), "# tuned by hand\n") || !strings.Contains(string(data), custom) {
+		t.Errorf("regenerated server lost the hand edits:\n%s", data)
+	}
+	if info, err := os.Stat(readmePath); err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("README.md was touched: %v, %v", info.ModTime(), err)
+	}
+
+	// A changed operation regenerates its tool, keeping the protected region
+	doc := petsDoc()
+	doc.Paths.Find("/pets/{petId}").Get.Summary = "Fetch a pet"
+	if err := generate(doc); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(serverPath)
+	if strings.Contains(string(data), "# tuned by hand\n") || !strings.Contains(string(data), "\"\"\"Fetch a pet\"\"\"") {
+		t.Errorf("changed tool wasn't regenerated:\n%s", data)
+	}
+	if !strings.Contains(string(data), custom) {
+		t.Errorf("regenerated server lost the protected region:\n%s", data)
+	}
+
+	// Edits outside of functions and protected regions are refused
+	if err := os.WriteFile(serverPath, append([]byte("import sys\n"), data...), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := generate(doc); err == nil || !strings.Contains(err.Error(), "edited by hand: src/mcp_server.py") {
+		t.Errorf("Generate() error = %v, want the edited server refused", err)
+	}
+}
+
+func TestNoOpRegenerationKeepsFiles(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("generate.langchain", true)
+
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, utils.ProjectFolderName("Pet Store"))
+	files := []string{filepath.Join("src", "mcp_server.py"), filepath.Join("src", "langchain_tools.py"), MetadataFileName}
+	generate := func(epoch string, doc *openapi3.T) map[string]string {
+		t.Helper()
+		t.Setenv("SOURCE_DATE_EPOCH", epoch)
+		if err := New(zap.NewNop(), dir).Generate(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+		contents := make(map[string]string)
+		for _, file := range files {
+			data, err := os.ReadFile(filepath.Join(projectDir, file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents[file] = string(data)
+		}
+		return contents
+	}
+
+	first := generate("1000000000", petsDoc())
+	second := generate("2000000000", petsDoc())
+	for _, file := range files {
+		if first[file] != second[file] {
+			t.Errorf("regenerating from the same spec rewrote %s:\n%s", file, second[file])
+		}
+	}
+
+	// A changed operation is stamped with the time of the regeneration
+	doc := petsDoc()
+	doc.Paths.Find("/pets/{petId}").Get.Summary = "Fetch a pet"
+	changed := generate("2000000000", doc)
+	for _, file := range files {
+		if !strings.Contains(changed[file], "2033-05-18T03:33:20Z") {
+			t.Errorf("%s of a changed spec isn't stamped with the regeneration time:\n%s", file, changed[file])
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/export"
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// jsOperation describes an operation tool to the generated JavaScript servers
+type jsOperation struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	// Source is the namespace of the operation's API in aggregated servers
+	Source      string                 `json:"source,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	// Params map the tool's arguments to the operation's parameters
+	Params []jsParam `json:"params"`
+	// Constants are the parameters request rules inject
+	Constants []jsConstant `json:"constants,omitempty"`
+}
+
+// jsParam is a parameter of an operation, set from the argument of a tool
+type jsParam struct {
+	Argument string `json:"argument"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+}
+
+// jsConstant is a parameter of an operation set to the same value by every call
+type jsConstant struct {
+	Name  string      `json:"name"`
+	In    string      `json:"in"`
+	Value interface{} `json:"value"`
+}
+
+// jsOperations returns the operation tools of the last server built, by name,
+// with the arguments serve mode gives them
+func (g *Generator) jsOperations() (map[string]jsOperation, error) {
+	rules := g.toolRules(g.document)
+	operations := make(map[string]jsOperation, len(g.operations))
+	for name, operation := range g.operations {
+		op := operation.Operation
+		schema, err := export.InputSchema(g.tools[name])
+		if err != nil {
+			return nil, err
+		}
+		description := op.Summary
+		if description == "" {
+			description = op.Description
+		}
+		if description == "" {
+			description = fmt.Sprintf("%s %s", operation.Method, operation.Path)
+		}
+
+		rule := rules.For(name)
+		argNames := argumentNames(op, rule)
+		jsOp := jsOperation{
+			Method:      operation.Method,
+			Path:        openapi.UpstreamPath(operation.Path, op),
+			Description: description,
+			Source:      openapi.SourceName(op),
+			InputSchema: schema,
+			Params:      []jsParam{},
+		}
+		for _, paramRef := range op.Parameters {
+			if paramRef == nil || paramRef.Value == nil {
+				continue
+			}
+			param := paramRef.Value
+			if value, ok := rule.Injected(param.Name); ok {
+				jsOp.Constants = append(jsOp.Constants, jsConstant{Name: param.Name, In: param.In, Value: value})
+				continue
+			}
+			if rule.Dropped(param.Name) || param.In == openapi3.ParameterInCookie {
+				continue
+			}
+			jsOp.Params = append(jsOp.Params, jsParam{Argument: argNames[param], Name: param.Name, In: param.In})
+		}
+		operations[name] = jsOp
+	}
+	return operations, nil
+}
+
+// generatedHeader returns the comment, in lines starting with comment, tracing
+// a generated file back to mcprox and its specs
+func generatedHeader(metadata Metadata, comment string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s Generated by mcprox %s at %s. Do not edit by hand; regenerate instead.\n",
+		comment, metadata.GeneratorVersion, metadata.GeneratedAt)
+	for _, spec := range metadata.Specs {
+		fmt.Fprintf(&sb, "%s Spec: %s (sha256:%s)\n", comment, spec.URL, spec.SHA256)
+	}
+	return sb.String()
+}
+
+// jsString returns s as a JavaScript string literal
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// jsRuntime returns the JavaScript shared by the generated servers: the
+// operations, the upstream calls of their tools, and handleMessage, which
+// answers a JSON-RPC message of an MCP client with the settings read from env
+func (g *Generator) jsRuntime() (string, error) {
+	operations, err := g.jsOperations()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(operations, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode operations: %w", err)
+	}
+
+	// Aggregated APIs default to their configured base URLs
+	sources := make(map[string]string)
+	for _, source := range apiSources(g.document) {
+		sources[source.Name] = source.ServiceURL
+	}
+	sourceData, err := json.Marshal(sources)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode sources: %w", err)
+	}
+
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fmt.Sprintf(`
+const SERVER_INFO = { name: %s, version: %s };
+const PROTOCOL_VERSION = "2024-11-05";
+
+// Operation tools by name: the upstream operation, the tool's input schema and
+// the parameters its arguments are sent as
+const OPERATIONS = %s;
+
+// Default base URLs of the aggregated APIs, by namespace
+const SOURCES = %s;
+
+// settingsFrom reads the upstream service and credentials from variables such
+// as process.env, with the names the Python server reads
+function settingsFrom(env) {
+  const get = (name, fallback = "") => (env && env[name]) || fallback;
+  const serviceURL = get("SERVICE_URL", "http://localhost:8080");
+  return {
+    serviceURL,
+    authHeader: get("SERVICE_AUTH_HEADER"),
+    bearerToken: get("SERVICE_AUTH_BEARER_TOKEN"),
+    basicUsername: get("SERVICE_AUTH_BASIC_USERNAME"),
+    basicPassword: get("SERVICE_AUTH_BASIC_PASSWORD"),
+    apiKey: get("SERVICE_AUTH_API_KEY"),
+    apiKeyHeader: get("SERVICE_AUTH_API_KEY_HEADER", "X-API-Key"),
+    apiKeyQuery: get("SERVICE_AUTH_API_KEY_QUERY"),
+    timeoutMs: Number(get("HTTP_TIMEOUT", "30")) * 1000,
+    sources: Object.fromEntries(Object.entries(SOURCES).map(([name, url]) => [name, {
+      serviceURL: get("SERVICE_URL_" + name.toUpperCase(), url || serviceURL),
+      authHeader: get("SERVICE_AUTH_HEADER_" + name.toUpperCase()),
+    }])),
+  };
+}
+
+// buildRequest returns the URL and fetch options of an operation called with
+// the arguments of its tool
+function buildRequest(operation, args, settings) {
+  const source = operation.source ? settings.sources[operation.source] : undefined;
+  const values = [
+    ...operation.params.filter((p) => args[p.argument] !== undefined && args[p.argument] !== null)
+      .map((p) => ({ name: p.name, in: p.in, value: args[p.argument] })),
+    ...(operation.constants || []),
+  ];
+
+  let path = operation.path;
+  for (const v of values.filter((v) => v.in === "path")) {
+    // Escaped, so an argument can't change the rest of the URL
+    path = path.split("{" + v.name + "}").join(encodeURIComponent(String(v.value)));
+  }
+  const base = source ? source.serviceURL : settings.serviceURL;
+  const url = new URL(base.replace(/\/+$/, "") + "/" + path.replace(/^\/+/, ""));
+  for (const v of values.filter((v) => v.in === "query")) {
+    url.searchParams.append(v.name, String(v.value));
+  }
+
+  const headers = { "Content-Type": "application/json", Accept: "application/json" };
+  for (const v of values.filter((v) => v.in === "header")) {
+    headers[v.name] = String(v.value);
+  }
+  if (source && source.authHeader) {
+    headers.Authorization = source.authHeader;
+  } else if (settings.authHeader) {
+    headers.Authorization = settings.authHeader;
+  } else if (settings.bearerToken) {
+    headers.Authorization = "Bearer " + settings.bearerToken;
+  } else if (settings.basicUsername) {
+    headers.Authorization = "Basic " + btoa(settings.basicUsername + ":" + settings.basicPassword);
+  }
+  if (settings.apiKey) {
+    if (settings.apiKeyQuery) {
+      url.searchParams.set(settings.apiKeyQuery, settings.apiKey);
+    } else {
+      headers[settings.apiKeyHeader] = settings.apiKey;
+    }
+  }
+
+  const init = { method: operation.method, headers, signal: AbortSignal.timeout(settings.timeoutMs) };
+  if (["POST", "PUT", "PATCH"].includes(operation.method) && args.body !== undefined && args.body !== null) {
+    init.body = typeof args.body === "string" ? args.body : JSON.stringify(args.body);
+  }
+  return { url, init };
+}
+
+// callTool calls the upstream operation of a tool, returning its response as
+// the tool's result
+async function callTool(name, args, settings) {
+  const operation = OPERATIONS[name];
+  if (!operation) {
+    throw new RpcError(-32602, "Unknown tool: " + name);
+  }
+  const { url, init } = buildRequest(operation, args || {}, settings);
+  let response;
+  try {
+    response = await fetch(url, init);
+  } catch (error) {
+    return { content: [{ type: "text", text: "API request failed: " + error.message }], isError: true };
+  }
+  const text = await response.text();
+  if (response.status >= 400) {
+    return { content: [{ type: "text", text: "API returned error status: " + response.status + " - " + text }], isError: true };
+  }
+  return { content: [{ type: "text", text }] };
+}
+
+class RpcError extends Error {
+  constructor(code, message) {
+    super(message);
+    this.code = code;
+  }
+}
+
+// listTools returns the tools of the server, in a stable order
+function listTools() {
+  return [%s].map((name) => ({
+    name,
+    description: OPERATIONS[name].description,
+    inputSchema: OPERATIONS[name].inputSchema,
+  }));
+}
+
+// handleMessage answers a JSON-RPC message of an MCP client, returning the
+// response, or null for notifications
+async function handleMessage(message, settings) {
+  if (!message || typeof message !== "object" || message.jsonrpc !== "2.0") {
+    return { jsonrpc: "2.0", id: null, error: { code: -32600, message: "Invalid Request" } };
+  }
+  if (message.id === undefined || message.id === null) {
+    return null;
+  }
+  try {
+    const params = message.params || {};
+    let result;
+    switch (message.method) {
+      case "initialize":
+        result = {
+          protocolVersion: params.protocolVersion || PROTOCOL_VERSION,
+          capabilities: { tools: { listChanged: false } },
+          serverInfo: SERVER_INFO,
+        };
+        break;
+      case "ping":
+        result = {};
+        break;
+      case "tools/list":
+        result = { tools: listTools() };
+        break;
+      case "tools/call":
+        result = await callTool(params.name, params.arguments, settings);
+        break;
+      default:
+        throw new RpcError(-32601, "Method not found: " + message.method);
+    }
+    return { jsonrpc: "2.0", id: message.id, result };
+  } catch (error) {
+    return { jsonrpc: "2.0", id: message.id, error: { code: error.code || -32603, message: error.message } };
+  }
+}
+`, jsString(g.document.Info.Title), jsString(g.document.Info.Version), data, sourceData, jsNames(names)), nil
+}
+
+// jsHTTPHandler is the JavaScript of the generated servers serving MCP over
+// streamable HTTP, which defines handleHTTP after jsRuntime
+const jsHTTPHandler = `
+// handleHTTP serves MCP over streamable HTTP at /mcp without sessions: each
+// POST carries a JSON-RPC message or batch, answered with JSON. Clients must
+// present the MCP_API_KEY secret, if set, as a bearer token or in X-API-Key.
+async function handleHTTP(request, env) {
+  const url = new URL(request.url);
+  if (url.pathname !== "/mcp") {
+    return new Response("Not found", { status: 404 });
+  }
+  if (env.MCP_API_KEY) {
+    const authorization = request.headers.get("Authorization") || "";
+    const presented = request.headers.get("X-API-Key") || authorization.replace(/^Bearer\s+/i, "");
+    if (presented !== env.MCP_API_KEY) {
+      return new Response("A valid API key is required", { status: 401, headers: { "WWW-Authenticate": "Bearer" } });
+    }
+  }
+  if (request.method !== "POST") {
+    return new Response("Method not allowed", { status: 405, headers: { Allow: "POST" } });
+  }
+
+  let body;
+  try {
+    body = await request.json();
+  } catch (error) {
+    return Response.json({ jsonrpc: "2.0", id: null, error: { code: -32700, message: "Parse error" } }, { status: 400 });
+  }
+  const settings = settingsFrom(env);
+  const messages = Array.isArray(body) ? body : [body];
+  const responses = (await Promise.all(messages.map((message) => handleMessage(message, settings)))).filter((response) => response);
+  if (responses.length === 0) {
+    // Only notifications
+    return new Response(null, { status: 202 });
+  }
+  return Response.json(Array.isArray(body) ? responses : responses[0]);
+}
+`
+
+// jsNames returns names as a list of JavaScript string literals
+func jsNames(names []string) string {
+	literals := make([]string, 0, len(names))
+	for _, name := range names {
+		literals = append(literals, jsString(name))
+	}
+	return strings.Join(literals, ", ")
+}
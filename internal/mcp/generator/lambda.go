@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+)
+
+// lambdaRuntime is the Lambda runtime generated functions run on
+const lambdaRuntime = "nodejs20.x"
+
+// lambdaTimeout is the timeout of generated functions in seconds, within the
+// 30 seconds API Gateway waits for an integration
+const lambdaTimeout = 29
+
+// lambdaHandler is the JavaScript exporting the function's handler, which
+// adapts API Gateway events to handleHTTP
+const lambdaHandler = `
+// handler serves the events of API Gateway HTTP APIs (payload 2.0), REST APIs
+// and function URLs, reading the settings from the function's environment
+export async function handler(event) {
+  const method = (event.requestContext && event.requestContext.http && event.requestContext.http.method) || event.httpMethod || "GET";
+  const path = event.rawPath || event.path || "/";
+  const query = event.rawQueryString !== undefined
+    ? event.rawQueryString
+    : new URLSearchParams(event.queryStringParameters || {}).toString();
+  const headers = new Headers();
+  for (const [name, value] of Object.entries(event.headers || {})) {
+    if (value !== undefined && value !== null) {
+      headers.set(name, value);
+    }
+  }
+
+  const init = { method, headers };
+  if (event.body && method !== "GET" && method !== "HEAD") {
+    init.body = event.isBase64Encoded ? Buffer.from(event.body, "base64") : event.body;
+  }
+  const url = "https://" + (headers.get("host") || "lambda.local") + path + (query ? "?" + query : "");
+  const response = await handleHTTP(new Request(url, init), process.env);
+  return {
+    statusCode: response.status,
+    headers: Object.fromEntries(response.headers),
+    body: await response.text(),
+    isBase64Encoded: false,
+  };
+}
+`
+
+// generateLambda writes the server as an AWS Lambda function serving MCP
+// over streamable HTTP behind API Gateway, deployed with SAM or terraform
+func (g *Generator) generateLambda() error {
+	runtime, err := g.jsRuntime()
+	if err != nil {
+		return err
+	}
+	name := utils.ScriptName(g.document.Info.Title)
+	index := generatedHeader(g.metadata, "//") + runtime + jsHTTPHandler + lambdaHandler
+
+	// The plain variables of the function, with their defaults
+	variables := [][2]string{
+		{"SERVICE_URL", defaultServiceURL(g.document)},
+		{"HTTP_TIMEOUT", fmt.Sprint(lambdaTimeout - 4)},
+	}
+	for _, source := range apiSources(g.document) {
+		if source.ServiceURL != "" {
+			variables = append(variables, [2]string{"SERVICE_URL_" + strings.ToUpper(source.Name), source.ServiceURL})
+		}
+	}
+
+	specFiles, specs, err := g.specFiles()
+	if err != nil {
+		return err
+	}
+	return g.writeProject("AWS Lambda", append([]projectFile{
+		{path: "src/index.mjs", content: index},
+		{path: "template.yaml", content: g.samTemplate(name, variables)},
+		{path: "main.tf", content: g.terraformModule(name, variables)},
+		{path: ".gitignore", content: ".aws-sam/\nsamconfig.toml\n.terraform/\nbuild/\n*.tfstate*\n*.tfvars\n"},
+		{path: "README.md", content: lambdaReadme(g.document.Info.Title, name) + "\n" + strings.TrimSuffix(specs.ReadmeSection(), "\n")},
+	}, specFiles...))
+}
+
+// samTemplate returns the SAM template deploying the function behind an API
+// Gateway HTTP API, its credentials passed as NoEcho parameters
+func (g *Generator) samTemplate(name string, variables [][2]string) string {
+	var sb strings.Builder
+	sb.WriteString(generatedHeader(g.metadata, "#"))
+	sb.WriteString("AWSTemplateFormatVersion: \"2010-09-09\"\n")
+	sb.WriteString("Transform: AWS::Serverless-2016-10-31\n")
+	fmt.Fprintf(&sb, "Description: %s\n\n", jsString(g.document.Info.Title+" MCP server generated by mcprox"))
+
+	sb.WriteString("Parameters:\n")
+	for _, variable := range variables {
+		fmt.Fprintf(&sb, "  %s:\n    Type: String\n    Default: %s\n", pascalCase(variable[0]), jsString(variable[1]))
+	}
+	for _, secret := range jsSecrets {
+		fmt.Fprintf(&sb, "  %s:\n    Type: String\n    NoEcho: true\n    Default: \"\"\n", pascalCase(secret))
+	}
+
+	sb.WriteString("\nResources:\n")
+	sb.WriteString("  McpFunction:\n")
+	sb.WriteString("    Type: AWS::Serverless::Function\n")
+	sb.WriteString("    Properties:\n")
+	fmt.Fprintf(&sb, "      FunctionName: %s\n", jsString(name))
+	sb.WriteString("      CodeUri: src/\n")
+	sb.WriteString("      Handler: index.handler\n")
+	fmt.Fprintf(&sb, "      Runtime: %s\n", lambdaRuntime)
+	sb.WriteString("      Architectures: [arm64]\n")
+	sb.WriteString("      MemorySize: 256\n")
+	fmt.Fprintf(&sb, "      Timeout: %d\n", lambdaTimeout)
+	sb.WriteString("      Environment:\n        Variables:\n")
+	for _, variable := range variables {
+		fmt.Fprintf(&sb, "          %s: !Ref %s\n", variable[0], pascalCase(variable[0]))
+	}
+	for _, secret := range jsSecrets {
+		fmt.Fprintf(&sb, "          %s: !Ref %s\n", secret, pascalCase(secret))
+	}
+	sb.WriteString("      Events:\n")
+	sb.WriteString("        Mcp:\n")
+	sb.WriteString("          Type: HttpApi\n")
+	sb.WriteString("          Properties:\n")
+	sb.WriteString("            Path: /mcp\n")
+	sb.WriteString("            Method: ANY\n")
+
+	sb.WriteString("\nOutputs:\n")
+	sb.WriteString("  McpEndpoint:\n")
+	sb.WriteString("    Description: URL MCP clients connect to\n")
+	sb.WriteString("    Value: !Sub \"https://${ServerlessHttpApi}.execute-api.${AWS::Region}.amazonaws.com/mcp\"\n")
+	return sb.String()
+}
+
+// terraformModule returns the terraform configuration deploying the function
+// behind an API Gateway HTTP API, its credentials passed as sensitive variables
+func (g *Generator) terraformModule(name string, variables [][2]string) string {
+	var sb strings.Builder
+	sb.WriteString(generatedHeader(g.metadata, "#"))
+	for _, variable := range variables {
+		fmt.Fprintf(&sb, "\nvariable %q {\n  type    = string\n  default = %s\n}\n", strings.ToLower(variable[0]), jsString(variable[1]))
+	}
+	for _, secret := range jsSecrets {
+		fmt.Fprintf(&sb, "\nvariable %q {\n  type      = string\n  default   = \"\"\n  sensitive = true\n}\n", strings.ToLower(secret))
+	}
+
+	fmt.Fprintf(&sb, `
+data "archive_file" "mcp" {
+  type        = "zip"
+  source_dir  = "${path.module}/src"
+  output_path = "${path.module}/build/function.zip"
+}
+
+resource "aws_iam_role" "mcp" {
+  name = %[1]s
+  assume_role_policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [{
+      Effect    = "Allow"
+      Action    = "sts:AssumeRole"
+      Principal = { Service = "lambda.amazonaws.com" }
+    }]
+  })
+}
+
+resource "aws_iam_role_policy_attachment" "mcp_logs" {
+  role       = aws_iam_role.mcp.name
+  policy_arn = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+}
+
+resource "aws_lambda_function" "mcp" {
+  function_name    = %[1]s
+  role             = aws_iam_role.mcp.arn
+  handler          = "index.handler"
+  runtime          = %[2]q
+  architectures    = ["arm64"]
+  memory_size      = 256
+  timeout          = %[3]d
+  filename         = data.archive_file.mcp.output_path
+  source_code_hash = data.archive_file.mcp.output_base64sha256
+
+  environment {
+    variables = {
+`, jsString(name), lambdaRuntime, lambdaTimeout)
+	for _, variable := range variables {
+		fmt.Fprintf(&sb, "      %s = var.%s\n", variable[0], strings.ToLower(variable[0]))
+	}
+	for _, secret := range jsSecrets {
+		fmt.Fprintf(&sb, "      %s = var.%s\n", secret, strings.ToLower(secret))
+	}
+	fmt.Fprintf(&sb, `    }
+  }
+}
+
+resource "aws_apigatewayv2_api" "mcp" {
+  name          = %[1]s
+  protocol_type = "HTTP"
+}
+
+resource "aws_apigatewayv2_integration" "mcp" {
+  api_id                 = aws_apigatewayv2_api.mcp.id
+  integration_type       = "AWS_PROXY"
+  integration_uri        = aws_lambda_function.mcp.invoke_arn
+  payload_format_version = "2.0"
+}
+
+resource "aws_apigatewayv2_route" "mcp" {
+  api_id    = aws_apigatewayv2_api.mcp.id
+  route_key = "ANY /mcp"
+  target    = "integrations/${aws_apigatewayv2_integration.mcp.id}"
+}
+
+resource "aws_apigatewayv2_stage" "default" {
+  api_id      = aws_apigatewayv2_api.mcp.id
+  name        = "$default"
+  auto_deploy = true
+}
+
+resource "aws_lambda_permission" "api_gateway" {
+  statement_id  = "AllowAPIGatewayInvoke"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.mcp.function_name
+  principal     = "apigateway.amazonaws.com"
+  source_arn    = "${aws_apigatewayv2_api.mcp.execution_arn}/*/*"
+}
+
+output "mcp_endpoint" {
+  description = "URL MCP clients connect to"
+  value       = "${aws_apigatewayv2_api.mcp.api_endpoint}/mcp"
+}
+`, jsString(name))
+	return sb.String()
+}
+
+// lambdaReadme returns the README of a generated Lambda project
+func lambdaReadme(title, name string) string {
+	return fmt.Sprintf("# %s MCP Server on AWS Lambda\n\n"+
+		"Generated by mcprox. Serves the operations of %s as MCP tools over streamable HTTP, at `/mcp` of an API Gateway HTTP API. The function, `src/index.mjs`, runs on Node.js 20 without dependencies.\n\n"+
+		"## Deploying with SAM\n\n"+
+		"```bash\n"+
+		"sam deploy --guided --parameter-overrides McpApiKey=<key> ServiceAuthBearerToken=<token>\n"+
+		"```\n\n"+
+		"## Deploying with terraform\n\n"+
+		"```bash\n"+
+		"terraform init\n"+
+		"terraform apply -var mcp_api_key=<key> -var service_auth_bearer_token=<token>\n"+
+		"```\n\n"+
+		"Both print the endpoint MCP clients connect to, as `McpEndpoint` or `mcp_endpoint`. Clients present the key as `Authorization: Bearer <key>` or in an `X-API-Key` header; without `MCP_API_KEY`, anyone can call the tools.\n\n"+
+		"The API's base URL is `SERVICE_URL`. Its credentials are `SERVICE_AUTH_HEADER`, `SERVICE_AUTH_BEARER_TOKEN`, `SERVICE_AUTH_BASIC_USERNAME` and `SERVICE_AUTH_BASIC_PASSWORD`, or `SERVICE_AUTH_API_KEY`. They are set in the environment of the function `%s`, which Lambda encrypts at rest; never commit them, e.g. in `samconfig.toml` or a `.tfvars` file.\n",
+		title, title, name)
+}
+
+// pascalCase returns an environment variable name such as SERVICE_URL in
+// the PascalCase of CloudFormation parameters, e.g. ServiceUrl
+func pascalCase(name string) string {
+	var sb strings.Builder
+	for _, word := range strings.Split(strings.ToLower(name), "_") {
+		if word != "" {
+			sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return sb.String()
+}
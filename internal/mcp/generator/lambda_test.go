@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// generateLambda generates the Lambda project of petsDoc into dir
+func generateLambda(dir string) error {
+	g := New(zap.NewNop(), dir)
+	g.SetTarget(config.TargetAWSLambda)
+	return g.Generate(context.Background(), petsDoc())
+}
+
+func TestGenerateLambda(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("service.url", "https://api.example.com/v1")
+
+	dir := t.TempDir()
+	if err := generateLambda(dir); err != nil {
+		t.Fatal(err)
+	}
+	projectDir := filepath.Join(dir, "pet-store-mcp")
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(projectDir, name))
+		if err != nil {
+			t.Errorf("project lacks %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	index := read("src/index.mjs")
+	for _, want := range []string{"// Generated by mcprox ", `"get_pets_petid": {`, "export async function handler(event)"} {
+		if !strings.Contains(index, want) {
+			t.Errorf("src/index.mjs lacks %q", want)
+		}
+	}
+	template := read("template.yaml")
+	for _, want := range []string{"# Generated by mcprox ", "Transform: AWS::Serverless-2016-10-31", "  ServiceUrl:\n    Type: String\n    Default: \"https://api.example.com/v1\"\n", "  McpApiKey:\n    Type: String\n    NoEcho: true\n", "          MCP_API_KEY: !Ref McpApiKey\n", "Path: /mcp"} {
+		if !strings.Contains(template, want) {
+			t.Errorf("template.yaml lacks %q", want)
+		}
+	}
+	module := read("main.tf")
+	for _, want := range []string{`variable "mcp_api_key" {`, "sensitive = true", `function_name    = "pet-store-mcp"`, "      SERVICE_URL = var.service_url\n", `route_key = "ANY /mcp"`} {
+		if !strings.Contains(module, want) {
+			t.Errorf("main.tf lacks %q", want)
+		}
+	}
+	read("README.md")
+	read(".gitignore")
+}
+
+func TestPascalCase(t *testing.T) {
+	for name, want := range map[string]string{"SERVICE_URL": "ServiceUrl", "MCP_API_KEY": "McpApiKey", "SERVICE_URL_BILLING": "ServiceUrlBilling"} {
+		if got := pascalCase(name); got != want {
+			t.Errorf("pascalCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLambdaHandler(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node isn't installed")
+	}
+	viper.Reset()
+	defer viper.Reset()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/pets/") + `"}`))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	if err := generateLambda(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Events of an HTTP API, with payload 2.0, and of a REST API
+	script := `
+process.env.SERVICE_URL = process.argv[2];
+process.env.MCP_API_KEY = "key";
+const { handler } = await import(process.argv[1]);
+const call = '{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_pets_petid","arguments":{"petId":"7"}}}';
+const results = {
+  unauthorized: await handler({ rawPath: "/mcp", rawQueryString: "", headers: {}, body: call, requestContext: { http: { method: "POST" } } }),
+  httpAPI: await handler({ rawPath: "/mcp", rawQueryString: "", headers: { authorization: "Bearer key" }, body: Buffer.from(call).toString("base64"), isBase64Encoded: true, requestContext: { http: { method: "POST" } } }),
+  restAPI: await handler({ path: "/mcp", httpMethod: "POST", headers: { "X-API-Key": "key" }, body: call }),
+};
+console.log(JSON.stringify(results));
+`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, node, "--input-type=module", "-e", script, filepath.Join(dir, "pet-store-mcp", "src", "index.mjs"), upstream.URL).Output()
+	if err != nil {
+		t.Fatalf("node error = %v", err)
+	}
+
+	type result struct {
+		StatusCode int    `json:"statusCode"`
+		Body       string `json:"body"`
+	}
+	var results map[string]result
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatalf("output = %s: %v", out, err)
+	}
+	if results["unauthorized"].StatusCode != 401 {
+		t.Errorf("unauthorized = %+v", results["unauthorized"])
+	}
+	for _, kind := range []string{"httpAPI", "restAPI"} {
+		r := results[kind]
+		if r.StatusCode != 200 || !strings.Contains(r.Body, `"text":"{\"id\":\"7\"}"`) {
+			t.Errorf("%s = %+v", kind, r)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+)
+
+// langChainDependency is the requirement of the generated LangChain tools
+const langChainDependency = "langchain-core"
+
+// generateLangChainTools writes a Python module exposing the operation tools
+// of the generated server as LangChain StructuredTools, which call the same
+// functions and so the same upstream requests
+func (g *Generator) generateLangChainTools(filePath string) error {
+	if err := os.WriteFile(filePath, []byte(formatPythonCode(langChainModule(g.metadata, g.document.Info.Title))), 0644); err != nil {
+		return fmt.Errorf("failed to write LangChain tools: %w", err)
+	}
+	return nil
+}
+
+// langChainModule returns the code of the LangChain tools module
+func langChainModule(metadata Metadata, title string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Generated by mcprox %s at %s. Do not edit by hand; regenerate instead.\n",
+		metadata.GeneratorVersion, metadata.GeneratedAt)
+	for _, spec := range metadata.Specs {
+		fmt.Fprintf(&sb, "# Spec: %s (sha256:%s)\n", spec.URL, spec.SHA256)
+	}
+	fmt.Fprintf(&sb, `"""
+LangChain tools for %s.
+
+Each tool wraps the operation tool of the same name of mcp_server, making the
+same upstream request, so that LangChain agents can call the API without MCP:
+
+    from langchain_tools import TOOLS
+    agent = create_react_agent(model, TOOLS)
+"""
+
+from typing import List
+
+from langchain_core.tools import StructuredTool
+
+from mcp_server import OPERATIONS, OPERATION_TOOLS
+
+
+def get_tools() -> List[StructuredTool]:
+    """Return a LangChain tool per operation of the API."""
+    return [
+        StructuredTool.from_function(
+            coroutine=tool_fn,
+            name=tool_name,
+            description=OPERATIONS[tool_name]["description"] or tool_name,
+        )
+        for tool_name, tool_fn in OPERATION_TOOLS.items()
+    ]
+
+
+TOOLS = get_tools()
+`, strings.ReplaceAll(title, `"""`, `\"\"\"`))
+	return sb.String()
+}
+
+// withLangChain adds the LangChain dependency to the extra requirements of a
+// project generating LangChain tools, unless it is already there
+func withLangChain(deps utils.PythonDependencies) utils.PythonDependencies {
+	if !config.GetBool("generate.langchain") {
+		return deps
+	}
+	for _, extra := range deps.Extra {
+		if utils.RequirementName(extra) == langChainDependency {
+			return deps
+		}
+	}
+	deps.Extra = append(append([]string{}, deps.Extra...), langChainDependency)
+	return deps
+}
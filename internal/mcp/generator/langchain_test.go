@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/spf13/viper"
+)
+
+func TestLangChainModule(t *testing.T) {
+	metadata := Metadata{GeneratorVersion: "v1.2.3", GeneratedAt: "2024-01-01T00:00:00Z", Specs: []openapi.SpecSource{{URL: "https://api.test/openapi.json", SHA256: "abc"}}}
+	code := langChainModule(metadata, `Pets """API"""`)
+	for _, want := range []string{
+		"# Generated by mcprox v1.2.3 at 2024-01-01T00:00:00Z.",
+		"# Spec: https://api.test/openapi.json (sha256:abc)",
+		`LangChain tools for Pets \"\"\"API\"\"\".`,
+		"from mcp_server import OPERATIONS, OPERATION_TOOLS",
+		"StructuredTool.from_function(\n            coroutine=tool_fn,",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("module lacks %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestWithLangChain(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	deps := utils.PythonDependencies{Extra: []string{"rich"}}
+	if got := withLangChain(deps); len(got.Extra) != 1 {
+		t.Errorf("extra without generate.langchain = %v", got.Extra)
+	}
+
+	viper.Set("generate.langchain", true)
+	if got := withLangChain(deps); len(got.Extra) != 2 || got.Extra[1] != langChainDependency || len(deps.Extra) != 1 {
+		t.Errorf("extra = %v, want rich and %s", got.Extra, langChainDependency)
+	}
+	pinned := utils.PythonDependencies{Extra: []string{"LangChain_Core>=0.3"}}
+	if got := withLangChain(pinned); len(got.Extra) != 1 {
+		t.Errorf("extra = %v, want the pinned requirement only", got.Extra)
+	}
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"go.uber.org/zap"
+)
+
+// lockDependencies resolves the dependencies of the generated project to exact
+// versions, writing uv.lock, or poetry.lock with Poetry packaging, for
+// pyproject.toml and requirements.lock for requirements.txt. It returns the
+// lock files written.
+func (g *Generator) lockDependencies(deps utils.PythonDependencies) ([]string, error) {
+	var files []string
+	if deps.UsesPyproject() {
+		tool, lockFile := "uv", "uv.lock"
+		if deps.UsesPoetry() {
+			tool, lockFile = "poetry", "poetry.lock"
+		}
+		if err := g.runLockTool(tool, "lock"); err != nil {
+			return nil, err
+		}
+		files = append(files, lockFile)
+	}
+	if deps.UsesRequirements() {
+		if err := g.runLockTool("uv", "pip", "compile", "requirements.txt",
+			"--output-file", "requirements.lock",
+			"--python-version", deps.MinimumPythonVersion(),
+			"--generate-hashes", "--quiet"); err != nil {
+			return nil, err
+		}
+		files = append(files, "requirements.lock")
+	}
+
+	g.logger.Info("Locked dependencies", zap.Strings("files", files))
+	return files, nil
+}
+
+// lockTools returns the executables needed to lock the dependencies
+func lockTools(deps utils.PythonDependencies) []string {
+	var tools []string
+	if deps.UsesPoetry() {
+		tools = append(tools, "poetry")
+	}
+	if !deps.UsesPoetry() || deps.UsesRequirements() {
+		tools = append(tools, "uv")
+	}
+	return tools
+}
+
+// findLockTool returns the path of an executable needed to lock the dependencies
+func findLockTool(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("locking dependencies requires %s: %w", name, err)
+	}
+	return path, nil
+}
+
+// runLockTool runs a command of a locking tool in the project directory
+func (g *Generator) runLockTool(name string, args ...string) error {
+	path, err := findLockTool(name)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Dir = g.outputDir
+	cmd.Stderr = &stderr
+
+	g.logger.Debug("Locking dependencies", zap.String("tool", name), zap.Strings("args", args))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
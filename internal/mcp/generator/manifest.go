@@ -0,0 +1,233 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestFileName is the file recording what a generated project consists of
+const ManifestFileName = ".mcprox-manifest.json"
+
+// Manifest lists the files written by a generation run
+type Manifest struct {
+	Generator string         `json:"generator"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a generated file, relative to the project directory, and the
+// SHA-256 digest of its content as generated
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	// Sections are the digests of the module-level functions and protected
+	// regions of a Python module regenerated incrementally, by name, and
+	// Skeleton the digest of the code around them
+	Sections map[string]string `json:"sections,omitempty"`
+	Skeleton string            `json:"skeleton,omitempty"`
+}
+
+// writeManifest records the generated files of a project and their digests.
+// The entries of modules replace those computed from the files, which may
+// hold hand edits carried over.
+func writeManifest(projectDir string, files []string, modules ...ManifestFile) error {
+	manifest := Manifest{Generator: "mcprox"}
+	entries := make(map[string]ManifestFile, len(modules))
+	for _, module := range modules {
+		entries[module.Path] = module
+	}
+	for _, file := range files {
+		if entry, ok := entries[file]; ok {
+			manifest.Files = append(manifest.Files, entry)
+			continue
+		}
+		digest, err := fileDigest(filepath.Join(projectDir, filepath.FromSlash(file)))
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, ManifestFile{Path: file, SHA256: digest})
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(projectDir, ManifestFileName), append(data, '\n'), 0644)
+}
+
+// ReadManifest reads the manifest of a generated project
+func ReadManifest(projectDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(projectDir, ManifestFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s is not a generated project: no %s found", projectDir, ManifestFileName)
+		}
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %w", projectDir, err)
+	}
+	return &manifest, nil
+}
+
+// ModifiedFiles returns the files of the manifest whose content no longer
+// matches the generated one, i.e. that were edited by hand. Deleted files
+// don't count as modified.
+func (m *Manifest) ModifiedFiles(projectDir string) ([]string, error) {
+	var modified []string
+	for _, file := range m.Files {
+		digest, err := fileDigest(filepath.Join(projectDir, filepath.FromSlash(file.Path)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if digest != file.SHA256 {
+			modified = append(modified, file.Path)
+		}
+	}
+	return modified, nil
+}
+
+// fileDigest returns the hex SHA-256 digest of a file's content
+func fileDigest(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Clean removes the files listed in a project's manifest, the manifest itself
+// and any directory left empty. Files added by hand are left alone, and so are
+// generated files edited since, unless force is set. It returns the removed
+// and the kept edited paths; with dryRun nothing is removed.
+func Clean(projectDir string, dryRun, force bool) (removed, kept []string, err error) {
+	projectDir = filepath.Clean(projectDir)
+	manifest, err := ReadManifest(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	modified, err := manifest.ModifiedFiles(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	edited := make(map[string]bool, len(modified))
+	for _, file := range modified {
+		edited[file] = true
+	}
+
+	dirs := map[string]bool{projectDir: true}
+	paths := []string{ManifestFileName}
+	for _, file := range manifest.Files {
+		if edited[file.Path] && !force {
+			kept = append(kept, filepath.Join(projectDir, filepath.FromSlash(file.Path)))
+			continue
+		}
+		paths = append(paths, file.Path)
+	}
+
+	for _, rel := range paths {
+		// Never follow a tampered manifest outside of the project
+		clean := filepath.Clean(filepath.FromSlash(rel))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return removed, kept, fmt.Errorf("manifest entry %q points outside of %s", rel, projectDir)
+		}
+
+		path := filepath.Join(projectDir, clean)
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return removed, kept, err
+			}
+		}
+		removed = append(removed, path)
+
+		// Bytecode compiled from a generated module goes along with it
+		if strings.HasSuffix(path, ".py") {
+			cacheDir := filepath.Join(filepath.Dir(path), "__pycache__")
+			stem := strings.TrimSuffix(filepath.Base(path), ".py")
+			caches, _ := filepath.Glob(filepath.Join(cacheDir, stem+".*.pyc"))
+			for _, cache := range caches {
+				if !dryRun {
+					if err := os.Remove(cache); err != nil {
+						return removed, kept, err
+					}
+				}
+				removed = append(removed, cache)
+			}
+			if len(caches) > 0 {
+				dirs[cacheDir] = true
+			}
+		}
+
+		for dir := filepath.Dir(path); dir != projectDir && strings.HasPrefix(dir, projectDir); dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	if dryRun {
+		return removed, kept, nil
+	}
+
+	// Remove directories left empty, deepest first
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	for _, dir := range sorted {
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+			if err := os.Remove(dir); err == nil {
+				removed = append(removed, dir)
+			}
+		}
+	}
+
+	return removed, kept, nil
+}
+
+// Has reports whether a file, relative to the project directory, was generated
+func (m *Manifest) Has(path string) bool {
+	for _, file := range m.Files {
+		if file.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallCommand returns the command installing the dependencies of the
+// generated project into its virtual environment, as its setup scripts do.
+// Poetry creates the environment itself; uv needs one created beforehand.
+func (m *Manifest) InstallCommand() []string {
+	switch {
+	case m.Has("poetry.toml"):
+		return []string{"poetry", "install"}
+	case m.Has("uv.lock"):
+		return []string{"uv", "sync", "--frozen"}
+	case m.Has("requirements.lock"):
+		return []string{"uv", "pip", "install", "-r", "requirements.lock"}
+	case m.Has("pyproject.toml"):
+		return []string{"uv", "pip", "install", "-e", "."}
+	default:
+		return []string{"uv", "pip", "install", "-r", "requirements.txt"}
+	}
+}
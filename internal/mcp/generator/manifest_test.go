@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"README.md", "src/mcp_server.py", "src/custom.py", "tests/test_server.py"} {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeManifest(dir, []string{"README.md", "src/mcp_server.py", "tests/test_server.py"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit a generated file by hand
+	if err := os.WriteFile(filepath.Join(dir, "tests", "test_server.py"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	modified, err := manifest.ModifiedFiles(dir)
+	if err != nil || len(modified) != 1 || modified[0] != "tests/test_server.py" {
+		t.Errorf("Expected tests/test_server.py to be modified, got %v (%v)", modified, err)
+	}
+
+	if _, _, err := Clean(dir, true, false); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); err != nil {
+		t.Error("Expected dry run to keep files")
+	}
+
+	_, kept, err := Clean(dir, false, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(kept) != 1 {
+		t.Errorf("Expected the edited file to be kept, got %v", kept)
+	}
+	for _, gone := range []string{"README.md", "src/mcp_server.py", ManifestFileName} {
+		if _, err := os.Stat(filepath.Join(dir, gone)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", gone)
+		}
+	}
+	for _, kept := range []string{"src/custom.py", "tests/test_server.py"} {
+		if _, err := os.Stat(filepath.Join(dir, kept)); err != nil {
+			t.Errorf("Expected %s to be kept", kept)
+		}
+	}
+
+	if _, _, err := Clean(dir, false, false); err == nil {
+		t.Error("Expected error for a directory without manifest but got none")
+	}
+}
+
+func TestManifestInstallCommand(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"pyproject.toml", "requirements.txt"}, "uv pip install -e ."},
+		{[]string{"requirements.txt"}, "uv pip install -r requirements.txt"},
+		{[]string{"pyproject.toml", "uv.lock"}, "uv sync --frozen"},
+		{[]string{"requirements.txt", "requirements.lock"}, "uv pip install -r requirements.lock"},
+		{[]string{"pyproject.toml", "poetry.toml", "poetry.lock"}, "poetry install"},
+	}
+	for _, tt := range tests {
+		manifest := &Manifest{}
+		for _, file := range tt.files {
+			manifest.Files = append(manifest.Files, ManifestFile{Path: file})
+		}
+		if got := strings.Join(manifest.InstallCommand(), " "); got != tt.want {
+			t.Errorf("InstallCommand() for %v = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
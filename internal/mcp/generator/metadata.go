@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/berkantay/mcprox/internal/version"
+)
+
+// MetadataFileName is the file tracing a generated project back to the mcprox
+// version and OpenAPI documents it was generated from
+const MetadataFileName = ".mcprox-metadata.json"
+
+// Metadata identifies what produced a generated project
+type Metadata struct {
+	GeneratorVersion string               `json:"generator_version"`
+	GeneratedAt      string               `json:"generated_at"`
+	Specs            []openapi.SpecSource `json:"specs"`
+}
+
+// newMetadata describes a generation run from the given specs. The timestamp
+// honours SOURCE_DATE_EPOCH so that builds can stay reproducible.
+func newMetadata(specs []openapi.SpecSource) Metadata {
+	generatedAt := time.Now()
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		generatedAt = time.Unix(epoch, 0)
+	}
+
+	metadata := Metadata{
+		GeneratorVersion: version.Get(),
+		GeneratedAt:      generatedAt.UTC().Format(time.RFC3339),
+		Specs:            []openapi.SpecSource{},
+	}
+	for _, spec := range specs {
+		metadata.Specs = append(metadata.Specs, openapi.SpecSource{URL: publicURL(spec.URL), SHA256: spec.SHA256})
+	}
+	return metadata
+}
+
+// publicURL strips credentials and the query string, which may carry tokens,
+// from a spec URL before it is written into the generated project
+func publicURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// writeMetadata writes the metadata file of a project
+func writeMetadata(projectDir string, metadata Metadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(projectDir, MetadataFileName), append(data, '\n'), 0644)
+}
@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// ToolMiddleware wraps the handlers of serve mode's tools, e.g. to add
+// authorization, quotas or logging around every tool call. The name of the
+// called tool is request.Params.Name.
+type ToolMiddleware interface {
+	Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc
+}
+
+// ToolMiddlewareFunc adapts a function to a ToolMiddleware
+type ToolMiddlewareFunc func(next server.ToolHandlerFunc) server.ToolHandlerFunc
+
+// Wrap calls f(next)
+func (f ToolMiddlewareFunc) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return f(next)
+}
+
+// UseToolMiddleware adds middlewares around the tool calls of the servers
+// BuildServer creates, the first one outermost. They log through the logger
+// of the call's context, which is tagged with the tool and a request ID.
+func (g *Generator) UseToolMiddleware(middlewares ...ToolMiddleware) {
+	g.toolMiddlewares = append(g.toolMiddlewares, middlewares...)
+}
+
+// wrapTool wraps the handler of a tool in the registered middlewares, which
+// see the call's context with its tagged logger, and in the webhook notifier,
+// so that its events also cover calls the middlewares refuse
+func (g *Generator) wrapTool(toolID string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	for i := len(g.toolMiddlewares) - 1; i >= 0; i-- {
+		if g.toolMiddlewares[i] != nil {
+			handler = g.toolMiddlewares[i].Wrap(handler)
+		}
+	}
+	if g.notifier != nil {
+		handler = g.notifier.Wrap(handler)
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, _ = logging.With(ctx, zap.String("tool", toolID), zap.String("request_id", logging.NewRequestID()))
+		return handler(ctx, request)
+	}
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestUseToolMiddleware(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List pets", Responses: openapi3.NewResponses()}})
+
+	var calls []string
+	record := func(name string) ToolMiddleware {
+		return ToolMiddlewareFunc(func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+			return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				calls = append(calls, name+" "+request.Params.Name)
+				logging.FromContext(ctx).Info("call")
+				return next(ctx, request)
+			}
+		})
+	}
+	allowed := 1
+	quota := ToolMiddlewareFunc(func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if allowed == 0 {
+				return nil, errors.New("quota exceeded")
+			}
+			allowed--
+			return next(ctx, request)
+		}
+	})
+
+	g := New(zap.NewNop())
+	g.UseToolMiddleware(record("outer"), nil, quota)
+	g.UseToolMiddleware(record("inner"))
+	s, err := g.BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := logging.WithLogger(context.Background(), zap.New(core))
+	request := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_pets","arguments":{}}}`)
+	if response, ok := s.HandleMessage(ctx, request).(mcp.JSONRPCResponse); !ok || response.Result.(*mcp.CallToolResult).IsError {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	if want := []string{"outer get_pets", "inner get_pets"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		if fields["tool"] != "get_pets" || fields["request_id"] == nil {
+			t.Errorf("middleware logged without the call's fields: %v", fields)
+		}
+	}
+
+	// The quota middleware stops the second call before the inner one
+	if _, ok := s.HandleMessage(ctx, request).(mcp.JSONRPCError); !ok {
+		t.Error("expected the quota middleware to fail the call")
+	}
+	if want := []string{"outer get_pets", "inner get_pets", "outer get_pets"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
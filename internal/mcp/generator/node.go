@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"go.uber.org/zap"
+)
+
+// nodeShebang starts the generated single-file servers, which are executable
+const nodeShebang = "#!/usr/bin/env node\n"
+
+// generateNodeSingle writes the server as a single executable ES module for
+// Node.js 18 or later, or Bun, serving MCP over stdio without dependencies
+// or a build step
+func (g *Generator) generateNodeSingle() error {
+	filePath := filepath.Join(g.outputDir, utils.ScriptName(g.document.Info.Title)+".mjs")
+	if err := g.prepareOutputFile(filePath); err != nil {
+		return err
+	}
+	runtime, err := g.jsRuntime()
+	if err != nil {
+		return err
+	}
+
+	code := nodeShebang + generatedHeader(g.metadata, "//") + `
+import { createInterface } from "node:readline";
+` + runtime + `
+// Serve MCP over stdio: a JSON-RPC message per line, logs on standard error
+const settings = settingsFrom(process.env);
+const lines = createInterface({ input: process.stdin, crlfDelay: Infinity });
+console.error(SERVER_INFO.name + " MCP server running on stdio, calling " + settings.serviceURL);
+lines.on("line", async (line) => {
+  if (!line.trim()) {
+    return;
+  }
+  let message;
+  try {
+    message = JSON.parse(line);
+  } catch (error) {
+    process.stdout.write(JSON.stringify({ jsonrpc: "2.0", id: null, error: { code: -32700, message: "Parse error" } }) + "\n");
+    return;
+  }
+  const response = await handleMessage(message, settings);
+  if (response) {
+    process.stdout.write(JSON.stringify(response) + "\n");
+  }
+});
+`
+	if err := os.MkdirAll(g.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(filePath, []byte(code), 0755); err != nil {
+		return fmt.Errorf("failed to write server: %w", err)
+	}
+	g.logger.Info("Successfully generated single-file MCP server", zap.String("file", filePath))
+	return nil
+}
+
+// prepareOutputFile checks that generating a single-file server won't
+// overwrite a file mcprox didn't generate, unless generate.force is set
+func (g *Generator) prepareOutputFile(filePath string) error {
+	f, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	defer f.Close()
+	if config.GetBool("generate.force") {
+		g.logger.Warn("Overwriting existing file", zap.String("file", filePath))
+		return nil
+	}
+
+	// Generated files carry the generator's header in their first lines
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < 2 && scanner.Scan(); i++ {
+		if strings.HasPrefix(scanner.Text(), "// Generated by mcprox ") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s already exists and wasn't generated by mcprox; use --force to overwrite it", filePath)
+}
@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// petsDoc returns a document with a GET operation taking path, query and
+// header parameters
+func petsDoc() *openapi3.T {
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pet Store", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets/{petId}", &openapi3.PathItem{Get: &openapi3.Operation{
+		Summary: "Get a pet",
+		Parameters: openapi3.Parameters{
+			{Value: openapi3.NewPathParameter("petId").WithRequired(true).WithSchema(openapi3.NewStringSchema())},
+			{Value: openapi3.NewQueryParameter("fields").WithSchema(openapi3.NewStringSchema())},
+			{Value: openapi3.NewHeaderParameter("X-Tenant").WithSchema(openapi3.NewStringSchema())},
+		},
+		Responses: openapi3.NewResponses(),
+	}})
+	return doc
+}
+
+// generateNodeSingle generates the single-file server of petsDoc into dir
+func generateNodeSingle(dir string) error {
+	g := New(zap.NewNop(), dir)
+	g.SetTarget(config.TargetNodeSingle)
+	return g.Generate(context.Background(), petsDoc())
+}
+
+func TestGenerateNodeSingle(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	if err := generateNodeSingle(dir); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "pet-store-mcp.mjs")
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&0100 == 0 {
+		t.Errorf("mode = %v, want an executable file", info.Mode())
+	}
+	code, _ := os.ReadFile(filePath)
+	for _, want := range []string{nodeShebang + "// Generated by mcprox ", `"get_pets_petid": {`, `"argument": "X-Tenant"`} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("server lacks %q", want)
+		}
+	}
+
+	// Regenerated in place, but another file is only overwritten with force
+	if err := generateNodeSingle(dir); err != nil {
+		t.Errorf("regenerating error = %v", err)
+	}
+	os.WriteFile(filePath, []byte("console.log('mine')\n"), 0644)
+	if err := generateNodeSingle(dir); err == nil {
+		t.Error("expected a file mcprox didn't generate to be kept")
+	}
+	viper.Set("generate.force", true)
+	if err := generateNodeSingle(dir); err != nil {
+		t.Errorf("forced error = %v", err)
+	}
+}
+
+func TestNodeSingleServer(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node isn't installed")
+	}
+	viper.Reset()
+	defer viper.Reset()
+
+	requests := make(chan *http.Request, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte(`{"name":"Rex"}`))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	if err := generateNodeSingle(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, node, filepath.Join(dir, "pet-store-mcp.mjs"))
+	cmd.Env = append(os.Environ(), "SERVICE_URL="+upstream.URL, "SERVICE_AUTH_BEARER_TOKEN=secret")
+	stdin, _ := cmd.StdinPipe()
+	stdout, _ := cmd.StdoutPipe()
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer stdin.Close()
+
+	responses := bufio.NewScanner(stdout)
+	call := func(message string) map[string]interface{} {
+		t.Helper()
+		stdin.Write([]byte(message + "\n"))
+		if !responses.Scan() {
+			t.Fatalf("no response to %s", message)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(responses.Bytes(), &response); err != nil || response["error"] != nil {
+			t.Fatalf("response = %s, %v", responses.Text(), err)
+		}
+		return response["result"].(map[string]interface{})
+	}
+
+	if info := call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)["serverInfo"].(map[string]interface{}); info["name"] != "Pet Store" {
+		t.Errorf("serverInfo = %v", info)
+	}
+	stdin.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n"))
+	tools := call(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)["tools"].([]interface{})
+	if len(tools) != 1 || tools[0].(map[string]interface{})["name"] != "get_pets_petid" {
+		t.Fatalf("tools = %v", tools)
+	}
+
+	result := call(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_pets_petid","arguments":{"petId":"a/b","fields":"name","X-Tenant":"acme"}}}`)
+	if text := result["content"].([]interface{})[0].(map[string]interface{})["text"]; text != `{"name":"Rex"}` || result["isError"] != nil {
+		t.Errorf("result = %v", result)
+	}
+	r := <-requests
+	if r.URL.EscapedPath() != "/pets/a%2Fb" || r.URL.Query().Get("fields") != "name" {
+		t.Errorf("upstream request = %s", r.URL)
+	}
+	if r.Header.Get("X-Tenant") != "acme" || r.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("upstream headers = %v", r.Header)
+	}
+}
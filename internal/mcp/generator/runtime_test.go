@@ -0,0 +1,454 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// pythonStubs stands in for the packages generated servers import, so that
+// tests can call their tools without installing them: FastMCP records the
+// tools, and httpx clients answer with the coroutine tests set as
+// httpx.AsyncClient.handler. The module given as argument is imported as
+// server.
+const pythonStubs = `import asyncio
+import collections
+import importlib.util
+import json
+import sys
+import types
+
+
+def _module(name, **attrs):
+    module = types.ModuleType(name)
+    module.__dict__.update(attrs)
+    sys.modules[name] = module
+    return module
+
+
+class FastMCP:
+    def __init__(self, name, **kwargs):
+        self.name = name
+        self.kwargs = kwargs
+        self.tools = {}
+        self.settings = types.SimpleNamespace()
+
+    def tool(self, name=None, **kwargs):
+        def register(fn):
+            self.tools[name or fn.__name__] = fn
+            return fn
+        return register
+
+    def add_tool(self, fn, name=None, **kwargs):
+        self.tools[name or fn.__name__] = fn
+
+
+class RequestError(Exception):
+    pass
+
+
+class TransportError(RequestError):
+    pass
+
+
+class ConnectError(TransportError):
+    pass
+
+
+class HTTPStatusError(Exception):
+    def __init__(self, message, request=None, response=None):
+        super().__init__(message)
+        self.response = response
+
+
+class Response:
+    def __init__(self, status_code, text="", headers=None):
+        self.status_code = status_code
+        self.text = text
+        self.headers = headers or {}
+
+    @property
+    def is_success(self):
+        return 200 <= self.status_code < 300
+
+    def raise_for_status(self):
+        if not self.is_success:
+            raise HTTPStatusError(f"status {self.status_code}", response=self)
+
+
+class AsyncClient:
+    handler = None
+
+    def __init__(self, **kwargs):
+        self.is_closed = False
+
+    async def request(self, method, url, **kwargs):
+        return await AsyncClient.handler(method, url, **kwargs)
+
+    async def get(self, url, **kwargs):
+        return await self.request("GET", url, **kwargs)
+
+
+class _Settings:
+    def __init__(self, *args, **kwargs):
+        pass
+
+
+_module("httpx", AsyncClient=AsyncClient, Response=Response, Request=object, Auth=object,
+        Limits=_Settings, Timeout=_Settings, RequestError=RequestError, TransportError=TransportError,
+        ConnectError=ConnectError, HTTPStatusError=HTTPStatusError)
+_module("dotenv", load_dotenv=lambda *args, **kwargs: None)
+_module("mcp")
+_module("mcp.server")
+_module("mcp.server.fastmcp", FastMCP=FastMCP)
+_module("mcp.server.streamable_http", EventStore=object, EventCallback=object,
+        EventMessage=collections.namedtuple("EventMessage", ["message", "event_id"]))
+import httpx
+
+_spec = importlib.util.spec_from_file_location("server", sys.argv[1])
+server = importlib.util.module_from_spec(_spec)
+_spec.loader.exec_module(server)
+`
+
+// generatePythonServer generates the Python server of doc and returns the path
+// of its module
+func generatePythonServer(t *testing.T, doc *openapi3.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := New(zap.NewNop(), dir).Generate(context.Background(), doc); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, utils.ProjectFolderName(doc.Info.Title), "src", "mcp_server.py")
+}
+
+// runGeneratedServer imports a generated server module with the stubbed
+// packages and runs script, a coroutine body which may await its tools, with
+// the given environment. It returns the lines script prints.
+func runGeneratedServer(t *testing.T, module, script string, env ...string) []string {
+	t.Helper()
+	python := findPython()
+	if python == "" {
+		t.Skip("Python interpreter not available")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(pythonStubs + "\n\nasync def main():\n")
+	for _, line := range strings.Split(strings.TrimSpace(script), "\n") {
+		sb.WriteString("    " + line + "\n")
+	}
+	sb.WriteString("\n\nasyncio.run(main())\n")
+	scriptFile := filepath.Join(t.TempDir(), "run.py")
+	if err := os.WriteFile(scriptFile, []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(python, scriptFile, module)
+	cmd.Env = append(os.Environ(), append([]string{"PYTHONDONTWRITEBYTECODE=1"}, env...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running the generated server failed: %v\n%s", err, stderr.String())
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
+
+// decodeLine decodes a JSON line printed by a generated server's tool
+func decodeLine(t *testing.T, line string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(line), v); err != nil {
+		t.Fatalf("invalid tool output %q: %v", line, err)
+	}
+}
+
+func TestGeneratedHealthCheck(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	module := generatePythonServer(t, petsDoc())
+	lines := runGeneratedServer(t, module, `
+async def unhealthy(method, url, **kwargs):
+    return httpx.Response(503)
+httpx.AsyncClient.handler = unhealthy
+print(await server.check_api_health())
+
+async def down(method, url, **kwargs):
+    raise httpx.ConnectError("connection refused")
+httpx.AsyncClient.handler = down
+print(await server.check_api_health())
+
+print(json.dumps(sorted(server.mcp.tools)))
+`, "SERVICE_URL=https://api.example.com/v1", "HEALTH_CHECK_PATH=/healthz")
+	if len(lines) != 3 {
+		t.Fatalf("output = %q, want 3 lines", lines)
+	}
+
+	var up map[string]interface{}
+	decodeLine(t, lines[0], &up)
+	if up["reachable"] != true || up["healthy"] != false || up["status_code"] != float64(503) || up["url"] != "https://api.example.com/v1/healthz" {
+		t.Errorf("check_api_health() of an unhealthy API = %v", up)
+	}
+	if _, ok := up["latency_ms"].(float64); !ok {
+		t.Errorf("check_api_health() reports no latency: %v", up)
+	}
+
+	var down map[string]interface{}
+	decodeLine(t, lines[1], &down)
+	if down["reachable"] != false || !strings.Contains(down["error"].(string), "ConnectError: connection refused") {
+		t.Errorf("check_api_health() of an unreachable API = %v", down)
+	}
+
+	var tools []string
+	decodeLine(t, lines[2], &tools)
+	if strings.Join(tools, ",") != "check_api_health,get_pets_petid" {
+		t.Errorf("registered tools = %v, want check_api_health and get_pets_petid", tools)
+	}
+}
+
+func TestGeneratedSearchEndpoints(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("generate.meta_tools", true)
+
+	doc := petsDoc()
+	doc.Paths.Set("/pets", &openapi3.PathItem{Post: &openapi3.Operation{Summary: "Add a pet", Tags: []string{"pets"}, Responses: openapi3.NewResponses()}})
+	doc.Paths.Set("/owners", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List owners", Responses: openapi3.NewResponses()}})
+	module := generatePythonServer(t, doc)
+	lines := runGeneratedServer(t, module, `
+print(await server.search_endpoints("pet"))
+print(await server.search_endpoints("", limit=1))
+
+async def owners(method, url, **kwargs):
+    return httpx.Response(200, json.dumps({"method": method, "url": url}))
+httpx.AsyncClient.handler = owners
+print(await server.call_endpoint("get_owners"))
+try:
+    await server.call_endpoint("delete_owners")
+except ValueError as e:
+    print(json.dumps(str(e)))
+
+print(json.dumps(sorted(server.mcp.tools)))
+`, "SERVICE_URL=https://api.example.com", "MCP_CORE_TOOLS=get_pets_petid")
+	if len(lines) != 5 {
+		t.Fatalf("output = %q, want 5 lines", lines)
+	}
+
+	// Matches are ranked by how often the query occurs, and only the core
+	// tools are registered
+	var pets []struct {
+		Name       string   `json:"name"`
+		Method     string   `json:"method"`
+		Path       string   `json:"path"`
+		Parameters []string `json:"parameters"`
+		Registered bool     `json:"registered"`
+	}
+	decodeLine(t, lines[0], &pets)
+	if len(pets) != 2 || pets[0].Name != "get_pets_petid" || pets[1].Name != "post_pets" {
+		t.Fatalf("search_endpoints(pet) = %s, want get_pets_petid and post_pets", lines[0])
+	}
+	if pets[0].Method != "GET" || pets[0].Path != "/pets/{petId}" || !pets[0].Registered || pets[1].Registered {
+		t.Errorf("search_endpoints(pet) = %s", lines[0])
+	}
+	if strings.Join(pets[0].Parameters, ",") != "petId,fields,X_Tenant" {
+		t.Errorf("get_pets_petid parameters = %v", pets[0].Parameters)
+	}
+
+	// An empty query lists every operation, up to the limit
+	var all []struct {
+		Name string `json:"name"`
+	}
+	decodeLine(t, lines[1], &all)
+	if len(all) != 1 || all[0].Name != "get_owners" {
+		t.Errorf("search_endpoints(\"\", limit=1) = %s, want get_owners", lines[1])
+	}
+
+	// Operations that aren't registered can still be called
+	var request map[string]string
+	decodeLine(t, lines[2], &request)
+	if request["method"] != "GET" || request["url"] != "https://api.example.com/owners" {
+		t.Errorf("call_endpoint(get_owners) requested %v", request)
+	}
+	var unknown string
+	decodeLine(t, lines[3], &unknown)
+	if !strings.Contains(unknown, "Unknown operation 'delete_owners'") {
+		t.Errorf("call_endpoint(delete_owners) = %q, want an unknown operation error", unknown)
+	}
+
+	var tools []string
+	decodeLine(t, lines[4], &tools)
+	if strings.Join(tools, ",") != "call_endpoint,check_api_health,describe_operation,get_pets_petid,search_endpoints" {
+		t.Errorf("registered tools = %v", tools)
+	}
+}
+
+func TestGeneratedDescribeOperation(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("generate.meta_tools", true)
+
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "Pet Store", "version": "1.0.0"},
+		"paths": {"/pets/{petId}": {"get": {
+			"summary": "Get a pet",
+			"parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}, "example": "rex"}],
+			"responses": {"200": {"description": "The pet", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}}}
+		}}},
+		"components": {"schemas": {
+			"Pet": {"type": "object", "properties": {"owner": {"$ref": "#/components/schemas/Owner"}}},
+			"Owner": {"type": "object", "properties": {"pets": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}}},
+			"Unused": {"type": "string"}
+		}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := generatePythonServer(t, doc)
+	lines := runGeneratedServer(t, module, `
+print(await server.describe_operation("get_pets_petid"))
+try:
+    await server.describe_operation("get_pets")
+except ValueError as e:
+    print(json.dumps(str(e)))
+`)
+	if len(lines) != 2 {
+		t.Fatalf("output = %q, want 2 lines", lines)
+	}
+
+	var details struct {
+		Name       string `json:"name"`
+		Method     string `json:"method"`
+		Path       string `json:"path"`
+		Summary    string `json:"summary"`
+		Parameters []struct {
+			Name     string      `json:"name"`
+			Argument string      `json:"argument"`
+			Required bool        `json:"required"`
+			Example  interface{} `json:"example"`
+		} `json:"parameters"`
+		Responses map[string]json.RawMessage `json:"responses"`
+		Schemas   map[string]json.RawMessage `json:"schemas"`
+	}
+	decodeLine(t, lines[0], &details)
+	if details.Name != "get_pets_petid" || details.Method != "GET" || details.Path != "/pets/{petId}" || details.Summary != "Get a pet" {
+		t.Errorf("describe_operation(get_pets_petid) = %s", lines[0])
+	}
+	if len(details.Parameters) != 1 || details.Parameters[0].Argument != "petId" || !details.Parameters[0].Required || details.Parameters[0].Example != "rex" {
+		t.Errorf("parameters = %+v, want petId with its example", details.Parameters)
+	}
+	if !strings.Contains(string(details.Responses["200"]), `"$ref":"#/components/schemas/Pet"`) {
+		t.Errorf("responses = %s, want the Pet schema referenced", details.Responses["200"])
+	}
+	if len(details.Schemas) != 2 || details.Schemas["Pet"] == nil || details.Schemas["Owner"] == nil {
+		t.Errorf("schemas = %v, want Pet and Owner", details.Schemas)
+	}
+
+	var unknown string
+	decodeLine(t, lines[1], &unknown)
+	if !strings.Contains(unknown, "Unknown operation 'get_pets'") {
+		t.Errorf("describe_operation(get_pets) = %q, want an unknown operation error", unknown)
+	}
+}
+
+func TestGeneratedEventStoreReplay(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	module := generatePythonServer(t, petsDoc())
+	lines := runGeneratedServer(t, module, `
+store = server.event_store
+ids = {}
+for name, stream_id, message in [("a0", "s1", "m0"), ("b0", "s2", "n0"), ("a1", "s1", "m1"),
+                                 ("b1", "s2", "n1"), ("a2", "s1", None), ("a3", "s1", "m3")]:
+    ids[name] = await store.store_event(stream_id, message)
+print(json.dumps(ids))
+
+async def replay(name):
+    sent = []
+    async def send(event):
+        sent.append([event.message, event.event_id])
+    stream_id = await store.replay_events_after(ids[name], send)
+    return {"stream": stream_id, "sent": sent}
+
+print(json.dumps([await replay(name) for name in ["a1", "b0", "a3", "a0"]]))
+print(json.dumps([store.max_events, server.mcp.kwargs.get("event_store") is store]))
+`, "MCP_EVENT_HISTORY=5")
+	if len(lines) != 3 {
+		t.Fatalf("output = %q, want 3 lines", lines)
+	}
+
+	var ids map[string]string
+	decodeLine(t, lines[0], &ids)
+	type replay struct {
+		Stream *string    `json:"stream"`
+		Sent   [][]string `json:"sent"`
+	}
+	var replays []replay
+	decodeLine(t, lines[1], &replays)
+	if len(replays) != 4 {
+		t.Fatalf("replays = %s, want 4", lines[1])
+	}
+
+	// Only later events of the same stream are replayed, skipping the ones
+	// without a message
+	want := []struct {
+		stream string
+		sent   [][]string
+	}{
+		{"s1", [][]string{{"m3", ids["a3"]}}},
+		{"s2", [][]string{{"n1", ids["b1"]}}},
+		{"s1", nil},
+	}
+	for i, w := range want {
+		got := replays[i]
+		if got.Stream == nil || *got.Stream != w.stream || len(got.Sent) != len(w.sent) {
+			t.Errorf("replay %d = %+v, want stream %s sending %v", i, got, w.stream, w.sent)
+			continue
+		}
+		for j := range w.sent {
+			if strings.Join(got.Sent[j], ",") != strings.Join(w.sent[j], ",") {
+				t.Errorf("replay %d sent %v, want %v", i, got.Sent, w.sent)
+			}
+		}
+	}
+
+	// The oldest event was evicted beyond MCP_EVENT_HISTORY, so the stream
+	// can't be resumed from it
+	if replays[3].Stream != nil || len(replays[3].Sent) != 0 {
+		t.Errorf("replay after an evicted event = %+v, want none", replays[3])
+	}
+	if lines[2] != "[5, true]" {
+		t.Errorf("max_events and event store passed to FastMCP = %s, want MCP_EVENT_HISTORY and true", lines[2])
+	}
+}
+
+func TestGeneratedSameNamedParameters(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	doc := petsDoc()
+	doc.Paths.Value("/pets/{petId}").Get.Parameters = append(doc.Paths.Value("/pets/{petId}").Get.Parameters,
+		&openapi3.ParameterRef{Value: openapi3.NewQueryParameter("petId").WithSchema(openapi3.NewStringSchema())})
+	module := generatePythonServer(t, doc)
+	lines := runGeneratedServer(t, module, `
+async def echo(method, url, **kwargs):
+    return httpx.Response(200, json.dumps({"url": url, "tenant": kwargs["headers"].get("X-Tenant")}))
+httpx.AsyncClient.handler = echo
+print(await server.get_pets_petid("rex", fields="name", X_Tenant="acme", petId_2="cat"))
+`, "SERVICE_URL=https://api.example.com")
+
+	var request map[string]string
+	decodeLine(t, lines[0], &request)
+	if request["url"] != "https://api.example.com/pets/rex?fields=name&petId=cat" || request["tenant"] != "acme" {
+		t.Errorf("get_pets_petid() requested %v, want the path and query petId apart and X-Tenant only as a header", request)
+	}
+}
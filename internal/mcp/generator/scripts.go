@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/berkantay/mcprox/internal/script"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// addScripts loads the configured scripts and adds their tools to the server.
+// A script named after a generated tool overrides it, keeping its description
+// and arguments unless the script configures its own.
+func (g *Generator) addScripts(s *server.MCPServer) error {
+	definitions, err := script.FromConfig()
+	if err != nil {
+		return err
+	}
+
+	g.scripts = nil
+	for _, definition := range definitions {
+		loaded, err := script.Load(definition)
+		if err != nil {
+			return err
+		}
+		g.scripts = append(g.scripts, loaded)
+
+		tool, overridden := g.tools[definition.Tool]
+		if !overridden || len(definition.Arguments) > 0 {
+			tool = scriptTool(definition)
+		} else if definition.Description != "" {
+			tool.Description = definition.Description
+		}
+		g.addTool(s, tool, g.scriptHandler(loaded))
+		delete(g.operations, definition.Tool)
+
+		g.logger.Debug("Added script tool",
+			zap.String("id", definition.Tool),
+			zap.String("file", definition.File),
+			zap.Bool("overrides", overridden))
+	}
+	return nil
+}
+
+// scriptTool describes the tool a script defines
+func scriptTool(definition script.Definition) mcp.Tool {
+	toolOpts := []mcp.ToolOption{mcp.WithDescription(definition.Description)}
+	for _, argument := range definition.Arguments {
+		propOpts := []mcp.PropertyOption{}
+		if argument.Required {
+			propOpts = append(propOpts, mcp.Required())
+		}
+		if argument.Description != "" {
+			propOpts = append(propOpts, mcp.Description(argument.Description))
+		}
+
+		switch argument.Type {
+		case script.TypeNumber:
+			toolOpts = append(toolOpts, mcp.WithNumber(argument.Name, propOpts...))
+		case script.TypeBoolean:
+			toolOpts = append(toolOpts, mcp.WithBoolean(argument.Name, propOpts...))
+		default:
+			toolOpts = append(toolOpts, mcp.WithString(argument.Name, propOpts...))
+		}
+	}
+	return mcp.NewTool(definition.Tool, toolOpts...)
+}
+
+// scriptHandler returns the handler running a script. The script calls the
+// generated tools directly, so an overridden tool can still call the handler
+// it replaces. It keeps the handlers of the server being built, which a
+// reload doesn't touch.
+func (g *Generator) scriptHandler(loaded *script.Script) server.ToolHandlerFunc {
+	handlers := g.handlers
+	call := func(ctx context.Context, tool string, args map[string]interface{}) (string, error) {
+		handler, ok := handlers[tool]
+		if !ok {
+			return "", fmt.Errorf("no tool %q", tool)
+		}
+		logging.FromContext(ctx).Debug("Script calling tool", zap.String("called", tool))
+
+		request := mcp.CallToolRequest{}
+		request.Params.Name = tool
+		request.Params.Arguments = args
+		result, err := handler(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		return resultText(result), nil
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		text, err := loaded.Run(ctx, request.Params.Arguments, call)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Script failed", zap.Error(err))
+			return nil, err
+		}
+		return mcp.NewToolResultText(text), nil
+	}
+}
+
+// resultText returns the text of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var text string
+	for _, content := range result.Content {
+		if textContent, ok := content.(mcp.TextContent); ok {
+			text += textContent.Text
+		}
+	}
+	return text
+}
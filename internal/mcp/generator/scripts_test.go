@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestScripts(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	override := filepath.Join(dir, "get_pets.star")
+	if err := os.WriteFile(override, []byte("def handle(args):\n    pets = call(\"get_pets\")\n    return \"overridden\" if pets else \"empty\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	greet := filepath.Join(dir, "greet.star")
+	if err := os.WriteFile(greet, []byte("def handle(args):\n    return {\"greeting\": \"hello \" + args[\"name\"]}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("scripts", []map[string]interface{}{
+		{"tool": "get_pets", "file": override},
+		{"tool": "greet", "file": greet, "description": "Greet someone", "arguments": []map[string]interface{}{{"name": "name", "type": "string", "required": true}}},
+	})
+
+	doc := &openapi3.T{OpenAPI: "3.0.0", Info: &openapi3.Info{Title: "Pets", Version: "1.0.0"}, Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/pets", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List pets", Responses: openapi3.NewResponses()}})
+	owners := openapi3.NewArraySchema()
+	doc.Paths.Set("/owners", &openapi3.PathItem{Get: &openapi3.Operation{Summary: "List owners", Responses: openapi3.NewResponses(
+		openapi3.WithStatus(200, &openapi3.ResponseRef{Value: openapi3.NewResponse().WithJSONSchema(owners)}))}})
+
+	g := New(zap.NewNop())
+	s, err := g.BuildServer(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operation, ok := g.ToolOperation("get_owners"); !ok || operation.Method != "GET" || operation.Path != "/owners" {
+		t.Errorf("operation of get_owners = %+v, %v, want GET /owners", operation, ok)
+	}
+	if _, ok := g.ToolOperation("get_pets"); ok {
+		t.Error("overridden get_pets still has an upstream operation")
+	}
+	exported := g.ExportedTools()
+	if len(exported) != 3 || exported[0].Name != "get_owners" || exported[0].Output != owners || exported[1].Method != "" {
+		t.Errorf("exported tools = %+v", exported)
+	}
+
+	call := func(name, arguments string) string {
+		t.Helper()
+		request := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + arguments + `}}`)
+		response, ok := s.HandleMessage(context.Background(), request).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("%s failed: %+v", name, s.HandleMessage(context.Background(), request))
+		}
+		return resultText(response.Result.(*mcp.CallToolResult))
+	}
+	if text := call("get_pets", `{}`); text != "overridden" {
+		t.Errorf("get_pets = %q", text)
+	}
+	if text := call("greet", `{"name":"Rex"}`); text != `{"greeting":"hello Rex"}` {
+		t.Errorf("greet = %q", text)
+	}
+
+	list, ok := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("tools/list failed")
+	}
+	tools := map[string]mcp.Tool{}
+	for _, tool := range list.Result.(mcp.ListToolsResult).Tools {
+		tools[tool.Name] = tool
+	}
+	if len(tools) != 3 || tools["get_pets"].Description == "" || tools["greet"].Description != "Greet someone" {
+		t.Errorf("unexpected tools %+v", tools)
+	}
+}
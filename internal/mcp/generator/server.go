@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/openapi"
 )
 
 // generateServerCode writes the MCP server code to a file
@@ -13,6 +16,11 @@ func (g *Generator) generateServerCode(filePath string) error {
 
 	// Create a new ToolBuilder to handle code generation
 	tb := NewToolBuilder()
+	tb.SetSDKVersion(config.GetString("generate.python_sdk_version"))
+	tb.SetMaxNameLength(g.maxNameLength)
+
+	// Write the module header tracing the code back to its spec
+	tb.WriteHeader(g.metadata)
 
 	// Write Python imports
 	tb.WriteImports()
@@ -20,35 +28,89 @@ func (g *Generator) generateServerCode(filePath string) error {
 	// Write logger setup
 	tb.WriteSetupLogger()
 
+	// Write the response redaction helper
+	tb.WriteResponseRedaction(config.GetStringSlice("response.redact"))
+
+	// Write the response transforms BuildServer compiled, translated from CEL
+	if config.GetBool("generate.transforms") {
+		if err := tb.WriteTransforms(g.transformer); err != nil {
+			return err
+		}
+	}
+
+	// Write the request rules BuildServer read, before the tools they mutate
+	if err := tb.WriteRequestRules(doc, g.toolRules(doc)); err != nil {
+		return err
+	}
+
+	// Make streamable HTTP sessions resumable
+	tb.WriteEventStore()
+
 	// Create MCP server
 	tb.WriteCreateMCPServer(doc.Info.Title)
 
 	// Get service URL from environment
 	tb.WriteGetServiceURL()
 
+	// Write the base URLs and credentials of aggregated APIs
+	tb.WriteSourceSettings(apiSources(doc))
+
+	// Write upstream authentication
+	tb.WriteServiceAuth()
+
+	// Write the shared async HTTP client
+	tb.WriteHTTPClient()
+
+	// Write concurrency limiting and request coalescing
+	tb.WriteConcurrencyControls()
+
 	// Write function to build URL with path parameters and query parameters
 	tb.WriteBuildURL()
 
-	// Iterate over all paths in the OpenAPI document
-	for path, pathItem := range doc.Paths.Map() {
-		for method, op := range pathItem.Operations() {
-			if op == nil {
-				continue
-			}
+	// Write the built-in upstream health check tool
+	tb.WriteHealthCheckTool()
+
+	// Iterate over all operations in a stable order
+	for _, op := range openapi.Operations(doc) {
+		// Generate the tool definition code
+		if err := tb.WriteToolDefinition(op.Path, op.Method, op.Operation); err != nil {
+			return err
+		}
+	}
+
+	// Register the operation tools with the server
+	tb.WriteToolRegistry()
 
-			// Generate the tool definition code
-			tb.WriteToolDefinition(path, method, op)
+	// Write the optional discovery tools for lazy tool loading
+	if config.GetBool("generate.meta_tools") {
+		tb.WriteSearchEndpointsTool()
+		if err := tb.WriteDescribeOperationTool(doc); err != nil {
+			return err
 		}
 	}
 
+	// Leave room for code of the user's own
+	tb.WriteProtectedRegion("custom")
+
 	// Add main block
 	tb.WriteMainBlock()
 
+	// Lay the code out consistently, whatever the emitters left between blocks
+	tb.Format()
+
 	// Ensure the directory exists
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		return fmt.Errorf("failed to create directory for server code: %w", err)
 	}
 
 	// Write the code to file
-	return os.WriteFile(filePath, []byte(tb.String()), 0755)
+	if err := os.WriteFile(filePath, []byte(tb.String()), 0755); err != nil {
+		return err
+	}
+
+	// Make sure the emitted code at least compiles before shipping it
+	if config.GetBool("generate.syntax_check") {
+		return g.checkPythonSyntax(filePath, tb)
+	}
+	return nil
 }
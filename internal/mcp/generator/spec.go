@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+)
+
+// specFiles returns the copies of the OpenAPI documents embedded into the
+// generated project: every document as served and after preprocessing, the
+// log of the changes preprocessing made, and the normalized document the
+// server is generated from, which mcprox generate accepts back offline
+func (g *Generator) specFiles() ([]projectFile, utils.EmbeddedSpecs, error) {
+	normalized, err := json.MarshalIndent(g.document, "", "  ")
+	if err != nil {
+		return nil, utils.EmbeddedSpecs{}, fmt.Errorf("failed to marshal normalized OpenAPI spec: %w", err)
+	}
+
+	specs := utils.EmbeddedSpecs{Normalized: path.Join(utils.SpecDir, "openapi.json")}
+	if g.target != config.TargetPython {
+		specs.Target = g.target
+	}
+	var files []projectFile
+	var changes strings.Builder
+	changes.WriteString("# Changes mcprox made to the OpenAPI documents while preprocessing them\n")
+	for i, spec := range g.specs {
+		if spec.Content == nil {
+			continue
+		}
+		suffix := ""
+		if len(g.specs) > 1 {
+			suffix = fmt.Sprintf("-%d", i+1)
+		}
+		original := path.Join(utils.SpecDir, "original"+suffix+".json")
+		specs.Originals = append(specs.Originals, original)
+		files = append(files, projectFile{path: original, content: string(spec.Content)})
+
+		if spec.Preprocessed != nil {
+			var preprocessed bytes.Buffer
+			if err := json.Indent(&preprocessed, spec.Preprocessed, "", "  "); err != nil {
+				return nil, utils.EmbeddedSpecs{}, fmt.Errorf("failed to indent preprocessed OpenAPI spec: %w", err)
+			}
+			name := path.Join(utils.SpecDir, "preprocessed"+suffix+".json")
+			specs.Preprocessed = append(specs.Preprocessed, name)
+			files = append(files, projectFile{path: name, content: preprocessed.String() + "\n"})
+		}
+
+		fmt.Fprintf(&changes, "\n# %s (%s)\n", publicURL(spec.URL), original)
+		for _, transformation := range spec.Transformations {
+			changes.WriteString(transformation.String() + "\n")
+		}
+		if len(spec.Transformations) == 0 {
+			changes.WriteString("No changes\n")
+		}
+	}
+	if len(specs.Originals) > 0 {
+		specs.Transformations = path.Join(utils.SpecDir, "transformations.log")
+		files = append(files, projectFile{path: specs.Transformations, content: changes.String()})
+	}
+	files = append(files, projectFile{path: specs.Normalized, content: string(normalized) + "\n"})
+	return files, specs, nil
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// syntaxCheckScript compiles a Python file without writing bytecode and reports
+// the first syntax error as "<line>:<message>"
+const syntaxCheckScript = `import sys
+path = sys.argv[1]
+with open(path, encoding="utf-8") as f:
+    source = f.read()
+try:
+    compile(source, path, "exec")
+except SyntaxError as e:
+    print(f"{e.lineno or 0}:{e.msg}")
+    sys.exit(1)
+`
+
+// findPython returns the path of a local Python interpreter, if any
+func findPython() string {
+	for _, name := range []string{"python3", "python"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// checkPythonSyntax compiles the generated server code with the local Python
+// interpreter and reports the operation responsible for any syntax error
+func (g *Generator) checkPythonSyntax(filePath string, tb *ToolBuilder) error {
+	python := findPython()
+	if python == "" {
+		g.logger.Warn("Python interpreter not found, skipping syntax check of generated code")
+		return nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(python, "-c", syntaxCheckScript, filePath)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err == nil {
+		g.logger.Debug("Generated code passed syntax check", zap.String("file", filePath))
+		return nil
+	}
+
+	// Anything other than a reported syntax error means the check itself failed
+	lineStr, msg, found := strings.Cut(strings.TrimSpace(stdout.String()), ":")
+	line, convErr := strconv.Atoi(lineStr)
+	if !found || convErr != nil {
+		return fmt.Errorf("failed to run syntax check: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if op, ok := tb.operationAt(line); ok {
+		return fmt.Errorf("generated code for operation %s %s (tool %s) has a syntax error at %s:%d: %s",
+			op.method, op.path, op.toolID, filePath, line, msg)
+	}
+	return fmt.Errorf("generated code has a syntax error at %s:%d: %s", filePath, line, msg)
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.uber.org/zap"
+)
+
+func TestCheckPythonSyntaxNamesOperation(t *testing.T) {
+	if findPython() == "" {
+		t.Skip("Python interpreter not found")
+	}
+
+	doc := petsDoc()
+	doc.Paths.Set("/pets", &openapi3.PathItem{Post: &openapi3.Operation{Summary: "Add a pet", Responses: openapi3.NewResponses()}})
+	tb := NewToolBuilder()
+	tb.WriteHeader(Metadata{})
+	tb.WriteImports()
+	for _, op := range openapi.Operations(doc) {
+		if err := tb.WriteToolDefinition(op.Path, op.Method, op.Operation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tb.Format()
+
+	g := New(zap.NewNop(), t.TempDir())
+	filePath := filepath.Join(t.TempDir(), "mcp_server.py")
+	check := func(line int) error {
+		t.Helper()
+		lines := strings.Split(tb.String(), "\n")
+		if line > 0 {
+			lines[line-1] = "def broken(:"
+		}
+		if err := os.WriteFile(filePath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return g.checkPythonSyntax(filePath, tb)
+	}
+
+	if err := check(0); err != nil {
+		t.Fatalf("generated code fails the syntax check: %v", err)
+	}
+
+	// A bad line within the code of an operation names its method and path
+	if len(tb.operations) != 2 {
+		t.Fatalf("operations = %+v, want 2", tb.operations)
+	}
+	for i, want := range []string{"operation POST /pets (tool post_pets)", "operation GET /pets/{petId} (tool get_pets_petid)"} {
+		span := tb.operations[i]
+		line := (span.startLine + span.endLine) / 2
+		if err := check(line); err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), filePath) {
+			t.Errorf("syntax error at line %d = %v, want it to name %s", line, err, want)
+		}
+	}
+
+	// One outside of them doesn't
+	if err := check(2); err == nil || strings.Contains(err.Error(), "operation") {
+		t.Errorf("syntax error in the header = %v, want no operation named", err)
+	}
+}
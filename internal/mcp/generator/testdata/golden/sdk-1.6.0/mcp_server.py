@@ -0,0 +1,423 @@
+#!/usr/bin/env python3
+# Generated by mcprox dev at 2023-11-14T22:13:20Z. Do not edit by hand; regenerate instead.
+"""
+MCP Server generated from OpenAPI specification.
+"""
+import os
+import inspect
+import asyncio
+import base64
+import argparse
+import httpx
+import logging
+import json
+import time
+from urllib.parse import urlencode, urlsplit, urlunsplit, parse_qsl
+from typing import Dict, Any, Optional, Union, Callable, Awaitable, Generator, List, Set, Tuple
+
+# Import MCP framework
+from mcp.server.fastmcp import FastMCP
+from dotenv import load_dotenv
+
+# Load configuration from a .env file in the project root, if present
+load_dotenv()
+
+# Configure logging
+log_level = os.getenv("LOG_LEVEL", "INFO").upper()
+log_format = os.getenv("LOG_FORMAT", "text").lower()
+
+
+class JSONFormatter(logging.Formatter):
+    """Format log records as single-line JSON objects."""
+
+    def format(self, record: logging.LogRecord) -> str:
+        entry = {
+            "time": self.formatTime(record),
+            "level": record.levelname,
+            "logger": record.name,
+            "message": record.getMessage(),
+        }
+        if record.exc_info:
+            entry["exception"] = self.formatException(record.exc_info)
+        return json.dumps(entry)
+
+
+log_handler = logging.StreamHandler()
+if log_format == "json":
+    log_handler.setFormatter(JSONFormatter())
+else:
+    log_handler.setFormatter(logging.Formatter("%(asctime)s %(levelname)s %(name)s: %(message)s"))
+logging.basicConfig(level=log_level, handlers=[log_handler])
+logger = logging.getLogger(__name__)
+
+# Query parameters and headers whose values are never written to the logs
+REDACTED = "***"
+SENSITIVE_MARKERS = ("token", "secret", "password", "passwd", "apikey", "api_key", "api-key", "auth", "session", "signature", "credential")
+SENSITIVE_KEYS = {k.strip().lower() for k in os.getenv("LOG_REDACT_KEYS", "").split(",") if k.strip()}
+
+
+def is_sensitive(name: str) -> bool:
+    """Report whether a query parameter or header name carries a secret."""
+    lowered = name.lower()
+    return lowered in SENSITIVE_KEYS or any(marker in lowered for marker in SENSITIVE_MARKERS)
+
+
+def redact_url(url: str) -> str:
+    """Mask credentials and sensitive query parameters in a URL before logging it."""
+    parts = urlsplit(url)
+    netloc = parts.netloc
+    if parts.password:
+        netloc = netloc.replace(f":{parts.password}@", f":{REDACTED}@")
+    query = parts.query
+    if query:
+        pairs = parse_qsl(query, keep_blank_values=True)
+        query = urlencode([(k, REDACTED if is_sensitive(k) else v) for k, v in pairs], safe="*")
+    return urlunsplit(parts._replace(netloc=netloc, query=query))
+
+
+def redact_headers(headers: Dict[str, str]) -> Dict[str, str]:
+    """Return a copy of the headers with sensitive values masked."""
+    return {k: REDACTED if is_sensitive(k) else v for k, v in headers.items()}
+
+
+# JSON fields masked in API responses before they reach the model: a name
+# matches the field at any depth, a dotted path such as customer.email the
+# field at the end of that path
+REDACT_FIELDS: List[str] = []
+REDACT_FIELDS += [f.strip().lower() for f in os.getenv("RESPONSE_REDACT", "").split(",") if f.strip()]
+REDACT_RULES: List[Tuple[str, ...]] = [tuple(f.split(".")) for f in REDACT_FIELDS]
+
+
+def _redact_value(value: Any, path: Tuple[str, ...]) -> Any:
+    if isinstance(value, dict):
+        redacted: Dict[Any, Any] = {}
+        for key, field in value.items():
+            field_path = path + (str(key).lower(),)
+            if any(field_path[-len(rule):] == rule for rule in REDACT_RULES if len(rule) <= len(field_path)):
+                redacted[key] = REDACTED
+            else:
+                redacted[key] = _redact_value(field, field_path)
+        return redacted
+    if isinstance(value, list):
+        return [_redact_value(element, path) for element in value]
+    return value
+
+
+def redact_response(text: str) -> str:
+    """Mask the configured fields of a JSON response; other responses are returned as is."""
+    if not REDACT_RULES:
+        return text
+    try:
+        data = json.loads(text)
+    except ValueError:
+        return text
+    redacted = _redact_value(data, ())
+    if redacted == data:
+        return text
+    return json.dumps(redacted, ensure_ascii=False)
+
+
+# Create MCP server
+mcp = FastMCP("Pet Store", instructions="MCP Server for Pet Store API")
+
+# Get service URL from environment
+service_url = os.getenv("SERVICE_URL", "http://localhost:8080")
+logger.info(f"Using service URL: {redact_url(service_url)}")
+
+# Upstream authentication, configured through SERVICE_AUTH_* environment variables
+auth_header = os.getenv("SERVICE_AUTH_HEADER", "")
+auth_bearer_token = os.getenv("SERVICE_AUTH_BEARER_TOKEN", "")
+auth_basic_username = os.getenv("SERVICE_AUTH_BASIC_USERNAME", "")
+auth_basic_password = os.getenv("SERVICE_AUTH_BASIC_PASSWORD", "")
+auth_api_key = os.getenv("SERVICE_AUTH_API_KEY", "")
+auth_api_key_header = os.getenv("SERVICE_AUTH_API_KEY_HEADER", "X-API-Key")
+auth_api_key_query = os.getenv("SERVICE_AUTH_API_KEY_QUERY", "")
+
+
+class ServiceAuth(httpx.Auth):
+    """Inject the configured credentials into every request sent to the service."""
+
+    def auth_flow(self, request: httpx.Request) -> Generator[httpx.Request, httpx.Response, None]:
+        # Authorization header: raw value, bearer token or basic credentials,
+        # unless the tool already set one for its API
+        if "Authorization" in request.headers:
+            pass
+        elif auth_header:
+            request.headers["Authorization"] = auth_header
+        elif auth_bearer_token:
+            request.headers["Authorization"] = f"Bearer {auth_bearer_token}"
+        elif auth_basic_username:
+            credentials = f"{auth_basic_username}:{auth_basic_password}".encode()
+            request.headers["Authorization"] = f"Basic {base64.b64encode(credentials).decode()}"
+
+        # API key, sent either as a query parameter or as a header
+        if auth_api_key:
+            if auth_api_key_query:
+                request.url = request.url.copy_merge_params({auth_api_key_query: auth_api_key})
+            else:
+                request.headers[auth_api_key_header] = auth_api_key
+
+        yield request
+
+
+def redact_secrets(text: str) -> str:
+    """Mask any configured credential that appears verbatim in a log message."""
+    for secret in (auth_header, auth_bearer_token, auth_basic_password, auth_api_key):
+        if secret:
+            text = text.replace(secret, REDACTED)
+    return text
+
+
+# Shared async HTTP client with connection pooling
+http_limits = httpx.Limits(
+    max_connections=int(os.getenv("HTTP_MAX_CONNECTIONS", "100")),
+    max_keepalive_connections=int(os.getenv("HTTP_MAX_KEEPALIVE_CONNECTIONS", "20")),
+)
+http_timeout = httpx.Timeout(
+    float(os.getenv("HTTP_TIMEOUT", "30")),
+    connect=float(os.getenv("HTTP_CONNECT_TIMEOUT", "10")),
+)
+_http_client: Optional[httpx.AsyncClient] = None
+
+
+def get_http_client() -> httpx.AsyncClient:
+    """Return the shared HTTP client, creating it on first use."""
+    global _http_client
+    if _http_client is None or _http_client.is_closed:
+        _http_client = httpx.AsyncClient(limits=http_limits, timeout=http_timeout, auth=ServiceAuth())
+    return _http_client
+
+
+# Retry policy for transient upstream failures
+http_retries = int(os.getenv("HTTP_RETRIES", "2"))
+http_retry_backoff = float(os.getenv("HTTP_RETRY_BACKOFF", "0.5"))
+http_retry_max_delay = float(os.getenv("HTTP_RETRY_MAX_DELAY", "10"))
+http_retry_unsafe = os.getenv("HTTP_RETRY_UNSAFE", "false").lower() in ("1", "true", "yes")
+RETRYABLE_STATUS_CODES = {429, 502, 503, 504}
+IDEMPOTENT_METHODS = {"GET", "HEAD", "OPTIONS", "PUT", "DELETE"}
+
+
+def retry_delay(attempt: int, response: Optional[httpx.Response] = None) -> float:
+    """Compute the exponential backoff delay, honouring a numeric Retry-After header."""
+    delay = http_retry_backoff * (2 ** attempt)
+    if response is not None:
+        retry_after = response.headers.get("Retry-After", "")
+        if retry_after.isdigit():
+            delay = float(retry_after)
+    return min(delay, http_retry_max_delay)
+
+
+async def request_with_retries(method: str, url: str, **kwargs: Any) -> httpx.Response:
+    """Send a request to the service, retrying transient failures with backoff.
+
+    Non-idempotent methods are only retried when HTTP_RETRY_UNSAFE is enabled.
+    """
+    client = get_http_client()
+    retries = http_retries if method in IDEMPOTENT_METHODS or http_retry_unsafe else 0
+    attempt = 0
+    while True:
+        try:
+            if request_semaphore is None:
+                response = await client.request(method, url, **kwargs)
+            else:
+                async with request_semaphore:
+                    response = await client.request(method, url, **kwargs)
+            if response.status_code not in RETRYABLE_STATUS_CODES or attempt >= retries:
+                return response
+            delay = retry_delay(attempt, response)
+            reason = f"status {response.status_code}"
+        except httpx.TransportError as e:
+            if attempt >= retries:
+                raise
+            delay = retry_delay(attempt)
+            reason = type(e).__name__
+        attempt += 1
+        logger.warning(f"{method} {redact_url(url)} failed ({reason}), retrying in {delay:.1f}s ({attempt}/{retries})")
+        await asyncio.sleep(delay)
+
+
+# Concurrency controls for upstream calls
+max_concurrent_requests = int(os.getenv("HTTP_MAX_CONCURRENT_REQUESTS", "0"))
+coalesce_get_requests = os.getenv("HTTP_COALESCE_GETS", "false").lower() in ("1", "true", "yes")
+request_semaphore: Optional[asyncio.Semaphore] = (
+    asyncio.Semaphore(max_concurrent_requests) if max_concurrent_requests > 0 else None
+)
+_inflight_gets: Dict[str, "asyncio.Future[httpx.Response]"] = {}
+
+
+async def send_request(method: str, url: str, **kwargs: Any) -> httpx.Response:
+    """Send a request to the service.
+
+    Identical GET requests that are already in flight share a single upstream
+    call when HTTP_COALESCE_GETS is enabled.
+    """
+    if method != "GET" or not coalesce_get_requests:
+        return await request_with_retries(method, url, **kwargs)
+
+    headers = kwargs.get("headers") or {}
+    key = url + "\n" + json.dumps(sorted(headers.items()))
+    pending = _inflight_gets.get(key)
+    if pending is None:
+        pending = asyncio.ensure_future(request_with_retries(method, url, **kwargs))
+        _inflight_gets[key] = pending
+        pending.add_done_callback(lambda _: _inflight_gets.pop(key, None))
+    else:
+        logger.debug(f"Coalescing GET {redact_url(url)} with an in-flight request")
+    # Shield the shared call so one cancelled caller doesn't cancel it for the others
+    return await asyncio.shield(pending)
+
+
+def build_url(base_url: str, path: str, path_params: Optional[Dict[str, Any]] = None,
+              query_params: Optional[Dict[str, Any]] = None) -> str:
+    """Build URL with path parameters and query parameters."""
+    # Handle path parameters
+    if path_params:
+        for key, value in path_params.items():
+            path = path.replace("{" + key + "}", str(value))
+
+    # Normalize URL joining
+    if base_url.endswith("/") and path.startswith("/"):
+        path = path[1:]
+    elif not base_url.endswith("/") and not path.startswith("/"):
+        base_url += "/"
+
+    url = base_url + path
+
+    # Add query parameters
+    if query_params:
+        url += "?" + urlencode(query_params)
+
+    # Return the URL
+    return url
+
+
+# Path probed by the check_api_health tool
+health_check_path = os.getenv("HEALTH_CHECK_PATH", "/")
+
+
+@mcp.tool()
+async def check_api_health() -> str:
+    """Check whether the API service is reachable and report its response latency.
+
+    Use this to diagnose failing tool calls before retrying them.
+    """
+    url = build_url(service_url, health_check_path)
+    started = time.perf_counter()
+    try:
+        response = await get_http_client().get(url)
+    except httpx.RequestError as e:
+        return json.dumps({
+            "reachable": False,
+            "url": redact_url(url),
+            "error": redact_secrets(f"{type(e).__name__}: {e}"),
+        })
+    latency_ms = round((time.perf_counter() - started) * 1000, 1)
+    return json.dumps({
+        "reachable": True,
+        "healthy": response.is_success,
+        "status_code": response.status_code,
+        "latency_ms": latency_ms,
+        "url": redact_url(url),
+    })
+
+
+async def get_pets_petid(petId: str, fields: Optional[str] = None, X_Tenant: Optional[str] = None) -> str:
+    """Get a pet"""
+    path_params: Dict[str, Any] = {}
+    query_params: Dict[str, Any] = {}
+    if petId is not None:
+        path_params["petId"] = petId
+    if fields is not None:
+        query_params["fields"] = fields
+    url = build_url(service_url, "/pets/{petId}", path_params, query_params)
+    logger.info(f"Making request to: {redact_url(url)}")
+
+    headers = {"Content-Type": "application/json"}
+    if X_Tenant is not None:
+        headers["X-Tenant"] = str(X_Tenant)
+    logger.debug(f"Request headers: {redact_headers(headers)}")
+
+    try:
+        response = await send_request("GET", url, headers=headers)
+        response.raise_for_status()
+        return redact_response(response.text)
+    except httpx.RequestError as e:
+        error_msg = redact_secrets(str(e))
+        logger.error(f"get_ request failed: {error_msg}")
+        raise
+    except httpx.HTTPStatusError as e:
+        error_msg = str(e)
+        if e.response is not None:
+            error_msg = f"{error_msg} - Response: {redact_response(e.response.text)}"
+        error_msg = redact_secrets(error_msg)
+        logger.error(f"get_ request failed: {error_msg}")
+        raise
+
+
+# Metadata for every operation tool, used for registration and discovery
+OPERATIONS: Dict[str, Dict[str, Any]] = {
+    "get_pets_petid": {"method": "GET", "path": "/pets/{petId}", "description": "Get a pet", "tags": []},
+}
+
+OPERATION_TOOLS: Dict[str, Callable[..., Awaitable[str]]] = {
+    "get_pets_petid": get_pets_petid,
+}
+
+# Register operation tools; when MCP_CORE_TOOLS is set only the listed ones are
+# registered up front
+core_tools = {name.strip() for name in os.getenv("MCP_CORE_TOOLS", "").split(",") if name.strip()}
+registered_tools: Set[str] = set()
+for tool_name, tool_fn in OPERATION_TOOLS.items():
+    if not core_tools or tool_name in core_tools:
+        mcp.add_tool(tool_fn, name=tool_name)
+        registered_tools.add(tool_name)
+
+
+# mcprox:begin protected custom
+# Code between these markers, e.g. tools of your own decorated with
+# @mcp.tool(), is kept when the server is regenerated.
+# mcprox:end protected custom
+
+
+def parse_args() -> argparse.Namespace:
+    """Parse command line arguments, falling back to environment variables."""
+    parser = argparse.ArgumentParser(description="Run the MCP server")
+    parser.add_argument(
+        "--transport",
+        choices=["stdio", "sse"],
+        default=os.getenv("MCP_TRANSPORT", "stdio"),
+        help="MCP transport to use (default: stdio)",
+    )
+    parser.add_argument(
+        "--host",
+        default=os.getenv("MCP_HOST", "127.0.0.1"),
+        help="Interface for network transports, 0.0.0.0 to accept remote clients (ignored for stdio)",
+    )
+    parser.add_argument(
+        "--port",
+        type=int,
+        default=int(os.getenv("PORT", "8000")),
+        help="Port for network transports (ignored for stdio)",
+    )
+    return parser.parse_args()
+
+
+def main() -> None:
+    """Run the MCP server; the entry point of the installed console command."""
+    args = parse_args()
+    if args.transport == "stdio":
+        logger.info("Starting MCP server on stdio")
+    else:
+        # Host and port are only meaningful for network transports
+        mcp.settings.host = args.host
+        mcp.settings.port = args.port
+        if args.host not in ("127.0.0.1", "localhost", "::1") and getattr(mcp.settings, "transport_security", None) is not None:
+            # FastMCP created for the default loopback host only accepts
+            # requests addressed to localhost, which remote clients aren't
+            mcp.settings.transport_security = None
+        logger.info(f"Starting MCP server with {args.transport} transport on {args.host}:{args.port}")
+    mcp.run(transport=args.transport)
+
+
+if __name__ == "__main__":
+    main()
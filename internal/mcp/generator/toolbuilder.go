@@ -1,23 +1,113 @@
 package generator
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
+	"github.com/berkantay/mcprox/internal/config"
 	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/berkantay/mcprox/internal/transform"
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
 // ToolBuilder handles the generation of Python code for MCP tools
 type ToolBuilder struct {
-	builder strings.Builder
+	builder    codeBuilder
+	operations []operationSpan
+	// paramNames maps the parameters of the operation being written to their
+	// Python identifiers
+	paramNames map[*openapi3.Parameter]string
+	// sources are the APIs aggregated into a merged document, if any
+	sources []config.Source
+	// toolID is the operation tool being written
+	toolID string
+	// transformed lists the tools whose responses are transformed
+	transformed map[string]bool
+	// requestRules mutate the upstream requests of tools
+	requestRules transform.RequestRules
+	// rule is the request rule of the operation being written
+	rule transform.RequestRule
+	// mutatedBodies lists the tools whose request bodies are mutated
+	mutatedBodies map[string]bool
+	// sdkVersion is the MCP Python SDK release the code targets, empty for
+	// the latest
+	sdkVersion string
+	// maxNameLength is the length tool IDs are shortened to, 0 for no limit
+	maxNameLength int
+}
+
+// reservedFunctionNames are identifiers referenced inside generated tool functions;
+// a parameter with one of these names would shadow them
+var reservedFunctionNames = map[string]bool{
+	"path_params": true, "query_params": true, "url": true, "headers": true, "body": true, "response": true,
+	"json_body": true, "e": true, "error_msg": true, "service_url": true,
+	"build_url": true, "send_request": true, "logger": true, "json": true,
+	"httpx": true, "redact_url": true, "redact_headers": true, "redact_secrets": true,
+	"redact_response": true, "transform_response": true, "transform_request_body": true,
+	"str": true, "isinstance": true, "source_service_urls": true, "source_auth_headers": true,
+}
+
+// operationSpan records a generated operation tool and which lines of the
+// generated code belong to it
+type operationSpan struct {
+	toolID      string
+	method      string
+	path        string
+	source      string
+	description string
+	tags        []string
+	op          *openapi3.Operation
+	startLine   int
+	endLine     int
+}
+
+// codeBuilder is a strings.Builder keeping count of the lines written, so
+// that the current line is known without rescanning the code
+type codeBuilder struct {
+	strings.Builder
+	// newlines is the number of newlines written
+	newlines int
+}
+
+// Write appends p to the code
+func (b *codeBuilder) Write(p []byte) (int, error) {
+	b.newlines += bytes.Count(p, []byte("\n"))
+	return b.Builder.Write(p)
+}
+
+// WriteString appends s to the code
+func (b *codeBuilder) WriteString(s string) (int, error) {
+	b.newlines += strings.Count(s, "\n")
+	return b.Builder.WriteString(s)
+}
+
+// Reset empties the code
+func (b *codeBuilder) Reset() {
+	b.Builder.Reset()
+	b.newlines = 0
 }
 
 // NewToolBuilder creates a new ToolBuilder instance
 func NewToolBuilder() *ToolBuilder {
-	return &ToolBuilder{
-		builder: strings.Builder{},
-	}
+	return &ToolBuilder{}
+}
+
+// SetSDKVersion sets the MCP Python SDK release the code targets, empty for
+// the latest one
+func (tb *ToolBuilder) SetSDKVersion(version string) {
+	tb.sdkVersion = version
+}
+
+// SetMaxNameLength sets the length tool IDs are shortened to, 0 for no limit
+func (tb *ToolBuilder) SetMaxNameLength(length int) {
+	tb.maxNameLength = length
+}
+
+// streamableHTTP reports whether the targeted SDK serves streamable HTTP
+func (tb *ToolBuilder) streamableHTTP() bool {
+	return utils.SDKAtLeast(tb.sdkVersion, utils.StreamableHTTPSDKVersion)
 }
 
 // String returns the built string
@@ -25,40 +115,321 @@ func (tb *ToolBuilder) String() string {
 	return tb.builder.String()
 }
 
-// WriteImports writes the Python imports
-func (tb *ToolBuilder) WriteImports() {
-	fmt.Fprintf(&tb.builder, `
-#!/usr/bin/env python3
-"""
+// currentLine returns the 1-based number of the line being written
+func (tb *ToolBuilder) currentLine() int {
+	return tb.builder.newlines + 1
+}
+
+// operationAt returns the operation whose generated code contains the given line
+func (tb *ToolBuilder) operationAt(line int) (operationSpan, bool) {
+	for _, op := range tb.operations {
+		if line >= op.startLine && line <= op.endLine {
+			return op, true
+		}
+	}
+	return operationSpan{}, false
+}
+
+// WriteHeader writes the module header, stamped with the mcprox version and
+// the specs the code was generated from
+func (tb *ToolBuilder) WriteHeader(metadata Metadata) {
+	fmt.Fprintf(&tb.builder, "#!/usr/bin/env python3\n")
+	fmt.Fprintf(&tb.builder, "# Generated by mcprox %s at %s. Do not edit by hand; regenerate instead.\n",
+		metadata.GeneratorVersion, metadata.GeneratedAt)
+	for _, spec := range metadata.Specs {
+		fmt.Fprintf(&tb.builder, "# Spec: %s (sha256:%s)\n", spec.URL, spec.SHA256)
+	}
+	fmt.Fprintf(&tb.builder, `"""
 MCP Server generated from OpenAPI specification.
 """
-import os
+`)
+}
+
+// WriteImports writes the Python imports
+func (tb *ToolBuilder) WriteImports() {
+	fmt.Fprintf(&tb.builder, `import os
+import inspect
+import asyncio
+import base64
+import argparse
 import httpx
 import logging
 import json
-from urllib.parse import urlencode
-from typing import Dict, Any, Optional, Union
+import time
+from urllib.parse import urlencode, urlsplit, urlunsplit, parse_qsl
+from typing import Dict, Any, Optional, Union, Callable, Awaitable, Generator, List, Set, Tuple
 
 # Import MCP framework
 from mcp.server.fastmcp import FastMCP
+from dotenv import load_dotenv
+
+# Load configuration from a .env file in the project root, if present
+load_dotenv()
 `)
 }
 
-// WriteSetupLogger writes the logger setup code
+// WriteSetupLogger writes the logger setup code and the log redaction helpers
 func (tb *ToolBuilder) WriteSetupLogger() {
 	fmt.Fprintf(&tb.builder, `
 # Configure logging
-logging.basicConfig(level=logging.INFO)
+log_level = os.getenv("LOG_LEVEL", "INFO").upper()
+log_format = os.getenv("LOG_FORMAT", "text").lower()
+
+
+class JSONFormatter(logging.Formatter):
+    """Format log records as single-line JSON objects."""
+
+    def format(self, record: logging.LogRecord) -> str:
+        entry = {
+            "time": self.formatTime(record),
+            "level": record.levelname,
+            "logger": record.name,
+            "message": record.getMessage(),
+        }
+        if record.exc_info:
+            entry["exception"] = self.formatException(record.exc_info)
+        return json.dumps(entry)
+
+
+log_handler = logging.StreamHandler()
+if log_format == "json":
+    log_handler.setFormatter(JSONFormatter())
+else:
+    log_handler.setFormatter(logging.Formatter("%%(asctime)s %%(levelname)s %%(name)s: %%(message)s"))
+logging.basicConfig(level=log_level, handlers=[log_handler])
 logger = logging.getLogger(__name__)
+
+# Query parameters and headers whose values are never written to the logs
+REDACTED = "***"
+SENSITIVE_MARKERS = ("token", "secret", "password", "passwd", "apikey", "api_key", "api-key", "auth", "session", "signature", "credential")
+SENSITIVE_KEYS = {k.strip().lower() for k in os.getenv("LOG_REDACT_KEYS", "").split(",") if k.strip()}
+
+
+def is_sensitive(name: str) -> bool:
+    """Report whether a query parameter or header name carries a secret."""
+    lowered = name.lower()
+    return lowered in SENSITIVE_KEYS or any(marker in lowered for marker in SENSITIVE_MARKERS)
+
+
+def redact_url(url: str) -> str:
+    """Mask credentials and sensitive query parameters in a URL before logging it."""
+    parts = urlsplit(url)
+    netloc = parts.netloc
+    if parts.password:
+        netloc = netloc.replace(f":{parts.password}@", f":{REDACTED}@")
+    query = parts.query
+    if query:
+        pairs = parse_qsl(query, keep_blank_values=True)
+        query = urlencode([(k, REDACTED if is_sensitive(k) else v) for k, v in pairs], safe="*")
+    return urlunsplit(parts._replace(netloc=netloc, query=query))
+
+
+def redact_headers(headers: Dict[str, str]) -> Dict[str, str]:
+    """Return a copy of the headers with sensitive values masked."""
+    return {k: REDACTED if is_sensitive(k) else v for k, v in headers.items()}
+`)
+}
+
+// WriteResponseRedaction writes the helper masking sensitive fields of API
+// responses before tools return them, configured by response.redact at
+// generation time and RESPONSE_REDACT at runtime
+func (tb *ToolBuilder) WriteResponseRedaction(fields []string) {
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			quoted = append(quoted, pyString(strings.ToLower(field)))
+		}
+	}
+	fmt.Fprintf(&tb.builder, `
+# JSON fields masked in API responses before they reach the model: a name
+# matches the field at any depth, a dotted path such as customer.email the
+# field at the end of that path
+REDACT_FIELDS: List[str] = [%s]
+REDACT_FIELDS += [f.strip().lower() for f in os.getenv("RESPONSE_REDACT", "").split(",") if f.strip()]
+REDACT_RULES: List[Tuple[str, ...]] = [tuple(f.split(".")) for f in REDACT_FIELDS]
+
+
+def _redact_value(value: Any, path: Tuple[str, ...]) -> Any:
+    if isinstance(value, dict):
+        redacted: Dict[Any, Any] = {}
+        for key, field in value.items():
+            field_path = path + (str(key).lower(),)
+            if any(field_path[-len(rule):] == rule for rule in REDACT_RULES if len(rule) <= len(field_path)):
+                redacted[key] = REDACTED
+            else:
+                redacted[key] = _redact_value(field, field_path)
+        return redacted
+    if isinstance(value, list):
+        return [_redact_value(element, path) for element in value]
+    return value
+
+
+def redact_response(text: str) -> str:
+    """Mask the configured fields of a JSON response; other responses are returned as is."""
+    if not REDACT_RULES:
+        return text
+    try:
+        data = json.loads(text)
+    except ValueError:
+        return text
+    redacted = _redact_value(data, ())
+    if redacted == data:
+        return text
+    return json.dumps(redacted, ensure_ascii=False)
+`, strings.Join(quoted, ", "))
+}
+
+// WriteTransforms writes the response transforms configured per tool,
+// translated from CEL into Python, and the helper applying them. It must be
+// called before the tool definitions.
+func (tb *ToolBuilder) WriteTransforms(transformer *transform.Transformer) error {
+	tools := transformer.Tools()
+	if len(tools) == 0 {
+		return nil
+	}
+
+	tb.transformed = make(map[string]bool, len(tools))
+	fmt.Fprintf(&tb.builder, "\n# Helpers of the response transforms, translated from CEL\n%s", transform.PythonHelpers)
+	fmt.Fprintf(&tb.builder, "\n\n# Response transforms per tool, translated from their CEL expressions\n")
+	fmt.Fprintf(&tb.builder, "TRANSFORMS: Dict[str, Callable[[Any], Any]] = {\n")
+	for _, tool := range tools {
+		code, err := transformer.Python(tool)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&tb.builder, "    %s: lambda response: %s,\n", pyString(tool), code)
+		tb.transformed[tool] = true
+	}
+	fmt.Fprintf(&tb.builder, `}
+
+
+def transform_response(tool: str, text: str) -> str:
+    """Reshape a JSON response with the tool's transform."""
+    return json.dumps(TRANSFORMS[tool](json.loads(text)), ensure_ascii=False, separators=(",", ":"))
+`)
+	return nil
+}
+
+// WriteRequestRules writes the parts of the request rules applying to request
+// bodies and the helper applying them, and records the rules renaming, setting
+// and dropping parameters. It must be called before the tool definitions.
+func (tb *ToolBuilder) WriteRequestRules(doc *openapi3.T, rules transform.RequestRules) error {
+	tb.requestRules = rules
+	tb.mutatedBodies = make(map[string]bool)
+
+	var entries []string
+	for _, operation := range openapi.Operations(doc) {
+		toolID := utils.SanitizePathForToolID(operation.Path, operation.Method, tb.maxNameLength)
+		rule := bodyRule(operation.Operation, rules.For(toolID))
+		if rule.IsZero() {
+			continue
+		}
+
+		renames := make(map[string]string, len(rule.Rename))
+		for _, rename := range rule.Rename {
+			renames[rename.Argument] = rename.Field
+		}
+		injections := make(map[string]interface{}, len(rule.Inject))
+		for _, injection := range rule.Inject {
+			injections[injection.Field] = injection.Value
+		}
+		code, err := pyValue(map[string]interface{}{"rename": renames, "drop": append([]string{}, rule.Drop...), "inject": injections})
+		if err != nil {
+			return fmt.Errorf("invalid request rule of tool %s: %w", toolID, err)
+		}
+		entries = append(entries, fmt.Sprintf("    %s: %s,\n", pyString(toolID), code))
+		tb.mutatedBodies[toolID] = true
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(&tb.builder, "\n\n# Request body rules per tool: arguments renamed back to the fields the API\n")
+	fmt.Fprintf(&tb.builder, "# expects, fields dropped and constants injected\n")
+	fmt.Fprintf(&tb.builder, "REQUEST_BODY_RULES: Dict[str, Dict[str, Any]] = {\n%s}\n", strings.Join(entries, ""))
+	fmt.Fprintf(&tb.builder, `
+
+def transform_request_body(tool: str, body: Any) -> Any:
+    """Apply the tool's request body rule to a JSON body; other bodies are returned as is."""
+    rule = REQUEST_BODY_RULES[tool]
+    if body is None and rule["inject"]:
+        body = {}
+    if not isinstance(body, dict):
+        return body
+    body = dict(body)
+    for argument, field in rule["rename"].items():
+        if argument in body:
+            body[field] = body.pop(argument)
+    for field in rule["drop"]:
+        body.pop(field, None)
+    body.update(rule["inject"])
+    return body
+`)
+	return nil
+}
+
+// WriteEventStore writes the event store that makes streamable HTTP sessions
+// resumable: clients reconnecting with a Last-Event-ID header are replayed the
+// messages of their stream they missed, e.g. an in-flight tool result
+func (tb *ToolBuilder) WriteEventStore() {
+	if !tb.streamableHTTP() {
+		return
+	}
+	fmt.Fprintf(&tb.builder, `
+# Event store of the streamable-http transport, replaying the messages a client
+# missed when it reconnects with a Last-Event-ID header. Older SDKs without
+# resumability support serve without it.
+try:
+    from collections import OrderedDict
+    from uuid import uuid4
+
+    from mcp.server.streamable_http import EventCallback, EventMessage, EventStore
+
+    class InMemoryEventStore(EventStore):
+        """Keep the latest messages of all streams, up to max_events in total."""
+
+        def __init__(self, max_events: int) -> None:
+            self.max_events = max_events
+            self.events: "OrderedDict[str, tuple[str, Any]]" = OrderedDict()
+
+        async def store_event(self, stream_id: str, message: Any) -> str:
+            event_id = uuid4().hex
+            self.events[event_id] = (stream_id, message)
+            while len(self.events) > self.max_events:
+                self.events.popitem(last=False)
+            return event_id
+
+        async def replay_events_after(self, last_event_id: str, send_callback: EventCallback) -> Optional[str]:
+            if last_event_id not in self.events:
+                logger.warning(f"Cannot resume stream: event {last_event_id} is unknown or expired")
+                return None
+            stream_id = self.events[last_event_id][0]
+            replay = False
+            for event_id, (event_stream_id, message) in list(self.events.items()):
+                if replay and event_stream_id == stream_id and message is not None:
+                    await send_callback(EventMessage(message, event_id))
+                elif event_id == last_event_id:
+                    replay = True
+            return stream_id
+
+    event_history = int(os.getenv("MCP_EVENT_HISTORY", "10000"))
+    event_store: Optional[EventStore] = InMemoryEventStore(event_history) if event_history > 0 else None
+except ImportError:
+    event_store = None
 `)
 }
 
-// WriteCreateMCPServer writes the code to create an MCP server
+// WriteCreateMCPServer writes the code to create an MCP server, describing
+// it to clients in the instructions FastMCP takes
 func (tb *ToolBuilder) WriteCreateMCPServer(serverName string) {
+	eventStore := ""
+	if tb.streamableHTTP() {
+		eventStore = ", event_store=event_store"
+	}
 	fmt.Fprintf(&tb.builder, `
 # Create MCP server
-mcp = FastMCP("%s", description="MCP Server for %s API")
-`, serverName, serverName)
+mcp = FastMCP(%s, instructions=%s%s)
+`, pyString(serverName), pyString(fmt.Sprintf("MCP Server for %s API", serverName)), eventStore)
 }
 
 // WriteGetServiceURL writes the code to get the service URL from environment
@@ -66,21 +437,209 @@ func (tb *ToolBuilder) WriteGetServiceURL() {
 	fmt.Fprintf(&tb.builder, `
 # Get service URL from environment
 service_url = os.getenv("SERVICE_URL", "http://localhost:8080")
-logger.info(f"Using service URL: {service_url}")
+logger.info(f"Using service URL: {redact_url(service_url)}")
+`)
+}
+
+// WriteSourceSettings writes the base URL and Authorization header of every
+// API aggregated into the server. It writes nothing for single-API servers.
+func (tb *ToolBuilder) WriteSourceSettings(sources []config.Source) {
+	tb.sources = sources
+	if len(sources) == 0 {
+		return
+	}
+
+	fmt.Fprintf(&tb.builder, "\n# Base URLs and Authorization headers of the aggregated APIs; unset ones fall\n")
+	fmt.Fprintf(&tb.builder, "# back to SERVICE_URL and the SERVICE_AUTH_* credentials\n")
+	fmt.Fprintf(&tb.builder, "source_service_urls = {\n")
+	for _, source := range sources {
+		fallback := "service_url"
+		if source.ServiceURL != "" {
+			fallback = pyString(source.ServiceURL)
+		}
+		fmt.Fprintf(&tb.builder, "    %s: os.getenv(%s, %s),\n",
+			pyString(source.Name), pyString("SERVICE_URL_"+strings.ToUpper(source.Name)), fallback)
+	}
+	fmt.Fprintf(&tb.builder, "}\n")
+	fmt.Fprintf(&tb.builder, "source_auth_headers = {\n")
+	for _, source := range sources {
+		fmt.Fprintf(&tb.builder, "    %s: os.getenv(%s, \"\"),\n",
+			pyString(source.Name), pyString("SERVICE_AUTH_HEADER_"+strings.ToUpper(source.Name)))
+	}
+	fmt.Fprintf(&tb.builder, "}\n")
+}
+
+// WriteServiceAuth writes the authentication hook applied to every upstream request
+func (tb *ToolBuilder) WriteServiceAuth() {
+	fmt.Fprintf(&tb.builder, `
+# Upstream authentication, configured through SERVICE_AUTH_* environment variables
+auth_header = os.getenv("SERVICE_AUTH_HEADER", "")
+auth_bearer_token = os.getenv("SERVICE_AUTH_BEARER_TOKEN", "")
+auth_basic_username = os.getenv("SERVICE_AUTH_BASIC_USERNAME", "")
+auth_basic_password = os.getenv("SERVICE_AUTH_BASIC_PASSWORD", "")
+auth_api_key = os.getenv("SERVICE_AUTH_API_KEY", "")
+auth_api_key_header = os.getenv("SERVICE_AUTH_API_KEY_HEADER", "X-API-Key")
+auth_api_key_query = os.getenv("SERVICE_AUTH_API_KEY_QUERY", "")
+
+
+class ServiceAuth(httpx.Auth):
+    """Inject the configured credentials into every request sent to the service."""
+
+    def auth_flow(self, request: httpx.Request) -> Generator[httpx.Request, httpx.Response, None]:
+        # Authorization header: raw value, bearer token or basic credentials,
+        # unless the tool already set one for its API
+        if "Authorization" in request.headers:
+            pass
+        elif auth_header:
+            request.headers["Authorization"] = auth_header
+        elif auth_bearer_token:
+            request.headers["Authorization"] = f"Bearer {auth_bearer_token}"
+        elif auth_basic_username:
+            credentials = f"{auth_basic_username}:{auth_basic_password}".encode()
+            request.headers["Authorization"] = f"Basic {base64.b64encode(credentials).decode()}"
+
+        # API key, sent either as a query parameter or as a header
+        if auth_api_key:
+            if auth_api_key_query:
+                request.url = request.url.copy_merge_params({auth_api_key_query: auth_api_key})
+            else:
+                request.headers[auth_api_key_header] = auth_api_key
+
+        yield request
+`)
+
+	secrets := "auth_header, auth_bearer_token, auth_basic_password, auth_api_key"
+	if len(tb.sources) > 0 {
+		secrets += ", *source_auth_headers.values()"
+	}
+	fmt.Fprintf(&tb.builder, `
+
+def redact_secrets(text: str) -> str:
+    """Mask any configured credential that appears verbatim in a log message."""
+    for secret in (%s):
+        if secret:
+            text = text.replace(secret, REDACTED)
+    return text
+`, secrets)
+}
+
+// WriteHTTPClient writes the shared async HTTP client used by all tools
+func (tb *ToolBuilder) WriteHTTPClient() {
+	fmt.Fprintf(&tb.builder, `
+# Shared async HTTP client with connection pooling
+http_limits = httpx.Limits(
+    max_connections=int(os.getenv("HTTP_MAX_CONNECTIONS", "100")),
+    max_keepalive_connections=int(os.getenv("HTTP_MAX_KEEPALIVE_CONNECTIONS", "20")),
+)
+http_timeout = httpx.Timeout(
+    float(os.getenv("HTTP_TIMEOUT", "30")),
+    connect=float(os.getenv("HTTP_CONNECT_TIMEOUT", "10")),
+)
+_http_client: Optional[httpx.AsyncClient] = None
+
+
+def get_http_client() -> httpx.AsyncClient:
+    """Return the shared HTTP client, creating it on first use."""
+    global _http_client
+    if _http_client is None or _http_client.is_closed:
+        _http_client = httpx.AsyncClient(limits=http_limits, timeout=http_timeout, auth=ServiceAuth())
+    return _http_client
+
+
+# Retry policy for transient upstream failures
+http_retries = int(os.getenv("HTTP_RETRIES", "2"))
+http_retry_backoff = float(os.getenv("HTTP_RETRY_BACKOFF", "0.5"))
+http_retry_max_delay = float(os.getenv("HTTP_RETRY_MAX_DELAY", "10"))
+http_retry_unsafe = os.getenv("HTTP_RETRY_UNSAFE", "false").lower() in ("1", "true", "yes")
+RETRYABLE_STATUS_CODES = {429, 502, 503, 504}
+IDEMPOTENT_METHODS = {"GET", "HEAD", "OPTIONS", "PUT", "DELETE"}
+
+
+def retry_delay(attempt: int, response: Optional[httpx.Response] = None) -> float:
+    """Compute the exponential backoff delay, honouring a numeric Retry-After header."""
+    delay = http_retry_backoff * (2 ** attempt)
+    if response is not None:
+        retry_after = response.headers.get("Retry-After", "")
+        if retry_after.isdigit():
+            delay = float(retry_after)
+    return min(delay, http_retry_max_delay)
+
+
+async def request_with_retries(method: str, url: str, **kwargs: Any) -> httpx.Response:
+    """Send a request to the service, retrying transient failures with backoff.
+
+    Non-idempotent methods are only retried when HTTP_RETRY_UNSAFE is enabled.
+    """
+    client = get_http_client()
+    retries = http_retries if method in IDEMPOTENT_METHODS or http_retry_unsafe else 0
+    attempt = 0
+    while True:
+        try:
+            if request_semaphore is None:
+                response = await client.request(method, url, **kwargs)
+            else:
+                async with request_semaphore:
+                    response = await client.request(method, url, **kwargs)
+            if response.status_code not in RETRYABLE_STATUS_CODES or attempt >= retries:
+                return response
+            delay = retry_delay(attempt, response)
+            reason = f"status {response.status_code}"
+        except httpx.TransportError as e:
+            if attempt >= retries:
+                raise
+            delay = retry_delay(attempt)
+            reason = type(e).__name__
+        attempt += 1
+        logger.warning(f"{method} {redact_url(url)} failed ({reason}), retrying in {delay:.1f}s ({attempt}/{retries})")
+        await asyncio.sleep(delay)
+`)
+}
+
+// WriteConcurrencyControls writes the concurrency limit and GET coalescing around upstream calls
+func (tb *ToolBuilder) WriteConcurrencyControls() {
+	fmt.Fprintf(&tb.builder, `
+# Concurrency controls for upstream calls
+max_concurrent_requests = int(os.getenv("HTTP_MAX_CONCURRENT_REQUESTS", "0"))
+coalesce_get_requests = os.getenv("HTTP_COALESCE_GETS", "false").lower() in ("1", "true", "yes")
+request_semaphore: Optional[asyncio.Semaphore] = (
+    asyncio.Semaphore(max_concurrent_requests) if max_concurrent_requests > 0 else None
+)
+_inflight_gets: Dict[str, "asyncio.Future[httpx.Response]"] = {}
+
+
+async def send_request(method: str, url: str, **kwargs: Any) -> httpx.Response:
+    """Send a request to the service.
+
+    Identical GET requests that are already in flight share a single upstream
+    call when HTTP_COALESCE_GETS is enabled.
+    """
+    if method != "GET" or not coalesce_get_requests:
+        return await request_with_retries(method, url, **kwargs)
+
+    headers = kwargs.get("headers") or {}
+    key = url + "\n" + json.dumps(sorted(headers.items()))
+    pending = _inflight_gets.get(key)
+    if pending is None:
+        pending = asyncio.ensure_future(request_with_retries(method, url, **kwargs))
+        _inflight_gets[key] = pending
+        pending.add_done_callback(lambda _: _inflight_gets.pop(key, None))
+    else:
+        logger.debug(f"Coalescing GET {redact_url(url)} with an in-flight request")
+    # Shield the shared call so one cancelled caller doesn't cancel it for the others
+    return await asyncio.shield(pending)
 `)
 }
 
 // WriteBuildURL writes the function to build URLs
 func (tb *ToolBuilder) WriteBuildURL() {
 	fmt.Fprintf(&tb.builder, `
-def build_url(base_url: str, path: str, params: Dict[str, Any] = None) -> str:
+def build_url(base_url: str, path: str, path_params: Optional[Dict[str, Any]] = None,
+              query_params: Optional[Dict[str, Any]] = None) -> str:
     """Build URL with path parameters and query parameters."""
     # Handle path parameters
-    url = base_url
-    if params:
-        for key, value in params.items():
-            if "{" + key + "}" in path:
-                path = path.replace("{" + key + "}", str(value))
+    if path_params:
+        for key, value in path_params.items():
+            path = path.replace("{" + key + "}", str(value))
 
     # Normalize URL joining
     if base_url.endswith("/") and path.startswith("/"):
@@ -91,19 +650,53 @@ def build_url(base_url: str, path: str, params: Dict[str, Any] = None) -> str:
     url = base_url + path
 
     # Add query parameters
-    if params:
-        query_params = {k: v for k, v in params.items() if "{" + k + "}" not in path}
-        if query_params:
-            url += "?" + urlencode(query_params)
+    if query_params:
+        url += "?" + urlencode(query_params)
 
     # Return the URL
     return url
 `)
 }
 
+// WriteHealthCheckTool writes the built-in tool reporting upstream reachability
+func (tb *ToolBuilder) WriteHealthCheckTool() {
+	fmt.Fprintf(&tb.builder, `
+# Path probed by the check_api_health tool
+health_check_path = os.getenv("HEALTH_CHECK_PATH", "/")
+
+
+@mcp.tool()
+async def check_api_health() -> str:
+    """Check whether the API service is reachable and report its response latency.
+
+    Use this to diagnose failing tool calls before retrying them.
+    """
+    url = build_url(service_url, health_check_path)
+    started = time.perf_counter()
+    try:
+        response = await get_http_client().get(url)
+    except httpx.RequestError as e:
+        return json.dumps({
+            "reachable": False,
+            "url": redact_url(url),
+            "error": redact_secrets(f"{type(e).__name__}: {e}"),
+        })
+    latency_ms = round((time.perf_counter() - started) * 1000, 1)
+    return json.dumps({
+        "reachable": True,
+        "healthy": response.is_success,
+        "status_code": response.status_code,
+        "latency_ms": latency_ms,
+        "url": redact_url(url),
+    })
+`)
+}
+
 // WriteToolDefinition writes the code for a tool definition
-func (tb *ToolBuilder) WriteToolDefinition(path, method string, op *openapi3.Operation) {
-	toolID := utils.SanitizePathForToolID(path, method)
+func (tb *ToolBuilder) WriteToolDefinition(path, method string, op *openapi3.Operation) error {
+	toolID := utils.SanitizePathForToolID(path, method, tb.maxNameLength)
+	source := openapi.SourceName(op)
+	path = openapi.UpstreamPath(path, op)
 	description := op.Summary
 	if description == "" {
 		description = op.Description
@@ -112,8 +705,28 @@ func (tb *ToolBuilder) WriteToolDefinition(path, method string, op *openapi3.Ope
 		description = fmt.Sprintf("%s %s", method, path)
 	}
 
-	// Start building tool registration code
-	fmt.Fprintf(&tb.builder, "\n@mcp.tool()\ndef %s(", toolID)
+	// Track the lines of this operation so errors can be traced back to it
+	span := operationSpan{
+		toolID:      toolID,
+		method:      method,
+		path:        path,
+		source:      source,
+		description: description,
+		tags:        op.Tags,
+		op:          op,
+		startLine:   tb.currentLine() + 1,
+	}
+	defer func() {
+		span.endLine = tb.currentLine()
+		tb.operations = append(tb.operations, span)
+	}()
+
+	tb.toolID = toolID
+	tb.rule = tb.requestRules.For(toolID)
+	tb.paramNames = resolveParamNames(op, tb.rule)
+
+	// Start building the tool function; it is registered by WriteToolRegistry
+	fmt.Fprintf(&tb.builder, "\n\nasync def %s(", toolID)
 
 	// Add parameters
 	var params []string
@@ -126,12 +739,42 @@ func (tb *ToolBuilder) WriteToolDefinition(path, method string, op *openapi3.Ope
 	params = append(requiredParams, optionalParams...)
 
 	fmt.Fprintf(&tb.builder, "%s) -> str:\n", strings.Join(params, ", "))
-	fmt.Fprintf(&tb.builder, "    \"\"\"%s\"\"\"\n", description)
+	fmt.Fprintf(&tb.builder, "    \"\"\"%s\"\"\"\n", pyDocstring(description))
 
-	tb.writeParametersDictionary(op)
-	tb.writeBuildURLCall(path)
-	tb.writeHeadersSetup(op)
+	if err := tb.writeParametersDictionary(op); err != nil {
+		return err
+	}
+	tb.writeBuildURLCall(path, source)
+	tb.writeHeadersSetup(op, source)
 	tb.writeRequestCode(method, op)
+	return nil
+}
+
+// resolveParamNames assigns a unique, valid Python identifier to every parameter
+// of an operation, as renamed by its request rule, renaming those that would
+// shadow names used by the generated code or collide with each other after
+// sanitization
+func resolveParamNames(op *openapi3.Operation, rule transform.RequestRule) map[*openapi3.Parameter]string {
+	var params []*openapi3.Parameter
+	var names []string
+	for _, paramRef := range op.Parameters {
+		if paramRef == nil || paramRef.Value == nil {
+			continue
+		}
+
+		name := utils.SanitizeParamName(rule.Argument(paramRef.Value.Name))
+		for reservedFunctionNames[name] {
+			name += "_"
+		}
+		params = append(params, paramRef.Value)
+		names = append(names, name)
+	}
+
+	resolved := make(map[*openapi3.Parameter]string, len(params))
+	for i, name := range utils.UniqueNames(names) {
+		resolved[params[i]] = name
+	}
+	return resolved
 }
 
 // buildParameterLists builds the lists of required and optional parameters
@@ -143,7 +786,10 @@ func (tb *ToolBuilder) buildParameterLists(op *openapi3.Operation, requiredParam
 		}
 
 		param := paramRef.Value
-		paramName := utils.SanitizeParamName(param.Name)
+		if tb.rule.Hidden(param.Name) {
+			continue
+		}
+		paramName := tb.paramNames[param]
 		paramType := "str" // Default to string type
 
 		if param.Schema != nil && param.Schema.Value != nil {
@@ -174,88 +820,311 @@ func (tb *ToolBuilder) buildParameterLists(op *openapi3.Operation, requiredParam
 	}
 }
 
-// writeParametersDictionary writes the code to build the parameters dictionary
-func (tb *ToolBuilder) writeParametersDictionary(op *openapi3.Operation) {
-	fmt.Fprintf(&tb.builder, "    params: Dict[str, Any] = {}\n")
+// paramDictionaries are the dictionaries of the generated code collecting the
+// parameters build_url puts into the URL, by location. Header parameters are
+// set by writeHeadersSetup.
+var paramDictionaries = map[string]string{
+	"path":  "path_params",
+	"query": "query_params",
+}
+
+// writeParametersDictionary writes the code to build the path and query
+// parameter dictionaries, with the constants of injected parameters and
+// without dropped ones. They are keyed by location and name, as a path and a
+// query parameter may share a name.
+func (tb *ToolBuilder) writeParametersDictionary(op *openapi3.Operation) error {
+	fmt.Fprintf(&tb.builder, "    path_params: Dict[str, Any] = {}\n")
+	fmt.Fprintf(&tb.builder, "    query_params: Dict[str, Any] = {}\n")
 	for _, paramRef := range op.Parameters {
 		if paramRef == nil || paramRef.Value == nil {
 			continue
 		}
 
 		param := paramRef.Value
-		paramName := utils.SanitizeParamName(param.Name)
+		dict, ok := paramDictionaries[param.In]
+		if !ok {
+			continue
+		}
+		if value, ok := tb.rule.Injected(param.Name); ok {
+			code, err := pyValue(value)
+			if err != nil {
+				return fmt.Errorf("invalid value injected into %s of tool %s: %w", param.Name, tb.toolID, err)
+			}
+			fmt.Fprintf(&tb.builder, "    %s[%s] = %s\n", dict, pyString(param.Name), code)
+			continue
+		}
+		if tb.rule.Dropped(param.Name) {
+			continue
+		}
+		paramName := tb.paramNames[param]
 		fmt.Fprintf(&tb.builder, "    if %s is not None:\n", paramName)
-		fmt.Fprintf(&tb.builder, "        params[\"%s\"] = %s\n", param.Name, paramName)
+		fmt.Fprintf(&tb.builder, "        %s[%s] = %s\n", dict, pyString(param.Name), paramName)
 	}
+	return nil
 }
 
-// writeBuildURLCall writes the code to build the URL
-func (tb *ToolBuilder) writeBuildURLCall(path string) {
-	fmt.Fprintf(&tb.builder, "    url = build_url(service_url, \"%s\", params)\n", path)
-	fmt.Fprintf(&tb.builder, "    logger.info(f\"Making request to: {url}\")\n\n")
+// writeBuildURLCall writes the code to build the URL against the base URL of
+// the operation's API
+func (tb *ToolBuilder) writeBuildURLCall(path, source string) {
+	baseURL := "service_url"
+	if source != "" {
+		baseURL = fmt.Sprintf("source_service_urls[%s]", pyString(source))
+	}
+	fmt.Fprintf(&tb.builder, "    url = build_url(%s, %s, path_params, query_params)\n", baseURL, pyString(path))
+	fmt.Fprintf(&tb.builder, "    logger.info(f\"Making request to: {redact_url(url)}\")\n\n")
 }
 
 // writeHeadersSetup writes the code to set up headers
-func (tb *ToolBuilder) writeHeadersSetup(op *openapi3.Operation) {
+func (tb *ToolBuilder) writeHeadersSetup(op *openapi3.Operation, source string) {
 	fmt.Fprintf(&tb.builder, "    headers = {\"Content-Type\": \"application/json\"}\n")
+	if source != "" {
+		authHeader := fmt.Sprintf("source_auth_headers[%s]", pyString(source))
+		fmt.Fprintf(&tb.builder, "    if %s:\n", authHeader)
+		fmt.Fprintf(&tb.builder, "        headers[\"Authorization\"] = %s\n", authHeader)
+	}
 	for _, paramRef := range op.Parameters {
 		if paramRef == nil || paramRef.Value == nil {
 			continue
 		}
 
 		param := paramRef.Value
-		if param.In == "header" {
-			paramName := utils.SanitizeParamName(param.Name)
-			fmt.Fprintf(&tb.builder, "    if %s is not None:\n", paramName)
-			fmt.Fprintf(&tb.builder, "        headers[\"%s\"] = str(%s)\n", param.Name, paramName)
+		if param.In != "header" || tb.rule.Dropped(param.Name) {
+			continue
+		}
+		if value, ok := tb.rule.Injected(param.Name); ok {
+			// Formatted as serve mode formats header values
+			fmt.Fprintf(&tb.builder, "    headers[%s] = %s\n", pyString(param.Name), pyString(fmt.Sprintf("%v", value)))
+			continue
 		}
+		paramName := tb.paramNames[param]
+		fmt.Fprintf(&tb.builder, "    if %s is not None:\n", paramName)
+		fmt.Fprintf(&tb.builder, "        headers[%s] = str(%s)\n", pyString(param.Name), paramName)
 	}
+	fmt.Fprintf(&tb.builder, "    logger.debug(f\"Request headers: {redact_headers(headers)}\")\n")
 }
 
 // writeRequestCode writes the code to make the HTTP request
 func (tb *ToolBuilder) writeRequestCode(method string, op *openapi3.Operation) {
-	toolID := utils.SanitizePathForToolID("", method) // Only need method for error message
+	toolID := utils.SanitizePathForToolID("", method, tb.maxNameLength) // Only need method for error message
+
+	httpMethod := strings.ToUpper(method)
+
+	// Bodies of tools with a request body rule are mutated before they are sent
+	jsonBody, dictBody := "json_body", "body"
+	if tb.mutatedBodies[tb.toolID] {
+		jsonBody = fmt.Sprintf("transform_request_body(%s, json_body)", pyString(tb.toolID))
+		dictBody = fmt.Sprintf("transform_request_body(%s, body)", pyString(tb.toolID))
+	}
 
 	fmt.Fprintf(&tb.builder, "\n    try:\n")
-	if method == "GET" {
-		fmt.Fprintf(&tb.builder, "        response = httpx.get(url, headers=headers)\n")
+	if method != "GET" && op.RequestBody != nil && op.RequestBody.Value != nil {
+		fmt.Fprintf(&tb.builder, "        # Handle request body\n")
+		fmt.Fprintf(&tb.builder, "        if isinstance(body, str):\n")
+		fmt.Fprintf(&tb.builder, "            try:\n")
+		fmt.Fprintf(&tb.builder, "                # Try to parse as JSON\n")
+		fmt.Fprintf(&tb.builder, "                json_body = json.loads(body)\n")
+		fmt.Fprintf(&tb.builder, "                response = await send_request(\"%s\", url, headers=headers, json=%s)\n", httpMethod, jsonBody)
+		fmt.Fprintf(&tb.builder, "            except json.JSONDecodeError:\n")
+		fmt.Fprintf(&tb.builder, "                # If not JSON, send as raw string\n")
+		fmt.Fprintf(&tb.builder, "                response = await send_request(\"%s\", url, headers=headers, content=body)\n", httpMethod)
+		fmt.Fprintf(&tb.builder, "        else:\n")
+		fmt.Fprintf(&tb.builder, "            response = await send_request(\"%s\", url, headers=headers, json=%s)\n", httpMethod, dictBody)
 	} else {
-		if op.RequestBody != nil && op.RequestBody.Value != nil {
-			fmt.Fprintf(&tb.builder, "        # Handle request body\n")
-			fmt.Fprintf(&tb.builder, "        if isinstance(body, str):\n")
-			fmt.Fprintf(&tb.builder, "            try:\n")
-			fmt.Fprintf(&tb.builder, "                # Try to parse as JSON\n")
-			fmt.Fprintf(&tb.builder, "                json_body = json.loads(body)\n")
-			fmt.Fprintf(&tb.builder, "                response = httpx.%s(url, headers=headers, json=json_body)\n", strings.ToLower(method))
-			fmt.Fprintf(&tb.builder, "            except json.JSONDecodeError:\n")
-			fmt.Fprintf(&tb.builder, "                # If not JSON, send as raw string\n")
-			fmt.Fprintf(&tb.builder, "                response = httpx.%s(url, headers=headers, content=body)\n", strings.ToLower(method))
-			fmt.Fprintf(&tb.builder, "        else:\n")
-			fmt.Fprintf(&tb.builder, "            response = httpx.%s(url, headers=headers, json=body)\n", strings.ToLower(method))
-		} else {
-			fmt.Fprintf(&tb.builder, "        response = httpx.%s(url, headers=headers)\n", strings.ToLower(method))
-		}
+		fmt.Fprintf(&tb.builder, "        response = await send_request(\"%s\", url, headers=headers)\n", httpMethod)
 	}
 	fmt.Fprintf(&tb.builder, "        response.raise_for_status()\n")
-	fmt.Fprintf(&tb.builder, "        return response.text\n")
+	if tb.transformed[tb.toolID] {
+		fmt.Fprintf(&tb.builder, "        return transform_response(%s, redact_response(response.text))\n", pyString(tb.toolID))
+	} else {
+		fmt.Fprintf(&tb.builder, "        return redact_response(response.text)\n")
+	}
 	fmt.Fprintf(&tb.builder, "    except httpx.RequestError as e:\n")
-	fmt.Fprintf(&tb.builder, "        error_msg = str(e)\n")
+	fmt.Fprintf(&tb.builder, "        error_msg = redact_secrets(str(e))\n")
 	fmt.Fprintf(&tb.builder, "        logger.error(f\"%s request failed: {error_msg}\")\n", toolID)
 	fmt.Fprintf(&tb.builder, "        raise\n")
 	fmt.Fprintf(&tb.builder, "    except httpx.HTTPStatusError as e:\n")
 	fmt.Fprintf(&tb.builder, "        error_msg = str(e)\n")
 	fmt.Fprintf(&tb.builder, "        if e.response is not None:\n")
-	fmt.Fprintf(&tb.builder, "            error_msg = f\"{error_msg} - Response: {e.response.text}\"\n")
+	fmt.Fprintf(&tb.builder, "            error_msg = f\"{error_msg} - Response: {redact_response(e.response.text)}\"\n")
+	fmt.Fprintf(&tb.builder, "        error_msg = redact_secrets(error_msg)\n")
 	fmt.Fprintf(&tb.builder, "        logger.error(f\"%s request failed: {error_msg}\")\n", toolID)
 	fmt.Fprintf(&tb.builder, "        raise\n")
 }
 
-// WriteMainBlock writes the code for the main block to run the server
+// WriteToolRegistry writes the registry of operation tools and registers them
+// with the server. It must be called after every WriteToolDefinition.
+func (tb *ToolBuilder) WriteToolRegistry() {
+	fmt.Fprintf(&tb.builder, "\n\n# Metadata for every operation tool, used for registration and discovery\n")
+	fmt.Fprintf(&tb.builder, "OPERATIONS: Dict[str, Dict[str, Any]] = {\n")
+	for _, op := range tb.operations {
+		tags := make([]string, 0, len(op.tags))
+		for _, tag := range op.tags {
+			tags = append(tags, pyString(tag))
+		}
+		fmt.Fprintf(&tb.builder, "    %s: {\"method\": %s, \"path\": %s, \"description\": %s, \"tags\": [%s]},\n",
+			pyString(op.toolID), pyString(op.method), pyString(op.path), pyString(op.description), strings.Join(tags, ", "))
+	}
+	fmt.Fprintf(&tb.builder, "}\n\n")
+
+	fmt.Fprintf(&tb.builder, "OPERATION_TOOLS: Dict[str, Callable[..., Awaitable[str]]] = {\n")
+	for _, op := range tb.operations {
+		fmt.Fprintf(&tb.builder, "    %s: %s,\n", pyString(op.toolID), op.toolID)
+	}
+	fmt.Fprintf(&tb.builder, "}\n")
+
+	fmt.Fprintf(&tb.builder, `
+# Register operation tools; when MCP_CORE_TOOLS is set only the listed ones are
+# registered up front
+core_tools = {name.strip() for name in os.getenv("MCP_CORE_TOOLS", "").split(",") if name.strip()}
+registered_tools: Set[str] = set()
+for tool_name, tool_fn in OPERATION_TOOLS.items():
+    if not core_tools or tool_name in core_tools:
+        mcp.add_tool(tool_fn, name=tool_name)
+        registered_tools.add(tool_name)
+`)
+}
+
+// WriteSearchEndpointsTool writes the meta tools for discovering and invoking
+// operations that aren't registered up front
+func (tb *ToolBuilder) WriteSearchEndpointsTool() {
+	fmt.Fprintf(&tb.builder, `
+
+@mcp.tool()
+async def search_endpoints(query: str, limit: int = 10) -> str:
+    """Search the API operations by keyword.
+
+    Returns matching tool names with their HTTP method, path, description and
+    parameters. Operations that are not registered as tools can be invoked
+    with call_endpoint.
+    """
+    terms = query.lower().split()
+    matches: List[Tuple[int, str]] = []
+    for name, info in OPERATIONS.items():
+        text = " ".join([name, info["method"], info["path"], info["description"], *info["tags"]]).lower()
+        score = sum(text.count(term) for term in terms)
+        if score > 0 or not terms:
+            matches.append((score, name))
+    matches.sort(key=lambda match: (-match[0], match[1]))
+
+    results: List[Dict[str, Any]] = []
+    for _, name in matches[:max(limit, 1)]:
+        info = OPERATIONS[name]
+        results.append({
+            "name": name,
+            "method": info["method"],
+            "path": info["path"],
+            "description": info["description"],
+            "parameters": list(inspect.signature(OPERATION_TOOLS[name]).parameters),
+            "registered": name in registered_tools,
+        })
+    return json.dumps(results)
+
+
+@mcp.tool()
+async def call_endpoint(name: str, arguments: Optional[Dict[str, Any]] = None) -> str:
+    """Invoke an API operation found with search_endpoints by its tool name."""
+    tool = OPERATION_TOOLS.get(name)
+    if tool is None:
+        raise ValueError(f"Unknown operation {name!r}; use search_endpoints to find available operations")
+    return await tool(**(arguments or {}))
+`)
+}
+
+// WriteDescribeOperationTool writes the meta tool returning the full schema
+// detail of an operation, as extracted from the OpenAPI document
+func (tb *ToolBuilder) WriteDescribeOperationTool(doc *openapi3.T) error {
+	fmt.Fprintf(&tb.builder, "\n\n# Full parameter, request body and response schemas of every operation, as JSON\n")
+	fmt.Fprintf(&tb.builder, "OPERATION_DETAILS: Dict[str, str] = {\n")
+	for _, op := range tb.operations {
+		details, err := describeOperation(doc, op, tb.requestRules.For(op.toolID))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&tb.builder, "    %s: %s,\n", pyString(op.toolID), pyString(details))
+	}
+	fmt.Fprintf(&tb.builder, "}\n")
+
+	fmt.Fprintf(&tb.builder, `
+
+@mcp.tool()
+async def describe_operation(name: str) -> str:
+    """Describe an API operation in full.
+
+    Returns the complete parameter schemas and examples, the request body and
+    the response schemas of the operation exposed as the given tool name.
+    Useful when a tool description is truncated.
+    """
+    details = OPERATION_DETAILS.get(name)
+    if details is None:
+        raise ValueError(f"Unknown operation {name!r}; use search_endpoints to find available operations")
+    return details
+`)
+	return nil
+}
+
+// WriteProtectedRegion writes an empty protected region, whose code
+// regeneration keeps, e.g. for tools written by hand
+func (tb *ToolBuilder) WriteProtectedRegion(name string) {
+	fmt.Fprintf(&tb.builder, `
+
+
+%s%s
+# Code between these markers, e.g. tools of your own decorated with
+# @mcp.tool(), is kept when the server is regenerated.
+%s%s
+`, protectedBegin, name, protectedEnd, name)
+}
+
+// WriteMainBlock writes the main function running the server, which the
+// generated project installs as a console command, and the main block calling it
 func (tb *ToolBuilder) WriteMainBlock() {
-	fmt.Fprintf(&tb.builder, "\nif __name__ == \"__main__\":\n")
-	fmt.Fprintf(&tb.builder, "    # Get server port from environment or use default\n")
-	fmt.Fprintf(&tb.builder, "    port = int(os.getenv(\"PORT\", \"8000\"))\n")
-	fmt.Fprintf(&tb.builder, "    logger.info(f\"Starting MCP server on port {port}\")\n")
-	fmt.Fprintf(&tb.builder, "    # Run the server\n")
-	fmt.Fprintf(&tb.builder, "    mcp.run(port=port)\n")
+	transports := `"stdio", "sse"`
+	if tb.streamableHTTP() {
+		transports += `, "streamable-http"`
+	}
+	fmt.Fprintf(&tb.builder, `
+
+def parse_args() -> argparse.Namespace:
+    """Parse command line arguments, falling back to environment variables."""
+    parser = argparse.ArgumentParser(description="Run the MCP server")
+    parser.add_argument(
+        "--transport",
+        choices=[%s],
+        default=os.getenv("MCP_TRANSPORT", "stdio"),
+        help="MCP transport to use (default: stdio)",
+    )
+    parser.add_argument(
+        "--host",
+        default=os.getenv("MCP_HOST", "127.0.0.1"),
+        help="Interface for network transports, 0.0.0.0 to accept remote clients (ignored for stdio)",
+    )
+    parser.add_argument(
+        "--port",
+        type=int,
+        default=int(os.getenv("PORT", "8000")),
+        help="Port for network transports (ignored for stdio)",
+    )
+    return parser.parse_args()
+
+
+def main() -> None:
+    """Run the MCP server; the entry point of the installed console command."""
+    args = parse_args()
+    if args.transport == "stdio":
+        logger.info("Starting MCP server on stdio")
+    else:
+        # Host and port are only meaningful for network transports
+        mcp.settings.host = args.host
+        mcp.settings.port = args.port
+        if args.host not in ("127.0.0.1", "localhost", "::1") and getattr(mcp.settings, "transport_security", None) is not None:
+            # FastMCP created for the default loopback host only accepts
+            # requests addressed to localhost, which remote clients aren't
+            mcp.settings.transport_security = None
+        logger.info(f"Starting MCP server with {args.transport} transport on {args.host}:{args.port}")
+    mcp.run(transport=args.transport)
+
+
+if __name__ == "__main__":
+    main()
+`, transports)
 }
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestWriteToolDefinitionSameNamedParameters(t *testing.T) {
+	op := &openapi3.Operation{
+		Summary: "Get a pet",
+		Parameters: openapi3.Parameters{
+			{Value: openapi3.NewPathParameter("petId").WithRequired(true).WithSchema(openapi3.NewStringSchema())},
+			{Value: openapi3.NewQueryParameter("petId").WithSchema(openapi3.NewStringSchema())},
+			{Value: openapi3.NewHeaderParameter("petId").WithSchema(openapi3.NewStringSchema())},
+		},
+		Responses: openapi3.NewResponses(),
+	}
+	tb := NewToolBuilder()
+	if err := tb.WriteToolDefinition("/pets/{petId}", "GET", op); err != nil {
+		t.Fatal(err)
+	}
+	code := tb.String()
+
+	// Each parameter keeps its own argument and goes where it belongs, rather
+	// than the query value replacing the path one
+	for _, want := range []string{
+		"async def get_pets_petid(petId: str, petId_2: Optional[str] = None, petId_3: Optional[str] = None) -> str:",
+		`        path_params["petId"] = petId` + "\n",
+		`        query_params["petId"] = petId_2` + "\n",
+		`        headers["petId"] = str(petId_3)` + "\n",
+		`    url = build_url(service_url, "/pets/{petId}", path_params, query_params)` + "\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code lacks %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestCurrentLine(t *testing.T) {
+	tb := NewToolBuilder()
+	check := func(when string) {
+		t.Helper()
+		if got, want := tb.currentLine(), strings.Count(tb.String(), "\n")+1; got != want {
+			t.Errorf("currentLine() %s = %d, want %d", when, got, want)
+		}
+	}
+	check("of empty code")
+
+	tb.WriteHeader(Metadata{})
+	tb.WriteImports()
+	if err := tb.WriteToolDefinition("/pets/{petId}", "GET", petsDoc().Paths.Value("/pets/{petId}").Get); err != nil {
+		t.Fatal(err)
+	}
+	check("after writing")
+	tb.Format()
+	check("after formatting")
+}
@@ -11,8 +11,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/berkantay/mcprox/internal/auth"
 	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/download"
+	"github.com/berkantay/mcprox/internal/idempotency"
+	"github.com/berkantay/mcprox/internal/logging"
 	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/berkantay/mcprox/internal/openapi"
+	"github.com/berkantay/mcprox/internal/tenant"
+	"github.com/berkantay/mcprox/internal/transform"
+	"github.com/berkantay/mcprox/internal/upload"
+	"github.com/berkantay/mcprox/internal/upstream"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -22,117 +31,227 @@ import (
 // processPathsIntoTools converts OpenAPI paths to MCP tools
 func (g *Generator) processPathsIntoTools(doc *openapi3.T, s *server.MCPServer) error {
 	g.document = doc
+	g.tools = make(map[string]mcp.Tool)
+	g.handlers = make(map[string]server.ToolHandlerFunc)
+	g.operations = make(map[string]openapi.Operation)
+	rules := g.toolRules(doc)
+
+	// Process each operation in a stable order
+	for _, operation := range openapi.Operations(doc) {
+		path, method, op := operation.Path, operation.Method, operation.Operation
+		toolID := utils.SanitizePathForToolID(path, method, g.maxNameLength)
+		toolDesc := op.Summary
+		if toolDesc == "" {
+			toolDesc = op.Description
+		}
+
+		// Create tool options
+		toolOpts := []mcp.ToolOption{mcp.WithDescription(toolDesc)}
+		rule := rules.For(toolID)
+		argNames := argumentNames(op, rule)
 
-	for path, pathItem := range doc.Paths.Map() {
-		// Process each HTTP method
-		for method, opRef := range pathItem.Operations() {
-			if opRef == nil {
+		// Process parameters into tool options
+		for _, paramRef := range op.Parameters {
+			if paramRef == nil || paramRef.Value == nil {
 				continue
 			}
 
-			op := opRef
-			toolID := utils.SanitizePathForToolID(path, method)
-			toolDesc := op.Summary
-			if toolDesc == "" {
-				toolDesc = op.Description
+			param := paramRef.Value
+			if param.Schema == nil || param.Schema.Value == nil || rule.Hidden(param.Name) {
+				continue
 			}
 
-			// Create tool options
-			toolOpts := []mcp.ToolOption{mcp.WithDescription(toolDesc)}
+			schema := param.Schema.Value
+			propOpts := []mcp.PropertyOption{}
+
+			if param.Required {
+				propOpts = append(propOpts, mcp.Required())
+			}
 
-			// Process parameters into tool options
-			for _, paramRef := range op.Parameters {
-				if paramRef == nil || paramRef.Value == nil {
-					continue
+			description := param.Description
+			if g.pagination && param.In == openapi3.ParameterInQuery {
+				if argument, ok := transform.PaginationArgument(param.Name, schema.Type); ok && argument == argNames[param] {
+					description = transform.PaginationDescription(argument, description)
 				}
+			}
+			if description != "" {
+				propOpts = append(propOpts, mcp.Description(description))
+			}
 
-				param := paramRef.Value
-				if param.Schema == nil || param.Schema.Value == nil {
-					continue
+			switch schema.Type {
+			case "string":
+				// Add enum values if available
+				if len(schema.Enum) > 0 {
+					enumValues := make([]string, 0, len(schema.Enum))
+					for _, v := range schema.Enum {
+						if s, ok := v.(string); ok {
+							enumValues = append(enumValues, s)
+						}
+					}
+					if len(enumValues) > 0 {
+						propOpts = append(propOpts, mcp.Enum(enumValues...))
+					}
 				}
 
-				schema := param.Schema.Value
-				propOpts := []mcp.PropertyOption{}
+				toolOpts = append(toolOpts, mcp.WithString(argNames[param], propOpts...))
+			case "integer", "number":
+				toolOpts = append(toolOpts, mcp.WithNumber(argNames[param], propOpts...))
+			case "boolean":
+				toolOpts = append(toolOpts, mcp.WithBoolean(argNames[param], propOpts...))
+			default:
+				// Handle arrays and objects as strings for simplicity
+				toolOpts = append(toolOpts, mcp.WithString(argNames[param], propOpts...))
+			}
+		}
 
-				if param.Required {
-					propOpts = append(propOpts, mcp.Required())
-				}
+		// Upload tools may take the path of a local file instead of a body,
+		// unless the operation has an argument of that name
+		var form *upload.Form
+		if g.uploads != nil && !hasArgument(argNames, upload.PathArgument) {
+			if f, ok := upload.FormOf(op); ok {
+				form = &f
+				toolOpts = append(toolOpts, mcp.WithString(upload.PathArgument,
+					mcp.Description(fmt.Sprintf("Path of a local file to upload, under %s", strings.Join(g.uploads.Roots, ", ")))))
+			}
+		}
 
-				if param.Description != "" {
-					propOpts = append(propOpts, mcp.Description(param.Description))
-				}
+		// Process request body
+		if op.RequestBody != nil && op.RequestBody.Value != nil {
+			reqBody := op.RequestBody.Value
 
-				switch schema.Type {
-				case "string":
-					// Add enum values if available
-					if len(schema.Enum) > 0 {
-						enumValues := make([]string, 0, len(schema.Enum))
-						for _, v := range schema.Enum {
-							if s, ok := v.(string); ok {
-								enumValues = append(enumValues, s)
-							}
-						}
-						if len(enumValues) > 0 {
-							propOpts = append(propOpts, mcp.Enum(enumValues...))
-						}
+			for _, mediaType := range reqBody.Content {
+				if mediaType.Schema != nil && mediaType.Schema.Value != nil {
+					propOpts := []mcp.PropertyOption{}
+
+					if reqBody.Required && form == nil {
+						propOpts = append(propOpts, mcp.Required())
+					}
+
+					desc := "Request body"
+					if reqBody.Description != "" {
+						desc = reqBody.Description
 					}
 
-					toolOpts = append(toolOpts, mcp.WithString(param.Name, propOpts...))
-				case "integer", "number":
-					toolOpts = append(toolOpts, mcp.WithNumber(param.Name, propOpts...))
-				case "boolean":
-					toolOpts = append(toolOpts, mcp.WithBoolean(param.Name, propOpts...))
-				default:
-					// Handle arrays and objects as strings for simplicity
-					toolOpts = append(toolOpts, mcp.WithString(param.Name, propOpts...))
+					propOpts = append(propOpts, mcp.Description(desc))
+					toolOpts = append(toolOpts, mcp.WithString("body", propOpts...))
+					break
 				}
 			}
+		}
 
-			// Process request body
-			if op.RequestBody != nil && op.RequestBody.Value != nil {
-				reqBody := op.RequestBody.Value
+		// GET tools may let the caller select the fields of the response,
+		// unless the operation has an argument of that name
+		selectFields := g.selectFields && method == http.MethodGet && !hasArgument(argNames, transform.FieldsArgument)
+		if selectFields {
+			toolOpts = append(toolOpts, mcp.WithString(transform.FieldsArgument,
+				mcp.Description("Comma separated fields of the response to return, as names or dotted paths such as items.id, to keep large responses short; all if empty")))
+		}
 
-				for _, mediaType := range reqBody.Content {
-					if mediaType.Schema != nil && mediaType.Schema.Value != nil {
-						propOpts := []mcp.PropertyOption{}
+		// Create the tool with all options
+		tool := mcp.NewTool(toolID, toolOpts...)
 
-						if reqBody.Required {
-							propOpts = append(propOpts, mcp.Required())
-						}
+		// Add tool to server with handler, recorded for the scripts calling it
+		handler := g.createToolHandler(toolID, op, path, method, argNames, rule, selectFields, form)
+		g.tools[toolID] = tool
+		g.handlers[toolID] = handler
+		g.operations[toolID] = operation
+		g.addTool(s, tool, handler)
 
-						desc := "Request body"
-						if reqBody.Description != "" {
-							desc = reqBody.Description
-						}
+		g.logger.Debug("Added tool",
+			zap.String("id", toolID),
+			zap.String("path", path),
+			zap.String("method", method))
+	}
 
-						propOpts = append(propOpts, mcp.Description(desc))
-						toolOpts = append(toolOpts, mcp.WithString("body", propOpts...))
-						break
-					}
-				}
-			}
+	return nil
+}
 
-			// Create the tool with all options
-			tool := mcp.NewTool(toolID, toolOpts...)
+// hasArgument reports whether an operation has an argument of a name
+func hasArgument(argNames map[*openapi3.Parameter]string, name string) bool {
+	for _, argName := range argNames {
+		if argName == name {
+			return true
+		}
+	}
+	return false
+}
 
-			// Add tool to server with handler
-			s.AddTool(tool, g.createToolHandler(op, path, method))
+// toolRules returns the request rules of the tools of doc, with the
+// pagination parameters of every operation renamed to the same arguments if
+// pagination.normalize is set
+func (g *Generator) toolRules(doc *openapi3.T) transform.RequestRules {
+	if !g.pagination {
+		return g.requestRules
+	}
+	rules := make(transform.RequestRules, len(g.requestRules))
+	for tool, rule := range g.requestRules {
+		rules[tool] = rule
+	}
+	for _, operation := range openapi.Operations(doc) {
+		toolID := utils.SanitizePathForToolID(operation.Path, operation.Method, g.maxNameLength)
+		if rule := rules.For(toolID).Paginated(operation.Operation.Parameters); !rule.IsZero() {
+			rules[strings.ToLower(toolID)] = rule
+		}
+	}
+	return rules
+}
 
-			g.logger.Debug("Added tool",
-				zap.String("id", toolID),
-				zap.String("path", path),
-				zap.String("method", method))
+// argumentNames maps each parameter of an operation to the name of its tool
+// argument, as renamed by the tool's request rule. Parameters sharing a name,
+// e.g. an "id" in both the path and the query, get deterministic suffixes so
+// neither shadows the other.
+func argumentNames(op *openapi3.Operation, rule transform.RequestRule) map[*openapi3.Parameter]string {
+	var params []*openapi3.Parameter
+	var names []string
+	for _, paramRef := range op.Parameters {
+		if paramRef == nil || paramRef.Value == nil {
+			continue
 		}
+		params = append(params, paramRef.Value)
+		names = append(names, rule.Argument(paramRef.Value.Name))
 	}
 
-	return nil
+	// "body" is reserved for the request body argument
+	argNames := make(map[*openapi3.Parameter]string, len(params))
+	for i, name := range utils.UniqueNames(names, "body") {
+		argNames[params[i]] = name
+	}
+	return argNames
 }
 
-// createToolHandler returns a handler function for an MCP tool
-func (g *Generator) createToolHandler(op *openapi3.Operation, path, method string) server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		// Get the service URL from config
+// createToolHandler returns a handler function for an MCP tool. It logs
+// through the logger of the call's context, e.g. the session's in serve mode,
+// which wrapTool tags with the tool and a request ID.
+func (g *Generator) createToolHandler(toolID string, op *openapi3.Operation, path, method string, argNames map[*openapi3.Parameter]string, rule transform.RequestRule, selectFields bool, form *upload.Form) server.ToolHandlerFunc {
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		logger := logging.FromContext(ctx)
+
+		// Get the service URL and credentials from config, preferring those of
+		// the operation's own API when several APIs are aggregated
 		serviceURL := config.GetString("service.url")
+		var authHeader string
+		if source, ok := config.GetSource(openapi.SourceName(op)); ok {
+			if source.ServiceURL != "" {
+				serviceURL = source.ServiceURL
+			}
+			authHeader = source.Authorization
+		}
+		// The tenant of the call, if any, has its own service and credentials
+		if t, ok := tenant.FromContext(ctx); ok {
+			if t.ServiceURL != "" {
+				serviceURL = t.ServiceURL
+			}
+			if t.Authorization != "" {
+				authHeader = t.Authorization
+			}
+		}
+		// A share of the calls to the main service may go to its canary
+		if g.canary != nil && serviceURL != "" && serviceURL == config.GetString("service.url") && g.canary.Route(toolID) {
+			serviceURL = g.canary.URL
+			logger = logger.With(zap.Bool("canary", true))
+			logger.Debug("Routing call to the canary", zap.String("url", serviceURL))
+		}
+		path := openapi.UpstreamPath(path, op)
 		if serviceURL == "" {
 			// If no service URL is provided, return a mock response
 			resultText := fmt.Sprintf("Mock response for %s %s\nParams: %v",
@@ -142,42 +261,68 @@ func (g *Generator) createToolHandler(op *openapi3.Operation, path, method strin
 			return mcp.NewToolResultText(resultText), nil
 		}
 
-		// Create the full URL
-		fullURL := buildURL(serviceURL, path, request.Params.Arguments, op.Parameters)
+		// Create the full URL from the arguments the upstream expects
+		args := requestArguments(request.Params.Arguments, op, argNames, rule)
+		fullURL := buildURL(serviceURL, path, args, op.Parameters, argNames)
+		filePath, _ := args[upload.PathArgument].(string)
+		if form != nil {
+			args = withoutArgument(args, upload.PathArgument)
+		}
 
-		// Create HTTP request
-		httpReq, err := createHTTPRequest(ctx, method, fullURL, request.Params.Arguments, op)
+		// Create HTTP request, described for the token manager authorizing it
+		authCtx := auth.WithRequest(ctx, auth.Request{
+			Tool:   toolID,
+			Method: method,
+			Path:   path,
+			API:    openapi.SourceName(op),
+		})
+		var httpReq *http.Request
+		var err error
+		contentType := "application/json"
+		if form != nil && filePath != "" {
+			httpReq, contentType, err = g.uploadRequest(authCtx, method, fullURL, filePath, args["body"], *form, bodyRule(op, rule))
+		} else {
+			httpReq, err = createHTTPRequest(authCtx, method, fullURL, args, op, argNames, bodyRule(op, rule))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
-		// Add authorization header if provided
-		authHeader := config.GetString("service.authorization")
+		// The credentials of a source or tenant take precedence over the
+		// configured auth provider, which the token manager applies otherwise
 		if authHeader != "" {
 			httpReq.Header.Set("Authorization", authHeader)
 		}
 
 		// Set common headers
-		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Content-Type", contentType)
 		httpReq.Header.Set("Accept", "application/json")
+		setHeaderParameters(httpReq, args, op.Parameters, argNames)
+		if key, ok := idempotency.KeyFromContext(ctx); ok {
+			httpReq.Header.Set(g.idempotency.Header, key)
+		}
 
 		// Create HTTP client with timeout
-		timeout := config.GetDuration("client.timeout")
+		timeout := time.Duration(config.GetInt("client.timeout")) * time.Second
 		if timeout == 0 {
 			timeout = 30 * time.Second
 		}
 		client := &http.Client{
-			Timeout: timeout,
+			Timeout:       timeout,
+			Transport:     g.transport,
+			CheckRedirect: upstream.LimitRedirects(g.maxRedirects),
 		}
 
 		// Execute the request
-		g.logger.Debug("Executing API request",
+		logger.Debug("Executing API request",
 			zap.String("method", method),
 			zap.String("url", fullURL),
 		)
 
+		start := time.Now()
 		resp, err := client.Do(httpReq)
 		if err != nil {
+			logger.Warn("API request failed", zap.Error(err))
 			return nil, fmt.Errorf("API request failed: %w", err)
 		}
 		defer resp.Body.Close()
@@ -188,18 +333,131 @@ func (g *Generator) createToolHandler(op *openapi3.Operation, path, method strin
 			return nil, fmt.Errorf("failed to read response: %w", err)
 		}
 
+		logger.Debug("API request completed",
+			zap.Int("status", resp.StatusCode),
+			zap.Duration("duration", time.Since(start)))
+
+		// Files, e.g. images or PDFs, are saved rather than returned inline
+		if g.downloads != nil && resp.StatusCode < 400 && download.IsFile(resp.Header) {
+			logger.Debug("Saving downloaded file", zap.Int("size", len(body)))
+			return g.downloads.Result(toolID, resp.Header, body)
+		}
+
+		// Mask sensitive fields before they reach the model, errors included
+		body = g.redactor.Redact(body)
+		if resp.StatusCode < 400 {
+			if body, err = g.transformer.Apply(toolID, body); err != nil {
+				return nil, err
+			}
+			if fields, _ := request.Params.Arguments[transform.FieldsArgument].(string); selectFields && fields != "" {
+				body = transform.SelectFields(body, transform.ParseFields(fields))
+			}
+		}
+		if body, err = g.scrub(ctx, toolID, body); err != nil {
+			return nil, err
+		}
+
 		// Check if response is successful
 		if resp.StatusCode >= 400 {
 			return nil, fmt.Errorf("API returned error status: %d - %s", resp.StatusCode, string(body))
 		}
 
+		// Responses too large for the model's context are stored as resources
+		if g.offload != nil && g.offload.Oversized(body) {
+			logger.Debug("Offloading response", zap.Int("size", len(body)))
+			return g.offload.Result(resp.Header.Get("Content-Type"), body)
+		}
+
 		// Return the response
 		return mcp.NewToolResultText(string(body)), nil
 	}
+	if g.idempotency == nil || method != http.MethodPost {
+		return handler
+	}
+	return g.idempotent(toolID, handler)
+}
+
+// idempotent returns a handler sending the idempotency key of each call
+// upstream, and answering the retries of a call with its result
+func (g *Generator) idempotent(toolID string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		key := g.idempotency.Key(ctx, toolID, request.Params.Arguments)
+		result, retried, err := g.idempotency.Do(ctx, key, func(ctx context.Context) (*mcp.CallToolResult, error) {
+			return handler(ctx, request)
+		})
+		if retried {
+			logging.FromContext(ctx).Info("Answered a retried call with the result of the first", zap.String("idempotency_key", key))
+		}
+		return result, err
+	}
+}
+
+// scrub scans a tool result for personal data with the configured scrubber,
+// recording what was removed or why the result was withheld in the audit log
+func (g *Generator) scrub(ctx context.Context, toolID string, body []byte) ([]byte, error) {
+	if g.scrubber == nil {
+		return body, nil
+	}
+	text, findings, err := g.scrubber.Scrub(ctx, toolID, string(body))
+	if len(findings) > 0 || err != nil {
+		entities := make(map[string]int, len(findings))
+		for _, finding := range findings {
+			entities[finding.Entity] = finding.Count
+		}
+		fields := []zap.Field{zap.Any("entities", entities), zap.Bool("blocked", err != nil)}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+		logging.Component(logging.FromContext(ctx), "audit").Info("Personal data found in tool result", fields...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(text), nil
+}
+
+// requestArguments applies the request rule of a tool to the arguments of a
+// call: injected parameters are set to their constants and dropped ones removed
+func requestArguments(args map[string]interface{}, op *openapi3.Operation, argNames map[*openapi3.Parameter]string, rule transform.RequestRule) map[string]interface{} {
+	if rule.IsZero() {
+		return args
+	}
+
+	mutated := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		mutated[name] = value
+	}
+	for _, paramRef := range op.Parameters {
+		if paramRef == nil || paramRef.Value == nil {
+			continue
+		}
+		param := paramRef.Value
+		if value, ok := rule.Injected(param.Name); ok {
+			mutated[argNames[param]] = value
+		} else if rule.Dropped(param.Name) {
+			delete(mutated, argNames[param])
+		}
+	}
+	return mutated
+}
+
+// bodyRule returns the part of a tool's request rule applying to its request
+// body, i.e. to fields that aren't parameters of the operation
+func bodyRule(op *openapi3.Operation, rule transform.RequestRule) transform.RequestRule {
+	if op.RequestBody == nil || rule.IsZero() {
+		return transform.RequestRule{}
+	}
+	var params []string
+	for _, paramRef := range op.Parameters {
+		if paramRef != nil && paramRef.Value != nil {
+			params = append(params, paramRef.Value.Name)
+		}
+	}
+	return rule.Without(params...)
 }
 
 // buildURL constructs the full URL with path parameters and query parameters
-func buildURL(baseURL, path string, args map[string]interface{}, parameters []*openapi3.ParameterRef) string {
+func buildURL(baseURL, path string, args map[string]interface{}, parameters []*openapi3.ParameterRef, argNames map[*openapi3.Parameter]string) string {
 	// Replace path parameters
 	for _, paramRef := range parameters {
 		if paramRef == nil || paramRef.Value == nil {
@@ -208,9 +466,10 @@ func buildURL(baseURL, path string, args map[string]interface{}, parameters []*o
 
 		param := paramRef.Value
 		if param.In == "path" {
-			if val, ok := args[param.Name]; ok {
+			if val, ok := args[argNames[param]]; ok {
+				// Escaped, so an argument can't change the rest of the URL
 				placeholder := fmt.Sprintf("{%s}", param.Name)
-				path = strings.Replace(path, placeholder, fmt.Sprintf("%v", val), -1)
+				path = strings.Replace(path, placeholder, url.PathEscape(fmt.Sprintf("%v", val)), -1)
 			}
 		}
 	}
@@ -237,7 +496,7 @@ func buildURL(baseURL, path string, args map[string]interface{}, parameters []*o
 
 		param := paramRef.Value
 		if param.In == "query" {
-			if val, ok := args[param.Name]; ok {
+			if val, ok := args[argNames[param]]; ok {
 				q.Add(param.Name, fmt.Sprintf("%v", val))
 			}
 		}
@@ -247,8 +506,23 @@ func buildURL(baseURL, path string, args map[string]interface{}, parameters []*o
 	return u.String()
 }
 
-// createHTTPRequest creates an HTTP request with the appropriate method and body
-func createHTTPRequest(ctx context.Context, method, url string, args map[string]interface{}, op *openapi3.Operation) (*http.Request, error) {
+// setHeaderParameters sets the header parameters of an operation from the
+// arguments of a call
+func setHeaderParameters(req *http.Request, args map[string]interface{}, parameters []*openapi3.ParameterRef, argNames map[*openapi3.Parameter]string) {
+	for _, paramRef := range parameters {
+		if paramRef == nil || paramRef.Value == nil || paramRef.Value.In != "header" {
+			continue
+		}
+		param := paramRef.Value
+		if val, ok := args[argNames[param]]; ok {
+			req.Header.Set(param.Name, fmt.Sprintf("%v", val))
+		}
+	}
+}
+
+// createHTTPRequest creates an HTTP request with the appropriate method and
+// body, to which the request rule of the body applies
+func createHTTPRequest(ctx context.Context, method, url string, args map[string]interface{}, op *openapi3.Operation, argNames map[*openapi3.Parameter]string, rule transform.RequestRule) (*http.Request, error) {
 	var body []byte
 	var err error
 
@@ -267,20 +541,20 @@ func createHTTPRequest(ctx context.Context, method, url string, args map[string]
 				}
 			}
 		} else {
-			// If no body parameter is found, use all arguments that are not used in path or query
+			// If no body parameter is found, use all arguments that are not parameters
 			bodyMap := make(map[string]interface{})
 			for name, value := range args {
-				isPathOrQuery := false
+				isParameter := false
 				for _, paramRef := range op.Parameters {
 					if paramRef != nil && paramRef.Value != nil {
 						param := paramRef.Value
-						if (param.In == "path" || param.In == "query") && param.Name == name {
-							isPathOrQuery = true
+						if (param.In == "path" || param.In == "query" || param.In == "header") && argNames[param] == name {
+							isParameter = true
 							break
 						}
 					}
 				}
-				if !isPathOrQuery {
+				if !isParameter {
 					bodyMap[name] = value
 				}
 			}
@@ -292,6 +566,10 @@ func createHTTPRequest(ctx context.Context, method, url string, args map[string]
 				}
 			}
 		}
+
+		if body, err = applyBodyRule(body, rule); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create the request
@@ -300,3 +578,71 @@ func createHTTPRequest(ctx context.Context, method, url string, args map[string]
 	}
 	return http.NewRequestWithContext(ctx, method, url, nil)
 }
+
+// uploadRequest creates an HTTP request uploading a local file the way form
+// takes it. The fields of a multipart body are those of the body argument, a
+// JSON object, to which the request rule of the body applies.
+func (g *Generator) uploadRequest(ctx context.Context, method, url, path string, bodyArg interface{}, form upload.Form, rule transform.RequestRule) (*http.Request, string, error) {
+	data, err := g.uploads.Read(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var fields map[string]interface{}
+	if form.Field != "" {
+		if bodyStr, ok := bodyArg.(string); ok && bodyStr != "" {
+			if err := json.Unmarshal([]byte(bodyStr), &bodyArg); err != nil {
+				return nil, "", fmt.Errorf("the body of a file upload must be a JSON object of form fields: %w", err)
+			}
+		}
+		fields, _ = rule.ApplyBody(bodyArg).(map[string]interface{})
+	}
+
+	body, contentType, err := form.Body(path, data, fields)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	return req, contentType, err
+}
+
+// withoutArgument returns a copy of the arguments without one
+func withoutArgument(args map[string]interface{}, name string) map[string]interface{} {
+	if _, ok := args[name]; !ok {
+		return args
+	}
+	copied := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		if key != name {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
+// applyBodyRule applies a request rule to a JSON request body. Bodies that
+// aren't JSON are sent as they are.
+func applyBodyRule(body []byte, rule transform.RequestRule) ([]byte, error) {
+	if rule.IsZero() {
+		return body, nil
+	}
+
+	var payload interface{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&payload); err != nil {
+			return body, nil
+		}
+	}
+	payload = rule.ApplyBody(payload)
+	if payload == nil {
+		return body, nil
+	}
+
+	mutated, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return mutated, nil
+}
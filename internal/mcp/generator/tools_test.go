@@ -0,0 +1,40 @@
+package generator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/transform"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestToolHandlerTimeoutInSeconds(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer upstream.Close()
+	viper.Set("service.url", upstream.URL)
+
+	// client.timeout is a number of seconds, as when fetching specs; read as
+	// a duration, 30 was 30ns and every upstream call timed out
+	viper.Set("client.timeout", 30)
+	op := &openapi3.Operation{Responses: openapi3.NewResponses()}
+	handler := New(zap.NewNop()).createToolHandler("get_pets", op, "/pets", "GET", nil, transform.RequestRule{}, false, nil)
+	result, err := handler(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("tool call with client.timeout 30 failed: %v", err)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; text != `{"id": 1}` {
+		t.Errorf("tool call = %s, want the upstream response", text)
+	}
+}
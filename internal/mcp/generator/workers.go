@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/mcp/utils"
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.uber.org/zap"
+)
+
+// workersCompatibilityDate is the Workers runtime version generated workers
+// are written against
+const workersCompatibilityDate = "2024-09-23"
+
+// jsSecrets are the variables of the generated JavaScript servers holding
+// credentials, which are configured as secrets rather than plain variables
+var jsSecrets = []string{
+	"MCP_API_KEY",
+	"SERVICE_AUTH_HEADER",
+	"SERVICE_AUTH_BEARER_TOKEN",
+	"SERVICE_AUTH_BASIC_USERNAME",
+	"SERVICE_AUTH_BASIC_PASSWORD",
+	"SERVICE_AUTH_API_KEY",
+}
+
+// projectFile is a file of a generated project, relative to its directory
+type projectFile struct {
+	path    string
+	content string
+}
+
+// generateWorkers writes the server as a Cloudflare Workers project serving
+// MCP over streamable HTTP, deployed with wrangler
+func (g *Generator) generateWorkers() error {
+	runtime, err := g.jsRuntime()
+	if err != nil {
+		return err
+	}
+	name := utils.ScriptName(g.document.Info.Title)
+	index := generatedHeader(g.metadata, "//") + runtime + jsHTTPHandler + `
+export default {
+  async fetch(request, env) {
+    return handleHTTP(request, env);
+  },
+};
+`
+
+	var wrangler strings.Builder
+	wrangler.WriteString(generatedHeader(g.metadata, "#"))
+	fmt.Fprintf(&wrangler, "name = %s\n", jsString(name))
+	wrangler.WriteString("main = \"src/index.js\"\n")
+	fmt.Fprintf(&wrangler, "compatibility_date = %s\n\n", jsString(workersCompatibilityDate))
+	wrangler.WriteString("[vars]\n")
+	fmt.Fprintf(&wrangler, "SERVICE_URL = %s\n", jsString(defaultServiceURL(g.document)))
+	wrangler.WriteString("HTTP_TIMEOUT = \"30\"\n")
+	for _, source := range apiSources(g.document) {
+		if source.ServiceURL != "" {
+			fmt.Fprintf(&wrangler, "SERVICE_URL_%s = %s\n", strings.ToUpper(source.Name), jsString(source.ServiceURL))
+		}
+	}
+	wrangler.WriteString("\n# Credentials are secrets, set with `npx wrangler secret put <NAME>`:\n")
+	for _, secret := range jsSecrets {
+		fmt.Fprintf(&wrangler, "#   %s\n", secret)
+	}
+
+	var devVars strings.Builder
+	devVars.WriteString("# Secrets of `npx wrangler dev`; copy to .dev.vars and fill in\n")
+	for _, secret := range jsSecrets {
+		fmt.Fprintf(&devVars, "%s=\n", secret)
+	}
+
+	specFiles, specs, err := g.specFiles()
+	if err != nil {
+		return err
+	}
+	return g.writeProject("Cloudflare Workers", append([]projectFile{
+		{path: "src/index.js", content: index},
+		{path: "wrangler.toml", content: wrangler.String()},
+		{path: ".dev.vars.example", content: devVars.String()},
+		{path: ".gitignore", content: ".dev.vars\n.wrangler/\nnode_modules/\n"},
+		{path: "README.md", content: workersReadme(g.document.Info.Title, name) + "\n" + strings.TrimSuffix(specs.ReadmeSection(), "\n")},
+	}, specFiles...))
+}
+
+// workersReadme returns the README of a generated Workers project
+func workersReadme(title, name string) string {
+	return fmt.Sprintf("# %s MCP Server on Cloudflare Workers\n\n"+
+		"Generated by mcprox. Serves the operations of %s as MCP tools over streamable HTTP, at `/mcp` of the worker.\n\n"+
+		"## Deploying\n\n"+
+		"```bash\n"+
+		"npx wrangler secret put MCP_API_KEY            # key MCP clients present\n"+
+		"npx wrangler secret put SERVICE_AUTH_BEARER_TOKEN\n"+
+		"npx wrangler deploy\n"+
+		"```\n\n"+
+		"Clients connect to `https://%s.<your-subdomain>.workers.dev/mcp`, presenting the key as `Authorization: Bearer <key>` or in an `X-API-Key` header. Without `MCP_API_KEY`, anyone can call the tools.\n\n"+
+		"The API's base URL is the `SERVICE_URL` variable of `wrangler.toml`. Its credentials are secrets: `SERVICE_AUTH_HEADER`, `SERVICE_AUTH_BEARER_TOKEN`, `SERVICE_AUTH_BASIC_USERNAME` and `SERVICE_AUTH_BASIC_PASSWORD`, or `SERVICE_AUTH_API_KEY`, sent in the header named by `SERVICE_AUTH_API_KEY_HEADER` or the query parameter named by `SERVICE_AUTH_API_KEY_QUERY`.\n\n"+
+		"## Developing\n\n"+
+		"```bash\n"+
+		"cp .dev.vars.example .dev.vars   # fill in the secrets\n"+
+		"npx wrangler dev\n"+
+		"```\n", title, title, name)
+}
+
+// writeProject writes the files of a project generated for a target other
+// than Python, with its metadata and manifest, into the project directory
+func (g *Generator) writeProject(target string, files []projectFile) error {
+	g.outputDir = filepath.Join(g.outputDir, utils.ScriptName(g.document.Info.Title))
+	if err := g.prepareOutputDir(); err != nil {
+		return err
+	}
+	if err := g.writeFiles(files); err != nil {
+		return err
+	}
+	if err := writeMetadata(g.outputDir, g.metadata); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	g.addFiles(MetadataFileName)
+	if err := writeManifest(g.outputDir, g.files); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	g.keepTimestamps()
+	g.logger.Info("Successfully generated MCP server project", zap.String("target", target), zap.String("project_dir", g.outputDir))
+	return nil
+}
+
+// writeFiles writes files into the project directory and records them
+func (g *Generator) writeFiles(files []projectFile) error {
+	for _, file := range files {
+		path := filepath.Join(g.outputDir, filepath.FromSlash(file.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(file.content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.path, err)
+		}
+		g.addFiles(file.path)
+	}
+	return nil
+}
+
+// defaultServiceURL returns the base URL generated servers call unless
+// configured otherwise: the configured service, else the spec's first server
+func defaultServiceURL(doc *openapi3.T) string {
+	if serviceURL := config.GetString("service.url"); serviceURL != "" {
+		return serviceURL
+	}
+	for _, server := range doc.Servers {
+		if server != nil && (strings.HasPrefix(server.URL, "https://") || strings.HasPrefix(server.URL, "http://")) {
+			return server.URL
+		}
+	}
+	return "http://localhost:8080"
+}
@@ -0,0 +1,128 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// generateWorkers generates the Workers project of petsDoc into dir
+func generateWorkers(dir string) error {
+	g := New(zap.NewNop(), dir)
+	g.SetTarget(config.TargetCloudflareWorkers)
+	return g.Generate(context.Background(), petsDoc())
+}
+
+func TestGenerateWorkers(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("service.url", "https://api.example.com/v1")
+
+	dir := t.TempDir()
+	if err := generateWorkers(dir); err != nil {
+		t.Fatal(err)
+	}
+	projectDir := filepath.Join(dir, "pet-store-mcp")
+	for _, name := range []string{"src/index.js", "wrangler.toml", ".dev.vars.example", ".gitignore", "README.md", MetadataFileName} {
+		if _, err := os.Stat(filepath.Join(projectDir, name)); err != nil {
+			t.Errorf("project lacks %s: %v", name, err)
+		}
+	}
+
+	index, _ := os.ReadFile(filepath.Join(projectDir, "src", "index.js"))
+	for _, want := range []string{"// Generated by mcprox ", `"get_pets_petid": {`, "async function handleHTTP(", "export default {"} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("src/index.js lacks %q", want)
+		}
+	}
+	wrangler, _ := os.ReadFile(filepath.Join(projectDir, "wrangler.toml"))
+	for _, want := range []string{"# Generated by mcprox ", `name = "pet-store-mcp"`, `main = "src/index.js"`, "[vars]\n", `SERVICE_URL = "https://api.example.com/v1"`, "#   MCP_API_KEY\n"} {
+		if !strings.Contains(string(wrangler), want) {
+			t.Errorf("wrangler.toml lacks %q", want)
+		}
+	}
+
+	// Regenerated in place
+	if err := generateWorkers(dir); err != nil {
+		t.Errorf("regenerating error = %v", err)
+	}
+}
+
+func TestWorkersHandler(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node isn't installed")
+	}
+	viper.Reset()
+	defer viper.Reset()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/pets/") + `"}`))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	if err := generateWorkers(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Workers load src/index.js as a module, as node does under an .mjs name
+	code, _ := os.ReadFile(filepath.Join(dir, "pet-store-mcp", "src", "index.js"))
+	worker := filepath.Join(dir, "worker.mjs")
+	os.WriteFile(worker, code, 0644)
+	script := `
+const { default: worker } = await import(process.argv[1]);
+const env = { SERVICE_URL: process.argv[2], MCP_API_KEY: "key" };
+const post = (body, headers) => worker.fetch(new Request("https://worker.example.com/mcp", {
+  method: "POST", headers: { "Content-Type": "application/json", ...headers }, body,
+}), env);
+const call = '{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_pets_petid","arguments":{"petId":"7"}}}';
+const results = {
+  unauthorized: (await post(call, {})).status,
+  notFound: (await worker.fetch(new Request("https://worker.example.com/"), env)).status,
+  notification: (await post('{"jsonrpc":"2.0","method":"notifications/initialized"}', { "X-API-Key": "key" })).status,
+  call: await (await post(call, { Authorization: "Bearer key" })).json(),
+};
+console.log(JSON.stringify(results));
+`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, node, "--input-type=module", "-e", script, worker, upstream.URL).Output()
+	if err != nil {
+		t.Fatalf("node error = %v", err)
+	}
+
+	var results struct {
+		Unauthorized int `json:"unauthorized"`
+		NotFound     int `json:"notFound"`
+		Notification int `json:"notification"`
+		Call         struct {
+			Result struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+				IsError bool `json:"isError"`
+			} `json:"result"`
+		} `json:"call"`
+	}
+	if err := json.Unmarshal(out, &results); err != nil {
+		t.Fatalf("output = %s: %v", out, err)
+	}
+	if results.Unauthorized != 401 || results.NotFound != 404 || results.Notification != 202 {
+		t.Errorf("statuses = %+v", results)
+	}
+	if content := results.Call.Result.Content; len(content) != 1 || content[0].Text != `{"id":"7"}` || results.Call.Result.IsError {
+		t.Errorf("call result = %+v", results.Call.Result)
+	}
+}
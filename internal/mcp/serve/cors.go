@@ -0,0 +1,191 @@
+package serve
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// DefaultCORSHeaders are the request headers browser clients may send
+var DefaultCORSHeaders = []string{"Authorization", "Content-Type", "X-API-Key", "Mcp-Session-Id", "Last-Event-ID"}
+
+// corsMaxAge is how long browsers may cache the result of a preflight request
+const corsMaxAge = 10 * 60
+
+// CORS configures which web origins may reach network transports. Requests
+// must name the server by one of its own hosts: the host it listens on, its
+// ACME domains or a loopback name, with its port. Requests carrying an Origin
+// header, i.e. made by browsers, must moreover come from the origin of one of
+// those hosts or an allowed one. A page whose DNS name was rebound to the
+// server's address names neither, so servers on localhost are protected from
+// DNS rebinding. Servers listening on every interface can be reached by any
+// name, so only requests without an Origin accept other hosts there.
+type CORS struct {
+	// AllowedOrigins are origins such as https://app.example.com allowed to
+	// connect, any if it contains "*"
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers allowed origins may send,
+	// DefaultCORSHeaders if empty
+	AllowedHeaders []string
+
+	// host, domains, port and https describe how the server is reached, as
+	// set by Serve
+	host    string
+	domains []string
+	port    int
+	https   bool
+}
+
+// hosts returns the values of the Host header naming the server
+func (c CORS) hosts() []string {
+	names := []string{"localhost", "127.0.0.1", "[::1]"}
+	if host := strings.Trim(c.host, "[]"); host != "" && !isWildcard(host) {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		names = append(names, host)
+	}
+	names = append(names, c.domains...)
+
+	port := strconv.Itoa(c.port)
+	defaultPort := c.https && c.port == 443 || !c.https && c.port == 80
+	var hosts []string
+	for _, name := range names {
+		hosts = append(hosts, name+":"+port)
+		if defaultPort {
+			hosts = append(hosts, name)
+		}
+	}
+	return hosts
+}
+
+// isWildcard reports whether a listen host stands for every interface
+func isWildcard(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// allowsHost reports whether a request names the server by one of its hosts
+func (c CORS) allowsHost(r *http.Request) bool {
+	for _, host := range c.hosts() {
+		if strings.EqualFold(host, r.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a request's origin may reach the server
+func (c CORS) allows(r *http.Request, origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	if !c.allowsHost(r) {
+		return false
+	}
+	scheme := "http://"
+	if c.https {
+		scheme = "https://"
+	}
+	for _, host := range c.hosts() {
+		if strings.EqualFold(scheme+host, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap returns a handler checking the origin of requests, answering the
+// preflight requests of allowed origins and adding CORS headers to the
+// responses of next
+func (c CORS) Wrap(next http.Handler, logger *zap.Logger) http.Handler {
+	headers := c.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+	allowHeaders := strings.Join(headers, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// Not a browser request, or a same-origin one of a page that
+			// may have been rebound to the server's address
+			if c.host != "" && !isWildcard(strings.Trim(c.host, "[]")) && !c.allowsHost(r) {
+				logger.Warn("Refused request for another host", zap.String("host", r.Host), zap.String("path", r.URL.Path))
+				http.Error(w, "host not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !c.allows(r, origin) {
+			logger.Warn("Refused request from a disallowed origin", zap.String("origin", origin), zap.String("path", r.URL.Path))
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.Header().Add("Vary", "Origin")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(&corsWriter{ResponseWriter: w, origin: origin}, r)
+	})
+}
+
+// corsWriter sets the CORS headers of a response when it's written,
+// replacing the wildcard the SSE transport sets itself
+type corsWriter struct {
+	http.ResponseWriter
+	origin      string
+	wroteHeader bool
+}
+
+func (w *corsWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("Access-Control-Allow-Origin", w.origin)
+		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id, WWW-Authenticate")
+		w.Header().Add("Vary", "Origin")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *corsWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets the SSE transport stream events through the writer
+func (w *corsWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the WebSocket transport take over the connection, whose
+// upgrade response browsers don't check CORS headers of
+func (w *corsWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("connection can't be taken over")
+	}
+	return hijacker.Hijack()
+}
@@ -0,0 +1,109 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCORS(t *testing.T) {
+	// Like the SSE transport, the handler allows any origin itself
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write([]byte("event: endpoint\n\n"))
+		w.(http.Flusher).Flush()
+	})
+	handler := CORS{AllowedOrigins: []string{"https://app.example.com/"}, host: "localhost", port: 8080}.Wrap(next, zap.NewNop())
+
+	serve := func(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://localhost:8080/sse", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for origin, want := range map[string]string{
+		"https://app.example.com": "https://app.example.com",
+		"http://localhost:8080":   "http://localhost:8080",
+		"http://127.0.0.1:8080":   "http://127.0.0.1:8080",
+		"":                        "*",
+	} {
+		w := serve(http.MethodGet, origin, nil)
+		if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != want {
+			t.Errorf("origin %q: status %d, Access-Control-Allow-Origin %q, want %q", origin, w.Code, w.Header().Get("Access-Control-Allow-Origin"), want)
+		}
+	}
+
+	if w := serve(http.MethodGet, "https://evil.example.com", nil); w.Code != http.StatusForbidden {
+		t.Errorf("disallowed origin: status %d, want 403", w.Code)
+	}
+
+	w := serve(http.MethodOptions, "https://app.example.com", map[string]string{"Access-Control-Request-Method": "POST"})
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Headers") == "" || w.Body.Len() != 0 {
+		t.Errorf("preflight: status %d, headers %v", w.Code, w.Header())
+	}
+
+	wildcard := CORS{AllowedOrigins: []string{"*"}}.Wrap(next, zap.NewNop())
+	r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/sse", nil)
+	r.Header.Set("Origin", "https://anywhere.example.com")
+	anyW := httptest.NewRecorder()
+	wildcard.ServeHTTP(anyW, r)
+	if anyW.Code != http.StatusOK {
+		t.Errorf("wildcard: status %d, want 200", anyW.Code)
+	}
+
+	// The server's own hosts are only its own on its port
+	if w := serve(http.MethodGet, "http://localhost:9090", nil); w.Code != http.StatusForbidden {
+		t.Errorf("other port: status %d, want 403", w.Code)
+	}
+}
+
+func TestCORSRejectsDNSRebinding(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(cors CORS, host, origin string) int {
+		r := httptest.NewRequest(http.MethodGet, "http://"+host+"/sse", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		cors.Wrap(next, zap.NewNop()).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// evil.example resolves to 127.0.0.1, so the page's requests reach the
+	// server naming the attacker's host, with or without an Origin
+	local := CORS{host: "127.0.0.1", port: 8080}
+	if code := serve(local, "evil.example:8080", "http://evil.example:8080"); code != http.StatusForbidden {
+		t.Errorf("rebound request: status %d, want 403", code)
+	}
+	if code := serve(local, "evil.example:8080", ""); code != http.StatusForbidden {
+		t.Errorf("rebound same-origin request: status %d, want 403", code)
+	}
+	if code := serve(local, "localhost:8080", "http://evil.example:8080"); code != http.StatusForbidden {
+		t.Errorf("foreign origin: status %d, want 403", code)
+	}
+	if code := serve(local, "[::1]:8080", "http://[::1]:8080"); code != http.StatusOK {
+		t.Errorf("own origin: status %d, want 200", code)
+	}
+
+	// Servers on every interface are reached by any name, but browsers still
+	// need an own or allowed origin
+	all := CORS{host: "0.0.0.0", port: 8080, AllowedOrigins: []string{"https://app.example.com"}}
+	if code := serve(all, "mcp.example.com:8080", ""); code != http.StatusOK {
+		t.Errorf("remote client: status %d, want 200", code)
+	}
+	if code := serve(all, "evil.example:8080", "http://evil.example:8080"); code != http.StatusForbidden {
+		t.Errorf("rebound request on every interface: status %d, want 403", code)
+	}
+	if code := serve(all, "mcp.example.com:8080", "https://app.example.com"); code != http.StatusOK {
+		t.Errorf("allowed origin: status %d, want 200", code)
+	}
+}
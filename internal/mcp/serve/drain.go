@@ -0,0 +1,199 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// DefaultDrainTimeout is how long in-flight tool calls may finish when
+// shutting down if no timeout is configured
+const DefaultDrainTimeout = 20 * time.Second
+
+// cancelGrace is how long canceled tool calls get to return once the drain
+// timeout has passed
+const cancelGrace = time.Second
+
+// Drainer tracks the tool calls in flight so that shutting down lets them
+// finish. It is a tool middleware, and must wrap the tools of the server
+// Run serves with it.
+type Drainer struct {
+	// Timeout bounds how long Drain waits for calls, DefaultDrainTimeout if 0
+	Timeout time.Duration
+
+	mu       sync.Mutex
+	draining bool
+	calls    map[*inflightCall]struct{}
+	done     chan struct{}
+}
+
+// inflightCall is a tool call Drain waits for
+type inflightCall struct {
+	tool      string
+	requestID interface{}
+	session   server.ClientSession
+	cancel    context.CancelFunc
+}
+
+// Wrap returns a handler tracking the calls of next, which refuses new calls
+// once draining has begun
+func (d *Drainer) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		call := &inflightCall{
+			tool:      request.Params.Name,
+			requestID: requestIDFromContext(ctx),
+			session:   server.ClientSessionFromContext(ctx),
+			cancel:    cancel,
+		}
+
+		d.mu.Lock()
+		if d.draining {
+			d.mu.Unlock()
+			result := mcp.NewToolResultText("The server is shutting down and doesn't accept new tool calls; retry once it's back")
+			result.IsError = true
+			return result, nil
+		}
+		if d.calls == nil {
+			d.calls = make(map[*inflightCall]struct{})
+		}
+		d.calls[call] = struct{}{}
+		d.mu.Unlock()
+
+		defer d.finish(call)
+		return next(ctx, request)
+	}
+}
+
+// finish stops tracking a call, signaling Drain once none is left
+func (d *Drainer) finish(call *inflightCall) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.calls, call)
+	if d.draining && len(d.calls) == 0 && d.done != nil {
+		close(d.done)
+		d.done = nil
+	}
+}
+
+// Drain refuses new tool calls and waits for those in flight to finish, up
+// to the timeout. Calls still running then are canceled, and their clients
+// are sent a notifications/cancelled for them. It returns the number of
+// canceled calls.
+func (d *Drainer) Drain(logger *zap.Logger) int {
+	d.mu.Lock()
+	d.draining = true
+	inflight := len(d.calls)
+	done := make(chan struct{})
+	if inflight == 0 {
+		close(done)
+	} else {
+		d.done = done
+	}
+	d.mu.Unlock()
+
+	if inflight > 0 {
+		logger.Info("Draining tool calls in flight", zap.Int("calls", inflight), zap.Duration("timeout", d.timeout()))
+	}
+	select {
+	case <-done:
+		return 0
+	case <-time.After(d.timeout()):
+	}
+
+	d.mu.Lock()
+	canceled := make([]*inflightCall, 0, len(d.calls))
+	for call := range d.calls {
+		canceled = append(canceled, call)
+	}
+	d.mu.Unlock()
+	for _, call := range canceled {
+		logger.Warn("Canceled tool call still in flight at shutdown", zap.String("tool", call.tool))
+		notifyCancelled(call)
+		call.cancel()
+	}
+
+	// Give the canceled calls a moment to return their errors
+	select {
+	case <-done:
+	case <-time.After(cancelGrace):
+	}
+	return len(canceled)
+}
+
+func (d *Drainer) timeout() time.Duration {
+	if d.Timeout <= 0 {
+		return DefaultDrainTimeout
+	}
+	return d.Timeout
+}
+
+// notifyCancelled tells the client of a call that it won't complete. Only
+// calls whose request ID is known, those made over network transports, can
+// be referred to.
+func notifyCancelled(call *inflightCall) {
+	if call.session == nil || call.requestID == nil {
+		return
+	}
+	notification := mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: "notifications/cancelled",
+			Params: mcp.NotificationParams{
+				AdditionalFields: map[string]interface{}{
+					"requestId": call.requestID,
+					"reason":    "server shutting down",
+				},
+			},
+		},
+	}
+	select {
+	case call.session.NotificationChannel() <- notification:
+	default:
+		// The client isn't reading its notifications
+	}
+}
+
+type requestIDKey struct{}
+
+// requestIDFromContext returns the JSON-RPC ID of the request a context
+// handles, if known
+func requestIDFromContext(ctx context.Context) interface{} {
+	return ctx.Value(requestIDKey{})
+}
+
+// withRequestIDs returns a handler adding the JSON-RPC ID of the messages
+// posted to next to their context, which mcp-go doesn't pass to tool
+// handlers
+func withRequestIDs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var message struct {
+			ID interface{} `json:"id"`
+		}
+		if json.Unmarshal(body, &message) == nil && message.ID != nil {
+			r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, message.ID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
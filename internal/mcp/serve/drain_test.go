@@ -0,0 +1,106 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// testSession records the notifications sent to a client
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func (s *testSession) SessionID() string { return "test-session" }
+
+func TestDrainerWaits(t *testing.T) {
+	d := &Drainer{Timeout: 5 * time.Second}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := d.Wrap(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-release
+		return mcp.NewToolResultText("done"), ctx.Err()
+	})
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := handler(context.Background(), mcp.CallToolRequest{})
+		callErr <- err
+	}()
+	<-started
+
+	drained := make(chan int, 1)
+	go func() { drained <- d.Drain(zap.NewNop()) }()
+
+	// New calls are refused while draining
+	for draining := false; !draining; time.Sleep(time.Millisecond) {
+		d.mu.Lock()
+		draining = d.draining
+		d.mu.Unlock()
+	}
+	if result, err := handler(context.Background(), mcp.CallToolRequest{}); err != nil || !result.IsError {
+		t.Errorf("new call while draining = %+v, %v, want it refused", result, err)
+	}
+
+	close(release)
+	if canceled := <-drained; canceled != 0 {
+		t.Errorf("Drain() canceled %d calls, want 0", canceled)
+	}
+	if err := <-callErr; err != nil {
+		t.Errorf("call in flight error = %v, want it to finish", err)
+	}
+}
+
+func TestDrainerCancels(t *testing.T) {
+	d := &Drainer{Timeout: 50 * time.Millisecond}
+	started := make(chan struct{})
+	handler := d.Wrap(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 1)}
+	ctx := server.NewMCPServer("Test", "1.0.0").WithContext(context.Background(), session)
+
+	// The request ID reaches the handler from the posted message
+	var ctxWithID context.Context
+	withRequestIDs(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxWithID = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/call"}`)).WithContext(ctx))
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := handler(ctxWithID, mcp.CallToolRequest{})
+		callErr <- err
+	}()
+	<-started
+
+	if canceled := d.Drain(zap.NewNop()); canceled != 1 {
+		t.Errorf("Drain() canceled %d calls, want 1", canceled)
+	}
+	if err := <-callErr; err != context.Canceled {
+		t.Errorf("call in flight error = %v, want it canceled", err)
+	}
+	select {
+	case notification := <-session.notifications:
+		params := notification.Params.AdditionalFields
+		if notification.Method != "notifications/cancelled" || params["requestId"] != float64(7) {
+			t.Errorf("notification = %+v, want the cancellation of request 7", notification)
+		}
+	default:
+		t.Error("client wasn't notified of the cancellation")
+	}
+}
@@ -0,0 +1,375 @@
+// Package serve runs MCP servers over the transports mcprox supports
+package serve
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/auth"
+	"github.com/berkantay/mcprox/internal/clientauth"
+	"github.com/berkantay/mcprox/internal/health"
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/berkantay/mcprox/internal/metrics"
+	"github.com/berkantay/mcprox/internal/tenant"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// Transports an MCP server can be served over
+const (
+	// TransportStdio serves a single client over standard input and output
+	TransportStdio = "stdio"
+	// TransportSSE serves clients over HTTP with Server-Sent Events
+	TransportSSE = "sse"
+	// TransportWebSocket serves clients over WebSocket connections at
+	// WebSocketPath, a session per connection
+	TransportWebSocket = "websocket"
+)
+
+// shutdownTimeout bounds how long the HTTP server waits for open connections
+// when shutting down
+const shutdownTimeout = 5 * time.Second
+
+// Options configures how an MCP server is served
+type Options struct {
+	// Transport is TransportStdio, TransportSSE or TransportWebSocket
+	Transport string
+	// Host is the interface network transports listen on, all if empty
+	Host string
+	// Port is the port network transports listen on, a free one if 0
+	Port int
+	// CredentialHeader, if set, names the header of the client's requests
+	// whose value tool calls forward upstream, see auth.Passthrough
+	CredentialHeader string
+	// Auth, if set, authenticates every request to network transports
+	Auth *clientauth.Authenticator
+	// CORS controls which web origins may reach network transports
+	CORS CORS
+	// TLS, if enabled, serves network transports over HTTPS
+	TLS TLS
+	// Health, if set, serves the liveness, readiness and version endpoints
+	// of network transports
+	Health *health.Checker
+	// Metrics, if set, serves the tool call metrics of network transports at
+	// metrics.Path, to clients authenticated like MCP clients
+	Metrics http.Handler
+	// Drain, if set, lets the tool calls in flight finish when shutting down.
+	// It must wrap the tools of the served server.
+	Drain *Drainer
+	// Tenants, if any, are served by network transports under their own path
+	// prefix, e.g. /acme/sse or /acme/ws, and at the root endpoints to clients presenting
+	// their API key. Every request must present the key of its tenant.
+	Tenants []tenant.Tenant
+	// Ready, if set, is called with the URL of the endpoint clients connect
+	// to once network transports accept connections
+	Ready func(url string)
+}
+
+// Validate checks the transport and port
+func (o Options) Validate() error {
+	switch o.Transport {
+	case TransportStdio:
+		if len(o.Tenants) > 0 {
+			return fmt.Errorf("tenants can only be served over %s or %s", TransportSSE, TransportWebSocket)
+		}
+		if o.TLS.Enabled() {
+			return fmt.Errorf("TLS can only be used over %s or %s", TransportSSE, TransportWebSocket)
+		}
+		return nil
+	case TransportSSE, TransportWebSocket:
+		if o.Port < 0 || o.Port > 65535 {
+			return fmt.Errorf("invalid port %d", o.Port)
+		}
+		return o.TLS.Validate()
+	default:
+		return fmt.Errorf("invalid transport %q: must be %s, %s or %s", o.Transport, TransportStdio, TransportSSE, TransportWebSocket)
+	}
+}
+
+// Addr returns the address network transports listen on
+func (o Options) Addr() string {
+	return net.JoinHostPort(o.Host, strconv.Itoa(o.Port))
+}
+
+// BaseURL returns the URL clients reach network transports at
+func (o Options) BaseURL() string {
+	host := o.Host
+	if len(o.TLS.ACMEDomains) > 0 {
+		// Certificates are only valid for the domains
+		host = o.TLS.ACMEDomains[0]
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	scheme := "http://"
+	if o.TLS.Enabled() {
+		scheme = "https://"
+	}
+	return scheme + net.JoinHostPort(host, strconv.Itoa(o.Port))
+}
+
+// SSEURL returns the URL of the SSE endpoint clients connect to
+func (o Options) SSEURL() string {
+	return o.BaseURL() + "/sse"
+}
+
+// WebSocketURL returns the URL of the WebSocket endpoint clients connect to
+func (o Options) WebSocketURL() string {
+	base := o.BaseURL()
+	if strings.HasPrefix(base, "https://") {
+		return "wss://" + strings.TrimPrefix(base, "https://") + WebSocketPath
+	}
+	return "ws://" + strings.TrimPrefix(base, "http://") + WebSocketPath
+}
+
+// URL returns the URL of the endpoint of the transport clients connect to
+func (o Options) URL() string {
+	if o.Transport == TransportWebSocket {
+		return o.WebSocketURL()
+	}
+	return o.SSEURL()
+}
+
+// Run serves the MCP server until the context is canceled or the transport
+// fails. Nothing but protocol messages may be written to standard output
+// while serving over stdio, so logs must go to standard error.
+func Run(ctx context.Context, s *server.MCPServer, opts Options, logger *zap.Logger) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	logger = logging.Component(logger, "serve")
+
+	if opts.Transport == TransportStdio {
+		logger.Info("Serving MCP server on stdio")
+		if opts.CredentialHeader != "" {
+			logger.Warn("Client credentials can only be forwarded over sse or websocket", zap.String("header", opts.CredentialHeader))
+		}
+		if opts.Auth != nil {
+			logger.Warn("Clients are only authenticated over sse or websocket; stdio is reachable by the process launching mcprox only")
+		}
+		stdio := server.NewStdioServer(s)
+		stdio.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+		stdio.SetContextFunc(func(ctx context.Context) context.Context {
+			return sessionContext(ctx, logger)
+		})
+
+		// Tool calls run with the context of Listen, which must outlive ctx
+		// for them to be drained
+		listenCtx, stopListening := context.WithCancel(context.WithoutCancel(ctx))
+		defer stopListening()
+		drained := make(chan struct{})
+		go func() {
+			defer close(drained)
+			select {
+			case <-ctx.Done():
+				if opts.Drain != nil {
+					opts.Drain.Drain(logger)
+				}
+				stopListening()
+			case <-listenCtx.Done():
+			}
+		}()
+		err := stdio.Listen(listenCtx, os.Stdin, os.Stdout)
+		stopListening()
+		<-drained
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	}
+
+	// Listen first so that the server is reachable once Run has logged its URL
+	listener, err := net.Listen("tcp", opts.Addr())
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", opts.Addr(), err)
+	}
+
+	// Port 0 picks a free port, which clients need to know
+	opts.Port = listener.Addr().(*net.TCPAddr).Port
+
+	if opts.TLS.Enabled() {
+		tlsConfig, err := opts.TLS.Config(opts.Host, logger)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	httpServer := &http.Server{}
+	contextFunc := func(ctx context.Context, r *http.Request) context.Context {
+		if opts.CredentialHeader != "" {
+			ctx = auth.WithClientCredentials(ctx, r.Header.Get(opts.CredentialHeader))
+		}
+		return sessionContext(ctx, logger)
+	}
+	newEndpoints := func(basePath string) endpoints {
+		if opts.Transport == TransportWebSocket {
+			return newWebSocketServer(s, httpServer, basePath+WebSocketPath, contextFunc, logger)
+		}
+		return server.NewSSEServer(s,
+			server.WithBaseURL(opts.BaseURL()),
+			server.WithBasePath(basePath),
+			server.WithHTTPServer(httpServer),
+			server.WithSSEContextFunc(contextFunc))
+	}
+	root := newEndpoints("")
+	httpServer.Handler = root
+
+	// Every tenant gets its own endpoints, sharing the server's tools
+	var tenantServers []endpoints
+	if len(opts.Tenants) > 0 {
+		handler := &tenantHandler{tenants: opts.Tenants, root: root, servers: make(map[string]http.Handler), logger: logger}
+		for _, t := range opts.Tenants {
+			tenantServer := newEndpoints("/" + t.Name)
+			handler.servers[t.Name] = tenantServer
+			tenantServers = append(tenantServers, tenantServer)
+		}
+		httpServer.Handler = handler
+		logger.Info("Serving tenants", zap.Int("tenants", len(opts.Tenants)))
+	}
+
+	// Cancellation notifications refer to the requests of tool calls
+	if opts.Drain != nil {
+		httpServer.Handler = withRequestIDs(httpServer.Handler)
+	}
+
+	if opts.Metrics != nil {
+		httpServer.Handler = withMetrics(httpServer.Handler, opts.Metrics)
+	}
+
+	// Authenticate clients before any endpoint sees their requests
+	if opts.Auth != nil {
+		if opts.Auth.Resource == "" {
+			opts.Auth.Resource = opts.BaseURL()
+		}
+		if opts.Auth.Logger == nil {
+			opts.Auth.Logger = logger
+		}
+		httpServer.Handler = opts.Auth.Wrap(httpServer.Handler)
+	}
+
+	// Probes carry no credentials
+	if opts.Health != nil {
+		httpServer.Handler = opts.Health.Wrap(httpServer.Handler)
+	}
+
+	// Check the origin of browsers first, whose preflight requests carry no
+	// credentials
+	cors := opts.CORS
+	cors.host, cors.domains, cors.port, cors.https = opts.Host, opts.TLS.ACMEDomains, opts.Port, opts.TLS.Enabled()
+	httpServer.Handler = cors.Wrap(httpServer.Handler, logger)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+	if opts.Transport == TransportWebSocket {
+		logger.Info("Serving MCP server over WebSocket", zap.String("url", opts.URL()), zap.Bool("tls", opts.TLS.Enabled()))
+	} else {
+		logger.Info("Serving MCP server over SSE", zap.String("url", opts.URL()), zap.Bool("tls", opts.TLS.Enabled()))
+	}
+	if opts.Ready != nil {
+		opts.Ready(opts.URL())
+	}
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		// Finish the tool calls in flight while their sessions are open
+		if opts.Drain != nil {
+			opts.Drain.Drain(logger)
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		// Closes the sessions, then the HTTP server
+		for _, tenantServer := range tenantServers {
+			if err := tenantServer.Shutdown(shutdownCtx); err != nil {
+				return err
+			}
+		}
+		return root.Shutdown(shutdownCtx)
+	}
+}
+
+// endpoints are the MCP endpoints of a network transport, sharing the HTTP
+// server their Shutdown shuts down
+type endpoints interface {
+	http.Handler
+	Shutdown(ctx context.Context) error
+}
+
+// tenantHandler routes the requests of network transports to the endpoints
+// of their tenant, by path prefix or else by the API key they present, which
+// must be the tenant's either way
+type tenantHandler struct {
+	tenants []tenant.Tenant
+	// root serves the root endpoints, servers those of each tenant by name
+	root    http.Handler
+	servers map[string]http.Handler
+	logger  *zap.Logger
+}
+
+func (h *tenantHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := tenant.PresentedKey(r)
+	handler, t, ok := h.root, tenant.Tenant{}, false
+	name, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if prefixed, found := h.servers[name]; found {
+		handler = prefixed
+		for _, candidate := range h.tenants {
+			if candidate.Name == name {
+				t, ok = candidate, candidate.Authenticates(key)
+			}
+		}
+	} else {
+		t, ok = tenant.ByKey(h.tenants, key)
+	}
+
+	if !ok {
+		h.logger.Warn("Refused request without a valid tenant API key", zap.String("path", r.URL.Path), zap.String("remote", r.RemoteAddr))
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "a valid tenant API key is required", http.StatusUnauthorized)
+		return
+	}
+	handler.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), t)))
+}
+
+// sessionContext returns a copy of the context of an MCP message carrying a
+// logger tagged with the message's session, which tool handlers log through
+func sessionContext(ctx context.Context, logger *zap.Logger) context.Context {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		logger = logger.With(zap.String("session_id", session.SessionID()))
+	}
+	if t, ok := tenant.FromContext(ctx); ok {
+		logger = logger.With(zap.String("tenant", t.Name))
+	}
+	if identity, ok := clientauth.IdentityFromContext(ctx); ok && identity.Subject != "" {
+		logger = logger.With(zap.String("subject", identity.Subject))
+	}
+	return logging.WithLogger(ctx, logger)
+}
+
+// withMetrics returns a handler serving metrics at metrics.Path and passing
+// other requests to next
+func withMetrics(next, metricsHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metrics.Path && r.Method == http.MethodGet {
+			metricsHandler.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,331 @@
+package serve
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/auth"
+	"github.com/berkantay/mcprox/internal/clientauth"
+	"github.com/berkantay/mcprox/internal/health"
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/berkantay/mcprox/internal/metrics"
+	"github.com/berkantay/mcprox/internal/tenant"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRunSSE(t *testing.T) {
+	s := server.NewMCPServer("Test", "1.0.0")
+	s.AddTool(mcp.NewTool("ping", mcp.WithDescription("Ping")), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		logging.FromContext(ctx).Info("Pinged")
+		return mcp.NewToolResultText("pong"), nil
+	})
+	core, logs := observer.New(zap.InfoLevel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan string, 1)
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- Run(ctx, s, Options{Transport: TransportSSE, Host: "localhost", Ready: func(url string) { ready <- url }}, zap.New(core))
+	}()
+
+	var sseURL string
+	select {
+	case sseURL = <-ready:
+	case err := <-stopped:
+		t.Fatalf("Run() error = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+
+	c, err := client.NewSSEMCPClient(sseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "ping" {
+		t.Errorf("ListTools() = %+v, want the ping tool", tools.Tools)
+	}
+
+	callRequest := mcp.CallToolRequest{}
+	callRequest.Params.Name = "ping"
+	if _, err := c.CallTool(ctx, callRequest); err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	pinged := logs.FilterMessage("Pinged").All()
+	if len(pinged) != 1 {
+		t.Fatalf("tool handler logged %d entries, want 1", len(pinged))
+	}
+	if pinged[0].LoggerName != "serve" || pinged[0].ContextMap()["session_id"] == "" {
+		t.Errorf("tool handler log = %+v, want the serve logger with the session ID", pinged[0])
+	}
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Run() error after cancel = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+}
+
+// headerTransport adds a header to every request, as MCP clients presenting
+// credentials do
+type headerTransport struct {
+	next        http.RoundTripper
+	name, value string
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(t.name, t.value)
+	return t.next.RoundTrip(req)
+}
+
+func TestRunSSEClientCredentials(t *testing.T) {
+	// The SSE client of mcp-go can't set headers, but sends through the default transport
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = headerTransport{next: defaultTransport, name: "X-Api-Token", value: "user-token"}
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	credentials := make(chan string, 1)
+	s := server.NewMCPServer("Test", "1.0.0")
+	s.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		credentials <- auth.ClientCredentials(ctx)
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	go Run(ctx, s, Options{Transport: TransportSSE, Host: "localhost", CredentialHeader: "X-Api-Token", Ready: func(url string) { ready <- url }}, zap.NewNop())
+
+	var sseURL string
+	select {
+	case sseURL = <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+	c, err := client.NewSSEMCPClient(sseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	if _, err := c.Initialize(ctx, initRequest); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	callRequest := mcp.CallToolRequest{}
+	callRequest.Params.Name = "whoami"
+	if _, err := c.CallTool(ctx, callRequest); err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if got := <-credentials; got != "user-token" {
+		t.Errorf("ClientCredentials() = %q, want the client's header", got)
+	}
+}
+
+func TestRunSSETenants(t *testing.T) {
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = headerTransport{next: defaultTransport, name: tenant.APIKeyHeader, value: "acme-key"}
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	tenants := make(chan string, 2)
+	s := server.NewMCPServer("Test", "1.0.0")
+	s.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		caller, _ := tenant.FromContext(ctx)
+		tenants <- caller.Name
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	opts := Options{
+		Transport: TransportSSE,
+		Host:      "localhost",
+		Tenants:   []tenant.Tenant{{Name: "acme", APIKey: "acme-key"}, {Name: "globex", APIKey: "globex-key"}},
+		Ready:     func(url string) { ready <- url },
+	}
+	go Run(ctx, s, opts, zap.NewNop())
+
+	var sseURL string
+	select {
+	case sseURL = <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+	baseURL := strings.TrimSuffix(sseURL, "/sse")
+
+	// The root endpoints and acme's own serve acme's key, globex's refuse it
+	for _, url := range []string{sseURL, baseURL + "/acme/sse"} {
+		c, err := client.NewSSEMCPClient(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if err := c.Start(ctx); err != nil {
+			t.Fatalf("Start(%s) error = %v", url, err)
+		}
+		initRequest := mcp.InitializeRequest{}
+		initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		if _, err := c.Initialize(ctx, initRequest); err != nil {
+			t.Fatalf("Initialize(%s) error = %v", url, err)
+		}
+		callRequest := mcp.CallToolRequest{}
+		callRequest.Params.Name = "whoami"
+		if _, err := c.CallTool(ctx, callRequest); err != nil {
+			t.Fatalf("CallTool(%s) error = %v", url, err)
+		}
+		if got := <-tenants; got != "acme" {
+			t.Errorf("tenant of %s = %q, want acme", url, got)
+		}
+	}
+
+	resp, err := http.Get(baseURL + "/globex/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("globex endpoint with acme's key: status %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	opts := Options{Transport: TransportSSE, Host: "0.0.0.0", Port: 8080}
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if got, want := opts.Addr(), "0.0.0.0:8080"; got != want {
+		t.Errorf("Addr() = %q, want %q", got, want)
+	}
+	if got, want := opts.SSEURL(), "http://localhost:8080/sse"; got != want {
+		t.Errorf("SSEURL() = %q, want %q", got, want)
+	}
+
+	if err := (Options{Transport: TransportStdio, Tenants: []tenant.Tenant{{Name: "acme"}}}).Validate(); err == nil {
+		t.Error("Validate() with tenants over stdio succeeded, want error")
+	}
+	opts.Transport = TransportWebSocket
+	if got, want := opts.URL(), "ws://localhost:8080/ws"; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+	opts.TLS = TLS{SelfSigned: true}
+	if got, want := opts.URL(), "wss://localhost:8080/ws"; got != want {
+		t.Errorf("URL() over TLS = %q, want %q", got, want)
+	}
+	if err := (Options{Transport: "grpc"}).Validate(); err == nil {
+		t.Error("Validate() with an unknown transport succeeded, want error")
+	}
+}
+
+func TestRunSSEHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	opts := Options{
+		Transport: TransportSSE,
+		Host:      "localhost",
+		Auth:      &clientauth.Authenticator{Keys: []string{"client-key"}},
+		Health:    &health.Checker{LoadedAt: time.Now()},
+		Ready:     func(url string) { ready <- url },
+	}
+	go Run(ctx, server.NewMCPServer("Test", "1.0.0"), opts, zap.NewNop())
+
+	var sseURL string
+	select {
+	case sseURL = <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+	baseURL := strings.TrimSuffix(sseURL, "/sse")
+
+	// Probes carry no credentials, MCP clients must
+	for path, want := range map[string]int{
+		health.LivenessPath:  http.StatusOK,
+		health.ReadinessPath: http.StatusOK,
+		health.VersionPath:   http.StatusOK,
+		"/sse":               http.StatusUnauthorized,
+	} {
+		resp, err := http.Get(baseURL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s: status %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestRunSSEMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	toolMetrics := metrics.New()
+	toolMetrics.Observe("get_pets", "ok", time.Millisecond)
+	opts := Options{
+		Transport: TransportSSE,
+		Host:      "localhost",
+		Auth:      &clientauth.Authenticator{Keys: []string{"client-key"}},
+		Metrics:   toolMetrics,
+		Ready:     func(url string) { ready <- url },
+	}
+	go Run(ctx, server.NewMCPServer("Test", "1.0.0"), opts, zap.NewNop())
+
+	var sseURL string
+	select {
+	case sseURL = <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+	metricsURL := strings.TrimSuffix(sseURL, "/sse") + metrics.Path
+
+	// Metrics are only served to authenticated clients
+	resp, err := http.Get(metricsURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status without a key = %d, want 401", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, metricsURL, nil)
+	req.Header.Set("X-API-Key", "client-key")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `mcprox_tool_calls_total{tool="get_pets",status="ok"} 1`) {
+		t.Errorf("metrics = %d %s", resp.StatusCode, body)
+	}
+}
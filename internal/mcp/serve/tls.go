@@ -0,0 +1,150 @@
+package serve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// selfSignedValidity is how long self-signed development certificates last
+const selfSignedValidity = 30 * 24 * time.Hour
+
+// TLS configures how network transports terminate TLS. At most one of a
+// certificate file, a self-signed certificate and ACME may be used.
+type TLS struct {
+	// CertFile and KeyFile are a PEM certificate chain and its private key
+	CertFile string
+	KeyFile  string
+	// SelfSigned serves a certificate generated at startup, for development
+	SelfSigned bool
+	// ACMEDomains obtains certificates for these domains from Let's Encrypt,
+	// which must reach the server on port 443
+	ACMEDomains []string
+	// ACMEEmail is the contact address of the ACME account, if set
+	ACMEEmail string
+	// ACMECacheDir keeps the ACME account and certificates across restarts,
+	// mcprox/acme in the user cache directory if empty
+	ACMECacheDir string
+	// ACMEDirectoryURL is the ACME directory, Let's Encrypt's if empty
+	ACMEDirectoryURL string
+}
+
+// Enabled reports whether TLS is configured
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != "" || t.SelfSigned || len(t.ACMEDomains) > 0
+}
+
+// Validate checks that a single, complete way of obtaining certificates is
+// configured
+func (t TLS) Validate() error {
+	modes := 0
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return errors.New("--tls-cert and --tls-key must be given together")
+		}
+		modes++
+	}
+	if t.SelfSigned {
+		modes++
+	}
+	if len(t.ACMEDomains) > 0 {
+		modes++
+	}
+	if modes > 1 {
+		return errors.New("use only one of --tls-cert/--tls-key, --tls-self-signed and --acme-domain")
+	}
+	return nil
+}
+
+// Config returns the TLS configuration of a server listening on host
+func (t TLS) Config(host string, logger *zap.Logger) (*tls.Config, error) {
+	switch {
+	case t.CertFile != "":
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+	case t.SelfSigned:
+		cert, err := selfSignedCertificate(host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate a self-signed certificate: %w", err)
+		}
+		// Clients pin or trust the certificate by its fingerprint
+		fingerprint := sha256.Sum256(cert.Certificate[0])
+		logger.Warn("Serving a self-signed certificate, for development only", zap.String("sha256", hex.EncodeToString(fingerprint[:])))
+		return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+	default:
+		cacheDir := t.ACMECacheDir
+		if cacheDir == "" {
+			userCache, err := os.UserCacheDir()
+			if err != nil {
+				return nil, err
+			}
+			cacheDir = filepath.Join(userCache, "mcprox", "acme")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(t.ACMEDomains...),
+			Cache:      autocert.DirCache(cacheDir),
+			Email:      t.ACMEEmail,
+		}
+		if t.ACMEDirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: t.ACMEDirectoryURL}
+		}
+		logger.Info("Obtaining certificates with ACME", zap.Strings("domains", t.ACMEDomains), zap.String("cache_dir", cacheDir))
+		// Answers the tls-alpn-01 challenge on the server's own port
+		return manager.TLSConfig(), nil
+	}
+}
+
+// selfSignedCertificate generates a certificate for host, localhost and the
+// loopback addresses
+func selfSignedCertificate(host string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"mcprox development"}, CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	} else if host != "" && host != "localhost" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,91 @@
+package serve
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+func TestRunSSESelfSigned(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan string, 1)
+	stopped := make(chan error, 1)
+	opts := Options{Transport: TransportSSE, Host: "localhost", TLS: TLS{SelfSigned: true}, Ready: func(url string) { ready <- url }}
+	go func() {
+		stopped <- Run(ctx, server.NewMCPServer("Test", "1.0.0"), opts, zap.NewNop())
+	}()
+
+	var sseURL string
+	select {
+	case sseURL = <-ready:
+	case err := <-stopped:
+		t.Fatalf("Run() error = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+	if !strings.HasPrefix(sseURL, "https://localhost:") {
+		t.Fatalf("SSE URL = %q, want https", sseURL)
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get(sseURL)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", sseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 || resp.TLS.PeerCertificates[0].VerifyHostname("localhost") != nil {
+		t.Fatal("server didn't present a certificate for localhost")
+	}
+
+	// The endpoint clients post messages to is advertised over https too
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			if !strings.HasPrefix(data, "https://") {
+				t.Errorf("message endpoint = %q, want https", data)
+			}
+			break
+		}
+	}
+}
+
+func TestTLSValidate(t *testing.T) {
+	valid := []TLS{
+		{},
+		{CertFile: "cert.pem", KeyFile: "key.pem"},
+		{SelfSigned: true},
+		{ACMEDomains: []string{"mcp.example.com"}},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Errorf("Validate(%+v) error = %v", c, err)
+		}
+	}
+
+	invalid := []TLS{
+		{CertFile: "cert.pem"},
+		{SelfSigned: true, ACMEDomains: []string{"mcp.example.com"}},
+		{CertFile: "cert.pem", KeyFile: "key.pem", SelfSigned: true},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", c)
+		}
+	}
+
+	if err := (Options{Transport: TransportStdio, TLS: TLS{SelfSigned: true}}).Validate(); err == nil {
+		t.Error("Validate() with TLS over stdio succeeded, want error")
+	}
+	opts := Options{Transport: TransportSSE, Port: 443, TLS: TLS{ACMEDomains: []string{"mcp.example.com"}}}
+	if got, want := opts.SSEURL(), "https://mcp.example.com:443/sse"; got != want {
+		t.Errorf("SSEURL() = %q, want %q", got, want)
+	}
+}
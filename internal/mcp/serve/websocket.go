@@ -0,0 +1,217 @@
+package serve
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketPath is the path of the WebSocket endpoint, under the prefix of
+// tenants if any
+const WebSocketPath = "/ws"
+
+// maxSessionMessages is how many messages of a WebSocket session are handled
+// at a time, as many as the SSE transport queues the responses of
+const maxSessionMessages = 100
+
+// webSocketServer serves MCP sessions over WebSocket connections, one per
+// connection, each frame carrying a JSON-RPC message either way
+type webSocketServer struct {
+	server     *server.MCPServer
+	httpServer *http.Server
+	path       string
+	// contextFunc adds to the context of a session what its upgrade request
+	// carries, e.g. the client's credentials
+	contextFunc func(ctx context.Context, r *http.Request) context.Context
+	logger      *zap.Logger
+	// maxMessages is how many messages of a session are handled at a time
+	maxMessages int
+
+	mu       sync.Mutex
+	sessions map[*webSocketSession]struct{}
+	closed   bool
+}
+
+// webSocketSession is the MCP session of a WebSocket connection
+type webSocketSession struct {
+	id            string
+	conn          *websocket.Conn
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *webSocketSession) SessionID() string {
+	return s.id
+}
+
+func (s *webSocketSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// newWebSocketServer returns the WebSocket endpoint at path of the MCP server
+// s, whose Shutdown also shuts httpServer down
+func newWebSocketServer(s *server.MCPServer, httpServer *http.Server, path string, contextFunc func(ctx context.Context, r *http.Request) context.Context, logger *zap.Logger) *webSocketServer {
+	return &webSocketServer{
+		server:      s,
+		httpServer:  httpServer,
+		path:        path,
+		contextFunc: contextFunc,
+		logger:      logger,
+		maxMessages: maxSessionMessages,
+		sessions:    make(map[*webSocketSession]struct{}),
+	}
+}
+
+func (ws *webSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != ws.path {
+		http.NotFound(w, r)
+		return
+	}
+	// The host and the origin of browsers are checked by CORS before the
+	// upgrade, refusing pages rebound to the server's address
+	websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   ws.serveSession,
+	}.ServeHTTP(w, r)
+}
+
+// serveSession handles the messages of a connection until it closes. Every
+// message is handled concurrently, so that a client can cancel or ping while
+// its tool calls run; at most maxMessages at a time, the connection isn't
+// read while that many are being handled.
+func (ws *webSocketServer) serveSession(conn *websocket.Conn) {
+	session := &webSocketSession{id: newSessionID(), conn: conn, notifications: make(chan mcp.JSONRPCNotification, 100)}
+	if !ws.track(session) {
+		conn.Close()
+		return
+	}
+	defer ws.untrack(session)
+	if err := ws.server.RegisterSession(session); err != nil {
+		ws.logger.Error("Failed to register WebSocket session", zap.Error(err))
+		conn.Close()
+		return
+	}
+	defer ws.server.UnregisterSession(session.id)
+
+	ctx, cancel := context.WithCancel(conn.Request().Context())
+	defer cancel()
+	ctx = ws.server.WithContext(ctx, session)
+	if ws.contextFunc != nil {
+		ctx = ws.contextFunc(ctx, conn.Request())
+	}
+
+	go func() {
+		for {
+			select {
+			case notification := <-session.notifications:
+				ws.write(session, notification)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var handling sync.WaitGroup
+	slots := make(chan struct{}, ws.maxMessages)
+	for {
+		slots <- struct{}{}
+		var message []byte
+		if err := websocket.Message.Receive(conn, &message); err != nil {
+			if !errors.Is(err, io.EOF) {
+				ws.logger.Debug("WebSocket session closed", zap.String("session_id", session.id), zap.Error(err))
+			}
+			break
+		}
+		handling.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				handling.Done()
+			}()
+			ws.handle(ctx, session, message)
+		}()
+	}
+	cancel()
+	handling.Wait()
+	conn.Close()
+}
+
+// handle passes a message to the MCP server and writes its response, if any
+func (ws *webSocketServer) handle(ctx context.Context, session *webSocketSession, message []byte) {
+	var request struct {
+		ID interface{} `json:"id"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil {
+		response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION}
+		response.Error.Code = mcp.PARSE_ERROR
+		response.Error.Message = "Parse error"
+		ws.write(session, response)
+		return
+	}
+	// Cancellation notifications refer to the requests of tool calls
+	if request.ID != nil {
+		ctx = context.WithValue(ctx, requestIDKey{}, request.ID)
+	}
+	if response := ws.server.HandleMessage(ctx, message); response != nil {
+		ws.write(session, response)
+	}
+}
+
+// write sends a message to the client of a session in a text frame
+func (ws *webSocketServer) write(session *webSocketSession, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		ws.logger.Error("Failed to encode WebSocket message", zap.Error(err))
+		return
+	}
+	if err := websocket.Message.Send(session.conn, string(data)); err != nil {
+		ws.logger.Debug("Failed to write WebSocket message", zap.String("session_id", session.id), zap.Error(err))
+	}
+}
+
+// track adds a session to those Shutdown closes, unless shutting down
+func (ws *webSocketServer) track(session *webSocketSession) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return false
+	}
+	ws.sessions[session] = struct{}{}
+	return true
+}
+
+func (ws *webSocketServer) untrack(session *webSocketSession) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.sessions, session)
+}
+
+// Shutdown closes the open WebSocket connections, which the HTTP server
+// doesn't track once upgraded, then shuts the HTTP server down
+func (ws *webSocketServer) Shutdown(ctx context.Context) error {
+	ws.mu.Lock()
+	ws.closed = true
+	for session := range ws.sessions {
+		session.conn.Close()
+	}
+	ws.mu.Unlock()
+	return ws.httpServer.Shutdown(ctx)
+}
+
+// newSessionID returns a random identifier of a WebSocket session
+func newSessionID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,180 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/auth"
+	"github.com/berkantay/mcprox/internal/clientauth"
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/net/websocket"
+)
+
+// dialWebSocket connects to a WebSocket endpoint with headers, from the
+// server's own origin
+func dialWebSocket(t *testing.T, endpoint string, header http.Header) (*websocket.Conn, error) {
+	t.Helper()
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := websocket.NewConfig(endpoint, "http://"+u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Header = header
+	return websocket.DialConfig(config)
+}
+
+// call sends a JSON-RPC request over a connection and returns the result of
+// its response
+func call(t *testing.T, conn *websocket.Conn, id int, method string, params interface{}) json.RawMessage {
+	t.Helper()
+	request := map[string]interface{}{"jsonrpc": mcp.JSONRPC_VERSION, "id": id, "method": method, "params": params}
+	if err := websocket.JSON.Send(conn, request); err != nil {
+		t.Fatalf("%s: send error = %v", method, err)
+	}
+	var response struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := websocket.JSON.Receive(conn, &response); err != nil {
+		t.Fatalf("%s: receive error = %v", method, err)
+	}
+	if response.ID != id || response.Error != nil {
+		t.Fatalf("%s: response %d error %+v, want the result of %d", method, response.ID, response.Error, id)
+	}
+	return response.Result
+}
+
+func TestRunWebSocket(t *testing.T) {
+	credentials := make(chan string, 1)
+	s := server.NewMCPServer("Test", "1.0.0")
+	s.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		logging.FromContext(ctx).Info("Called")
+		credentials <- auth.ClientCredentials(ctx)
+		return mcp.NewToolResultText("ok"), nil
+	})
+	core, logs := observer.New(zap.InfoLevel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan string, 1)
+	stopped := make(chan error, 1)
+	opts := Options{
+		Transport:        TransportWebSocket,
+		Host:             "localhost",
+		CredentialHeader: "X-Api-Token",
+		Auth:             &clientauth.Authenticator{Keys: []string{"secret"}},
+		Ready:            func(url string) { ready <- url },
+	}
+	go func() {
+		stopped <- Run(ctx, s, opts, zap.New(core))
+	}()
+
+	var wsURL string
+	select {
+	case wsURL = <-ready:
+	case err := <-stopped:
+		t.Fatalf("Run() error = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+
+	if _, err := dialWebSocket(t, wsURL, nil); err == nil {
+		t.Error("connecting without an API key succeeded, want it refused")
+	}
+	conn, err := dialWebSocket(t, wsURL, http.Header{clientauth.APIKeyHeader: {"secret"}, "X-Api-Token": {"user-token"}})
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	call(t, conn, 1, "initialize", map[string]interface{}{"protocolVersion": mcp.LATEST_PROTOCOL_VERSION, "clientInfo": map[string]string{"name": "test"}})
+	var tools mcp.ListToolsResult
+	if err := json.Unmarshal(call(t, conn, 2, "tools/list", nil), &tools); err != nil || len(tools.Tools) != 1 {
+		t.Fatalf("tools/list = %+v, %v, want the whoami tool", tools, err)
+	}
+	call(t, conn, 3, "tools/call", map[string]interface{}{"name": "whoami"})
+	if got := <-credentials; got != "user-token" {
+		t.Errorf("ClientCredentials() = %q, want the client's header", got)
+	}
+	called := logs.FilterMessage("Called").All()
+	if len(called) != 1 || called[0].ContextMap()["session_id"] == "" {
+		t.Errorf("tool handler logs = %+v, want one with the session ID", called)
+	}
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Run() error after cancel = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+}
+
+func TestWebSocketSessionLimit(t *testing.T) {
+	var started atomic.Int32
+	release := make(chan struct{})
+	s := server.NewMCPServer("Test", "1.0.0")
+	s.AddTool(mcp.NewTool("wait"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		started.Add(1)
+		<-release
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ws := newWebSocketServer(s, &http.Server{}, WebSocketPath, nil, zap.NewNop())
+	ws.maxMessages = 2
+	ts := httptest.NewServer(ws)
+	defer ts.Close()
+
+	conn, err := dialWebSocket(t, "ws"+strings.TrimPrefix(ts.URL, "http")+WebSocketPath, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+	call(t, conn, 1, "initialize", map[string]interface{}{"protocolVersion": mcp.LATEST_PROTOCOL_VERSION, "clientInfo": map[string]string{"name": "test"}})
+
+	for id := 2; id <= 4; id++ {
+		request := map[string]interface{}{"jsonrpc": mcp.JSONRPC_VERSION, "id": id, "method": "tools/call", "params": map[string]interface{}{"name": "wait"}}
+		if err := websocket.JSON.Send(conn, request); err != nil {
+			t.Fatalf("send error = %v", err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for started.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := started.Load(); got != 2 {
+		t.Errorf("%d calls started, want the limit of 2", got)
+	}
+
+	// The last call is handled once the others are done
+	close(release)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i := 0; i < 3; i++ {
+		var response map[string]interface{}
+		if err := websocket.JSON.Receive(conn, &response); err != nil {
+			t.Fatalf("receive error = %v", err)
+		}
+	}
+	if got := started.Load(); got != 3 {
+		t.Errorf("%d calls started, want 3", got)
+	}
+}
@@ -0,0 +1,129 @@
+// Package smoketest checks that a running MCP server answers the protocol
+package smoketest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/version"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// HealthTool is the tool called when none is configured, if the server has it
+const HealthTool = "check_api_health"
+
+// Options configures a smoke test
+type Options struct {
+	// Tool is the tool to call; HealthTool if empty and the server has it
+	Tool string
+	// Arguments are the arguments the tool is called with
+	Arguments map[string]interface{}
+}
+
+// Step is the outcome of one check of a smoke test
+type Step struct {
+	Name   string
+	Detail string
+	Err    error
+}
+
+// Passed reports whether the check succeeded
+func (s Step) Passed() bool {
+	return s.Err == nil
+}
+
+// Run connects to the SSE endpoint of an MCP server, performs the MCP
+// handshake, lists the tools and calls one of them. It returns the checks
+// performed, stopping at the first failed one, and an error if any failed.
+func Run(ctx context.Context, sseURL string, opts Options) ([]Step, error) {
+	var steps []Step
+	fail := func(name string, err error) ([]Step, error) {
+		steps = append(steps, Step{Name: name, Err: err})
+		return steps, fmt.Errorf("%s failed: %w", name, err)
+	}
+
+	c, err := client.NewSSEMCPClient(sseURL)
+	if err != nil {
+		return fail("connect", err)
+	}
+	defer c.Close()
+	if err := c.Start(ctx); err != nil {
+		return fail("connect", err)
+	}
+	steps = append(steps, Step{Name: "connect", Detail: sseURL})
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "mcprox-test", Version: version.Get()}
+	initResult, err := c.Initialize(ctx, initRequest)
+	if err != nil {
+		return fail("initialize", err)
+	}
+	steps = append(steps, Step{
+		Name: "initialize",
+		Detail: fmt.Sprintf("%s %s, protocol %s", initResult.ServerInfo.Name,
+			initResult.ServerInfo.Version, initResult.ProtocolVersion),
+	})
+
+	tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return fail("list tools", err)
+	}
+	if len(tools.Tools) == 0 {
+		return fail("list tools", errors.New("the server has no tools"))
+	}
+	names := make(map[string]bool, len(tools.Tools))
+	for _, tool := range tools.Tools {
+		names[tool.Name] = true
+	}
+	steps = append(steps, Step{Name: "list tools", Detail: fmt.Sprintf("%d tools", len(tools.Tools))})
+
+	tool := opts.Tool
+	if tool == "" {
+		if !names[HealthTool] {
+			steps = append(steps, Step{Name: "call tool", Detail: "skipped, no tool to call configured"})
+			return steps, nil
+		}
+		tool = HealthTool
+	}
+	name := "call " + tool
+	if !names[tool] {
+		return fail(name, fmt.Errorf("the server has no tool %q", tool))
+	}
+
+	callRequest := mcp.CallToolRequest{}
+	callRequest.Params.Name = tool
+	callRequest.Params.Arguments = opts.Arguments
+	result, err := c.CallTool(ctx, callRequest)
+	if err != nil {
+		return fail(name, err)
+	}
+	if result.IsError {
+		return fail(name, fmt.Errorf("tool returned an error: %s", resultText(result)))
+	}
+	steps = append(steps, Step{Name: name, Detail: summarize(resultText(result))})
+	return steps, nil
+}
+
+// resultText returns the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// summarize shortens a tool result to a single line for reporting
+func summarize(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) > 80 {
+		return string(runes[:77]) + "..."
+	}
+	return string(runes)
+}
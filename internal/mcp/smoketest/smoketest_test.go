@@ -0,0 +1,124 @@
+package smoketest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/mcp/serve"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// startServer serves s over SSE until the test ends and returns its URL
+func startServer(t *testing.T, s *server.MCPServer) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan string, 1)
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- serve.Run(ctx, s, serve.Options{Transport: serve.TransportSSE, Host: "localhost", Ready: func(url string) { ready <- url }}, zap.NewNop())
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	select {
+	case url := <-ready:
+		return url
+	case err := <-stopped:
+		t.Fatalf("serve.Run() error = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't become ready")
+	}
+	return ""
+}
+
+func TestRun(t *testing.T) {
+	s := server.NewMCPServer("Test", "1.0.0")
+	s.AddTool(mcp.NewTool(HealthTool), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("API is healthy"), nil
+	})
+	s.AddTool(mcp.NewTool("echo"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(request.Params.Arguments["text"].(string)), nil
+	})
+	s.AddTool(mcp.NewTool("broken"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result := mcp.NewToolResultText("upstream unavailable")
+		result.IsError = true
+		return result, nil
+	})
+	sseURL := startServer(t, s)
+
+	tests := []struct {
+		name       string
+		opts       Options
+		wantErr    string
+		wantDetail string
+	}{
+		{name: "health tool by default", wantDetail: "API is healthy"},
+		{name: "configured tool", opts: Options{Tool: "echo", Arguments: map[string]interface{}{"text": "hello"}}, wantDetail: "hello"},
+		{name: "unknown tool", opts: Options{Tool: "missing"}, wantErr: `no tool "missing"`},
+		{name: "tool error", opts: Options{Tool: "broken"}, wantErr: "upstream unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			steps, err := Run(ctx, sseURL, tt.opts)
+			if len(steps) != 4 {
+				t.Fatalf("Run() steps = %+v, want 4", steps)
+			}
+			last := steps[len(steps)-1]
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || last.Passed() {
+					t.Errorf("Run() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if last.Detail != tt.wantDetail {
+				t.Errorf("Run() last step detail = %q, want %q", last.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestRunWithoutTools(t *testing.T) {
+	sseURL := startServer(t, server.NewMCPServer("Test", "1.0.0"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	steps, err := Run(ctx, sseURL, Options{})
+	if err == nil {
+		t.Fatal("Run() error = nil, want an error for a server without tools")
+	}
+	if last := steps[len(steps)-1]; last.Name != "list tools" || last.Passed() {
+		t.Errorf("Run() last step = %+v, want a failed list tools step", last)
+	}
+}
+
+func TestStepPassed(t *testing.T) {
+	if !(Step{Name: "connect"}).Passed() {
+		t.Error("Passed() = false for a step without error")
+	}
+	if (Step{Name: "connect", Err: errors.New("refused")}).Passed() {
+		t.Error("Passed() = true for a step with an error")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	if got := summarize("a\n  b\tc"); got != "a b c" {
+		t.Errorf("summarize() = %q, want %q", got, "a b c")
+	}
+	long := strings.Repeat("é", 100)
+	if got := []rune(summarize(long)); len(got) != 80 {
+		t.Errorf("summarize() length = %d runes, want 80", len(got))
+	}
+}
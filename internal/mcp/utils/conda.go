@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// GenerateCondaEnvironment writes an environment.yml creating a conda
+// environment for the project. Python comes from conda-forge, the runtime
+// requirements are installed from PyPI with pip.
+func GenerateCondaEnvironment(filePath string, doc *openapi3.T, deps PythonDependencies) error {
+	runtime, _, err := deps.Resolve()
+	if err != nil {
+		return err
+	}
+
+	// Conda match specs don't know the compatible release operator
+	python := "python" + deps.PythonVersion()
+	if strings.Contains(python, "~=") {
+		python = "python>=" + deps.MinimumPythonVersion()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Generated by mcprox\n")
+	sb.WriteString(fmt.Sprintf("name: %s\n", SanitizeForPackageName(doc.Info.Title)))
+	sb.WriteString("channels:\n")
+	sb.WriteString("  - conda-forge\n")
+	sb.WriteString("dependencies:\n")
+	sb.WriteString(fmt.Sprintf("  - %s\n", strconv.Quote(python)))
+	sb.WriteString("  - pip\n")
+	sb.WriteString("  - pip:\n")
+	for _, requirement := range runtime {
+		sb.WriteString(fmt.Sprintf("      - %s\n", strconv.Quote(requirement)))
+	}
+
+	return os.WriteFile(filePath, []byte(sb.String()), 0644)
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultRequiresPython is the Python version generated projects require
+const DefaultRequiresPython = ">=3.11"
+
+// Formats in which generated projects declare their dependencies
+const (
+	// DepsPyproject declares dependencies in pyproject.toml only
+	DepsPyproject = "pyproject"
+	// DepsRequirements declares dependencies in requirements files only
+	DepsRequirements = "requirements"
+	// DepsBoth declares dependencies in pyproject.toml and requirements files
+	DepsBoth = "both"
+)
+
+// Build systems of generated projects
+const (
+	// PackagingSetuptools builds projects with setuptools and installs them with uv
+	PackagingSetuptools = "setuptools"
+	// PackagingPoetry builds and installs projects with Poetry
+	PackagingPoetry = "poetry"
+)
+
+// Formatters run on generated Python code after the emitter's own layout
+const (
+	// FormatterNone leaves the code as the emitter lays it out
+	FormatterNone = "none"
+	// FormatterAuto runs ruff, or black if ruff isn't installed
+	FormatterAuto = "auto"
+	// FormatterBlack runs black
+	FormatterBlack = "black"
+	// FormatterRuff runs ruff format
+	FormatterRuff = "ruff"
+)
+
+// Formatters lists the formatters of generated code
+var Formatters = []string{FormatterNone, FormatterAuto, FormatterBlack, FormatterRuff}
+
+// ValidFormatter reports whether a formatter of generated code is known,
+// empty meaning FormatterNone
+func ValidFormatter(formatter string) bool {
+	if formatter == "" {
+		return true
+	}
+	for _, f := range Formatters {
+		if f == formatter {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultDependencies are the runtime dependencies of generated servers
+var DefaultDependencies = []string{"mcp", "httpx", "python-dotenv"}
+
+// MinimumSDKVersion is the oldest MCP Python SDK generated servers run on, the
+// first whose FastMCP takes instructions
+const MinimumSDKVersion = "1.3.0"
+
+// StreamableHTTPSDKVersion is the first MCP Python SDK serving the
+// streamable-http transport, with resumable sessions
+const StreamableHTTPSDKVersion = "1.8.0"
+
+// DefaultDevDependencies are the development dependencies of generated projects
+var DefaultDevDependencies = []string{"pytest", "black", "ruff", "mypy"}
+
+// requirementName matches the distribution name at the start of a PEP 508 requirement
+var requirementName = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?`)
+
+// requirementSeparators matches the runs of characters PEP 503 treats as equivalent
+var requirementSeparators = regexp.MustCompile(`[-_.]+`)
+
+// sdkVersion matches the release versions of the MCP Python SDK, e.g. 1.9.4
+var sdkVersion = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)$`)
+
+// pythonMinorVersion matches the first major.minor version of a specifier
+var pythonMinorVersion = regexp.MustCompile(`3\.(\d+)`)
+
+// PythonDependencies configures the Python version and packages of a
+// generated project
+type PythonDependencies struct {
+	// Format is one of DepsPyproject, DepsRequirements and DepsBoth,
+	// DepsPyproject if empty
+	Format string
+	// RequiresPython is the requires-python specifier, DefaultRequiresPython if empty
+	RequiresPython string
+	// Pins replace default dependencies with pinned requirements, e.g. "mcp==1.6.0"
+	Pins []string
+	// Extra are additional runtime requirements
+	Extra []string
+	// Packaging is PackagingSetuptools or PackagingPoetry, PackagingSetuptools if empty
+	Packaging string
+	// Conda reports whether an environment.yml is generated for conda users
+	Conda bool
+	// Locked reports whether the dependencies are locked to exact versions,
+	// in uv.lock or poetry.lock for pyproject.toml and requirements.lock for
+	// requirements.txt
+	Locked bool
+	// SDKVersion is the MCP Python SDK release the generated code targets,
+	// pinning the mcp dependency unless Pins does; empty for the latest one
+	SDKVersion string
+}
+
+// Validate checks the dependency format, packaging and requirements
+func (d PythonDependencies) Validate() error {
+	switch d.Format {
+	case "", DepsPyproject, DepsRequirements, DepsBoth:
+	default:
+		return fmt.Errorf("invalid Python dependency format %q: must be %s, %s or %s", d.Format, DepsPyproject, DepsRequirements, DepsBoth)
+	}
+	switch d.Packaging {
+	case "", PackagingSetuptools:
+	case PackagingPoetry:
+		if !d.UsesPyproject() {
+			return fmt.Errorf("%s packaging requires a pyproject.toml; use --python-deps %s or %s", PackagingPoetry, DepsPyproject, DepsBoth)
+		}
+	default:
+		return fmt.Errorf("invalid Python packaging %q: must be %s or %s", d.Packaging, PackagingSetuptools, PackagingPoetry)
+	}
+	if d.SDKVersion != "" {
+		if !sdkVersion.MatchString(d.SDKVersion) {
+			return fmt.Errorf("invalid MCP Python SDK version %q: must be a release such as 1.9.4", d.SDKVersion)
+		}
+		if !SDKAtLeast(d.SDKVersion, MinimumSDKVersion) {
+			return fmt.Errorf("MCP Python SDK %s is unsupported: generated servers need %s or later", d.SDKVersion, MinimumSDKVersion)
+		}
+	}
+	_, _, err := d.Resolve()
+	return err
+}
+
+// SDKAtLeast reports whether an MCP Python SDK version, empty for the latest
+// one, is minimum or later
+func SDKAtLeast(version, minimum string) bool {
+	if version == "" {
+		return true
+	}
+	v, m := sdkVersion.FindStringSubmatch(version), sdkVersion.FindStringSubmatch(minimum)
+	if v == nil || m == nil {
+		return false
+	}
+	for i := 1; i <= 3; i++ {
+		a, _ := strconv.Atoi(v[i])
+		b, _ := strconv.Atoi(m[i])
+		if a != b {
+			return a > b
+		}
+	}
+	return true
+}
+
+// UsesPyproject reports whether dependencies are declared in pyproject.toml
+func (d PythonDependencies) UsesPyproject() bool {
+	return d.Format != DepsRequirements
+}
+
+// UsesRequirements reports whether dependencies are declared in requirements files
+func (d PythonDependencies) UsesRequirements() bool {
+	return d.Format == DepsRequirements || d.Format == DepsBoth
+}
+
+// UsesPoetry reports whether the project is built with Poetry
+func (d PythonDependencies) UsesPoetry() bool {
+	return d.Packaging == PackagingPoetry
+}
+
+// PythonVersion returns the requires-python specifier
+func (d PythonDependencies) PythonVersion() string {
+	if d.RequiresPython == "" {
+		return DefaultRequiresPython
+	}
+	return d.RequiresPython
+}
+
+// MinimumPythonVersion returns the lowest supported Python version, e.g. "3.11"
+func (d PythonDependencies) MinimumPythonVersion() string {
+	if m := pythonMinorVersion.FindStringSubmatch(d.PythonVersion()); m != nil {
+		return "3." + m[1]
+	}
+	return "3.11"
+}
+
+// TargetVersion returns the lowest supported Python version in the "py311"
+// form used by code formatters
+func (d PythonDependencies) TargetVersion() string {
+	return "py" + strings.ReplaceAll(d.MinimumPythonVersion(), ".", "")
+}
+
+// Resolve returns the runtime and development requirements with the pins
+// applied. Every pin must name a default dependency and carry a version.
+func (d PythonDependencies) Resolve() (runtime, dev []string, err error) {
+	pins := make(map[string]string, len(d.Pins)+1)
+	if d.SDKVersion != "" {
+		pins["mcp"] = "mcp==" + d.SDKVersion
+	}
+	for _, pin := range d.Pins {
+		name := requirementName.FindString(strings.TrimSpace(pin))
+		if name == "" {
+			return nil, nil, fmt.Errorf("invalid pinned requirement %q", pin)
+		}
+		if strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(pin), name)) == "" {
+			return nil, nil, fmt.Errorf("pinned requirement %q has no version specifier", pin)
+		}
+		pins[normalizeRequirementName(name)] = strings.TrimSpace(pin)
+	}
+
+	apply := func(defaults []string) []string {
+		requirements := make([]string, 0, len(defaults))
+		for _, name := range defaults {
+			if pin, ok := pins[normalizeRequirementName(name)]; ok {
+				requirements = append(requirements, pin)
+				delete(pins, normalizeRequirementName(name))
+			} else {
+				requirements = append(requirements, name)
+			}
+		}
+		return requirements
+	}
+	runtime = apply(DefaultDependencies)
+	dev = apply(DefaultDevDependencies)
+
+	for name := range pins {
+		return nil, nil, fmt.Errorf("pinned requirement %q is not a dependency of generated projects; add it as an extra dependency instead", pins[name])
+	}
+
+	for _, extra := range d.Extra {
+		if requirementName.FindString(strings.TrimSpace(extra)) == "" {
+			return nil, nil, fmt.Errorf("invalid extra requirement %q", extra)
+		}
+		runtime = append(runtime, strings.TrimSpace(extra))
+	}
+	return runtime, dev, nil
+}
+
+// RequirementName returns the normalized distribution name of a PEP 508
+// requirement, empty if it has none
+func RequirementName(requirement string) string {
+	return normalizeRequirementName(requirementName.FindString(strings.TrimSpace(requirement)))
+}
+
+// normalizeRequirementName normalizes a distribution name as described in PEP 503
+func normalizeRequirementName(name string) string {
+	return strings.ToLower(requirementSeparators.ReplaceAllString(name, "-"))
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvVar describes an environment variable read by the generated server
+type EnvVar struct {
+	Name        string
+	Default     string
+	Description string
+}
+
+// EnvGroup is a titled set of related environment variables
+type EnvGroup struct {
+	Title string
+	Vars  []EnvVar
+}
+
+// ServerEnvVars lists every environment variable the generated server reads.
+// The README and .env.example are both rendered from this list, so any new
+// os.getenv call emitted by the ToolBuilder must be registered here as well.
+var ServerEnvVars = []EnvGroup{
+	{
+		Title: "Server",
+		Vars: []EnvVar{
+			{Name: "MCP_TRANSPORT", Default: "stdio", Description: "MCP transport to use: stdio, sse or streamable-http"},
+			{Name: "MCP_HOST", Default: "127.0.0.1", Description: "Interface to listen on for network transports; 0.0.0.0 accepts remote clients"},
+			{Name: "PORT", Default: "8000", Description: "Port to run the MCP server on for network transports"},
+			{Name: "MCP_EVENT_HISTORY", Default: "10000", Description: "Messages of streamable-http sessions kept for clients resuming with Last-Event-ID (0 disables resumption)"},
+			{Name: "MCP_CORE_TOOLS", Description: "Comma-separated operation tools to register up front (default: all); the rest stay reachable through search_endpoints when meta tools are generated"},
+		},
+	},
+	{
+		Title: "Logging",
+		Vars: []EnvVar{
+			{Name: "LOG_LEVEL", Default: "INFO", Description: "Log level: DEBUG, INFO, WARNING or ERROR"},
+			{Name: "LOG_FORMAT", Default: "text", Description: "Log format: text or json"},
+			{Name: "LOG_REDACT_KEYS", Description: "Comma-separated extra query parameter and header names to mask in logs"},
+			{Name: "RESPONSE_REDACT", Description: "Comma-separated extra JSON fields masked in API responses, by name or dotted path such as customer.email"},
+		},
+	},
+	{
+		Title: "Service",
+		Vars: []EnvVar{
+			{Name: "SERVICE_URL", Default: "http://localhost:8080", Description: "Base URL of the service to proxy"},
+			{Name: "HEALTH_CHECK_PATH", Default: "/", Description: "Path probed by the check_api_health tool"},
+			{Name: "HTTP_MAX_CONNECTIONS", Default: "100", Description: "Maximum number of concurrent connections to the service"},
+			{Name: "HTTP_MAX_KEEPALIVE_CONNECTIONS", Default: "20", Description: "Maximum number of idle keep-alive connections"},
+			{Name: "HTTP_TIMEOUT", Default: "30", Description: "Timeout in seconds for requests to the service"},
+			{Name: "HTTP_CONNECT_TIMEOUT", Default: "10", Description: "Timeout in seconds for establishing a connection"},
+			{Name: "HTTP_RETRIES", Default: "2", Description: "Number of retries for connection errors and 429/502/503/504 responses"},
+			{Name: "HTTP_RETRY_BACKOFF", Default: "0.5", Description: "Base delay in seconds for exponential retry backoff"},
+			{Name: "HTTP_RETRY_MAX_DELAY", Default: "10", Description: "Maximum delay in seconds between retries"},
+			{Name: "HTTP_RETRY_UNSAFE", Default: "false", Description: "Also retry non-idempotent methods such as POST and PATCH"},
+			{Name: "HTTP_MAX_CONCURRENT_REQUESTS", Default: "0", Description: "Maximum number of simultaneous requests to the service (0 means unlimited)"},
+			{Name: "HTTP_COALESCE_GETS", Default: "false", Description: "Share one upstream call between identical GET requests that are in flight"},
+		},
+	},
+	{
+		Title: "Authentication",
+		Vars: []EnvVar{
+			{Name: "SERVICE_AUTH_HEADER", Description: "Raw Authorization header value, e.g. \"Bearer abc123\""},
+			{Name: "SERVICE_AUTH_BEARER_TOKEN", Description: "Token sent as \"Authorization: Bearer <token>\""},
+			{Name: "SERVICE_AUTH_BASIC_USERNAME", Description: "Username for HTTP basic authentication"},
+			{Name: "SERVICE_AUTH_BASIC_PASSWORD", Description: "Password for HTTP basic authentication"},
+			{Name: "SERVICE_AUTH_API_KEY", Description: "API key sent with every request"},
+			{Name: "SERVICE_AUTH_API_KEY_HEADER", Default: "X-API-Key", Description: "Header carrying the API key"},
+			{Name: "SERVICE_AUTH_API_KEY_QUERY", Description: "Send the API key as this query parameter instead of a header"},
+		},
+	},
+}
+
+// AggregatedAPIEnvGroup lists the variables configuring each API of a server
+// generated from several merged specs. serviceURLs maps API namespaces to the
+// base URL configured for them, if any.
+func AggregatedAPIEnvGroup(names []string, serviceURLs map[string]string) EnvGroup {
+	group := EnvGroup{Title: "Aggregated APIs"}
+	for _, name := range names {
+		suffix := strings.ToUpper(name)
+		group.Vars = append(group.Vars,
+			EnvVar{Name: "SERVICE_URL_" + suffix, Default: serviceURLs[name], Description: fmt.Sprintf("Base URL of the %s API (default: SERVICE_URL)", name)},
+			EnvVar{Name: "SERVICE_AUTH_HEADER_" + suffix, Description: fmt.Sprintf("Authorization header sent to the %s API instead of the SERVICE_AUTH_* credentials", name)},
+		)
+	}
+	return group
+}
+
+// GenerateEnvExample generates a .env.example file listing every supported
+// variable, followed by the variables of any extra groups
+func GenerateEnvExample(filePath string, extra ...EnvGroup) error {
+	var sb strings.Builder
+
+	sb.WriteString("# Environment configuration for the generated MCP server.\n")
+	sb.WriteString("# Copy this file to .env and adjust the values; it is loaded automatically on startup.\n")
+
+	for _, group := range append(ServerEnvVars, extra...) {
+		sb.WriteString(fmt.Sprintf("\n# --- %s ---\n", group.Title))
+		for _, v := range group.Vars {
+			sb.WriteString(fmt.Sprintf("\n# %s\n", v.Description))
+			// Variables without a default stay commented out so that an empty
+			// value doesn't override the server's own behaviour
+			if v.Default == "" {
+				sb.WriteString(fmt.Sprintf("# %s=\n", v.Name))
+			} else {
+				sb.WriteString(fmt.Sprintf("%s=%s\n", v.Name, v.Default))
+			}
+		}
+	}
+
+	return os.WriteFile(filePath, []byte(sb.String()), 0644)
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// requirementParts splits a PEP 508 requirement into its name, extras,
+// version specifier or URL, and environment marker
+var requirementParts = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[([^\]]*)\])?\s*([^;]*?)\s*(?:;\s*(.*?))?\s*$`)
+
+// poetrySetupSh is the Unix setup script of Poetry projects
+const poetrySetupSh = `#!/bin/bash
+# Setup script for MCP server
+
+# Check if Poetry is installed
+if ! command -v poetry &> /dev/null; then
+    echo "Poetry not found, please install it from https://python-poetry.org/docs/#installation"
+    exit 1
+fi
+
+# Create virtual environment and install dependencies
+cd "$(dirname "$0")/.."
+poetry install
+echo "Setup complete. Run 'source .venv/bin/activate' to activate the environment."
+`
+
+// poetrySetupBat is the Windows setup script of Poetry projects
+const poetrySetupBat = `@echo off
+REM Setup script for MCP server
+
+REM Check if Poetry is installed
+where poetry >nul 2>nul
+if %ERRORLEVEL% neq 0 (
+    echo Poetry not found, please install it from https://python-poetry.org/docs/#installation
+    exit /b 1
+)
+
+REM Create virtual environment and install dependencies
+cd %~dp0\..
+poetry install
+echo Setup complete. Run '.venv\Scripts\activate.bat' to activate the environment.
+`
+
+// generatePoetryPyprojectToml generates a pyproject.toml file for a project
+// built with Poetry
+func generatePoetryPyprojectToml(filePath string, doc *openapi3.T, meta ProjectMetadata, deps PythonDependencies) error {
+	projectName := SanitizeForPackageName(doc.Info.Title)
+
+	runtime, dev, err := deps.Resolve()
+	if err != nil {
+		return err
+	}
+	runtimeTable, err := poetryDependencies(runtime)
+	if err != nil {
+		return err
+	}
+	devTable, err := poetryDependencies(dev)
+	if err != nil {
+		return err
+	}
+
+	author := meta.Author
+	if meta.Email != "" {
+		author += fmt.Sprintf(" <%s>", meta.Email)
+	}
+
+	license := meta.License
+	if license == "" {
+		license = "MIT"
+	}
+
+	var urls strings.Builder
+	if meta.Homepage != "" {
+		urls.WriteString(fmt.Sprintf("homepage = %s\n", tomlString(meta.Homepage)))
+	}
+	if meta.BugTracker != "" {
+		urls.WriteString(fmt.Sprintf("\n[tool.poetry.urls]\n\"Bug Tracker\" = %s\n", tomlString(meta.BugTracker)))
+	}
+
+	content := fmt.Sprintf(`[build-system]
+requires = ["poetry-core>=1.0.0"]
+build-backend = "poetry.core.masonry.api"
+
+[tool.poetry]
+name = "%s"
+version = %s
+description = "Model Context Protocol (MCP) server generated from OpenAPI specs"
+authors = [%s]
+license = %s
+readme = "README.md"
+packages = [{include = "mcp_server.py", from = "src"}]
+%s
+[tool.poetry.dependencies]
+python = %s
+%s
+[tool.poetry.group.dev.dependencies]
+%s
+[tool.poetry.scripts]
+%s = "mcp_server:main"
+
+[tool.ruff]
+line-length = 100
+target-version = "%s"
+
+[tool.black]
+line-length = 100
+target-version = ["%s"]
+
+[tool.mypy]
+python_version = "%s"
+files = ["src"]
+mypy_path = "src"
+strict = true
+
+[tool.pytest.ini_options]
+testpaths = ["tests"]
+pythonpath = ["src"]
+`, projectName, tomlString(doc.Info.Version), tomlString(author), tomlString(license), urls.String(),
+		tomlString(deps.PythonVersion()), runtimeTable, devTable, ScriptName(doc.Info.Title), deps.TargetVersion(), deps.TargetVersion(), deps.MinimumPythonVersion())
+
+	return os.WriteFile(filePath, []byte(content), 0644)
+}
+
+// poetryDependencies renders PEP 508 requirements as the entries of a Poetry
+// dependency table
+func poetryDependencies(requirements []string) (string, error) {
+	var sb strings.Builder
+	for _, requirement := range requirements {
+		m := requirementParts.FindStringSubmatch(requirement)
+		if m == nil {
+			return "", fmt.Errorf("invalid requirement %q", requirement)
+		}
+		name, extras, spec, marker := m[1], m[2], m[3], m[4]
+
+		var fields []string
+		if url, ok := strings.CutPrefix(spec, "@"); ok {
+			fields = append(fields, "url = "+tomlString(strings.TrimSpace(url)))
+		} else {
+			if spec == "" {
+				spec = "*"
+			}
+			fields = append(fields, "version = "+tomlString(spec))
+		}
+		if extras != "" {
+			var quoted []string
+			for _, extra := range strings.Split(extras, ",") {
+				quoted = append(quoted, tomlString(strings.TrimSpace(extra)))
+			}
+			fields = append(fields, "extras = ["+strings.Join(quoted, ", ")+"]")
+		}
+		if marker != "" {
+			fields = append(fields, "markers = "+tomlString(marker))
+		}
+
+		// Plain version constraints use the short form
+		if len(fields) == 1 && strings.HasPrefix(fields[0], "version = ") {
+			sb.WriteString(fmt.Sprintf("%s = %s\n", name, strings.TrimPrefix(fields[0], "version = ")))
+		} else {
+			sb.WriteString(fmt.Sprintf("%s = {%s}\n", name, strings.Join(fields, ", ")))
+		}
+	}
+	return sb.String(), nil
+}
+
+// GeneratePoetryConfig writes the poetry.toml keeping the virtual environment
+// of the project in .venv, where the scripts expect it
+func GeneratePoetryConfig(filePath string) error {
+	content := `[virtualenvs]
+in-project = true
+`
+	return os.WriteFile(filePath, []byte(content), 0644)
+}
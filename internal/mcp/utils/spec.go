@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SpecDir is the directory of a generated project holding the OpenAPI
+// documents it was generated from
+const SpecDir = "spec"
+
+// EmbeddedSpecs lists the OpenAPI documents copied into a generated project,
+// relative to its directory with forward slashes
+type EmbeddedSpecs struct {
+	// Originals are the documents as served, in the order they were fetched
+	Originals []string
+	// Preprocessed are the same documents after preprocessing
+	Preprocessed []string
+	// Transformations is the log of the changes preprocessing made
+	Transformations string
+	// Normalized is the document the server was generated from, after
+	// preprocessing, merging and filtering
+	Normalized string
+	// Target is the kind of server generated, passed back to mcprox generate
+	Target string
+}
+
+// ReadmeSection returns the README section describing the embedded documents
+// and how to regenerate the server from them, or "" if there are none
+func (s EmbeddedSpecs) ReadmeSection() string {
+	if s.Normalized == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## OpenAPI Spec\n\n")
+	fmt.Fprintf(&sb, "The OpenAPI documents this server was generated from are kept in `%s/`:\n\n", SpecDir)
+	for _, original := range s.Originals {
+		fmt.Fprintf(&sb, "- `%s`: the document as served\n", original)
+	}
+	for _, preprocessed := range s.Preprocessed {
+		fmt.Fprintf(&sb, "- `%s`: the document after mcprox converted it to OpenAPI 3.0 and cleaned it up\n", preprocessed)
+	}
+	if s.Transformations != "" {
+		fmt.Fprintf(&sb, "- `%s`: every change that conversion made, by JSON pointer\n", s.Transformations)
+	}
+	fmt.Fprintf(&sb, "- `%s`: the document the tools were generated from, after that conversion and the\n", s.Normalized)
+	sb.WriteString("  configured merging and filtering\n\n")
+
+	sb.WriteString("To regenerate the server offline, e.g. with a newer mcprox, generate it from the embedded copy\n")
+	sb.WriteString("by running this in the project directory:\n\n")
+	sb.WriteString("```bash\n")
+	command := fmt.Sprintf("mcprox generate --url %s --output ..", s.Normalized)
+	if s.Target != "" {
+		command += " --target " + s.Target
+	}
+	sb.WriteString(command + "\n")
+	sb.WriteString("```\n\n")
+	return sb.String()
+}
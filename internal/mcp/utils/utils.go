@@ -1,28 +1,76 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"unicode"
 
+	"github.com/berkantay/mcprox/internal/openapi"
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
-// SanitizePathForToolID converts an OpenAPI path to a valid tool ID
-func SanitizePathForToolID(path, method string) string {
+// minToolIDLength is the shortest maximum tool ID length honored, leaving
+// room for a hash suffix after the method
+const minToolIDLength = 16
+
+// SanitizePathForToolID converts an OpenAPI path to a valid tool ID. IDs
+// longer than maxLength, typically tools.max_name_length, are cut short and
+// end in a hash of the method and path instead, so that they stay unique and
+// stable across runs. A maxLength of 0 or less leaves IDs as they are.
+func SanitizePathForToolID(path, method string, maxLength int) string {
 	// Replace path parameters with snake_case names
 	sanitized := strings.ReplaceAll(path, "{", "")
 	sanitized = strings.ReplaceAll(sanitized, "}", "")
 	sanitized = strings.ReplaceAll(sanitized, "/", "_")
 	sanitized = strings.ReplaceAll(sanitized, "-", "_")
 
+	// Replace any other character that isn't valid in an identifier
+	sanitized = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, sanitized)
+
 	// Remove leading underscore if present
 	sanitized = strings.TrimPrefix(sanitized, "_")
 
 	// Add method prefix with snake_case
-	return fmt.Sprintf("%s_%s", strings.ToLower(method), strings.ToLower(sanitized))
+	id := fmt.Sprintf("%s_%s", strings.ToLower(method), strings.ToLower(sanitized))
+	return shortenToolID(id, path, method, maxLength)
+}
+
+// shortenToolID cuts a tool ID longer than limit characters short, ending it
+// in the first 8 hex digits of the SHA-256 of the method and path it was
+// derived from. A limit of 0 or less leaves IDs as they are.
+func shortenToolID(id, path, method string, limit int) string {
+	if limit <= 0 {
+		return id
+	}
+	limit = max(limit, minToolIDLength)
+	runes := []rune(id)
+	if len(runes) <= limit {
+		return id
+	}
+	sum := sha256.Sum256([]byte(strings.ToUpper(method) + " " + path))
+	suffix := "_" + hex.EncodeToString(sum[:])[:8]
+	prefix := strings.TrimRight(string(runes[:limit-len(suffix)]), "_")
+	return prefix + suffix
+}
+
+// pythonKeywords are the reserved words that can't be used as Python identifiers
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true,
+	"def": true, "del": true, "elif": true, "else": true, "except": true, "finally": true,
+	"for": true, "from": true, "global": true, "if": true, "import": true, "in": true,
+	"is": true, "lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true,
+	"raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
 }
 
 // SanitizeParamName converts an OpenAPI parameter name to a valid Python variable name
@@ -36,44 +84,198 @@ func SanitizeParamName(name string) string {
 		}
 		return '_'
 	}, name)
+
+	// Identifiers can't be empty or start with a digit. A leading underscore is
+	// avoided because pydantic treats such fields as private
+	if name == "" {
+		return "param"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "param_" + name
+	}
+
+	// Escape reserved keywords with a trailing underscore (PEP 8 convention)
+	if pythonKeywords[name] {
+		name += "_"
+	}
 	return name
 }
 
-// SanitizeForPackageName sanitizes a string to be used as a package name
+// UniqueNames disambiguates duplicate names by appending deterministic numeric
+// suffixes to later occurrences, e.g. ["id", "id"] becomes ["id", "id_2"].
+// Names are compared case-insensitively and names in reserved count as taken.
+func UniqueNames(names []string, reserved ...string) []string {
+	taken := make(map[string]bool, len(names)+len(reserved))
+	for _, name := range reserved {
+		taken[strings.ToLower(name)] = true
+	}
+
+	unique := make([]string, len(names))
+	for i, name := range names {
+		candidate := name
+		for n := 2; taken[strings.ToLower(candidate)]; n++ {
+			candidate = fmt.Sprintf("%s_%d", name, n)
+		}
+		taken[strings.ToLower(candidate)] = true
+		unique[i] = candidate
+	}
+	return unique
+}
+
+// DefaultPackageName is used when a title has no characters that survive sanitization
+const DefaultPackageName = "api"
+
+// SanitizeForPackageName sanitizes a string to be used as a package name. The
+// result is always a non-empty, lowercase ASCII Python identifier.
 func SanitizeForPackageName(name string) string {
-	// Convert to lowercase and replace spaces with underscores
-	name = strings.ToLower(name)
-	name = strings.ReplaceAll(name, " ", "_")
+	// Fold to lowercase ASCII where possible
+	name = Transliterate(name)
 
-	// Replace invalid characters with underscores
-	name = strings.Map(func(r rune) rune {
-		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
-			return r
+	// Replace runs of invalid characters with a single underscore
+	var sb strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "_") {
+			sb.WriteRune('_')
 		}
-		return '_'
-	}, name)
+	}
+	name = strings.TrimSuffix(sb.String(), "_")
+
+	// Titles made only of symbols or unsupported scripts fall back to a default
+	if name == "" {
+		return DefaultPackageName
+	}
 
 	// Ensure it starts with a letter
-	if len(name) > 0 && !unicode.IsLetter(rune(name[0])) {
+	if !unicode.IsLetter(rune(name[0])) {
 		name = "mcp_" + name
 	}
 
 	return name
 }
 
-// GenerateRequirements writes the Python package requirements to a file
-func GenerateRequirements(filePath string) error {
-	requirements := `mcp-sdk>=0.1.0
-httpx>=0.25.0
+// ProjectFolderName returns the directory name for the project generated from an API title
+func ProjectFolderName(title string) string {
+	return SanitizeForPackageName(title) + "_mcp_server"
+}
+
+// ScriptName returns the name of the console command installed with a
+// generated project, e.g. "pet-store-mcp" for "Pet Store"
+func ScriptName(title string) string {
+	return strings.ReplaceAll(SanitizeForPackageName(title), "_", "-") + "-mcp"
+}
+
+// GenerateRequirements writes the runtime requirements of the project to
+// requirements.txt and the development ones to requirements-dev.txt
+func GenerateRequirements(outputDir string, deps PythonDependencies) error {
+	runtime, dev, err := deps.Resolve()
+	if err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("# Generated by mcprox; requires Python %s\n", deps.PythonVersion())
+	requirements := header + strings.Join(runtime, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(outputDir, "requirements.txt"), []byte(requirements), 0644); err != nil {
+		return fmt.Errorf("failed to generate requirements.txt: %w", err)
+	}
+
+	devRequirements := header + "-r requirements.txt\n" + strings.Join(dev, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(outputDir, "requirements-dev.txt"), []byte(devRequirements), 0644); err != nil {
+		return fmt.Errorf("failed to generate requirements-dev.txt: %w", err)
+	}
+	return nil
+}
+
+// GeneratePytestConfig writes the pytest configuration of projects without a
+// pyproject.toml to hold it
+func GeneratePytestConfig(filePath string) error {
+	content := `[pytest]
+testpaths = tests
+pythonpath = src
 `
-	return os.WriteFile(filePath, []byte(requirements), 0644)
+	return os.WriteFile(filePath, []byte(content), 0644)
+}
+
+// ProjectMetadata is the author, license and link information written to the
+// pyproject.toml of a generated project
+type ProjectMetadata struct {
+	Author     string
+	Email      string
+	Homepage   string
+	BugTracker string
+	License    string
+}
+
+// DefaultProjectAuthor is the author of generated projects when neither the
+// configuration nor the spec names one
+const DefaultProjectAuthor = "Generated by mcprox"
+
+// WithSpecDefaults fills the fields left empty from the contact and license
+// information of the spec, and the author from DefaultProjectAuthor
+func (m ProjectMetadata) WithSpecDefaults(doc *openapi3.T) ProjectMetadata {
+	if doc.Info != nil && doc.Info.Contact != nil {
+		contact := doc.Info.Contact
+		if m.Author == "" {
+			m.Author = contact.Name
+		}
+		if m.Email == "" {
+			m.Email = contact.Email
+		}
+		if m.Homepage == "" {
+			m.Homepage = contact.URL
+		}
+	}
+	if doc.Info != nil && doc.Info.License != nil && m.License == "" {
+		m.License = doc.Info.License.Name
+	}
+	if m.Author == "" {
+		m.Author = DefaultProjectAuthor
+	}
+	return m
 }
 
 // GeneratePyprojectToml generates a pyproject.toml file for the project
-func GeneratePyprojectToml(filePath string, doc *openapi3.T) error {
+func GeneratePyprojectToml(filePath string, doc *openapi3.T, meta ProjectMetadata, deps PythonDependencies) error {
+	if deps.UsesPoetry() {
+		return generatePoetryPyprojectToml(filePath, doc, meta, deps)
+	}
+
 	projectName := SanitizeForPackageName(doc.Info.Title)
-	if projectName == "" {
-		projectName = "mcp_server"
+
+	runtime, dev, err := deps.Resolve()
+	if err != nil {
+		return err
+	}
+
+	author := fmt.Sprintf("name = %s", tomlString(meta.Author))
+	if meta.Email != "" {
+		author += fmt.Sprintf(", email = %s", tomlString(meta.Email))
+	}
+
+	// The license classifier only applies to the default MIT license
+	license := `classifiers = [
+    "Programming Language :: Python :: 3",
+    "License :: OSI Approved :: MIT License",
+    "Operating System :: OS Independent",
+]`
+	if meta.License != "" {
+		license = fmt.Sprintf(`license = {text = %s}
+classifiers = [
+    "Programming Language :: Python :: 3",
+    "Operating System :: OS Independent",
+]`, tomlString(meta.License))
+	}
+
+	var urls strings.Builder
+	if meta.Homepage != "" || meta.BugTracker != "" {
+		urls.WriteString("\n[project.urls]\n")
+		if meta.Homepage != "" {
+			urls.WriteString(fmt.Sprintf("\"Homepage\" = %s\n", tomlString(meta.Homepage)))
+		}
+		if meta.BugTracker != "" {
+			urls.WriteString(fmt.Sprintf("\"Bug Tracker\" = %s\n", tomlString(meta.BugTracker)))
+		}
 	}
 
 	content := fmt.Sprintf(`[build-system]
@@ -82,49 +284,85 @@ build-backend = "setuptools.build_meta"
 
 [project]
 name = "%s"
-version = "%s"
+version = %s
 authors = [
-    {name = "Generated by mcprox", email = "berkantay.5@gmail.com"},
+    {%s},
 ]
 description = "Model Context Protocol (MCP) server generated from OpenAPI specs"
 readme = "README.md"
-requires-python = ">=3.11"
-classifiers = [
-    "Programming Language :: Python :: 3",
-    "License :: OSI Approved :: MIT License",
-    "Operating System :: OS Independent",
-]
+requires-python = %s
+%s
 dependencies = [
-    "mcp",
-    "httpx",
-]
+%s]
 
 [project.optional-dependencies]
 dev = [
-    "pytest",
-    "black",
-    "ruff",
-]
-
-[project.urls]
-"Homepage" = "https://github.com/berkantay/mcprox"
-"Bug Tracker" = "https://github.com/berkantay/mcprox/issues"
+%s]
 
+[project.scripts]
+%s = "mcp_server:main"
+%s
 [tool.setuptools]
 package-dir = {"" = "src"}
 
 [tool.ruff]
 line-length = 100
-target-version = "py311"
+target-version = "%s"
 
 [tool.black]
 line-length = 100
-target-version = ["py311"]
-`, projectName, doc.Info.Version)
+target-version = ["%s"]
+
+[tool.mypy]
+python_version = "%s"
+files = ["src"]
+mypy_path = "src"
+strict = true
+
+[tool.pytest.ini_options]
+testpaths = ["tests"]
+pythonpath = ["src"]
+`, projectName, tomlString(doc.Info.Version), author, tomlString(deps.PythonVersion()), license,
+		tomlList(runtime), tomlList(dev), ScriptName(doc.Info.Title), urls.String(), deps.TargetVersion(), deps.TargetVersion(), deps.MinimumPythonVersion())
 
 	return os.WriteFile(filePath, []byte(content), 0644)
 }
 
+// tomlList renders strings as the indented items of a multi-line TOML array
+func tomlList(items []string) string {
+	var sb strings.Builder
+	for _, item := range items {
+		sb.WriteString(fmt.Sprintf("    %s,\n", tomlString(item)))
+	}
+	return sb.String()
+}
+
+// tomlString quotes a string as a TOML basic string
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch {
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			sb.WriteString(fmt.Sprintf(`\u%04X`, r))
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
 // GenerateGitignore generates a .gitignore file for the project
 func GenerateGitignore(filePath string) error {
 	content := `# Python
@@ -172,8 +410,9 @@ Thumbs.db
 	return os.WriteFile(filePath, []byte(content), 0644)
 }
 
-// GenerateReadme generates a README.md file for the project
-func GenerateReadme(filePath string, doc *openapi3.T) error {
+// GenerateReadme generates a README.md file for the project, documenting the
+// variables of any extra groups after the standard ones
+func GenerateReadme(filePath string, doc *openapi3.T, deps PythonDependencies, specs EmbeddedSpecs, extra ...EnvGroup) error {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf("# %s MCP Server\n\n", doc.Info.Title))
@@ -183,7 +422,106 @@ func GenerateReadme(filePath string, doc *openapi3.T) error {
 	sb.WriteString(doc.Info.Description)
 	sb.WriteString("\n\n")
 
+	sb.WriteString("## Tools\n\n")
+	sb.WriteString("The server exposes one tool per API operation, plus a built-in `check_api_health` tool\n")
+	sb.WriteString("that reports whether the service is reachable and how long it takes to respond.\n\n")
+
 	sb.WriteString("## Installation\n\n")
+	sb.WriteString("With [mcprox](https://github.com/berkantay/mcprox) installed, a single command sets up the\n")
+	sb.WriteString("virtual environment, installs the dependencies and runs the server:\n\n")
+	sb.WriteString("```bash\n")
+	sb.WriteString("mcprox run .\n")
+	sb.WriteString("```\n\n")
+	sb.WriteString("To set the project up by hand instead, follow the steps below.\n\n")
+	if deps.UsesPoetry() {
+		writePoetryInstallSection(&sb)
+	} else {
+		writeUVInstallSection(&sb)
+	}
+	writePipInstallSection(&sb, deps)
+	if deps.Conda {
+		writeCondaInstallSection(&sb, SanitizeForPackageName(doc.Info.Title))
+	}
+
+	sb.WriteString("## Running the Server\n\n")
+	sb.WriteString("You can run the server using the provided script:\n\n")
+	sb.WriteString("```bash\n")
+	sb.WriteString("python scripts/run.py\n")
+	sb.WriteString("```\n\n")
+
+	sb.WriteString("Or directly:\n\n")
+	sb.WriteString("```bash\n")
+	sb.WriteString("python src/mcp_server.py\n")
+	sb.WriteString("```\n\n")
+
+	if deps.UsesPyproject() {
+		script := ScriptName(doc.Info.Title)
+		sb.WriteString(fmt.Sprintf("Installing the project also installs the server as the `%s` command,\n", script))
+		sb.WriteString("which is the easiest to reference from MCP client configurations:\n\n")
+		sb.WriteString("```json\n")
+		sb.WriteString("{\n")
+		sb.WriteString("  \"mcpServers\": {\n")
+		sb.WriteString(fmt.Sprintf("    %q: {\n", SanitizeForPackageName(doc.Info.Title)))
+		sb.WriteString(fmt.Sprintf("      \"command\": %q\n", script))
+		sb.WriteString("    }\n")
+		sb.WriteString("  }\n")
+		sb.WriteString("}\n")
+		sb.WriteString("```\n\n")
+		sb.WriteString("Use the absolute path of the command inside `.venv` if the client doesn't run in the virtual environment.\n\n")
+	}
+
+	sb.WriteString("The server uses the stdio transport by default, which is what most MCP clients expect.\n")
+	sb.WriteString("To expose it over the network instead, select a different transport:\n\n")
+	sb.WriteString("```bash\n")
+	sb.WriteString("python src/mcp_server.py --transport sse --port 8000\n")
+	sb.WriteString("python src/mcp_server.py --transport streamable-http --port 8000\n")
+	sb.WriteString("```\n\n")
+
+	sb.WriteString("## Running Tests\n\n")
+	sb.WriteString("Smoke tests checking that the server imports and registers every tool live in `tests/`:\n\n")
+	sb.WriteString("```bash\n")
+	switch {
+	case deps.UsesPoetry():
+		sb.WriteString("poetry install\n")
+		sb.WriteString("poetry run pytest\n")
+	case deps.UsesPyproject():
+		sb.WriteString("pip install -e \".[dev]\"\n")
+		sb.WriteString("pytest\n")
+	default:
+		sb.WriteString("pip install -r requirements-dev.txt\n")
+		sb.WriteString("pytest\n")
+	}
+	sb.WriteString("```\n\n")
+
+	sb.WriteString("## Configuration\n\n")
+	sb.WriteString("The server is configured through environment variables. A `.env` file in the project\n")
+	sb.WriteString("root is loaded automatically on startup; copy `.env.example` to get started:\n\n")
+	sb.WriteString("```bash\n")
+	sb.WriteString("cp .env.example .env\n")
+	sb.WriteString("```\n\n")
+
+	for _, group := range append(ServerEnvVars, extra...) {
+		sb.WriteString(fmt.Sprintf("### %s\n\n", group.Title))
+		for _, v := range group.Vars {
+			if v.Default != "" {
+				sb.WriteString(fmt.Sprintf("- `%s`: %s (default: %s)\n", v.Name, v.Description, v.Default))
+			} else {
+				sb.WriteString(fmt.Sprintf("- `%s`: %s\n", v.Name, v.Description))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(specs.ReadmeSection())
+
+	sb.WriteString("## License\n\n")
+	sb.WriteString("MIT\n")
+
+	return os.WriteFile(filePath, []byte(sb.String()), 0644)
+}
+
+// writeUVInstallSection writes the README instructions installing the project with uv
+func writeUVInstallSection(sb *strings.Builder) {
 	sb.WriteString("### Using uv (recommended)\n\n")
 	sb.WriteString("This project uses [uv](https://astral.sh/uv) for dependency management and virtual environments.\n\n")
 
@@ -209,7 +547,40 @@ func GenerateReadme(filePath string, doc *openapi3.T) error {
 	sb.WriteString("   # On Windows\n")
 	sb.WriteString("   .venv\\Scripts\\activate.bat\n")
 	sb.WriteString("   ```\n\n")
+}
+
+// writePoetryInstallSection writes the README instructions installing the
+// project with Poetry
+func writePoetryInstallSection(sb *strings.Builder) {
+	sb.WriteString("### Using Poetry (recommended)\n\n")
+	sb.WriteString("This project uses [Poetry](https://python-poetry.org) for dependency management and virtual environments.\n\n")
+
+	sb.WriteString("1. Install Poetry (if not already installed):\n")
+	sb.WriteString("   ```bash\n")
+	sb.WriteString("   pipx install poetry\n")
+	sb.WriteString("   ```\n\n")
+
+	sb.WriteString("2. Run the setup script, which installs the dependencies into `.venv`:\n")
+	sb.WriteString("   ```bash\n")
+	sb.WriteString("   # On Unix/Linux/MacOS\n")
+	sb.WriteString("   ./scripts/setup.sh\n")
+	sb.WriteString("   \n")
+	sb.WriteString("   # On Windows\n")
+	sb.WriteString("   scripts\\setup.bat\n")
+	sb.WriteString("   ```\n\n")
+
+	sb.WriteString("3. Activate the virtual environment:\n")
+	sb.WriteString("   ```bash\n")
+	sb.WriteString("   # On Unix/Linux/MacOS\n")
+	sb.WriteString("   source .venv/bin/activate\n")
+	sb.WriteString("   \n")
+	sb.WriteString("   # On Windows\n")
+	sb.WriteString("   .venv\\Scripts\\activate.bat\n")
+	sb.WriteString("   ```\n\n")
+}
 
+// writePipInstallSection writes the README instructions installing the project with pip
+func writePipInstallSection(sb *strings.Builder, deps PythonDependencies) {
 	sb.WriteString("### Using pip\n\n")
 	sb.WriteString("Alternatively, you can use pip:\n\n")
 
@@ -229,33 +600,44 @@ func GenerateReadme(filePath string, doc *openapi3.T) error {
 
 	sb.WriteString("3. Install dependencies:\n")
 	sb.WriteString("   ```bash\n")
-	sb.WriteString("   pip install -e .\n")
+	switch {
+	case deps.Locked && deps.UsesRequirements():
+		sb.WriteString("   pip install -r requirements.lock\n")
+	case deps.UsesPyproject():
+		sb.WriteString("   pip install -e .\n")
+	default:
+		sb.WriteString("   pip install -r requirements.txt\n")
+	}
 	sb.WriteString("   ```\n\n")
+}
 
-	sb.WriteString("## Running the Server\n\n")
-	sb.WriteString("You can run the server using the provided script:\n\n")
-	sb.WriteString("```bash\n")
-	sb.WriteString("python scripts/run.py\n")
-	sb.WriteString("```\n\n")
+// writeCondaInstallSection writes the README instructions installing the
+// project into a conda environment
+func writeCondaInstallSection(sb *strings.Builder, name string) {
+	sb.WriteString("### Using conda\n\n")
+	sb.WriteString("For managed conda installations, `environment.yml` creates an environment with\n")
+	sb.WriteString("Python from conda-forge and the server's dependencies from PyPI:\n\n")
 
-	sb.WriteString("Or directly:\n\n")
 	sb.WriteString("```bash\n")
-	sb.WriteString("python src/mcp_server.py\n")
+	sb.WriteString("conda env create -f environment.yml\n")
+	sb.WriteString(fmt.Sprintf("conda activate %s\n", name))
 	sb.WriteString("```\n\n")
-
-	sb.WriteString("## Configuration\n\n")
-	sb.WriteString("Set the following environment variables to configure the server:\n\n")
-	sb.WriteString("- `SERVICE_URL`: The base URL of the service to proxy (default: http://localhost:8080)\n")
-	sb.WriteString("- `PORT`: The port to run the MCP server on (default: 8000)\n\n")
-
-	sb.WriteString("## License\n\n")
-	sb.WriteString("MIT\n")
-
-	return os.WriteFile(filePath, []byte(sb.String()), 0644)
 }
 
 // GenerateSetupScripts generates setup scripts for the project
-func GenerateSetupScripts(outputDir string) error {
+func GenerateSetupScripts(outputDir string, deps PythonDependencies) error {
+	var install string
+	switch {
+	case deps.Locked && deps.UsesPyproject():
+		install = "uv sync --frozen"
+	case deps.Locked:
+		install = "uv pip install -r requirements.lock"
+	case deps.UsesPyproject():
+		install = "uv pip install -e ."
+	default:
+		install = "uv pip install -r requirements.txt"
+	}
+
 	// Generate setup.sh (for Unix-based systems)
 	setupShPath := filepath.Join(outputDir, "scripts", "setup.sh")
 	setupShContent := `#!/bin/bash
@@ -271,10 +653,12 @@ fi
 cd "$(dirname "$0")/.."
 uv venv
 source .venv/bin/activate
-uv add mcp[cli]
-uv sync
+` + install + `
 echo "Setup complete. Run 'source .venv/bin/activate' to activate the environment."
 `
+	if deps.UsesPoetry() {
+		setupShContent = poetrySetupSh
+	}
 	if err := os.WriteFile(setupShPath, []byte(setupShContent), 0755); err != nil {
 		return fmt.Errorf("failed to generate setup.sh: %w", err)
 	}
@@ -294,9 +678,12 @@ if %ERRORLEVEL% neq 0 (
 REM Create virtual environment and install dependencies
 cd %~dp0\..
 uv venv
-uv pip install -e .
+` + install + `
 echo Setup complete. Run '.venv\Scripts\activate.bat' to activate the environment.
 `
+	if deps.UsesPoetry() {
+		setupBatContent = poetrySetupBat
+	}
 	if err := os.WriteFile(setupBatPath, []byte(setupBatContent), 0644); err != nil {
 		return fmt.Errorf("failed to generate setup.bat: %w", err)
 	}
@@ -367,3 +754,74 @@ func GenerateInitFiles(outputDir string) error {
 
 	return nil
 }
+
+// GenerateTests generates pytest smoke tests for the generated server. Besides
+// one tool per operation, named with IDs of up to maxNameLength characters,
+// the server is expected to register builtinTools.
+func GenerateTests(outputDir string, doc *openapi3.T, maxNameLength int, builtinTools ...string) error {
+	// Collect the tool IDs the server is expected to register
+	toolIDs := append([]string{}, builtinTools...)
+	for _, op := range openapi.Operations(doc) {
+		toolIDs = append(toolIDs, SanitizePathForToolID(op.Path, op.Method, maxNameLength))
+	}
+	sort.Strings(toolIDs)
+
+	var expected strings.Builder
+	for _, id := range toolIDs {
+		expected.WriteString(fmt.Sprintf("    \"%s\",\n", id))
+	}
+
+	content := fmt.Sprintf(`"""Smoke tests for the generated MCP server."""
+import asyncio
+
+import httpx
+
+import mcp_server
+
+EXPECTED_TOOLS = [
+%s]
+
+
+def test_server_imports():
+    assert mcp_server.mcp is not None
+
+
+def test_main_entry_point():
+    assert callable(mcp_server.main)
+
+
+def test_tools_registered():
+    tools = asyncio.run(mcp_server.mcp.list_tools())
+    names = {tool.name for tool in tools}
+    missing = [name for name in EXPECTED_TOOLS if name not in names]
+    assert not missing, f"tools not registered: {missing}"
+
+
+def test_build_url_substitutes_path_parameters():
+    url = mcp_server.build_url("http://api.test", "/items/{id}", {"id": 7})
+    assert url.startswith("http://api.test/items/7")
+
+
+def test_redact_url_masks_secrets():
+    assert "secret" not in mcp_server.redact_url("http://api.test/items?api_key=secret")
+
+
+def test_send_request_retries_transient_errors(monkeypatch):
+    calls = []
+
+    def handler(request):
+        calls.append(request)
+        return httpx.Response(503 if len(calls) == 1 else 200, text="ok")
+
+    client = httpx.AsyncClient(transport=httpx.MockTransport(handler))
+    monkeypatch.setattr(mcp_server, "_http_client", client)
+    monkeypatch.setattr(mcp_server, "http_retries", 1)
+    monkeypatch.setattr(mcp_server, "http_retry_backoff", 0)
+
+    response = asyncio.run(mcp_server.send_request("GET", "http://api.test/items"))
+    assert response.status_code == 200
+    assert len(calls) == 2
+`, expected.String())
+
+	return os.WriteFile(filepath.Join(outputDir, "tests", "test_server.py"), []byte(content), 0644)
+}
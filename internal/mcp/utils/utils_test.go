@@ -0,0 +1,316 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestSanitizeParamName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"user-id", "user_id"},
+		{"X-Request-Id", "X_Request_Id"},
+		{"filter[name]", "filter_name_"},
+		{"from", "from_"},
+		{"class", "class_"},
+		{"None", "None_"},
+		{"1st", "param_1st"},
+		{"2fa-code", "param_2fa_code"},
+		{"", "param"},
+		{"match", "match"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeParamName(tt.name); got != tt.expected {
+			t.Errorf("SanitizeParamName(%q): expected '%s', got '%s'", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	got := UniqueNames([]string{"id", "X_Request_Id", "x_request_id", "id", "body", "id_2"}, "body")
+	expected := []string{"id", "X_Request_Id", "x_request_id_2", "id_2", "body_2", "id_2_2"}
+
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d names, got %d", len(expected), len(got))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected name %d to be '%s', got '%s'", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestSanitizePathForToolID(t *testing.T) {
+	tests := []struct {
+		path     string
+		method   string
+		expected string
+	}{
+		{"/users/{id}/posts", "GET", "get_users_id_posts"},
+		{"/store-items", "POST", "post_store_items"},
+		{"/files/{name}.json", "GET", "get_files_name_json"},
+		{"/v1/search~fuzzy", "GET", "get_v1_search_fuzzy"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizePathForToolID(tt.path, tt.method, 0); got != tt.expected {
+			t.Errorf("SanitizePathForToolID(%q, %q): expected '%s', got '%s'", tt.path, tt.method, tt.expected, got)
+		}
+	}
+}
+
+func TestShortenToolID(t *testing.T) {
+	path := "/organizations/{orgId}/projects/{projectId}/environments/{envId}"
+	id := SanitizePathForToolID(path, "GET", 32)
+	if len(id) != 32 || !strings.HasPrefix(id, "get_organizations_orgid_") {
+		t.Errorf("SanitizePathForToolID() = %s, want it shortened to 32 characters", id)
+	}
+	if again := SanitizePathForToolID(path, "GET", 32); again != id {
+		t.Errorf("SanitizePathForToolID() = %s, then %s, want it stable", id, again)
+	}
+
+	// Paths sharing the kept prefix still get distinct IDs
+	other := SanitizePathForToolID("/organizations/{orgId}/projects/{projectId}/environments/{envId}/secrets", "GET", 32)
+	if other == id || len(other) != 32 {
+		t.Errorf("SanitizePathForToolID() = %s for both paths, want distinct IDs", id)
+	}
+
+	// Limits below the minimum still leave room for the hash
+	if got := SanitizePathForToolID(path, "GET", 4); len(got) != minToolIDLength {
+		t.Errorf("SanitizePathForToolID() = %s, want it shortened to %d characters", got, minToolIDLength)
+	}
+
+	// Short IDs are left alone, and so is everything without a limit
+	if got := SanitizePathForToolID("/users", "GET", 32); got != "get_users" {
+		t.Errorf("SanitizePathForToolID() = %s, want get_users", got)
+	}
+	if got := SanitizePathForToolID(path, "GET", 0); got != "get_organizations_orgid_projects_projectid_environments_envid" {
+		t.Errorf("SanitizePathForToolID() = %s, want it unshortened", got)
+	}
+}
+
+func TestSanitizeForPackageName(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{"Pet Store", "pet_store"},
+		{"My-API v2.0", "my_api_v2_0"},
+		{"Café Ürün API", "cafe_urun_api"},
+		{"Straße", "strasse"},
+		{"Погода", "pogoda"},
+		{"日本語", DefaultPackageName},
+		{"*** !!!", DefaultPackageName},
+		{"", DefaultPackageName},
+		{"123 API", "mcp_123_api"},
+		{"../../etc", "etc"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeForPackageName(tt.title); got != tt.expected {
+			t.Errorf("SanitizeForPackageName(%q): expected '%s', got '%s'", tt.title, tt.expected, got)
+		}
+	}
+}
+
+func TestScriptName(t *testing.T) {
+	tests := map[string]string{
+		"Pet Store":        "pet-store-mcp",
+		"Swagger Petstore": "swagger-petstore-mcp",
+		"my_api v2":        "my-api-v2-mcp",
+	}
+	for title, want := range tests {
+		if got := ScriptName(title); got != want {
+			t.Errorf("ScriptName(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestProjectMetadataWithSpecDefaults(t *testing.T) {
+	doc := &openapi3.T{Info: &openapi3.Info{
+		Contact: &openapi3.Contact{Name: "API Team", Email: "team@example.com", URL: "https://example.com"},
+		License: &openapi3.License{Name: "Apache 2.0"},
+	}}
+
+	got := ProjectMetadata{Author: "Platform"}.WithSpecDefaults(doc)
+	expected := ProjectMetadata{Author: "Platform", Email: "team@example.com", Homepage: "https://example.com", License: "Apache 2.0"}
+	if got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	got = ProjectMetadata{}.WithSpecDefaults(&openapi3.T{Info: &openapi3.Info{}})
+	if got != (ProjectMetadata{Author: DefaultProjectAuthor}) {
+		t.Errorf("Expected only the default author, got %+v", got)
+	}
+}
+
+func TestTomlString(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\path`, `"C:\\path"`},
+		{"two\nlines", `"two\nlines"`},
+		{"bell\a", `"bell\u0007"`},
+	}
+
+	for _, tt := range tests {
+		if got := tomlString(tt.value); got != tt.expected {
+			t.Errorf("tomlString(%q): expected %s, got %s", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestPythonDependenciesResolve(t *testing.T) {
+	deps := PythonDependencies{
+		Pins:  []string{"mcp==1.6.0", "Python_Dotenv==1.0.1", "pytest>=8"},
+		Extra: []string{"sentry-sdk==2.0"},
+	}
+	runtime, dev, err := deps.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	wantRuntime := []string{"mcp==1.6.0", "httpx", "Python_Dotenv==1.0.1", "sentry-sdk==2.0"}
+	wantDev := []string{"pytest>=8", "black", "ruff", "mypy"}
+	if strings.Join(runtime, " ") != strings.Join(wantRuntime, " ") {
+		t.Errorf("runtime = %v, want %v", runtime, wantRuntime)
+	}
+	if strings.Join(dev, " ") != strings.Join(wantDev, " ") {
+		t.Errorf("dev = %v, want %v", dev, wantDev)
+	}
+
+	for _, pins := range [][]string{{"mcp"}, {"requests==2.32.0"}, {"==1.0"}} {
+		if _, _, err := (PythonDependencies{Pins: pins}).Resolve(); err == nil {
+			t.Errorf("Resolve() with pins %v succeeded, want error", pins)
+		}
+	}
+}
+
+func TestPythonDependenciesSDKVersion(t *testing.T) {
+	runtime, _, err := (PythonDependencies{SDKVersion: "1.9.4"}).Resolve()
+	if err != nil || runtime[0] != "mcp==1.9.4" {
+		t.Errorf("Resolve() = %v, %v, want mcp==1.9.4", runtime, err)
+	}
+	// Pins take precedence
+	if runtime, _, _ := (PythonDependencies{SDKVersion: "1.9.4", Pins: []string{"mcp~=1.9"}}).Resolve(); runtime[0] != "mcp~=1.9" {
+		t.Errorf("Resolve() with a pin = %v", runtime)
+	}
+
+	for _, version := range []string{"", "1.3.0", "1.10.0"} {
+		if err := (PythonDependencies{SDKVersion: version}).Validate(); err != nil {
+			t.Errorf("Validate() with SDK %q error = %v", version, err)
+		}
+	}
+	for _, version := range []string{"1.9", "v1.9.4", "1.2.1", "0.9.0"} {
+		if err := (PythonDependencies{SDKVersion: version}).Validate(); err == nil {
+			t.Errorf("Validate() with SDK %q succeeded, want error", version)
+		}
+	}
+
+	tests := []struct {
+		version, minimum string
+		want             bool
+	}{
+		{"", StreamableHTTPSDKVersion, true},
+		{"1.8.0", StreamableHTTPSDKVersion, true},
+		{"1.10.0", StreamableHTTPSDKVersion, true},
+		{"1.7.1", StreamableHTTPSDKVersion, false},
+	}
+	for _, tt := range tests {
+		if got := SDKAtLeast(tt.version, tt.minimum); got != tt.want {
+			t.Errorf("SDKAtLeast(%q, %q) = %v, want %v", tt.version, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+func TestPythonDependenciesTargetVersion(t *testing.T) {
+	tests := map[string]string{
+		"":             "py311",
+		">=3.12":       "py312",
+		">=3.10,<3.13": "py310",
+		"~=3.9":        "py39",
+	}
+	for requires, want := range tests {
+		if got := (PythonDependencies{RequiresPython: requires}).TargetVersion(); got != want {
+			t.Errorf("TargetVersion(%q) = %q, want %q", requires, got, want)
+		}
+	}
+}
+
+func TestGenerateRequirements(t *testing.T) {
+	dir := t.TempDir()
+	deps := PythonDependencies{Format: DepsRequirements, Pins: []string{"httpx==0.28.1"}}
+	if err := GenerateRequirements(dir, deps); err != nil {
+		t.Fatalf("GenerateRequirements() error = %v", err)
+	}
+
+	runtime, err := os.ReadFile(filepath.Join(dir, "requirements.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"mcp\n", "httpx==0.28.1\n", "python-dotenv\n"} {
+		if !strings.Contains(string(runtime), want) {
+			t.Errorf("requirements.txt = %q, want it to contain %q", runtime, want)
+		}
+	}
+	if strings.Contains(string(runtime), "mcp-sdk") {
+		t.Errorf("requirements.txt = %q, references the nonexistent mcp-sdk package", runtime)
+	}
+
+	dev, err := os.ReadFile(filepath.Join(dir, "requirements-dev.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(dev), "-r requirements.txt\n") || !strings.Contains(string(dev), "pytest\n") {
+		t.Errorf("requirements-dev.txt = %q, want it to include requirements.txt and pytest", dev)
+	}
+}
+
+func TestPoetryDependencies(t *testing.T) {
+	got, err := poetryDependencies([]string{
+		"httpx",
+		"mcp[cli]==1.6.0",
+		"uvloop>=0.19; sys_platform != 'win32'",
+		"internal-client @ https://example.com/internal_client-1.0.tar.gz",
+	})
+	if err != nil {
+		t.Fatalf("poetryDependencies() error = %v", err)
+	}
+
+	want := `httpx = "*"
+mcp = {version = "==1.6.0", extras = ["cli"]}
+uvloop = {version = ">=0.19", markers = "sys_platform != 'win32'"}
+internal-client = {url = "https://example.com/internal_client-1.0.tar.gz"}
+`
+	if got != want {
+		t.Errorf("poetryDependencies() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerateCondaEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "environment.yml")
+	doc := &openapi3.T{Info: &openapi3.Info{Title: "Pet Store"}}
+	deps := PythonDependencies{RequiresPython: "~=3.12", Pins: []string{"mcp==1.6.0"}}
+	if err := GenerateCondaEnvironment(path, doc, deps); err != nil {
+		t.Fatalf("GenerateCondaEnvironment() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"name: pet_store\n", "  - \"python>=3.12\"\n", "      - \"mcp==1.6.0\"\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("environment.yml = %q, want it to contain %q", data, want)
+		}
+	}
+}
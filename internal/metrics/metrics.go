@@ -0,0 +1,134 @@
+// Package metrics counts serve mode's tool calls, their errors and latency by
+// tool, and exposes them in the Prometheus text format, so API owners can see
+// which operations models actually use
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/history"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Path is where the metrics are served
+const Path = "/metrics"
+
+// latencyBuckets are the upper bounds in seconds of the latency histogram
+var latencyBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics counts tool calls, safe for concurrent use
+type Metrics struct {
+	mu    sync.Mutex
+	tools map[string]*toolMetrics
+}
+
+// toolMetrics are the counts of a tool
+type toolMetrics struct {
+	// calls by status, see history.StatusOK
+	calls map[string]uint64
+	// buckets count the calls up to each of latencyBuckets
+	buckets []uint64
+	seconds float64
+	count   uint64
+}
+
+// New returns metrics without any calls counted
+func New() *Metrics {
+	return &Metrics{tools: make(map[string]*toolMetrics)}
+}
+
+// Observe counts a call of a tool that ended with status after a latency
+func (m *Metrics) Observe(tool, status string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tools[tool]
+	if !ok {
+		t = &toolMetrics{calls: make(map[string]uint64), buckets: make([]uint64, len(latencyBuckets))}
+		m.tools[tool] = t
+	}
+	t.calls[status]++
+	seconds := latency.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			t.buckets[i]++
+		}
+	}
+	t.seconds += seconds
+	t.count++
+}
+
+// Wrap returns a handler counting each call of next
+func (m *Metrics) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+		status := history.StatusOK
+		switch {
+		case err != nil:
+			status = history.StatusError
+		case result != nil && result.IsError:
+			status = history.StatusToolError
+		}
+		m.Observe(request.Params.Name, status, time.Since(start))
+		return result, err
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, m.String())
+}
+
+// String returns the metrics in the Prometheus text format
+func (m *Metrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.tools))
+	for name := range m.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("# HELP mcprox_tool_calls_total Tool calls by tool and status: ok, tool_error or error.\n")
+	b.WriteString("# TYPE mcprox_tool_calls_total counter\n")
+	for _, name := range names {
+		t := m.tools[name]
+		statuses := make([]string, 0, len(t.calls))
+		for status := range t.calls {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "mcprox_tool_calls_total{tool=%s,status=%s} %d\n", quote(name), quote(status), t.calls[status])
+		}
+	}
+
+	b.WriteString("# HELP mcprox_tool_call_duration_seconds Latency of tool calls by tool.\n")
+	b.WriteString("# TYPE mcprox_tool_call_duration_seconds histogram\n")
+	for _, name := range names {
+		t := m.tools[name]
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(&b, "mcprox_tool_call_duration_seconds_bucket{tool=%s,le=%q} %d\n", quote(name), strconv.FormatFloat(bound, 'g', -1, 64), t.buckets[i])
+		}
+		fmt.Fprintf(&b, "mcprox_tool_call_duration_seconds_bucket{tool=%s,le=\"+Inf\"} %d\n", quote(name), t.count)
+		fmt.Fprintf(&b, "mcprox_tool_call_duration_seconds_sum{tool=%s} %s\n", quote(name), strconv.FormatFloat(t.seconds, 'g', -1, 64))
+		fmt.Fprintf(&b, "mcprox_tool_call_duration_seconds_count{tool=%s} %d\n", quote(name), t.count)
+	}
+	return b.String()
+}
+
+// quote quotes a label value, escaping as the text format requires
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestWrap(t *testing.T) {
+	m := New()
+	handler := m.Wrap(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		switch request.Params.Name {
+		case "delete_pet":
+			return nil, errors.New("upstream unavailable")
+		case "post_pet":
+			result := mcp.NewToolResultText("invalid pet")
+			result.IsError = true
+			return result, nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+	for _, tool := range []string{"get_pets", "get_pets", "post_pet", "delete_pet"} {
+		var request mcp.CallToolRequest
+		request.Params.Name = tool
+		handler(context.Background(), request)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", Path, nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`mcprox_tool_calls_total{tool="get_pets",status="ok"} 2`,
+		`mcprox_tool_calls_total{tool="post_pet",status="tool_error"} 1`,
+		`mcprox_tool_calls_total{tool="delete_pet",status="error"} 1`,
+		`mcprox_tool_call_duration_seconds_count{tool="get_pets"} 2`,
+		`mcprox_tool_call_duration_seconds_bucket{tool="get_pets",le="+Inf"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestObserveBuckets(t *testing.T) {
+	m := New()
+	m.Observe("get_pets", "ok", 200*time.Millisecond)
+	m.Observe("get_pets", "ok", 3*time.Second)
+
+	body := m.String()
+	for _, want := range []string{
+		`mcprox_tool_call_duration_seconds_bucket{tool="get_pets",le="0.1"} 0`,
+		`mcprox_tool_call_duration_seconds_bucket{tool="get_pets",le="0.25"} 1`,
+		`mcprox_tool_call_duration_seconds_bucket{tool="get_pets",le="5"} 2`,
+		`mcprox_tool_call_duration_seconds_sum{tool="get_pets"} 3.2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("quote() = %s", got)
+	}
+}
@@ -0,0 +1,228 @@
+// Package offload keeps oversized tool responses out of the model's context:
+// a response over a size threshold is stored, in memory or in a directory, and
+// returned as the URI of an MCP resource with a summary, which the client reads
+// when it needs the whole response
+package offload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// URIPrefix is the prefix of the URIs of stored responses
+const URIPrefix = "mcprox://responses/"
+
+// previewSize is how many bytes of a stored response its result shows
+const previewSize = 500
+
+// Store holds the responses offloaded from tool results until they expire
+type Store struct {
+	// Threshold is the size in bytes above which responses are offloaded,
+	// none if 0
+	Threshold int
+	// Dir is the directory responses are written to, memory if empty
+	Dir string
+	// TTL is how long a response can be read after it was stored
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+// entry is a stored response, whose data is in its file if Dir is set
+type entry struct {
+	mimeType string
+	data     []byte
+	expires  time.Time
+}
+
+// New returns a store offloading responses larger than threshold bytes to
+// dir, or to memory if dir is empty, for ttl
+func New(threshold int, dir string, ttl time.Duration) *Store {
+	return &Store{Threshold: threshold, Dir: dir, TTL: ttl, entries: make(map[string]*entry), now: time.Now}
+}
+
+// FromConfig returns the store configured under offload. It offloads no
+// responses if offload.threshold is 0, but can still store other payloads,
+// e.g. downloaded files.
+func FromConfig() (*Store, error) {
+	dir := config.GetString("offload.dir")
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("invalid offload directory: %w", err)
+		}
+	}
+	return New(max(config.GetInt("offload.threshold"), 0), dir, time.Duration(config.GetInt("offload.ttl"))*time.Second), nil
+}
+
+// Oversized reports whether a response is offloaded
+func (s *Store) Oversized(body []byte) bool {
+	return s.Threshold > 0 && len(body) > s.Threshold
+}
+
+// Put stores a response and returns the URI it is read at
+func (s *Store) Put(mimeType string, body []byte) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b[:])
+
+	e := &entry{mimeType: mimeType, expires: s.now().Add(s.TTL)}
+	if s.Dir == "" {
+		e.data = body
+	} else if err := os.WriteFile(filepath.Join(s.Dir, id), body, 0o600); err != nil {
+		return "", fmt.Errorf("failed to store response: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	s.entries[id] = e
+	return URIPrefix + id, nil
+}
+
+// Get returns a stored response and its MIME type
+func (s *Store) Get(uri string) ([]byte, string, error) {
+	id := strings.TrimPrefix(uri, URIPrefix)
+	s.mu.Lock()
+	s.expire()
+	e, ok := s.entries[id]
+	s.mu.Unlock()
+	if !ok || id == uri {
+		return nil, "", fmt.Errorf("no stored response %s; it may have expired", uri)
+	}
+	if s.Dir == "" {
+		return e.data, e.mimeType, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.Dir, id))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read stored response: %w", err)
+	}
+	return data, e.mimeType, nil
+}
+
+// Result stores a response and returns the tool result pointing to it, with
+// a summary and the start of the response
+func (s *Store) Result(mimeType string, body []byte) (*mcp.CallToolResult, error) {
+	uri, err := s.Put(mimeType, body)
+	if err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf("The response, %d bytes, is too large to return here. It is stored as the resource %s until %s; read the resource for the whole response.\n%s",
+		len(body), uri, s.now().Add(s.TTL).UTC().Format(time.RFC3339), Summarize(body))
+	if preview := Preview(body); preview != "" && utf8.Valid(body) {
+		text += "\n\nStart of the response:\n" + preview
+	}
+	return mcp.NewToolResultText(text), nil
+}
+
+// ResourceTemplate returns the template of the URIs of stored responses
+func (s *Store) ResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(URIPrefix+"{id}", "Stored tool responses",
+		mcp.WithTemplateDescription("Responses too large to return in a tool result, by the URI the result gives"))
+}
+
+// ReadResource reads a stored response, as text unless it is binary
+func (s *Store) ReadResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := request.Params.URI
+	data, mimeType, err := s.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return []mcp.ResourceContents{mcp.BlobResourceContents{URI: uri, MIMEType: mimeType, Blob: base64.StdEncoding.EncodeToString(data)}}, nil
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: mimeType, Text: string(data)}}, nil
+}
+
+// expire forgets the responses past their TTL, removing their files
+func (s *Store) expire() {
+	now := s.now()
+	for id, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, id)
+			if s.Dir != "" {
+				os.Remove(filepath.Join(s.Dir, id))
+			}
+		}
+	}
+}
+
+// Summarize describes the shape of a response: the length of a JSON array
+// and the fields of its first element, or the fields of a JSON object and the
+// length of its arrays
+func Summarize(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "It isn't JSON."
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		summary := fmt.Sprintf("It is a JSON array of %d items", len(v))
+		if len(v) > 0 {
+			if first, ok := v[0].(map[string]interface{}); ok {
+				summary += ", the first with the fields " + fieldList(first)
+			}
+		}
+		return summary + "."
+	case map[string]interface{}:
+		summary := "It is a JSON object with the fields " + fieldList(v)
+		var arrays []string
+		for _, name := range sortedKeys(v) {
+			if items, ok := v[name].([]interface{}); ok {
+				arrays = append(arrays, fmt.Sprintf("%s has %d items", name, len(items)))
+			}
+		}
+		if len(arrays) > 0 {
+			summary += "; " + strings.Join(arrays, ", ")
+		}
+		return summary + "."
+	}
+	return "It is a single JSON value."
+}
+
+// Preview returns the start of a response, cut at a character boundary
+func Preview(body []byte) string {
+	if len(body) <= previewSize {
+		return string(body)
+	}
+	end := previewSize
+	for end > 0 && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return string(body[:end]) + "..."
+}
+
+// fieldList lists the fields of an object, at most 20
+func fieldList(object map[string]interface{}) string {
+	names := sortedKeys(object)
+	if len(names) > 20 {
+		return fmt.Sprintf("%s and %d more", strings.Join(names[:20], ", "), len(names)-20)
+	}
+	return strings.Join(names, ", ")
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
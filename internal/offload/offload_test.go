@@ -0,0 +1,90 @@
+package offload
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestStore(t *testing.T) {
+	for name, dir := range map[string]string{"memory": "", "directory": t.TempDir()} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			s := New(10, dir, time.Minute)
+			s.now = func() time.Time { return now }
+
+			uri, err := s.Put("application/json", []byte(`{"pets": []}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(uri, URIPrefix) {
+				t.Errorf("URI = %s, want it under %s", uri, URIPrefix)
+			}
+			data, mimeType, err := s.Get(uri)
+			if err != nil || string(data) != `{"pets": []}` || mimeType != "application/json" {
+				t.Errorf("Get() = %s, %s, %v", data, mimeType, err)
+			}
+
+			now = now.Add(2 * time.Minute)
+			if _, _, err := s.Get(uri); err == nil {
+				t.Error("expected the response to expire")
+			}
+			if dir != "" {
+				if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+					t.Errorf("files left after expiry: %v", entries)
+				}
+			}
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	s := New(10, "", time.Hour)
+	body := `{"total": 2, "pets": [{"id": 1, "name": "Rex"}, {"id": 2, "name": "Tom"}]}`
+	result, err := s.Result("application/json", []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	for _, want := range []string{URIPrefix, "It is a JSON object with the fields pets, total; pets has 2 items.", body} {
+		if !strings.Contains(text, want) {
+			t.Errorf("result %q doesn't contain %q", text, want)
+		}
+	}
+
+	uri := text[strings.Index(text, URIPrefix):]
+	uri = uri[:strings.IndexByte(uri, ' ')]
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	contents, err := s.ReadResource(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contents[0].(mcp.TextResourceContents); got.Text != body || got.URI != uri {
+		t.Errorf("resource = %+v, want the response", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	for body, want := range map[string]string{
+		`[{"id": 1, "name": "Rex"}, {"id": 2}]`: "It is a JSON array of 2 items, the first with the fields id, name.",
+		`{"id": 1}`:                             "It is a JSON object with the fields id.",
+		`"text"`:                                "It is a single JSON value.",
+		`<html></html>`:                         "It isn't JSON.",
+	} {
+		if got := Summarize([]byte(body)); got != want {
+			t.Errorf("Summarize(%s) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	body := strings.Repeat("a", previewSize-1) + "é"
+	if got := Preview([]byte(body)); got != strings.Repeat("a", previewSize-1)+"..." {
+		t.Errorf("Preview() cut a character: %q", got[len(got)-5:])
+	}
+}
@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// ErrAuthWall is returned for spec endpoints answering with an authentication
+// error, or a login page, instead of the OpenAPI document
+var ErrAuthWall = errors.New("spec endpoint requires authentication")
+
+// ErrMalformedSpec is returned for documents that aren't JSON objects
+var ErrMalformedSpec = errors.New("malformed OpenAPI document")
+
+// cookieJar returns a cookie jar holding the configured spec cookies for the
+// spec URL and the cookies of the configured cookie file. Cookies the spec
+// server sets while redirecting are kept in it too, as browsers do.
+func cookieJar(specURL *url.URL) (*cookiejar.Jar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []*http.Cookie
+	for _, cookie := range config.GetStringSlice("spec.cookies") {
+		name, value, found := strings.Cut(cookie, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid spec cookie %q: expected name=value", cookie)
+		}
+		cookies = append(cookies, &http.Cookie{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value), Path: "/"})
+	}
+	jar.SetCookies(specURL, cookies)
+
+	if file := config.GetString("spec.cookie_file"); file != "" {
+		if err := readCookieFile(jar, file); err != nil {
+			return nil, fmt.Errorf("failed to read cookie file %s: %w", file, err)
+		}
+	}
+	return jar, nil
+}
+
+// readCookieFile adds the cookies of a file in the Netscape format written by
+// curl -c and browser extensions exporting cookies.txt to a jar, skipping
+// expired ones
+func readCookieFile(jar *cookiejar.Jar, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line, httpOnly = rest, true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("line %d: expected 7 tab-separated fields", n)
+		}
+		domain, subdomains, path, secure, expires, name, value := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+		expiry, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid expiry %q", n, expires)
+		}
+
+		cookie := &http.Cookie{Name: name, Value: value, Path: path, Secure: secure == "TRUE", HttpOnly: httpOnly}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+			if cookie.Expires.Before(time.Now()) {
+				continue
+			}
+		}
+		host := strings.TrimPrefix(domain, ".")
+		if subdomains == "TRUE" {
+			cookie.Domain = host
+		}
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: path}, []*http.Cookie{cookie})
+	}
+	return scanner.Err()
+}
+
+// checkResponse tells the responses of auth walls apart from OpenAPI
+// documents: authentication errors, redirects that weren't followed, and HTML
+// pages such as login forms or Swagger UI
+func checkResponse(resp *http.Response, specURL string, body []byte) error {
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusProxyAuthRequired:
+		challenge := ""
+		if scheme := resp.Header.Get("WWW-Authenticate"); scheme != "" {
+			challenge = " asking for " + scheme
+		}
+		return fmt.Errorf("%w: received %s%s; send credentials with spec.headers, spec.cookies or spec.cookie_file", ErrAuthWall, resp.Status, challenge)
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		return fmt.Errorf("received %s redirecting to %s, which spec.max_redirects doesn't allow following", resp.Status, resp.Header.Get("Location"))
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("received non-OK response: %s", resp.Status)
+	}
+
+	if !isHTML(resp, body) {
+		return nil
+	}
+	final := resp.Request.URL.String()
+	lower := bytes.ToLower(body)
+	if final != specURL || bytes.Contains(lower, []byte(`type="password"`)) || bytes.Contains(lower, []byte(`type='password'`)) {
+		return fmt.Errorf("%w: received a login page at %s instead of the OpenAPI document; send the session cookie with spec.cookies or spec.cookie_file", ErrAuthWall, final)
+	}
+	if bytes.Contains(lower, []byte("swagger-ui")) || bytes.Contains(lower, []byte("redoc")) {
+		return fmt.Errorf("%w: received a documentation page instead of the OpenAPI document; use the URL of the JSON document the page loads", ErrMalformedSpec)
+	}
+	return fmt.Errorf("%w: received an HTML page instead of the OpenAPI document", ErrMalformedSpec)
+}
+
+// isHTML reports whether a response is an HTML page
+func isHTML(resp *http.Response, body []byte) bool {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return true
+	}
+	start := bytes.ToLower(bytes.TrimSpace(body))
+	return bytes.HasPrefix(start, []byte("<!doctype html")) || bytes.HasPrefix(start, []byte("<html"))
+}
+
+// decode returns a document as UTF-8. Gzip-compressed documents served
+// without a Content-Encoding, such as .json.gz files, are decompressed, and
+// documents in other encodings are converted, as told by their byte order
+// mark or else the charset of their Content-Type.
+func decode(body []byte, contentType string) ([]byte, error) {
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip document: %w", err)
+		}
+		if body, err = io.ReadAll(gz); err != nil {
+			return nil, fmt.Errorf("invalid gzip document: %w", err)
+		}
+	}
+
+	var enc encoding.Encoding
+	switch {
+	case bytes.HasPrefix(body, []byte{0xef, 0xbb, 0xbf}):
+		return body[3:], nil
+	case bytes.HasPrefix(body, []byte{0xff, 0xfe}):
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(body, []byte{0xfe, 0xff}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	default:
+		_, params, err := mime.ParseMediaType(contentType)
+		label := params["charset"]
+		if err != nil || label == "" {
+			return body, nil
+		}
+		if enc, err = htmlindex.Get(label); err != nil {
+			return nil, fmt.Errorf("unsupported charset %q", label)
+		}
+		if name, _ := htmlindex.Name(enc); name == "utf-8" {
+			return body, nil
+		}
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document encoding: %w", err)
+	}
+	return decoded, nil
+}
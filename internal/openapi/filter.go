@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"path"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// FilterOperations removes the operations of a document not selected by the
+// filters, dropping paths left without operations. Path patterns are globs as
+// understood by path.Match, where a trailing "/**" also matches every path
+// below. It returns the number of operations removed.
+func FilterOperations(doc *openapi3.T, filters config.Filters) int {
+	removed := 0
+	kept := openapi3.NewPaths()
+	kept.Extensions = doc.Paths.Extensions
+	for p, pathItem := range doc.Paths.Map() {
+		for method, op := range pathItem.Operations() {
+			if op == nil || keepOperation(p, op, filters) {
+				continue
+			}
+			pathItem.SetOperation(method, nil)
+			removed++
+		}
+		if len(pathItem.Operations()) > 0 {
+			kept.Set(p, pathItem)
+		}
+	}
+	doc.Paths = kept
+	return removed
+}
+
+// keepOperation reports whether an operation passes the filters
+func keepOperation(p string, op *openapi3.Operation, filters config.Filters) bool {
+	if len(filters.IncludeTags) > 0 && !hasAnyTag(op, filters.IncludeTags) {
+		return false
+	}
+	if hasAnyTag(op, filters.ExcludeTags) {
+		return false
+	}
+	if len(filters.IncludePaths) > 0 && !matchesAny(p, filters.IncludePaths) {
+		return false
+	}
+	return !matchesAny(p, filters.ExcludePaths)
+}
+
+// hasAnyTag reports whether an operation has one of the given tags
+func hasAnyTag(op *openapi3.Operation, tags []string) bool {
+	for _, tag := range op.Tags {
+		for _, want := range tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether a path matches one of the given glob patterns
+func matchesAny(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		candidate := p
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+			// Compare only as many segments as the prefix has
+			segments := strings.Split(p, "/")
+			if n := strings.Count(prefix, "/") + 1; len(segments) > n {
+				candidate = strings.Join(segments[:n], "/")
+			}
+			pattern = prefix
+		}
+		if ok, _ := path.Match(pattern, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestFilterOperations(t *testing.T) {
+	newDoc := func() *openapi3.T {
+		doc := &openapi3.T{Paths: openapi3.NewPaths()}
+		doc.Paths.Set("/users", &openapi3.PathItem{
+			Get:  &openapi3.Operation{Tags: []string{"users"}},
+			Post: &openapi3.Operation{Tags: []string{"users", "admin"}},
+		})
+		doc.Paths.Set("/users/{id}", &openapi3.PathItem{Get: &openapi3.Operation{Tags: []string{"users"}}})
+		doc.Paths.Set("/stores/{id}/items", &openapi3.PathItem{Get: &openapi3.Operation{Tags: []string{"stores"}}})
+		return doc
+	}
+
+	tests := []struct {
+		name    string
+		filters config.Filters
+		removed int
+		paths   int
+	}{
+		{"no filters", config.Filters{}, 0, 3},
+		{"include tag", config.Filters{IncludeTags: []string{"stores"}}, 3, 1},
+		{"exclude tag", config.Filters{ExcludeTags: []string{"admin"}}, 1, 3},
+		{"include path glob", config.Filters{IncludePaths: []string{"/users/*"}}, 3, 1},
+		{"exclude path prefix", config.Filters{ExcludePaths: []string{"/stores/**"}}, 1, 2},
+		{"include and exclude", config.Filters{IncludeTags: []string{"users"}, ExcludePaths: []string{"/users"}}, 3, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newDoc()
+			removed := FilterOperations(doc, tt.filters)
+			if removed != tt.removed {
+				t.Errorf("Expected %d removed operations, got %d", tt.removed, removed)
+			}
+			if doc.Paths.Len() != tt.paths {
+				t.Errorf("Expected %d remaining paths, got %d", tt.paths, doc.Paths.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.uber.org/zap"
+)
+
+// httpMethods are the keys of a path item naming operations
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// LazyDocument is an OpenAPI document whose operations are loaded one at a
+// time, each with only the components it references. Walking the operations
+// of a huge spec then holds a single operation's graph in memory at once,
+// besides the raw JSON of the document, rather than the graph of all of them.
+type LazyDocument struct {
+	// header holds the top-level fields every operation is loaded with
+	header map[string]json.RawMessage
+	// paths holds the raw path items, and components the raw components by
+	// kind and name
+	paths      map[string]map[string]json.RawMessage
+	components map[string]map[string]json.RawMessage
+}
+
+// NewLazyDocument splits a preprocessed OpenAPI document into its raw path
+// items and components
+func NewLazyDocument(body []byte) (*LazyDocument, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(body, &top); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedSpec, err)
+	}
+
+	d := &LazyDocument{header: map[string]json.RawMessage{}}
+	for _, key := range []string{"openapi", "info", "servers", "security"} {
+		if value, ok := top[key]; ok {
+			d.header[key] = value
+		}
+	}
+	if paths, ok := top["paths"]; ok {
+		if err := json.Unmarshal(paths, &d.paths); err != nil {
+			return nil, fmt.Errorf("%w: invalid paths: %v", ErrMalformedSpec, err)
+		}
+	}
+	if components, ok := top["components"]; ok {
+		if err := json.Unmarshal(components, &d.components); err != nil {
+			return nil, fmt.Errorf("%w: invalid components: %v", ErrMalformedSpec, err)
+		}
+	}
+	return d, nil
+}
+
+// Len returns the number of operations of the document, without loading them
+func (d *LazyDocument) Len() int {
+	n := 0
+	for _, item := range d.paths {
+		for key := range item {
+			if httpMethods[key] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Operations returns an iterator over the operations of the document, loaded
+// and validated one at a time with their references resolved, in the order of
+// Operations. An operation failing to load or validate is yielded with its
+// path and method and the error, and iteration goes on with the next one.
+func (d *LazyDocument) Operations(ctx context.Context) iter.Seq2[Operation, error] {
+	return func(yield func(Operation, error) bool) {
+		d.each(ctx, func(path, method string, item *openapi3.PathItem, err error) bool {
+			var op *openapi3.Operation
+			if item != nil {
+				op = item.GetOperation(method)
+			}
+			return yield(Operation{Path: path, Method: method, Operation: op}, err)
+		})
+	}
+}
+
+// each calls f with every operation of the document, within a path item
+// holding only it and the parameters of its path, until f returns false
+func (d *LazyDocument) each(ctx context.Context, f func(path, method string, item *openapi3.PathItem, err error) bool) {
+	for _, path := range sortedKeys(d.paths) {
+		item := d.paths[path]
+		var methods []string
+		for key := range item {
+			if httpMethods[key] {
+				methods = append(methods, strings.ToUpper(key))
+			}
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			loaded, err := d.load(ctx, path, method)
+			if !f(path, method, loaded, err) {
+				return
+			}
+		}
+	}
+}
+
+// load loads a single operation within a document holding only it and the
+// components it references
+func (d *LazyDocument) load(ctx context.Context, path, method string) (*openapi3.PathItem, error) {
+	item := d.paths[path]
+	if _, ok := item["$ref"]; ok {
+		return nil, fmt.Errorf("path item references aren't supported")
+	}
+
+	pathItem := map[string]json.RawMessage{strings.ToLower(method): item[strings.ToLower(method)]}
+	if parameters, ok := item["parameters"]; ok {
+		pathItem["parameters"] = parameters
+	}
+	loaded, err := d.loadDocument(map[string]interface{}{path: pathItem}, d.referenced(pathItem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse operation: %w", err)
+	}
+	if err := loaded.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return loaded.Paths.Value(path), nil
+}
+
+// skeleton loads the document without its operations: its header and
+// security schemes, with empty paths
+func (d *LazyDocument) skeleton(ctx context.Context) (*openapi3.T, error) {
+	components := map[string]map[string]json.RawMessage{}
+	if schemes, ok := d.components["securitySchemes"]; ok {
+		components["securitySchemes"] = schemes
+	}
+	doc, err := d.loadDocument(map[string]interface{}{}, components)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI documentation: %w", err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// loadDocument loads a document of the header of this one with the given
+// paths and components
+func (d *LazyDocument) loadDocument(paths map[string]interface{}, components map[string]map[string]json.RawMessage) (*openapi3.T, error) {
+	doc := map[string]interface{}{}
+	for key, value := range d.header {
+		doc[key] = value
+	}
+	doc["paths"] = paths
+	if len(components) > 0 {
+		doc["components"] = components
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return openapi3.NewLoader().LoadFromData(data)
+}
+
+// referenced returns the components a raw value references, directly or
+// through other components, by kind and name. Security schemes are referenced
+// by name rather than $ref, so all of them are included.
+func (d *LazyDocument) referenced(value interface{}) map[string]map[string]json.RawMessage {
+	components := map[string]map[string]json.RawMessage{}
+	if schemes, ok := d.components["securitySchemes"]; ok {
+		components["securitySchemes"] = schemes
+	}
+
+	var walk func(raw json.RawMessage)
+	visit := func(ref string) {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		if !strings.HasPrefix(ref, "#/components/") || len(parts) < 2 {
+			return
+		}
+		kind, name := parts[0], strings.NewReplacer("~1", "/", "~0", "~").Replace(parts[1])
+		raw, ok := d.components[kind][name]
+		if !ok {
+			return
+		}
+		if components[kind] == nil {
+			components[kind] = map[string]json.RawMessage{}
+		}
+		if _, seen := components[kind][name]; seen {
+			return
+		}
+		components[kind][name] = raw
+		walk(raw)
+	}
+	walk = func(raw json.RawMessage) {
+		var node interface{}
+		if json.Unmarshal(raw, &node) == nil {
+			forEachRef(node, visit)
+		}
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		walk(data)
+	}
+	return components
+}
+
+// forEachRef calls f with every $ref of a JSON value
+func forEachRef(node interface{}, f func(ref string)) {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for key, value := range node {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				f(ref)
+				continue
+			}
+			forEachRef(value, f)
+		}
+	case []interface{}:
+		for _, value := range node {
+			forEachRef(value, f)
+		}
+	}
+}
+
+// parseLazily parses a preprocessed OpenAPI document too large to be loaded as
+// a whole. Its operations are loaded and validated one at a time, each with
+// only the components it references, and gathered into a document sharing
+// the schemas of the same component, so that memory holds the graph of the
+// document once besides that of the operation being loaded. Operations
+// failing validation are skipped if spec.lenient is set.
+func (p *Parser) parseLazily(ctx context.Context, body []byte) (*openapi3.T, error) {
+	lazy, err := NewLazyDocument(body)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := lazy.skeleton(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI documentation validation failed: %w", err)
+	}
+	if doc.Paths == nil {
+		doc.Paths = openapi3.NewPaths()
+	}
+	if doc.Components == nil {
+		doc.Components = &openapi3.Components{}
+	}
+
+	lenient := config.GetBool("spec.lenient")
+	shared := schemaSharer{}
+	var skipped []SkippedOperation
+	lazy.each(ctx, func(path, method string, item *openapi3.PathItem, loadErr error) bool {
+		if loadErr != nil {
+			if lenient {
+				skipped = append(skipped, SkippedOperation{Method: method, Path: path, Err: loadErr})
+				p.logger.Warn("Skipping invalid operation",
+					zap.String("method", method),
+					zap.String("path", path),
+					zap.Error(loadErr))
+				return true
+			}
+			err = fmt.Errorf("OpenAPI documentation validation failed: %s %s: %w", method, path, loadErr)
+			return false
+		}
+
+		shared.pathItem(item)
+		if existing := doc.Paths.Value(path); existing != nil {
+			existing.SetOperation(method, item.GetOperation(method))
+		} else {
+			doc.Paths.Set(path, item)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Components.Schemas = make(openapi3.Schemas, len(shared))
+	for name, schema := range shared {
+		doc.Components.Schemas[name] = &openapi3.SchemaRef{Value: schema}
+	}
+	p.skipped = append(p.skipped, skipped...)
+	if len(skipped) > 0 {
+		p.logger.Warn("Skipped operations that failed validation", zap.Int("skipped", len(skipped)))
+	}
+
+	p.logger.Info("Successfully parsed OpenAPI documentation one operation at a time",
+		zap.Int("paths", len(doc.Paths.Map())),
+		zap.Int("components", len(doc.Components.Schemas)))
+	return doc, nil
+}
+
+// schemaSharer makes the operations loaded on their own share the schemas of
+// the components they reference, keyed by name, with the first operation
+// loaded. The other components they reference are inlined, since the
+// gathered document only has the schemas.
+type schemaSharer map[string]*openapi3.Schema
+
+// pathItem replaces the component schemas an operation's path item references
+// with those already shared
+func (s schemaSharer) pathItem(item *openapi3.PathItem) {
+	seen := map[*openapi3.Schema]bool{}
+	for _, parameter := range item.Parameters {
+		s.parameter(parameter, seen)
+	}
+	for _, op := range item.Operations() {
+		for _, parameter := range op.Parameters {
+			s.parameter(parameter, seen)
+		}
+		if op.RequestBody != nil && op.RequestBody.Value != nil {
+			op.RequestBody.Ref = ""
+			s.content(op.RequestBody.Value.Content, seen)
+		}
+		if op.Responses == nil {
+			continue
+		}
+		for _, response := range op.Responses.Map() {
+			if response == nil || response.Value == nil {
+				continue
+			}
+			response.Ref = ""
+			for _, header := range response.Value.Headers {
+				if header != nil && header.Value != nil {
+					header.Ref = ""
+					s.schema(header.Value.Schema, seen)
+				}
+			}
+			s.content(response.Value.Content, seen)
+		}
+	}
+}
+
+func (s schemaSharer) parameter(parameter *openapi3.ParameterRef, seen map[*openapi3.Schema]bool) {
+	if parameter != nil && parameter.Value != nil {
+		parameter.Ref = ""
+		s.schema(parameter.Value.Schema, seen)
+		s.content(parameter.Value.Content, seen)
+	}
+}
+
+func (s schemaSharer) content(content openapi3.Content, seen map[*openapi3.Schema]bool) {
+	for _, mediaType := range content {
+		if mediaType == nil {
+			continue
+		}
+		for _, example := range mediaType.Examples {
+			if example != nil {
+				example.Ref = ""
+			}
+		}
+		s.schema(mediaType.Schema, seen)
+	}
+}
+
+func (s schemaSharer) schema(ref *openapi3.SchemaRef, seen map[*openapi3.Schema]bool) {
+	if ref == nil || ref.Value == nil {
+		return
+	}
+	if name, ok := strings.CutPrefix(ref.Ref, "#/components/schemas/"); ok {
+		name = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+		if schema, ok := s[name]; ok {
+			ref.Value = schema
+			return
+		}
+		s[name] = ref.Value
+	}
+	if seen[ref.Value] {
+		return
+	}
+	seen[ref.Value] = true
+
+	schema := ref.Value
+	for _, property := range schema.Properties {
+		s.schema(property, seen)
+	}
+	for _, refs := range []openapi3.SchemaRefs{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for _, ref := range refs {
+			s.schema(ref, seen)
+		}
+	}
+	s.schema(schema.Items, seen)
+	s.schema(schema.Not, seen)
+	s.schema(schema.AdditionalProperties.Schema, seen)
+}
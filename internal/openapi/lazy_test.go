@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestLazyDocument(t *testing.T) {
+	spec := `{
+		"openapi": "3.0.0",
+		"info": {"title": "Pets", "version": "1.0.0"},
+		"paths": {
+			"/pets/{id}": {
+				"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+				"get": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}}}},
+				"delete": {"responses": {"204": {"description": "Deleted"}}}
+			},
+			"/broken": {"get": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "unknown"}}}}}}}
+		},
+		"components": {"schemas": {
+			"Pet": {"type": "object", "properties": {"tag": {"$ref": "#/components/schemas/Tag"}}},
+			"Tag": {"type": "string"},
+			"Unused": {"type": "array"}
+		}}
+	}`
+	// Unused is invalid, so operations only validate if it's left out
+	doc, err := NewLazyDocument([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", doc.Len())
+	}
+
+	var got []string
+	for op, err := range doc.Operations(context.Background()) {
+		got = append(got, op.Method+" "+op.Path)
+		switch op.Path + " " + op.Method {
+		case "/broken GET":
+			if err == nil {
+				t.Error("expected the invalid operation to fail validation")
+			}
+		case "/pets/{id} GET":
+			if err != nil {
+				t.Fatal(err)
+			}
+			tag := op.Operation.Responses.Value("200").Value.Content.Get("application/json").Schema.Value.Properties["tag"]
+			if tag == nil || tag.Value == nil || tag.Value.Type != "string" {
+				t.Errorf("tag property wasn't resolved: %+v", tag)
+			}
+		default:
+			if err != nil {
+				t.Errorf("%s %s: %v", op.Method, op.Path, err)
+			}
+		}
+	}
+	want := "GET /broken,DELETE /pets/{id},GET /pets/{id}"
+	if joined := strings.Join(got, ","); joined != want {
+		t.Errorf("operations = %s, want %s", joined, want)
+	}
+
+	// Iteration stops when the loop does
+	n := 0
+	for range doc.Operations(context.Background()) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("iterated over %d operations after break, want 1", n)
+	}
+}
+
+func TestParseLazily(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("spec.lazy_threshold", 1)
+
+	spec := `{
+		"openapi": "3.0.0",
+		"info": {"title": "Pets", "version": "1.0.0"},
+		"paths": {
+			"/pets": {
+				"get": {"responses": {"200": {"$ref": "#/components/responses/Pets"}}},
+				"post": {
+					"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}},
+					"responses": {"201": {"description": "Created"}}
+				}
+			},
+			"/broken": {"get": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "unknown"}}}}}}}
+		},
+		"components": {
+			"schemas": {
+				"Pet": {"type": "object", "properties": {"parent": {"$ref": "#/components/schemas/Pet"}}}
+			},
+			"responses": {
+				"Pets": {"description": "OK", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}}}}
+			}
+		}
+	}`
+
+	if _, err := NewParser(zap.NewNop()).parse(context.Background(), []byte(spec)); err == nil || !strings.Contains(err.Error(), "GET /broken") {
+		t.Errorf("parse() error = %v, want GET /broken failing validation", err)
+	}
+
+	viper.Set("spec.lenient", true)
+	parser := NewParser(zap.NewNop())
+	doc, err := parser.parse(context.Background(), []byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped := parser.Skipped(); len(skipped) != 1 || skipped[0].Method != "GET" || skipped[0].Path != "/broken" {
+		t.Errorf("Skipped() = %+v, want GET /broken", skipped)
+	}
+	var got []string
+	for _, op := range Operations(doc) {
+		got = append(got, op.Method+" "+op.Path)
+	}
+	if joined := strings.Join(got, ","); joined != "GET /pets,POST /pets" {
+		t.Errorf("operations = %s, want GET /pets,POST /pets", joined)
+	}
+
+	// Both operations, and the cycle through parent, share the schema of Pet
+	pet := doc.Components.Schemas["Pet"]
+	if pet == nil || pet.Value == nil {
+		t.Fatalf("components lack Pet: %+v", doc.Components.Schemas)
+	}
+	listed := doc.Paths.Value("/pets").Get.Responses.Value("200").Value.Content.Get("application/json").Schema.Value.Items.Value
+	posted := doc.Paths.Value("/pets").Post.RequestBody.Value.Content.Get("application/json").Schema.Value
+	if listed != pet.Value || posted != pet.Value || pet.Value.Properties["parent"].Value != pet.Value {
+		t.Error("operations don't share the schema of Pet")
+	}
+
+	// The document marshals without references to the components left out
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "#/components/responses") {
+		t.Errorf("document references responses it lacks: %s", data)
+	}
+}
@@ -0,0 +1,320 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.uber.org/zap"
+)
+
+// Extensions recorded on every operation of a merged document
+const (
+	// SourceExtension holds the namespace of the API the operation came from
+	SourceExtension = "x-mcprox-source"
+	// PathExtension holds the operation's path in its own API, without the namespace prefix
+	PathExtension = "x-mcprox-path"
+)
+
+// componentSections lists the component maps that are merged and whose
+// conflicting entries get renamed
+var componentSections = []string{
+	"schemas", "responses", "parameters", "examples", "requestBodies",
+	"headers", "securitySchemes", "links", "callbacks",
+}
+
+// Spec identifies one OpenAPI document taking part in a merge
+type Spec struct {
+	// Name is the namespace of the API's tools; derived from the title if empty
+	Name string
+	// URL is where the OpenAPI document is fetched from
+	URL string
+}
+
+// FetchAndMerge retrieves several OpenAPI documents and merges them into one.
+// Paths are prefixed with the namespace of their API, and every operation
+// records its namespace and original path in the SourceExtension and
+// PathExtension extensions. Component names defined differently by several
+// APIs are prefixed with the namespace of every API but the first.
+func (p *Parser) FetchAndMerge(ctx context.Context, specs []Spec) (*openapi3.T, error) {
+	raw := make([]map[string]interface{}, 0, len(specs))
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		body, err := p.fetch(ctx, spec.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec.URL, err)
+		}
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("%s: error unmarshaling OpenAPI spec: %w", spec.URL, err)
+		}
+		raw = append(raw, doc)
+		names = append(names, spec.Name)
+	}
+
+	merged, err := mergeSpecs(names, raw, p.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged OpenAPI spec: %w", err)
+	}
+	return p.parse(ctx, body)
+}
+
+// SourceName returns the namespace of the API a merged operation came from, or
+// "" if the document wasn't merged
+func SourceName(op *openapi3.Operation) string {
+	name, _ := op.Extensions[SourceExtension].(string)
+	return name
+}
+
+// UpstreamPath returns the path an operation is served at by its own API,
+// stripping the namespace prefix that merging adds to path
+func UpstreamPath(path string, op *openapi3.Operation) string {
+	if original, ok := op.Extensions[PathExtension].(string); ok {
+		return original
+	}
+	return path
+}
+
+// mergeSpecs merges preprocessed OpenAPI documents. The namespace of each
+// document is taken from names, falling back to its title; clashing
+// namespaces get numeric suffixes in order.
+func mergeSpecs(names []string, specs []map[string]interface{}, logger *zap.Logger) (map[string]interface{}, error) {
+	paths := map[string]interface{}{}
+	components := map[string]interface{}{}
+	var tags []interface{}
+	seenTags := map[string]bool{}
+	var titles []string
+	var summary []string
+	used := map[string]bool{}
+	operationIDs := map[string]bool{}
+
+	for i, spec := range specs {
+		info, _ := spec["info"].(map[string]interface{})
+		title, _ := info["title"].(string)
+		titles = append(titles, title)
+
+		ns := config.Namespace(names[i])
+		if ns == "" {
+			ns = config.Namespace(title)
+		}
+		if ns == "" {
+			ns = "api"
+		}
+		for base, n := ns, 2; used[ns]; n++ {
+			ns = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[ns] = true
+		summary = append(summary, fmt.Sprintf("%s (%s)", ns, title))
+
+		// Resolve component name conflicts first so that references can be
+		// rewritten before anything is copied
+		renames := map[string]map[string]string{}
+		specComponents, _ := spec["components"].(map[string]interface{})
+		for _, section := range componentSections {
+			entries, _ := specComponents[section].(map[string]interface{})
+			existing, _ := components[section].(map[string]interface{})
+			for _, name := range sortedKeys(entries) {
+				current, ok := existing[name]
+				if !ok || reflect.DeepEqual(current, entries[name]) {
+					continue
+				}
+				renamed := ns + "_" + name
+				for n := 2; existing[renamed] != nil || entries[renamed] != nil; n++ {
+					renamed = fmt.Sprintf("%s_%s_%d", ns, name, n)
+				}
+				if renames[section] == nil {
+					renames[section] = map[string]string{}
+				}
+				renames[section][name] = renamed
+				logger.Info("Renamed conflicting component",
+					zap.String("api", ns),
+					zap.String("section", section),
+					zap.String("from", name),
+					zap.String("to", renamed))
+			}
+		}
+		if len(renames) > 0 {
+			rewriteRefs(spec, renames)
+		}
+
+		specComponents, _ = spec["components"].(map[string]interface{})
+		for _, section := range componentSections {
+			entries, _ := specComponents[section].(map[string]interface{})
+			if len(entries) == 0 {
+				continue
+			}
+			existing, _ := components[section].(map[string]interface{})
+			if existing == nil {
+				existing = map[string]interface{}{}
+				components[section] = existing
+			}
+			for name, value := range entries {
+				if renamed, ok := renames[section][name]; ok {
+					name = renamed
+				}
+				existing[name] = value
+			}
+		}
+
+		// Document-wide security requirements apply to every operation of
+		// this API only, so push them down before merging
+		security := renameSecurity(spec["security"], renames["securitySchemes"])
+
+		specPaths, _ := spec["paths"].(map[string]interface{})
+		for path, value := range specPaths {
+			item, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for method, opValue := range item {
+				op, ok := opValue.(map[string]interface{})
+				if !ok || !isOperationKey(method) {
+					continue
+				}
+				if opSecurity, ok := op["security"]; ok {
+					op["security"] = renameSecurity(opSecurity, renames["securitySchemes"])
+				} else if security != nil {
+					op["security"] = security
+				}
+				op[SourceExtension] = ns
+				op[PathExtension] = path
+
+				// Operation IDs must stay unique across the merged document
+				if id, ok := op["operationId"].(string); ok && id != "" {
+					if operationIDs[id] {
+						id = ns + "_" + id
+						op["operationId"] = id
+					}
+					operationIDs[id] = true
+				}
+			}
+			paths["/"+ns+path] = item
+		}
+
+		specTags, _ := spec["tags"].([]interface{})
+		for _, tag := range specTags {
+			tagObj, _ := tag.(map[string]interface{})
+			name, _ := tagObj["name"].(string)
+			if name == "" || seenTags[name] {
+				continue
+			}
+			seenTags[name] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no OpenAPI documents to merge")
+	}
+	firstInfo, _ := specs[0]["info"].(map[string]interface{})
+	version, _ := firstInfo["version"].(string)
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	merged := map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":       strings.Join(titles, " + "),
+			"version":     version,
+			"description": "Aggregates the following APIs: " + strings.Join(summary, ", "),
+		},
+		"paths": paths,
+	}
+	if len(components) > 0 {
+		merged["components"] = components
+	}
+	if len(tags) > 0 {
+		merged["tags"] = tags
+	}
+	return merged, nil
+}
+
+// rewriteRefs points every $ref to a renamed component at its new name
+func rewriteRefs(value interface{}, renames map[string]map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				v[key] = renameRef(ref, renames)
+				continue
+			}
+			rewriteRefs(child, renames)
+		}
+	case []interface{}:
+		for _, child := range v {
+			rewriteRefs(child, renames)
+		}
+	}
+}
+
+// renameRef rewrites a local component reference if its target was renamed
+func renameRef(ref string, renames map[string]map[string]string) string {
+	parts := strings.SplitN(ref, "/", 4)
+	if len(parts) != 4 || parts[0] != "#" || parts[1] != "components" {
+		return ref
+	}
+	if renamed, ok := renames[parts[2]][parts[3]]; ok {
+		return "#/components/" + parts[2] + "/" + renamed
+	}
+	return ref
+}
+
+// renameSecurity rewrites the scheme names of security requirements, which
+// reference security schemes by key rather than by $ref
+func renameSecurity(security interface{}, renames map[string]string) interface{} {
+	requirements, ok := security.([]interface{})
+	if !ok {
+		return security
+	}
+	if len(renames) == 0 {
+		return requirements
+	}
+	renamed := make([]interface{}, 0, len(requirements))
+	for _, requirement := range requirements {
+		schemes, ok := requirement.(map[string]interface{})
+		if !ok {
+			renamed = append(renamed, requirement)
+			continue
+		}
+		out := make(map[string]interface{}, len(schemes))
+		for name, scopes := range schemes {
+			if newName, ok := renames[name]; ok {
+				name = newName
+			}
+			out[name] = scopes
+		}
+		renamed = append(renamed, out)
+	}
+	return renamed
+}
+
+// isOperationKey reports whether a path item key holds an operation
+func isOperationKey(key string) bool {
+	switch strings.ToLower(key) {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFetchAndMerge(t *testing.T) {
+	specs := map[string]string{
+		"/billing": `{
+			"openapi": "3.0.0",
+			"info": {"title": "Billing API", "version": "2.0.0"},
+			"security": [{"token": []}],
+			"paths": {
+				"/items": {
+					"get": {
+						"responses": {
+							"200": {
+								"description": "Invoices",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}
+							}
+						}
+					}
+				}
+			},
+			"components": {
+				"schemas": {
+					"Item": {"type": "object", "properties": {"amount": {"type": "number"}}},
+					"Error": {"type": "object", "properties": {"message": {"type": "string"}}}
+				},
+				"securitySchemes": {"token": {"type": "http", "scheme": "bearer"}}
+			}
+		}`,
+		"/users": `{
+			"openapi": "3.0.0",
+			"info": {"title": "Users API", "version": "1.0.0"},
+			"paths": {
+				"/items": {
+					"get": {
+						"security": [{"token": []}],
+						"responses": {
+							"200": {
+								"description": "Users",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}
+							}
+						}
+					}
+				}
+			},
+			"components": {
+				"schemas": {
+					"Item": {"type": "object", "properties": {"name": {"type": "string"}}},
+					"Error": {"type": "object", "properties": {"message": {"type": "string"}}}
+				},
+				"securitySchemes": {"token": {"type": "apiKey", "in": "header", "name": "X-Key"}}
+			}
+		}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(specs[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	logger, _ := zap.NewDevelopment()
+	parser := NewParser(logger)
+
+	doc, err := parser.FetchAndMerge(context.Background(), []Spec{
+		{URL: server.URL + "/billing"},
+		{Name: "people", URL: server.URL + "/users"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if doc.Info.Title != "Billing API + Users API" {
+		t.Errorf("Expected merged title, got '%s'", doc.Info.Title)
+	}
+
+	// Paths are namespaced and remember their origin
+	billing := doc.Paths.Find("/billing_api/items")
+	people := doc.Paths.Find("/people/items")
+	if billing == nil || people == nil {
+		t.Fatalf("Expected namespaced paths, got %v", doc.Paths.InMatchingOrder())
+	}
+	if SourceName(billing.Get) != "billing_api" || UpstreamPath("/billing_api/items", billing.Get) != "/items" {
+		t.Errorf("Expected billing_api /items, got %s %s", SourceName(billing.Get), UpstreamPath("/billing_api/items", billing.Get))
+	}
+	if SourceName(people.Get) != "people" {
+		t.Errorf("Expected source people, got %s", SourceName(people.Get))
+	}
+
+	// Identical components are shared, conflicting ones are renamed for the later API
+	for _, name := range []string{"Item", "people_Item", "Error"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("Expected schema %s", name)
+		}
+	}
+	if doc.Components.Schemas["people_Error"] != nil {
+		t.Error("Expected identical Error schemas to be shared")
+	}
+	ref := people.Get.Responses.Value("200").Value.Content["application/json"].Schema.Ref
+	if ref != "#/components/schemas/people_Item" {
+		t.Errorf("Expected reference to people_Item, got %s", ref)
+	}
+
+	// Security requirements follow renamed schemes and document-wide ones are pushed down
+	if people.Get.Security == nil || (*people.Get.Security)[0]["people_token"] == nil {
+		t.Errorf("Expected people operation to use people_token, got %v", people.Get.Security)
+	}
+	if billing.Get.Security == nil || (*billing.Get.Security)[0]["token"] == nil {
+		t.Errorf("Expected billing operation to inherit the token requirement, got %v", billing.Get.Security)
+	}
+}
@@ -0,0 +1,46 @@
+package openapi
+
+import (
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Operation is an operation of a document along with its path and method
+type Operation struct {
+	Path      string
+	Method    string
+	Operation *openapi3.Operation
+}
+
+// Operations returns every operation of a document sorted by path, then by
+// method, so that code generated from them is reproducible
+func Operations(doc *openapi3.T) []Operation {
+	var ops []Operation
+	if doc.Paths == nil {
+		return ops
+	}
+
+	paths := doc.Paths.Map()
+	keys := make([]string, 0, len(paths))
+	for path := range paths {
+		keys = append(keys, path)
+	}
+	sort.Strings(keys)
+
+	for _, path := range keys {
+		operations := paths[path].Operations()
+		methods := make([]string, 0, len(operations))
+		for method, op := range operations {
+			if op != nil {
+				methods = append(methods, method)
+			}
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			ops = append(ops, Operation{Path: path, Method: method, Operation: operations[method]})
+		}
+	}
+	return ops
+}
@@ -0,0 +1,33 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestOperationsSorted(t *testing.T) {
+	doc := &openapi3.T{Paths: openapi3.NewPaths()}
+	doc.Paths.Set("/users/{id}", &openapi3.PathItem{
+		Get:    &openapi3.Operation{},
+		Delete: &openapi3.Operation{},
+	})
+	doc.Paths.Set("/users", &openapi3.PathItem{
+		Post: &openapi3.Operation{},
+		Get:  &openapi3.Operation{},
+	})
+	doc.Paths.Set("/orders", &openapi3.PathItem{Get: &openapi3.Operation{}})
+
+	expected := []string{"GET /orders", "GET /users", "POST /users", "DELETE /users/{id}", "GET /users/{id}"}
+	for i := 0; i < 10; i++ {
+		ops := Operations(doc)
+		if len(ops) != len(expected) {
+			t.Fatalf("Expected %d operations, got %d", len(expected), len(ops))
+		}
+		for j, op := range ops {
+			if got := op.Method + " " + op.Path; got != expected[j] {
+				t.Fatalf("Expected %s at position %d, got %s", expected[j], j, got)
+			}
+		}
+	}
+}
@@ -2,15 +2,22 @@ package openapi
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/logging"
+	"github.com/berkantay/mcprox/internal/upstream"
 	"github.com/getkin/kin-openapi/openapi3"
 	"go.uber.org/zap"
 )
@@ -19,58 +26,189 @@ import (
 type Parser struct {
 	logger        *zap.Logger
 	clientTimeout time.Duration
+	fetched       []SpecSource
+	skipped       []SkippedOperation
+}
+
+// SkippedOperation is an operation lenient parsing left out of a document
+// because it failed validation
+type SkippedOperation struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+// SpecSource identifies a fetched OpenAPI document by its URL and the SHA-256
+// digest of its content as served
+type SpecSource struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	// Content is the document as served, before preprocessing
+	Content []byte `json:"-"`
+	// Preprocessed is the document after preprocessing, and Transformations
+	// the changes preprocessing made
+	Preprocessed    []byte           `json:"-"`
+	Transformations []Transformation `json:"-"`
 }
 
 // NewParser creates a new OpenAPI parser
 func NewParser(logger *zap.Logger) *Parser {
 	timeout := time.Duration(config.GetInt("client.timeout")) * time.Second
 	return &Parser{
-		logger:        logger,
+		logger:        logging.Component(logger, "openapi"),
 		clientTimeout: timeout,
 	}
 }
 
 // FetchAndParse retrieves OpenAPI documentation from a URL and parses it
 func (p *Parser) FetchAndParse(ctx context.Context, swaggerURL string) (*openapi3.T, error) {
+	body, err := p.fetch(ctx, swaggerURL)
+	if err != nil {
+		return nil, err
+	}
+	return p.parse(ctx, body)
+}
+
+// Fetched returns the OpenAPI documents fetched so far, in order
+func (p *Parser) Fetched() []SpecSource {
+	return p.fetched
+}
+
+// Skipped returns the operations lenient parsing left out of the documents
+// parsed so far, in order
+func (p *Parser) Skipped() []SkippedOperation {
+	return p.skipped
+}
+
+// fetch retrieves OpenAPI documentation from a URL, or a local file given as
+// a path or file:// URL, and preprocesses it for compatibility with the loader
+func (p *Parser) fetch(ctx context.Context, swaggerURL string) ([]byte, error) {
 	p.logger.Info("Fetching OpenAPI documentation", zap.String("url", swaggerURL))
 
+	var body []byte
+	var contentType string
+	var err error
+	if path, ok := localPath(swaggerURL); ok {
+		body, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenAPI documentation: %w", err)
+		}
+	} else if body, contentType, err = p.download(ctx, swaggerURL); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	source := SpecSource{URL: swaggerURL, SHA256: hex.EncodeToString(sum[:]), Content: body}
+
+	// Compressed files and other encodings than UTF-8 fail to unmarshal
+	body, err = decode(body, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode OpenAPI documentation: %w", err)
+	}
+
+	// Pre-process body for OpenAPI 3.1.0 compatibility
+	body, source.Transformations, err = preprocessOpenAPISpec(body, p.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preprocess OpenAPI spec: %w", err)
+	}
+	source.Preprocessed = body
+	p.fetched = append(p.fetched, source)
+
+	return body, nil
+}
+
+// localPath returns the path of a spec given as a file:// URL or as a path
+// without a URL scheme
+func localPath(swaggerURL string) (string, bool) {
+	if path, ok := strings.CutPrefix(swaggerURL, "file://"); ok {
+		return path, true
+	}
+	if strings.Contains(swaggerURL, "://") {
+		return "", false
+	}
+	return swaggerURL, true
+}
+
+// download retrieves OpenAPI documentation over HTTP, along with its
+// Content-Type
+func (p *Parser) download(ctx context.Context, swaggerURL string) ([]byte, string, error) {
 	// Validate URL
-	_, err := url.ParseRequestURI(swaggerURL)
+	specURL, err := url.ParseRequestURI(swaggerURL)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
+		return nil, "", fmt.Errorf("invalid URL: %w", err)
 	}
 
-	// Create HTTP client with timeout
+	// Create HTTP client with timeout, connecting to the overridden
+	// addresses of hosts not in DNS yet
+	overrides, err := upstream.ParseOverrides(config.GetStringSlice("service.resolve"))
+	if err != nil {
+		return nil, "", err
+	}
+	transport, err := upstream.NewTransport(upstream.HTTP2Auto, overrides, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	// Spec endpoints behind SSO redirect through login pages and need
+	// session cookies
+	jar, err := cookieJar(specURL)
+	if err != nil {
+		return nil, "", err
+	}
+	limitRedirects := upstream.LimitRedirects(config.GetInt("spec.max_redirects"))
 	client := &http.Client{
-		Timeout: p.clientTimeout,
+		Timeout:   p.clientTimeout,
+		Transport: transport,
+		Jar:       jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			p.logger.Debug("Following redirect", zap.String("url", req.URL.Redacted()))
+			return limitRedirects(req, via)
+		},
 	}
 
 	// Make HTTP request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, swaggerURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Protected spec endpoints need credentials of their own
+	for _, header := range config.GetStringSlice("spec.headers") {
+		name, value, found := strings.Cut(header, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, "", fmt.Errorf("invalid spec header %q: expected Name: value", header)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	// Large specs are often served compressed
+	if config.GetBool("client.compression") {
+		req.Header.Set("Accept-Encoding", upstream.AcceptEncoding)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch OpenAPI documentation: %w", err)
+		return nil, "", fmt.Errorf("failed to fetch OpenAPI documentation: %w", err)
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-OK response: %s", resp.Status)
+	if err := upstream.Decompress(resp); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch OpenAPI documentation: %w", err)
 	}
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err := checkResponse(resp, swaggerURL, body); err != nil {
+		return nil, "", err
 	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
 
-	// Pre-process body for OpenAPI 3.1.0 compatibility
-	body, err = preprocessOpenAPISpec(body, p.logger)
-	if err != nil {
-		return nil, fmt.Errorf("failed to preprocess OpenAPI spec: %w", err)
+// parse loads and validates a preprocessed OpenAPI document
+func (p *Parser) parse(ctx context.Context, body []byte) (*openapi3.T, error) {
+	if threshold := config.GetInt("spec.lazy_threshold"); threshold > 0 && len(body) > threshold {
+		return p.parseLazily(ctx, body)
 	}
 
 	// Parse OpenAPI document
@@ -82,6 +220,14 @@ func (p *Parser) FetchAndParse(ctx context.Context, swaggerURL string) (*openapi
 
 	// Validate the document
 	err = doc.Validate(ctx)
+	if err != nil {
+		v := newValidator(ctx, doc, body, config.GetBool("spec.lenient"))
+		if v.lenient {
+			err = p.skipInvalidOperations(v)
+		} else {
+			err = v.document(err)
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("OpenAPI documentation validation failed: %w", err)
 	}
@@ -93,7 +239,7 @@ func (p *Parser) FetchAndParse(ctx context.Context, swaggerURL string) (*openapi
 	}
 
 	schemaCount := 0
-	if doc.Components.Schemas != nil {
+	if doc.Components != nil && doc.Components.Schemas != nil {
 		schemaCount = len(doc.Components.Schemas)
 	}
 
@@ -104,13 +250,92 @@ func (p *Parser) FetchAndParse(ctx context.Context, swaggerURL string) (*openapi
 	return doc, nil
 }
 
-// preprocessOpenAPISpec adapts OpenAPI 3.1.0 to be compatible with OpenAPI 3.0.x
-func preprocessOpenAPISpec(data []byte, logger *zap.Logger) ([]byte, error) {
+// skipInvalidOperations validates every operation of a document on its own,
+// removes those that fail, dropping paths left without operations, and
+// validates the rest of the document again
+func (p *Parser) skipInvalidOperations(v *validator) error {
+	doc := v.doc
+	if doc.Paths == nil {
+		return v.document(doc.Validate(v.ctx))
+	}
+
+	var skipped []SkippedOperation
+	for _, op := range Operations(doc) {
+		problems, err := v.operation(op)
+		if err == nil {
+			continue
+		}
+		if len(problems) > 0 {
+			err = &ValidationError{Problems: problems}
+		}
+		skipped = append(skipped, SkippedOperation{Method: op.Method, Path: op.Path, Err: err})
+		doc.Paths.Value(op.Path).SetOperation(op.Method, nil)
+		p.logger.Warn("Skipping invalid operation",
+			zap.String("method", op.Method),
+			zap.String("path", op.Path),
+			zap.Error(err))
+	}
+
+	kept := openapi3.NewPaths()
+	kept.Extensions = doc.Paths.Extensions
+	for path, pathItem := range doc.Paths.Map() {
+		if len(pathItem.Operations()) > 0 {
+			kept.Set(path, pathItem)
+		}
+	}
+	doc.Paths = kept
+	p.skipped = append(p.skipped, skipped...)
+	if len(skipped) > 0 {
+		p.logger.Warn("Skipped operations that failed validation", zap.Int("skipped", len(skipped)))
+	}
+
+	// What remains fails outside of operations, which can't be skipped
+	if err := doc.Validate(v.ctx); err != nil {
+		return v.document(err)
+	}
+	return nil
+}
+
+// Transformation is a change preprocessing made to an OpenAPI document
+type Transformation struct {
+	// Pointer is the JSON pointer of the changed value
+	Pointer string `json:"pointer"`
+	// Change describes the change
+	Change string `json:"change"`
+}
+
+// String returns the transformation as a line of a transformation log
+func (t Transformation) String() string {
+	return t.Pointer + ": " + t.Change
+}
+
+// preprocessor records the transformations made while preprocessing a document
+type preprocessor struct {
+	logger          *zap.Logger
+	transformations []Transformation
+}
+
+// record notes a change to the value at the path of keys
+func (p *preprocessor) record(path []string, change string) {
+	pointer := jsonPointer(path)
+	p.transformations = append(p.transformations, Transformation{Pointer: pointer, Change: change})
+	p.logger.Debug("Transformed OpenAPI spec", zap.String("pointer", pointer), zap.String("change", change))
+}
+
+// at returns path extended with keys, leaving path unchanged
+func at(path []string, keys ...string) []string {
+	return append(append([]string(nil), path...), keys...)
+}
+
+// preprocessOpenAPISpec adapts OpenAPI 3.1.0 to be compatible with OpenAPI
+// 3.0.x. It also returns the changes made, sorted by JSON pointer.
+func preprocessOpenAPISpec(data []byte, logger *zap.Logger) ([]byte, []Transformation, error) {
 	// Parse the JSON into a generic map
 	var spec map[string]interface{}
 	if err := json.Unmarshal(data, &spec); err != nil {
-		return nil, fmt.Errorf("error unmarshaling OpenAPI spec: %w", err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrMalformedSpec, err)
 	}
+	p := &preprocessor{logger: logger}
 
 	// Check OpenAPI version
 	if version, ok := spec["openapi"].(string); ok {
@@ -118,6 +343,7 @@ func preprocessOpenAPISpec(data []byte, logger *zap.Logger) ([]byte, error) {
 			logger.Info("Converting OpenAPI 3.1.x spec to 3.0.0 for compatibility")
 			// Change version to 3.0.0
 			spec["openapi"] = "3.0.0"
+			p.record([]string{"openapi"}, fmt.Sprintf("downgraded version %s to 3.0.0", version))
 		}
 	}
 
@@ -127,11 +353,12 @@ func preprocessOpenAPISpec(data []byte, logger *zap.Logger) ([]byte, error) {
 			if schemasInterface, exists := components["schemas"]; exists {
 				if schemas, ok := schemasInterface.(map[string]interface{}); ok {
 					// Process each schema
-					for _, schemaValue := range schemas {
+					for name, schemaValue := range schemas {
 						if schema, ok := schemaValue.(map[string]interface{}); ok {
-							fixNullTypes(schema, logger)
+							path := []string{"components", "schemas", name}
+							p.fixNullTypes(schema, path)
 							// Remove non-standard fields
-							removeNonStandardFields(schema, logger)
+							p.removeNonStandardFields(schema, path)
 						}
 					}
 				}
@@ -142,17 +369,17 @@ func preprocessOpenAPISpec(data []byte, logger *zap.Logger) ([]byte, error) {
 	// Fix anyOf in parameters
 	if pathsInterface, exists := spec["paths"]; exists {
 		if paths, ok := pathsInterface.(map[string]interface{}); ok {
-			for _, pathValue := range paths {
+			for pathName, pathValue := range paths {
 				if path, ok := pathValue.(map[string]interface{}); ok {
-					for _, methodValue := range path {
+					for methodName, methodValue := range path {
 						if method, ok := methodValue.(map[string]interface{}); ok {
 							if paramsInterface, exists := method["parameters"]; exists {
 								if params, ok := paramsInterface.([]interface{}); ok {
-									for _, paramInterface := range params {
+									for i, paramInterface := range params {
 										if param, ok := paramInterface.(map[string]interface{}); ok {
 											if schemaInterface, exists := param["schema"]; exists {
 												if schema, ok := schemaInterface.(map[string]interface{}); ok {
-													fixAnyOf(schema, logger)
+													p.fixAnyOf(schema, []string{"paths", pathName, methodName, "parameters", strconv.Itoa(i), "schema"})
 												}
 											}
 										}
@@ -166,12 +393,20 @@ func preprocessOpenAPISpec(data []byte, logger *zap.Logger) ([]byte, error) {
 		}
 	}
 
+	sort.SliceStable(p.transformations, func(i, j int) bool {
+		return p.transformations[i].Pointer < p.transformations[j].Pointer
+	})
+
 	// Re-marshal the modified spec
-	return json.Marshal(spec)
+	body, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, p.transformations, nil
 }
 
 // removeNonStandardFields removes fields that are not standard in OpenAPI 3.0
-func removeNonStandardFields(schema map[string]interface{}, logger *zap.Logger) {
+func (p *preprocessor) removeNonStandardFields(schema map[string]interface{}, path []string) {
 	// List of non-standard fields to remove
 	nonStandardFields := []string{
 		"error_messages",
@@ -182,44 +417,45 @@ func removeNonStandardFields(schema map[string]interface{}, logger *zap.Logger)
 	for _, field := range nonStandardFields {
 		if _, exists := schema[field]; exists {
 			delete(schema, field)
-			logger.Debug("Removed non-standard field from schema", zap.String("field", field))
+			p.record(at(path, field), "removed non-standard field")
 		}
 	}
 
 	// Recursively process properties
 	if properties, exists := schema["properties"].(map[string]interface{}); exists {
-		for _, propValue := range properties {
+		for name, propValue := range properties {
 			if propObj, ok := propValue.(map[string]interface{}); ok {
-				removeNonStandardFields(propObj, logger)
+				p.removeNonStandardFields(propObj, at(path, "properties", name))
 			}
 		}
 	}
 
 	// Process items in arrays
 	if items, exists := schema["items"].(map[string]interface{}); exists {
-		removeNonStandardFields(items, logger)
+		p.removeNonStandardFields(items, at(path, "items"))
 	}
 }
 
 // fixNullTypes recursively finds and fixes null types in schemas
-func fixNullTypes(schema map[string]interface{}, logger *zap.Logger) {
+func (p *preprocessor) fixNullTypes(schema map[string]interface{}, path []string) {
 	if properties, exists := schema["properties"].(map[string]interface{}); exists {
-		for _, propValue := range properties {
+		for name, propValue := range properties {
 			if propObj, ok := propValue.(map[string]interface{}); ok {
+				propPath := at(path, "properties", name)
 				// Check for anyOf that includes null
 				if _, exists := propObj["anyOf"]; exists {
-					fixAnyOf(propObj, logger)
+					p.fixAnyOf(propObj, propPath)
 				}
 
 				// Recursively process nested properties
-				fixNullTypes(propObj, logger)
+				p.fixNullTypes(propObj, propPath)
 			}
 		}
 	}
 }
 
 // fixAnyOf handles anyOf with null type by converting to a standard type with nullable
-func fixAnyOf(schema map[string]interface{}, logger *zap.Logger) {
+func (p *preprocessor) fixAnyOf(schema map[string]interface{}, path []string) {
 	if anyOfInterface, exists := schema["anyOf"]; exists {
 		if anyOf, ok := anyOfInterface.([]interface{}); ok {
 			// Look for [{"type": "something"}, {"type": "null"}] pattern
@@ -246,7 +482,7 @@ func fixAnyOf(schema map[string]interface{}, logger *zap.Logger) {
 					}
 					schema["nullable"] = true
 					delete(schema, "anyOf")
-					logger.Debug("Converted anyOf with null to nullable type")
+					p.record(at(path, "anyOf"), fmt.Sprintf("replaced anyOf with null by a nullable %v", mainType["type"]))
 				}
 			}
 		}
@@ -1,11 +1,19 @@
 package openapi
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -101,3 +109,289 @@ func TestFetchAndParse(t *testing.T) {
 		t.Error("Expected error for invalid URL but got none")
 	}
 }
+
+func TestFetchCompressedSpec(t *testing.T) {
+	viper.Set("client.compression", true)
+	defer viper.Reset()
+
+	spec := `{"openapi": "3.0.0", "info": {"title": "Pets", "version": "1.0.0"}, "paths": {}, "components": {}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "deflate") {
+			w.Write([]byte(spec))
+			return
+		}
+		w.Header().Set("Content-Encoding", "deflate")
+		z := zlib.NewWriter(w)
+		z.Write([]byte(spec))
+		z.Close()
+	}))
+	defer server.Close()
+
+	doc, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info.Title != "Pets" {
+		t.Errorf("title = %s, want Pets", doc.Info.Title)
+	}
+}
+
+func TestFetchEncodedSpec(t *testing.T) {
+	spec := `{"openapi": "3.0.0", "info": {"title": "Café", "version": "1.0.0"}, "paths": {}}`
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(spec))
+	gz.Close()
+	latin1 := bytes.Replace([]byte(spec), []byte("é"), []byte{0xe9}, 1)
+	utf16 := []byte{0xff, 0xfe}
+	for _, r := range spec {
+		utf16 = append(utf16, byte(r), byte(r>>8))
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"gzip file", "application/gzip", gzipped.Bytes()},
+		{"latin-1", "application/json; charset=ISO-8859-1", latin1},
+		{"utf-8 with bom", "application/json", append([]byte{0xef, 0xbb, 0xbf}, spec...)},
+		{"utf-16 with bom", "application/json", utf16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			doc, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Info.Title != "Café" {
+				t.Errorf("title = %q, want Café", doc.Info.Title)
+			}
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=x-unknown")
+		w.Write([]byte(spec))
+	}))
+	defer server.Close()
+	if _, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), server.URL); err == nil || !strings.Contains(err.Error(), "unsupported charset") {
+		t.Errorf("error = %v, want an unsupported charset", err)
+	}
+}
+
+func TestFetchLocalSpec(t *testing.T) {
+	spec := `{"openapi": "3.1.0", "info": {"title": "Pets", "version": "1.0.0"}, "paths": {}}`
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, specURL := range []string{path, "file://" + path} {
+		parser := NewParser(zap.NewNop())
+		doc, err := parser.FetchAndParse(context.Background(), specURL)
+		if err != nil {
+			t.Fatalf("FetchAndParse(%q) error = %v", specURL, err)
+		}
+		if doc.Info.Title != "Pets" {
+			t.Errorf("title = %s, want Pets", doc.Info.Title)
+		}
+		if fetched := parser.Fetched(); len(fetched) != 1 || string(fetched[0].Content) != spec {
+			t.Errorf("Fetched() = %+v, want the document as read", fetched)
+		}
+	}
+}
+
+func TestPreprocessTransformations(t *testing.T) {
+	spec := `{
+		"openapi": "3.1.0",
+		"paths": {"/pets/{id}": {"get": {"parameters": [{"name": "id", "in": "path", "schema": {"anyOf": [{"type": "string"}, {"type": "null"}]}}]}}},
+		"components": {"schemas": {"Pet": {"error_messages": {}, "properties": {"tag": {"anyOf": [{"type": "null"}, {"type": "string"}]}}}}}
+	}`
+	_, transformations, err := preprocessOpenAPISpec([]byte(spec), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"/components/schemas/Pet/error_messages: removed non-standard field",
+		"/components/schemas/Pet/properties/tag/anyOf: replaced anyOf with null by a nullable string",
+		"/openapi: downgraded version 3.1.0 to 3.0.0",
+		"/paths/~1pets~1{id}/get/parameters/0/schema/anyOf: replaced anyOf with null by a nullable string",
+	}
+	if len(transformations) != len(want) {
+		t.Fatalf("transformations = %v, want %v", transformations, want)
+	}
+	for i := range want {
+		if got := transformations[i].String(); got != want[i] {
+			t.Errorf("transformation %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestFetchSpecHeaders(t *testing.T) {
+	viper.Set("spec.headers", []string{"Authorization: Bearer token", "X-Team:pets"})
+	defer viper.Reset()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Team") != "pets" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"openapi": "3.0.0", "info": {"title": "Pets", "version": "1.0.0"}, "paths": {}}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("spec.headers", []string{"Authorization"})
+	if _, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), server.URL); err == nil {
+		t.Error("expected a header without a value to be rejected")
+	}
+}
+
+func TestLenientParsing(t *testing.T) {
+	defer viper.Reset()
+
+	spec := `{
+		"openapi": "3.0.0",
+		"info": {"title": "Pets", "version": "1.0.0"},
+		"paths": {
+			"/pets": {
+				"get": {"responses": {"200": {"description": "OK"}}},
+				"post": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "unknown"}}}}}}
+			},
+			"/pets/{id}": {"get": {"responses": {"200": {"description": "OK"}}}}
+		}
+	}`
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), path); err == nil {
+		t.Fatal("expected the invalid operations to fail validation")
+	}
+
+	viper.Set("spec.lenient", true)
+	parser := NewParser(zap.NewNop())
+	doc, err := parser.FetchAndParse(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, op := range Operations(doc) {
+		ops = append(ops, op.Method+" "+op.Path)
+	}
+	if strings.Join(ops, ",") != "GET /pets" {
+		t.Errorf("operations = %v, want only GET /pets", ops)
+	}
+	var skipped []string
+	for _, op := range parser.Skipped() {
+		skipped = append(skipped, op.Method+" "+op.Path)
+		if op.Err == nil {
+			t.Errorf("skipped %s %s without an error", op.Method, op.Path)
+		}
+	}
+	if strings.Join(skipped, ",") != "POST /pets,GET /pets/{id}" {
+		t.Errorf("Skipped() = %v, want POST /pets and GET /pets/{id}", skipped)
+	}
+
+	// Errors outside of operations still fail the document
+	invalid := strings.Replace(spec, `"title": "Pets", `, "", 1)
+	if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), path); err == nil {
+		t.Error("expected a document without a title to fail validation")
+	}
+}
+
+func TestFetchAuthWalls(t *testing.T) {
+	defer viper.Reset()
+
+	spec := `{"openapi": "3.0.0", "info": {"title": "Pets", "version": "1.0.0"}, "paths": {}}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "ok" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.Write([]byte(spec))
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><form><input type="password" name="password"></form></html>`))
+	})
+	mux.HandleFunc("/sso", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+		http.Redirect(w, r, "/openapi.json", http.StatusFound)
+	})
+	mux.HandleFunc("/basic", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="docs"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><div id="swagger-ui"></div>`))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"openapi": `))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetch := func(path string) error {
+		_, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), server.URL+path)
+		return err
+	}
+	viper.Set("spec.max_redirects", 10)
+
+	if err := fetch("/openapi.json"); !errors.Is(err, ErrAuthWall) || !strings.Contains(err.Error(), "/login") {
+		t.Errorf("redirect to a login page: error = %v, want an auth wall", err)
+	}
+	if err := fetch("/basic"); !errors.Is(err, ErrAuthWall) || !strings.Contains(err.Error(), `Basic realm="docs"`) {
+		t.Errorf("401: error = %v, want an auth wall with the challenge", err)
+	}
+	if err := fetch("/docs"); !errors.Is(err, ErrMalformedSpec) || errors.Is(err, ErrAuthWall) {
+		t.Errorf("Swagger UI page: error = %v, want a malformed spec", err)
+	}
+	if err := fetch("/broken"); !errors.Is(err, ErrMalformedSpec) {
+		t.Errorf("truncated document: error = %v, want a malformed spec", err)
+	}
+
+	// Cookies set along redirects are kept
+	if err := fetch("/sso"); err != nil {
+		t.Errorf("cookie set while redirecting: %v", err)
+	}
+
+	// Redirects can be refused
+	viper.Set("spec.max_redirects", 0)
+	if err := fetch("/sso"); err == nil || !strings.Contains(err.Error(), "spec.max_redirects") {
+		t.Errorf("refused redirect: error = %v", err)
+	}
+
+	// Configured and exported session cookies are sent
+	viper.Set("spec.cookies", []string{"session=ok"})
+	if err := fetch("/openapi.json"); err != nil {
+		t.Errorf("spec.cookies: %v", err)
+	}
+	viper.Set("spec.cookies", []string{})
+	host := strings.Split(strings.TrimPrefix(server.URL, "http://"), ":")[0]
+	cookieFile := filepath.Join(t.TempDir(), "cookies.txt")
+	cookies := "# Netscape HTTP Cookie File\n#HttpOnly_" + host + "\tFALSE\t/\tFALSE\t0\tsession\tok\n"
+	if err := os.WriteFile(cookieFile, []byte(cookies), 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("spec.cookie_file", cookieFile)
+	if err := fetch("/openapi.json"); err != nil {
+		t.Errorf("spec.cookie_file: %v", err)
+	}
+}
@@ -0,0 +1,406 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxProblemValue is the length above which the offending value of a
+// validation problem is truncated
+const maxProblemValue = 120
+
+// ValidationProblem is a problem validation found in an OpenAPI document
+type ValidationProblem struct {
+	// Pointer is the JSON pointer of the invalid value, empty for the document
+	Pointer string `json:"pointer"`
+	// Value is the invalid value as compact JSON, truncated if long
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+	// Suggestion is a way around the problem, if there is one
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// String formats a problem as "pointer: message (value: ...); suggestion"
+func (p ValidationProblem) String() string {
+	s := p.Message
+	if p.Pointer != "" {
+		s = p.Pointer + ": " + s
+	}
+	if p.Value != "" {
+		s += " (value: " + p.Value + ")"
+	}
+	if p.Suggestion != "" {
+		s += "; " + p.Suggestion
+	}
+	return s
+}
+
+// ValidationError is the error of an OpenAPI document failing validation,
+// listing every problem found
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].String()
+	}
+	var s strings.Builder
+	fmt.Fprintf(&s, "%d problems found", len(e.Problems))
+	for _, problem := range e.Problems {
+		s.WriteString("\n  " + problem.String())
+	}
+	return s.String()
+}
+
+// validator locates the problems of an OpenAPI document that failed
+// validation. kin-openapi stops at the first problem and doesn't say where it
+// is, so the document is validated again piece by piece, descending into the
+// pieces that fail down to the innermost one.
+type validator struct {
+	ctx context.Context
+	doc *openapi3.T
+	// raw is the document as plain JSON values, to quote offending values
+	raw      interface{}
+	lenient  bool
+	problems []ValidationProblem
+	reported map[string]bool
+}
+
+// newValidator creates a validator of a document loaded from body
+func newValidator(ctx context.Context, doc *openapi3.T, body []byte, lenient bool) *validator {
+	v := &validator{ctx: ctx, doc: doc, lenient: lenient, reported: map[string]bool{}}
+	json.Unmarshal(body, &v.raw)
+	return v
+}
+
+// document returns the problems of the document, given the error of its
+// validation as a whole
+func (v *validator) document(err error) *ValidationError {
+	v.problems = nil
+	v.reported = map[string]bool{}
+	doc := v.doc
+	if doc.OpenAPI == "" {
+		v.report([]string{"openapi"}, errors.New("must be a non-empty string"), `set it to the OpenAPI version of the document, e.g. "3.0.3"`)
+	}
+	if doc.Components != nil {
+		v.components()
+	}
+	if doc.Info == nil {
+		v.report([]string{"info"}, errors.New("must be an object"), "add an info object with the title and version of the API")
+	} else if err := doc.Info.Validate(v.ctx); err != nil {
+		v.report([]string{"info"}, err, "")
+	}
+	if doc.Paths == nil {
+		v.report([]string{"paths"}, errors.New("must be an object"), `add "paths": {} for a document without operations`)
+	} else {
+		for _, op := range Operations(doc) {
+			problems, _ := v.operation(op)
+			v.problems = append(v.problems, problems...)
+		}
+	}
+	if doc.Security != nil {
+		if err := doc.Security.Validate(v.ctx); err != nil {
+			v.report([]string{"security"}, err, "")
+		}
+	}
+	for i, server := range doc.Servers {
+		if err := server.Validate(v.ctx); err != nil {
+			v.report([]string{"servers", strconv.Itoa(i)}, err, "")
+		}
+	}
+	if doc.Tags != nil {
+		if err := doc.Tags.Validate(v.ctx); err != nil {
+			v.report([]string{"tags"}, err, "")
+		}
+	}
+	if doc.ExternalDocs != nil {
+		if err := doc.ExternalDocs.Validate(v.ctx); err != nil {
+			v.report([]string{"externalDocs"}, err, "")
+		}
+	}
+
+	if len(v.problems) == 0 {
+		v.report(nil, err, "")
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// components collects the problems of the reusable components
+func (v *validator) components() {
+	c := v.doc.Components
+	path := []string{"components"}
+	start := len(v.problems)
+	defer func() {
+		for i := range v.problems[start:] {
+			v.problems[start+i].Suggestion = "fix the component, --lenient only skips operations"
+		}
+	}()
+	for _, name := range sortedKeys(c.Schemas) {
+		v.schema(at(path, "schemas", name), c.Schemas[name])
+	}
+	for _, name := range sortedKeys(c.Parameters) {
+		v.parameter(at(path, "parameters", name), c.Parameters[name])
+	}
+	for _, name := range sortedKeys(c.RequestBodies) {
+		v.requestBody(at(path, "requestBodies", name), c.RequestBodies[name])
+	}
+	for _, name := range sortedKeys(c.Responses) {
+		v.response(at(path, "responses", name), c.Responses[name])
+	}
+	for _, name := range sortedKeys(c.Headers) {
+		v.header(at(path, "headers", name), c.Headers[name])
+	}
+	for _, name := range sortedKeys(c.SecuritySchemes) {
+		if ref := c.SecuritySchemes[name]; ref != nil {
+			if err := ref.Validate(v.ctx); err != nil {
+				v.report(at(path, "securitySchemes", name), err, "")
+			}
+		}
+	}
+	// Names and the remaining kinds of components are checked as a whole
+	if err := c.Validate(v.ctx); err != nil && !v.has(path) {
+		v.report(path, err, "")
+	}
+}
+
+// operation returns the problems of an operation, and the error of its
+// validation on its own. Problems of the components it references are left
+// to components, so an operation can fail without problems of its own.
+func (v *validator) operation(op Operation) ([]ValidationProblem, error) {
+	problems := v.problems
+	v.problems = nil
+	defer func() { v.problems = problems }()
+
+	// Validate the operation within a copy of its path item holding only it,
+	// so that the path's parameters are checked too
+	item := *v.doc.Paths.Value(op.Path)
+	for method := range item.Operations() {
+		item.SetOperation(method, nil)
+	}
+	item.SetOperation(op.Method, op.Operation)
+	err := openapi3.NewPaths(openapi3.WithPath(op.Path, &item)).Validate(v.ctx)
+	if err == nil {
+		return nil, nil
+	}
+
+	suggestion := ""
+	if !v.lenient {
+		suggestion = fmt.Sprintf("use --lenient to skip %s %s", op.Method, op.Path)
+	}
+	path := []string{"paths", op.Path}
+	found := false
+	for i, parameter := range item.Parameters {
+		found = v.parameter(at(path, "parameters", strconv.Itoa(i)), parameter) || found
+	}
+	path = at(path, strings.ToLower(op.Method))
+	for i, parameter := range op.Operation.Parameters {
+		found = v.parameter(at(path, "parameters", strconv.Itoa(i)), parameter) || found
+	}
+	found = v.requestBody(at(path, "requestBody"), op.Operation.RequestBody) || found
+	if op.Operation.Responses != nil {
+		responses := op.Operation.Responses.Map()
+		for _, code := range sortedKeys(responses) {
+			found = v.response(at(path, "responses", code), responses[code]) || found
+		}
+	}
+	if !found {
+		if opErr := op.Operation.Validate(v.ctx); opErr != nil {
+			err = opErr
+		}
+		v.report(path, err, "")
+	}
+	for i := range v.problems {
+		v.problems[i].Suggestion = suggestion
+	}
+	return v.problems, err
+}
+
+// The following methods collect the problems of a piece of the document and
+// report whether it failed validation. Failures of references to components
+// are left to components, which reports them once.
+
+func (v *validator) parameter(path []string, ref *openapi3.ParameterRef) bool {
+	if ref == nil || ref.Value == nil {
+		return false
+	}
+	err := ref.Validate(v.ctx)
+	if err == nil || ref.Ref != "" {
+		return err != nil
+	}
+	found := v.schema(at(path, "schema"), ref.Value.Schema)
+	found = v.content(at(path, "content"), ref.Value.Content) || found
+	if !found {
+		v.report(path, err, "")
+	}
+	return true
+}
+
+func (v *validator) requestBody(path []string, ref *openapi3.RequestBodyRef) bool {
+	if ref == nil || ref.Value == nil {
+		return false
+	}
+	err := ref.Validate(v.ctx)
+	if err == nil || ref.Ref != "" {
+		return err != nil
+	}
+	if !v.content(at(path, "content"), ref.Value.Content) {
+		v.report(path, err, "")
+	}
+	return true
+}
+
+func (v *validator) response(path []string, ref *openapi3.ResponseRef) bool {
+	if ref == nil || ref.Value == nil {
+		return false
+	}
+	err := ref.Validate(v.ctx)
+	if err == nil || ref.Ref != "" {
+		return err != nil
+	}
+	found := v.content(at(path, "content"), ref.Value.Content)
+	for _, name := range sortedKeys(ref.Value.Headers) {
+		found = v.header(at(path, "headers", name), ref.Value.Headers[name]) || found
+	}
+	if !found {
+		v.report(path, err, "")
+	}
+	return true
+}
+
+func (v *validator) header(path []string, ref *openapi3.HeaderRef) bool {
+	if ref == nil || ref.Value == nil {
+		return false
+	}
+	err := ref.Validate(v.ctx)
+	if err == nil || ref.Ref != "" {
+		return err != nil
+	}
+	found := v.schema(at(path, "schema"), ref.Value.Schema)
+	found = v.content(at(path, "content"), ref.Value.Content) || found
+	if !found {
+		v.report(path, err, "")
+	}
+	return true
+}
+
+func (v *validator) content(path []string, content openapi3.Content) bool {
+	found := false
+	for _, mediaType := range sortedKeys(content) {
+		if content[mediaType] == nil {
+			continue
+		}
+		typePath := at(path, mediaType)
+		err := content[mediaType].Validate(v.ctx)
+		if err == nil {
+			continue
+		}
+		if !v.schema(at(typePath, "schema"), content[mediaType].Schema) {
+			v.report(typePath, err, "")
+		}
+		found = true
+	}
+	return found
+}
+
+func (v *validator) schema(path []string, ref *openapi3.SchemaRef) bool {
+	if ref == nil || ref.Value == nil {
+		return false
+	}
+	err := ref.Validate(v.ctx)
+	if err == nil || ref.Ref != "" {
+		return err != nil
+	}
+	schema := ref.Value
+	found := false
+	for _, name := range sortedKeys(schema.Properties) {
+		found = v.schema(at(path, "properties", name), schema.Properties[name]) || found
+	}
+	found = v.schema(at(path, "items"), schema.Items) || found
+	found = v.schema(at(path, "additionalProperties"), schema.AdditionalProperties.Schema) || found
+	found = v.schema(at(path, "not"), schema.Not) || found
+	for _, composition := range []struct {
+		keyword string
+		schemas openapi3.SchemaRefs
+	}{{"allOf", schema.AllOf}, {"anyOf", schema.AnyOf}, {"oneOf", schema.OneOf}} {
+		for i, child := range composition.schemas {
+			found = v.schema(at(path, composition.keyword, strconv.Itoa(i)), child) || found
+		}
+	}
+	if !found {
+		v.report(path, err, "")
+	}
+	return true
+}
+
+// report records a problem at the value of a path, once per path
+func (v *validator) report(path []string, err error, suggestion string) {
+	pointer := jsonPointer(path)
+	if v.reported[pointer] {
+		return
+	}
+	v.reported[pointer] = true
+	v.problems = append(v.problems, ValidationProblem{
+		Pointer:    pointer,
+		Value:      v.value(path),
+		Message:    err.Error(),
+		Suggestion: suggestion,
+	})
+}
+
+// has reports whether a problem was reported at or below a path
+func (v *validator) has(path []string) bool {
+	pointer := jsonPointer(path)
+	for reported := range v.reported {
+		if reported == pointer || strings.HasPrefix(reported, pointer+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the value of the raw document at a path as compact JSON,
+// truncated if long, or "" if the document has no value there
+func (v *validator) value(path []string) string {
+	value := v.raw
+	for _, key := range path {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return ""
+			}
+			value = node[i]
+		default:
+			return ""
+		}
+	}
+	if value == nil || len(path) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	if runes := []rune(string(data)); len(runes) > maxProblemValue {
+		return string(runes[:maxProblemValue]) + "..."
+	}
+	return string(data)
+}
+
+// jsonPointer returns the JSON pointer of a path of keys
+func jsonPointer(path []string) string {
+	var pointer strings.Builder
+	for _, key := range path {
+		pointer.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key))
+	}
+	return pointer.String()
+}
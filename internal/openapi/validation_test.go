@@ -0,0 +1,77 @@
+package openapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestValidationProblems(t *testing.T) {
+	spec := `{
+		"openapi": "3.0.0",
+		"info": {"title": "Pets", "version": "1.0.0"},
+		"paths": {
+			"/pets": {
+				"get": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}}}},
+				"post": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "object", "properties": {"tag": {"type": "unknown"}}}}}}}}
+			},
+			"/pets/{id}": {"get": {"responses": {"200": {"description": "OK"}}}}
+		},
+		"components": {"schemas": {"Pet": {"type": "array"}}}
+	}`
+	path := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewParser(zap.NewNop()).FetchAndParse(context.Background(), path)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("FetchAndParse() error = %v, want a ValidationError", err)
+	}
+	want := []ValidationProblem{{
+		Pointer:    "/components/schemas/Pet",
+		Value:      `{"type":"array"}`,
+		Message:    "when schema type is 'array', schema 'items' must be non-null",
+		Suggestion: "fix the component, --lenient only skips operations",
+	}, {
+		Pointer:    "/paths/~1pets/post/responses/200/content/application~1json/schema/properties/tag",
+		Value:      `{"type":"unknown"}`,
+		Message:    `unsupported 'type' value "unknown"`,
+		Suggestion: "use --lenient to skip POST /pets",
+	}, {
+		Pointer:    "/paths/~1pets~1{id}/get",
+		Value:      `{"responses":{"200":{"description":"OK"}}}`,
+		Message:    "operation GET /pets/{id} must define exactly all path parameters (missing: [id])",
+		Suggestion: "use --lenient to skip GET /pets/{id}",
+	}}
+	if len(validationErr.Problems) != len(want) {
+		t.Fatalf("problems = %v, want %v", validationErr.Problems, want)
+	}
+	for i := range want {
+		if validationErr.Problems[i] != want[i] {
+			t.Errorf("problem %d = %+v, want %+v", i, validationErr.Problems[i], want[i])
+		}
+	}
+}
+
+func TestValidationProblemValue(t *testing.T) {
+	v := newValidator(context.Background(), nil, []byte(`{"paths": {"/a/b": {"get": {"tags": ["x~y"]}}}, "long": "`+strings.Repeat("é", 200)+`"}`), false)
+	if got := v.value([]string{"paths", "/a/b", "get", "tags", "0"}); got != `"x~y"` {
+		t.Errorf("value() = %s, want \"x~y\"", got)
+	}
+	if got := v.value([]string{"paths", "/a/b", "post"}); got != "" {
+		t.Errorf("value() of a missing key = %s, want none", got)
+	}
+	if got := v.value([]string{"long"}); !strings.HasSuffix(got, "...") || len([]rune(got)) != maxProblemValue+3 {
+		t.Errorf("value() of a long string = %s, want it truncated", got)
+	}
+	if got := jsonPointer([]string{"paths", "/a~b"}); got != "/paths/~1a~0b" {
+		t.Errorf("jsonPointer() = %s, want /paths/~1a~0b", got)
+	}
+}
@@ -0,0 +1,186 @@
+// Package pii scans the results of tool calls for personal data, scrubbing it
+// or withholding the result before it reaches the model
+package pii
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+)
+
+// Actions taken on tool results containing PII
+const (
+	// ActionScrub replaces every match with a placeholder naming its entity
+	ActionScrub = "scrub"
+	// ActionBlock withholds the whole result
+	ActionBlock = "block"
+)
+
+// Built-in entity types
+const (
+	EntityEmail      = "EMAIL"
+	EntityPhone      = "PHONE"
+	EntitySSN        = "SSN"
+	EntityCreditCard = "CREDIT_CARD"
+	EntityIPAddress  = "IP_ADDRESS"
+	EntityIBAN       = "IBAN"
+)
+
+// Finding records how many matches of an entity type a result contained. The
+// matches themselves are never recorded.
+type Finding struct {
+	Entity string
+	Count  int
+}
+
+// BlockedError is returned for results withheld because they contain PII
+type BlockedError struct {
+	Findings []Finding
+}
+
+func (e *BlockedError) Error() string {
+	entities := make([]string, len(e.Findings))
+	for i, finding := range e.Findings {
+		entities[i] = finding.Entity
+	}
+	return fmt.Sprintf("tool result withheld: it contains personal data (%s)", strings.Join(entities, ", "))
+}
+
+// Scrubber scans tool results for personal data. Embedders can plug in their
+// own, e.g. one calling a Presidio analyzer.
+type Scrubber interface {
+	// Scrub returns the result with personal data removed and what was
+	// found, or a *BlockedError if the result must not be returned at all
+	Scrub(ctx context.Context, tool, text string) (string, []Finding, error)
+}
+
+// Pattern recognizes one entity type
+type Pattern struct {
+	Entity string
+	Regexp *regexp.Regexp
+	// Validate, if set, filters candidate matches, e.g. with a checksum
+	Validate func(match string) bool
+}
+
+// BuiltinPatterns returns the patterns of the built-in entity types
+func BuiltinPatterns() []Pattern {
+	return []Pattern{
+		{Entity: EntityEmail, Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+		{Entity: EntitySSN, Regexp: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+		{Entity: EntityCreditCard, Regexp: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Validate: luhn},
+		{Entity: EntityIBAN, Regexp: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`)},
+		{Entity: EntityPhone, Regexp: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`)},
+		{Entity: EntityIPAddress, Regexp: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+	}
+}
+
+// PatternScrubber is the built-in scrubber, recognizing personal data with
+// regular expressions
+type PatternScrubber struct {
+	patterns []Pattern
+	action   string
+}
+
+// NewPatternScrubber creates a scrubber taking action on matches of the
+// patterns, which are tried in order
+func NewPatternScrubber(action string, patterns []Pattern) (*PatternScrubber, error) {
+	if action != ActionScrub && action != ActionBlock {
+		return nil, fmt.Errorf("invalid PII action %q: must be %s or %s", action, ActionScrub, ActionBlock)
+	}
+	return &PatternScrubber{patterns: patterns, action: action}, nil
+}
+
+// Scrub replaces personal data with placeholders such as <EMAIL>, or
+// withholds the result if the action is ActionBlock
+func (s *PatternScrubber) Scrub(ctx context.Context, tool, text string) (string, []Finding, error) {
+	counts := make(map[string]int)
+	for _, pattern := range s.patterns {
+		text = pattern.Regexp.ReplaceAllStringFunc(text, func(match string) string {
+			if pattern.Validate != nil && !pattern.Validate(match) {
+				return match
+			}
+			counts[pattern.Entity]++
+			return "<" + pattern.Entity + ">"
+		})
+	}
+
+	findings := make([]Finding, 0, len(counts))
+	for entity, count := range counts {
+		findings = append(findings, Finding{Entity: entity, Count: count})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Entity < findings[j].Entity })
+
+	if len(findings) > 0 && s.action == ActionBlock {
+		return "", findings, &BlockedError{Findings: findings}
+	}
+	return text, findings, nil
+}
+
+// luhn reports whether the digits of a candidate card number pass the Luhn checksum
+func luhn(match string) bool {
+	sum, double := 0, false
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// FromConfig returns the scrubber configured by pii.action, nil if unset.
+// pii.entities restricts the built-in entity types, all by default, and
+// pii.patterns adds custom entity types as name: regular expression.
+func FromConfig() (Scrubber, error) {
+	action := config.GetString("pii.action")
+	if action == "" {
+		return nil, nil
+	}
+
+	var patterns []Pattern
+	entities := config.GetStringSlice("pii.entities")
+	for _, pattern := range BuiltinPatterns() {
+		if len(entities) == 0 || containsFold(entities, pattern.Entity) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	custom := config.GetStringMapString("pii.patterns")
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		re, err := regexp.Compile(custom[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern %s: %w", name, err)
+		}
+		patterns = append(patterns, Pattern{Entity: strings.ToUpper(name), Regexp: re})
+	}
+
+	return NewPatternScrubber(action, patterns)
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package pii
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestPatternScrubber(t *testing.T) {
+	scrubber, err := NewPatternScrubber(ActionScrub, BuiltinPatterns())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		findings []Finding
+	}{
+		{
+			name:     "email",
+			text:     `{"contact":"jane.doe@example.com","cc":"ops@example.org"}`,
+			want:     `{"contact":"<EMAIL>","cc":"<EMAIL>"}`,
+			findings: []Finding{{EntityEmail, 2}},
+		},
+		{
+			name:     "ssn and phone",
+			text:     `SSN 123-45-6789, call (555) 123-4567 or +1 555.123.4567`,
+			want:     `SSN <SSN>, call <PHONE> or <PHONE>`,
+			findings: []Finding{{EntityPhone, 2}, {EntitySSN, 1}},
+		},
+		{
+			name:     "credit cards pass the Luhn check",
+			text:     `card 4111 1111 1111 1111, order 1234567812345678`,
+			want:     `card <CREDIT_CARD>, order 1234567812345678`,
+			findings: []Finding{{EntityCreditCard, 1}},
+		},
+		{
+			name:     "iban and ip address",
+			text:     `pay DE89 3704 0044 0532 0130 00 from 192.168.1.20`,
+			want:     `pay <IBAN> from <IP_ADDRESS>`,
+			findings: []Finding{{EntityIBAN, 1}, {EntityIPAddress, 1}},
+		},
+		{
+			name: "clean",
+			text: `{"id":42,"name":"Rex","version":"1.2.3"}`,
+			want: `{"id":42,"name":"Rex","version":"1.2.3"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, findings, err := scrubber.Scrub(context.Background(), "tool", tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Scrub() = %s, want %s", got, tt.want)
+			}
+			if len(findings) != 0 || len(tt.findings) != 0 {
+				if !reflect.DeepEqual(findings, tt.findings) {
+					t.Errorf("findings = %v, want %v", findings, tt.findings)
+				}
+			}
+		})
+	}
+}
+
+func TestPatternScrubberBlock(t *testing.T) {
+	scrubber, _ := NewPatternScrubber(ActionBlock, BuiltinPatterns())
+
+	_, findings, err := scrubber.Scrub(context.Background(), "tool", `{"email":"jane@example.com","ssn":"123-45-6789"}`)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("expected the result to be blocked, got %v", err)
+	}
+	if len(findings) != 2 || err.Error() != "tool result withheld: it contains personal data (EMAIL, SSN)" {
+		t.Errorf("unexpected block %v with findings %v", err, findings)
+	}
+
+	if text, _, err := scrubber.Scrub(context.Background(), "tool", `{"id":1}`); err != nil || text != `{"id":1}` {
+		t.Errorf("expected clean results to pass, got %q, %v", text, err)
+	}
+
+	if _, err := NewPatternScrubber("drop", nil); err == nil {
+		t.Error("expected an invalid action to be rejected")
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if scrubber, err := FromConfig(); scrubber != nil || err != nil {
+		t.Fatalf("expected no scrubber by default, got %v, %v", scrubber, err)
+	}
+
+	viper.Set("pii.action", ActionScrub)
+	viper.Set("pii.entities", []string{"email"})
+	viper.Set("pii.patterns", map[string]interface{}{"employee_id": `EMP-\d{6}`})
+	scrubber, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _, _ := scrubber.Scrub(context.Background(), "tool", "jane@example.com EMP-004217 123-45-6789")
+	if text != "<EMAIL> <EMPLOYEE_ID> 123-45-6789" {
+		t.Errorf("unexpected scrubbed text %q", text)
+	}
+
+	viper.Set("pii.patterns", map[string]interface{}{"broken": `(`})
+	if _, err := FromConfig(); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+}
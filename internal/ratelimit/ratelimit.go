@@ -0,0 +1,147 @@
+// Package ratelimit limits how often serve mode's tools may be called, per
+// tool and across clients, so that a misbehaving model can't flood an
+// endpoint of the API
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// AllTools keys the limit of tools without one of their own
+const AllTools = "*"
+
+// Limiter allows each tool a number of calls per minute. Calls may come in
+// bursts of up to the limit, and are refused once it's used up until the
+// allowance refills.
+type Limiter struct {
+	mu      sync.Mutex
+	limits  map[string]int
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// bucket holds the calls a tool may make right now
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// New returns a limiter with limits in calls per minute, keyed by tool name
+// or AllTools
+func New(limits map[string]int) *Limiter {
+	l := &Limiter{limits: make(map[string]int), buckets: make(map[string]*bucket), now: time.Now}
+	for tool, perMinute := range limits {
+		l.limits[tool] = perMinute
+	}
+	return l
+}
+
+// FromConfig returns a limiter with the limits configured by rate_limits
+func FromConfig() (*Limiter, error) {
+	limits := make(map[string]int)
+	for tool, value := range config.GetStringMapString("rate_limits") {
+		perMinute, err := strconv.Atoi(value)
+		if err != nil || perMinute < 0 {
+			return nil, fmt.Errorf("invalid rate limit %v of %s: must be a number of calls per minute", value, tool)
+		}
+		limits[tool] = perMinute
+	}
+	return New(limits), nil
+}
+
+// Limit returns the calls per minute a tool is allowed, 0 if unlimited
+func (l *Limiter) Limit(tool string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit(tool)
+}
+
+func (l *Limiter) limit(tool string) int {
+	if perMinute, ok := l.limits[tool]; ok {
+		return perMinute
+	}
+	return l.limits[AllTools]
+}
+
+// Limits returns the configured limits, keyed by tool name or AllTools
+func (l *Limiter) Limits() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limits := make(map[string]int, len(l.limits))
+	for tool, perMinute := range l.limits {
+		limits[tool] = perMinute
+	}
+	return limits
+}
+
+// Set changes the calls per minute a tool, or AllTools, is allowed. A tool
+// with 0 falls back to the limit of all tools; AllTools with 0 is unlimited.
+func (l *Limiter) Set(tool string, perMinute int) error {
+	if perMinute < 0 {
+		return fmt.Errorf("invalid rate limit %d: must not be negative", perMinute)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if perMinute == 0 {
+		delete(l.limits, tool)
+	} else {
+		l.limits[tool] = perMinute
+	}
+	// Start over with the new allowance
+	if tool == AllTools {
+		l.buckets = make(map[string]*bucket)
+	} else {
+		delete(l.buckets, tool)
+	}
+	return nil
+}
+
+// Allow reports whether a tool may be called now, taking one call of its
+// allowance if so. Otherwise it returns how long until the next call is
+// allowed.
+func (l *Limiter) Allow(tool string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	perMinute := l.limit(tool)
+	if perMinute <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	b, ok := l.buckets[tool]
+	if !ok {
+		b = &bucket{tokens: float64(perMinute), updated: now}
+		l.buckets[tool] = b
+	}
+	perSecond := float64(perMinute) / 60
+	b.tokens = math.Min(float64(perMinute), b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Wrap returns a handler refusing the calls of next beyond their tool's
+// limit with an error result the model can act on
+func (l *Limiter) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if ok, retryAfter := l.Allow(request.Params.Name); !ok {
+			result := mcp.NewToolResultText(fmt.Sprintf("Rate limit of %d calls per minute exceeded for %s; retry in %s",
+				l.Limit(request.Params.Name), request.Params.Name, retryAfter.Truncate(time.Second)+time.Second))
+			result.IsError = true
+			return result, nil
+		}
+		return next(ctx, request)
+	}
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := New(map[string]int{"get_pets": 2, AllTools: 60})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("get_pets"); !ok {
+			t.Fatalf("call %d refused, want a burst of 2 allowed", i+1)
+		}
+	}
+	ok, retryAfter := l.Allow("get_pets")
+	if ok {
+		t.Fatal("third call allowed, want it refused")
+	}
+	if retryAfter != 30*time.Second {
+		t.Errorf("retry after = %s, want 30s", retryAfter)
+	}
+
+	// Other tools have their own allowance
+	if ok, _ := l.Allow("get_owners"); !ok {
+		t.Error("get_owners refused, want it allowed by the limit of all tools")
+	}
+
+	now = now.Add(30 * time.Second)
+	if ok, _ := l.Allow("get_pets"); !ok {
+		t.Error("call refused after 30s, want the allowance refilled")
+	}
+}
+
+func TestSet(t *testing.T) {
+	l := New(map[string]int{AllTools: 1})
+	if ok, _ := l.Allow("get_pets"); !ok {
+		t.Fatal("first call refused")
+	}
+	if ok, _ := l.Allow("get_pets"); ok {
+		t.Fatal("second call allowed, want it refused")
+	}
+
+	if err := l.Set("get_pets", 10); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := l.Allow("get_pets"); !ok {
+		t.Error("call refused after raising the limit")
+	}
+	if got := l.Limits(); got["get_pets"] != 10 || got[AllTools] != 1 {
+		t.Errorf("limits = %v, want get_pets 10 and * 1", got)
+	}
+
+	if err := l.Set(AllTools, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Limit("get_owners"); got != 0 {
+		t.Errorf("limit of get_owners = %d, want 0 once * is removed", got)
+	}
+	if err := l.Set("get_pets", -1); err == nil {
+		t.Error("negative limit accepted")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	l := New(map[string]int{"get_pets": 1})
+	calls := 0
+	handler := l.Wrap(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	var request mcp.CallToolRequest
+	request.Params.Name = "get_pets"
+	for i := 0; i < 2; i++ {
+		if _, err := handler(context.Background(), request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "1 calls per minute") {
+		t.Errorf("result = %+v, want a rate limit error", result)
+	}
+}
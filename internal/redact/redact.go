@@ -0,0 +1,113 @@
+// Package redact masks sensitive fields of the API responses tools return,
+// before they reach the model's context
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Mask replaces the values of redacted fields
+const Mask = "***"
+
+// Redactor masks the JSON fields matching a set of rules. A rule is a field
+// name, matching that field at any depth, or a dotted path such as
+// "customer.email", matching the field at the end of that path at any depth.
+// Array elements are traversed transparently and names match case-insensitively.
+type Redactor struct {
+	rules [][]string
+}
+
+// New creates a redactor for the given rules. It returns nil, which redacts
+// nothing, if there are none.
+func New(rules []string) *Redactor {
+	var parsed [][]string
+	for _, rule := range rules {
+		rule = strings.TrimSpace(strings.ToLower(rule))
+		if rule == "" {
+			continue
+		}
+		parsed = append(parsed, strings.Split(rule, "."))
+	}
+	if len(parsed) == 0 {
+		return nil
+	}
+	return &Redactor{rules: parsed}
+}
+
+// Redact returns the body with matching fields masked. Bodies that aren't
+// JSON or contain no matching field are returned unchanged.
+func (r *Redactor) Redact(body []byte) []byte {
+	if r == nil {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+	redacted, changed := r.redact(value, nil)
+	if !changed {
+		return body
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redacted); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// redact masks the matching fields of a decoded JSON value found at path
+func (r *Redactor) redact(value interface{}, path []string) (interface{}, bool) {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			fieldPath := append(path[:len(path):len(path)], strings.ToLower(key))
+			if r.matches(fieldPath) {
+				v[key] = Mask
+				changed = true
+				continue
+			}
+			if redacted, ok := r.redact(field, fieldPath); ok {
+				v[key] = redacted
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, element := range v {
+			if redacted, ok := r.redact(element, path); ok {
+				v[i] = redacted
+				changed = true
+			}
+		}
+	}
+	return value, changed
+}
+
+// matches reports whether a rule matches the trailing names of a field's path
+func (r *Redactor) matches(path []string) bool {
+	for _, rule := range r.rules {
+		if len(rule) > len(path) {
+			continue
+		}
+		tail := path[len(path)-len(rule):]
+		matched := true
+		for i := range rule {
+			if rule[i] != tail[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
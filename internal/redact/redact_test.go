@@ -0,0 +1,61 @@
+package redact
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	redactor := New([]string{"ssn", "credit_card", "customer.email", " "})
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "fields at any depth",
+			body: `{"id":1,"ssn":"123-45-6789","payment":{"Credit_Card":{"number":"4111"}}}`,
+			want: `{"id":1,"payment":{"Credit_Card":"***"},"ssn":"***"}`,
+		},
+		{
+			name: "dotted path",
+			body: `{"email":"ops@example.com","customer":{"email":"jane@example.com","name":"Jane"}}`,
+			want: `{"customer":{"email":"***","name":"Jane"},"email":"ops@example.com"}`,
+		},
+		{
+			name: "arrays",
+			body: `[{"order":{"customer":{"email":"a@example.com"}}},{"customer":[{"email":"b@example.com"}]}]`,
+			want: `[{"order":{"customer":{"email":"***"}}},{"customer":[{"email":"***"}]}]`,
+		},
+		{
+			name: "numbers keep their precision",
+			body: `{"ssn":123456789,"id":12345678901234567890}`,
+			want: `{"id":12345678901234567890,"ssn":"***"}`,
+		},
+		{
+			name: "no match is unchanged",
+			body: `{ "id": 1, "name": "Rex" }`,
+			want: `{ "id": 1, "name": "Rex" }`,
+		},
+		{
+			name: "not JSON",
+			body: `ssn=123-45-6789`,
+			want: `ssn=123-45-6789`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(redactor.Redact([]byte(tt.body))); got != tt.want {
+				t.Errorf("Redact() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWithoutRules(t *testing.T) {
+	redactor := New([]string{"", "  "})
+	if redactor != nil {
+		t.Fatalf("New() = %+v, want nil", redactor)
+	}
+	if got := string(redactor.Redact([]byte(`{"ssn":"1"}`))); got != `{"ssn":"1"}` {
+		t.Errorf("nil Redactor changed the body to %s", got)
+	}
+}
@@ -0,0 +1,208 @@
+// Package script runs the Starlark scripts defining or overriding tools of
+// serve mode. Scripts are sandboxed: they have no access to files, the network
+// or the clock, only to the json module and to the generated tools through call.
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/syntax"
+)
+
+// MaxSteps bounds the computation of a single tool call of a script, so that
+// a runaway loop can't hang the server
+const MaxSteps = 10_000_000
+
+// Argument types of the tools scripts define
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// Argument is an argument of a tool defined by a script
+type Argument struct {
+	Name        string `mapstructure:"name"`
+	Type        string `mapstructure:"type"`
+	Description string `mapstructure:"description"`
+	Required    bool   `mapstructure:"required"`
+}
+
+// Definition configures a script: the tool it defines, or overrides if the
+// spec already has a tool of that name, and the file defining its handler
+type Definition struct {
+	Tool        string `mapstructure:"tool"`
+	File        string `mapstructure:"file"`
+	Description string `mapstructure:"description"`
+	// Arguments replace those of an overridden tool
+	Arguments []Argument `mapstructure:"arguments"`
+}
+
+// Caller calls a generated tool on behalf of a script and returns its result
+type Caller func(ctx context.Context, tool string, args map[string]interface{}) (string, error)
+
+// Script is a loaded script, whose handler can run concurrently
+type Script struct {
+	Definition
+	handle *starlark.Function
+}
+
+// FromConfig reads the scripts configured under scripts
+func FromConfig() ([]Definition, error) {
+	var definitions []Definition
+	if err := viper.UnmarshalKey("scripts", &definitions); err != nil {
+		return nil, fmt.Errorf("invalid scripts configuration: %w", err)
+	}
+	for i, definition := range definitions {
+		if definition.Tool == "" || definition.File == "" {
+			return nil, fmt.Errorf("invalid script %d: a tool and a file are required", i+1)
+		}
+		for _, argument := range definition.Arguments {
+			switch argument.Type {
+			case TypeString, TypeNumber, TypeBoolean:
+			default:
+				return nil, fmt.Errorf("invalid argument %s of script tool %s: type must be %s, %s or %s", argument.Name, definition.Tool, TypeString, TypeNumber, TypeBoolean)
+			}
+		}
+		definitions[i].Tool = strings.ToLower(definition.Tool)
+	}
+	return definitions, nil
+}
+
+// Load reads and executes the file of a script, which must define a
+// handle(args) function
+func Load(definition Definition) (*Script, error) {
+	src, err := os.ReadFile(definition.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script of tool %s: %w", definition.Tool, err)
+	}
+
+	thread := &starlark.Thread{Name: definition.Tool, Load: noLoad}
+	thread.SetMaxExecutionSteps(MaxSteps)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, definition.File, src, predeclared())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load script of tool %s: %w", definition.Tool, err)
+	}
+
+	handle, ok := globals["handle"].(*starlark.Function)
+	if !ok || handle.NumParams() != 1 {
+		return nil, fmt.Errorf("script of tool %s must define handle(args)", definition.Tool)
+	}
+	globals.Freeze()
+	return &Script{Definition: definition, handle: handle}, nil
+}
+
+// Run calls the script's handler with the arguments of a tool call. A string
+// result is returned as is, any other result as JSON. Scripts fail a call with
+// fail(message).
+func (s *Script) Run(ctx context.Context, args map[string]interface{}, call Caller) (string, error) {
+	thread := &starlark.Thread{Name: s.Tool, Load: noLoad}
+	thread.SetMaxExecutionSteps(MaxSteps)
+	thread.SetLocal(callerKey, caller{ctx: ctx, call: call})
+
+	// Stop the script when the call is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	value, err := fromJSON(thread, args)
+	if err != nil {
+		return "", err
+	}
+	result, err := starlark.Call(thread, s.handle, starlark.Tuple{value}, nil)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return "", fmt.Errorf("script of tool %s failed: %s", s.Tool, evalErr.Backtrace())
+		}
+		return "", fmt.Errorf("script of tool %s failed: %w", s.Tool, err)
+	}
+
+	if text, ok := starlark.AsString(result); ok {
+		return text, nil
+	}
+	encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{result}, nil)
+	if err != nil {
+		return "", fmt.Errorf("script of tool %s returned a value that can't be encoded as JSON: %w", s.Tool, err)
+	}
+	return string(encoded.(starlark.String)), nil
+}
+
+// callerKey is the thread-local key of the caller of a tool call
+const callerKey = "mcprox.caller"
+
+// caller is how the call builtin of a tool call's thread reaches the tools
+type caller struct {
+	ctx  context.Context
+	call Caller
+}
+
+// predeclared returns the names every script can use
+func predeclared() starlark.StringDict {
+	return starlark.StringDict{
+		"json": starlarkjson.Module,
+		"call": starlark.NewBuiltin("call", callBuiltin),
+	}
+}
+
+// callBuiltin implements call(tool, args={}), which calls a generated tool and
+// returns its result as a string
+func callBuiltin(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var tool string
+	var arguments *starlark.Dict
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "tool", &tool, "args?", &arguments); err != nil {
+		return nil, err
+	}
+	c, ok := thread.Local(callerKey).(caller)
+	if !ok {
+		return nil, fmt.Errorf("%s: tools can only be called while handling a tool call", fn.Name())
+	}
+
+	toolArgs := map[string]interface{}{}
+	if arguments != nil {
+		encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{arguments}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(encoded.(starlark.String)), &toolArgs); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := c.call(c.ctx, tool, toolArgs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.Name(), err)
+	}
+	return starlark.String(result), nil
+}
+
+// fromJSON converts a JSON value into the equivalent Starlark value
+func fromJSON(thread *starlark.Thread, v interface{}) (starlark.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(data)}, nil)
+}
+
+// noLoad refuses load statements, so scripts can't read other files
+func noLoad(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+	return nil, fmt.Errorf("cannot load %s: scripts can't load modules", module)
+}
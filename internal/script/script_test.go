@@ -0,0 +1,124 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tool.star")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	viper.Set("scripts", []map[string]interface{}{
+		{"tool": "Greet", "file": "greet.star", "arguments": []map[string]interface{}{{"name": "name", "type": "string", "required": true}}},
+	})
+	definitions, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) != 1 || definitions[0].Tool != "greet" || !definitions[0].Arguments[0].Required {
+		t.Errorf("unexpected definitions %+v", definitions)
+	}
+
+	viper.Set("scripts", []map[string]interface{}{{"tool": "greet"}})
+	if _, err := FromConfig(); err == nil {
+		t.Error("expected an error for a script without a file")
+	}
+
+	viper.Set("scripts", []map[string]interface{}{
+		{"tool": "greet", "file": "greet.star", "arguments": []map[string]interface{}{{"name": "tags", "type": "array"}}},
+	})
+	if _, err := FromConfig(); err == nil {
+		t.Error("expected an error for an unsupported argument type")
+	}
+}
+
+func TestRun(t *testing.T) {
+	path := writeScript(t, `
+def handle(args):
+    pets = json.decode(call("get_pets", {"limit": args["limit"]}))
+    return {"count": len(pets), "first": pets[0]["name"]}
+`)
+	s, err := Load(Definition{Tool: "summary", File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var called map[string]interface{}
+	call := func(_ context.Context, tool string, args map[string]interface{}) (string, error) {
+		if tool != "get_pets" {
+			return "", errors.New("unexpected tool " + tool)
+		}
+		called = args
+		return `[{"name":"Rex"},{"name":"Tom"}]`, nil
+	}
+	result, err := s.Run(context.Background(), map[string]interface{}{"limit": 2}, call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != `{"count":2,"first":"Rex"}` {
+		t.Errorf("result = %s", result)
+	}
+	if called["limit"] != float64(2) {
+		t.Errorf("called with %v", called)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	call := func(context.Context, string, map[string]interface{}) (string, error) {
+		return "", errors.New("upstream unavailable")
+	}
+
+	for name, src := range map[string]string{
+		"fail":      "def handle(args):\n    fail(\"no pets\")\n",
+		"call":      "def handle(args):\n    return call(\"get_pets\")\n",
+		"unbounded": "def handle(args):\n    n = 0\n    for i in range(1000000000):\n        n += i\n    return n\n",
+	} {
+		s, err := Load(Definition{Tool: name, File: writeScript(t, src)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Run(context.Background(), nil, call); err == nil || !strings.Contains(err.Error(), "script of tool "+name+" failed") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	s, err := Load(Definition{Tool: "spin", File: writeScript(t, "def handle(args):\n    for i in range(1000000000):\n        pass\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Run(ctx, nil, nil); err == nil {
+		t.Error("expected a cancelled call to fail")
+	}
+}
+
+func TestLoadSandbox(t *testing.T) {
+	for name, src := range map[string]string{
+		"no handler": "x = 1\n",
+		"load":       "load(\"other.star\", \"f\")\ndef handle(args):\n    return f(args)\n",
+		"open":       "def handle(args):\n    return open(\"/etc/passwd\")\n",
+	} {
+		if _, err := Load(Definition{Tool: "t", File: writeScript(t, src)}); err == nil {
+			t.Errorf("%s: expected the script to be rejected", name)
+		}
+	}
+}
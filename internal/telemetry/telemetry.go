@@ -0,0 +1,216 @@
+// Package telemetry reports coarse, anonymous usage events when enabled with
+// the telemetry configuration key. Nothing is collected or sent otherwise.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/version"
+	"go.uber.org/zap"
+)
+
+// sendTimeout bounds how long reporting an event may delay mcprox's exit
+const sendTimeout = 2 * time.Second
+
+// Error classes reported instead of error messages
+const (
+	ClassNetwork    = "network"
+	ClassTimeout    = "timeout"
+	ClassFilesystem = "filesystem"
+	ClassSpec       = "spec"
+	ClassOther      = "other"
+)
+
+// Event is everything reported about one mcprox invocation. It deliberately
+// carries no identifiers, URLs, paths or error messages.
+type Event struct {
+	Command    string `json:"command"`
+	Version    string `json:"version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	Target     string `json:"target,omitempty"`
+	SpecSize   string `json:"spec_size,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"`
+	Duration   string `json:"duration"`
+}
+
+var (
+	mu       sync.Mutex
+	target   string
+	specSize string
+)
+
+// Enabled reports whether telemetry was opted into
+func Enabled() bool {
+	return config.GetBool("telemetry")
+}
+
+// SetTarget records the kind of server the invocation produced, e.g. python
+// for generated servers or serve for serve mode
+func SetTarget(t string) {
+	mu.Lock()
+	defer mu.Unlock()
+	target = t
+}
+
+// SetSpecSize records the number of operations of the loaded OpenAPI
+// documentation, which is reported as a bucket
+func SetSpecSize(operations int) {
+	mu.Lock()
+	defer mu.Unlock()
+	specSize = SizeBucket(operations)
+}
+
+// SizeBucket returns the coarse bucket a number of operations falls into
+func SizeBucket(operations int) string {
+	switch {
+	case operations < 10:
+		return "0-9"
+	case operations < 50:
+		return "10-49"
+	case operations < 200:
+		return "50-199"
+	case operations < 1000:
+		return "200-999"
+	default:
+		return "1000+"
+	}
+}
+
+// classError attaches an error class to an error
+type classError struct {
+	err   error
+	class string
+}
+
+func (e *classError) Error() string { return e.err.Error() }
+func (e *classError) Unwrap() error { return e.err }
+
+// WithClass marks err as belonging to class, unless a more specific class
+// such as a network error applies
+func WithClass(err error, class string) error {
+	if err == nil {
+		return nil
+	}
+	return &classError{err: err, class: class}
+}
+
+// Classify returns the class of an error, empty for nil
+func Classify(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ClassTimeout
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return ClassNetwork
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return ClassFilesystem
+	}
+	var classErr *classError
+	if errors.As(err, &classErr) {
+		return classErr.class
+	}
+	return ClassOther
+}
+
+// NewEvent returns the event of an invocation of command that took duration
+// and failed with err, if not nil
+func NewEvent(command string, duration time.Duration, err error) Event {
+	mu.Lock()
+	defer mu.Unlock()
+	return Event{
+		Command:    command,
+		Version:    version.Get(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Target:     target,
+		SpecSize:   specSize,
+		ErrorClass: Classify(err),
+		Duration:   durationBucket(duration),
+	}
+}
+
+// durationBucket returns the coarse bucket a duration falls into
+func durationBucket(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return "<1s"
+	case d < 10*time.Second:
+		return "1-10s"
+	case d < time.Minute:
+		return "10-60s"
+	default:
+		return ">1m"
+	}
+}
+
+// Report sends an event to the configured telemetry_endpoint if telemetry is
+// enabled. The payload is logged at debug level first, so users can see
+// exactly what is sent. Failures are logged and otherwise ignored.
+func Report(ctx context.Context, event Event, logger *zap.Logger) {
+	if !Enabled() {
+		return
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(event); err != nil {
+		return
+	}
+	payload := bytes.TrimSpace(buf.Bytes())
+	endpoint := config.GetString("telemetry_endpoint")
+	logger.Debug("Telemetry event", zap.ByteString("payload", payload), zap.String("endpoint", endpoint))
+	if endpoint == "" {
+		logger.Debug("No telemetry_endpoint configured; telemetry event not sent")
+		return
+	}
+
+	if err := send(ctx, endpoint, payload); err != nil {
+		logger.Debug("Failed to send telemetry event", zap.Error(err))
+	}
+}
+
+// send posts a payload to the telemetry endpoint
+func send(ctx context.Context, endpoint string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mcprox/"+version.Get())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestReport(t *testing.T) {
+	received := make(chan Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("invalid payload %s: %v", body, err)
+		}
+		received <- event
+	}))
+	defer srv.Close()
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("telemetry_endpoint", srv.URL)
+
+	SetTarget("python")
+	SetSpecSize(42)
+	event := NewEvent("mcprox generate", 3*time.Second, WithClass(errors.New("bad spec"), ClassSpec))
+
+	// Disabled by default: nothing is sent
+	Report(context.Background(), event, zap.NewNop())
+	select {
+	case <-received:
+		t.Fatal("event sent without telemetry enabled")
+	default:
+	}
+
+	viper.Set("telemetry", true)
+	Report(context.Background(), event, zap.NewNop())
+	select {
+	case got := <-received:
+		want := Event{Command: "mcprox generate", Version: got.Version, OS: got.OS, Arch: got.Arch, Target: "python", SpecSize: "10-49", ErrorClass: ClassSpec, Duration: "1-10s"}
+		if got != want {
+			t.Errorf("sent event = %+v, want %+v", got, want)
+		}
+	default:
+		t.Fatal("no event sent with telemetry enabled")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{errors.New("boom"), ClassOther},
+		{fmt.Errorf("fetch: %w", &url.Error{Op: "Get", URL: "http://x", Err: errors.New("connection refused")}), ClassNetwork},
+		{fmt.Errorf("fetch: %w", context.DeadlineExceeded), ClassTimeout},
+		{&os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}, ClassFilesystem},
+		{WithClass(errors.New("invalid"), ClassSpec), ClassSpec},
+		{WithClass(&url.Error{Op: "Get", URL: "http://x", Err: errors.New("refused")}, ClassSpec), ClassNetwork},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSizeBucket(t *testing.T) {
+	for operations, want := range map[int]string{0: "0-9", 9: "0-9", 10: "10-49", 199: "50-199", 200: "200-999", 5000: "1000+"} {
+		if got := SizeBucket(operations); got != want {
+			t.Errorf("SizeBucket(%d) = %q, want %q", operations, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package tenant lets one serve mode instance host several tenants, each with
+// its own API key for the MCP endpoint and its own upstream service
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// APIKeyHeader carries a tenant's API key, as an alternative to an
+// Authorization: Bearer header
+const APIKeyHeader = "X-API-Key"
+
+// validName matches tenant names, which are used as path prefixes
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Tenant is one customer of a multi-tenant serve mode instance
+type Tenant struct {
+	// Name is the path prefix of the tenant's endpoints, e.g. /acme/sse
+	Name string `mapstructure:"name"`
+	// APIKey authenticates the tenant's MCP clients
+	APIKey string `mapstructure:"api_key"`
+	// ServiceURL is the tenant's upstream base URL, overriding service.url
+	ServiceURL string `mapstructure:"service_url"`
+	// Authorization is the tenant's upstream Authorization header, overriding
+	// the configured credentials
+	Authorization string `mapstructure:"authorization"`
+}
+
+// FromConfig reads the tenants listed under the tenants key, checking that
+// each has a unique name and API key
+func FromConfig() ([]Tenant, error) {
+	var tenants []Tenant
+	if err := viper.UnmarshalKey("tenants", &tenants); err != nil {
+		return nil, fmt.Errorf("invalid tenants configuration: %w", err)
+	}
+
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for i, t := range tenants {
+		if !validName.MatchString(t.Name) {
+			return nil, fmt.Errorf("invalid tenant %d: name %q must be lowercase letters, digits, - and _", i+1, t.Name)
+		}
+		if t.APIKey == "" {
+			return nil, fmt.Errorf("invalid tenant %s: an api_key is required", t.Name)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("duplicate tenant %s", t.Name)
+		}
+		if keys[t.APIKey] {
+			return nil, fmt.Errorf("invalid tenant %s: its api_key is already used by another tenant", t.Name)
+		}
+		names[t.Name] = true
+		keys[t.APIKey] = true
+	}
+	return tenants, nil
+}
+
+// Authenticates reports whether key is the tenant's API key
+func (t Tenant) Authenticates(key string) bool {
+	return key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(t.APIKey)) == 1
+}
+
+// PresentedKey returns the API key of a request, from its X-API-Key header or
+// else its Authorization: Bearer header
+func PresentedKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// ByKey returns the tenant whose API key is key
+func ByKey(tenants []Tenant, key string) (Tenant, bool) {
+	for _, t := range tenants {
+		if t.Authenticates(key) {
+			return t, true
+		}
+	}
+	return Tenant{}, false
+}
+
+type contextKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant of a request
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant stored by WithTenant
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(Tenant)
+	return t, ok
+}
@@ -0,0 +1,60 @@
+package tenant
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	viper.Set("tenants", []map[string]interface{}{
+		{"name": "acme", "api_key": "acme-key", "service_url": "https://acme.example.com"},
+		{"name": "globex", "api_key": "globex-key"},
+	})
+	tenants, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants) != 2 || tenants[0].ServiceURL != "https://acme.example.com" {
+		t.Errorf("unexpected tenants %+v", tenants)
+	}
+
+	for name, tenants := range map[string][]map[string]interface{}{
+		"invalid name":   {{"name": "Acme Corp", "api_key": "k"}},
+		"missing key":    {{"name": "acme"}},
+		"duplicate name": {{"name": "acme", "api_key": "a"}, {"name": "acme", "api_key": "b"}},
+		"duplicate key":  {{"name": "acme", "api_key": "k"}, {"name": "globex", "api_key": "k"}},
+	} {
+		viper.Set("tenants", tenants)
+		if _, err := FromConfig(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPresentedKey(t *testing.T) {
+	tenants := []Tenant{{Name: "acme", APIKey: "acme-key"}, {Name: "globex", APIKey: "globex-key"}}
+	for header, want := range map[string]string{
+		APIKeyHeader:    "globex",
+		"Authorization": "acme",
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "http://localhost/sse", nil)
+		if header == APIKeyHeader {
+			r.Header.Set(header, "globex-key")
+		} else {
+			r.Header.Set(header, "Bearer acme-key")
+		}
+		if got, ok := ByKey(tenants, PresentedKey(r)); !ok || got.Name != want {
+			t.Errorf("%s: tenant = %q, want %s", header, got.Name, want)
+		}
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, "http://localhost/sse", nil)
+	if _, ok := ByKey(tenants, PresentedKey(r)); ok {
+		t.Error("a request without a key matched a tenant")
+	}
+}
@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// FieldsArgument is the argument of GET tools naming the fields of the
+// response to return, when response.fields is set
+const FieldsArgument = "fields"
+
+// ParseFields splits the value of the fields argument, comma separated names
+// or dotted paths such as items.id, into paths
+func ParseFields(value string) [][]string {
+	var paths [][]string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(strings.ToLower(field))
+		if field == "" {
+			continue
+		}
+		paths = append(paths, strings.Split(field, "."))
+	}
+	return paths
+}
+
+// SelectFields returns a JSON body with only the fields at paths, from the
+// root, kept. Array elements are traversed transparently, so "id" keeps the id
+// of every element of a list, and names match case-insensitively. A field
+// whose value is an object or array is kept whole. Bodies that aren't JSON,
+// or that have none of the fields, are returned unchanged.
+func SelectFields(body []byte, paths [][]string) []byte {
+	if len(paths) == 0 {
+		return body
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+	selected, found := selectFields(value, paths)
+	if !found {
+		return body
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(selected); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// selectFields keeps the fields at paths of a decoded JSON value, reporting
+// whether any was found
+func selectFields(value interface{}, paths [][]string) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		selected := make(map[string]interface{})
+		found := false
+		for key, field := range v {
+			var rest [][]string
+			whole := false
+			for _, path := range paths {
+				if path[0] != strings.ToLower(key) {
+					continue
+				}
+				if len(path) == 1 {
+					whole = true
+				} else {
+					rest = append(rest, path[1:])
+				}
+			}
+			if whole {
+				selected[key] = field
+				found = true
+			} else if len(rest) > 0 {
+				if nested, ok := selectFields(field, rest); ok {
+					selected[key] = nested
+					found = true
+				}
+			}
+		}
+		return selected, found
+	case []interface{}:
+		selected := make([]interface{}, len(v))
+		found := false
+		for i, element := range v {
+			nested, ok := selectFields(element, paths)
+			selected[i] = nested
+			found = found || ok
+		}
+		return selected, found
+	}
+	return value, false
+}
@@ -0,0 +1,59 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	got := ParseFields(" id, Owner.Email ,,")
+	want := [][]string{{"id"}, {"owner", "email"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFields() = %v, want %v", got, want)
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	for name, test := range map[string]struct {
+		body   string
+		fields string
+		want   string
+	}{
+		"top level": {
+			body:   `{"id": 1, "name": "Rex", "tag": "dog"}`,
+			fields: "id,name",
+			want:   `{"id":1,"name":"Rex"}`,
+		},
+		"list": {
+			body:   `[{"id": 1, "name": "Rex"}, {"id": 2, "name": "Tom"}]`,
+			fields: "name",
+			want:   `[{"name":"Rex"},{"name":"Tom"}]`,
+		},
+		"nested path": {
+			body:   petsResponse,
+			fields: "total,pets.name,pets.owner.email",
+			want:   `{"pets":[{"name":"Rex","owner":{"email":"jane@example.com"}},{"name":"Tom"},{"name":"Kit"}],"total":3}`,
+		},
+		"whole object": {
+			body:   `{"id": 1, "owner": {"name": "Jane", "email": "jane@example.com"}}`,
+			fields: "OWNER",
+			want:   `{"owner":{"email":"jane@example.com","name":"Jane"}}`,
+		},
+		"no field found": {
+			body:   `{"id": 1}`,
+			fields: "name",
+			want:   `{"id": 1}`,
+		},
+		"not json": {
+			body:   `plain text`,
+			fields: "id",
+			want:   `plain text`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := string(SelectFields([]byte(test.body), ParseFields(test.fields))); got != test.want {
+				t.Errorf("SelectFields() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package transform
+
+import (
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// The arguments pagination parameters are presented as, when
+// pagination.normalize is set
+const (
+	PageArgument   = "page"
+	LimitArgument  = "limit"
+	OffsetArgument = "offset"
+	CursorArgument = "cursor"
+)
+
+// paginationNames are the spellings of the query parameters of common
+// pagination schemes, lower-cased without separators, by the argument they
+// are presented as
+var paginationNames = map[string][]string{
+	PageArgument:   {"page", "pagenumber", "pagenum", "pageno", "pageindex"},
+	LimitArgument:  {"limit", "perpage", "pagesize", "size", "maxresults", "maxitems"},
+	OffsetArgument: {"offset", "skip", "startindex"},
+	CursorArgument: {"cursor", "after", "nexttoken", "pagetoken", "continuationtoken", "startingafter", "marker"},
+}
+
+// paginationDescriptions are the descriptions of the pagination arguments
+var paginationDescriptions = map[string]string{
+	PageArgument:   "Page of results to return",
+	LimitArgument:  "Maximum number of results to return per page",
+	OffsetArgument: "Number of results to skip before the first one returned",
+	CursorArgument: "Opaque cursor from the previous page of results, to return the next page; empty for the first page",
+}
+
+// PaginationArgument returns the argument a query parameter is presented as
+// if its name and type are those of a common pagination parameter, e.g.
+// "limit" for per_page or pageSize. Pages, limits and offsets are numbers,
+// cursors strings.
+func PaginationArgument(name, schemaType string) (string, bool) {
+	normalized := strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(name))
+	for argument, names := range paginationNames {
+		for _, candidate := range names {
+			if normalized != candidate {
+				continue
+			}
+			numeric := schemaType == "integer" || schemaType == "number"
+			if numeric == (argument != CursorArgument) {
+				return argument, true
+			}
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// PaginationDescription returns the description of a pagination argument,
+// followed by the upstream one if any, so that every tool explains its
+// pagination the same way
+func PaginationDescription(argument, upstream string) string {
+	description := paginationDescriptions[argument]
+	if upstream == "" || upstream == description {
+		return description
+	}
+	return description + ". " + upstream
+}
+
+// Paginated returns the rule with the pagination query parameters among
+// params renamed to their argument. Parameters the rule renames or hides, and
+// those whose argument is already taken by another parameter, keep their name.
+func (r RequestRule) Paginated(params openapi3.Parameters) RequestRule {
+	taken := make(map[string]bool, len(params))
+	for _, paramRef := range params {
+		if paramRef != nil && paramRef.Value != nil {
+			taken[r.Argument(paramRef.Value.Name)] = true
+		}
+	}
+
+	paginated := r
+	paginated.Rename = append([]Rename(nil), r.Rename...)
+	for _, paramRef := range params {
+		if paramRef == nil || paramRef.Value == nil || paramRef.Value.In != openapi3.ParameterInQuery ||
+			paramRef.Value.Schema == nil || paramRef.Value.Schema.Value == nil {
+			continue
+		}
+		name := paramRef.Value.Name
+		argument, ok := PaginationArgument(name, paramRef.Value.Schema.Value.Type)
+		if !ok || argument == name || taken[argument] || r.Argument(name) != name || r.Hidden(name) {
+			continue
+		}
+		paginated.Rename = append(paginated.Rename, Rename{Field: name, Argument: argument})
+		taken[argument] = true
+	}
+	return paginated
+}
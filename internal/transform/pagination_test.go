@@ -0,0 +1,72 @@
+package transform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestPaginationArgument(t *testing.T) {
+	for _, test := range []struct {
+		name, schemaType string
+		want             string
+	}{
+		{"per_page", "integer", LimitArgument},
+		{"pageSize", "integer", LimitArgument},
+		{"page-number", "integer", PageArgument},
+		{"skip", "number", OffsetArgument},
+		{"next_token", "string", CursorArgument},
+		{"startingAfter", "string", CursorArgument},
+		{"page", "string", ""},
+		{"after", "integer", ""},
+		{"sort", "string", ""},
+	} {
+		if got, _ := PaginationArgument(test.name, test.schemaType); got != test.want {
+			t.Errorf("PaginationArgument(%s, %s) = %q, want %q", test.name, test.schemaType, got, test.want)
+		}
+	}
+}
+
+func TestPaginated(t *testing.T) {
+	query := func(name, schemaType string) *openapi3.ParameterRef {
+		return &openapi3.ParameterRef{Value: &openapi3.Parameter{
+			Name:   name,
+			In:     openapi3.ParameterInQuery,
+			Schema: &openapi3.SchemaRef{Value: &openapi3.Schema{Type: schemaType}},
+		}}
+	}
+	params := openapi3.Parameters{
+		query("per_page", "integer"),
+		query("limit", "integer"),
+		query("pageNumber", "integer"),
+		query("nextToken", "string"),
+		query("skip", "integer"),
+		{Value: &openapi3.Parameter{Name: "page_token", In: openapi3.ParameterInHeader, Schema: &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "string"}}}},
+	}
+	rule := RequestRule{
+		Rename: []Rename{{Field: "nextToken", Argument: "token"}},
+		Drop:   []string{"skip"},
+	}
+
+	got := rule.Paginated(params)
+	want := []Rename{
+		{Field: "nextToken", Argument: "token"},
+		{Field: "pageNumber", Argument: PageArgument},
+	}
+	if !reflect.DeepEqual(got.Rename, want) {
+		t.Errorf("renames = %+v, want %+v", got.Rename, want)
+	}
+	if len(rule.Rename) != 1 {
+		t.Errorf("Paginated changed the rule's renames: %+v", rule.Rename)
+	}
+}
+
+func TestPaginationDescription(t *testing.T) {
+	if got := PaginationDescription(LimitArgument, ""); got != "Maximum number of results to return per page" {
+		t.Errorf("description = %q", got)
+	}
+	if got := PaginationDescription(LimitArgument, "At most 100"); got != "Maximum number of results to return per page. At most 100" {
+		t.Errorf("description with the upstream one = %q", got)
+	}
+}
@@ -0,0 +1,259 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+// PythonHelpers are the helper functions translated expressions call, which
+// the generated module must define once
+const PythonHelpers = `import math
+import re
+
+
+def _cel_div(a: Any, b: Any) -> Any:
+    """Divide like CEL: integer division truncates toward zero."""
+    if isinstance(a, int) and isinstance(b, int):
+        quotient = abs(a) // abs(b)
+        return quotient if (a >= 0) == (b >= 0) else -quotient
+    return a / b
+
+
+def _cel_mod(a: Any, b: Any) -> Any:
+    """Take the remainder like CEL, with the sign of the dividend."""
+    if isinstance(a, int) and isinstance(b, int):
+        return int(math.fmod(a, b))
+    return math.fmod(a, b)
+
+
+def _cel_string(value: Any) -> str:
+    """Convert a value to a string like CEL's string()."""
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    return str(value)
+`
+
+// pythonOperators maps CEL's binary operators to Python's
+var pythonOperators = map[string]string{
+	"_+_": "+", "_-_": "-", "_*_": "*",
+	"_==_": "==", "_!=_": "!=", "_<_": "<", "_<=_": "<=", "_>_": ">", "_>=_": ">=",
+	"_&&_": "and", "_||_": "or", "@in": "in",
+}
+
+// pythonFunctions maps CEL's global functions to Python expressions, given
+// the translated arguments
+var pythonFunctions = map[string]func(args []string) string{
+	"_/_":    func(args []string) string { return fmt.Sprintf("_cel_div(%s, %s)", args[0], args[1]) },
+	"_%_":    func(args []string) string { return fmt.Sprintf("_cel_mod(%s, %s)", args[0], args[1]) },
+	"!_":     func(args []string) string { return fmt.Sprintf("(not %s)", args[0]) },
+	"-_":     func(args []string) string { return fmt.Sprintf("(-%s)", args[0]) },
+	"_?_:_":  func(args []string) string { return fmt.Sprintf("(%s if %s else %s)", args[1], args[0], args[2]) },
+	"_[_]":   func(args []string) string { return fmt.Sprintf("%s[%s]", args[0], args[1]) },
+	"size":   func(args []string) string { return fmt.Sprintf("len(%s)", args[0]) },
+	"int":    func(args []string) string { return fmt.Sprintf("int(%s)", args[0]) },
+	"double": func(args []string) string { return fmt.Sprintf("float(%s)", args[0]) },
+	"string": func(args []string) string { return fmt.Sprintf("_cel_string(%s)", args[0]) },
+	"bool":   func(args []string) string { return fmt.Sprintf("(%s in (True, \"true\"))", args[0]) },
+	"contains": func(args []string) string {
+		return fmt.Sprintf("(%s in %s)", args[1], args[0])
+	},
+	"startsWith": func(args []string) string { return fmt.Sprintf("%s.startswith(%s)", args[0], args[1]) },
+	"endsWith":   func(args []string) string { return fmt.Sprintf("%s.endswith(%s)", args[0], args[1]) },
+	"matches": func(args []string) string {
+		return fmt.Sprintf("(re.search(%s, %s) is not None)", args[1], args[0])
+	},
+}
+
+// Python returns the tool's expression translated into an equivalent Python
+// expression over the variable response, calling the PythonHelpers. Only
+// CEL's standard operators, macros and the functions size, int, double,
+// string, bool, contains, startsWith, endsWith and matches are supported.
+func (t *Transformer) Python(tool string) (string, error) {
+	checked, ok := t.asts[strings.ToLower(tool)]
+	if !ok {
+		return "", fmt.Errorf("no transform for tool %s", tool)
+	}
+	native := checked.NativeRep()
+	translator := pythonTranslator{macros: native.SourceInfo().MacroCalls()}
+	code, err := translator.expr(native.Expr())
+	if err != nil {
+		return "", fmt.Errorf("transform of tool %s can't be generated into Python: %w", tool, err)
+	}
+	return code, nil
+}
+
+// pythonTranslator translates CEL expressions into Python
+type pythonTranslator struct {
+	// macros maps the IDs of expanded macros to their original calls
+	macros map[int64]ast.Expr
+}
+
+func (p pythonTranslator) expr(e ast.Expr) (string, error) {
+	// Macros are expanded into comprehensions, translated from their calls
+	if call, ok := p.macros[e.ID()]; ok {
+		return p.macro(call)
+	}
+
+	switch e.Kind() {
+	case ast.IdentKind:
+		return pythonIdent(e.AsIdent()), nil
+	case ast.LiteralKind:
+		return pythonLiteral(e.AsLiteral())
+	case ast.SelectKind:
+		sel := e.AsSelect()
+		operand, err := p.expr(sel.Operand())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s[%s]", operand, strconv.Quote(sel.FieldName())), nil
+	case ast.ListKind:
+		elements, err := p.exprs(e.AsList().Elements())
+		if err != nil {
+			return "", err
+		}
+		return "[" + strings.Join(elements, ", ") + "]", nil
+	case ast.MapKind:
+		var entries []string
+		for _, entry := range e.AsMap().Entries() {
+			key, err := p.expr(entry.AsMapEntry().Key())
+			if err != nil {
+				return "", err
+			}
+			value, err := p.expr(entry.AsMapEntry().Value())
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, key+": "+value)
+		}
+		return "{" + strings.Join(entries, ", ") + "}", nil
+	case ast.CallKind:
+		return p.call(e.AsCall())
+	default:
+		return "", fmt.Errorf("unsupported expression of kind %d", e.Kind())
+	}
+}
+
+func (p pythonTranslator) exprs(exprs []ast.Expr) ([]string, error) {
+	translated := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		code, err := p.expr(e)
+		if err != nil {
+			return nil, err
+		}
+		translated = append(translated, code)
+	}
+	return translated, nil
+}
+
+func (p pythonTranslator) call(call ast.CallExpr) (string, error) {
+	args := call.Args()
+	if call.IsMemberFunction() {
+		args = append([]ast.Expr{call.Target()}, args...)
+	}
+	translated, err := p.exprs(args)
+	if err != nil {
+		return "", err
+	}
+
+	name := call.FunctionName()
+	if op, ok := pythonOperators[name]; ok && len(translated) == 2 {
+		return fmt.Sprintf("(%s %s %s)", translated[0], op, translated[1]), nil
+	}
+	if fn, ok := pythonFunctions[name]; ok {
+		return fn(translated), nil
+	}
+	return "", fmt.Errorf("unsupported function %s", name)
+}
+
+// macro translates a macro call into a Python comprehension, or has() into
+// a membership test
+func (p pythonTranslator) macro(call ast.Expr) (string, error) {
+	c := call.AsCall()
+	if c.FunctionName() == "has" && len(c.Args()) == 1 && c.Args()[0].Kind() == ast.SelectKind {
+		sel := c.Args()[0].AsSelect()
+		operand, err := p.expr(sel.Operand())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s in %s)", strconv.Quote(sel.FieldName()), operand), nil
+	}
+	if !c.IsMemberFunction() || len(c.Args()) < 2 {
+		return "", fmt.Errorf("unsupported macro %s", c.FunctionName())
+	}
+	target, err := p.expr(c.Target())
+	if err != nil {
+		return "", err
+	}
+	if c.Args()[0].Kind() != ast.IdentKind {
+		return "", fmt.Errorf("unsupported macro %s", c.FunctionName())
+	}
+	v := pythonIdent(c.Args()[0].AsIdent())
+	args, err := p.exprs(c.Args()[1:])
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case c.FunctionName() == "all" && len(args) == 1:
+		return fmt.Sprintf("all(%s for %s in %s)", args[0], v, target), nil
+	case c.FunctionName() == "exists" && len(args) == 1:
+		return fmt.Sprintf("any(%s for %s in %s)", args[0], v, target), nil
+	case c.FunctionName() == "exists_one" && len(args) == 1:
+		return fmt.Sprintf("(sum(1 for %s in %s if %s) == 1)", v, target, args[0]), nil
+	case c.FunctionName() == "filter" && len(args) == 1:
+		return fmt.Sprintf("[%s for %s in %s if %s]", v, v, target, args[0]), nil
+	case c.FunctionName() == "map" && len(args) == 1:
+		return fmt.Sprintf("[%s for %s in %s]", args[0], v, target), nil
+	case c.FunctionName() == "map" && len(args) == 2:
+		return fmt.Sprintf("[%s for %s in %s if %s]", args[1], v, target, args[0]), nil
+	default:
+		return "", fmt.Errorf("unsupported macro %s", c.FunctionName())
+	}
+}
+
+// pythonIdent returns the Python name of a CEL identifier. Names other than
+// response get a suffix, so they can't clash with Python keywords or builtins.
+func pythonIdent(name string) string {
+	if name == Variable {
+		return name
+	}
+	return name + "_"
+}
+
+// pythonLiteral returns a CEL constant as a Python literal
+func pythonLiteral(value ref.Val) (string, error) {
+	switch v := value.(type) {
+	case types.Bool:
+		if v {
+			return "True", nil
+		}
+		return "False", nil
+	case types.Null:
+		return "None", nil
+	case types.Int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case types.Uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case types.Double:
+		f := float64(v)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return fmt.Sprintf("float(%q)", strconv.FormatFloat(f, 'g', -1, 64)), nil
+		}
+		literal := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".e") {
+			// Keep it a float in Python
+			literal += ".0"
+		}
+		return literal, nil
+	case types.String:
+		return strconv.Quote(string(v)), nil
+	default:
+		return "", fmt.Errorf("unsupported literal %v", value.Value())
+	}
+}
@@ -0,0 +1,176 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Rename exposes an upstream parameter or body field under another argument name
+type Rename struct {
+	// Field is the name the upstream API expects
+	Field string `mapstructure:"field"`
+	// Argument is the name the tool presents
+	Argument string `mapstructure:"argument"`
+}
+
+// Injection sets an upstream parameter or body field to a constant
+type Injection struct {
+	Field string      `mapstructure:"field"`
+	Value interface{} `mapstructure:"value"`
+}
+
+// RequestRule mutates the requests of a tool, so that the tool can present a
+// simpler argument surface while the upstream API still receives its exact
+// contract. Parameters that are injected or dropped are hidden from the tool.
+type RequestRule struct {
+	Rename []Rename    `mapstructure:"rename"`
+	Inject []Injection `mapstructure:"inject"`
+	Drop   []string    `mapstructure:"drop"`
+}
+
+// RequestRules are the request rules of tools, keyed by lower-cased tool name
+type RequestRules map[string]RequestRule
+
+// RequestRulesFromConfig reads the rules configured under request. Field
+// names are lists of entries rather than map keys, which would lose their case.
+func RequestRulesFromConfig() (RequestRules, error) {
+	var rules map[string]RequestRule
+	if err := viper.UnmarshalKey("request", &rules); err != nil {
+		return nil, fmt.Errorf("invalid request configuration: %w", err)
+	}
+
+	normalized := make(RequestRules, len(rules))
+	for tool, rule := range rules {
+		for _, rename := range rule.Rename {
+			if rename.Field == "" || rename.Argument == "" {
+				return nil, fmt.Errorf("invalid request rule of tool %s: renames need a field and an argument", tool)
+			}
+		}
+		for _, injection := range rule.Inject {
+			if injection.Field == "" {
+				return nil, fmt.Errorf("invalid request rule of tool %s: injections need a field", tool)
+			}
+		}
+		normalized[strings.ToLower(tool)] = rule
+	}
+	return normalized, nil
+}
+
+// For returns the rule of a tool, the zero rule changing nothing if none is configured
+func (r RequestRules) For(tool string) RequestRule {
+	return r[strings.ToLower(tool)]
+}
+
+// Tools returns the lower-cased names of the tools with a rule, sorted
+func (r RequestRules) Tools() []string {
+	tools := make([]string, 0, len(r))
+	for tool := range r {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// IsZero reports whether the rule changes nothing
+func (r RequestRule) IsZero() bool {
+	return len(r.Rename) == 0 && len(r.Inject) == 0 && len(r.Drop) == 0
+}
+
+// Argument returns the argument name the tool presents for an upstream field
+func (r RequestRule) Argument(field string) string {
+	for _, rename := range r.Rename {
+		if rename.Field == field {
+			return rename.Argument
+		}
+	}
+	return field
+}
+
+// Injected returns the constant an upstream field is set to, if any
+func (r RequestRule) Injected(field string) (interface{}, bool) {
+	for _, injection := range r.Inject {
+		if injection.Field == field {
+			return injection.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Dropped reports whether an upstream field is never sent
+func (r RequestRule) Dropped(field string) bool {
+	for _, dropped := range r.Drop {
+		if dropped == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Hidden reports whether a parameter is hidden from the tool, because it is
+// injected or dropped
+func (r RequestRule) Hidden(field string) bool {
+	_, injected := r.Injected(field)
+	return injected || r.Dropped(field)
+}
+
+// ApplyBody maps the top-level fields of a JSON request body from their
+// argument names back to the upstream ones, removes the dropped fields and
+// sets the injected ones. Bodies that aren't JSON objects are returned as is;
+// a nil body becomes an object if there is anything to inject.
+func (r RequestRule) ApplyBody(body interface{}) interface{} {
+	if body == nil && len(r.Inject) > 0 {
+		body = map[string]interface{}{}
+	}
+	fields, ok := body.(map[string]interface{})
+	if !ok {
+		return body
+	}
+
+	mutated := make(map[string]interface{}, len(fields)+len(r.Inject))
+	for name, value := range fields {
+		mutated[name] = value
+	}
+	for _, rename := range r.Rename {
+		if value, ok := mutated[rename.Argument]; ok {
+			delete(mutated, rename.Argument)
+			mutated[rename.Field] = value
+		}
+	}
+	for _, field := range r.Drop {
+		delete(mutated, field)
+	}
+	for _, injection := range r.Inject {
+		mutated[injection.Field] = injection.Value
+	}
+	return mutated
+}
+
+// Without returns the rule without the entries of the given fields, e.g. the
+// part of a rule applying to the body rather than an operation's parameters
+func (r RequestRule) Without(fields ...string) RequestRule {
+	excluded := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		excluded[field] = true
+	}
+
+	var rule RequestRule
+	for _, rename := range r.Rename {
+		if !excluded[rename.Field] {
+			rule.Rename = append(rule.Rename, rename)
+		}
+	}
+	for _, injection := range r.Inject {
+		if !excluded[injection.Field] {
+			rule.Inject = append(rule.Inject, injection)
+		}
+	}
+	for _, field := range r.Drop {
+		if !excluded[field] {
+			rule.Drop = append(rule.Drop, field)
+		}
+	}
+	return rule
+}
@@ -0,0 +1,106 @@
+package transform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const requestConfig = `
+request:
+  POST_pets:
+    rename:
+      - {field: petName, argument: name}
+    inject:
+      - {field: apiVersion, value: "2024-01"}
+      - {field: limit, value: 10}
+    drop: [internalNotes]
+`
+
+func TestRequestRulesFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if rules, err := RequestRulesFromConfig(); len(rules) != 0 || err != nil {
+		t.Fatalf("expected no rules by default, got %v, %v", rules, err)
+	}
+
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(requestConfig)); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := RequestRulesFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rules.Tools(); !reflect.DeepEqual(got, []string{"post_pets"}) {
+		t.Errorf("Tools() = %v", got)
+	}
+
+	// Field names keep their case, unlike configuration keys
+	rule := rules.For("post_pets")
+	if got := rule.Argument("petName"); got != "name" {
+		t.Errorf("Argument(petName) = %q, want name", got)
+	}
+	if got := rule.Argument("tag"); got != "tag" {
+		t.Errorf("Argument(tag) = %q, want tag", got)
+	}
+	if value, ok := rule.Injected("apiVersion"); !ok || value != "2024-01" {
+		t.Errorf("Injected(apiVersion) = %v, %v", value, ok)
+	}
+	if !rule.Hidden("internalNotes") || !rule.Hidden("limit") || rule.Hidden("petName") {
+		t.Errorf("unexpected hidden fields of %+v", rule)
+	}
+	if !rules.For("get_pets").IsZero() {
+		t.Error("expected the zero rule for a tool without rules")
+	}
+
+	viper.Set("request", map[string]interface{}{"get_pets": map[string]interface{}{"rename": []interface{}{map[string]interface{}{"field": "q"}}}})
+	if _, err := RequestRulesFromConfig(); err == nil {
+		t.Error("expected an error for a rename without an argument")
+	}
+}
+
+func TestApplyBody(t *testing.T) {
+	rule := RequestRule{
+		Rename: []Rename{{Field: "petName", Argument: "name"}},
+		Inject: []Injection{{Field: "apiVersion", Value: "2024-01"}},
+		Drop:   []string{"internalNotes"},
+	}
+
+	body := map[string]interface{}{"name": "Rex", "internalNotes": "bites", "apiVersion": "1999", "tag": "dog"}
+	want := map[string]interface{}{"petName": "Rex", "apiVersion": "2024-01", "tag": "dog"}
+	if got := rule.ApplyBody(body); !reflect.DeepEqual(got, want) {
+		t.Errorf("ApplyBody() = %v, want %v", got, want)
+	}
+	if _, ok := body["petName"]; ok {
+		t.Error("ApplyBody modified its argument")
+	}
+
+	if got := rule.ApplyBody(nil); !reflect.DeepEqual(got, map[string]interface{}{"apiVersion": "2024-01"}) {
+		t.Errorf("ApplyBody(nil) = %v", got)
+	}
+	if got := rule.ApplyBody([]interface{}{"Rex"}); !reflect.DeepEqual(got, []interface{}{"Rex"}) {
+		t.Errorf("ApplyBody() of an array = %v", got)
+	}
+	if got := (RequestRule{Drop: []string{"x"}}).ApplyBody(nil); got != nil {
+		t.Errorf("ApplyBody(nil) without injections = %v, want nil", got)
+	}
+}
+
+func TestWithout(t *testing.T) {
+	rule := RequestRule{
+		Rename: []Rename{{Field: "petName", Argument: "name"}, {Field: "q", Argument: "query"}},
+		Inject: []Injection{{Field: "limit", Value: 10}},
+		Drop:   []string{"internalNotes", "trace"},
+	}
+	want := RequestRule{
+		Rename: []Rename{{Field: "petName", Argument: "name"}},
+		Drop:   []string{"internalNotes"},
+	}
+	if got := rule.Without("q", "limit", "trace"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Without() = %+v, want %+v", got, want)
+	}
+}
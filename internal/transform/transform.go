@@ -0,0 +1,149 @@
+// Package transform reshapes the JSON responses of tools with CEL expressions
+// configured per tool, e.g. to filter lists or compute derived fields before
+// a response reaches the model
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/google/cel-go/cel"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Variable is the name the upstream response is bound to in expressions
+const Variable = "response"
+
+// Transformer applies the expression configured for a tool to its responses
+type Transformer struct {
+	programs map[string]cel.Program
+	asts     map[string]*cel.Ast
+}
+
+// newEnv returns the CEL environment expressions are compiled in
+func newEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable(Variable, cel.DynType),
+		// Keeps the macro calls, which the Python translation needs
+		cel.EnableMacroCallTracking(),
+	)
+}
+
+// New compiles the expressions, keyed by tool name. It returns nil, which
+// transforms nothing, if there are none.
+func New(expressions map[string]string) (*Transformer, error) {
+	if len(expressions) == 0 {
+		return nil, nil
+	}
+	env, err := newEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transformer{programs: make(map[string]cel.Program), asts: make(map[string]*cel.Ast)}
+	for _, tool := range sortedKeys(expressions) {
+		ast, issues := env.Compile(expressions[tool])
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("invalid transform of tool %s: %w", tool, issues.Err())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transform of tool %s: %w", tool, err)
+		}
+		key := strings.ToLower(tool)
+		t.programs[key] = program
+		t.asts[key] = ast
+	}
+	return t, nil
+}
+
+// FromConfig compiles the expressions configured under transform
+func FromConfig() (*Transformer, error) {
+	return New(config.GetStringMapString("transform"))
+}
+
+// Has reports whether a transform is configured for the tool
+func (t *Transformer) Has(tool string) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.programs[strings.ToLower(tool)]
+	return ok
+}
+
+// Tools returns the lower-cased names of the tools with a transform, in order
+func (t *Transformer) Tools() []string {
+	if t == nil {
+		return nil
+	}
+	tools := make([]string, 0, len(t.programs))
+	for tool := range t.programs {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// Apply evaluates the tool's expression with the JSON body bound to
+// response and returns the result as JSON. Bodies of tools without a
+// transform are returned unchanged. Integral JSON numbers are bound as CEL
+// ints, other numbers as doubles.
+func (t *Transformer) Apply(tool string, body []byte) ([]byte, error) {
+	if !t.Has(tool) {
+		return body, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var response interface{}
+	if err := decoder.Decode(&response); err != nil {
+		return nil, fmt.Errorf("transform of tool %s needs a JSON response: %w", tool, err)
+	}
+
+	out, _, err := t.programs[strings.ToLower(tool)].Eval(map[string]interface{}{Variable: celValue(response)})
+	if err != nil {
+		return nil, fmt.Errorf("transform of tool %s failed: %w", tool, err)
+	}
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("transform of tool %s returned a value that isn't JSON: %w", tool, err)
+	}
+	return json.Marshal(native.(*structpb.Value).AsInterface())
+}
+
+// celValue converts decoded JSON into values CEL computes with, turning
+// integral numbers into ints so that arithmetic with int literals works
+func celValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = celValue(field)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = celValue(element)
+		}
+	}
+	return value
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+package transform
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const petsResponse = `{"total": 3, "pets": [
+	{"id": 1, "name": "Rex", "tag": "dog", "price": 12.5, "owner": {"email": "jane@example.com"}},
+	{"id": 2, "name": "Tom", "tag": "cat", "price": 8},
+	{"id": 3, "name": "Kit", "tag": "cat", "price": 9.5}
+]}`
+
+var transformTests = []struct {
+	name string
+	expr string
+	want string
+}{
+	{"reshape", `response.pets.map(p, {"id": p.id, "name": p.name})`, `[{"id":1,"name":"Rex"},{"id":2,"name":"Tom"},{"id":3,"name":"Kit"}]`},
+	{"filter", `response.pets.filter(p, p.tag == "cat").map(p, p.name)`, `["Tom","Kit"]`},
+	{"map with filter", `response.pets.map(p, p.price > 9.0, p.id)`, `[1,3]`},
+	{"derived fields", `{"count": size(response.pets), "pages": response.total / 2, "rest": response.total % 2, "cats": response.pets.exists(p, p.tag == "cat")}`, `{"cats":true,"count":3,"pages":1,"rest":1}`},
+	{"presence and conditionals", `response.pets.map(p, has(p.owner) ? p.owner.email : "none")`, `["jane@example.com","none","none"]`},
+	{"strings", `response.pets.filter(p, p.name.startsWith("T") || p.name.matches("^K")).map(p, p.name + ":" + string(p.id))`, `["Tom:2","Kit:3"]`},
+	{"quantifiers", `[response.pets.all(p, p.id > 0), response.pets.exists_one(p, p.tag == "dog"), "cat" in response.pets.map(p, p.tag), !(size(response.pets) == 0)]`, `[true,true,true,true]`},
+}
+
+func TestApply(t *testing.T) {
+	for _, tt := range transformTests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := New(map[string]string{"get_pets": tt.expr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := transformer.Apply("GET_PETS", []byte(petsResponse))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	if _, err := New(map[string]string{"get_pets": `response.pets.map(`}); err == nil {
+		t.Error("expected an invalid expression to be rejected")
+	}
+
+	transformer, err := New(map[string]string{"get_pets": `response.missing.id`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transformer.Apply("get_pets", []byte(petsResponse)); err == nil {
+		t.Error("expected a missing field to fail")
+	}
+	if _, err := transformer.Apply("get_pets", []byte("not json")); err == nil {
+		t.Error("expected a non-JSON response to fail")
+	}
+	if got, err := transformer.Apply("other_tool", []byte("not json")); err != nil || string(got) != "not json" {
+		t.Errorf("expected tools without a transform to be left alone, got %q, %v", got, err)
+	}
+
+	var none *Transformer
+	if none.Has("get_pets") {
+		t.Error("expected a nil transformer to have no transforms")
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	if transformer, err := FromConfig(); transformer != nil || err != nil {
+		t.Fatalf("expected no transformer by default, got %v, %v", transformer, err)
+	}
+	viper.Set("transform", map[string]interface{}{"get_pets": "size(response.pets)"})
+	transformer, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := transformer.Apply("get_pets", []byte(petsResponse)); string(got) != "3" {
+		t.Errorf("expected 3, got %s", got)
+	}
+}
+
+func TestPythonUnsupported(t *testing.T) {
+	transformer, err := New(map[string]string{"get_pets": `timestamp("2024-01-01T00:00:00Z")`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transformer.Python("get_pets"); err == nil {
+		t.Error("expected unsupported functions to be rejected")
+	}
+}
+
+// TestPythonEquivalence checks that the translated expressions compute the
+// same results in Python as in Go
+func TestPythonEquivalence(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("Python interpreter not available")
+	}
+
+	for _, tt := range transformTests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer, err := New(map[string]string{"get_pets": tt.expr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, err := transformer.Python("get_pets")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			script := "import json, sys\nfrom typing import Any\n" + PythonHelpers +
+				"\nresponse = json.load(open(sys.argv[1]))\nprint(json.dumps(" + code + "))\n"
+			dir := t.TempDir()
+			scriptFile := filepath.Join(dir, "transform.py")
+			responseFile := filepath.Join(dir, "response.json")
+			os.WriteFile(scriptFile, []byte(script), 0o644)
+			os.WriteFile(responseFile, []byte(petsResponse), 0o644)
+
+			out, err := exec.Command(python, scriptFile, responseFile).CombinedOutput()
+			if err != nil {
+				t.Fatalf("Python translation %s failed: %v\n%s", code, err, out)
+			}
+			assertJSONEqual(t, out, tt.want)
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	json.Unmarshal([]byte(want), &wantValue)
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
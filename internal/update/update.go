@@ -0,0 +1,364 @@
+// Package update replaces the running mcprox binary with a signed release
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PublicKey is the base64 ed25519 public key release checksums are signed
+// with. It is set at build time with
+// -ldflags "-X github.com/berkantay/mcprox/internal/update.PublicKey=<key>"
+var PublicKey = ""
+
+// DefaultRepository is the GitHub repository mcprox is released from
+const DefaultRepository = "berkantay/mcprox"
+
+// DefaultAPIURL is the GitHub API releases are looked up with
+const DefaultAPIURL = "https://api.github.com"
+
+// ChecksumsFile lists the SHA-256 digests of a release's archives, and
+// SignatureFile holds the ed25519 signature of ChecksumsFile
+const (
+	ChecksumsFile = "checksums.txt"
+	SignatureFile = ChecksumsFile + ".sig"
+)
+
+// maxDownloadSize bounds the size of a downloaded release asset
+const maxDownloadSize = 200 << 20
+
+// ErrNoPublicKey is returned when signatures must be verified but the binary
+// was built without PublicKey
+var ErrNoPublicKey = errors.New("this build of mcprox has no release signing key")
+
+// Release is a published mcprox release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// asset returns the release's asset with the given name
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Updater looks up and downloads releases
+type Updater struct {
+	// APIURL is the GitHub API base URL, DefaultAPIURL if empty
+	APIURL string
+	// Repository is the repository releases come from, DefaultRepository if empty
+	Repository string
+	// PublicKey verifies the signature of the checksums; if nil, only the
+	// checksums are verified
+	PublicKey ed25519.PublicKey
+
+	client *http.Client
+}
+
+// New creates an updater for the official releases, verifying them with the
+// embedded PublicKey. Without one it fails with ErrNoPublicKey unless
+// skipSignature is set.
+func New(skipSignature bool) (*Updater, error) {
+	u := &Updater{client: &http.Client{Timeout: 5 * time.Minute}}
+	if skipSignature {
+		return u, nil
+	}
+	if PublicKey == "" {
+		return nil, ErrNoPublicKey
+	}
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key %q", PublicKey)
+	}
+	u.PublicKey = key
+	return u, nil
+}
+
+// Release looks up the release with the given tag, or the latest one if tag
+// is empty
+func (u *Updater) Release(ctx context.Context, tag string) (*Release, error) {
+	apiURL := u.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	repository := u.Repository
+	if repository == "" {
+		repository = DefaultRepository
+	}
+	releaseURL := fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimSuffix(apiURL, "/"), repository)
+	if tag != "" {
+		releaseURL = fmt.Sprintf("%s/repos/%s/releases/tags/%s", strings.TrimSuffix(apiURL, "/"), repository, tag)
+	}
+
+	data, err := u.get(ctx, releaseURL, "application/vnd.github+json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up release: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(data, &release); err != nil {
+		return nil, fmt.Errorf("invalid release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, errors.New("invalid release: no tag")
+	}
+	return &release, nil
+}
+
+// Download fetches the archive of a release for the running platform,
+// verifies its signed checksum and returns the mcprox binary it contains
+func (u *Updater) Download(ctx context.Context, release *Release) ([]byte, error) {
+	checksums, err := u.downloadAsset(ctx, release, ChecksumsFile)
+	if err != nil {
+		return nil, err
+	}
+	if u.PublicKey != nil {
+		signature, err := u.downloadAsset(ctx, release, SignatureFile)
+		if err != nil {
+			return nil, err
+		}
+		if !ed25519.Verify(u.PublicKey, checksums, signature) {
+			return nil, fmt.Errorf("invalid signature of %s in release %s", ChecksumsFile, release.TagName)
+		}
+	}
+
+	name := ArchiveName(release.TagName, runtime.GOOS, runtime.GOARCH)
+	expected, err := checksum(checksums, name)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := u.downloadAsset(ctx, release, name)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+
+	return extractBinary(archive, name)
+}
+
+// downloadAsset fetches an asset of a release
+func (u *Updater) downloadAsset(ctx context.Context, release *Release, name string) ([]byte, error) {
+	asset, ok := release.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s", release.TagName, name)
+	}
+	data, err := u.get(ctx, asset.URL, "application/octet-stream")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// get fetches a URL, bounding the size of the response
+func (u *Updater) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	client := u.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-OK response: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("%s exceeds %d bytes", url, maxDownloadSize)
+	}
+	return data, nil
+}
+
+// ArchiveName returns the name of the release archive for a platform, e.g.
+// mcprox_1.2.0_linux_amd64.tar.gz
+func ArchiveName(tag, goos, goarch string) string {
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("mcprox_%s_%s_%s%s", strings.TrimPrefix(tag, "v"), goos, goarch, ext)
+}
+
+// checksum returns the digest listed for a file in a checksums file, in the
+// format written by sha256sum
+func checksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", ChecksumsFile, name)
+}
+
+// binaryName returns the name of the mcprox binary on a platform
+func binaryName(goos string) string {
+	if goos == "windows" {
+		return "mcprox.exe"
+	}
+	return "mcprox"
+}
+
+// extractBinary returns the mcprox binary of a release archive
+func extractBinary(archive []byte, name string) ([]byte, error) {
+	binary := binaryName(runtime.GOOS)
+
+	if strings.HasSuffix(name, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive %s: %w", name, err)
+		}
+		for _, file := range reader.File {
+			if path.Base(file.Name) != binary {
+				continue
+			}
+			f, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return io.ReadAll(io.LimitReader(f, maxDownloadSize))
+		}
+		return nil, fmt.Errorf("archive %s contains no %s", name, binary)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive %s: %w", name, err)
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive %s contains no %s", name, binary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive %s: %w", name, err)
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == binary {
+			return io.ReadAll(io.LimitReader(reader, maxDownloadSize))
+		}
+	}
+}
+
+// Replace atomically replaces the binary at exePath. The new binary is written
+// next to it and renamed over it; on Windows, where a running binary can't be
+// overwritten, the old one is moved aside to <exePath>.old first.
+func Replace(exePath string, binary []byte) error {
+	dir := filepath.Dir(exePath)
+	tmp, err := os.CreateTemp(dir, ".mcprox-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to write the new binary next to %s: %w", exePath, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exePath + ".old"
+		os.Remove(old)
+		if err := os.Rename(exePath, old); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", exePath, err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), exePath); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exePath, err)
+	}
+	return nil
+}
+
+// Newer reports whether version a is newer than version b. Versions are
+// compared as vMAJOR.MINOR.PATCH; a version that doesn't parse, such as a
+// development build's, is older than any that does.
+func Newer(a, b string) bool {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA {
+		return false
+	}
+	if !okB {
+		return true
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses the numeric part of a vMAJOR.MINOR.PATCH version,
+// ignoring any pre-release or build suffix
+func parseVersion(version string) ([3]int, bool) {
+	var parsed [3]int
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
@@ -0,0 +1,231 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// archive packs a binary the way release archives do for the running platform
+func archive(t *testing.T, binary []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	name := binaryName(runtime.GOOS)
+	if runtime.GOOS == "windows" {
+		w := zip.NewWriter(&buf)
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(binary)
+		w.Close()
+		return buf.Bytes()
+	}
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Size: 2, Typeflag: tar.TypeReg})
+	tw.Write([]byte("hi"))
+	tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg})
+	tw.Write(binary)
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// releaseServer serves a GitHub-like API with one release and its assets
+func releaseServer(t *testing.T, tag string, assets map[string][]byte) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/"+DefaultRepository+"/releases/latest" || r.URL.Path == "/repos/"+DefaultRepository+"/releases/tags/"+tag:
+			release := Release{TagName: tag}
+			for name := range assets {
+				release.Assets = append(release.Assets, Asset{Name: name, URL: srv.URL + "/download/" + name})
+			}
+			json.NewEncoder(w).Encode(release)
+		case strings.HasPrefix(r.URL.Path, "/download/"):
+			data, ok := assets[strings.TrimPrefix(r.URL.Path, "/download/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDownload(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("new mcprox binary")
+	name := ArchiveName("v1.2.0", runtime.GOOS, runtime.GOARCH)
+	packed := archive(t, binary)
+	sum := sha256.Sum256(packed)
+	checksums := []byte(fmt.Sprintf("%s  %s\n%s  mcprox_1.2.0_other_arch.tar.gz\n", hex.EncodeToString(sum[:]), name, strings.Repeat("0", 64)))
+
+	tests := []struct {
+		name    string
+		assets  map[string][]byte
+		key     ed25519.PublicKey
+		wantErr string
+	}{
+		{
+			name:   "signed release",
+			assets: map[string][]byte{name: packed, ChecksumsFile: checksums, SignatureFile: ed25519.Sign(privateKey, checksums)},
+			key:    publicKey,
+		},
+		{
+			name:   "checksum only",
+			assets: map[string][]byte{name: packed, ChecksumsFile: checksums},
+		},
+		{
+			name:    "bad signature",
+			assets:  map[string][]byte{name: packed, ChecksumsFile: checksums, SignatureFile: ed25519.Sign(privateKey, []byte("other"))},
+			key:     publicKey,
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "missing signature",
+			assets:  map[string][]byte{name: packed, ChecksumsFile: checksums},
+			key:     publicKey,
+			wantErr: "has no " + SignatureFile,
+		},
+		{
+			name:    "tampered archive",
+			assets:  map[string][]byte{name: append(packed, 0), ChecksumsFile: checksums},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name:    "no archive for platform",
+			assets:  map[string][]byte{ChecksumsFile: []byte("")},
+			wantErr: "no checksum",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := releaseServer(t, "v1.2.0", tt.assets)
+			u := &Updater{APIURL: srv.URL, PublicKey: tt.key}
+
+			release, err := u.Release(context.Background(), "")
+			if err != nil {
+				t.Fatalf("Release() error = %v", err)
+			}
+			got, err := u.Download(context.Background(), release)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Download() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if !bytes.Equal(got, binary) {
+				t.Errorf("Download() = %q, want %q", got, binary)
+			}
+		})
+	}
+}
+
+func TestReleaseByTag(t *testing.T) {
+	srv := releaseServer(t, "v1.1.0", nil)
+	u := &Updater{APIURL: srv.URL}
+	release, err := u.Release(context.Background(), "v1.1.0")
+	if err != nil || release.TagName != "v1.1.0" {
+		t.Errorf("Release() = %+v, %v, want v1.1.0", release, err)
+	}
+	if _, err := u.Release(context.Background(), "v9.9.9"); err == nil {
+		t.Error("Release() error = nil, want an error for an unknown tag")
+	}
+}
+
+func TestNew(t *testing.T) {
+	defer func(key string) { PublicKey = key }(PublicKey)
+
+	PublicKey = ""
+	if _, err := New(false); err != ErrNoPublicKey {
+		t.Errorf("New() error = %v, want ErrNoPublicKey", err)
+	}
+	if u, err := New(true); err != nil || u.PublicKey != nil {
+		t.Errorf("New(true) = %+v, %v, want an updater without key", u, err)
+	}
+
+	PublicKey = "jh1aTnGQl9DKQm9vv1OQ9aG28erxjfgZyPVDtDu5YSE="
+	if u, err := New(false); err != nil || len(u.PublicKey) != ed25519.PublicKeySize {
+		t.Errorf("New() = %+v, %v, want an updater with the key", u, err)
+	}
+	PublicKey = "not a key"
+	if _, err := New(false); err == nil {
+		t.Error("New() error = nil, want an error for an invalid key")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	exePath := filepath.Join(t.TempDir(), "mcprox")
+	if err := os.WriteFile(exePath, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replace(exePath, []byte("new")); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	data, err := os.ReadFile(exePath)
+	if err != nil || string(data) != "new" {
+		t.Errorf("binary = %q, %v, want new", data, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exePath))
+	if runtime.GOOS != "windows" && len(entries) != 1 {
+		t.Errorf("Replace() left %d files behind, want only the binary", len(entries)-1)
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.1.0", "v1.2.0", false},
+		{"v1.2.0", "dev", true},
+		{"v1.2.0", "v0.0.0-20261015060722-10b01abfc6a5", true},
+		{"dev", "v1.2.0", false},
+		{"v2.0.0-rc1", "v1.9.0", true},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.a, tt.b); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	if got := ArchiveName("v1.2.0", "linux", "amd64"); got != "mcprox_1.2.0_linux_amd64.tar.gz" {
+		t.Errorf("ArchiveName() = %q", got)
+	}
+	if got := ArchiveName("v1.2.0", "windows", "arm64"); got != "mcprox_1.2.0_windows_arm64.zip" {
+		t.Errorf("ArchiveName() = %q", got)
+	}
+}
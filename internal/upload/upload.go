@@ -0,0 +1,200 @@
+// Package upload lets the tools of upload endpoints send local files, so that
+// agents can upload the artifacts they produce by path rather than pasting
+// their content into arguments
+package upload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// PathArgument is the argument of upload tools naming the file to upload
+const PathArgument = "file_path"
+
+// Uploader reads the files tools upload, from under its roots only
+type Uploader struct {
+	// Roots are the absolute, resolved directories files can be uploaded from
+	Roots []string
+	// MaxSize is the size in bytes of the largest file uploaded, any if 0
+	MaxSize int64
+}
+
+// Form is how an operation takes a file: as the whole request body of
+// MediaType, or as the Field part of a multipart/form-data body
+type Form struct {
+	MediaType string
+	Field     string
+}
+
+// New returns an uploader reading files from under roots, resolving them
+func New(roots []string, maxSize int64) (*Uploader, error) {
+	u := &Uploader{MaxSize: maxSize}
+	for _, root := range roots {
+		resolved, err := resolve(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upload root %s: %w", root, err)
+		}
+		u.Roots = append(u.Roots, resolved)
+	}
+	return u, nil
+}
+
+// FromConfig returns the uploader configured under upload, nil if
+// upload.roots is empty
+func FromConfig() (*Uploader, error) {
+	roots := config.GetStringSlice("upload.roots")
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	return New(roots, int64(config.GetInt("upload.max_size")))
+}
+
+// Read returns the content of a file, which must be a regular file under one
+// of the roots once symbolic links are resolved
+func (u *Uploader) Read(path string) ([]byte, error) {
+	resolved, err := resolve(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot upload %s: %w", path, err)
+	}
+	if !u.allowed(resolved) {
+		return nil, fmt.Errorf("cannot upload %s: files can only be uploaded from %s", path, strings.Join(u.Roots, ", "))
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("cannot upload %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("cannot upload %s: not a regular file", path)
+	}
+	if u.MaxSize > 0 && info.Size() > u.MaxSize {
+		return nil, fmt.Errorf("cannot upload %s: %d bytes, larger than the %d allowed", path, info.Size(), u.MaxSize)
+	}
+	return os.ReadFile(resolved)
+}
+
+// allowed reports whether a resolved path is under one of the roots
+func (u *Uploader) allowed(path string) bool {
+	for _, root := range u.Roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the absolute path of a file with symbolic links resolved
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// FormOf returns how an operation takes a file, if it does: a multipart
+// body with a binary property, or a body of a type that isn't JSON, text or
+// a form, such as application/octet-stream or image/png
+func FormOf(op *openapi3.Operation) (Form, bool) {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return Form{}, false
+	}
+	content := op.RequestBody.Value.Content
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+
+	if media := content.Get("multipart/form-data"); media != nil && media.Schema != nil && media.Schema.Value != nil {
+		names := make([]string, 0, len(media.Schema.Value.Properties))
+		for name := range media.Schema.Value.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property := media.Schema.Value.Properties[name]; property != nil && property.Value != nil && property.Value.Format == "binary" {
+				return Form{MediaType: "multipart/form-data", Field: name}, true
+			}
+		}
+	}
+	for _, mediaType := range mediaTypes {
+		if isRaw(mediaType) {
+			return Form{MediaType: mediaType}, true
+		}
+	}
+	return Form{}, false
+}
+
+// isRaw reports whether a request body of a media type is a file's content
+func isRaw(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") || strings.HasPrefix(mediaType, "multipart/") ||
+		strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") {
+		return false
+	}
+	return mediaType != "application/x-www-form-urlencoded"
+}
+
+// Body returns the request body uploading a file and its content type. A
+// multipart body also has a part per field, strings as they are and other
+// values as JSON.
+func (f Form) Body(path string, data []byte, fields map[string]interface{}) ([]byte, string, error) {
+	fileType := mime.TypeByExtension(filepath.Ext(path))
+	if fileType == "" {
+		fileType = "application/octet-stream"
+	}
+	if f.Field == "" {
+		// Wildcards such as image/* take the type of the file
+		if strings.Contains(f.MediaType, "*") {
+			return data, fileType, nil
+		}
+		return data, f.MediaType, nil
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := fields[name].(string)
+		if !ok {
+			encoded, err := json.Marshal(fields[name])
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to encode field %s: %w", name, err)
+			}
+			value = string(encoded)
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": f.Field, "filename": filepath.Base(path)}))
+	header.Set("Content-Type", fileType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
@@ -0,0 +1,98 @@
+package upload
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestRead(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	report := filepath.Join(root, "report.csv")
+	os.WriteFile(report, []byte("id,name\n1,Rex\n"), 0o600)
+	secret := filepath.Join(outside, "secret.txt")
+	os.WriteFile(secret, []byte("secret"), 0o600)
+	link := filepath.Join(root, "link.txt")
+	if err := os.Symlink(secret, link); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := New([]string{root}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Read(report); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Read() of a file over the maximum size: %v", err)
+	}
+
+	u.MaxSize = 0
+	if data, err := u.Read(report); err != nil || string(data) != "id,name\n1,Rex\n" {
+		t.Errorf("Read() = %q, %v", data, err)
+	}
+	for _, path := range []string{secret, link, filepath.Join(root, "..", filepath.Base(outside), "secret.txt"), root} {
+		if _, err := u.Read(path); err == nil {
+			t.Errorf("Read(%s) succeeded, want it refused", path)
+		}
+	}
+}
+
+func TestFormOf(t *testing.T) {
+	body := func(content openapi3.Content) *openapi3.Operation {
+		return &openapi3.Operation{RequestBody: &openapi3.RequestBodyRef{Value: &openapi3.RequestBody{Content: content}}}
+	}
+	multipartSchema := openapi3.NewObjectSchema().
+		WithProperty("description", openapi3.NewStringSchema()).
+		WithProperty("file", openapi3.NewStringSchema().WithFormat("binary"))
+
+	for name, test := range map[string]struct {
+		op   *openapi3.Operation
+		want Form
+		ok   bool
+	}{
+		"multipart": {body(openapi3.NewContentWithFormDataSchema(multipartSchema)), Form{MediaType: "multipart/form-data", Field: "file"}, true},
+		"raw":       {body(openapi3.Content{"image/png": openapi3.NewMediaType()}), Form{MediaType: "image/png"}, true},
+		"json":      {body(openapi3.NewContentWithJSONSchema(openapi3.NewObjectSchema())), Form{}, false},
+		"no body":   {&openapi3.Operation{}, Form{}, false},
+	} {
+		if got, ok := FormOf(test.op); got != test.want || ok != test.ok {
+			t.Errorf("%s: FormOf() = %+v, %v, want %+v, %v", name, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestMultipartBody(t *testing.T) {
+	form := Form{MediaType: "multipart/form-data", Field: "file"}
+	body, contentType, err := form.Body("/tmp/report.csv", []byte("id\n1\n"), map[string]interface{}{"description": "Pets", "tags": []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, params, _ := mime.ParseMediaType(contentType)
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	parts := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		parts[part.FormName()] = string(data)
+		if part.FormName() == "file" && (part.FileName() != "report.csv" || !strings.HasPrefix(part.Header.Get("Content-Type"), "text/csv")) {
+			t.Errorf("file part: name %s, type %s", part.FileName(), part.Header.Get("Content-Type"))
+		}
+	}
+	if parts["file"] != "id\n1\n" || parts["description"] != "Pets" || parts["tags"] != `["a"]` {
+		t.Errorf("parts = %v", parts)
+	}
+}
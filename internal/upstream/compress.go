@@ -0,0 +1,129 @@
+package upstream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// AcceptEncoding is the Accept-Encoding header of compressed upstream requests
+const AcceptEncoding = "gzip, deflate"
+
+// Compression asks upstream APIs for compressed responses, which it
+// decompresses, and compresses large request bodies, for APIs behind strict
+// bandwidth limits
+type Compression struct {
+	// MinRequestSize is the size in bytes above which request bodies are
+	// gzipped, none if 0
+	MinRequestSize int
+}
+
+// Wrap returns a round tripper sending compressed requests. It is a
+// Middleware, meant to be the innermost so that the others see plain bodies.
+func (c *Compression) Wrap(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// The transport only decompresses gzip responses by itself as long
+		// as requests don't set Accept-Encoding
+		if req.Header.Get("Accept-Encoding") != "" {
+			return next.RoundTrip(req)
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", AcceptEncoding)
+		if err := c.compressBody(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		if err := Decompress(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		return resp, nil
+	})
+}
+
+// compressBody gzips the body of a request larger than MinRequestSize
+func (c *Compression) compressBody(req *http.Request) error {
+	if c.MinRequestSize <= 0 || req.Body == nil || req.ContentLength <= int64(c.MinRequestSize) || req.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	compressed := buf.Bytes()
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
+
+// Decompress replaces the body of a gzip or deflate encoded response with
+// its decompressed content. Other responses are left as they are.
+func Decompress(resp *http.Response) error {
+	var decompressed io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("invalid gzip response: %w", err)
+		}
+		decompressed = gz
+	case "deflate":
+		decompressed = inflate(resp.Body)
+	default:
+		return nil
+	}
+
+	resp.Body = &decompressedBody{ReadCloser: decompressed, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// inflate returns a reader of a deflate body, which servers send either
+// zlib-wrapped, as the specification says, or raw
+func inflate(body io.Reader) io.ReadCloser {
+	buffered := bufio.NewReader(body)
+	if header, err := buffered.Peek(2); err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if z, err := zlib.NewReader(buffered); err == nil {
+			return z
+		}
+	}
+	return flate.NewReader(buffered)
+}
+
+// decompressedBody closes both the decompressor and the body it reads
+type decompressedBody struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	b.ReadCloser.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,83 @@
+package upstream
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompressionDecompressesResponses(t *testing.T) {
+	for encoding, compress := range map[string]func(w io.Writer) io.WriteCloser{
+		"gzip":           func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate (zlib)": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"deflate (raw)": func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		},
+	} {
+		t.Run(encoding, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != AcceptEncoding {
+					t.Errorf("Accept-Encoding = %q, want %q", got, AcceptEncoding)
+				}
+				w.Header().Set("Content-Encoding", strings.Fields(encoding)[0])
+				cw := compress(w)
+				cw.Write([]byte(`{"name": "Rex"}`))
+				cw.Close()
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: Chain(nil, (&Compression{}).Wrap)}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil || string(body) != `{"name": "Rex"}` {
+				t.Errorf("body = %q, %v, want it decompressed", body, err)
+			}
+			if resp.Header.Get("Content-Encoding") != "" {
+				t.Error("Content-Encoding kept after decompression")
+			}
+		})
+	}
+}
+
+func TestCompressionGzipsLargeBodies(t *testing.T) {
+	var encodings []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = gz
+		}
+		data, _ := io.ReadAll(body)
+		bodies = append(bodies, string(data))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: Chain(nil, (&Compression{MinRequestSize: 10}).Wrap)}
+	large := strings.Repeat("pet ", 10)
+	for _, body := range []string{"small", large} {
+		resp, err := client.Post(server.URL, "text/plain", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if strings.Join(encodings, ",") != ",gzip" || bodies[0] != "small" || bodies[1] != large {
+		t.Errorf("encodings = %v, bodies = %v, want only the large body gzipped", encodings, bodies)
+	}
+}
@@ -0,0 +1,174 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Failover sends the requests to a primary API to a fallback base URL once
+// the primary failed Threshold times in a row, by being unreachable or
+// answering with a server error. After Cooldown, requests try the primary
+// again, and it takes over once it succeeds.
+type Failover struct {
+	// Threshold is how many failures in a row fail over
+	Threshold int
+	// Cooldown is how long requests go to the fallback before the primary is
+	// tried again
+	Cooldown time.Duration
+
+	primary  *url.URL
+	fallback *url.URL
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	failures int
+	// failedAt is when requests last failed over, zero while the primary serves
+	failedAt time.Time
+	now      func() time.Time
+}
+
+// NewFailover returns a failover from the primary base URL to the fallback one
+func NewFailover(primary, fallback string, threshold int, cooldown time.Duration, logger *zap.Logger) (*Failover, error) {
+	p, err := url.Parse(primary)
+	if err != nil || p.Host == "" {
+		return nil, fmt.Errorf("invalid service URL %q to fail over from", primary)
+	}
+	f, err := url.Parse(fallback)
+	if err != nil || f.Host == "" {
+		return nil, fmt.Errorf("invalid fallback URL %q", fallback)
+	}
+	return &Failover{
+		Threshold: max(threshold, 1),
+		Cooldown:  cooldown,
+		primary:   p,
+		fallback:  f,
+		logger:    logger,
+		now:       time.Now,
+	}, nil
+}
+
+// Wrap returns a round tripper failing requests to the primary over. It is
+// a Middleware. Requests that couldn't connect to the primary, which
+// therefore never got them, are sent to the fallback at once.
+func (f *Failover) Wrap(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !f.isPrimary(req.URL) {
+			return next.RoundTrip(req)
+		}
+		if f.failedOver() {
+			fallbackReq, err := f.toFallback(req)
+			if err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(fallbackReq)
+		}
+
+		resp, err := next.RoundTrip(req)
+		switch {
+		case err != nil:
+			f.record(err.Error())
+		case resp.StatusCode >= http.StatusInternalServerError:
+			f.record(resp.Status)
+		default:
+			f.recover()
+		}
+
+		var opErr *net.OpError
+		if err != nil && errors.As(err, &opErr) && opErr.Op == "dial" && (req.Body == nil || req.GetBody != nil) {
+			fallbackReq, ferr := f.toFallback(req)
+			if ferr != nil {
+				return nil, err
+			}
+			f.logger.Info("Primary upstream unreachable, sending the request to the fallback", zap.String("fallback", f.fallback.Redacted()))
+			return next.RoundTrip(fallbackReq)
+		}
+		return resp, err
+	})
+}
+
+// failedOver reports whether requests currently go to the fallback
+func (f *Failover) failedOver() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return !f.failedAt.IsZero() && f.now().Sub(f.failedAt) < f.Cooldown
+}
+
+// record counts a failure of the primary, failing over at the threshold
+func (f *Failover) record(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures++
+	if f.failures < f.Threshold {
+		return
+	}
+	if f.failedAt.IsZero() {
+		f.logger.Warn("Failing over to the fallback upstream",
+			zap.String("primary", f.primary.Redacted()),
+			zap.String("fallback", f.fallback.Redacted()),
+			zap.Int("failures", f.failures),
+			zap.String("reason", reason),
+			zap.Duration("retry_in", f.Cooldown))
+	} else {
+		f.logger.Info("Primary upstream still failing, staying on the fallback",
+			zap.String("reason", reason),
+			zap.Duration("retry_in", f.Cooldown))
+	}
+	f.failedAt = f.now()
+}
+
+// recover records a success of the primary, which takes over again
+func (f *Failover) recover() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.failedAt.IsZero() {
+		f.logger.Info("Primary upstream recovered, failing back", zap.String("primary", f.primary.Redacted()))
+	}
+	f.failures = 0
+	f.failedAt = time.Time{}
+}
+
+// isPrimary reports whether a URL is under the primary base URL
+func (f *Failover) isPrimary(u *url.URL) bool {
+	if u.Scheme != f.primary.Scheme || !strings.EqualFold(u.Host, f.primary.Host) {
+		return false
+	}
+	rest, ok := strings.CutPrefix(u.EscapedPath(), basePath(f.primary))
+	return ok && (rest == "" || strings.HasPrefix(rest, "/"))
+}
+
+// basePath returns the escaped path of a base URL without a trailing slash
+func basePath(u *url.URL) string {
+	return strings.TrimSuffix(u.EscapedPath(), "/")
+}
+
+// toFallback returns a copy of a request to the primary sent to the fallback
+func (f *Failover) toFallback(req *http.Request) (*http.Request, error) {
+	fallbackReq := req.Clone(req.Context())
+	u := *req.URL
+	u.Scheme = f.fallback.Scheme
+	u.Host = f.fallback.Host
+	escaped := basePath(f.fallback) + strings.TrimPrefix(req.URL.EscapedPath(), basePath(f.primary))
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return nil, err
+	}
+	u.Path, u.RawPath = path, escaped
+	fallbackReq.URL = &u
+	fallbackReq.Host = ""
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		fallbackReq.Body = body
+	}
+	return fallbackReq, nil
+}
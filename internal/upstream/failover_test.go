@@ -0,0 +1,95 @@
+package upstream
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestFailover(t *testing.T) {
+	primaryStatus := http.StatusServiceUnavailable
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(primaryStatus)
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fallback " + r.URL.Path))
+	}))
+	defer fallback.Close()
+
+	now := time.Unix(0, 0)
+	f, err := NewFailover(primary.URL+"/v1", fallback.URL+"/api/v1", 2, time.Minute, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.now = func() time.Time { return now }
+	client := &http.Client{Transport: Chain(nil, f.Wrap)}
+	get := func() string {
+		resp, err := client.Get(primary.URL + "/v1/pets")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// Server errors are returned until the threshold, then the fallback serves
+	for i, want := range []string{"primary", "primary", "fallback /api/v1/pets", "fallback /api/v1/pets"} {
+		if got := get(); got != want {
+			t.Errorf("request %d: got %q, want %q", i, got, want)
+		}
+	}
+
+	// After the cooldown, the recovered primary takes over again
+	primaryStatus = http.StatusOK
+	now = now.Add(2 * time.Minute)
+	if got := get(); got != "primary" {
+		t.Errorf("after the cooldown: got %q, want the primary", got)
+	}
+	if f.failedOver() {
+		t.Error("still failed over after the primary recovered")
+	}
+
+	// Other hosts are left alone
+	if _, err := client.Get(fallback.URL + "/other"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailoverUnreachablePrimary(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable := "http://" + listener.Addr().String()
+	listener.Close()
+
+	var body string
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer fallback.Close()
+
+	f, err := NewFailover(unreachable, fallback.URL, 3, time.Minute, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: Chain(nil, f.Wrap)}
+	resp, err := client.Post(unreachable+"/orders", "application/json", strings.NewReader(`{"item": "book"}`))
+	if err != nil {
+		t.Fatalf("request failed instead of reaching the fallback: %v", err)
+	}
+	resp.Body.Close()
+	if body != `{"item": "book"}` {
+		t.Errorf("fallback got body %q, want the request's", body)
+	}
+}
@@ -0,0 +1,145 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// DefaultMaxRedirects is how many redirects upstream requests follow when
+// none is configured
+const DefaultMaxRedirects = 5
+
+// ErrHostNotAllowed is returned for upstream requests to hosts outside the allowlist
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// ErrPrivateRedirect is returned for redirects to private, loopback or
+// link-local addresses
+var ErrPrivateRedirect = errors.New("redirect to a private address")
+
+// Guard keeps upstream requests from reaching hosts other than the
+// configured APIs, so a malicious spec or tool argument can't turn the proxy
+// into an SSRF gadget. Redirects are kept from private addresses by Control,
+// which the transport must dial with.
+type Guard struct {
+	// AllowedHosts lists the hosts requests may be sent to, as host or
+	// host:port. A leading "*." matches any subdomain and "*" any host.
+	AllowedHosts []string
+	// AllowPrivateRedirects lets redirects to other hosts reach private,
+	// loopback and link-local addresses. The configured hosts themselves may
+	// be private.
+	AllowPrivateRedirects bool
+}
+
+// publicOnlyKey is the context key of the redirect targets whose connections
+// Control refuses to private addresses, by host name
+type publicOnlyKey struct{}
+
+// AllowedHosts returns the hosts of the given base URLs, e.g. the configured
+// service URLs, followed by the extra hosts
+func AllowedHosts(baseURLs []string, extra ...string) []string {
+	var hosts []string
+	for _, baseURL := range baseURLs {
+		if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return append(hosts, extra...)
+}
+
+// Wrap returns a round tripper refusing requests to hosts outside the
+// allowlist and redirects to private addresses. It is a Middleware.
+func (g *Guard) Wrap(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !g.Allowed(req.URL) {
+			return nil, fmt.Errorf("%w: %s", ErrHostNotAllowed, req.URL.Host)
+		}
+		// The client sets Response on the requests following a redirect.
+		// Redirects within the original host are fine, it was configured.
+		if req.Response != nil && !g.AllowPrivateRedirects && req.URL.Host != originalHost(req) {
+			req = req.WithContext(context.WithValue(req.Context(), publicOnlyKey{}, req.URL.Hostname()))
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// Allowed reports whether requests may be sent to the URL's host
+func (g *Guard) Allowed(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+
+	for _, allowed := range g.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" {
+			return true
+		}
+		allowedHost, allowedPort, err := net.SplitHostPort(allowed)
+		if err != nil {
+			// No port: any port of the host
+			allowedHost, allowedPort = strings.Trim(allowed, "[]"), ""
+		}
+		if allowedPort != "" && allowedPort != port {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(allowedHost, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowedHost {
+			return true
+		}
+	}
+	return false
+}
+
+// originalHost returns the host of the request a chain of redirects started with
+func originalHost(req *http.Request) string {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req.URL.Host
+}
+
+// Control refuses connections to private, loopback and link-local addresses
+// for the redirects Wrap marks. It is a ControlFunc: checking the address
+// actually connected to, rather than resolving the host beforehand, leaves DNS
+// no chance to answer differently in between.
+func (g *Guard) Control(ctx context.Context, network, address string, c syscall.RawConn) error {
+	host, ok := ctx.Value(publicOnlyKey{}).(string)
+	if !ok {
+		return nil
+	}
+	addr, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s resolves to %s", ErrPrivateRedirect, host, addr)
+	}
+	return nil
+}
+
+// LimitRedirects returns an http.Client CheckRedirect function following at
+// most max redirects. With max 0, the redirect response itself is returned.
+func LimitRedirects(max int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if max <= 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > max {
+			return fmt.Errorf("stopped after %d redirects", max)
+		}
+		return nil
+	}
+}
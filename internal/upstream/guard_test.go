@@ -0,0 +1,140 @@
+package upstream
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestGuardAllowed(t *testing.T) {
+	guard := &Guard{AllowedHosts: AllowedHosts(
+		[]string{"https://api.example.com/v1", "http://localhost:8080", ""},
+		"*.internal.example.com", "[::1]:9000",
+	)}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://api.example.com/pets", true},
+		{"https://API.example.com:443/pets", true},
+		{"http://api.example.com/pets", true},
+		{"https://evil.example.com/pets", false},
+		{"https://api.example.com.evil.com/pets", false},
+		{"http://localhost:8080/pets", true},
+		{"http://localhost:8081/pets", false},
+		{"https://billing.internal.example.com/", true},
+		{"https://internal.example.com/", false},
+		{"http://[::1]:9000/", true},
+		{"http://169.254.169.254/latest/meta-data", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := guard.Allowed(u); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	u, _ := url.Parse("http://anything.test")
+	if !(&Guard{AllowedHosts: []string{"*"}}).Allowed(u) {
+		t.Error("expected * to allow any host")
+	}
+}
+
+func TestGuardRedirects(t *testing.T) {
+	// The API lives on loopback, which is allowed; its redirects to other
+	// loopback hosts are not
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer target.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, target.URL, http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer api.Close()
+
+	apiURL, _ := url.Parse(api.URL)
+	targetURL, _ := url.Parse(target.URL)
+	client := func(guard *Guard, maxRedirects int) *http.Client {
+		base, err := NewTransport(HTTP2Auto, nil, guard.Control)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Client{Transport: Chain(base, guard.Wrap), CheckRedirect: LimitRedirects(maxRedirects)}
+	}
+
+	guard := &Guard{AllowedHosts: []string{apiURL.Host}}
+	resp, err := client(guard, 5).Get(api.URL + "/same")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a redirect within the API to be followed, got %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := client(guard, 5).Get(api.URL + "/away"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("expected a redirect to another host to be refused, got %v", err)
+	}
+
+	guard = &Guard{AllowedHosts: []string{apiURL.Host, targetURL.Host}}
+	if _, err := client(guard, 5).Get(api.URL + "/away"); !errors.Is(err, ErrPrivateRedirect) {
+		t.Errorf("expected a redirect to a private address to be refused, got %v", err)
+	}
+	guard.AllowPrivateRedirects = true
+	resp, err = client(guard, 5).Get(api.URL + "/away")
+	if err != nil {
+		t.Fatalf("expected private redirects to be allowed, got %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := client(guard, 3).Get(api.URL + "/loop"); err == nil {
+		t.Error("expected redirect loops to be stopped")
+	}
+	resp, err = client(guard, 0).Get(api.URL + "/same")
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Errorf("expected the redirect response without following it, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestGuardChecksConnectedAddress(t *testing.T) {
+	// public.test stands for a name whose DNS answer changes to a private
+	// address between a lookup and the connection; the override makes it
+	// connect to loopback whatever a lookup says
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://public.test:"+targetURL.Port()+"/", http.StatusFound)
+	}))
+	defer api.Close()
+	apiURL, _ := url.Parse(api.URL)
+
+	guard := &Guard{AllowedHosts: []string{apiURL.Host, "public.test"}}
+	base, err := NewTransport(HTTP2Auto, Overrides{"public.test:" + targetURL.Port(): targetURL.Host}, guard.Control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: Chain(base, guard.Wrap)}
+	if _, err := client.Get(api.URL); !errors.Is(err, ErrPrivateRedirect) {
+		t.Errorf("expected the connection to a private address to be refused, got %v", err)
+	}
+
+	// Requests to the configured hosts themselves may connect anywhere
+	resp, err := client.Get("http://public.test:" + targetURL.Port())
+	if err != nil {
+		t.Fatalf("expected a configured host on loopback to be reachable, got %v", err)
+	}
+	resp.Body.Close()
+}
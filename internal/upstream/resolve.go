@@ -0,0 +1,51 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DialFunc connects to an address, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Overrides maps the host:port addresses of upstream APIs to the addresses to
+// connect to instead, like curl's --resolve, e.g. for pre-production clusters
+// not yet in DNS. Requests keep their host, so TLS still verifies its name.
+type Overrides map[string]string
+
+// ParseOverrides parses overrides given as host:port=address:port. The port
+// of the address defaults to that of the host.
+func ParseOverrides(entries []string) (Overrides, error) {
+	overrides := make(Overrides, len(entries))
+	for _, entry := range entries {
+		from, to, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid address override %q: want host:port=address:port", entry)
+		}
+		_, port, err := net.SplitHostPort(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address override %q: %w", entry, err)
+		}
+		if _, _, err := net.SplitHostPort(to); err != nil {
+			to = net.JoinHostPort(strings.Trim(to, "[]"), port)
+		}
+		overrides[strings.ToLower(from)] = to
+	}
+	return overrides, nil
+}
+
+// Dial returns a dial function connecting to the override of an address, if
+// any, and to the address itself otherwise
+func (o Overrides) Dial(dial DialFunc) DialFunc {
+	if len(o) == 0 {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if to, ok := o[strings.ToLower(addr)]; ok {
+			addr = to
+		}
+		return dial(ctx, network, addr)
+	}
+}
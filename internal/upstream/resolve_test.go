@@ -0,0 +1,68 @@
+package upstream
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides([]string{"API.example.com:443=10.0.0.5:8443", "api.example.com:80=10.0.0.5", "v6.example.com:443=[::1]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Overrides{"api.example.com:443": "10.0.0.5:8443", "api.example.com:80": "10.0.0.5:80", "v6.example.com:443": "[::1]:443"}
+	for from, to := range want {
+		if overrides[from] != to {
+			t.Errorf("override of %s = %s, want %s", from, overrides[from], to)
+		}
+	}
+
+	for _, entry := range []string{"api.example.com:443", "api.example.com=10.0.0.5:8443"} {
+		if _, err := ParseOverrides([]string{entry}); err == nil {
+			t.Errorf("ParseOverrides(%q) succeeded, want an error", entry)
+		}
+	}
+}
+
+func TestTransportOverrides(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+
+	overrides, err := ParseOverrides([]string{"api.internal.example:80=" + strings.TrimPrefix(server.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []string{HTTP2Auto, HTTP2Off} {
+		transport, err := NewTransport(mode, overrides, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := (&http.Client{Transport: transport}).Get("http://api.internal.example/pets")
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		host, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(host) != "api.internal.example" {
+			t.Errorf("%s: Host = %s, want the original host", mode, host)
+		}
+	}
+
+	// Other addresses are dialed as they are
+	var dialed string
+	dial := overrides.Dial(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return nil, nil
+	})
+	dial(context.Background(), "tcp", "api.example.com:443")
+	if dialed != "api.example.com:443" {
+		t.Errorf("dialed %s, want the address itself", dialed)
+	}
+}
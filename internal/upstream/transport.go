@@ -0,0 +1,96 @@
+package upstream
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// The ways upstream requests use HTTP/2, see NewTransport
+const (
+	// HTTP2Auto negotiates HTTP/2 over TLS and uses HTTP/1.1 otherwise
+	HTTP2Auto = "auto"
+	// HTTP2PriorKnowledge always speaks HTTP/2, unencrypted (h2c) to http URLs
+	HTTP2PriorKnowledge = "prior_knowledge"
+	// HTTP2Off always speaks HTTP/1.1
+	HTTP2Off = "off"
+)
+
+// ControlFunc checks a connection about to be made to an address, after it
+// is resolved, like net.Dialer.ControlContext
+type ControlFunc func(ctx context.Context, network, address string, c syscall.RawConn) error
+
+// NewTransport returns the transport upstream requests are sent through,
+// shared by all tool calls, speaking HTTP/2 as mode says and connecting to
+// the overrides of addresses. An empty mode is HTTP2Auto. control, if not
+// nil, may refuse the connections, e.g. Guard.Control.
+func NewTransport(mode string, overrides Overrides, control ControlFunc) (http.RoundTripper, error) {
+	// As http.DefaultTransport dials
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, ControlContext: control}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = overrides.Dial(dialer.DialContext)
+	switch mode {
+	case "", HTTP2Auto:
+		return base, nil
+	case HTTP2Off:
+		// A non-nil, empty TLSNextProto keeps TLS connections from upgrading
+		base.ForceAttemptHTTP2 = false
+		base.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		return base, nil
+	case HTTP2PriorKnowledge:
+		dial := overrides.Dial((&net.Dialer{ControlContext: control}).DialContext)
+		cleartext := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}
+		encrypted := &http2.Transport{}
+		if len(overrides) > 0 {
+			encrypted.DialTLSContext = func(ctx context.Context, network, addr string, config *tls.Config) (net.Conn, error) {
+				return dialTLS(ctx, dial, network, addr, config)
+			}
+		}
+		return &priorKnowledge{tls: encrypted, cleartext: cleartext}, nil
+	}
+	return nil, fmt.Errorf("invalid HTTP/2 mode %q: use %s, %s or %s", mode, HTTP2Auto, HTTP2PriorKnowledge, HTTP2Off)
+}
+
+// priorKnowledge sends requests over HTTP/2 without negotiating it, over
+// TLS to https URLs and in cleartext to http ones
+type priorKnowledge struct {
+	tls       *http2.Transport
+	cleartext *http2.Transport
+}
+
+func (t *priorKnowledge) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.cleartext.RoundTrip(req)
+	}
+	return t.tls.RoundTrip(req)
+}
+
+// dialTLS connects to an address over TLS, verifying the server name of
+// config, which is that of the original address
+func dialTLS(ctx context.Context, dial DialFunc, network, addr string, config *tls.Config) (net.Conn, error) {
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if protocol := tlsConn.ConnectionState().NegotiatedProtocol; protocol != http2.NextProtoTLS {
+		conn.Close()
+		return nil, fmt.Errorf("http2: unexpected ALPN protocol %q; want %q", protocol, http2.NextProtoTLS)
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,60 @@
+package upstream
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestNewTransportPriorKnowledge(t *testing.T) {
+	server := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer server.Close()
+
+	transport, err := NewTransport(HTTP2PriorKnowledge, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("protocol = %s, want HTTP/2 without negotiation", resp.Proto)
+	}
+}
+
+func TestNewTransportNegotiation(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	for mode, want := range map[string]int{HTTP2Auto: 2, HTTP2Off: 1} {
+		transport, err := NewTransport(mode, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Trust the server, leaving the protocols to the transport
+		tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+		tlsConfig.NextProtos = nil
+		transport.(*http.Transport).TLSClientConfig = tlsConfig
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != want {
+			t.Errorf("%s: protocol = %s, want HTTP/%d", mode, resp.Proto, want)
+		}
+	}
+
+	if _, err := NewTransport("h3", nil, nil); err == nil {
+		t.Error("expected an unknown mode to be refused")
+	}
+}
@@ -0,0 +1,31 @@
+// Package upstream provides the middleware chain serve mode sends API requests through
+package upstream
+
+import "net/http"
+
+// Middleware wraps the round tripper upstream requests are sent through, e.g.
+// to authorize, retry or restrict them
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps base in the middlewares. The first middleware is the outermost,
+// so it sees a request first and its response last. A nil base stands for
+// http.DefaultTransport and nil middlewares are skipped.
+func Chain(base http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			base = middlewares[i](base)
+		}
+	}
+	return base
+}
@@ -0,0 +1,40 @@
+package upstream
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" request")
+				resp, err := next.RoundTrip(req)
+				calls = append(calls, name+" response")
+				return resp, err
+			})
+		}
+	}
+	base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls = append(calls, "base")
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://api.example.com/pets", nil)
+	if _, err := Chain(base, record("outer"), nil, record("inner")).RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"outer request", "inner request", "base", "inner response", "outer response"}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+
+	if Chain(nil) != http.DefaultTransport {
+		t.Error("expected a nil base to stand for http.DefaultTransport")
+	}
+}
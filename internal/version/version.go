@@ -0,0 +1,53 @@
+// Package version reports the version of the mcprox binary
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is set at build time with
+// -ldflags "-X github.com/berkantay/mcprox/internal/version.Version=<version>"
+var Version = "dev"
+
+// Get returns the version of the running binary, falling back to the module
+// version recorded by go install when none was set at build time
+func Get() string {
+	if Version != "dev" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return Version
+}
+
+// BuildInfo describes how the running binary was built
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	// Commit and Time are the VCS revision and its time, if the binary was
+	// built from a repository
+	Commit string `json:"commit,omitempty"`
+	Time   string `json:"time,omitempty"`
+	// Modified reports uncommitted changes in the repository
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Info returns the build information of the running binary
+func Info() BuildInfo {
+	info := BuildInfo{Version: Get(), GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.Time = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
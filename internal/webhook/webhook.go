@@ -0,0 +1,199 @@
+// Package webhook posts an event to a configured URL after every tool call of
+// serve mode, so that API activity initiated by models can be streamed into a
+// SIEM. Events never carry the arguments or results of calls.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/berkantay/mcprox/internal/config"
+	"github.com/berkantay/mcprox/internal/version"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// maxPending bounds the events being delivered at once; events beyond it are
+// dropped rather than queued, so a slow webhook can't exhaust memory
+const maxPending = 64
+
+// SignatureHeader carries the signature of the payload, see Sign, so that
+// receivers can authenticate events
+const SignatureHeader = "X-Mcprox-Signature"
+
+// Statuses of tool calls
+const (
+	// StatusOK is a call that returned a result
+	StatusOK = "ok"
+	// StatusToolError is a call whose result reports an error, e.g. an
+	// upstream error response
+	StatusToolError = "tool_error"
+	// StatusError is a call that failed without a result
+	StatusError = "error"
+)
+
+// Event is posted after each tool call
+type Event struct {
+	Tool string `json:"tool"`
+	// ArgsHash is the SHA-256 of the call's arguments as JSON, which lets
+	// identical calls be correlated without disclosing the arguments
+	ArgsHash  string    `json:"args_hash"`
+	Status    string    `json:"status"`
+	LatencyMS int64     `json:"latency_ms"`
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"session_id,omitempty"`
+	Server    string    `json:"server,omitempty"`
+}
+
+// Notifier posts the events of tool calls to a webhook
+type Notifier struct {
+	URL string
+	// Secret, if set, signs the payloads, see SignatureHeader
+	Secret string
+	// Server names the MCP server in events
+	Server  string
+	Timeout time.Duration
+	Client  *http.Client
+	Logger  *zap.Logger
+
+	once    sync.Once
+	pending chan struct{}
+}
+
+// FromConfig returns the notifier configured by webhook.url, nil if unset
+func FromConfig(serverName string, logger *zap.Logger) *Notifier {
+	url := config.GetString("webhook.url")
+	if url == "" {
+		return nil
+	}
+	return &Notifier{
+		URL:     url,
+		Secret:  config.GetString("webhook.secret"),
+		Server:  serverName,
+		Timeout: time.Duration(config.GetInt("webhook.timeout")) * time.Second,
+		Logger:  logger,
+	}
+}
+
+// Wrap returns a handler posting an event after each call of next. Events are
+// delivered in the background and never delay or fail the call.
+func (n *Notifier) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		event := Event{
+			Tool:      request.Params.Name,
+			ArgsHash:  HashArgs(request.Params.Arguments),
+			Status:    StatusOK,
+			LatencyMS: time.Since(start).Milliseconds(),
+			Time:      start.UTC(),
+			Server:    n.Server,
+		}
+		switch {
+		case err != nil:
+			event.Status = StatusError
+		case result != nil && result.IsError:
+			event.Status = StatusToolError
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			event.SessionID = session.SessionID()
+		}
+		n.Notify(event)
+		return result, err
+	}
+}
+
+// Notify delivers an event in the background, dropping it if too many are
+// being delivered already. Failures are logged and otherwise ignored.
+func (n *Notifier) Notify(event Event) {
+	n.once.Do(func() { n.pending = make(chan struct{}, maxPending) })
+	select {
+	case n.pending <- struct{}{}:
+	default:
+		n.logger().Warn("Too many webhook events pending; event dropped", zap.String("tool", event.Tool))
+		return
+	}
+
+	go func() {
+		defer func() { <-n.pending }()
+		if err := n.Send(context.Background(), event); err != nil {
+			n.logger().Warn("Failed to deliver webhook event", zap.String("tool", event.Tool), zap.Error(err))
+		}
+	}()
+}
+
+// Send posts an event to the webhook and waits for its response
+func (n *Notifier) Send(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if n.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mcprox/"+version.Get())
+	if n.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.Secret, payload))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the signature of a payload, sha256= followed by the hex
+// HMAC-SHA256 of the payload keyed with secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// HashArgs returns the SHA-256 of the arguments of a call as JSON, whose
+// object keys are sorted, so equal arguments always hash the same
+func HashArgs(args map[string]interface{}) string {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (n *Notifier) logger() *zap.Logger {
+	if n.Logger == nil {
+		return zap.NewNop()
+	}
+	return n.Logger
+}
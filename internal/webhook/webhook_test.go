@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestWrap(t *testing.T) {
+	received := make(chan *http.Request, 3)
+	bodies := make(chan []byte, 3)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer ts.Close()
+
+	n := &Notifier{URL: ts.URL, Secret: "s3cret", Server: "Pets", Timeout: time.Second}
+	results := map[string]func() (*mcp.CallToolResult, error){
+		"get_pets": func() (*mcp.CallToolResult, error) { return mcp.NewToolResultText("[]"), nil },
+		"post_pets": func() (*mcp.CallToolResult, error) {
+			result := mcp.NewToolResultText("400 Bad Request")
+			result.IsError = true
+			return result, nil
+		},
+		"delete_pet": func() (*mcp.CallToolResult, error) { return nil, errors.New("upstream unavailable") },
+	}
+	handler := n.Wrap(func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return results[request.Params.Name]()
+	})
+
+	want := map[string]string{"get_pets": StatusOK, "post_pets": StatusToolError, "delete_pet": StatusError}
+	for tool := range want {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = tool
+		request.Params.Arguments = map[string]interface{}{"secret": "value"}
+		handler(context.Background(), request)
+	}
+
+	for range want {
+		var r *http.Request
+		var body []byte
+		select {
+		case r = <-received:
+			body = <-bodies
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for webhook events")
+		}
+
+		if got := r.Header.Get(SignatureHeader); got != Sign("s3cret", body) {
+			t.Errorf("signature = %q", got)
+		}
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Status != want[event.Tool] || event.Server != "Pets" {
+			t.Errorf("unexpected event %+v", event)
+		}
+		if event.ArgsHash != HashArgs(map[string]interface{}{"secret": "value"}) {
+			t.Errorf("args hash = %q", event.ArgsHash)
+		}
+	}
+}
+
+func TestHashArgs(t *testing.T) {
+	a := HashArgs(map[string]interface{}{"a": 1, "b": "x"})
+	b := HashArgs(map[string]interface{}{"b": "x", "a": 1})
+	if a != b || len(a) != 64 {
+		t.Errorf("hashes of equal arguments differ: %s, %s", a, b)
+	}
+	if HashArgs(nil) != HashArgs(map[string]interface{}{}) {
+		t.Error("missing arguments should hash like empty ones")
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	n := &Notifier{URL: ts.URL}
+	if err := n.Send(context.Background(), Event{Tool: "get_pets"}); err == nil {
+		t.Error("expected an error for a failing webhook")
+	}
+}